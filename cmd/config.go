@@ -112,6 +112,13 @@ func setGenesis(ctx *cli.Context, cfg *config.OntologyConfig) error {
 		if cfg.Genesis.DBFT.GenBlockTime <= 0 {
 			cfg.Genesis.DBFT.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
 		}
+	case config.CONSENSUS_TYPE_SBFT:
+		if len(cfg.Genesis.SBFT.Bookkeepers) < config.SBFT_MIN_NODE_NUM {
+			return fmt.Errorf("SBFT consensus at least need %d bookkeepers in config", config.SBFT_MIN_NODE_NUM)
+		}
+		if cfg.Genesis.SBFT.GenBlockTime <= 0 {
+			cfg.Genesis.SBFT.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
+		}
 	case config.CONSENSUS_TYPE_VBFT:
 		err = nutils.CheckVBFTConfig(cfg.Genesis.VBFT)
 		if err != nil {
@@ -133,11 +140,19 @@ func setCommonConfig(ctx *cli.Context, cfg *config.CommonConfig) {
 	cfg.GasLimit = ctx.Uint64(utils.GetFlagName(utils.GasLimitFlag))
 	cfg.GasPrice = ctx.Uint64(utils.GetFlagName(utils.GasPriceFlag))
 	cfg.DataDir = ctx.String(utils.GetFlagName(utils.DataDirFlag))
+	cfg.PruneRetention = uint32(ctx.Uint(utils.GetFlagName(utils.PruneRetentionFlag)))
+	cfg.EnableArchive = ctx.Bool(utils.GetFlagName(utils.EnableArchiveFlag))
+	cfg.EnableStorageTrie = ctx.Bool(utils.GetFlagName(utils.EnableStorageTrieFlag))
+	cfg.DBBackend = ctx.String(utils.GetFlagName(utils.DBBackendFlag))
 }
 
 func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) {
 	cfg.EnableConsensus = ctx.Bool(utils.GetFlagName(utils.EnableConsensusFlag))
 	cfg.MaxTxInBlock = ctx.Uint(utils.GetFlagName(utils.MaxTxInBlockFlag))
+	cfg.EmergencyRotationTimeout = ctx.Uint(utils.GetFlagName(utils.EmergencyRotationTimeoutFlag))
+	cfg.MaxTxPerPayer = ctx.Uint(utils.GetFlagName(utils.MaxTxPerPayerFlag))
+	cfg.ReservedSysTxInBlock = ctx.Uint(utils.GetFlagName(utils.ReservedSysTxInBlockFlag))
+	cfg.MaxBlockGas = ctx.Uint64(utils.GetFlagName(utils.MaxBlockGasFlag))
 }
 
 func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
@@ -146,6 +161,7 @@ func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
 	cfg.NetworkName = config.GetNetworkName(cfg.NetworkId)
 	cfg.NodePort = ctx.Uint(utils.GetFlagName(utils.NodePortFlag))
 	cfg.HttpInfoPort = ctx.Uint(utils.GetFlagName(utils.HttpInfoPortFlag))
+	cfg.HttpMetricsPort = ctx.Uint(utils.GetFlagName(utils.HttpMetricsPortFlag))
 	cfg.ReservedPeersOnly = ctx.Bool(utils.GetFlagName(utils.ReservedPeersOnlyFlag))
 	cfg.MaxConnInBound = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundFlag))
 	cfg.MaxConnOutBound = ctx.Uint(utils.GetFlagName(utils.MaxConnOutBoundFlag))
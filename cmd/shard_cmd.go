@@ -0,0 +1,573 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	cmdcom "github.com/ontio/ontology/cmd/common"
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	httpcom "github.com/ontio/ontology/http/base/common"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_stake"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/urfave/cli"
+)
+
+var shardTxFlags = []cli.Flag{
+	utils.RPCPortFlag,
+	utils.ShardIDFlag,
+	utils.TransactionGasPriceFlag,
+	utils.TransactionGasLimitFlag,
+	utils.WalletFileFlag,
+	utils.AccountAddressFlag,
+	utils.ShardParamsFileFlag,
+}
+
+var ShardCommand = cli.Command{
+	Name:        "shard",
+	Action:      cli.ShowSubcommandHelp,
+	Usage:       "Manage shards",
+	ArgsUsage:   " ",
+	Description: "Shard management commands can create and config shard, handle peers joining or exiting shard, activate shard and query shard state.",
+	Subcommands: []cli.Command{
+		{
+			Action:    createShard,
+			Name:      "create",
+			Usage:     "Create a new shard, the creator is the signer account",
+			ArgsUsage: " ",
+			Flags:     shardTxFlags,
+		},
+		{
+			Action:    configShard,
+			Name:      "config",
+			Usage:     "Config shard network, gas and consensus parameters",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+				utils.ShardNetworkMinFlag,
+				utils.ShardStakeAssetFlag,
+				utils.ShardGasAssetFlag,
+				utils.ShardGasPriceFlag,
+				utils.ShardGasLimitFlag,
+				utils.ShardVbftConfigFlag,
+				utils.ShardConsensusTypeFlag,
+				utils.ShardConsensusConfigFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    applyJoinShard,
+			Name:      "applyjoin",
+			Usage:     "Apply peer to join shard",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+				utils.ShardPeerOwnerFlag,
+				utils.ShardPeerPubKeyFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    approveJoinShard,
+			Name:      "approvejoin",
+			Usage:     "Approve peers to join shard, only shard creator can approve",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+				utils.ShardPeerPubKeyFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    joinShard,
+			Name:      "join",
+			Usage:     "Join shard with init stake after approved",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+				utils.ShardPeerOwnerFlag,
+				utils.ShardPeerPubKeyFlag,
+				utils.ShardPeerIpAddressFlag,
+				utils.ShardStakeAmountFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    activateShard,
+			Name:      "activate",
+			Usage:     "Activate shard, only shard creator can activate",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    exitShard,
+			Name:      "exit",
+			Usage:     "Exit peer from shard",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+				utils.ShardPeerOwnerFlag,
+				utils.ShardPeerPubKeyFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    shardInfo,
+			Name:      "info",
+			Usage:     "Show shard detail",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				utils.RPCPortFlag,
+				utils.ShardTargetIDFlag,
+				utils.ShardParamsFileFlag,
+			},
+		},
+		{
+			Action:    shardPeers,
+			Name:      "peers",
+			Usage:     "Show peers stake info of shard at view",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				utils.RPCPortFlag,
+				utils.ShardTargetIDFlag,
+				utils.ShardViewFlag,
+				utils.ShardParamsFileFlag,
+			},
+		},
+		{
+			Action:    shardCommitDposStatus,
+			Name:      "commitdpos",
+			Usage:     "Show commit dpos status of shard",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				utils.RPCPortFlag,
+				utils.ShardTargetIDFlag,
+				utils.ShardParamsFileFlag,
+			},
+		},
+	},
+}
+
+// getShardCmdParams load params from json file, and overwrite them by flags
+func getShardCmdParams(ctx *cli.Context) (*utils.ShardCmdParams, error) {
+	params := &utils.ShardCmdParams{}
+	if file := ctx.String(utils.GetFlagName(utils.ShardParamsFileFlag)); file != "" {
+		var err error
+		params, err = utils.LoadShardCmdParams(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardTargetIDFlag)) {
+		params.ShardID = ctx.Uint64(utils.GetFlagName(utils.ShardTargetIDFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardPeerOwnerFlag)) {
+		params.PeerOwner = ctx.String(utils.GetFlagName(utils.ShardPeerOwnerFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardPeerPubKeyFlag)) {
+		pubKeys := strings.Split(ctx.String(utils.GetFlagName(utils.ShardPeerPubKeyFlag)), ",")
+		params.PeerPubKey = strings.TrimSpace(pubKeys[0])
+		params.PeerPubKeys = make([]string, 0, len(pubKeys))
+		for _, pubKey := range pubKeys {
+			params.PeerPubKeys = append(params.PeerPubKeys, strings.TrimSpace(pubKey))
+		}
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardPeerIpAddressFlag)) {
+		params.IpAddress = ctx.String(utils.GetFlagName(utils.ShardPeerIpAddressFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardStakeAmountFlag)) {
+		params.StakeAmount = ctx.Uint64(utils.GetFlagName(utils.ShardStakeAmountFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardPeerStakesFlag)) {
+		stakes, err := utils.ParsePeerStakes(ctx.String(utils.GetFlagName(utils.ShardPeerStakesFlag)))
+		if err != nil {
+			return nil, err
+		}
+		params.PeerStakes = stakes
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardNetworkMinFlag)) {
+		params.NetworkMin = uint32(ctx.Uint(utils.GetFlagName(utils.ShardNetworkMinFlag)))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardStakeAssetFlag)) || params.StakeAsset == "" {
+		params.StakeAsset = ctx.String(utils.GetFlagName(utils.ShardStakeAssetFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardGasAssetFlag)) || params.GasAsset == "" {
+		params.GasAsset = ctx.String(utils.GetFlagName(utils.ShardGasAssetFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardGasPriceFlag)) {
+		params.GasPrice = ctx.Uint64(utils.GetFlagName(utils.ShardGasPriceFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardGasLimitFlag)) {
+		params.GasLimit = ctx.Uint64(utils.GetFlagName(utils.ShardGasLimitFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardVbftConfigFlag)) {
+		file := ctx.String(utils.GetFlagName(utils.ShardVbftConfigFlag))
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read vbft config file:%s error:%s", file, err)
+		}
+		params.VbftConfig = &config.VBFTConfig{}
+		if err := json.Unmarshal(data, params.VbftConfig); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal vbft config error:%s", err)
+		}
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardConsensusTypeFlag)) || params.ConsensusType == "" {
+		params.ConsensusType = ctx.String(utils.GetFlagName(utils.ShardConsensusTypeFlag))
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardConsensusConfigFlag)) {
+		file := ctx.String(utils.GetFlagName(utils.ShardConsensusConfigFlag))
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read consensus config file:%s error:%s", file, err)
+		}
+		params.ConsensusConfig = &utils.ShardConsensusConfig{}
+		if err := json.Unmarshal(data, params.ConsensusConfig); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal consensus config error:%s", err)
+		}
+	}
+	if ctx.IsSet(utils.GetFlagName(utils.ShardViewFlag)) {
+		params.View = ctx.Uint64(utils.GetFlagName(utils.ShardViewFlag))
+	}
+	return params, nil
+}
+
+func getTargetShardID(params *utils.ShardCmdParams) (common.ShardID, error) {
+	if params.ShardID == 0 {
+		return common.ShardID{}, fmt.Errorf("missing %s argument", utils.ShardTargetIDFlag.Name)
+	}
+	return common.NewShardID(params.ShardID)
+}
+
+// parseAccountAddress parse address, label or index of account, use the account of AccountAddressFlag or the default
+// account of wallet if address is empty
+func parseAccountAddress(ctx *cli.Context, address string) (common.Address, error) {
+	if address == "" {
+		address = ctx.String(utils.GetFlagName(utils.AccountAddressFlag))
+	}
+	if address == "" {
+		wallet, err := cmdcom.OpenWallet(ctx)
+		if err != nil {
+			return common.ADDRESS_EMPTY, err
+		}
+		acc := wallet.GetDefaultAccountMetadata()
+		if acc == nil {
+			return common.ADDRESS_EMPTY, fmt.Errorf("cannot get default account")
+		}
+		address = acc.Address
+	}
+	addr, err := cmdcom.ParseAddress(address, ctx)
+	if err != nil {
+		return common.ADDRESS_EMPTY, err
+	}
+	return httpcom.GetAddress(addr)
+}
+
+func getAssetAddress(asset string) (common.Address, error) {
+	switch strings.ToLower(asset) {
+	case utils.ASSET_ONT:
+		return nutils.OntContractAddress, nil
+	case utils.ASSET_ONG:
+		return nutils.OngContractAddress, nil
+	default:
+		return common.ADDRESS_EMPTY, fmt.Errorf("unsupport asset:%s", asset)
+	}
+}
+
+// sendShardTx sign the native invoke transaction by account and send it to the shard of ShardIDFlag
+func sendShardTx(ctx *cli.Context, contract common.Address, method string, param interface{}) error {
+	SetRpcPort(ctx)
+	shardId, err := common.NewShardID(ctx.Uint64(utils.GetFlagName(utils.ShardIDFlag)))
+	if err != nil {
+		return err
+	}
+	signer, err := cmdcom.GetAccount(ctx)
+	if err != nil {
+		return fmt.Errorf("get signer account error:%s", err)
+	}
+	gasPrice := ctx.Uint64(utils.GetFlagName(utils.TransactionGasPriceFlag))
+	gasLimit := ctx.Uint64(utils.GetFlagName(utils.TransactionGasLimitFlag))
+	networkId, err := utils.GetNetworkId()
+	if err != nil {
+		return err
+	}
+	if networkId == config.NETWORK_ID_SOLO_NET {
+		gasPrice = 0
+	}
+	txHash, err := utils.InvokeShardNativeContract(shardId, gasPrice, gasLimit, signer, contract, method,
+		[]interface{}{param})
+	if err != nil {
+		return fmt.Errorf("invoke %s error:%s", method, err)
+	}
+	PrintInfoMsg("  TxHash:%s", txHash)
+	PrintInfoMsg("\nTip:")
+	PrintInfoMsg("  Using './ontology info status %s' to query transaction status.", txHash)
+	return nil
+}
+
+func createShard(ctx *cli.Context) error {
+	creator, err := parseAccountAddress(ctx, "")
+	if err != nil {
+		return err
+	}
+	param := &shardmgmt.CreateShardParam{ParentShardID: common.RootShardID, Creator: creator}
+	PrintInfoMsg("Create shard:")
+	PrintInfoMsg("  Creator:%s", creator.ToBase58())
+	return sendShardTx(ctx, nutils.ShardMgmtContractAddress, shardmgmt.CREATE_SHARD_NAME, param)
+}
+
+func configShard(ctx *cli.Context) error {
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	stakeAsset, err := getAssetAddress(params.StakeAsset)
+	if err != nil {
+		return err
+	}
+	gasAsset, err := getAssetAddress(params.GasAsset)
+	if err != nil {
+		return err
+	}
+	vbftCfgData, err := params.GetVbftConfigData()
+	if err != nil {
+		return err
+	}
+	consensusCfgData, err := params.GetConsensusConfigData()
+	if err != nil {
+		return err
+	}
+	param := &shardmgmt.ConfigShardParam{
+		ShardID:             shardId,
+		NetworkMin:          params.NetworkMin,
+		StakeAssetAddress:   stakeAsset,
+		GasAssetAddress:     gasAsset,
+		GasPrice:            params.GasPrice,
+		GasLimit:            params.GasLimit,
+		VbftConfigData:      vbftCfgData,
+		ConsensusType:       params.ConsensusType,
+		ConsensusConfigData: consensusCfgData,
+	}
+	PrintInfoMsg("Config shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  Consensus:%s", params.ConsensusType)
+	return sendShardTx(ctx, nutils.ShardMgmtContractAddress, shardmgmt.CONFIG_SHARD_NAME, param)
+}
+
+func applyJoinShard(ctx *cli.Context) error {
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	owner, err := parseAccountAddress(ctx, params.PeerOwner)
+	if err != nil {
+		return err
+	}
+	if params.PeerPubKey == "" {
+		return fmt.Errorf("missing %s argument", utils.ShardPeerPubKeyFlag.Name)
+	}
+	param := &shardmgmt.ApplyJoinShardParam{
+		ShardId:    shardId,
+		PeerOwner:  owner,
+		PeerPubKey: params.PeerPubKey,
+	}
+	PrintInfoMsg("Apply join shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  Peer:%s", params.PeerPubKey)
+	return sendShardTx(ctx, nutils.ShardMgmtContractAddress, shardmgmt.APPLY_JOIN_SHARD_NAME, param)
+}
+
+func approveJoinShard(ctx *cli.Context) error {
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	if len(params.PeerPubKeys) == 0 {
+		return fmt.Errorf("missing %s argument", utils.ShardPeerPubKeyFlag.Name)
+	}
+	param := &shardmgmt.ApproveJoinShardParam{
+		ShardId:    shardId,
+		PeerPubKey: params.PeerPubKeys,
+	}
+	PrintInfoMsg("Approve join shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  Peers:%s", strings.Join(params.PeerPubKeys, ","))
+	return sendShardTx(ctx, nutils.ShardMgmtContractAddress, shardmgmt.APPROVE_JOIN_SHARD_NAME, param)
+}
+
+func joinShard(ctx *cli.Context) error {
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	owner, err := parseAccountAddress(ctx, params.PeerOwner)
+	if err != nil {
+		return err
+	}
+	if params.PeerPubKey == "" || params.IpAddress == "" {
+		return fmt.Errorf("missing %s or %s argument", utils.ShardPeerPubKeyFlag.Name, utils.ShardPeerIpAddressFlag.Name)
+	}
+	param := &shardmgmt.JoinShardParam{
+		ShardID:     shardId,
+		IpAddress:   params.IpAddress,
+		PeerOwner:   owner,
+		PeerPubKey:  params.PeerPubKey,
+		StakeAmount: params.StakeAmount,
+	}
+	PrintInfoMsg("Join shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  Peer:%s", params.PeerPubKey)
+	PrintInfoMsg("  Stake:%d", params.StakeAmount)
+	return sendShardTx(ctx, nutils.ShardMgmtContractAddress, shardmgmt.JOIN_SHARD_NAME, param)
+}
+
+func activateShard(ctx *cli.Context) error {
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	param := &shardmgmt.ActivateShardParam{ShardID: shardId}
+	PrintInfoMsg("Activate shard:%d", shardId.ToUint64())
+	return sendShardTx(ctx, nutils.ShardMgmtContractAddress, shardmgmt.ACTIVATE_SHARD_NAME, param)
+}
+
+func exitShard(ctx *cli.Context) error {
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	owner, err := parseAccountAddress(ctx, params.PeerOwner)
+	if err != nil {
+		return err
+	}
+	if params.PeerPubKey == "" {
+		return fmt.Errorf("missing %s argument", utils.ShardPeerPubKeyFlag.Name)
+	}
+	param := &shardmgmt.ExitShardParam{
+		ShardId:    shardId,
+		PeerOwner:  owner,
+		PeerPubKey: params.PeerPubKey,
+	}
+	PrintInfoMsg("Exit shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  Peer:%s", params.PeerPubKey)
+	return sendShardTx(ctx, nutils.ShardMgmtContractAddress, shardmgmt.EXIT_SHARD_NAME, param)
+}
+
+func shardInfo(ctx *cli.Context) error {
+	SetRpcPort(ctx)
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	data, err := utils.PrepareInvokeShardNativeContract(common.RootShardID, nutils.ShardMgmtContractAddress,
+		shardmgmt.GET_SHARD_DETAIL, []interface{}{shardId.ToUint64()})
+	if err != nil {
+		return fmt.Errorf("get shard detail error:%s", err)
+	}
+	PrintJsonData(data)
+	return nil
+}
+
+func getShardCurrentView(shardId common.ShardID) (uint64, error) {
+	data, err := utils.PrepareInvokeShardNativeContract(common.RootShardID, nutils.ShardStakeAddress,
+		shard_stake.GET_CURRENT_VIEW, []interface{}{shardId})
+	if err != nil {
+		return 0, fmt.Errorf("get current view error:%s", err)
+	}
+	return common.BigIntFromNeoBytes(data).Uint64(), nil
+}
+
+func shardPeers(ctx *cli.Context) error {
+	SetRpcPort(ctx)
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	view := params.View
+	if !ctx.IsSet(utils.GetFlagName(utils.ShardViewFlag)) && view == 0 {
+		view, err = getShardCurrentView(shardId)
+		if err != nil {
+			return err
+		}
+	}
+	param := &shard_stake.GetPeerInfoParam{ShardId: shardId, View: view}
+	data, err := utils.PrepareInvokeShardNativeContract(common.RootShardID, nutils.ShardStakeAddress,
+		shard_stake.GET_PEER_INFO, []interface{}{param})
+	if err != nil {
+		return fmt.Errorf("get peer info error:%s", err)
+	}
+	PrintInfoMsg("Shard:%d View:%d", shardId.ToUint64(), view)
+	PrintJsonData(data)
+	return nil
+}
+
+func shardCommitDposStatus(ctx *cli.Context) error {
+	SetRpcPort(ctx)
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	view, err := getShardCurrentView(shardId)
+	if err != nil {
+		return err
+	}
+	data, err := utils.PrepareInvokeShardNativeContract(common.RootShardID, nutils.ShardStakeAddress,
+		shard_stake.GET_IS_COMMITTING, []interface{}{shardId})
+	if err != nil {
+		return fmt.Errorf("get is committing error:%s", err)
+	}
+	PrintInfoMsg("Shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  CurrentView:%d", view)
+	PrintInfoMsg("  IsCommitting:%v", bytes.Equal(data, nutils.BYTE_TRUE))
+	return nil
+}
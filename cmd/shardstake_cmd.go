@@ -0,0 +1,214 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_stake"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/urfave/cli"
+)
+
+var ShardStakeCommand = cli.Command{
+	Name:        "shardstake",
+	Action:      cli.ShowSubcommandHelp,
+	Usage:       "Manage stake of shard",
+	ArgsUsage:   " ",
+	Description: "Shard stake commands can stake asset to shard peers, unfreeze and withdraw stake asset, withdraw fee and query user stake info.",
+	Subcommands: []cli.Command{
+		{
+			Action:    userStake,
+			Name:      "stake",
+			Usage:     "Stake asset to shard peers",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+				utils.ShardPeerStakesFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    unfreezeStake,
+			Name:      "unfreeze",
+			Usage:     "Unfreeze stake asset from shard peers",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+				utils.ShardPeerStakesFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    withdrawStake,
+			Name:      "withdraw",
+			Usage:     "Withdraw unfrozen stake asset",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    withdrawStakeFee,
+			Name:      "withdrawfee",
+			Usage:     "Withdraw fee dividends of shard stake",
+			ArgsUsage: " ",
+			Flags: append([]cli.Flag{
+				utils.ShardTargetIDFlag,
+			}, shardTxFlags...),
+		},
+		{
+			Action:    stakeUserInfo,
+			Name:      "userinfo",
+			Usage:     "Show user stake info of shard at view",
+			ArgsUsage: "[<address|label|index>]",
+			Flags: []cli.Flag{
+				utils.RPCPortFlag,
+				utils.WalletFileFlag,
+				utils.AccountAddressFlag,
+				utils.ShardTargetIDFlag,
+				utils.ShardViewFlag,
+				utils.ShardParamsFileFlag,
+			},
+		},
+	},
+}
+
+// getPeerAmounts return peer stake amounts in the order of peer public key
+func getPeerAmounts(params *utils.ShardCmdParams) ([]*shard_stake.PeerAmount, error) {
+	if len(params.PeerStakes) == 0 {
+		return nil, fmt.Errorf("missing %s argument", utils.ShardPeerStakesFlag.Name)
+	}
+	peers := make([]string, 0, len(params.PeerStakes))
+	for peer := range params.PeerStakes {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+	amounts := make([]*shard_stake.PeerAmount, 0, len(peers))
+	for _, peer := range peers {
+		amounts = append(amounts, &shard_stake.PeerAmount{PeerPubKey: peer, Amount: params.PeerStakes[peer]})
+	}
+	return amounts, nil
+}
+
+func getStakeParams(ctx *cli.Context) (common.ShardID, common.Address, *utils.ShardCmdParams, error) {
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return common.ShardID{}, common.ADDRESS_EMPTY, nil, err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return common.ShardID{}, common.ADDRESS_EMPTY, nil, err
+	}
+	user, err := parseAccountAddress(ctx, "")
+	if err != nil {
+		return common.ShardID{}, common.ADDRESS_EMPTY, nil, err
+	}
+	return shardId, user, params, nil
+}
+
+func userStake(ctx *cli.Context) error {
+	shardId, user, params, err := getStakeParams(ctx)
+	if err != nil {
+		return err
+	}
+	amounts, err := getPeerAmounts(params)
+	if err != nil {
+		return err
+	}
+	param := &shard_stake.UserStakeParam{ShardId: shardId, User: user, Value: amounts}
+	PrintInfoMsg("Stake shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  User:%s", user.ToBase58())
+	for _, amount := range amounts {
+		PrintInfoMsg("  Peer:%s Amount:%d", amount.PeerPubKey, amount.Amount)
+	}
+	return sendShardTx(ctx, nutils.ShardStakeAddress, shard_stake.USER_STAKE, param)
+}
+
+func unfreezeStake(ctx *cli.Context) error {
+	shardId, user, params, err := getStakeParams(ctx)
+	if err != nil {
+		return err
+	}
+	amounts, err := getPeerAmounts(params)
+	if err != nil {
+		return err
+	}
+	param := &shard_stake.UnfreezeFromShardParam{ShardId: shardId, User: user, Value: amounts}
+	PrintInfoMsg("Unfreeze stake of shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  User:%s", user.ToBase58())
+	for _, amount := range amounts {
+		PrintInfoMsg("  Peer:%s Amount:%d", amount.PeerPubKey, amount.Amount)
+	}
+	return sendShardTx(ctx, nutils.ShardStakeAddress, shard_stake.UNFREEZE_STAKE, param)
+}
+
+func withdrawStake(ctx *cli.Context) error {
+	shardId, user, _, err := getStakeParams(ctx)
+	if err != nil {
+		return err
+	}
+	param := &shard_stake.WithdrawStakeAssetParam{ShardId: shardId, User: user}
+	PrintInfoMsg("Withdraw stake of shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  User:%s", user.ToBase58())
+	return sendShardTx(ctx, nutils.ShardStakeAddress, shard_stake.WITHDRAW_STAKE, param)
+}
+
+func withdrawStakeFee(ctx *cli.Context) error {
+	shardId, user, _, err := getStakeParams(ctx)
+	if err != nil {
+		return err
+	}
+	param := &shard_stake.WithdrawFeeParam{ShardId: shardId, User: user}
+	PrintInfoMsg("Withdraw fee of shard:%d", shardId.ToUint64())
+	PrintInfoMsg("  User:%s", user.ToBase58())
+	return sendShardTx(ctx, nutils.ShardStakeAddress, shard_stake.WITHDRAW_FEE, param)
+}
+
+func stakeUserInfo(ctx *cli.Context) error {
+	SetRpcPort(ctx)
+	params, err := getShardCmdParams(ctx)
+	if err != nil {
+		return err
+	}
+	shardId, err := getTargetShardID(params)
+	if err != nil {
+		return err
+	}
+	user, err := parseAccountAddress(ctx, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	view := params.View
+	if !ctx.IsSet(utils.GetFlagName(utils.ShardViewFlag)) && view == 0 {
+		view, err = getShardCurrentView(shardId)
+		if err != nil {
+			return err
+		}
+	}
+	param := &shard_stake.GetUserStakeInfoParam{ShardId: shardId, View: view, User: user}
+	data, err := utils.PrepareInvokeShardNativeContract(common.RootShardID, nutils.ShardStakeAddress,
+		shard_stake.GET_USER_INFO, []interface{}{param})
+	if err != nil {
+		return fmt.Errorf("get user info error:%s", err)
+	}
+	PrintInfoMsg("Shard:%d View:%d User:%s", shardId.ToUint64(), view, user.ToBase58())
+	PrintJsonData(data)
+	return nil
+}
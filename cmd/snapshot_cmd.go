@@ -0,0 +1,202 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/store/ledgerstore"
+	"github.com/ontio/ontology/core/types"
+	"github.com/urfave/cli"
+)
+
+var SnapshotCommand = cli.Command{
+	Name:        "snapshot",
+	Action:      cli.ShowSubcommandHelp,
+	Usage:       "Export or import ledger state snapshot",
+	ArgsUsage:   " ",
+	Description: "Snapshot commands dump the ledger state of a stopped node to a file, and bootstrap a new node from the file without replaying blocks.",
+	Subcommands: []cli.Command{
+		{
+			Action:    exportSnapshot,
+			Name:      "export",
+			Usage:     "Export ledger state at current block height to a snapshot file",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.SnapshotHeightFlag,
+				utils.DataDirFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+				utils.ShardIDFlag,
+			},
+			Description: "Note that the node should be stopped, and only state of current block height can be exported",
+		},
+		{
+			Action:    importSnapshot,
+			Name:      "import",
+			Usage:     "Import ledger state from a snapshot file, the node starts from the snapshot height",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.SnapshotNextHeaderFlag,
+				utils.DataDirFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+				utils.ShardIDFlag,
+			},
+			Description: "Note that blocks, transactions and events lower than snapshot height are not imported, and the header of snapshot height + 1 is needed to verify the snapshot",
+		},
+	},
+}
+
+func getSnapshotLedgerDir(ctx *cli.Context) (string, uint32, error) {
+	cfg, err := SetOntologyConfig(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("SetOntologyConfig error:%s", err)
+	}
+	shardID, err := common.NewShardID(ctx.Uint64(utils.GetFlagName(utils.ShardIDFlag)))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid shard id:%s", err)
+	}
+	stateHashHeight := uint32(0)
+	if shardID.IsRootShard() {
+		stateHashHeight = config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	return path.Join(dbDir, fmt.Sprintf("shard_%d", shardID.ToUint64())), stateHashHeight, nil
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	ledgerDir, stateHashHeight, err := getSnapshotLedgerDir(ctx)
+	if err != nil {
+		return err
+	}
+	height := uint32(ctx.Uint(utils.GetFlagName(utils.SnapshotHeightFlag)))
+	if height == 0 {
+		blockStore, err := ledgerstore.NewBlockStore(path.Join(ledgerDir, ledgerstore.DBDirBlock), false)
+		if err != nil {
+			return fmt.Errorf("NewBlockStore error:%s", err)
+		}
+		_, height, err = blockStore.GetCurrentBlock()
+		blockStore.Close()
+		if err != nil {
+			return fmt.Errorf("GetCurrentBlock error:%s", err)
+		}
+	}
+
+	ofile, err := os.OpenFile(snapshotFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("OpenFile error:%s", err)
+	}
+	defer ofile.Close()
+	fWriter := bufio.NewWriter(ofile)
+
+	PrintInfoMsg("Start export snapshot of height:%d.", height)
+	info, err := ledgerstore.ExportSnapshot(ledgerDir, height, stateHashHeight, fWriter)
+	if err != nil {
+		return fmt.Errorf("export snapshot error:%s", err)
+	}
+	if err := fWriter.Flush(); err != nil {
+		return fmt.Errorf("export snapshot flush file error:%s", err)
+	}
+	PrintInfoMsg("Export snapshot complete, height:%d, block hash:%s, state root:%s, storage root:%s, state count:%d.",
+		info.Height, info.BlockHash.ToHexString(), info.StateRoot.ToHexString(), info.StorageRoot.ToHexString(),
+		info.StateCount)
+	PrintInfoMsg("Header of height:%d is needed to import the snapshot.", info.Height+1)
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	headerData := ctx.String(utils.GetFlagName(utils.SnapshotNextHeaderFlag))
+	if headerData == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotNextHeaderFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	raw, err := common.HexToBytes(headerData)
+	if err != nil {
+		return fmt.Errorf("invalid next header:%s", err)
+	}
+	nextHeader, err := types.HeaderFromRawBytes(raw)
+	if err != nil {
+		return fmt.Errorf("invalid next header:%s", err)
+	}
+	ledgerDir, _, err := getSnapshotLedgerDir(ctx)
+	if err != nil {
+		return err
+	}
+	// snapshot is verified from local genesis block
+	shardID, err := common.NewShardID(ctx.Uint64(utils.GetFlagName(utils.ShardIDFlag)))
+	if err != nil {
+		return fmt.Errorf("invalid shard id:%s", err)
+	}
+	if !shardID.IsRootShard() {
+		return fmt.Errorf("genesis block of shard %d is not available, only snapshot of root shard can be imported",
+			shardID.ToUint64())
+	}
+	bookkeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis, config.DefConfig.Shard)
+	if err != nil {
+		return fmt.Errorf("BuildGenesisBlock error:%s", err)
+	}
+
+	ifile, err := os.OpenFile(snapshotFile, os.O_RDONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("OpenFile error:%s", err)
+	}
+	defer ifile.Close()
+
+	PrintInfoMsg("Start import snapshot.")
+	info, err := ledgerstore.ImportSnapshot(ledgerDir, ifile, genesisBlock.Hash(), nextHeader)
+	if err != nil {
+		return fmt.Errorf("import snapshot error:%s", err)
+	}
+	PrintInfoMsg("Import snapshot complete, height:%d, block hash:%s, state root:%s, storage root:%s, state count:%d.",
+		info.Height, info.BlockHash.ToHexString(), info.StateRoot.ToHexString(), info.StorageRoot.ToHexString(),
+		info.StateCount)
+	return nil
+}
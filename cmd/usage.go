@@ -102,6 +102,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.LogLevelFlag,
 			utils.DisableEventLogFlag,
 			utils.DataDirFlag,
+			utils.PruneRetentionFlag,
+			utils.EnableArchiveFlag,
+			utils.EnableStorageTrieFlag,
+			utils.DBBackendFlag,
 		},
 	},
 	{
@@ -132,6 +136,10 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.EnableConsensusFlag,
 			utils.MaxTxInBlockFlag,
+			utils.EmergencyRotationTimeoutFlag,
+			utils.MaxTxPerPayerFlag,
+			utils.ReservedSysTxInBlockFlag,
+			utils.MaxBlockGasFlag,
 		},
 	},
 	{
@@ -152,6 +160,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NetworkIdFlag,
 			utils.NodePortFlag,
 			utils.HttpInfoPortFlag,
+			utils.HttpMetricsPortFlag,
 			utils.MaxConnInBoundFlag,
 			utils.MaxConnOutBoundFlag,
 			utils.MaxConnInBoundForSingleIPFlag,
@@ -252,6 +261,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ImportEndHeightFlag,
 		},
 	},
+	{
+		Name: "SNAPSHOT",
+		Flags: []cli.Flag{
+			utils.SnapshotFileFlag,
+			utils.SnapshotHeightFlag,
+			utils.SnapshotNextHeaderFlag,
+		},
+	},
 	{
 		Name: "MISC",
 	},
@@ -263,6 +280,34 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ShardParentHeightFlag,
 		},
 	},
+	{
+		Name: "LIGHT NODE",
+		Flags: []cli.Flag{
+			utils.LightFlag,
+			utils.LightRpcAddrFlag,
+		},
+	},
+	{
+		Name: "SHARD MANAGEMENT",
+		Flags: []cli.Flag{
+			utils.ShardParamsFileFlag,
+			utils.ShardTargetIDFlag,
+			utils.ShardPeerOwnerFlag,
+			utils.ShardPeerPubKeyFlag,
+			utils.ShardPeerIpAddressFlag,
+			utils.ShardStakeAmountFlag,
+			utils.ShardPeerStakesFlag,
+			utils.ShardNetworkMinFlag,
+			utils.ShardStakeAssetFlag,
+			utils.ShardGasAssetFlag,
+			utils.ShardGasPriceFlag,
+			utils.ShardGasLimitFlag,
+			utils.ShardVbftConfigFlag,
+			utils.ShardConsensusTypeFlag,
+			utils.ShardConsensusConfigFlag,
+			utils.ShardViewFlag,
+		},
+	},
 }
 
 func init() {
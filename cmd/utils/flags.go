@@ -19,6 +19,7 @@
 package utils
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/ontio/ontology/common/config"
@@ -28,6 +29,7 @@ import (
 
 const (
 	DEFAULT_EXPORT_FILE   = "./OntBlocks.dat"
+	DEFAULT_SNAPSHOT_FILE = "./OntSnapshot.dat"
 	DEFAULT_ABI_PATH      = "./abi"
 	DEFAULT_EXPORT_HEIGHT = 0
 	DEFAULT_WALLET_PATH   = "./wallet_data"
@@ -68,6 +70,24 @@ var (
 		Usage: "Block data storage `<path>`",
 		Value: config.DEFAULT_DATA_DIR,
 	}
+	PruneRetentionFlag = cli.UintFlag{
+		Name:  "prune-retention",
+		Usage: "Prune block bodies, events and consumed cross-shard msgs older than latest `<number>` blocks. 0 to disable",
+		Value: uint(config.DEFAULT_PRUNE_RETENTION),
+	}
+	EnableArchiveFlag = cli.BoolFlag{
+		Name:  "enable-archive",
+		Usage: "Archive history states to support storage, balance and pre-execution queries at block height",
+	}
+	EnableStorageTrieFlag = cli.BoolFlag{
+		Name:  "enable-storage-trie",
+		Usage: "Maintain storage trie to support merkle proofs of storage values before it is required by consensus",
+	}
+	DBBackendFlag = cli.StringFlag{
+		Name:  "db-backend",
+		Usage: "Storage `<backend>` of ledger data. leveldb, badger or memory(only for test)",
+		Value: config.DEFAULT_DB_BACKEND,
+	}
 
 	//Consensus setting
 	EnableConsensusFlag = cli.BoolFlag{
@@ -79,6 +99,26 @@ var (
 		Usage: "Max transaction `<number>` in block",
 		Value: config.DEFAULT_MAX_TX_IN_BLOCK,
 	}
+	EmergencyRotationTimeoutFlag = cli.UintFlag{
+		Name:  "emergency-rotation-timeout",
+		Usage: "Vote to rotate crashed shard consensus peer out after `<seconds>` without heartbeat. 0 to disable",
+		Value: config.DEFAULT_EMERGENCY_ROTATION_TIMEOUT,
+	}
+	MaxTxPerPayerFlag = cli.UintFlag{
+		Name:  "max-tx-per-payer",
+		Usage: "Max transaction `<number>` of one payer in proposed block. 0 for no limit",
+		Value: config.DEFAULT_MAX_TX_PER_PAYER,
+	}
+	ReservedSysTxInBlockFlag = cli.UintFlag{
+		Name:  "reserved-systx-in-block",
+		Usage: "Reserved `<number>` of transactions for cross-shard transactions in proposed block",
+		Value: config.DEFAULT_RESERVED_SYS_TX_IN_BLOCK,
+	}
+	MaxBlockGasFlag = cli.Uint64Flag{
+		Name:  "max-block-gas",
+		Usage: "Max sum of gas limit `<value>` of user transactions in proposed block. 0 for no limit",
+		Value: config.DEFAULT_MAX_BLOCK_GAS,
+	}
 	GasLimitFlag = cli.Uint64Flag{
 		Name:  "gaslimit",
 		Usage: "Min gas limit `<value>` of transaction to be accepted by tx pool.",
@@ -126,6 +166,11 @@ var (
 		Usage: "The listening port of http server for viewing node information `<number>`",
 		Value: config.DEFAULT_HTTP_INFO_PORT,
 	}
+	HttpMetricsPortFlag = cli.UintFlag{
+		Name:  "metrics-port",
+		Usage: "The listening port of http server for exporting node metrics in prometheus format `<number>`",
+		Value: config.DEFAULT_HTTP_METRICS_PORT,
+	}
 	MaxConnInBoundFlag = cli.UintFlag{
 		Name:  "max-conn-in-bound",
 		Usage: "Max connection `<number>` in bound",
@@ -464,6 +509,21 @@ var (
 		Value: "m",
 	}
 
+	//Snapshot setting
+	SnapshotFileFlag = cli.StringFlag{
+		Name:  "snapshot-file",
+		Usage: "Path of snapshot `<file>`",
+		Value: DEFAULT_SNAPSHOT_FILE,
+	}
+	SnapshotHeightFlag = cli.UintFlag{
+		Name:  "height",
+		Usage: "Block `<height>` of snapshot, must be the current block height. 0 for current block height",
+	}
+	SnapshotNextHeaderFlag = cli.StringFlag{
+		Name:  "next-header",
+		Usage: "Block `<header>` in hex of snapshot height + 1, returned by getheader rpc of any node, to verify snapshot with",
+	}
+
 	//PreExecute switcher
 	TxpoolPreExecDisableFlag = cli.BoolFlag{
 		Name:  "disable-tx-pool-pre-exec",
@@ -492,6 +552,74 @@ var (
 		Usage: "Shard ID",
 		Value: config.DEFAULT_SHARD_ID,
 	}
+	//Shard management setting
+	ShardParamsFileFlag = cli.StringFlag{
+		Name:  "params-file",
+		Usage: "Json `<file>` of shard management parameters, flags take precedence over it",
+	}
+	ShardTargetIDFlag = cli.Uint64Flag{
+		Name:  "target-shard",
+		Usage: "Shard `<id>` to be managed",
+	}
+	ShardPeerOwnerFlag = cli.StringFlag{
+		Name:  "peer-owner",
+		Usage: "Owner `<address>` of shard peer",
+	}
+	ShardPeerPubKeyFlag = cli.StringFlag{
+		Name:  "peer-pubkey",
+		Usage: "Public `<key>` of shard peer, multiple keys are separated by ','",
+	}
+	ShardPeerIpAddressFlag = cli.StringFlag{
+		Name:  "peer-ip",
+		Usage: "Ip `<address>` of shard peer",
+	}
+	ShardStakeAmountFlag = cli.Uint64Flag{
+		Name:  "stake-amount",
+		Usage: "Stake `<amount>` of shard peer",
+	}
+	ShardPeerStakesFlag = cli.StringFlag{
+		Name:  "peer-stakes",
+		Usage: "Stake amount of each peer, in the format of `<pubkey:amount,pubkey:amount>`",
+	}
+	ShardNetworkMinFlag = cli.UintFlag{
+		Name:  "network-min",
+		Usage: "Min peer `<number>` of shard network",
+	}
+	ShardStakeAssetFlag = cli.StringFlag{
+		Name:  "stake-asset",
+		Usage: "Stake `<asset>` of shard, ont or ong",
+		Value: ASSET_ONT,
+	}
+	ShardGasAssetFlag = cli.StringFlag{
+		Name:  "gas-asset",
+		Usage: "Gas `<asset>` of shard, ont or ong",
+		Value: ASSET_ONG,
+	}
+	ShardGasPriceFlag = cli.Uint64Flag{
+		Name:  "shard-gasprice",
+		Usage: "Min gas price `<value>` of shard",
+	}
+	ShardGasLimitFlag = cli.Uint64Flag{
+		Name:  "shard-gaslimit",
+		Usage: "Min gas limit `<value>` of shard",
+	}
+	ShardVbftConfigFlag = cli.StringFlag{
+		Name:  "vbft-config",
+		Usage: "Json `<file>` of shard vbft config",
+	}
+	ShardConsensusTypeFlag = cli.StringFlag{
+		Name:  "consensus-type",
+		Usage: "Consensus `<type>` of shard, vbft, solo, dbft or sbft",
+		Value: config.CONSENSUS_TYPE_VBFT,
+	}
+	ShardConsensusConfigFlag = cli.StringFlag{
+		Name:  "consensus-config",
+		Usage: "Json `<file>` of shard solo/dbft/sbft config",
+	}
+	ShardViewFlag = cli.Uint64Flag{
+		Name:  "view",
+		Usage: "Shard stake view `<index>`, use current view if not set",
+	}
 	//solo shard setting
 	EnableSoloShardFlag = cli.BoolFlag{
 		Name:  "enable-solo-shard",
@@ -503,6 +631,17 @@ var (
 		Usage: "Parent Height `<number>`",
 		Value: config.DEFAULT_PARENT_HEIGHT_INCREMENT,
 	}
+
+	//light node setting
+	LightFlag = cli.BoolFlag{
+		Name:  "light",
+		Usage: "Run as light node, which only syncs and verifies vbft block headers",
+	}
+	LightRpcAddrFlag = cli.StringFlag{
+		Name:  "light-rpc",
+		Usage: "Json rpc `<address>` of full node followed by light node",
+		Value: fmt.Sprintf("http://localhost:%d", config.DEFAULT_RPC_PORT),
+	}
 )
 
 //GetFlagName deal with short flag, and return the flag name whether flag name have short name
@@ -0,0 +1,209 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	httpcom "github.com/ontio/ontology/http/base/common"
+	cstates "github.com/ontio/ontology/smartcontract/states"
+)
+
+const VERSION_CONTRACT_SHARD = byte(0)
+
+//ShardCmdParams is the parameters of shard management commands, could be loaded from json file
+type ShardCmdParams struct {
+	ShardID     uint64             `json:"shard_id"`
+	PeerOwner   string             `json:"peer_owner"`
+	PeerPubKey  string             `json:"peer_pub_key"`
+	PeerPubKeys []string           `json:"peer_pub_keys"`
+	IpAddress   string             `json:"ip_address"`
+	StakeAmount uint64             `json:"stake_amount"`
+	NetworkMin  uint32             `json:"network_min"`
+	StakeAsset  string             `json:"stake_asset"`
+	GasAsset    string             `json:"gas_asset"`
+	GasPrice    uint64             `json:"gas_price"`
+	GasLimit    uint64             `json:"gas_limit"`
+	VbftConfig  *config.VBFTConfig `json:"vbft_config"`
+	View        uint64             `json:"view"`
+	PeerStakes  map[string]uint64  `json:"peer_stakes"`
+
+	ConsensusType   string                `json:"consensus_type"`
+	ConsensusConfig *ShardConsensusConfig `json:"consensus_config"`
+}
+
+// ShardConsensusConfig is the config of shard running solo, dbft or sbft,
+// all consensus peers of shard are bookkeepers if bookkeepers not set
+type ShardConsensusConfig struct {
+	GenBlockTime       uint     `json:"gen_block_time"`
+	Bookkeepers        []string `json:"bookkeepers"`
+	MaxBlockChangeView uint32   `json:"max_block_change_view"` // dbft only
+}
+
+func (this *ShardCmdParams) isVbftShard() bool {
+	return this.ConsensusType == "" || this.ConsensusType == config.CONSENSUS_TYPE_VBFT
+}
+
+// GetVbftConfigData serialize vbft config, which is required by vbft shard only,
+// shard not running vbft takes it as stake config if it is set
+func (this *ShardCmdParams) GetVbftConfigData() ([]byte, error) {
+	if this.VbftConfig == nil {
+		if this.isVbftShard() {
+			return nil, fmt.Errorf("missing %s argument", ShardVbftConfigFlag.Name)
+		}
+		return nil, nil
+	}
+	buf := new(bytes.Buffer)
+	if err := this.VbftConfig.Serialize(buf); err != nil {
+		return nil, fmt.Errorf("serialize vbft config error:%s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GetConsensusConfigData serialize consensus config for shard not running vbft
+func (this *ShardCmdParams) GetConsensusConfigData() ([]byte, error) {
+	if this.isVbftShard() {
+		return nil, nil
+	}
+	if this.ConsensusConfig == nil {
+		return nil, fmt.Errorf("missing %s argument of %s shard", ShardConsensusConfigFlag.Name, this.ConsensusType)
+	}
+	sink := common.NewZeroCopySink(0)
+	switch this.ConsensusType {
+	case config.CONSENSUS_TYPE_SOLO:
+		cfg := &config.SOLOConfig{GenBlockTime: this.ConsensusConfig.GenBlockTime, Bookkeepers: this.ConsensusConfig.Bookkeepers}
+		cfg.Serialization(sink)
+	case config.CONSENSUS_TYPE_DBFT:
+		cfg := &config.DBFTConfig{
+			GenBlockTime:       this.ConsensusConfig.GenBlockTime,
+			Bookkeepers:        this.ConsensusConfig.Bookkeepers,
+			MaxBlockChangeView: this.ConsensusConfig.MaxBlockChangeView,
+		}
+		cfg.Serialization(sink)
+	case config.CONSENSUS_TYPE_SBFT:
+		cfg := &config.SBFTConfig{GenBlockTime: this.ConsensusConfig.GenBlockTime, Bookkeepers: this.ConsensusConfig.Bookkeepers}
+		cfg.Serialization(sink)
+	default:
+		return nil, fmt.Errorf("unsupported consensus type %s", this.ConsensusType)
+	}
+	return sink.Bytes(), nil
+}
+
+//LoadShardCmdParams load shard command parameters from json file
+func LoadShardCmdParams(file string) (*ShardCmdParams, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read params file:%s error:%s", file, err)
+	}
+	params := &ShardCmdParams{}
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal params file:%s error:%s", file, err)
+	}
+	return params, nil
+}
+
+//ParsePeerStakes parse peer stake amounts in the format of pubkey:amount,pubkey:amount
+func ParsePeerStakes(str string) (map[string]uint64, error) {
+	stakes := make(map[string]uint64)
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pair := strings.Split(item, ":")
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid peer stake:%s", item)
+		}
+		amount, err := strconv.ParseUint(pair[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stake amount:%s error:%s", pair[1], err)
+		}
+		stakes[pair[0]] = amount
+	}
+	return stakes, nil
+}
+
+//InvokeShardNativeContract sign and send native contract invoke transaction to shard
+func InvokeShardNativeContract(
+	shardId common.ShardID,
+	gasPrice,
+	gasLimit uint64,
+	signer *account.Account,
+	contractAddress common.Address,
+	method string,
+	params []interface{},
+) (string, error) {
+	tx, err := httpcom.NewNativeInvokeTransaction(gasPrice, gasLimit, contractAddress, VERSION_CONTRACT_SHARD, method, params)
+	if err != nil {
+		return "", err
+	}
+	tx.ShardID = shardId
+	return InvokeSmartContract(signer, tx)
+}
+
+//PrepareInvokeShardNativeContract pre-execute native contract at shard, return the raw bytes of result
+func PrepareInvokeShardNativeContract(
+	shardId common.ShardID,
+	contractAddress common.Address,
+	method string,
+	params []interface{},
+) ([]byte, error) {
+	mutable, err := httpcom.NewNativeInvokeTransaction(0, 0, contractAddress, VERSION_CONTRACT_SHARD, method, params)
+	if err != nil {
+		return nil, err
+	}
+	mutable.ShardID = shardId
+	tx, err := mutable.IntoImmutable()
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	err = tx.Serialize(&buffer)
+	if err != nil {
+		return nil, fmt.Errorf("tx serialize error:%s", err)
+	}
+	preResult, err := PrepareSendRawTransaction(hex.EncodeToString(buffer.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	return parsePreExecResultBytes(preResult)
+}
+
+func parsePreExecResultBytes(preResult *cstates.PreExecResult) ([]byte, error) {
+	if preResult.State == 0 {
+		return nil, fmt.Errorf("contract invoke failed")
+	}
+	hexStr, ok := preResult.Result.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid result type %T", preResult.Result)
+	}
+	data, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, fmt.Errorf("hex.DecodeString result error:%s", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePeerStakes(t *testing.T) {
+	stakes, err := ParsePeerStakes("02ab:100, 03cd:200")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]uint64{"02ab": 100, "03cd": 200}, stakes)
+
+	_, err = ParsePeerStakes("02ab")
+	assert.NotNil(t, err)
+	_, err = ParsePeerStakes("02ab:abc")
+	assert.NotNil(t, err)
+}
+
+func encodeConfigShardParam(t *testing.T, params *ShardCmdParams) *shardmgmt.ConfigShardParam {
+	vbftCfgData, err := params.GetVbftConfigData()
+	assert.Nil(t, err)
+	consensusCfgData, err := params.GetConsensusConfigData()
+	assert.Nil(t, err)
+	param := &shardmgmt.ConfigShardParam{
+		ShardID:             common.NewShardIDUnchecked(1),
+		NetworkMin:          1,
+		VbftConfigData:      vbftCfgData,
+		ConsensusType:       params.ConsensusType,
+		ConsensusConfigData: consensusCfgData,
+	}
+	buf := new(bytes.Buffer)
+	assert.Nil(t, param.Serialize(buf))
+	newParam := &shardmgmt.ConfigShardParam{}
+	assert.Nil(t, newParam.Deserialize(buf))
+	return newParam
+}
+
+func TestConfigShardParamVbft(t *testing.T) {
+	params := &ShardCmdParams{ConsensusType: config.CONSENSUS_TYPE_VBFT}
+	_, err := params.GetVbftConfigData()
+	assert.NotNil(t, err, "vbft shard should require vbft config")
+
+	params.VbftConfig = &config.VBFTConfig{N: 7, K: 7, MinInitStake: 10000, MaxBlockChangeView: 1000}
+	param := encodeConfigShardParam(t, params)
+	vbftCfg, err := param.GetConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(7), vbftCfg.N)
+	assert.Equal(t, uint32(7), vbftCfg.K)
+	assert.Equal(t, uint32(1000), vbftCfg.MaxBlockChangeView)
+	shardCfg := &shardstates.ShardConfig{}
+	assert.Nil(t, param.GetConsensusConfig(shardCfg))
+	assert.Equal(t, config.CONSENSUS_TYPE_VBFT, shardCfg.GetConsensusType())
+}
+
+func TestConfigShardParamNonVbft(t *testing.T) {
+	bookkeepers := []string{"bookkeeper1", "bookkeeper2"}
+	for _, consensusType := range []string{config.CONSENSUS_TYPE_SOLO, config.CONSENSUS_TYPE_DBFT, config.CONSENSUS_TYPE_SBFT} {
+		params := &ShardCmdParams{ConsensusType: consensusType}
+		_, err := params.GetConsensusConfigData()
+		assert.NotNil(t, err, "%s shard should require consensus config", consensusType)
+
+		// vbft config is not required
+		params.ConsensusConfig = &ShardConsensusConfig{GenBlockTime: 6, Bookkeepers: bookkeepers}
+		param := encodeConfigShardParam(t, params)
+		assert.Equal(t, 0, len(param.VbftConfigData))
+		vbftCfg, err := param.GetConfig()
+		assert.Nil(t, err)
+		assert.Nil(t, vbftCfg)
+
+		shardCfg := &shardstates.ShardConfig{}
+		assert.Nil(t, param.GetConsensusConfig(shardCfg))
+		assert.Equal(t, consensusType, shardCfg.GetConsensusType())
+		switch consensusType {
+		case config.CONSENSUS_TYPE_SOLO:
+			assert.Equal(t, &config.SOLOConfig{GenBlockTime: 6, Bookkeepers: bookkeepers}, shardCfg.SoloCfg)
+		case config.CONSENSUS_TYPE_DBFT:
+			assert.Equal(t, &config.DBFTConfig{GenBlockTime: 6, Bookkeepers: bookkeepers}, shardCfg.DbftCfg)
+		case config.CONSENSUS_TYPE_SBFT:
+			assert.Equal(t, &config.SBFTConfig{GenBlockTime: 6, Bookkeepers: bookkeepers}, shardCfg.SbftCfg)
+		}
+	}
+}
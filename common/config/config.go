@@ -44,10 +44,12 @@ const (
 	DBFT_MIN_NODE_NUM        = 4 //min node number of dbft consensus
 	SOLO_MIN_NODE_NUM        = 1 //min node number of solo consensus
 	VBFT_MIN_NODE_NUM        = 4 //min node number of vbft consensus
+	SBFT_MIN_NODE_NUM        = 4 //min node number of sbft consensus
 
 	CONSENSUS_TYPE_DBFT = "dbft"
 	CONSENSUS_TYPE_SOLO = "solo"
 	CONSENSUS_TYPE_VBFT = "vbft"
+	CONSENSUS_TYPE_SBFT = "sbft"
 
 	DEFAULT_LOG_LEVEL                       = log.InfoLog
 	DEFAULT_MAX_LOG_SIZE                    = 100 //MByte
@@ -62,7 +64,12 @@ const (
 	DEFAULT_MAX_CONN_OUT_BOUND              = uint(1024)
 	DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP = uint(16)
 	DEFAULT_HTTP_INFO_PORT                  = uint(0)
+	DEFAULT_HTTP_METRICS_PORT               = uint(0)
 	DEFAULT_MAX_TX_IN_BLOCK                 = 60000
+	DEFAULT_EMERGENCY_ROTATION_TIMEOUT      = uint(0)
+	DEFAULT_MAX_TX_PER_PAYER                = uint(0)
+	DEFAULT_RESERVED_SYS_TX_IN_BLOCK        = uint(0)
+	DEFAULT_MAX_BLOCK_GAS                   = uint64(0)
 	DEFAULT_MAX_SYNC_HEADER                 = 500
 	DEFAULT_ENABLE_CONSENSUS                = true
 	DEFAULT_ENABLE_EVENT_LOG                = true
@@ -73,10 +80,15 @@ const (
 
 	DEFAULT_DATA_DIR      = "./Chain"
 	DEFAULT_RESERVED_FILE = "./peers.rsv"
+	DEFAULT_DB_BACKEND    = "leveldb"
 
 	DEFAULT_SHARD_ID                = 0
 	DEFAULT_PARENT_HEIGHT           = 0
 	DEFAULT_PARENT_HEIGHT_INCREMENT = 5
+	DEFAULT_XSHARD_TX_TIMEOUT       = 100 // block count before a pending cross shard tx is aborted
+
+	DEFAULT_PRUNE_RETENTION = uint32(0)    // block count of bodies kept by ledger, 0 to disable pruning
+	MIN_PRUNE_RETENTION     = uint32(1000) // min block count of bodies kept by ledger when pruning enabled
 )
 
 const (
@@ -118,6 +130,24 @@ func GetStateHashCheckHeight(id uint32) uint32 {
 	return STATE_HASH_CHECK_HEIGHT[id]
 }
 
+// GetStateRootCheckHeight returns the height from which vbft block header must contain PrevStateRoot, 0 if it is
+// not required by the vbft genesis config
+func GetStateRootCheckHeight() uint32 {
+	if DefConfig.Genesis == nil || DefConfig.Genesis.VBFT == nil {
+		return 0
+	}
+	return DefConfig.Genesis.VBFT.StateRootHeight
+}
+
+// GetStorageRootCheckHeight returns the height from which vbft block header must contain PrevStorageRoot, 0 if it is
+// not required by the vbft genesis config
+func GetStorageRootCheckHeight() uint32 {
+	if DefConfig.Genesis == nil || DefConfig.Genesis.VBFT == nil {
+		return 0
+	}
+	return DefConfig.Genesis.VBFT.StorageRootHeight
+}
+
 func GetNetworkName(id uint32) string {
 	name, ok := NETWORK_NAME[id]
 	if ok {
@@ -193,6 +223,7 @@ var PolarisConfig = &GenesisConfig{
 	},
 	DBFT: &DBFTConfig{},
 	SOLO: &SOLOConfig{},
+	SBFT: &SBFTConfig{},
 }
 
 var MainNetConfig = &GenesisConfig{
@@ -256,6 +287,7 @@ var MainNetConfig = &GenesisConfig{
 	},
 	DBFT: &DBFTConfig{},
 	SOLO: &SOLOConfig{},
+	SBFT: &SBFTConfig{},
 }
 
 var DefConfig = NewOntologyConfig()
@@ -266,6 +298,7 @@ type GenesisConfig struct {
 	VBFT          *VBFTConfig
 	DBFT          *DBFTConfig
 	SOLO          *SOLOConfig
+	SBFT          *SBFTConfig
 }
 
 func NewGenesisConfig() *GenesisConfig {
@@ -275,6 +308,7 @@ func NewGenesisConfig() *GenesisConfig {
 		VBFT:          &VBFTConfig{},
 		DBFT:          &DBFTConfig{},
 		SOLO:          &SOLOConfig{},
+		SBFT:          &SBFTConfig{},
 	}
 }
 
@@ -295,6 +329,8 @@ type VBFTConfig struct {
 	VrfValue             string               `json:"vrf_value"`
 	VrfProof             string               `json:"vrf_proof"`
 	Peers                []*VBFTPeerStakeInfo `json:"peers"`
+	StateRootHeight      uint32               `json:"state_root_height"`   // blocks from the height commit state merkle root of previous block, 0 to disable
+	StorageRootHeight    uint32               `json:"storage_root_height"` // blocks from the height commit storage trie root of previous block, 0 to disable
 }
 
 func (this *VBFTConfig) Serialize(w io.Writer) error {
@@ -558,28 +594,119 @@ func (this *VBFTPeerStakeInfo) Deserialization(source *common.ZeroCopySource) er
 }
 
 type DBFTConfig struct {
+	GenBlockTime       uint     `json:"gen_block_time"`
+	Bookkeepers        []string `json:"bookkeepers"`
+	MaxBlockChangeView uint32   `json:"max_block_change_view"` // interval of shard commit dpos, 0 for default
+}
+
+type SOLOConfig struct {
 	GenBlockTime uint     `json:"gen_block_time"`
 	Bookkeepers  []string `json:"bookkeepers"`
 }
 
-type SOLOConfig struct {
+type SBFTConfig struct {
 	GenBlockTime uint     `json:"gen_block_time"`
 	Bookkeepers  []string `json:"bookkeepers"`
 }
 
+func serializeBookkeeperConfig(sink *common.ZeroCopySink, genBlockTime uint, bookkeepers []string) {
+	sink.WriteUint32(uint32(genBlockTime))
+	sink.WriteVarUint(uint64(len(bookkeepers)))
+	for _, bookkeeper := range bookkeepers {
+		sink.WriteString(bookkeeper)
+	}
+}
+
+func deserializeBookkeeperConfig(source *common.ZeroCopySource) (uint, []string, error) {
+	genBlockTime, eof := source.NextUint32()
+	if eof {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	num, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return 0, nil, common.ErrIrregularData
+	}
+	if eof {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	bookkeepers := make([]string, 0)
+	for i := uint64(0); i < num; i++ {
+		bookkeeper, _, irregular, eof := source.NextString()
+		if irregular {
+			return 0, nil, common.ErrIrregularData
+		}
+		if eof {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		bookkeepers = append(bookkeepers, bookkeeper)
+	}
+	return uint(genBlockTime), bookkeepers, nil
+}
+
+func (this *DBFTConfig) Serialization(sink *common.ZeroCopySink) {
+	serializeBookkeeperConfig(sink, this.GenBlockTime, this.Bookkeepers)
+	sink.WriteUint32(this.MaxBlockChangeView)
+}
+
+func (this *DBFTConfig) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.GenBlockTime, this.Bookkeepers, err = deserializeBookkeeperConfig(source)
+	if err != nil {
+		return err
+	}
+	if source.Len() == 0 {
+		// saved before max block change view was configurable
+		return nil
+	}
+	var eof bool
+	this.MaxBlockChangeView, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (this *SOLOConfig) Serialization(sink *common.ZeroCopySink) {
+	serializeBookkeeperConfig(sink, this.GenBlockTime, this.Bookkeepers)
+}
+
+func (this *SOLOConfig) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.GenBlockTime, this.Bookkeepers, err = deserializeBookkeeperConfig(source)
+	return err
+}
+
+func (this *SBFTConfig) Serialization(sink *common.ZeroCopySink) {
+	serializeBookkeeperConfig(sink, this.GenBlockTime, this.Bookkeepers)
+}
+
+func (this *SBFTConfig) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.GenBlockTime, this.Bookkeepers, err = deserializeBookkeeperConfig(source)
+	return err
+}
+
 type CommonConfig struct {
-	LogLevel       uint             `json:"log_level"`
-	NodeType       string           `json:"node_type"`
-	EnableEventLog bool             `json:"enable_event_log"`
-	SystemFee      map[string]int64 `json:"system_fee"`
-	GasLimit       uint64           `json:"gas_limit"`
-	GasPrice       uint64           `json:"gas_price"`
-	DataDir        string           `json:"data_dir"`
+	LogLevel          uint             `json:"log_level"`
+	NodeType          string           `json:"node_type"`
+	EnableEventLog    bool             `json:"enable_event_log"`
+	SystemFee         map[string]int64 `json:"system_fee"`
+	GasLimit          uint64           `json:"gas_limit"`
+	GasPrice          uint64           `json:"gas_price"`
+	DataDir           string           `json:"data_dir"`
+	PruneRetention    uint32           `json:"prune_retention"`     // block count of bodies kept by ledger, 0 to disable
+	EnableArchive     bool             `json:"enable_archive"`      // keep history states for queries at block height
+	EnableStorageTrie bool             `json:"enable_storage_trie"` // maintain storage trie for storage proofs
+	DBBackend         string           `json:"db_backend"`          // persist store implementation of ledger
 }
 
 type ConsensusConfig struct {
-	EnableConsensus bool `json:"enable_consensus"`
-	MaxTxInBlock    uint `json:"max_tx_in_block"`
+	EnableConsensus          bool   `json:"enable_consensus"`
+	MaxTxInBlock             uint   `json:"max_tx_in_block"`
+	EmergencyRotationTimeout uint   `json:"emergency_rotation_timeout"` // in second, 0 to disable
+	MaxTxPerPayer            uint   `json:"max_tx_per_payer"`           // 0 for no limit
+	ReservedSysTxInBlock     uint   `json:"reserved_sys_tx_in_block"`   // room of block for cross-shard txs, not taken by pool txs
+	MaxBlockGas              uint64 `json:"max_block_gas"`              // sum of gas limit of user txs in block, 0 for no limit
 }
 
 type P2PRsvConfig struct {
@@ -599,6 +726,7 @@ type P2PNodeConfig struct {
 	KeyPath                   string        `json:"key_path"`
 	CAPath                    string        `json:"ca_path"`
 	HttpInfoPort              uint          `json:"http_info_port"`
+	HttpMetricsPort           uint          `json:"http_metrics_port"`
 	MaxHdrSyncReqs            uint          `json:"max_hdr_sync_reqs"`
 	MaxConnInBound            uint          `json:"max_conn_in_bound"`
 	MaxConnOutBound           uint          `json:"max_conn_out_bound"`
@@ -652,10 +780,16 @@ func NewOntologyConfig() *OntologyConfig {
 			SystemFee:      make(map[string]int64),
 			GasLimit:       DEFAULT_GAS_LIMIT,
 			DataDir:        DEFAULT_DATA_DIR,
+			PruneRetention: DEFAULT_PRUNE_RETENTION,
+			DBBackend:      DEFAULT_DB_BACKEND,
 		},
 		Consensus: &ConsensusConfig{
-			EnableConsensus: true,
-			MaxTxInBlock:    DEFAULT_MAX_TX_IN_BLOCK,
+			EnableConsensus:          true,
+			MaxTxInBlock:             DEFAULT_MAX_TX_IN_BLOCK,
+			EmergencyRotationTimeout: DEFAULT_EMERGENCY_ROTATION_TIMEOUT,
+			MaxTxPerPayer:            DEFAULT_MAX_TX_PER_PAYER,
+			ReservedSysTxInBlock:     DEFAULT_RESERVED_SYS_TX_IN_BLOCK,
+			MaxBlockGas:              DEFAULT_MAX_BLOCK_GAS,
 		},
 		P2PNode: &P2PNodeConfig{
 			ReservedCfg:               &P2PRsvConfig{},
@@ -669,6 +803,7 @@ func NewOntologyConfig() *OntologyConfig {
 			KeyPath:                   "",
 			CAPath:                    "",
 			HttpInfoPort:              DEFAULT_HTTP_INFO_PORT,
+			HttpMetricsPort:           DEFAULT_HTTP_METRICS_PORT,
 			MaxHdrSyncReqs:            DEFAULT_MAX_SYNC_HEADER,
 			MaxConnInBound:            DEFAULT_MAX_CONN_IN_BOUND,
 			MaxConnOutBound:           DEFAULT_MAX_CONN_OUT_BOUND,
@@ -706,6 +841,8 @@ func (this *OntologyConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
 		bookKeepers = this.Genesis.DBFT.Bookkeepers
 	case CONSENSUS_TYPE_SOLO:
 		bookKeepers = this.Genesis.SOLO.Bookkeepers
+	case CONSENSUS_TYPE_SBFT:
+		bookKeepers = this.Genesis.SBFT.Bookkeepers
 	default:
 		return nil, fmt.Errorf("Does not support %s consensus", this.Genesis.ConsensusType)
 	}
@@ -753,6 +890,8 @@ func (this *OntologyConfig) getDefNetworkIDFromGenesisConfig(genCfg *GenesisConf
 		configData, err = json.Marshal(genCfg.VBFT)
 	case CONSENSUS_TYPE_DBFT:
 		configData, err = json.Marshal(genCfg.DBFT)
+	case CONSENSUS_TYPE_SBFT:
+		configData, err = json.Marshal(genCfg.SBFT)
 	case CONSENSUS_TYPE_SOLO:
 		return NETWORK_ID_SOLO_NET, nil
 	default:
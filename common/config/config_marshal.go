@@ -49,6 +49,8 @@ func (this *GenesisConfig) Serialize(w io.Writer) error {
 		return this.DBFT.Serialize(w)
 	case CONSENSUS_TYPE_SOLO:
 		return this.SOLO.Serialize(w)
+	case CONSENSUS_TYPE_SBFT:
+		return this.SBFT.Serialize(w)
 	}
 	return nil
 }
@@ -91,6 +93,12 @@ func (this *GenesisConfig) Deserialize(r io.Reader) error {
 			return err
 		}
 		this.SOLO = solo
+	case CONSENSUS_TYPE_SBFT:
+		sbft := new(SBFTConfig)
+		if err := sbft.Deserialize(r); err != nil {
+			return err
+		}
+		this.SBFT = sbft
 	}
 
 	this.SeedList = seedlist
@@ -114,6 +122,14 @@ func (this *SOLOConfig) Deserialize(r io.Reader) error {
 	return jsonDeserialize(r, this)
 }
 
+func (this *SBFTConfig) Serialize(w io.Writer) error {
+	return jsonSerialize(w, this)
+}
+
+func (this *SBFTConfig) Deserialize(r io.Reader) error {
+	return jsonDeserialize(r, this)
+}
+
 //
 // Note:
 // only serialize (genesis, common, consensus, p2pnode, shard),
@@ -0,0 +1,339 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package metrics provides a simple metrics registry, exported in prometheus text format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const labelSep = "\xff"
+
+// Metric is a named metric with optional labels
+type Metric interface {
+	Name() string
+	write(w io.Writer) error
+}
+
+// Registry keeps all metrics and collectors of node
+type Registry struct {
+	lock       sync.RWMutex
+	metrics    map[string]Metric
+	collectors []func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics:    make(map[string]Metric),
+		collectors: make([]func(), 0),
+	}
+}
+
+// DefRegistry is the default registry used by node modules
+var DefRegistry = NewRegistry()
+
+func (self *Registry) Register(m Metric) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if _, present := self.metrics[m.Name()]; present {
+		panic(fmt.Errorf("duplicated metric %s", m.Name()))
+	}
+	self.metrics[m.Name()] = m
+}
+
+// AddCollector adds func which updates metrics before metrics exported
+func (self *Registry) AddCollector(collector func()) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.collectors = append(self.collectors, collector)
+}
+
+// WriteTo writes all metrics in prometheus text format
+func (self *Registry) WriteTo(w io.Writer) error {
+	self.lock.RLock()
+	collectors := make([]func(), len(self.collectors))
+	copy(collectors, self.collectors)
+	self.lock.RUnlock()
+
+	for _, collect := range collectors {
+		collect()
+	}
+
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	names := make([]string, 0, len(self.metrics))
+	for name := range self.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := self.metrics[name].write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type metricDesc struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+}
+
+func (self *metricDesc) Name() string {
+	return self.name
+}
+
+func (self *metricDesc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", self.name, self.help, self.name, self.metricType)
+	return err
+}
+
+func (self *metricDesc) labelKey(labelValues []string) string {
+	if len(labelValues) != len(self.labelNames) {
+		panic(fmt.Errorf("metric %s expects %d label values, got %d", self.name, len(self.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, labelSep)
+}
+
+func (self *metricDesc) formatLabels(key string, extra ...string) string {
+	pairs := make([]string, 0)
+	if len(self.labelNames) > 0 {
+		for i, value := range strings.Split(key, labelSep) {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", self.labelNames[i], strconv.Quote(value)))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", extra[i], strconv.Quote(extra[i+1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter is monotonically increasing value, partitioned by labels
+type Counter struct {
+	metricDesc
+	lock   sync.RWMutex
+	values map[string]float64
+}
+
+// NewCounter creates counter and registers it to DefRegistry
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		metricDesc: metricDesc{name: name, help: help, metricType: "counter", labelNames: labelNames},
+		values:     make(map[string]float64),
+	}
+	DefRegistry.Register(c)
+	return c
+}
+
+func (self *Counter) Inc(labelValues ...string) {
+	self.Add(1, labelValues...)
+}
+
+func (self *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Errorf("counter %s cannot decrease", self.name))
+	}
+	key := self.labelKey(labelValues)
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.values[key] += v
+}
+
+func (self *Counter) Get(labelValues ...string) float64 {
+	key := self.labelKey(labelValues)
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.values[key]
+}
+
+func (self *Counter) write(w io.Writer) error {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if err := self.writeHeader(w); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(self.values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", self.name, self.formatLabels(key), formatValue(self.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gauge is value which can go up and down, partitioned by labels
+type Gauge struct {
+	metricDesc
+	lock   sync.RWMutex
+	values map[string]float64
+}
+
+// NewGauge creates gauge and registers it to DefRegistry
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{
+		metricDesc: metricDesc{name: name, help: help, metricType: "gauge", labelNames: labelNames},
+		values:     make(map[string]float64),
+	}
+	DefRegistry.Register(g)
+	return g
+}
+
+func (self *Gauge) Set(v float64, labelValues ...string) {
+	key := self.labelKey(labelValues)
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.values[key] = v
+}
+
+func (self *Gauge) Add(v float64, labelValues ...string) {
+	key := self.labelKey(labelValues)
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.values[key] += v
+}
+
+func (self *Gauge) Get(labelValues ...string) float64 {
+	key := self.labelKey(labelValues)
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.values[key]
+}
+
+func (self *Gauge) write(w io.Writer) error {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if err := self.writeHeader(w); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(self.values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", self.name, self.formatLabels(key), formatValue(self.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefBuckets are default histogram buckets, in seconds
+var DefBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type histogramValue struct {
+	buckets []uint64 // cumulative count of each bucket
+	sum     float64
+	count   uint64
+}
+
+// Histogram counts observations in buckets, partitioned by labels
+type Histogram struct {
+	metricDesc
+	bounds []float64
+	lock   sync.RWMutex
+	values map[string]*histogramValue
+}
+
+// NewHistogram creates histogram and registers it to DefRegistry
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	bounds := make([]float64, len(buckets))
+	copy(bounds, buckets)
+	sort.Float64s(bounds)
+	h := &Histogram{
+		metricDesc: metricDesc{name: name, help: help, metricType: "histogram", labelNames: labelNames},
+		bounds:     bounds,
+		values:     make(map[string]*histogramValue),
+	}
+	DefRegistry.Register(h)
+	return h
+}
+
+func (self *Histogram) Observe(v float64, labelValues ...string) {
+	key := self.labelKey(labelValues)
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	value, present := self.values[key]
+	if !present {
+		value = &histogramValue{buckets: make([]uint64, len(self.bounds))}
+		self.values[key] = value
+	}
+	for i, bound := range self.bounds {
+		if v <= bound {
+			value.buckets[i]++
+		}
+	}
+	value.sum += v
+	value.count++
+}
+
+func (self *Histogram) write(w io.Writer) error {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if err := self.writeHeader(w); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(self.values))
+	for key := range self.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := self.values[key]
+		for i, bound := range self.bounds {
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", self.name,
+				self.formatLabels(key, "le", formatValue(bound)), value.buckets[i]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", self.name,
+			self.formatLabels(key, "le", "+Inf"), value.count); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", self.name, self.formatLabels(key),
+			formatValue(value.sum), self.name, self.formatLabels(key), value.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
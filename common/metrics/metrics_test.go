@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter(t *testing.T) {
+	c := NewCounter("test_counter_total", "test counter", "type")
+	c.Inc("a")
+	c.Add(2, "a")
+	c.Inc("b")
+	assert.Equal(t, float64(3), c.Get("a"))
+	assert.Equal(t, float64(1), c.Get("b"))
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, c.write(buf))
+	assert.Equal(t, "# HELP test_counter_total test counter\n# TYPE test_counter_total counter\n"+
+		"test_counter_total{type=\"a\"} 3\ntest_counter_total{type=\"b\"} 1\n", buf.String())
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge("test_gauge", "test gauge")
+	g.Set(10)
+	g.Add(-3)
+	assert.Equal(t, float64(7), g.Get())
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, g.write(buf))
+	assert.True(t, strings.Contains(buf.String(), "test_gauge 7\n"))
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_histogram_seconds", "test histogram", []float64{1, 5}, "shard")
+	h.Observe(0.5, "1")
+	h.Observe(3, "1")
+	h.Observe(10, "1")
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, h.write(buf))
+	out := buf.String()
+	assert.True(t, strings.Contains(out, "test_histogram_seconds_bucket{shard=\"1\",le=\"1\"} 1\n"))
+	assert.True(t, strings.Contains(out, "test_histogram_seconds_bucket{shard=\"1\",le=\"5\"} 2\n"))
+	assert.True(t, strings.Contains(out, "test_histogram_seconds_bucket{shard=\"1\",le=\"+Inf\"} 3\n"))
+	assert.True(t, strings.Contains(out, "test_histogram_seconds_sum{shard=\"1\"} 13.5\n"))
+	assert.True(t, strings.Contains(out, "test_histogram_seconds_count{shard=\"1\"} 3\n"))
+}
+
+func TestRegistryCollector(t *testing.T) {
+	r := NewRegistry()
+	g := &Gauge{
+		metricDesc: metricDesc{name: "test_collected", help: "collected gauge", metricType: "gauge"},
+		values:     make(map[string]float64),
+	}
+	r.Register(g)
+	r.AddCollector(func() { g.Set(42) })
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, r.WriteTo(buf))
+	assert.True(t, strings.Contains(buf.String(), "test_collected 42\n"))
+	assert.Panics(t, func() { r.Register(g) })
+}
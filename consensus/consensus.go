@@ -24,6 +24,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/consensus/dbft"
+	"github.com/ontio/ontology/consensus/sbft"
 	"github.com/ontio/ontology/consensus/solo"
 	"github.com/ontio/ontology/consensus/vbft"
 	"github.com/ontio/ontology/core/ledger"
@@ -39,6 +40,7 @@ const (
 	CONSENSUS_DBFT = "dbft"
 	CONSENSUS_SOLO = "solo"
 	CONSENSUS_VBFT = "vbft"
+	CONSENSUS_SBFT = "sbft"
 )
 
 func NewConsensusService(consensusType string, shardID common.ShardID, account *account.Account, txpool *actor.PID, ledger *ledger.Ledger, p2p *actor.PID) (ConsensusService, error) {
@@ -54,6 +56,8 @@ func NewConsensusService(consensusType string, shardID common.ShardID, account *
 		consensus, err = solo.NewSoloService(shardID, account, txpool, ledger, p2p)
 	case CONSENSUS_VBFT:
 		consensus, err = vbft.NewVbftServer(shardID, account, txpool, ledger, p2p)
+	case CONSENSUS_SBFT:
+		consensus, err = sbft.NewSbftService(shardID, account, txpool, ledger, p2p)
 	}
 	log.Infof("ConsensusType:%s", consensusType)
 	return consensus, err
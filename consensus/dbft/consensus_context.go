@@ -50,7 +50,15 @@ type ConsensusContext struct {
 	Signatures      [][]byte
 	ExpectedView    []byte
 
+	// sharding
+	ShardID        common.ShardID
+	ParentHeight   uint32
+	ShardTxs       map[common.ShardID][]*types.CrossShardTxInfos
+	CrossMsgHashes []common.Uint256
+	CrossMsgSigs   [][]byte
+
 	header *types.Block
+	ledger *ledger.Ledger
 
 	isBookkeeperChanged bool
 	nmChangedblkHeight  uint32
@@ -80,6 +88,9 @@ func (ctx *ConsensusContext) ChangeView(viewNum byte) {
 	if ctx.State == Initial {
 		ctx.Transactions = nil
 		ctx.Signatures = make([][]byte, len(ctx.Bookkeepers))
+		ctx.ShardTxs = nil
+		ctx.CrossMsgHashes = nil
+		ctx.CrossMsgSigs = make([][]byte, len(ctx.Bookkeepers))
 		ctx.header = nil
 	}
 }
@@ -100,7 +111,7 @@ func (ctx *ConsensusContext) MakeHeader() *types.Block {
 			txHash = append(txHash, t.Hash())
 		}
 		txRoot := common.ComputeMerkleRoot(txHash)
-		blockRoot := ledger.DefLedger.GetBlockRootWithNewTxRoots(ctx.Height, []common.Uint256{txRoot})
+		blockRoot := ctx.ledger.GetBlockRootWithNewTxRoots(ctx.Height, []common.Uint256{txRoot})
 		header := &types.Header{
 			Version:          ContextVersion,
 			PrevBlockHash:    ctx.PrevHash,
@@ -110,6 +121,8 @@ func (ctx *ConsensusContext) MakeHeader() *types.Block {
 			Height:           ctx.Height,
 			ConsensusData:    ctx.Nonce,
 			NextBookkeeper:   ctx.NextBookkeeper,
+			ShardID:          ctx.ShardID,
+			ParentHeight:     ctx.ParentHeight,
 		}
 		ctx.header = &types.Block{
 			Header:       header,
@@ -140,15 +153,20 @@ func (ctx *ConsensusContext) MakePrepareRequest() *msg.ConsensusPayload {
 		NextBookkeeper: ctx.NextBookkeeper,
 		Transactions:   ctx.Transactions,
 		Signature:      ctx.Signatures[ctx.BookkeeperIndex],
+		ParentHeight:   ctx.ParentHeight,
+		ShardTxs:       ctx.ShardTxs,
+		CrossMsgHashes: ctx.CrossMsgHashes,
+		CrossMsgSig:    ctx.CrossMsgSigs[ctx.BookkeeperIndex],
 	}
 	preReq.msgData.Type = PrepareRequestMsg
 	return ctx.MakePayload(preReq)
 }
 
-func (ctx *ConsensusContext) MakePrepareResponse(signature []byte) *msg.ConsensusPayload {
+func (ctx *ConsensusContext) MakePrepareResponse(signature []byte, crossMsgSig []byte) *msg.ConsensusPayload {
 	log.Debug()
 	preRes := &PrepareResponse{
-		Signature: signature,
+		Signature:   signature,
+		CrossMsgSig: crossMsgSig,
 	}
 	preRes.msgData.Type = PrepareResponseMsg
 	return ctx.MakePayload(preRes)
@@ -188,14 +206,14 @@ func (ctx *ConsensusContext) GetStateDetail() string {
 }
 
 func (ctx *ConsensusContext) Reset(bkAccount *account.Account) {
-	preHash := ledger.DefLedger.GetCurrentBlockHash()
-	height := ledger.DefLedger.GetCurrentBlockHeight()
+	preHash := ctx.ledger.GetCurrentBlockHash()
+	height := ctx.ledger.GetCurrentBlockHeight()
 	header := ctx.MakeHeader()
 
 	if height != ctx.Height || header == nil || header.Hash() != preHash || len(ctx.NextBookkeepers) == 0 {
 		log.Info("[ConsensusContext] Calculate Bookkeepers from db")
 		var err error
-		ctx.Bookkeepers, err = vote.GetValidators([]*types.Transaction{})
+		ctx.Bookkeepers, err = ctx.GetValidators([]*types.Transaction{})
 		if err != nil {
 			log.Error("[ConsensusContext] GetNextBookkeeper failed", err)
 		}
@@ -214,6 +232,10 @@ func (ctx *ConsensusContext) Reset(bkAccount *account.Account) {
 	ctx.Transactions = nil
 	ctx.header = nil
 	ctx.Signatures = make([][]byte, bookkeeperLen)
+	ctx.ParentHeight = 0
+	ctx.ShardTxs = nil
+	ctx.CrossMsgHashes = nil
+	ctx.CrossMsgSigs = make([][]byte, bookkeeperLen)
 	ctx.ExpectedView = make([]byte, bookkeeperLen)
 
 	log.Debugf("bookkeepers number: %d", bookkeeperLen)
@@ -227,3 +249,16 @@ func (ctx *ConsensusContext) Reset(bkAccount *account.Account) {
 	}
 
 }
+
+// GetValidators: bookkeepers of root shard are elected by vote, bookkeepers of child shard are
+// configured in shardmgmt and kept unchanged in shard ledger
+func (ctx *ConsensusContext) GetValidators(txs []*types.Transaction) ([]keypair.PublicKey, error) {
+	if ctx.ShardID.IsRootShard() {
+		return vote.GetValidators(txs)
+	}
+	state, err := ctx.ledger.GetBookkeeperState()
+	if err != nil {
+		return nil, fmt.Errorf("GetBookkeeperState: failed, err: %s", err)
+	}
+	return state.CurrBookkeeper, nil
+}
@@ -30,11 +30,11 @@ import (
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	actorTypes "github.com/ontio/ontology/consensus/actor"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
 	"github.com/ontio/ontology/core/genesis"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/signature"
 	"github.com/ontio/ontology/core/types"
-	"github.com/ontio/ontology/core/vote"
 	"github.com/ontio/ontology/events"
 	"github.com/ontio/ontology/events/message"
 	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
@@ -54,9 +54,10 @@ type DbftService struct {
 	poolActor         *actorTypes.TxPoolActor
 	p2p               *actorTypes.P2PActor
 
-	pid     *actor.PID
-	sub     *events.ActorSubscriber
-	shardID common.ShardID
+	pid          *actor.PID
+	sub          *events.ActorSubscriber
+	shardID      common.ShardID
+	parentHeight uint32
 }
 
 func NewDbftService(shardID common.ShardID, bkAccount *account.Account, txpool *actor.PID, lgr *ledger.Ledger, p2p *actor.PID) (*DbftService, error) {
@@ -71,6 +72,12 @@ func NewDbftService(shardID common.ShardID, bkAccount *account.Account, txpool *
 		p2p:           &actorTypes.P2PActor{P2P: p2p},
 	}
 
+	service.context.ShardID = shardID
+	service.context.ledger = lgr
+	if header, err := lgr.GetHeaderByHeight(lgr.GetCurrentBlockHeight()); err == nil && header != nil {
+		service.parentHeight = header.ParentHeight
+	}
+
 	if !service.timer.Stop() {
 		<-service.timer.C
 	}
@@ -121,9 +128,13 @@ func (this *DbftService) Receive(context actor.Context) {
 		log.Info("dbft receive timeout")
 		this.Timeout()
 	case *message.SaveBlockCompleteMsg:
+		if msg.Block.Header.ShardID != this.shardID {
+			return
+		}
 		log.Infof("dbft actor receives block complete event. block height=%d, numtx=%d",
 			msg.Block.Header.Height, len(msg.Block.Transactions))
 		this.incrValidator.AddBlock(msg.Block)
+		this.parentHeight = msg.Block.Header.ParentHeight
 		this.handleBlockPersistCompleted(msg.Block)
 	case *p2pmsg.ConsensusPayload:
 		this.NewConsensusPayload(msg)
@@ -215,6 +226,7 @@ func (ds *DbftService) CheckSignatures() error {
 
 		//fill transactions
 		block.Transactions = ds.context.Transactions
+		block.ShardTxs = ds.context.ShardTxs
 
 		hash := block.Hash()
 		isExist, err := ds.ledger.IsContainBlock(hash)
@@ -232,6 +244,10 @@ func (ds *DbftService) CheckSignatures() error {
 			if err != nil {
 				return fmt.Errorf("CheckSignatures DefLedgerPid.RequestFuture Height:%d error:%s", block.Header.Height, err)
 			}
+			if err := xshard.DelCrossShardTxs(ds.ledger, block.ShardTxs); err != nil {
+				log.Errorf("DelCrossShardTxs Height:%d error:%s", block.Header.Height, err)
+			}
+			ds.broadcastCrossShardMsgs(block.Header.Height)
 
 			ds.context.State |= BlockGenerated
 			payload := ds.context.MakeBlockSignatures(sigs)
@@ -423,6 +439,9 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 	ds.context.Nonce = message.Nonce
 	ds.context.NextBookkeeper = message.NextBookkeeper
 	ds.context.Transactions = message.Transactions
+	ds.context.ParentHeight = message.ParentHeight
+	ds.context.ShardTxs = message.ShardTxs
+	ds.context.CrossMsgHashes = message.CrossMsgHashes
 	ds.context.header = nil
 
 	blockHash := ds.context.MakeHeader().Hash()
@@ -437,7 +456,17 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 	ds.context.Signatures = make([][]byte, len(ds.context.Bookkeepers))
 	ds.context.Signatures[payload.BookkeeperIndex] = message.Signature
 
-	if len(ds.context.Transactions) > 0 {
+	txs, err := ds.verifyShardProposal(payload.BookkeeperIndex, message)
+	if err != nil {
+		log.Warn("PrepareRequestReceived verify shard proposal failed.", err)
+		ds.context = backupContext
+		ds.RequestChangeView()
+		return
+	}
+	ds.context.CrossMsgSigs = make([][]byte, len(ds.context.Bookkeepers))
+	ds.context.CrossMsgSigs[payload.BookkeeperIndex] = message.CrossMsgSig
+
+	if len(txs) > 0 {
 		height := ds.context.Height - 1
 		start, end := ds.incrValidator.BlockRange()
 
@@ -449,7 +478,7 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 			log.Infof("incr validator block height %v != ledger block height %v", int(end)-1, height)
 		}
 
-		if err := ds.poolActor.VerifyBlock(ds.context.Transactions, validHeight); err != nil {
+		if err := ds.poolActor.VerifyBlock(txs, validHeight); err != nil {
 			log.Error("PrepareRequestReceived new transaction verification failed, will not sent Prepare Response", err)
 			ds.context = backupContext
 			ds.RequestChangeView()
@@ -457,7 +486,7 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 			return
 		}
 
-		for _, tx := range ds.context.Transactions {
+		for _, tx := range txs {
 			if err := ds.incrValidator.Verify(tx, validHeight); err != nil {
 				log.Error("PrepareRequestReceived new transaction increment verification failed, will not sent Prepare Response", err)
 				ds.context = backupContext
@@ -467,7 +496,7 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 		}
 	}
 
-	ds.context.NextBookkeepers, err = vote.GetValidators(ds.context.Transactions)
+	ds.context.NextBookkeepers, err = ds.context.GetValidators(ds.context.Transactions)
 	if err != nil {
 		ds.context = backupContext
 		log.Error("[PrepareRequestReceived] GetValidators failed")
@@ -501,8 +530,16 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 		return
 	}
 	ds.context.Signatures[ds.context.BookkeeperIndex] = sig
+	crossMsgSig, err := ds.signCrossMsgRoot()
+	if err != nil {
+		log.Error("[DbftService] signing cross shard msg root failed", err)
+		ds.context = backupContext
+		ds.RequestChangeView()
+		return
+	}
+	ds.context.CrossMsgSigs[ds.context.BookkeeperIndex] = crossMsgSig
 
-	payload = ds.context.MakePrepareResponse(ds.context.Signatures[ds.context.BookkeeperIndex])
+	payload = ds.context.MakePrepareResponse(sig, crossMsgSig)
 	ds.SignAndRelay(payload)
 
 	ds.blockReceivedTime = time.Now()
@@ -531,8 +568,13 @@ func (ds *DbftService) PrepareResponseReceived(payload *p2pmsg.ConsensusPayload,
 	if err != nil {
 		return
 	}
+	if err := ds.verifyCrossMsgSig(payload.BookkeeperIndex, message.CrossMsgSig); err != nil {
+		log.Warnf("PrepareResponseReceived verify cross shard msg sig from %d failed: %s", payload.BookkeeperIndex, err)
+		return
+	}
 
 	ds.context.Signatures[payload.BookkeeperIndex] = message.Signature
+	ds.context.CrossMsgSigs[payload.BookkeeperIndex] = message.CrossMsgSig
 	err = ds.CheckSignatures()
 	if err != nil {
 		log.Error("CheckSignatures failed", err)
@@ -688,9 +730,14 @@ func (ds *DbftService) Timeout() {
 				}
 			}
 
+			transactions, err = ds.makeShardProposal(transactions)
+			if err != nil {
+				log.Error("[Timeout] make shard proposal failed", err)
+				return
+			}
 			ds.context.Transactions = transactions
 
-			ds.context.NextBookkeepers, err = vote.GetValidators(ds.context.Transactions)
+			ds.context.NextBookkeepers, err = ds.context.GetValidators(ds.context.Transactions)
 			if err != nil {
 				log.Error("[Timeout] GetValidators failed", err.Error())
 				return
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dbft
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareRequestSerialization(t *testing.T) {
+	req := &PrepareRequest{
+		Nonce:          123456,
+		Signature:      []byte{1, 2, 3},
+		ParentHeight:   100,
+		CrossMsgHashes: []common.Uint256{{1}, {2}},
+		CrossMsgSig:    []byte{4, 5, 6},
+	}
+	req.msgData.Type = PrepareRequestMsg
+	req.msgData.ViewNumber = 2
+
+	msg, err := DeserializeMessage(common.SerializeToBytes(req))
+	assert.Nil(t, err)
+	r, ok := msg.(*PrepareRequest)
+	assert.True(t, ok)
+	assert.Equal(t, byte(2), r.ViewNumber())
+	assert.Equal(t, req.Nonce, r.Nonce)
+	assert.Equal(t, req.Signature, r.Signature)
+	assert.Equal(t, req.ParentHeight, r.ParentHeight)
+	assert.Equal(t, req.CrossMsgHashes, r.CrossMsgHashes)
+	assert.Equal(t, req.CrossMsgSig, r.CrossMsgSig)
+	assert.Equal(t, 0, len(r.ShardTxs))
+}
+
+func TestPrepareRequestWithoutShardFields(t *testing.T) {
+	req := &PrepareRequest{Nonce: 1, Signature: []byte{1, 2, 3}}
+	req.msgData.Type = PrepareRequestMsg
+
+	sink := common.NewZeroCopySink(0)
+	req.msgData.Serialization(sink)
+	sink.WriteVarUint(req.Nonce)
+	sink.WriteAddress(req.NextBookkeeper)
+	sink.WriteVarUint(0)
+	sink.WriteVarBytes(req.Signature)
+
+	msg, err := DeserializeMessage(sink.Bytes())
+	assert.Nil(t, err)
+	r := msg.(*PrepareRequest)
+	assert.Equal(t, req.Signature, r.Signature)
+	assert.Equal(t, uint32(0), r.ParentHeight)
+	assert.Nil(t, r.CrossMsgHashes)
+	assert.Nil(t, r.CrossMsgSig)
+}
+
+func TestPrepareResponseSerialization(t *testing.T) {
+	res := &PrepareResponse{Signature: []byte{1, 2, 3}, CrossMsgSig: []byte{4, 5}}
+	res.msgData.Type = PrepareResponseMsg
+
+	msg, err := DeserializeMessage(common.SerializeToBytes(res))
+	assert.Nil(t, err)
+	r, ok := msg.(*PrepareResponse)
+	assert.True(t, ok)
+	assert.Equal(t, res.Signature, r.Signature)
+	assert.Equal(t, res.CrossMsgSig, r.CrossMsgSig)
+}
@@ -33,6 +33,12 @@ type PrepareRequest struct {
 	NextBookkeeper common.Address
 	Transactions   []*types.Transaction
 	Signature      []byte
+
+	// sharding
+	ParentHeight   uint32
+	ShardTxs       map[common.ShardID][]*types.CrossShardTxInfos
+	CrossMsgHashes []common.Uint256 // hashes of cross shard msgs generated in previous block
+	CrossMsgSig    []byte           // signature on root of CrossMsgHashes
 }
 
 func (pr *PrepareRequest) Serialization(sink *common.ZeroCopySink) {
@@ -44,6 +50,13 @@ func (pr *PrepareRequest) Serialization(sink *common.ZeroCopySink) {
 		t.Serialization(sink)
 	}
 	sink.WriteVarBytes(pr.Signature)
+	sink.WriteUint32(pr.ParentHeight)
+	types.SerializeShardTxs(sink, pr.ShardTxs)
+	sink.WriteVarUint(uint64(len(pr.CrossMsgHashes)))
+	for _, hash := range pr.CrossMsgHashes {
+		sink.WriteHash(hash)
+	}
+	sink.WriteVarBytes(pr.CrossMsgSig)
 }
 
 func (pr *PrepareRequest) Deserialization(source *common.ZeroCopySource) error {
@@ -86,6 +99,39 @@ func (pr *PrepareRequest) Deserialization(source *common.ZeroCopySource) error {
 		return io.ErrUnexpectedEOF
 	}
 
+	// request from node without sharding support
+	if source.Len() == 0 {
+		return nil
+	}
+	pr.ParentHeight, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if pr.ShardTxs, err = types.DeserializeShardTxs(source); err != nil {
+		return fmt.Errorf("[PrepareRequest] shard txs deserialization failed: %s", err)
+	}
+	length, _, irregular, eof = source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	for i := 0; i < int(length); i++ {
+		hash, eof := source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		pr.CrossMsgHashes = append(pr.CrossMsgHashes, hash)
+	}
+	pr.CrossMsgSig, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+
 	return nil
 }
 
@@ -25,13 +25,15 @@ import (
 )
 
 type PrepareResponse struct {
-	msgData   ConsensusMessageData
-	Signature []byte
+	msgData     ConsensusMessageData
+	Signature   []byte
+	CrossMsgSig []byte // signature on root of cross shard msg hashes in prepare request
 }
 
 func (pres *PrepareResponse) Serialization(sink *common.ZeroCopySink) {
 	pres.msgData.Serialization(sink)
 	sink.WriteVarBytes(pres.Signature)
+	sink.WriteVarBytes(pres.CrossMsgSig)
 }
 
 //read data to reader
@@ -50,6 +52,18 @@ func (pres *PrepareResponse) Deserialization(source *common.ZeroCopySource) erro
 	}
 	pres.Signature = sign
 
+	// response from node without sharding support
+	if source.Len() == 0 {
+		return nil
+	}
+	pres.CrossMsgSig, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+
 	return nil
 }
 
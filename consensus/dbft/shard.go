@@ -0,0 +1,244 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dbft
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	csm "github.com/ontio/ontology/consensus/utils"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/signature"
+	com "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	ninit "github.com/ontio/ontology/smartcontract/service/native/init"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+// DEFAULT_MAX_BLOCK_CHANGE_VIEW: dbft shard notifies parent shard to commit dpos every max block change view blocks
+// after last commit, shard fees are settled with the commit. used if not set in dbft config of shard
+const DEFAULT_MAX_BLOCK_CHANGE_VIEW = 120000
+
+// maxBlockChangeView: interval of shard commit dpos, from dbft config of shard
+func (ds *DbftService) maxBlockChangeView() uint32 {
+	if cfg := config.DefConfig.Genesis.DBFT; cfg != nil && cfg.MaxBlockChangeView != 0 {
+		return cfg.MaxBlockChangeView
+	}
+	return DEFAULT_MAX_BLOCK_CHANGE_VIEW
+}
+
+// nextParentHeight: ParentHeight of new block, increases at most ParentHeightIncrement per block
+func (ds *DbftService) nextParentHeight() uint32 {
+	if ds.shardID.IsRootShard() {
+		return 0
+	}
+	parentHeight := ds.parentHeight
+	if ds.ledger.GetParentHeight() >= parentHeight+uint32(config.DefConfig.Shard.ParentHeightIncrement) {
+		parentHeight = parentHeight + uint32(config.DefConfig.Shard.ParentHeightIncrement)
+	} else {
+		parentHeight = ds.ledger.GetParentHeight()
+	}
+	return parentHeight
+}
+
+// getCrossMsgHashes: hashes and root of cross shard msgs generated in block, which are signed by bookkeepers
+// in consensus of next block
+func (ds *DbftService) getCrossMsgHashes(blkNum uint32) ([]common.Uint256, common.Uint256, error) {
+	msgs, err := xshard.GetShardMsgsInBlock(ds.ledger, blkNum)
+	if err != nil {
+		return nil, common.UINT256_EMPTY, err
+	}
+	if len(msgs) == 0 {
+		return nil, common.UINT256_EMPTY, nil
+	}
+	hashes, msgRoot := csm.BuildCrossShardMsgHash(msgs)
+	return hashes, msgRoot, nil
+}
+
+// needShardCommitDpos: check if block should contain shard gov tx,
+// the tx is proposed at fixed interval after last commit dpos, until parent shard commits
+func (ds *DbftService) needShardCommitDpos(blkNum uint32) bool {
+	if ds.shardID.IsRootShard() {
+		return false
+	}
+	height, err := xshard.GetShardCommitDposHeight(ds.ledger)
+	if err != nil && err != com.ErrNotFound {
+		log.Errorf("getShardCommitDposHeight failed:%s", err)
+		return false
+	}
+	return blkNum > height && (blkNum-height)%ds.maxBlockChangeView() == 0
+}
+
+// createShardGovTransaction: notify parent shard to commit dpos of shard
+func (ds *DbftService) createShardGovTransaction(blkNum uint32) (*types.Transaction, error) {
+	mutable := utils.BuildNativeTransaction(nutils.ShardMgmtContractAddress, shardmgmt.NOTIFY_PARENT_COMMIT_DPOS, []byte{})
+	mutable.Nonce = blkNum
+	return csm.SignSysTransaction(ds.Account, mutable)
+}
+
+func isShardGovTransaction(tx *types.Transaction) bool {
+	invoke, ok := tx.Payload.(*payload.InvokeCode)
+	return ok && bytes.Compare(invoke.Code, ninit.SHARD_COMMIT_DPOS_BYTES) == 0
+}
+
+// makeShardProposal: fill cross shard txs, shard gov tx and cross shard msg hashes of previous block
+// in proposal of primary
+func (ds *DbftService) makeShardProposal(transactions []*types.Transaction) ([]*types.Transaction, error) {
+	ctx := &ds.context
+	ctx.ParentHeight = ds.nextParentHeight()
+	shardTxs, err := xshard.GetCrossShardTxs(ds.ledger, ds.Account, ds.shardID, ds.parentHeight, ctx.ParentHeight,
+		xshard.CrossShardTxBudget(len(transactions)))
+	if err != nil {
+		log.Errorf("GetCrossShardTxs err:%s", err)
+	}
+	ctx.ShardTxs = shardTxs
+
+	if ds.needShardCommitDpos(ctx.Height) {
+		tx, err := ds.createShardGovTransaction(ctx.Height)
+		if err != nil {
+			return nil, fmt.Errorf("construct shard gov transaction: %s", err)
+		}
+		transactions = append([]*types.Transaction{tx}, transactions...)
+	}
+
+	hashes, msgRoot, err := ds.getCrossMsgHashes(ctx.Height - 1)
+	if err != nil {
+		return nil, err
+	}
+	ctx.CrossMsgHashes = hashes
+	if len(hashes) > 0 {
+		sig, err := signature.Sign(ds.Account, msgRoot[:])
+		if err != nil {
+			return nil, fmt.Errorf("sign cross shard msg root failed,msg hash:%s,err:%s", msgRoot.ToHexString(), err)
+		}
+		ctx.CrossMsgSigs[ctx.BookkeeperIndex] = sig
+	}
+	return transactions, nil
+}
+
+// verifyShardProposal: check parent height, cross shard txs, shard gov tx and cross shard msg hashes in proposal,
+// returns the user transactions to be verified with tx pool
+func (ds *DbftService) verifyShardProposal(primary uint16, message *PrepareRequest) ([]*types.Transaction, error) {
+	ctx := &ds.context
+	prevHeader, err := ds.ledger.GetHeaderByHash(ctx.PrevHash)
+	if err != nil {
+		return nil, fmt.Errorf("GetHeaderByHash %s error:%s", ctx.PrevHash.ToHexString(), err)
+	}
+	if !ds.shardID.IsRootShard() {
+		maxParentHeight := prevHeader.ParentHeight + uint32(config.DefConfig.Shard.ParentHeightIncrement)
+		if message.ParentHeight < prevHeader.ParentHeight || message.ParentHeight > maxParentHeight {
+			return nil, fmt.Errorf("invalid parent height: %d vs %d", maxParentHeight, message.ParentHeight)
+		}
+		if message.ParentHeight > ds.ledger.GetParentHeight() {
+			return nil, fmt.Errorf("parent height %d not synced, local %d", message.ParentHeight, ds.ledger.GetParentHeight())
+		}
+	} else if message.ParentHeight != 0 {
+		return nil, fmt.Errorf("invalid parent height %d at root shard", message.ParentHeight)
+	}
+	block := &types.Block{
+		Header:   &types.Header{ParentHeight: message.ParentHeight},
+		ShardTxs: message.ShardTxs,
+	}
+	if err := xshard.VerifyCrossShardTxs(ds.ledger, ds.shardID, block, prevHeader.ParentHeight); err != nil {
+		return nil, err
+	}
+
+	hashes, msgRoot, err := ds.getCrossMsgHashes(ctx.Height - 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(hashes) != len(message.CrossMsgHashes) || common.ComputeMerkleRoot(message.CrossMsgHashes) != msgRoot {
+		return nil, fmt.Errorf("unmatched cross shard msg hashes")
+	}
+	if len(hashes) > 0 {
+		if err := signature.Verify(ctx.Bookkeepers[primary], msgRoot[:], message.CrossMsgSig); err != nil {
+			return nil, fmt.Errorf("verify cross shard msg sig of primary: %s", err)
+		}
+	}
+
+	txs := message.Transactions
+	hasGovTx := len(txs) > 0 && isShardGovTransaction(txs[0])
+	if hasGovTx != ds.needShardCommitDpos(ctx.Height) {
+		return nil, fmt.Errorf("unmatched shard gov tx at height %d", ctx.Height)
+	}
+	if hasGovTx {
+		txs = txs[1:]
+	}
+	for _, tx := range txs {
+		if isShardGovTransaction(tx) {
+			return nil, fmt.Errorf("unexpected shard gov tx")
+		}
+	}
+	return txs, nil
+}
+
+// signCrossMsgRoot: sign the root of cross shard msg hashes in proposal, which is sent with prepare response
+func (ds *DbftService) signCrossMsgRoot() ([]byte, error) {
+	ctx := &ds.context
+	if len(ctx.CrossMsgHashes) == 0 {
+		return nil, nil
+	}
+	msgRoot := common.ComputeMerkleRoot(ctx.CrossMsgHashes)
+	return signature.Sign(ds.Account, msgRoot[:])
+}
+
+// verifyCrossMsgSig: check signature on root of cross shard msg hashes in prepare response
+func (ds *DbftService) verifyCrossMsgSig(index uint16, sig []byte) error {
+	ctx := &ds.context
+	if len(ctx.CrossMsgHashes) == 0 {
+		return nil
+	}
+	msgRoot := common.ComputeMerkleRoot(ctx.CrossMsgHashes)
+	return signature.Verify(ctx.Bookkeepers[index], msgRoot[:], sig)
+}
+
+// broadcastCrossShardMsgs: broadcast cross shard msgs of previous block after block sealed,
+// the msgs are signed by enough bookkeepers in consensus of the block
+func (ds *DbftService) broadcastCrossShardMsgs(blkNum uint32) {
+	ctx := &ds.context
+	if len(ctx.CrossMsgHashes) == 0 {
+		return
+	}
+	sigData := make(map[uint32][]byte)
+	for i, sig := range ctx.CrossMsgSigs {
+		if sig != nil {
+			sigData[uint32(i)] = sig
+		}
+	}
+	if len(sigData) < ctx.M() {
+		log.Infof("dbft: not enough cross shard msg sigs of block %d: %d", blkNum-1, len(sigData))
+		return
+	}
+	msgs, err := xshard.GetShardMsgsInBlock(ds.ledger, blkNum-1)
+	if err != nil {
+		log.Errorf("dbft: get shard msgs of block %d: %s", blkNum-1, err)
+		return
+	}
+	crossShardMsgHash := &types.CrossShardMsgHash{
+		ShardMsgHashs: ctx.CrossMsgHashes,
+		SigData:       sigData,
+	}
+	csm.SendCrossShardMsgs(ds.Account, ds.ledger, ds.p2p, blkNum, msgs, crossShardMsgHash)
+}
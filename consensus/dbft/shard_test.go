@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dbft
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const testShardDataDir = "./test_dbft_shard"
+
+func newShardTestService(t *testing.T, shardID common.ShardID) *DbftService {
+	os.RemoveAll(testShardDataDir)
+	lgr, err := ledger.NewLedger(testShardDataDir, 0)
+	if err != nil {
+		t.Fatalf("NewLedger error %s", err)
+	}
+	accs := []*account.Account{account.NewAccount(""), account.NewAccount(""), account.NewAccount(""), account.NewAccount("")}
+	bookkeepers := make([]keypair.PublicKey, 0, len(accs))
+	for _, acc := range accs {
+		bookkeepers = append(bookkeepers, acc.PublicKey)
+	}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis, config.DefConfig.Shard)
+	if err != nil {
+		t.Fatalf("BuildGenesisBlock error %s", err)
+	}
+	if err := lgr.Init(bookkeepers, block); err != nil {
+		t.Fatalf("Init ledger error %s", err)
+	}
+	ds := &DbftService{
+		Account: accs[0],
+		ledger:  lgr,
+		shardID: shardID,
+	}
+	ds.context.PrevHash = block.Hash()
+	ds.context.Height = 1
+	ds.context.Bookkeepers = bookkeepers
+	return ds
+}
+
+func cleanShardTestService(ds *DbftService) {
+	ds.ledger.Close()
+	os.RemoveAll(testShardDataDir)
+}
+
+func TestVerifyShardProposalParentHeight(t *testing.T) {
+	ds := newShardTestService(t, common.NewShardIDUnchecked(0))
+	_, err := ds.verifyShardProposal(0, &PrepareRequest{ParentHeight: 1})
+	assert.NotNil(t, err)
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{})
+	assert.Nil(t, err)
+	cleanShardTestService(ds)
+
+	ds = newShardTestService(t, common.NewShardIDUnchecked(1))
+	defer cleanShardTestService(ds)
+	maxParentHeight := uint32(config.DefConfig.Shard.ParentHeightIncrement)
+	// over the increment of parent height
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{ParentHeight: maxParentHeight + 1})
+	assert.NotNil(t, err)
+	// parent block not synced by local node
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{ParentHeight: 1})
+	assert.NotNil(t, err)
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{})
+	assert.Nil(t, err)
+}
+
+func TestVerifyShardProposalGovTx(t *testing.T) {
+	ds := newShardTestService(t, common.NewShardIDUnchecked(1))
+	defer cleanShardTestService(ds)
+
+	// commit dpos interval is read from dbft config of shard
+	interval := uint32(100)
+	oldInterval := config.DefConfig.Genesis.DBFT.MaxBlockChangeView
+	config.DefConfig.Genesis.DBFT.MaxBlockChangeView = interval
+	defer func() { config.DefConfig.Genesis.DBFT.MaxBlockChangeView = oldInterval }()
+	assert.False(t, ds.needShardCommitDpos(DEFAULT_MAX_BLOCK_CHANGE_VIEW+1))
+	assert.True(t, ds.needShardCommitDpos(interval))
+
+	govTx, err := ds.createShardGovTransaction(interval)
+	assert.Nil(t, err)
+	userTx, err := utils.BuildNativeTransaction(nutils.OntContractAddress, "transfer", []byte{1}).IntoImmutable()
+	assert.Nil(t, err)
+
+	// gov tx is not expected out of interval
+	ds.context.Height = interval - 1
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{Transactions: []*types.Transaction{govTx}})
+	assert.NotNil(t, err)
+	txs, err := ds.verifyShardProposal(0, &PrepareRequest{Transactions: []*types.Transaction{userTx}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(txs))
+
+	// gov tx is required at interval, as the first tx
+	ds.context.Height = interval
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{Transactions: []*types.Transaction{userTx}})
+	assert.NotNil(t, err)
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{Transactions: []*types.Transaction{userTx, govTx}})
+	assert.NotNil(t, err)
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{Transactions: []*types.Transaction{govTx, govTx}})
+	assert.NotNil(t, err)
+	txs, err = ds.verifyShardProposal(0, &PrepareRequest{Transactions: []*types.Transaction{govTx, userTx}})
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.Transaction{userTx}, txs)
+}
+
+func TestVerifyShardProposalCrossMsg(t *testing.T) {
+	ds := newShardTestService(t, common.NewShardIDUnchecked(0))
+	defer cleanShardTestService(ds)
+
+	// no cross shard msg generated in previous block
+	hashes := []common.Uint256{{1}, {2}}
+	msgRoot := common.ComputeMerkleRoot(hashes)
+	sig, err := signature.Sign(ds.Account, msgRoot[:])
+	assert.Nil(t, err)
+	_, err = ds.verifyShardProposal(0, &PrepareRequest{CrossMsgHashes: hashes, CrossMsgSig: sig})
+	assert.NotNil(t, err)
+
+	// cross shard msg root signature in prepare response
+	ds.context.CrossMsgHashes = hashes
+	assert.Nil(t, ds.verifyCrossMsgSig(0, sig))
+	assert.NotNil(t, ds.verifyCrossMsgSig(1, sig))
+	wrongRoot := common.ComputeMerkleRoot(hashes[:1])
+	wrongSig, err := signature.Sign(ds.Account, wrongRoot[:])
+	assert.Nil(t, err)
+	assert.NotNil(t, ds.verifyCrossMsgSig(0, wrongSig))
+
+	selfSig, err := ds.signCrossMsgRoot()
+	assert.Nil(t, err)
+	assert.Nil(t, ds.verifyCrossMsgSig(0, selfSig))
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/consensus/utils"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+)
+
+// validHeight returns the start height of transaction increment validation
+func (self *SbftService) validHeight() uint32 {
+	height := self.round.Height - 1
+	start, end := self.incrValidator.BlockRange()
+	if height+1 == end {
+		return start
+	}
+	self.incrValidator.Clean()
+	log.Infof("increment validator block height %v != ledger block height %v", int(end)-1, height)
+	return height
+}
+
+func (self *SbftService) makeBlock() (*types.Block, error) {
+	round := self.round
+	nextBookkeeper, err := types.AddressFromBookkeepers(round.Validators)
+	if err != nil {
+		return nil, fmt.Errorf("GetBookkeeperAddress error:%s", err)
+	}
+	prevHeader, err := self.ledger.GetHeaderByHash(round.PrevHash)
+	if err != nil {
+		return nil, fmt.Errorf("GetHeaderByHash %s error:%s", round.PrevHash.ToHexString(), err)
+	}
+
+	validHeight := self.validHeight()
+	txs := self.poolActor.GetTxnPool(true, validHeight)
+	transactions := make([]*types.Transaction, 0, len(txs))
+	for _, txEntry := range txs {
+		if err := self.incrValidator.Verify(txEntry.Tx, validHeight); err == nil {
+			transactions = append(transactions, txEntry.Tx)
+		}
+	}
+	txHash := make([]common.Uint256, 0, len(transactions))
+	for _, t := range transactions {
+		txHash = append(txHash, t.Hash())
+	}
+	txRoot := common.ComputeMerkleRoot(txHash)
+	blockRoot := self.ledger.GetBlockRootWithNewTxRoots(round.Height, []common.Uint256{txRoot})
+
+	// get ParentHeight for new block
+	parentHeight := self.parentHeight
+	if self.ledger.GetParentHeight() >= parentHeight+uint32(config.DefConfig.Shard.ParentHeightIncrement) {
+		parentHeight = parentHeight + uint32(config.DefConfig.Shard.ParentHeightIncrement)
+	} else {
+		parentHeight = self.ledger.GetParentHeight()
+	}
+	// get Cross-Shard Txs from chain-mgr
+	shardTxs, err := xshard.GetCrossShardTxs(self.ledger, self.Account, self.shardID, self.parentHeight, parentHeight,
+		xshard.CrossShardTxBudget(len(transactions)))
+	if err != nil {
+		log.Errorf("GetCrossShardTxs err:%s", err)
+	}
+
+	timestamp := uint32(time.Now().Unix())
+	if timestamp <= prevHeader.Timestamp {
+		timestamp = prevHeader.Timestamp + 1
+	}
+	header := &types.Header{
+		Version:          ContextVersion,
+		ShardID:          self.shardID,
+		ParentHeight:     parentHeight,
+		PrevBlockHash:    round.PrevHash,
+		TransactionsRoot: txRoot,
+		BlockRoot:        blockRoot,
+		Timestamp:        timestamp,
+		Height:           round.Height,
+		ConsensusData:    common.GetNonce(),
+		NextBookkeeper:   nextBookkeeper,
+	}
+	return &types.Block{
+		Header:       header,
+		ShardTxs:     shardTxs,
+		Transactions: transactions,
+	}, nil
+}
+
+// verifyBlock checks the proposal of other validators before preparing it
+func (self *SbftService) verifyBlock(block *types.Block) error {
+	round := self.round
+	header := block.Header
+	if header.ShardID != self.shardID {
+		return fmt.Errorf("unmatched shard %d", header.ShardID.ToUint64())
+	}
+	if header.Height != round.Height || header.PrevBlockHash != round.PrevHash {
+		return fmt.Errorf("unmatched height %d or prev hash %s", header.Height, header.PrevBlockHash.ToHexString())
+	}
+	prevHeader, err := self.ledger.GetHeaderByHash(round.PrevHash)
+	if err != nil {
+		return fmt.Errorf("GetHeaderByHash %s error:%s", round.PrevHash.ToHexString(), err)
+	}
+	if header.Timestamp <= prevHeader.Timestamp || header.Timestamp > uint32(time.Now().Add(MAX_TIMESTAMP_DRIFT).Unix()) {
+		return fmt.Errorf("invalid timestamp %d", header.Timestamp)
+	}
+	nextBookkeeper, err := types.AddressFromBookkeepers(round.Validators)
+	if err != nil {
+		return fmt.Errorf("GetBookkeeperAddress error:%s", err)
+	}
+	if header.NextBookkeeper != nextBookkeeper {
+		return fmt.Errorf("unmatched next bookkeeper %s", header.NextBookkeeper.ToBase58())
+	}
+	maxParentHeight := prevHeader.ParentHeight + uint32(config.DefConfig.Shard.ParentHeightIncrement)
+	if header.ParentHeight < prevHeader.ParentHeight || header.ParentHeight > maxParentHeight {
+		return fmt.Errorf("invalid parent height: %d vs %d", maxParentHeight, header.ParentHeight)
+	}
+	if header.ParentHeight > self.ledger.GetParentHeight() {
+		return fmt.Errorf("parent height %d not synced, local %d", header.ParentHeight, self.ledger.GetParentHeight())
+	}
+	txHash := make([]common.Uint256, 0, len(block.Transactions))
+	for _, t := range block.Transactions {
+		txHash = append(txHash, t.Hash())
+	}
+	txRoot := common.ComputeMerkleRoot(txHash)
+	if header.TransactionsRoot != txRoot {
+		return fmt.Errorf("unmatched transactions root")
+	}
+	if header.BlockRoot != self.ledger.GetBlockRootWithNewTxRoots(round.Height, []common.Uint256{txRoot}) {
+		return fmt.Errorf("unmatched block root")
+	}
+	if err := xshard.VerifyCrossShardTxs(self.ledger, self.shardID, block, prevHeader.ParentHeight); err != nil {
+		return err
+	}
+
+	if len(block.Transactions) > 0 {
+		validHeight := self.validHeight()
+		if err := self.poolActor.VerifyBlock(block.Transactions, validHeight); err != nil {
+			return fmt.Errorf("verify transactions: %s", err)
+		}
+		for _, tx := range block.Transactions {
+			if err := self.incrValidator.Verify(tx, validHeight); err != nil {
+				txHash := tx.Hash()
+				return fmt.Errorf("increment verify tx %s: %s", txHash.ToHexString(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// initCrossMsgs loads the cross shard msgs of the previous block, whose root is signed by
+// validators in their commits of the round
+func (self *SbftService) initCrossMsgs(round *roundState) {
+	msgs, err := xshard.GetShardMsgsInBlock(self.ledger, round.Height-1)
+	if err != nil {
+		log.Errorf("sbft: get shard msgs of block %d: %s", round.Height-1, err)
+		return
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	round.crossMsgHashes, round.crossMsgRoot = utils.BuildCrossShardMsgHash(msgs)
+}
+
+func (self *SbftService) signCrossMsgRoot(round *roundState) ([]byte, error) {
+	if !round.hasCrossMsgs() {
+		return nil, nil
+	}
+	sig, err := signature.Sign(self.Account, round.crossMsgRoot[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign cross shard msg root: %s", err)
+	}
+	return sig, nil
+}
+
+func (self *SbftService) verifyCrossMsgSig(round *roundState, index uint16, sig []byte) error {
+	if !round.hasCrossMsgs() {
+		if len(sig) != 0 {
+			return fmt.Errorf("unexpected cross shard msg sig")
+		}
+		return nil
+	}
+	return signature.Verify(round.Validators[index], round.crossMsgRoot[:], sig)
+}
+
+// broadcastCrossShardMsgs sends out the cross shard msgs of the block before blkNum,
+// the msgs are signed by a quorum of validators in consensus of the block
+func (self *SbftService) broadcastCrossShardMsgs(blkNum uint32) {
+	round := self.round
+	if !round.hasCrossMsgs() {
+		return
+	}
+	if len(round.crossMsgSigs) < round.Quorum() {
+		log.Infof("sbft: not enough cross shard msg sigs of block %d: %d", blkNum-1, len(round.crossMsgSigs))
+		return
+	}
+	shardMsgs, err := xshard.GetShardMsgsInBlock(self.ledger, blkNum-1)
+	if err != nil {
+		log.Errorf("sbft: get shard msgs of block %d: %s", blkNum-1, err)
+		return
+	}
+	sigData := make(map[uint32][]byte)
+	for index, sig := range round.crossMsgSigs {
+		sigData[uint32(index)] = sig
+	}
+	crossShardMsgHash := &types.CrossShardMsgHash{
+		ShardMsgHashs: round.crossMsgHashes,
+		SigData:       sigData,
+	}
+	utils.SendCrossShardMsgs(self.Account, self.ledger, self.p2p, blkNum, shardMsgs, crossShardMsgHash)
+}
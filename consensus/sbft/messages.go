@@ -0,0 +1,355 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+)
+
+type MessageType byte
+
+const (
+	ProposalMsg   MessageType = 0x01
+	PrepareMsg    MessageType = 0x02
+	CommitMsg     MessageType = 0x03
+	ViewChangeMsg MessageType = 0x04
+)
+
+type ConsensusMessage interface {
+	Type() MessageType
+	ViewNumber() uint32
+	Serialization(sink *common.ZeroCopySink)
+	Deserialization(source *common.ZeroCopySource) error
+}
+
+type msgHeader struct {
+	MsgType MessageType
+	View    uint32
+}
+
+func (self *msgHeader) Type() MessageType {
+	return self.MsgType
+}
+
+func (self *msgHeader) ViewNumber() uint32 {
+	return self.View
+}
+
+func (self *msgHeader) serialization(sink *common.ZeroCopySink) {
+	sink.WriteByte(byte(self.MsgType))
+	sink.WriteUint32(self.View)
+}
+
+func (self *msgHeader) deserialization(source *common.ZeroCopySource) error {
+	t, eof := source.NextByte()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	self.MsgType = MessageType(t)
+	self.View, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+type SignatureData struct {
+	Index     uint16
+	Signature []byte
+}
+
+// PreparedCert proves that a quorum of validators has prepared the block in the view
+type PreparedCert struct {
+	View       uint32
+	BlockHash  common.Uint256
+	Signatures []SignatureData
+}
+
+func (self *PreparedCert) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint32(self.View)
+	sink.WriteHash(self.BlockHash)
+	sink.WriteVarUint(uint64(len(self.Signatures)))
+	for _, sig := range self.Signatures {
+		sink.WriteUint16(sig.Index)
+		sink.WriteVarBytes(sig.Signature)
+	}
+}
+
+func (self *PreparedCert) Deserialization(source *common.ZeroCopySource) error {
+	var eof, irregular bool
+	self.View, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	self.BlockHash, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	for i := uint64(0); i < n; i++ {
+		sig := SignatureData{}
+		sig.Index, eof = source.NextUint16()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		sig.Signature, _, irregular, eof = source.NextVarBytes()
+		if irregular {
+			return common.ErrIrregularData
+		}
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		self.Signatures = append(self.Signatures, sig)
+	}
+	return nil
+}
+
+// Verify checks the cert carries at least quorum distinct valid prepare signatures
+func (self *PreparedCert) Verify(height uint32, validators []keypair.PublicKey, quorum int) error {
+	digest := prepareDigest(height, self.View, self.BlockHash)
+	signed := make(map[uint16]bool)
+	for _, sig := range self.Signatures {
+		if int(sig.Index) >= len(validators) || signed[sig.Index] {
+			continue
+		}
+		if err := signature.Verify(validators[sig.Index], digest[:], sig.Signature); err != nil {
+			continue
+		}
+		signed[sig.Index] = true
+	}
+	if len(signed) < quorum {
+		return fmt.Errorf("prepared cert has %d valid signatures, need %d", len(signed), quorum)
+	}
+	return nil
+}
+
+// prepareDigest is the data validators sign in prepare messages. Prepare signatures are
+// bound to the view so they can't be replayed as evidence for another view.
+func prepareDigest(height uint32, view uint32, blockHash common.Uint256) common.Uint256 {
+	sink := common.NewZeroCopySink(0)
+	sink.WriteUint32(height)
+	sink.WriteUint32(view)
+	sink.WriteHash(blockHash)
+	return common.Uint256(sha256.Sum256(sink.Bytes()))
+}
+
+type Proposal struct {
+	msgHeader
+	Block *types.Block
+	// Justify is set when the leader re-proposes a block prepared in an earlier view
+	Justify *PreparedCert
+}
+
+func (self *Proposal) Serialization(sink *common.ZeroCopySink) {
+	self.msgHeader.serialization(sink)
+	self.Block.Serialization(sink)
+	serializeCert(sink, self.Justify)
+}
+
+func (self *Proposal) Deserialization(source *common.ZeroCopySource) error {
+	if err := self.msgHeader.deserialization(source); err != nil {
+		return err
+	}
+	self.Block = &types.Block{}
+	if err := self.Block.Deserialization(source); err != nil {
+		return err
+	}
+	cert, err := deserializeCert(source)
+	if err != nil {
+		return err
+	}
+	self.Justify = cert
+	return nil
+}
+
+type Prepare struct {
+	msgHeader
+	BlockHash common.Uint256
+	Signature []byte
+}
+
+func (self *Prepare) Serialization(sink *common.ZeroCopySink) {
+	self.msgHeader.serialization(sink)
+	sink.WriteHash(self.BlockHash)
+	sink.WriteVarBytes(self.Signature)
+}
+
+func (self *Prepare) Deserialization(source *common.ZeroCopySource) error {
+	if err := self.msgHeader.deserialization(source); err != nil {
+		return err
+	}
+	var eof, irregular bool
+	self.BlockHash, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	self.Signature, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// Commit carries the validator's signature on the block hash, which is put into header SigData,
+// and its signature on the cross shard msg root of the previous block, if the block has any.
+type Commit struct {
+	msgHeader
+	BlockHash   common.Uint256
+	Signature   []byte
+	CrossMsgSig []byte
+}
+
+func (self *Commit) Serialization(sink *common.ZeroCopySink) {
+	self.msgHeader.serialization(sink)
+	sink.WriteHash(self.BlockHash)
+	sink.WriteVarBytes(self.Signature)
+	sink.WriteVarBytes(self.CrossMsgSig)
+}
+
+func (self *Commit) Deserialization(source *common.ZeroCopySource) error {
+	if err := self.msgHeader.deserialization(source); err != nil {
+		return err
+	}
+	var eof, irregular bool
+	self.BlockHash, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	self.Signature, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	self.CrossMsgSig, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+type ViewChange struct {
+	msgHeader
+	NewView uint32
+	// Prepared and Block are the highest prepared block of the sender, if any
+	Prepared *PreparedCert
+	Block    *types.Block
+}
+
+func (self *ViewChange) Serialization(sink *common.ZeroCopySink) {
+	self.msgHeader.serialization(sink)
+	sink.WriteUint32(self.NewView)
+	serializeCert(sink, self.Prepared)
+	if self.Prepared != nil {
+		self.Block.Serialization(sink)
+	}
+}
+
+func (self *ViewChange) Deserialization(source *common.ZeroCopySource) error {
+	if err := self.msgHeader.deserialization(source); err != nil {
+		return err
+	}
+	var eof bool
+	self.NewView, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	cert, err := deserializeCert(source)
+	if err != nil {
+		return err
+	}
+	self.Prepared = cert
+	if cert != nil {
+		self.Block = &types.Block{}
+		if err := self.Block.Deserialization(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serializeCert(sink *common.ZeroCopySink, cert *PreparedCert) {
+	sink.WriteBool(cert != nil)
+	if cert != nil {
+		cert.Serialization(sink)
+	}
+}
+
+func deserializeCert(source *common.ZeroCopySource) (*PreparedCert, error) {
+	present, irregular, eof := source.NextBool()
+	if irregular {
+		return nil, common.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if !present {
+		return nil, nil
+	}
+	cert := &PreparedCert{}
+	if err := cert.Deserialization(source); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+func DeserializeMessage(data []byte) (ConsensusMessage, error) {
+	if len(data) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	var msg ConsensusMessage
+	switch MessageType(data[0]) {
+	case ProposalMsg:
+		msg = &Proposal{}
+	case PrepareMsg:
+		msg = &Prepare{}
+	case CommitMsg:
+		msg = &Commit{}
+	case ViewChangeMsg:
+		msg = &ViewChange{}
+	default:
+		return nil, errors.New("the message is invalid")
+	}
+	if err := msg.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBlock(height uint32) *types.Block {
+	header := &types.Header{
+		Version:          ContextVersion,
+		TransactionsRoot: common.ComputeMerkleRoot(nil),
+		Timestamp:        uint32(time.Now().Unix()),
+		Height:           height,
+		ConsensusData:    123456,
+	}
+	return &types.Block{Header: header}
+}
+
+func newTestValidators(n int) ([]*account.Account, []keypair.PublicKey) {
+	accs := make([]*account.Account, 0, n)
+	pks := make([]keypair.PublicKey, 0, n)
+	for i := 0; i < n; i++ {
+		acc := account.NewAccount("")
+		accs = append(accs, acc)
+		pks = append(pks, acc.PublicKey)
+	}
+	return accs, pks
+}
+
+func newTestCert(t *testing.T, accs []*account.Account, height, view uint32, hash common.Uint256) *PreparedCert {
+	cert := &PreparedCert{View: view, BlockHash: hash}
+	digest := prepareDigest(height, view, hash)
+	for i, acc := range accs {
+		sig, err := signature.Sign(acc, digest[:])
+		assert.Nil(t, err)
+		cert.Signatures = append(cert.Signatures, SignatureData{Index: uint16(i), Signature: sig})
+	}
+	return cert
+}
+
+func TestProposalSerialization(t *testing.T) {
+	accs, _ := newTestValidators(3)
+	block := newTestBlock(10)
+	proposal := &Proposal{
+		msgHeader: msgHeader{MsgType: ProposalMsg, View: 2},
+		Block:     block,
+		Justify:   newTestCert(t, accs, 10, 1, block.Hash()),
+	}
+	msg, err := DeserializeMessage(common.SerializeToBytes(proposal))
+	assert.Nil(t, err)
+	p, ok := msg.(*Proposal)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), p.ViewNumber())
+	assert.Equal(t, block.Hash(), p.Block.Hash())
+	assert.Equal(t, proposal.Justify, p.Justify)
+
+	proposal.Justify = nil
+	msg, err = DeserializeMessage(common.SerializeToBytes(proposal))
+	assert.Nil(t, err)
+	assert.Nil(t, msg.(*Proposal).Justify)
+}
+
+func TestViewChangeSerialization(t *testing.T) {
+	vc := &ViewChange{
+		msgHeader: msgHeader{MsgType: ViewChangeMsg, View: 0},
+		NewView:   1,
+	}
+	msg, err := DeserializeMessage(common.SerializeToBytes(vc))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), msg.(*ViewChange).NewView)
+	assert.Nil(t, msg.(*ViewChange).Block)
+
+	accs, _ := newTestValidators(4)
+	block := newTestBlock(5)
+	vc.Prepared = newTestCert(t, accs, 5, 0, block.Hash())
+	vc.Block = block
+	msg, err = DeserializeMessage(common.SerializeToBytes(vc))
+	assert.Nil(t, err)
+	assert.Equal(t, block.Hash(), msg.(*ViewChange).Block.Hash())
+	assert.Equal(t, 4, len(msg.(*ViewChange).Prepared.Signatures))
+
+	_, err = DeserializeMessage([]byte{0xff})
+	assert.NotNil(t, err)
+}
+
+func TestCommitSerialization(t *testing.T) {
+	accs, pks := newTestValidators(4)
+	hash := newTestBlock(3).Hash()
+	root := common.ComputeMerkleRoot([]common.Uint256{hash})
+	sig, err := signature.Sign(accs[1], hash[:])
+	assert.Nil(t, err)
+	crossMsgSig, err := signature.Sign(accs[1], root[:])
+	assert.Nil(t, err)
+	commit := &Commit{
+		msgHeader:   msgHeader{MsgType: CommitMsg, View: 1},
+		BlockHash:   hash,
+		Signature:   sig,
+		CrossMsgSig: crossMsgSig,
+	}
+	msg, err := DeserializeMessage(common.SerializeToBytes(commit))
+	assert.Nil(t, err)
+	c := msg.(*Commit)
+	assert.Equal(t, hash, c.BlockHash)
+	assert.Equal(t, crossMsgSig, c.CrossMsgSig)
+
+	// cross shard msg sigs are checked against the root of the previous block
+	service := &SbftService{}
+	round := newRoundState(3, common.UINT256_EMPTY, pks, accs[0].PublicKey)
+	assert.NotNil(t, service.verifyCrossMsgSig(round, 1, c.CrossMsgSig))
+	assert.Nil(t, service.verifyCrossMsgSig(round, 1, nil))
+	round.crossMsgHashes, round.crossMsgRoot = []common.Uint256{hash}, root
+	assert.Nil(t, service.verifyCrossMsgSig(round, 1, c.CrossMsgSig))
+	assert.NotNil(t, service.verifyCrossMsgSig(round, 2, c.CrossMsgSig))
+	assert.NotNil(t, service.verifyCrossMsgSig(round, 1, nil))
+}
+
+func TestPreparedCertVerify(t *testing.T) {
+	accs, pks := newTestValidators(4)
+	hash := newTestBlock(7).Hash()
+	quorum := quorumSize(len(pks))
+	assert.Equal(t, 3, quorum)
+
+	cert := newTestCert(t, accs[:quorum], 7, 1, hash)
+	assert.Nil(t, cert.Verify(7, pks, quorum))
+	// signatures are bound to height and view
+	assert.NotNil(t, cert.Verify(8, pks, quorum))
+	cert.View = 2
+	assert.NotNil(t, cert.Verify(7, pks, quorum))
+
+	// duplicated signatures are counted once
+	cert = newTestCert(t, accs[:quorum-1], 7, 1, hash)
+	cert.Signatures = append(cert.Signatures, cert.Signatures[0])
+	assert.NotNil(t, cert.Verify(7, pks, quorum))
+}
+
+func TestRoundState(t *testing.T) {
+	accs, pks := newTestValidators(4)
+	round := newRoundState(5, common.Uint256{}, pks, accs[2].PublicKey)
+	assert.Equal(t, 2, round.Index)
+	assert.Equal(t, 1, round.Leader())
+	round.ChangeView(1)
+	assert.Equal(t, 2, round.Leader())
+	assert.True(t, round.IsLeader())
+
+	block := newTestBlock(5)
+	round.lock(block, &PreparedCert{View: 1, BlockHash: block.Hash()})
+	other := newTestBlock(6)
+	// lock from earlier view is ignored
+	round.lock(other, &PreparedCert{View: 0, BlockHash: other.Hash()})
+	assert.Equal(t, block.Hash(), round.lockedCert.BlockHash)
+
+	round = newRoundState(5, common.Uint256{}, pks, account.NewAccount("").PublicKey)
+	assert.Equal(t, -1, round.Index)
+	assert.False(t, round.IsLeader())
+}
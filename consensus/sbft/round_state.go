@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"sort"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+// roundState keeps the consensus state of the block under consensus.
+// Proposals and commits are indexed by block hash and kept for the whole height,
+// since a block prepared in one view may be committed in a later one.
+type roundState struct {
+	Height     uint32
+	PrevHash   common.Uint256
+	View       uint32
+	Validators []keypair.PublicKey
+	Index      int // index of local node in validators, -1 if not a validator
+
+	// state of current view
+	proposal   *types.Block
+	commitSent bool
+	prepares   map[common.Uint256]map[uint16][]byte
+
+	// state of current height
+	blocks      map[common.Uint256]*types.Block
+	commits     map[common.Uint256]map[uint16][]byte
+	locked      *types.Block
+	lockedCert  *PreparedCert
+	committed   bool
+	viewChanges map[uint32]map[uint16]*ViewChange
+	requestView uint32
+
+	// cross shard msgs of the previous block, signed by validators in their commits
+	crossMsgHashes []common.Uint256
+	crossMsgRoot   common.Uint256
+	crossMsgSigs   map[uint16][]byte
+}
+
+func newRoundState(height uint32, prevHash common.Uint256, validators []keypair.PublicKey, owner keypair.PublicKey) *roundState {
+	round := &roundState{
+		Height:       height,
+		PrevHash:     prevHash,
+		Validators:   validators,
+		Index:        -1,
+		prepares:     make(map[common.Uint256]map[uint16][]byte),
+		blocks:       make(map[common.Uint256]*types.Block),
+		commits:      make(map[common.Uint256]map[uint16][]byte),
+		viewChanges:  make(map[uint32]map[uint16]*ViewChange),
+		crossMsgSigs: make(map[uint16][]byte),
+	}
+	for i, pk := range validators {
+		if keypair.ComparePublicKey(owner, pk) {
+			round.Index = i
+			break
+		}
+	}
+	return round
+}
+
+// Quorum is the number of signatures a block needs, the same threshold as the ledger uses
+// to verify non-vbft block headers.
+func (self *roundState) Quorum() int {
+	return quorumSize(len(self.Validators))
+}
+
+func quorumSize(n int) int {
+	return n - (n-1)/3
+}
+
+func (self *roundState) Leader() int {
+	return leaderIndex(self.Height, self.View, len(self.Validators))
+}
+
+func leaderIndex(height, view uint32, n int) int {
+	return int((uint64(height) + uint64(view)) % uint64(n))
+}
+
+func (self *roundState) IsLeader() bool {
+	return self.Index >= 0 && self.Index == self.Leader()
+}
+
+func (self *roundState) ChangeView(view uint32) {
+	self.View = view
+	self.proposal = nil
+	self.commitSent = false
+	self.prepares = make(map[common.Uint256]map[uint16][]byte)
+	for v := range self.viewChanges {
+		if v <= view {
+			delete(self.viewChanges, v)
+		}
+	}
+}
+
+func (self *roundState) addPrepare(hash common.Uint256, index uint16, sig []byte) int {
+	if self.prepares[hash] == nil {
+		self.prepares[hash] = make(map[uint16][]byte)
+	}
+	self.prepares[hash][index] = sig
+	return len(self.prepares[hash])
+}
+
+func (self *roundState) addCommit(hash common.Uint256, index uint16, sig []byte) int {
+	if self.commits[hash] == nil {
+		self.commits[hash] = make(map[uint16][]byte)
+	}
+	self.commits[hash][index] = sig
+	return len(self.commits[hash])
+}
+
+func (self *roundState) hasCrossMsgs() bool {
+	return len(self.crossMsgHashes) != 0
+}
+
+func (self *roundState) addViewChange(index uint16, msg *ViewChange) int {
+	if self.viewChanges[msg.NewView] == nil {
+		self.viewChanges[msg.NewView] = make(map[uint16]*ViewChange)
+	}
+	self.viewChanges[msg.NewView][index] = msg
+	return len(self.viewChanges[msg.NewView])
+}
+
+func (self *roundState) preparedCert(hash common.Uint256) *PreparedCert {
+	return &PreparedCert{
+		View:       self.View,
+		BlockHash:  hash,
+		Signatures: sortedSigs(self.prepares[hash]),
+	}
+}
+
+// lock records the block as prepared, replacing any lock from an earlier view
+func (self *roundState) lock(block *types.Block, cert *PreparedCert) {
+	if self.lockedCert != nil && self.lockedCert.View >= cert.View {
+		return
+	}
+	self.locked = block
+	self.lockedCert = cert
+}
+
+func sortedSigs(sigs map[uint16][]byte) []SignatureData {
+	result := make([]SignatureData, 0, len(sigs))
+	for index, sig := range sigs {
+		result = append(result, SignatureData{Index: index, Signature: sig})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result
+}
@@ -18,13 +18,624 @@
 
 package sbft
 
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology-eventbus/actor"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	actorTypes "github.com/ontio/ontology/consensus/actor"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/events"
+	"github.com/ontio/ontology/events/message"
+	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/validator/increment"
+)
+
+/*
+*Simple BFT consensus for small shards.
+*
+*Validators take turns to be the leader of a view, leader = (height + view) % n.
+*The leader proposes a block, validators prepare it and, once a quorum has prepared,
+*sign the block hash in commit messages. A quorum of commit signatures is put into the
+*block header, so the block verifies against the ledger like any other non-vbft block.
+*If a view times out, validators vote for a view change and carry their prepared block
+*to the new view, where the new leader has to re-propose it.
+ */
+const ContextVersion uint32 = common.CURR_HEADER_VERSION
+
+const (
+	MAX_PENDING_MSGS    = 1024
+	MAX_TIMEOUT_SHIFT   = 6
+	MAX_TIMESTAMP_DRIFT = 10 * time.Minute
+)
+
+// proposeTimeout is fired when the leader should propose the block of the view
+type proposeTimeout struct {
+	Height uint32
+	View   uint32
+}
+
+// viewTimeout is fired when the view failed to commit a block in time
+type viewTimeout struct {
+	Height uint32
+	View   uint32
+}
+
+type pendingMsg struct {
+	index uint16
+	msg   ConsensusMessage
+}
+
 type SbftService struct {
+	Account          *account.Account
+	poolActor        *actorTypes.TxPoolActor
+	p2p              *actorTypes.P2PActor
+	incrValidator    *increment.IncrementValidator
+	genBlockInterval time.Duration
+	pid              *actor.PID
+	sub              *events.ActorSubscriber
+	started          bool
+
+	round         *roundState
+	pending       []*pendingMsg
+	proposeTimer  *time.Timer
+	viewTimer     *time.Timer
+	lastBlockTime time.Time
+
+	// sharding
+	shardID      common.ShardID
+	parentHeight uint32 // ParentHeight of last block
+	ledger       *ledger.Ledger
+}
+
+func NewSbftService(shardID common.ShardID, bkAccount *account.Account, txpool *actor.PID, lgr *ledger.Ledger, p2p *actor.PID) (*SbftService, error) {
+	genBlockTime := uint(config.DEFAULT_GEN_BLOCK_TIME)
+	if sbftCfg := config.DefConfig.Genesis.SBFT; sbftCfg != nil && sbftCfg.GenBlockTime >= config.MIN_GEN_BLOCK_TIME {
+		genBlockTime = sbftCfg.GenBlockTime
+	}
+	service := &SbftService{
+		Account:          bkAccount,
+		poolActor:        &actorTypes.TxPoolActor{Pool: txpool},
+		p2p:              &actorTypes.P2PActor{P2P: p2p},
+		incrValidator:    increment.NewIncrementValidator(20),
+		genBlockInterval: time.Duration(genBlockTime) * time.Second,
+		shardID:          shardID,
+		ledger:           lgr,
+	}
+
+	// load parentHeight from ledger
+	blkhdr, err := lgr.GetHeaderByHeight(lgr.GetCurrentBlockHeight())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current block header: %s", err)
+	}
+	service.parentHeight = blkhdr.ParentHeight
+
+	props := actor.FromProducer(func() actor.Actor {
+		return service
+	})
+	pid, err := actor.SpawnNamed(props, "consensus_sbft")
+	if err != nil {
+		return nil, err
+	}
+	service.pid = pid
+	service.sub = events.NewActorSubscriber(pid)
+	return service, nil
 }
 
-func (ss *SbftService) Halt() error {
+func (self *SbftService) Receive(context actor.Context) {
+	if _, ok := context.Message().(*actorTypes.StartConsensus); !self.started && !ok {
+		return
+	}
+
+	switch msg := context.Message().(type) {
+	case *actor.Restarting:
+		log.Info("sbft actor restarting")
+	case *actor.Stopping:
+		log.Info("sbft actor stopping")
+	case *actor.Stopped:
+		log.Info("sbft actor stopped")
+	case *actor.Started:
+		log.Info("sbft actor started")
+	case *actor.Restart:
+		log.Info("sbft actor restart")
+	case *actorTypes.StartConsensus:
+		self.start()
+	case *actorTypes.StopConsensus:
+		self.halt()
+	case *message.SaveBlockCompleteMsg:
+		if msg.Block.Header.ShardID != self.shardID {
+			return
+		}
+		log.Infof("sbft actor receives block complete event. block height=%d parent=%d txnum=%d shardTxNum=%d",
+			msg.Block.Header.Height, msg.Block.Header.ParentHeight, len(msg.Block.Transactions), len(msg.Block.ShardTxs))
+		self.incrValidator.AddBlock(msg.Block)
+		self.parentHeight = msg.Block.Header.ParentHeight
+		self.lastBlockTime = time.Now()
+		if self.round == nil || msg.Block.Header.Height >= self.round.Height {
+			self.newRound()
+		}
+	case *p2pmsg.ConsensusPayload:
+		self.handlePayload(msg)
+	case *proposeTimeout:
+		self.handleProposeTimeout(msg)
+	case *viewTimeout:
+		self.handleViewTimeout(msg)
+	default:
+		log.Info("sbft actor: Unknown msg ", msg, "type", reflect.TypeOf(msg))
+	}
+}
+
+func (self *SbftService) GetPID() *actor.PID {
+	return self.pid
+}
+
+func (self *SbftService) Start() error {
+	self.pid.Tell(&actorTypes.StartConsensus{})
+	return nil
+}
+
+func (self *SbftService) Halt() error {
+	self.pid.Tell(&actorTypes.StopConsensus{})
 	return nil
 }
 
-func (ds *SbftService) Start() error {
+func (self *SbftService) start() {
+	if self.started {
+		log.Info("consensus have started")
+		return
+	}
+	self.started = true
+	self.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	self.newRound()
+}
+
+func (self *SbftService) halt() {
+	log.Info("SBFT Stop")
+	self.stopTimers()
+	self.sub.Unsubscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	self.incrValidator.Clean()
+	self.round = nil
+	self.pending = nil
+	self.started = false
+}
+
+func (self *SbftService) newRound() {
+	self.stopTimers()
+	self.pending = nil
+
+	bkState, err := self.ledger.GetBookkeeperState()
+	if err != nil {
+		log.Errorf("sbft: failed to get bookkeepers of shard %d: %s", self.shardID.ToUint64(), err)
+		self.round = nil
+		return
+	}
+	if len(bkState.CurrBookkeeper) == 0 {
+		log.Errorf("sbft: no bookkeepers of shard %d", self.shardID.ToUint64())
+		self.round = nil
+		return
+	}
+	height := self.ledger.GetCurrentBlockHeight()
+	self.round = newRoundState(height+1, self.ledger.GetCurrentBlockHash(), bkState.CurrBookkeeper, self.Account.PublicKey)
+	if self.round.Index < 0 {
+		log.Infof("sbft: not a validator of shard %d", self.shardID.ToUint64())
+		return
+	}
+	self.initCrossMsgs(self.round)
+	self.startView()
+}
+
+func (self *SbftService) startView() {
+	self.stopTimers()
+	round := self.round
+	if round.IsLeader() {
+		delay := time.Duration(0)
+		if round.View == 0 {
+			delay = self.genBlockInterval - time.Since(self.lastBlockTime)
+			if delay < 0 {
+				delay = 0
+			}
+		}
+		self.proposeTimer = self.after(delay, &proposeTimeout{Height: round.Height, View: round.View})
+	}
+	self.viewTimer = self.after(self.timeoutOfView(round.View), &viewTimeout{Height: round.Height, View: round.View})
+}
+
+func (self *SbftService) timeoutOfView(view uint32) time.Duration {
+	shift := view
+	if shift > MAX_TIMEOUT_SHIFT {
+		shift = MAX_TIMEOUT_SHIFT
+	}
+	return (self.genBlockInterval * 2) << shift
+}
+
+func (self *SbftService) after(d time.Duration, msg interface{}) *time.Timer {
+	pid := self.pid
+	return time.AfterFunc(d, func() {
+		pid.Tell(msg)
+	})
+}
+
+func (self *SbftService) stopTimers() {
+	if self.proposeTimer != nil {
+		self.proposeTimer.Stop()
+		self.proposeTimer = nil
+	}
+	if self.viewTimer != nil {
+		self.viewTimer.Stop()
+		self.viewTimer = nil
+	}
+}
+
+func (self *SbftService) handleProposeTimeout(evt *proposeTimeout) {
+	round := self.round
+	if round == nil || round.Height != evt.Height || round.View != evt.View {
+		return
+	}
+	if !round.IsLeader() || round.proposal != nil || round.committed {
+		return
+	}
+
+	var block *types.Block
+	var justify *PreparedCert
+	if round.locked != nil {
+		// a block may have been committed by others in earlier view, only re-propose it
+		block, justify = round.locked, round.lockedCert
+	} else {
+		var err error
+		block, err = self.makeBlock()
+		if err != nil {
+			log.Errorf("sbft: make block height:%d error: %s", round.Height, err)
+			return
+		}
+	}
+	log.Infof("sbft: propose block height:%d view:%d txnum:%d", round.Height, round.View, len(block.Transactions))
+	proposal := &Proposal{
+		msgHeader: msgHeader{MsgType: ProposalMsg, View: round.View},
+		Block:     block,
+		Justify:   justify,
+	}
+	self.broadcast(proposal)
+	self.handleProposal(uint16(round.Index), proposal)
+}
+
+func (self *SbftService) handleViewTimeout(evt *viewTimeout) {
+	round := self.round
+	if round == nil || round.Height != evt.Height || round.committed {
+		return
+	}
+	view := round.View
+	if round.requestView > view {
+		view = round.requestView
+	}
+	if evt.View != view {
+		return
+	}
+	log.Infof("sbft: view timeout, height:%d view:%d", round.Height, evt.View)
+	self.requestViewChange(evt.View + 1)
+}
+
+func (self *SbftService) handlePayload(payload *p2pmsg.ConsensusPayload) {
+	round := self.round
+	if round == nil || round.Index < 0 {
+		return
+	}
+	if payload.Version != ContextVersion || payload.ShardID != self.shardID.ToUint64() {
+		return
+	}
+	if payload.Height != round.Height || payload.PrevHash != round.PrevHash {
+		log.Debugf("sbft: unmatched height %d, current %d", payload.Height, round.Height)
+		return
+	}
+	index := int(payload.BookkeeperIndex)
+	if index >= len(round.Validators) || index == round.Index {
+		return
+	}
+	if !keypair.ComparePublicKey(payload.Owner, round.Validators[index]) {
+		log.Warnf("sbft: payload owner is not validator %d", index)
+		return
+	}
+	if err := payload.Verify(); err != nil {
+		log.Warn(err.Error())
+		return
+	}
+	msg, err := DeserializeMessage(payload.Data)
+	if err != nil {
+		log.Errorf("sbft: deserialize message failed: %s", err)
+		return
+	}
+
+	if msg.Type() != ViewChangeMsg && msg.ViewNumber() > round.View {
+		// message from validators already in a later view
+		if len(self.pending) < MAX_PENDING_MSGS {
+			self.pending = append(self.pending, &pendingMsg{index: payload.BookkeeperIndex, msg: msg})
+		}
+		return
+	}
+	self.handleMessage(payload.BookkeeperIndex, msg)
+}
+
+func (self *SbftService) handleMessage(index uint16, msg ConsensusMessage) {
+	switch m := msg.(type) {
+	case *Proposal:
+		self.handleProposal(index, m)
+	case *Prepare:
+		self.handlePrepare(index, m)
+	case *Commit:
+		self.handleCommit(index, m)
+	case *ViewChange:
+		self.handleViewChange(index, m)
+	default:
+		log.Warn("sbft: unknown consensus message type")
+	}
+}
+
+func (self *SbftService) handleProposal(index uint16, msg *Proposal) {
+	round := self.round
+	if msg.View != round.View || int(index) != round.Leader() || round.proposal != nil {
+		return
+	}
+	block := msg.Block
+	hash := block.Hash()
+	log.Infof("sbft: proposal received, height:%d view:%d index:%d txnum:%d", round.Height, msg.View, index, len(block.Transactions))
+
+	if msg.Justify != nil {
+		if msg.Justify.BlockHash != hash {
+			log.Warnf("sbft: proposal justify unmatched block hash")
+			return
+		}
+		if err := msg.Justify.Verify(round.Height, round.Validators, round.Quorum()); err != nil {
+			log.Warnf("sbft: proposal justify: %s", err)
+			return
+		}
+	}
+	if round.locked != nil && round.locked.Hash() != hash {
+		if msg.Justify == nil || msg.Justify.View <= round.lockedCert.View {
+			log.Warnf("sbft: locked on block %s, reject proposal %s", round.lockedCert.BlockHash.ToHexString(), hash.ToHexString())
+			return
+		}
+	}
+	if int(index) != round.Index {
+		if err := self.verifyBlock(block); err != nil {
+			log.Errorf("sbft: verify proposal height:%d from %d: %s", round.Height, index, err)
+			return
+		}
+	}
+	if msg.Justify != nil {
+		round.lock(block, msg.Justify)
+	}
+
+	round.proposal = block
+	round.blocks[hash] = block
+
+	digest := prepareDigest(round.Height, round.View, hash)
+	sig, err := signature.Sign(self.Account, digest[:])
+	if err != nil {
+		log.Errorf("sbft: sign prepare: %s", err)
+		return
+	}
+	self.broadcast(&Prepare{
+		msgHeader: msgHeader{MsgType: PrepareMsg, View: round.View},
+		BlockHash: hash,
+		Signature: sig,
+	})
+	round.addPrepare(hash, uint16(round.Index), sig)
+	self.checkPrepared()
+	// commits may have arrived before the proposal
+	self.checkCommitted(hash)
+}
+
+func (self *SbftService) handlePrepare(index uint16, msg *Prepare) {
+	round := self.round
+	if msg.View != round.View {
+		return
+	}
+	digest := prepareDigest(round.Height, msg.View, msg.BlockHash)
+	if err := signature.Verify(round.Validators[index], digest[:], msg.Signature); err != nil {
+		log.Warnf("sbft: invalid prepare signature from %d", index)
+		return
+	}
+	round.addPrepare(msg.BlockHash, index, msg.Signature)
+	self.checkPrepared()
+}
+
+func (self *SbftService) checkPrepared() {
+	round := self.round
+	if round.proposal == nil || round.commitSent {
+		return
+	}
+	hash := round.proposal.Hash()
+	if len(round.prepares[hash]) < round.Quorum() {
+		return
+	}
+	round.lock(round.proposal, round.preparedCert(hash))
+
+	sig, err := signature.Sign(self.Account, hash[:])
+	if err != nil {
+		log.Errorf("sbft: sign block: %s", err)
+		return
+	}
+	crossMsgSig, err := self.signCrossMsgRoot(round)
+	if err != nil {
+		log.Errorf("sbft: %s", err)
+		return
+	}
+	round.commitSent = true
+	self.broadcast(&Commit{
+		msgHeader:   msgHeader{MsgType: CommitMsg, View: round.View},
+		BlockHash:   hash,
+		Signature:   sig,
+		CrossMsgSig: crossMsgSig,
+	})
+	if crossMsgSig != nil {
+		round.crossMsgSigs[uint16(round.Index)] = crossMsgSig
+	}
+	round.addCommit(hash, uint16(round.Index), sig)
+	self.checkCommitted(hash)
+}
+
+func (self *SbftService) handleCommit(index uint16, msg *Commit) {
+	round := self.round
+	if err := signature.Verify(round.Validators[index], msg.BlockHash[:], msg.Signature); err != nil {
+		log.Warnf("sbft: invalid commit signature from %d", index)
+		return
+	}
+	if err := self.verifyCrossMsgSig(round, index, msg.CrossMsgSig); err != nil {
+		log.Warnf("sbft: invalid cross shard msg signature from %d: %s", index, err)
+		return
+	}
+	if round.hasCrossMsgs() {
+		round.crossMsgSigs[index] = msg.CrossMsgSig
+	}
+	round.addCommit(msg.BlockHash, index, msg.Signature)
+	self.checkCommitted(msg.BlockHash)
+}
+
+func (self *SbftService) checkCommitted(hash common.Uint256) {
+	round := self.round
+	if round.committed {
+		return
+	}
+	block := round.blocks[hash]
+	if block == nil || len(round.commits[hash]) < round.Quorum() {
+		return
+	}
+	round.committed = true
+	self.stopTimers()
+	if err := self.sealBlock(block, round.commits[hash]); err != nil {
+		log.Errorf("sbft: seal block height:%d: %s", round.Height, err)
+		round.committed = false
+		self.startView()
+	}
+}
+
+func (self *SbftService) handleViewChange(index uint16, msg *ViewChange) {
+	round := self.round
+	if msg.NewView <= round.View {
+		return
+	}
+	if msg.Prepared != nil && msg.Block != nil && msg.Block.Hash() == msg.Prepared.BlockHash {
+		if err := msg.Prepared.Verify(round.Height, round.Validators, round.Quorum()); err == nil {
+			round.blocks[msg.Prepared.BlockHash] = msg.Block
+			round.lock(msg.Block, msg.Prepared)
+		} else {
+			log.Warnf("sbft: view change from %d: %s", index, err)
+		}
+	}
+
+	count := round.addViewChange(index, msg)
+	log.Infof("sbft: view change received, height:%d view:%d index:%d newview:%d count:%d",
+		round.Height, round.View, index, msg.NewView, count)
+	if count >= round.Quorum() {
+		self.changeView(msg.NewView)
+		return
+	}
+	// at least one honest validator asks for the view, join it
+	if count > len(round.Validators)-round.Quorum() && msg.NewView > round.requestView {
+		self.requestViewChange(msg.NewView)
+	}
+}
+
+func (self *SbftService) requestViewChange(view uint32) {
+	round := self.round
+	round.requestView = view
+	msg := &ViewChange{
+		msgHeader: msgHeader{MsgType: ViewChangeMsg, View: round.View},
+		NewView:   view,
+		Prepared:  round.lockedCert,
+		Block:     round.locked,
+	}
+	self.broadcast(msg)
+
+	if self.viewTimer != nil {
+		self.viewTimer.Stop()
+	}
+	self.viewTimer = self.after(self.timeoutOfView(view), &viewTimeout{Height: round.Height, View: view})
+
+	if round.addViewChange(uint16(round.Index), msg) >= round.Quorum() {
+		self.changeView(view)
+	}
+}
+
+func (self *SbftService) changeView(view uint32) {
+	round := self.round
+	log.Infof("sbft: change view, height:%d view:%d => %d", round.Height, round.View, view)
+	round.ChangeView(view)
+	self.startView()
+
+	pending := self.pending
+	self.pending = nil
+	for _, p := range pending {
+		if p.msg.ViewNumber() > view {
+			self.pending = append(self.pending, p)
+		} else if p.msg.ViewNumber() == view {
+			self.handleMessage(p.index, p.msg)
+		}
+	}
+}
+
+func (self *SbftService) broadcast(msg ConsensusMessage) {
+	round := self.round
+	payload := &p2pmsg.ConsensusPayload{
+		Version:         ContextVersion,
+		ShardID:         self.shardID.ToUint64(),
+		PrevHash:        round.PrevHash,
+		Height:          round.Height,
+		BookkeeperIndex: uint16(round.Index),
+		Timestamp:       uint32(time.Now().Unix()),
+		Data:            common.SerializeToBytes(msg),
+		Owner:           self.Account.PublicKey,
+	}
+	buf := new(bytes.Buffer)
+	if err := payload.SerializeUnsigned(buf); err != nil {
+		log.Errorf("sbft: serialize payload: %s", err)
+		return
+	}
+	sig, err := signature.Sign(self.Account, buf.Bytes())
+	if err != nil {
+		log.Errorf("sbft: sign payload: %s", err)
+		return
+	}
+	payload.Signature = sig
+	self.p2p.Broadcast(payload)
+}
+
+func (self *SbftService) sealBlock(block *types.Block, commits map[uint16][]byte) error {
+	round := self.round
+	hash := block.Hash()
+	sigs := sortedSigs(commits)
+	block.Header.Bookkeepers = round.Validators
+	block.Header.SigData = make([][]byte, 0, len(sigs))
+	for _, sig := range sigs {
+		block.Header.SigData = append(block.Header.SigData, sig.Signature)
+	}
+
+	isExist, err := self.ledger.IsContainBlock(hash)
+	if err != nil {
+		return fmt.Errorf("IsContainBlock hash:%s error:%s", hash.ToHexString(), err)
+	}
+	if isExist {
+		return nil
+	}
+	result, err := self.ledger.ExecuteBlock(block)
+	if err != nil {
+		return fmt.Errorf("executeBlock height:%d error:%s", block.Header.Height, err)
+	}
+	if err = self.ledger.SubmitBlock(block, result); err != nil {
+		return fmt.Errorf("submitBlock height:%d error:%s", block.Header.Height, err)
+	}
+	log.Infof("sbft: block committed, height:%d hash:%s", block.Header.Height, hash.ToHexString())
+	xshard.DelCrossShardTxs(self.ledger, block.ShardTxs)
+	self.broadcastCrossShardMsgs(block.Header.Height)
+	self.p2p.Broadcast(hash)
 	return nil
 }
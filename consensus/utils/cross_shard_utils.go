@@ -22,15 +22,18 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/account"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
+	actorTypes "github.com/ontio/ontology/consensus/actor"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/signature"
 	common2 "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/core/xshard_types"
+	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
 	shardstates "github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 	state "github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 )
@@ -118,6 +121,52 @@ func BuildCrossShardMsgs(signer *account.Account, lgr *ledger.Ledger, blkNum uin
 	return builtMsgs, hashRoot, nil
 }
 
+// SendCrossShardMsgs: build cross shard msgs of the block before blkNum, which are signed by bookkeepers in
+// consensus of block blkNum, save them to ledger and broadcast them to target shards.
+// Msgs to child shards are not broadcasted, they are delivered with blocks of local shard.
+func SendCrossShardMsgs(signer *account.Account, lgr *ledger.Ledger, p2p *actorTypes.P2PActor, blkNum uint32,
+	shardMsgs []xshard_types.CommonShardMsg, crossShardMsgHash *types.CrossShardMsgHash) {
+	crossShardMsgs, hashRoot, err := BuildCrossShardMsgs(signer, lgr, blkNum, shardMsgs, crossShardMsgHash)
+	if err != nil {
+		log.Errorf("%s", err)
+		return
+	}
+
+	for targetShardID, crossShardMsg := range crossShardMsgs {
+		if targetShardID.ParentID() == lgr.ShardID {
+			continue
+		}
+		// get last shard-msg-root of the target shard
+		prevMsgHash, err := lgr.GetShardMsgHash(targetShardID)
+		if err != nil && err != common2.ErrNotFound {
+			log.Errorf("SendCrossShardMsgToAll getshardmsghash err:%s", err)
+			return
+		}
+		// save shard-msg-root
+		err = lgr.SaveShardMsgHash(targetShardID, hashRoot)
+		if err != nil {
+			log.Errorf("SaveShardMsgHash shardID:%v,msgHash:%s,err:%s", targetShardID, hashRoot.ToHexString(), err)
+			return
+		}
+		// save cross-shard-msg
+		err = lgr.SaveCrossShardMsgByHash(prevMsgHash, crossShardMsg)
+		if err != nil {
+			log.Errorf("SaveCrossShardMsgByHash preMsgHash:%s,err:%s", prevMsgHash.ToHexString(), err)
+			return
+		}
+
+		// broadcast
+		sink := common.ZeroCopySink{}
+		crossShardMsg.Serialization(&sink)
+		msg := &p2pmsg.CrossShardPayload{
+			Version: common.VERSION_SUPPORT_SHARD,
+			ShardID: targetShardID,
+			Data:    sink.Bytes(),
+		}
+		p2p.Broadcast(msg)
+	}
+}
+
 func BuildCrossShardMsgHash(shardMsgs []xshard_types.CommonShardMsg) ([]common.Uint256, common.Uint256) {
 	shardList := make([]common.ShardID, 0)
 	shardMsgMap := make(map[common.ShardID][]xshard_types.CommonShardMsg)
@@ -177,3 +226,24 @@ func GetShardConfigByShardID(lgr *ledger.Ledger, shardID common.ShardID, blkNum
 	}
 	return cfg, err
 }
+
+// SignSysTransaction: sign consensus system tx with node account, the node pays no fee
+func SignSysTransaction(signer *account.Account, mutable *types.MutableTransaction) (*types.Transaction, error) {
+	mutable.GasPrice = 0
+	mutable.GasLimit = 200000
+	mutable.Payer = signer.Address
+	// add signatures
+	txHash := mutable.Hash()
+	sigData, err := signature.Sign(signer, txHash.ToArray())
+	if err != nil {
+		return nil, fmt.Errorf("sign tx: %s", err)
+	}
+	mutable.Sigs = []types.Sig{
+		{
+			PubKeys: []keypair.PublicKey{signer.PubKey()},
+			M:       1,
+			SigData: [][]byte{sigData},
+		},
+	}
+	return mutable.IntoImmutable()
+}
@@ -701,6 +701,18 @@ func (pool *BlockPool) getSealedBlock(blockNum uint32) (*Block, common.Uint256)
 	return blk, blk.Block.Hash()
 }
 
+// getSealedBlockHeader returns sealed block from cached candidate blocks, or block with header only from chainstore
+func (pool *BlockPool) getSealedBlockHeader(blockNum uint32) (*Block, error) {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	c := pool.candidateBlocks[blockNum]
+	if c != nil && c.SealedBlock != nil {
+		return c.SealedBlock, nil
+	}
+	return pool.chainStore.GetBlockHeader(blockNum)
+}
+
 func (pool *BlockPool) findConsensusEmptyProposal(blockNum uint32) (*blockProposalMsg, error) {
 	pool.lock.RLock()
 	defer pool.lock.RUnlock()
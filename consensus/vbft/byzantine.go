@@ -0,0 +1,126 @@
+// Copyright (C) 2019 The ontology Authors
+// This file is part of The ontology library.
+//
+// The ontology is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The ontology is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build byzantine
+
+package vbft
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ontio/ontology/common/log"
+)
+
+// ByzantineBehavior is set of misbehaviors a server can be scripted with.
+// It is only built with byzantine tag for simulation tests, production servers never misbehave.
+type ByzantineBehavior uint32
+
+type byzantineState struct {
+	behavior uint32 // ByzantineBehavior
+}
+
+const BYZANTINE_NONE ByzantineBehavior = 0
+
+const (
+	// proposer sends conflicting proposals of same height to different halves of peers
+	BYZANTINE_DOUBLE_PROPOSAL ByzantineBehavior = 1 << iota
+	// endorser never sends endorsements to peers
+	BYZANTINE_WITHHOLD_ENDORSEMENT
+)
+
+// SetByzantineBehavior: script misbehaviors of server, for simulation tests only
+func (self *Server) SetByzantineBehavior(behavior ByzantineBehavior) {
+	atomic.StoreUint32(&self.byzantine.behavior, uint32(behavior))
+}
+
+func (self *Server) hasByzantineBehavior(behavior ByzantineBehavior) bool {
+	return ByzantineBehavior(atomic.LoadUint32(&self.byzantine.behavior))&behavior != 0
+}
+
+// applyByzantineBehavior: rewrite outgoing msg with scripted misbehaviors
+func (self *Server) applyByzantineBehavior(evt *SendMsgEvent) []*SendMsgEvent {
+	if atomic.LoadUint32(&self.byzantine.behavior) == uint32(BYZANTINE_NONE) {
+		return []*SendMsgEvent{evt}
+	}
+	switch evt.Msg.Type() {
+	case BlockEndorseMessage:
+		if self.hasByzantineBehavior(BYZANTINE_WITHHOLD_ENDORSEMENT) {
+			log.Infof("server %d, byzantine: withhold endorsement for %d", self.Index, evt.Msg.GetBlockNum())
+			return nil
+		}
+	case BlockProposalMessage:
+		if self.hasByzantineBehavior(BYZANTINE_DOUBLE_PROPOSAL) && evt.ToPeer == math.MaxUint32 {
+			evts, err := self.buildDoubleProposal(evt.Msg.(*blockProposalMsg))
+			if err != nil {
+				log.Errorf("server %d, byzantine: build double proposal: %s", self.Index, err)
+				break
+			}
+			return evts
+		}
+	}
+	return []*SendMsgEvent{evt}
+}
+
+// buildDoubleProposal: re-propose same block with different nonce, the original proposal is sent
+// to first half of neighbours, and the conflicting one to the other half.
+// The last neighbour of first half receives both, to witness the faulty proposer.
+func (self *Server) buildDoubleProposal(proposal *blockProposalMsg) ([]*SendMsgEvent, error) {
+	blk := proposal.Block.Block
+	conflictBlk, err := self.constructBlock(blk.Header.Height, blk.Header.PrevBlockHash, blk.Transactions,
+		blk.Header.ConsensusPayload, blk.Header.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("construct conflicting block: %s", err)
+	}
+	conflictBlk.ShardTxs = blk.ShardTxs
+	conflict := &blockProposalMsg{
+		Block: &Block{
+			Block:               conflictBlk,
+			EmptyBlock:          proposal.Block.EmptyBlock,
+			Info:                proposal.Block.Info,
+			PrevBlockMerkleRoot: proposal.Block.PrevBlockMerkleRoot,
+			CrossMsgHash:        proposal.Block.CrossMsgHash,
+		},
+	}
+
+	peers := self.peerPool.getNeighbours()
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Index < peers[j].Index
+	})
+	evts := make([]*SendMsgEvent, 0, len(peers)+1)
+	for i, p := range peers {
+		var msg ConsensusMsg = proposal
+		if i >= len(peers)/2 {
+			msg = conflict
+		}
+		evts = append(evts, &SendMsgEvent{
+			ToPeer: p.Index,
+			Msg:    msg,
+		})
+		if i == len(peers)/2-1 {
+			evts = append(evts, &SendMsgEvent{
+				ToPeer: p.Index,
+				Msg:    conflict,
+			})
+		}
+	}
+	blkHash, conflictHash := blk.Hash(), conflictBlk.Hash()
+	log.Infof("server %d, byzantine: double proposal for %d, %s and %s", self.Index, blk.Header.Height,
+		blkHash.ToHexString(), conflictHash.ToHexString())
+	return evts, nil
+}
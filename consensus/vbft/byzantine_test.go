@@ -0,0 +1,43 @@
+// Copyright (C) 2019 The ontology Authors
+// This file is part of The ontology library.
+//
+// The ontology is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The ontology is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build byzantine
+
+package vbft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWithholdEndorsement(t *testing.T) {
+	server := &Server{Index: 1}
+	evt := &SendMsgEvent{
+		ToPeer: math.MaxUint32,
+		Msg:    &blockEndorseMsg{Endorser: 1, BlockNum: 10},
+	}
+	if evts := server.applyByzantineBehavior(evt); len(evts) != 1 || evts[0] != evt {
+		t.Fatalf("honest server should send endorsement, got %d msgs", len(evts))
+	}
+	server.SetByzantineBehavior(BYZANTINE_WITHHOLD_ENDORSEMENT)
+	if evts := server.applyByzantineBehavior(evt); len(evts) != 0 {
+		t.Fatalf("byzantine server should withhold endorsement, got %d msgs", len(evts))
+	}
+	server.SetByzantineBehavior(BYZANTINE_DOUBLE_PROPOSAL)
+	if evts := server.applyByzantineBehavior(evt); len(evts) != 1 {
+		t.Fatalf("double proposer should send endorsement, got %d msgs", len(evts))
+	}
+}
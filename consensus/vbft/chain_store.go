@@ -90,6 +90,19 @@ func (self *ChainStore) GetExecMerkleRoot(blkNum uint32) (common.Uint256, error)
 
 }
 
+// GetExecStorageRoot returns storage trie root of block, storage trie is maintained by ledger when storage trie
+// is enabled or PrevStorageRoot is required in the next block
+func (self *ChainStore) GetExecStorageRoot(blkNum uint32) (common.Uint256, error) {
+	if blk, present := self.pendingBlocks[blkNum]; blk != nil && present && blk.execResult.StorageRoot != common.UINT256_EMPTY {
+		return blk.execResult.StorageRoot, nil
+	}
+	storageRoot, err := self.db.GetStorageTrieRoot(blkNum)
+	if err != nil {
+		return common.Uint256{}, fmt.Errorf("GetStorageTrieRoot blockNum:%d, error :%s", blkNum, err)
+	}
+	return storageRoot, nil
+}
+
 func (self *ChainStore) GetExecShardNotify(blkNum uint32) []xshard_types.CommonShardMsg {
 	if blk, present := self.pendingBlocks[blkNum]; blk != nil && present {
 		return blk.execResult.ShardNotify
@@ -190,6 +203,46 @@ func (self *ChainStore) SubmitBlock(blkNum uint32) error {
 	return nil
 }
 
+func (self *ChainStore) SaveBlockConsensusMsgs(msgs *BlockConsensusMsgs) error {
+	data, err := msgs.Serialize()
+	if err != nil {
+		return fmt.Errorf("serialize consensus msgs of block %d: %s", msgs.BlockNum, err)
+	}
+	return self.db.SaveBlockConsensusMsgs(msgs.BlockNum, data)
+}
+
+func (self *ChainStore) GetBlockConsensusMsgs(blockNum uint32) (*BlockConsensusMsgs, error) {
+	data, err := self.db.GetBlockConsensusMsgs(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	msgs := &BlockConsensusMsgs{}
+	if err := msgs.Deserialize(data); err != nil {
+		return nil, fmt.Errorf("deserialize consensus msgs of block %d: %s", blockNum, err)
+	}
+	return msgs, nil
+}
+
+func (self *ChainStore) SaveRoundConsensusMsgs(msgs *RoundConsensusMsgs) error {
+	data, err := msgs.Serialize()
+	if err != nil {
+		return fmt.Errorf("serialize consensus msgs of round %d: %s", msgs.BlockNum, err)
+	}
+	return self.db.SaveRoundConsensusMsgs(data)
+}
+
+func (self *ChainStore) GetRoundConsensusMsgs() (*RoundConsensusMsgs, error) {
+	data, err := self.db.GetRoundConsensusMsgs()
+	if err != nil {
+		return nil, err
+	}
+	msgs := &RoundConsensusMsgs{}
+	if err := msgs.Deserialize(data); err != nil {
+		return nil, fmt.Errorf("deserialize consensus msgs of round: %s", err)
+	}
+	return msgs, nil
+}
+
 func (self *ChainStore) GetBlock(blockNum uint32) (*Block, error) {
 	if blk, present := self.pendingBlocks[blockNum]; present {
 		return blk.block, nil
@@ -208,3 +261,16 @@ func (self *ChainStore) GetBlock(blockNum uint32) (*Block, error) {
 	}
 	return initVbftBlock(block, prevMerkleRoot)
 }
+
+// GetBlockHeader returns vbft block with header only, which is still available after block body pruned by ledger.
+// It is used for reading chain config from config blocks.
+func (self *ChainStore) GetBlockHeader(blockNum uint32) (*Block, error) {
+	if blk, present := self.pendingBlocks[blockNum]; present {
+		return blk.block, nil
+	}
+	header, err := self.db.GetHeaderByHeight(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	return initVbftBlock(&types.Block{Header: header}, common.Uint256{})
+}
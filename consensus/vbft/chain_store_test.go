@@ -19,15 +19,21 @@
 package vbft
 
 import (
+	"encoding/json"
+	"math"
 	"os"
 	"testing"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/genesis"
 	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
 )
 
 func newChainStore(t *testing.T) *ChainStore {
@@ -84,3 +90,235 @@ func TestGetChainedBlockNum(t *testing.T) {
 	blocknum := chainstore.GetChainedBlockNum()
 	t.Logf("TestGetChainedBlockNum :%d", blocknum)
 }
+
+func newRoundTestServer(t *testing.T, chainstore *ChainStore) *Server {
+	server := constructServer()
+	server.Index = 2
+	server.chainStore = chainstore
+	server.currentBlockNum = chainstore.GetChainedBlockNum() + 1
+	server.msgPool = newMsgPool(server, 1)
+	blockPool, err := newBlockPool(server, 1, chainstore)
+	if err != nil {
+		t.Fatalf("newBlockPool failed: %s", err)
+	}
+	server.blockPool = blockPool
+	return server
+}
+
+func TestRoundConsensusMsgsRestart(t *testing.T) {
+	chainstore := newChainStore(t)
+	server := newRoundTestServer(t, chainstore)
+	blkNum := server.GetCurrentBlockNo()
+
+	block, err := constructBlock()
+	if err != nil {
+		t.Fatalf("constructBlock failed: %v", err)
+	}
+	block.Block.Header.Height = blkNum
+	proposal := &blockProposalMsg{Block: block}
+	endorse := &blockEndorseMsg{
+		Endorser:          server.Index,
+		EndorsedProposer:  block.getProposer(),
+		BlockNum:          blkNum,
+		EndorsedBlockHash: block.Block.Hash(),
+	}
+	commit := &blockCommitMsg{
+		Committer:       server.Index,
+		BlockProposer:   block.getProposer(),
+		BlockNum:        blkNum,
+		CommitBlockHash: block.Block.Hash(),
+	}
+	// endorsement received from peer is not persisted
+	peerEndorse := &blockEndorseMsg{
+		Endorser:          server.Index + 1,
+		EndorsedProposer:  block.getProposer(),
+		BlockNum:          blkNum,
+		EndorsedBlockHash: block.Block.Hash(),
+	}
+	for _, msg := range []ConsensusMsg{proposal, endorse, peerEndorse, commit} {
+		h, _ := HashMsg(msg)
+		if err := server.msgPool.AddMsg(msg, h); err != nil {
+			t.Fatalf("AddMsg failed: %s", err)
+		}
+	}
+	if err := server.persistRoundConsensusMsgs(blkNum, proposal, endorse); err != nil {
+		t.Fatalf("persistRoundConsensusMsgs failed: %s", err)
+	}
+	if err := server.persistRoundConsensusMsgs(blkNum, proposal, commit); err != nil {
+		t.Fatalf("persistRoundConsensusMsgs failed: %s", err)
+	}
+	if len(server.roundMsgs.Msgs) != 3 {
+		t.Fatalf("persisted %d msgs of round %d, proposal should be persisted once", len(server.roundMsgs.Msgs), blkNum)
+	}
+
+	// restart
+	chainstore.close()
+	db, err := ledger.NewLedger(config.DEFAULT_DATA_DIR, 0)
+	if err != nil {
+		t.Fatalf("NewLedger error %s", err)
+	}
+	chainstore, err = OpenBlockStore(db, nil)
+	if err != nil {
+		t.Fatalf("openblockstore failed: %v", err)
+	}
+	defer cleanChainStore(t, chainstore)
+	server = newRoundTestServer(t, chainstore)
+	server.loadRoundConsensusMsgs(blkNum)
+
+	if len(server.msgPool.GetProposalMsgs(blkNum)) != 1 ||
+		len(server.msgPool.GetEndorsementsMsgs(blkNum)) != 1 ||
+		len(server.msgPool.GetCommitMsgs(blkNum)) != 1 {
+		t.Fatalf("consensus msgs of round %d not resumed", blkNum)
+	}
+	if !server.blockPool.endorsedForBlock(blkNum) {
+		t.Errorf("self endorsement of round %d not resumed", blkNum)
+	}
+	if !server.blockPool.committedForBlock(blkNum) {
+		t.Errorf("self commitment of round %d not resumed", blkNum)
+	}
+
+	// msgs of finished round are ignored
+	server = newRoundTestServer(t, chainstore)
+	server.loadRoundConsensusMsgs(blkNum + 1)
+	if len(server.msgPool.GetProposalMsgs(blkNum)) != 0 {
+		t.Errorf("consensus msgs of finished round %d resumed", blkNum)
+	}
+}
+
+// newPruningChainStore builds vbft ledger with pruning enabled, chain config is updated to view 2 at block 1,
+// and blocks are added until the bodies of genesis block and config block are pruned
+func newPruningChainStore(t *testing.T) *ChainStore {
+	log.InitLog(log.InfoLog, log.Stdout)
+	accs := make([]*account.Account, 0)
+	bookkeepers := make([]keypair.PublicKey, 0)
+	vbftConfig := *config.MainNetConfig.VBFT
+	vbftConfig.Peers = nil
+	for i := uint32(1); i <= vbftConfig.K; i++ {
+		acc := account.NewAccount("")
+		accs = append(accs, acc)
+		bookkeepers = append(bookkeepers, acc.PublicKey)
+		vbftConfig.Peers = append(vbftConfig.Peers, &config.VBFTPeerStakeInfo{
+			Index:      i,
+			PeerPubkey: vconfig.PubkeyID(acc.PublicKey),
+			Address:    acc.Address.ToBase58(),
+		})
+	}
+	genesisConfig := *config.MainNetConfig
+	genesisConfig.VBFT = &vbftConfig
+	config.DefConfig.Genesis = &genesisConfig
+	config.DefConfig.Common.PruneRetention = config.MIN_PRUNE_RETENTION
+
+	db, err := ledger.NewLedger(config.DEFAULT_DATA_DIR, 0)
+	if err != nil {
+		t.Fatalf("NewLedger error %s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis, config.DefConfig.Shard)
+	if err != nil {
+		t.Fatalf("BuildGenesisBlock error %s", err)
+	}
+	if err := db.Init(bookkeepers, genesisBlock); err != nil {
+		t.Fatalf("InitLedgerStoreWithGenesisBlock error %s", err)
+	}
+	genesisInfo, err := vconfig.VbftBlock(genesisBlock.Header)
+	if err != nil {
+		t.Fatalf("VbftBlock error %s", err)
+	}
+	newConfig := *genesisInfo.NewChainConfig
+	newConfig.View++
+
+	prev := genesisBlock.Header
+	for height := uint32(1); height <= config.MIN_PRUNE_RETENTION+2; height++ {
+		info := &vconfig.VbftBlockInfo{
+			Proposer:           1,
+			VrfValue:           genesisInfo.VrfValue,
+			LastConfigBlockNum: 1,
+		}
+		if height == 1 {
+			info.NewChainConfig = &newConfig
+		}
+		payload, err := json.Marshal(info)
+		if err != nil {
+			t.Fatalf("marshal block info error %s", err)
+		}
+		header := &types.Header{
+			Version:          prev.Version,
+			PrevBlockHash:    prev.Hash(),
+			TransactionsRoot: common.ComputeMerkleRoot(nil),
+			Timestamp:        prev.Timestamp + 1,
+			Height:           height,
+			ConsensusPayload: payload,
+		}
+		hash := header.Hash()
+		sig, err := signature.Sign(accs[0], hash[:])
+		if err != nil {
+			t.Fatalf("sign header error %s", err)
+		}
+		header.Bookkeepers = []keypair.PublicKey{accs[0].PublicKey}
+		header.SigData = [][]byte{sig}
+		if err := db.AddBlock(&types.Block{Header: header}, common.UINT256_EMPTY); err != nil {
+			t.Fatalf("AddBlock %d error %s", height, err)
+		}
+		prev = header
+	}
+	for _, height := range []uint32{0, 1} {
+		if _, err := db.GetBlockByHeight(height); err == nil {
+			t.Fatalf("block %d not pruned", height)
+		}
+	}
+	db.Close()
+
+	// restart
+	db, err = ledger.NewLedger(config.DEFAULT_DATA_DIR, 0)
+	if err != nil {
+		t.Fatalf("NewLedger error %s", err)
+	}
+	if err := db.Init(bookkeepers, genesisBlock); err != nil {
+		t.Fatalf("InitLedgerStoreWithGenesisBlock error %s", err)
+	}
+	chainstore, err := OpenBlockStore(db, nil)
+	if err != nil {
+		t.Fatalf("openblockstore failed: %v", err)
+	}
+	return chainstore
+}
+
+func TestLoadChainConfigWithPruning(t *testing.T) {
+	genesisConfig := config.DefConfig.Genesis
+	pruneRetention := config.DefConfig.Common.PruneRetention
+	defer func() {
+		config.DefConfig.Genesis = genesisConfig
+		config.DefConfig.Common.PruneRetention = pruneRetention
+	}()
+	chainstore := newPruningChainStore(t)
+	defer cleanChainStore(t, chainstore)
+
+	server := newRoundTestServer(t, chainstore)
+	if err := server.LoadChainConfig(chainstore); err != nil {
+		t.Fatalf("LoadChainConfig failed: %s", err)
+	}
+	if server.config.View != 2 || server.LastConfigBlockNum != 1 {
+		t.Fatalf("unexpected chain config view %d, config block %d", server.config.View, server.LastConfigBlockNum)
+	}
+
+	// config blocks can still be served to peers
+	blk, err := server.findChainConfigBlock(2)
+	if err != nil || blk.getBlockNum() != 1 {
+		t.Fatalf("findChainConfigBlock of view 2 failed: %v", err)
+	}
+	blk, err = server.findChainConfigBlock(1)
+	if err != nil || blk.getBlockNum() != 0 || blk.getLastConfigBlockNum() != math.MaxUint32 {
+		t.Fatalf("findChainConfigBlock of view 1 failed: %v", err)
+	}
+	msg := server.constructChainConfigFetchRespMsg(2, blk)
+	data, err := msg.Serialize()
+	if err != nil {
+		t.Fatalf("ChainConfigFetchRespMsg Serialize failed: %s", err)
+	}
+	respMsg := &ChainConfigFetchRespMsg{}
+	if err := respMsg.Deserialize(data); err != nil {
+		t.Fatalf("ChainConfigFetchRespMsg Deserialize failed: %s", err)
+	}
+	if respMsg.BlockData.Block.Hash() != blk.Block.Hash() || respMsg.BlockData.getNewChainConfig() == nil {
+		t.Errorf("ChainConfigFetchRespMsg Deserialize unmatch")
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import "time"
+
+// Clock is time source of consensus server, servers run with system clock,
+// simulation tests drive servers with virtual clock.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is created by Clock.AfterFunc, *time.Timer is Timer of system clock
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (self *Server) getClock() Clock {
+	if self == nil || self.clock == nil {
+		return systemClock{}
+	}
+	return self.clock
+}
+
+// now: local time of server
+func (self *Server) now() time.Time {
+	return self.getClock().Now()
+}
+
+func (self *Server) afterFunc(d time.Duration, f func()) Timer {
+	return self.getClock().AfterFunc(d, f)
+}
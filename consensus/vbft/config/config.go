@@ -61,6 +61,9 @@ type VbftBlockInfo struct {
 	VrfProof           []byte       `json:"vrf_proof"`
 	LastConfigBlockNum uint32       `json:"last_config_block_num"`
 	NewChainConfig     *ChainConfig `json:"new_chain_config"`
+	EmergencyRotation  []byte       `json:"emergency_rotation,omitempty"` // votes of rotating crashed peer out
+	PrevStateRoot      []byte       `json:"prev_state_root,omitempty"`    // state merkle root of previous block, for light client
+	PrevStorageRoot    []byte       `json:"prev_storage_root,omitempty"`  // storage trie root of previous block, for storage proofs
 }
 
 const (
@@ -25,6 +25,8 @@ import (
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/signature"
 	scommon "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/store/overlaydb"
 	"github.com/ontio/ontology/core/types"
@@ -56,6 +58,61 @@ func VbftBlock(header *types.Header) (*VbftBlockInfo, error) {
 	return blkInfo, nil
 }
 
+// VerifyVbftHeader checks that header is signed by enough peers of current chain config,
+// and returns the peers to verify next header with, which changes at blocks with new chain config
+func VerifyVbftHeader(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	m := len(vbftPeerInfo) - (len(vbftPeerInfo)*6)/7
+	if len(header.Bookkeepers) < m {
+		return vbftPeerInfo, fmt.Errorf("header Bookkeepers %d more than 6/7 len vbftPeerInfo%d", len(header.Bookkeepers), len(vbftPeerInfo))
+	}
+	for _, bookkeeper := range header.Bookkeepers {
+		pubkey := PubkeyID(bookkeeper)
+		if _, present := vbftPeerInfo[pubkey]; !present {
+			return vbftPeerInfo, fmt.Errorf("invalid pubkey :%v", pubkey)
+		}
+	}
+	hash := header.Hash()
+	err := signature.VerifyMultiSignature(hash[:], header.Bookkeepers, m, header.SigData)
+	if err != nil {
+		return vbftPeerInfo, fmt.Errorf("VerifyMultiSignature:%s,Bookkeepers:%d,pubkey:%d,heigh:%d", err, len(header.Bookkeepers), len(vbftPeerInfo), header.Height)
+	}
+	blkInfo, err := VbftBlock(header)
+	if err != nil {
+		return vbftPeerInfo, err
+	}
+	if RequirePrevStateRoot(header.Height) && len(blkInfo.PrevStateRoot) == 0 {
+		return vbftPeerInfo, fmt.Errorf("header %d without PrevStateRoot", header.Height)
+	}
+	if RequirePrevStorageRoot(header.Height) && len(blkInfo.PrevStorageRoot) == 0 {
+		return vbftPeerInfo, fmt.Errorf("header %d without PrevStorageRoot", header.Height)
+	}
+	if blkInfo.NewChainConfig != nil {
+		return ChainConfigPeers(blkInfo.NewChainConfig), nil
+	}
+	return vbftPeerInfo, nil
+}
+
+// RequirePrevStateRoot checks if vbft block at height must commit state merkle root of previous block
+func RequirePrevStateRoot(height uint32) bool {
+	checkHeight := config.GetStateRootCheckHeight()
+	return checkHeight != 0 && height > 0 && height >= checkHeight
+}
+
+// RequirePrevStorageRoot checks if vbft block at height must commit storage trie root of previous block
+func RequirePrevStorageRoot(height uint32) bool {
+	checkHeight := config.GetStorageRootCheckHeight()
+	return checkHeight != 0 && height > 0 && height >= checkHeight
+}
+
+// ChainConfigPeers returns index of peers in chain config by pubkey id
+func ChainConfigPeers(cfg *ChainConfig) map[string]uint32 {
+	peerInfo := make(map[string]uint32)
+	for _, p := range cfg.Peers {
+		peerInfo[p.ID] = p.Index
+	}
+	return peerInfo
+}
+
 func GetRawStorageItemFromMemDb(memdb *overlaydb.MemDB, addr common.Address, key []byte) (value []byte, unkown bool) {
 	rawKey := make([]byte, 0, 1+common.ADDR_LEN+len(key))
 	rawKey = append(rawKey, byte(scommon.ST_STORAGE))
@@ -18,7 +18,10 @@
 
 package vbft
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func constructEventTimer() *EventTimer {
 	server := constructServer()
@@ -47,3 +50,82 @@ func TestCancelEventTimer(t *testing.T) {
 	t.Logf("startEventTimer: %v", err)
 	eventtimer.cancelEventTimer(EventProposeBlockTimeout, 1)
 }
+
+type manualTimer struct {
+	clock  *manualClock
+	when   time.Time
+	f      func()
+	active bool
+}
+
+func (t *manualTimer) Stop() bool {
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	active := t.Stop()
+	t.when = t.clock.now.Add(d)
+	t.active = true
+	t.clock.timers = append(t.clock.timers, t)
+	return active
+}
+
+// manualClock: clock only advanced by test
+type manualClock struct {
+	now    time.Time
+	timers []*manualTimer
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &manualTimer{clock: c, f: f}
+	t.Reset(d)
+	return t
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+	timers := c.timers
+	c.timers = nil
+	for _, t := range timers {
+		if !t.active {
+			continue
+		}
+		if t.when.After(c.now) {
+			c.timers = append(c.timers, t)
+			continue
+		}
+		t.active = false
+		t.f()
+	}
+}
+
+func TestEventTimerWithClock(t *testing.T) {
+	clock := &manualClock{now: time.Unix(0, 0)}
+	server := constructServer()
+	server.clock = clock
+	eventtimer := NewEventTimer(server)
+	eventtimer.StartTimer(1, time.Second)
+	eventtimer.StartTimer(2, time.Second)
+	eventtimer.CancelTimer(2)
+
+	clock.advance(time.Second - time.Millisecond)
+	if len(eventtimer.C) != 0 {
+		t.Fatalf("timer fired before timeout")
+	}
+	clock.advance(time.Millisecond)
+	if len(eventtimer.C) != 1 {
+		t.Fatalf("timer not fired after timeout, events: %d", len(eventtimer.C))
+	}
+	if evt := <-eventtimer.C; evt.blockNum != 1 {
+		t.Fatalf("unexpected timer event of %d", evt.blockNum)
+	}
+	if server.now() != clock.Now() {
+		t.Fatalf("server not running with clock")
+	}
+}
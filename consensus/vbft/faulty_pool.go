@@ -0,0 +1,271 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	gover "github.com/ontio/ontology/smartcontract/service/native/governance"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+// evidence not packed in block after expired blocks will be dropped,
+// it may have been reported by the tx of other peers
+const FAULTY_EVIDENCE_EXPIRE_BLOCKS = 64
+
+type faultyEvidenceItem struct {
+	evidence *nutils.FaultyEvidence
+	tx       *types.Transaction
+	blockNum uint32 // block number when evidence added
+}
+
+// FaultyPool keeps verified evidences of faulty peers, until they are reported by governance tx
+type FaultyPool struct {
+	lock   sync.RWMutex
+	server *Server
+	items  map[string]*faultyEvidenceItem
+}
+
+func newFaultyPool(server *Server) *FaultyPool {
+	return &FaultyPool{
+		server: server,
+		items:  make(map[string]*faultyEvidenceItem),
+	}
+}
+
+func faultyEvidenceKey(evidence *nutils.FaultyEvidence) string {
+	return fmt.Sprintf("%s-%d-%d", evidence.PeerPubkey, evidence.Type, evidence.Height())
+}
+
+func (pool *FaultyPool) hasEvidence(evidence *nutils.FaultyEvidence) bool {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	_, present := pool.items[faultyEvidenceKey(evidence)]
+	return present
+}
+
+func (pool *FaultyPool) addEvidence(evidence *nutils.FaultyEvidence, tx *types.Transaction, blkNum uint32) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	key := faultyEvidenceKey(evidence)
+	if _, present := pool.items[key]; present {
+		return false
+	}
+	pool.items[key] = &faultyEvidenceItem{
+		evidence: evidence,
+		tx:       tx,
+		blockNum: blkNum,
+	}
+	return true
+}
+
+func (pool *FaultyPool) getEvidenceTxs() []*types.Transaction {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	keys := make([]string, 0, len(pool.items))
+	for key := range pool.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	txs := make([]*types.Transaction, 0, len(keys))
+	for _, key := range keys {
+		txs = append(txs, pool.items[key].tx)
+	}
+	return txs
+}
+
+func (pool *FaultyPool) onBlockSealed(blk *types.Block) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if len(pool.items) == 0 {
+		return
+	}
+	sealedTxs := make(map[common.Uint256]bool)
+	for _, tx := range blk.Transactions {
+		sealedTxs[tx.Hash()] = true
+	}
+	for key, item := range pool.items {
+		if sealedTxs[item.tx.Hash()] || item.blockNum+FAULTY_EVIDENCE_EXPIRE_BLOCKS < blk.Header.Height {
+			delete(pool.items, key)
+		}
+	}
+}
+
+func sortFaultyProposals(proposals []*blockProposalMsg) {
+	sort.SliceStable(proposals, func(i, j int) bool {
+		hi, hj := proposals[i].Block.Block.Hash(), proposals[j].Block.Block.Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
+}
+
+func (self *Server) findProposalByHash(blkNum uint32, blkHash common.Uint256) *blockProposalMsg {
+	for _, p := range self.blockPool.getBlockProposals(blkNum) {
+		if p.Block.Block.Hash() == blkHash {
+			return p
+		}
+	}
+	for _, msg := range self.msgPool.GetProposalMsgs(blkNum) {
+		if p, ok := msg.(*blockProposalMsg); ok && p.Block.Block.Hash() == blkHash {
+			return p
+		}
+	}
+	return nil
+}
+
+// reportFaultyProposal: proposer has signed two different proposals for same block
+func (self *Server) reportFaultyProposal(proposal *blockProposalMsg) {
+	blkNum := proposal.GetBlockNum()
+	proposer := proposal.Block.getProposer()
+	var prev *blockProposalMsg
+	for _, p := range self.blockPool.getBlockProposals(blkNum) {
+		if p.Block.getProposer() == proposer {
+			prev = p
+			break
+		}
+	}
+	if prev == nil || prev.Block.EmptyBlock == nil || proposal.Block.EmptyBlock == nil {
+		return
+	}
+	pk := self.peerPool.GetPeerPubKey(proposer)
+	if pk == nil {
+		return
+	}
+	proposals := []*blockProposalMsg{prev, proposal}
+	sortFaultyProposals(proposals)
+	evidence := &nutils.FaultyEvidence{
+		Type:       nutils.FAULTY_PROPOSAL,
+		PeerPubkey: vconfig.PubkeyID(pk),
+		Proposals:  make([]*nutils.FaultyProposal, 0),
+	}
+	for _, p := range proposals {
+		evidence.Proposals = append(evidence.Proposals, nutils.NewFaultyProposal(p.Block.Block, p.Block.EmptyBlock))
+	}
+	log.Warnf("server %d detected faulty proposer %d, blk %d", self.Index, proposer, blkNum)
+	self.onFaultyEvidence(evidence)
+}
+
+// reportFaultyEndorsement: endorser has endorsed two different non-empty blocks for same block
+func (self *Server) reportFaultyEndorsement(endorse *blockEndorseMsg) {
+	if endorse.EndorseForEmpty {
+		return
+	}
+	blkNum := endorse.GetBlockNum()
+	for _, msg := range self.msgPool.GetEndorsementsMsgs(blkNum) {
+		e, ok := msg.(*blockEndorseMsg)
+		if !ok || e.Endorser != endorse.Endorser || e.EndorseForEmpty || e.EndorsedBlockHash == endorse.EndorsedBlockHash {
+			continue
+		}
+		p1 := self.findProposalByHash(blkNum, e.EndorsedBlockHash)
+		p2 := self.findProposalByHash(blkNum, endorse.EndorsedBlockHash)
+		if p1 == nil || p2 == nil || p1.Block.EmptyBlock == nil || p2.Block.EmptyBlock == nil {
+			log.Infof("server %d conflict endorsements from %d, blk %d, proposal not found",
+				self.Index, endorse.Endorser, blkNum)
+			return
+		}
+		pk := self.peerPool.GetPeerPubKey(endorse.Endorser)
+		if pk == nil {
+			return
+		}
+		sigs := map[common.Uint256][]byte{
+			e.EndorsedBlockHash:       e.EndorserSig,
+			endorse.EndorsedBlockHash: endorse.EndorserSig,
+		}
+		proposals := []*blockProposalMsg{p1, p2}
+		sortFaultyProposals(proposals)
+		evidence := &nutils.FaultyEvidence{
+			Type:        nutils.FAULTY_ENDORSEMENT,
+			PeerPubkey:  vconfig.PubkeyID(pk),
+			Proposals:   make([]*nutils.FaultyProposal, 0),
+			EndorseSigs: make([][]byte, 0),
+		}
+		for _, p := range proposals {
+			evidence.Proposals = append(evidence.Proposals, nutils.NewFaultyProposal(p.Block.Block, p.Block.EmptyBlock))
+			evidence.EndorseSigs = append(evidence.EndorseSigs, sigs[p.Block.Block.Hash()])
+		}
+		log.Warnf("server %d detected faulty endorser %d, blk %d", self.Index, endorse.Endorser, blkNum)
+		self.onFaultyEvidence(evidence)
+		return
+	}
+}
+
+// onFaultyEvidence verifies the evidence, adds it to faulty pool and gossips it to other peers.
+// evidence txs in faulty pool will be packed to block by proposer.
+func (self *Server) onFaultyEvidence(evidence *nutils.FaultyEvidence) {
+	if err := evidence.Verify(self.ShardID); err != nil {
+		log.Errorf("server %d received invalid faulty evidence: %s", self.Index, err)
+		return
+	}
+	if self.faultyPool.hasEvidence(evidence) {
+		return
+	}
+	tx, err := self.createFaultyEvidenceTransaction(evidence)
+	if err != nil {
+		log.Errorf("server %d failed to build faulty evidence tx: %s", self.Index, err)
+		return
+	}
+	if !self.faultyPool.addEvidence(evidence, tx, self.GetCurrentBlockNo()) {
+		return
+	}
+	msg, err := self.constructFaultyEvidenceMsg(evidence)
+	if err != nil {
+		log.Errorf("server %d failed to build faulty evidence msg: %s", self.Index, err)
+		return
+	}
+	self.broadcast(msg)
+}
+
+func (self *Server) constructFaultyEvidenceMsg(evidence *nutils.FaultyEvidence) (*faultyEvidenceMsg, error) {
+	buf := new(bytes.Buffer)
+	if err := evidence.Serialize(buf); err != nil {
+		return nil, fmt.Errorf("serialize evidence: %s", err)
+	}
+	return &faultyEvidenceMsg{
+		Evidence: buf.Bytes(),
+	}, nil
+}
+
+// createFaultyEvidenceTransaction: report faulty peer to governance contract at root shard,
+// or report to parent shard through shard management contract
+func (self *Server) createFaultyEvidenceTransaction(evidence *nutils.FaultyEvidence) (*types.Transaction, error) {
+	buf := new(bytes.Buffer)
+	if err := evidence.Serialize(buf); err != nil {
+		return nil, fmt.Errorf("serialize evidence: %s", err)
+	}
+	var mutable *types.MutableTransaction
+	if self.ShardID.IsRootShard() {
+		mutable = utils.BuildNativeTransaction(nutils.GovernanceContractAddress, gover.REPORT_FAULTY_NODE, buf.Bytes())
+	} else {
+		mutable = utils.BuildNativeTransaction(nutils.ShardMgmtContractAddress, shardmgmt.NOTIFY_PARENT_FAULTY_PEER, buf.Bytes())
+	}
+	mutable.Nonce = evidence.Height()
+	return self.signSysTransaction(mutable)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common/metrics"
+)
+
+// reasons of proposal rejection
+const (
+	REJECT_PREV_BLOCK_HASH   = "prev_block_hash"
+	REJECT_MERKLE_ROOT       = "merkle_root"
+	REJECT_STORAGE_ROOT      = "storage_root"
+	REJECT_CHAIN_CONFIG      = "chain_config"
+	REJECT_TIMESTAMP         = "timestamp"
+	REJECT_VRF               = "vrf"
+	REJECT_INVALID_TXS       = "invalid_txs"
+	REJECT_DUPLICATED        = "duplicated"
+	REJECT_SHARD_EVENT       = "shard_event"
+	REJECT_CROSS_SHARD_TXS   = "cross_shard_txs"
+	REJECT_TX_VERIFY_FAILURE = "tx_verify_failure"
+)
+
+var (
+	roundDuration = metrics.NewHistogram("ontology_vbft_round_duration_seconds",
+		"Duration from starting new round to sealing block", metrics.DefBuckets, "shard")
+	timerEventCounter = metrics.NewCounter("ontology_vbft_timer_events_total",
+		"Timer events fired, by timer event type", "shard", "event")
+	proposalRejectCounter = metrics.NewCounter("ontology_vbft_proposal_rejections_total",
+		"Block proposals rejected, by reason", "shard", "reason")
+	catchConsensusCounter = metrics.NewCounter("ontology_vbft_catch_consensus_total",
+		"Times of catching up consensus of current round", "shard")
+	fastForwardCounter = metrics.NewCounter("ontology_vbft_fast_forward_total",
+		"Times of fast forwarding with committed msgs in msg pool", "shard")
+)
+
+var timerEventNames = map[TimerEventType]string{
+	EventProposeBlockTimeout:      "propose_block_timeout",
+	EventProposalBackoff:          "proposal_backoff",
+	EventRandomBackoff:            "random_backoff",
+	EventPropose2ndBlockTimeout:   "propose_2nd_block_timeout",
+	EventEndorseBlockTimeout:      "endorse_block_timeout",
+	EventEndorseEmptyBlockTimeout: "endorse_empty_block_timeout",
+	EventCommitBlockTimeout:       "commit_block_timeout",
+	EventPeerHeartbeat:            "peer_heartbeat",
+	EventTxPool:                   "txpool",
+	EventTxBlockTimeout:           "tx_block_timeout",
+}
+
+func (t TimerEventType) String() string {
+	if name, present := timerEventNames[t]; present {
+		return name
+	}
+	return fmt.Sprintf("unknown_%d", int(t))
+}
+
+// roundMetrics records start time of current consensus round
+type roundMetrics struct {
+	lock      sync.Mutex
+	blockNum  uint32
+	startTime time.Time
+}
+
+func (self *Server) metricsShardLabel() string {
+	return strconv.FormatUint(self.ShardID.ToUint64(), 10)
+}
+
+func (self *Server) onRoundStarted(blkNum uint32) {
+	self.roundMetrics.lock.Lock()
+	defer self.roundMetrics.lock.Unlock()
+	if self.roundMetrics.blockNum != blkNum {
+		self.roundMetrics.blockNum = blkNum
+		self.roundMetrics.startTime = time.Now()
+	}
+}
+
+func (self *Server) onRoundSealed(blkNum uint32) {
+	self.roundMetrics.lock.Lock()
+	defer self.roundMetrics.lock.Unlock()
+	// round not started by this node, sealed in syncing or fast forward
+	if self.roundMetrics.blockNum != blkNum || self.roundMetrics.startTime.IsZero() {
+		return
+	}
+	roundDuration.Observe(time.Since(self.roundMetrics.startTime).Seconds(), self.metricsShardLabel())
+	self.roundMetrics.startTime = time.Time{}
+}
+
+func (self *Server) incTimerEvent(evtType TimerEventType) {
+	timerEventCounter.Inc(self.metricsShardLabel(), evtType.String())
+}
+
+func (self *Server) incProposalRejection(reason string) {
+	proposalRejectCounter.Inc(self.metricsShardLabel(), reason)
+}
@@ -21,7 +21,6 @@ package vbft
 import (
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
@@ -117,6 +116,30 @@ func DeserializeVbftMsg(msgPayload []byte) (ConsensusMsg, error) {
 			return nil, fmt.Errorf("failed to unmarshal msg (type: %d): %s", m.Type, err)
 		}
 		return t, nil
+	case FaultyEvidenceMessage:
+		t := &faultyEvidenceMsg{}
+		if err := json.Unmarshal(m.Payload, t); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal msg (type: %d): %s", m.Type, err)
+		}
+		return t, nil
+	case EmergencyRotationVoteMessage:
+		t := &emergencyRotationVoteMsg{}
+		if err := json.Unmarshal(m.Payload, t); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal msg (type: %d): %s", m.Type, err)
+		}
+		return t, nil
+	case ChainConfigFetchMessage:
+		t := &chainConfigFetchMsg{}
+		if err := json.Unmarshal(m.Payload, t); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal msg (type: %d): %s", m.Type, err)
+		}
+		return t, nil
+	case ChainConfigFetchRespMessage:
+		t := &ChainConfigFetchRespMsg{}
+		if err := t.Deserialize(m.Payload); err != nil {
+			return nil, fmt.Errorf("failed to Deserialize msg (type: %d): %s", m.Type, err)
+		}
+		return t, nil
 	}
 
 	return nil, fmt.Errorf("unknown msg type: %d", m.Type)
@@ -205,13 +228,15 @@ func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, tx
 	blockRoot := self.ledger.GetBlockRootWithNewTxRoots(lastBlock.Block.Header.Height, []common.Uint256{lastBlock.Block.Header.TransactionsRoot, txRoot})
 	var shardTxs map[common.ShardID][]*types.CrossShardTxInfos
 	if self.ShardID.IsRootShard() {
-		shardTxs, err = xshard.GetCrossShardTxs(self.ledger, self.account, self.ShardID, parentHeight, 0)
+		shardTxs, err = xshard.GetCrossShardTxs(self.ledger, self.account, self.ShardID, parentHeight, 0,
+			xshard.CrossShardTxBudget(len(txs)))
 		if err != nil {
 			log.Errorf("GetCrossShardTxs err:%s", err)
 		}
 	} else {
 		if parentHeight > lastBlock.Block.Header.ParentHeight {
-			shardTxs, err = xshard.GetCrossShardTxs(self.ledger, self.account, self.ShardID, lastBlock.Block.Header.ParentHeight, parentHeight)
+			shardTxs, err = xshard.GetCrossShardTxs(self.ledger, self.account, self.ShardID,
+				lastBlock.Block.Header.ParentHeight, parentHeight, xshard.CrossShardTxBudget(len(txs)))
 			if err != nil {
 				log.Errorf("GetCrossShardTxs err:%s", err)
 			}
@@ -266,13 +291,13 @@ func (self *Server) constructCrossShardHashMsg(blkNum uint32) (*types.CrossShard
 	}, nil
 }
 
-func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types.Transaction, chainconfig *vconfig.ChainConfig) (*blockProposalMsg, error) {
+func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types.Transaction, chainconfig *vconfig.ChainConfig, rotation []byte) (*blockProposalMsg, error) {
 
 	prevBlk, prevBlkHash := self.blockPool.getSealedBlock(blkNum - 1)
 	if prevBlk == nil {
 		return nil, fmt.Errorf("failed to get prevBlock (%d)", blkNum-1)
 	}
-	blocktimestamp := uint32(time.Now().Unix())
+	blocktimestamp := uint32(self.now().Unix())
 	if prevBlk.Block.Header.Timestamp >= blocktimestamp {
 		blocktimestamp = prevBlk.Block.Header.Timestamp + 1
 	}
@@ -289,12 +314,27 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 	if chainconfig != nil {
 		lastConfigBlkNum = blkNum
 	}
+	merkleRoot, err := self.chainStore.GetExecMerkleRoot(blkNum - 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetExecMerkleRoot: %s,blkNum:%d", err, (blkNum - 1))
+	}
 	vbftBlkInfo := &vconfig.VbftBlockInfo{
 		Proposer:           self.Index,
 		VrfValue:           vrfValue,
 		VrfProof:           vrfProof,
 		LastConfigBlockNum: lastConfigBlkNum,
 		NewChainConfig:     chainconfig,
+		EmergencyRotation:  rotation,
+	}
+	if merkleRoot != common.UINT256_EMPTY || vconfig.RequirePrevStateRoot(blkNum) {
+		vbftBlkInfo.PrevStateRoot = merkleRoot[:]
+	}
+	if vconfig.RequirePrevStorageRoot(blkNum) {
+		storageRoot, err := self.chainStore.GetExecStorageRoot(blkNum - 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to GetExecStorageRoot: %s,blkNum:%d", err, (blkNum - 1))
+		}
+		vbftBlkInfo.PrevStorageRoot = storageRoot[:]
 	}
 	consensusPayload, err := json.Marshal(vbftBlkInfo)
 	if err != nil {
@@ -308,10 +348,6 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 	if err != nil {
 		return nil, fmt.Errorf("failed to constuct blk: %s", err)
 	}
-	merkleRoot, err := self.chainStore.GetExecMerkleRoot(blkNum - 1)
-	if err != nil {
-		return nil, fmt.Errorf("failed to GetExecMerkleRoot: %s,blkNum:%d", err, (blkNum - 1))
-	}
 	crossShardMsgHash, err := self.constructCrossShardHashMsg(blkNum - 1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to CrossShardHashMsgs :%s,blkNum:%d", err, (blkNum - 1))
@@ -331,7 +367,7 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 
 func (self *Server) constructEndorseMsg(proposal *blockProposalMsg, forEmpty bool) (*blockEndorseMsg, error) {
 
-	// TODO, support faultyMsg reporting
+	// faulty msgs are reported with faultyEvidenceMsg
 
 	var proposerSig, endorserSig []byte
 	var blkHash common.Uint256
@@ -376,7 +412,7 @@ func (self *Server) constructEndorseMsg(proposal *blockProposalMsg, forEmpty boo
 
 func (self *Server) constructCommitMsg(proposal *blockProposalMsg, endorses []*blockEndorseMsg, forEmpty bool) (*blockCommitMsg, error) {
 
-	// TODO, support faultyMsg reporting
+	// faulty msgs are reported with faultyEvidenceMsg
 
 	var proposerSig, committerSig []byte
 	var blkHash common.Uint256
@@ -444,6 +480,19 @@ func (self *Server) constructBlockFetchRespMsg(blkNum uint32, blk *Block, blkHas
 	}
 }
 
+func (self *Server) constructChainConfigFetchMsg(view uint32) *chainConfigFetchMsg {
+	return &chainConfigFetchMsg{
+		ChainConfigView: view,
+	}
+}
+
+func (self *Server) constructChainConfigFetchRespMsg(view uint32, blk *Block) *ChainConfigFetchRespMsg {
+	return &ChainConfigFetchRespMsg{
+		ChainConfigView: view,
+		BlockData:       blk,
+	}
+}
+
 func (self *Server) constructBlockInfoFetchMsg(startBlkNum uint32) *BlockInfoFetchMsg {
 	return &BlockInfoFetchMsg{
 		StartBlockNum: startBlkNum,
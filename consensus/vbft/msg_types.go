@@ -29,6 +29,8 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/serialization"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/types"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
 )
 
 type MsgType uint8
@@ -47,6 +49,10 @@ const (
 	BlockFetchMessage
 	BlockFetchRespMessage
 	BlockSubmitMessage
+	FaultyEvidenceMessage
+	ChainConfigFetchMessage
+	ChainConfigFetchRespMessage
+	EmergencyRotationVoteMessage
 )
 
 type ConsensusMsg interface {
@@ -419,3 +425,125 @@ func (msg *blockSubmitMsg) GetBlockNum() uint32 {
 func (msg *blockSubmitMsg) Serialize() ([]byte, error) {
 	return json.Marshal(msg)
 }
+
+type faultyEvidenceMsg struct {
+	Evidence []byte `json:"evidence"`
+}
+
+func (msg *faultyEvidenceMsg) Type() MsgType {
+	return FaultyEvidenceMessage
+}
+
+// evidence is verified with signatures in it, not by the sender
+func (msg *faultyEvidenceMsg) Verify(pub keypair.PublicKey) error {
+	return nil
+}
+
+func (msg *faultyEvidenceMsg) GetBlockNum() uint32 {
+	return 0
+}
+
+func (msg *faultyEvidenceMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// chain config fetch msg is to fetch the block which updated chain config to the view
+type chainConfigFetchMsg struct {
+	ChainConfigView uint32 `json:"chain_config_view"`
+}
+
+func (msg *chainConfigFetchMsg) Type() MsgType {
+	return ChainConfigFetchMessage
+}
+
+func (msg *chainConfigFetchMsg) Verify(pub keypair.PublicKey) error {
+	return nil
+}
+
+func (msg *chainConfigFetchMsg) GetBlockNum() uint32 {
+	return 0
+}
+
+func (msg *chainConfigFetchMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// config block is verified with the signatures of block header
+type ChainConfigFetchRespMsg struct {
+	ChainConfigView uint32 `json:"chain_config_view"`
+	BlockData       *Block `json:"block_data"`
+}
+
+func (msg *ChainConfigFetchRespMsg) Type() MsgType {
+	return ChainConfigFetchRespMessage
+}
+
+func (msg *ChainConfigFetchRespMsg) Verify(pub keypair.PublicKey) error {
+	return nil
+}
+
+func (msg *ChainConfigFetchRespMsg) GetBlockNum() uint32 {
+	return 0
+}
+
+// only header of config block is sent, block body may have been pruned by ledger
+func (msg *ChainConfigFetchRespMsg) Serialize() ([]byte, error) {
+	if msg.BlockData == nil || msg.BlockData.Block == nil {
+		return nil, fmt.Errorf("nil config block")
+	}
+	buffer := bytes.NewBuffer([]byte{})
+	serialization.WriteUint32(buffer, msg.ChainConfigView)
+	buffer.Write(msg.BlockData.Block.Header.ToArray())
+	return buffer.Bytes(), nil
+}
+
+func (msg *ChainConfigFetchRespMsg) Deserialize(data []byte) error {
+	buffer := bytes.NewBuffer(data)
+	view, err := serialization.ReadUint32(buffer)
+	if err != nil {
+		return err
+	}
+	msg.ChainConfigView = view
+	header, err := types.HeaderFromRawBytes(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("unmarshal block header: %s", err)
+	}
+	blk, err := initVbftBlock(&types.Block{Header: header}, common.Uint256{})
+	if err != nil {
+		return err
+	}
+	msg.BlockData = blk
+	return nil
+}
+
+// emergency rotation vote msg is to vote rotating crashed peer out of consensus at next block
+type emergencyRotationVoteMsg struct {
+	ShardID    uint64 `json:"shard_id"`
+	View       uint32 `json:"view"`
+	PeerPubkey string `json:"peer_pubkey"`
+	Sig        []byte `json:"sig"`
+}
+
+func (msg *emergencyRotationVoteMsg) Type() MsgType {
+	return EmergencyRotationVoteMessage
+}
+
+func (msg *emergencyRotationVoteMsg) Verify(pub keypair.PublicKey) error {
+	hash := nutils.RotationVoteHash(common.NewShardIDUnchecked(msg.ShardID), msg.View, msg.PeerPubkey)
+	sig, err := signature.Deserialize(msg.Sig)
+	if err != nil {
+		return fmt.Errorf("deserialize vote sig: %s", err)
+	}
+	if !signature.Verify(pub, hash[:], sig) {
+		return fmt.Errorf("failed to verify vote sig")
+	}
+	return nil
+}
+
+func (msg *emergencyRotationVoteMsg) GetBlockNum() uint32 {
+	return 0
+}
+
+func (msg *emergencyRotationVoteMsg) Serialize() ([]byte, error) {
+	return json.Marshal(msg)
+}
@@ -441,3 +441,31 @@ func TestBlockFetchRespMsgDeserialize(t *testing.T) {
 	}
 	t.Logf("BlockFetchRespMsg Serialize succ: %v\n", respmsg.BlockNumber)
 }
+
+func TestChainConfigFetchRespMsgDeserialize(t *testing.T) {
+	blk, err := constructBlock()
+	if err != nil {
+		t.Errorf("constructBlock failed: %v", err)
+		return
+	}
+	configfetchrespmsg := &ChainConfigFetchRespMsg{
+		ChainConfigView: 2,
+		BlockData:       blk,
+	}
+	msg, err := configfetchrespmsg.Serialize()
+	if err != nil {
+		t.Errorf("ChainConfigFetchRespMsg Serialize failed: %v", err)
+		return
+	}
+	respmsg := &ChainConfigFetchRespMsg{}
+	err = respmsg.Deserialize(msg)
+	if err != nil {
+		t.Errorf("ChainConfigFetchRespMsg Deserialize failed: %v", err)
+		return
+	}
+	if respmsg.ChainConfigView != 2 || respmsg.BlockData.Block.Hash() != blk.Block.Hash() {
+		t.Errorf("ChainConfigFetchRespMsg Deserialize unmatch")
+		return
+	}
+	t.Logf("ChainConfigFetchRespMsg Deserialize succ: %v\n", respmsg.ChainConfigView)
+}
@@ -21,7 +21,6 @@ package vbft
 import (
 	"fmt"
 	"sync"
-	"time"
 
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
@@ -447,7 +446,10 @@ func (self *PeerSyncer) requestBlock(blkNum uint32) (*Block, error) {
 		Msg:    msg,
 	}
 
-	t := time.NewTimer(makeProposalTimeout * 2)
+	timeoutC := make(chan struct{})
+	t := self.server.afterFunc(makeProposalTimeout*2, func() {
+		close(timeoutC)
+	})
 	defer t.Stop()
 
 	select {
@@ -463,7 +465,7 @@ func (self *PeerSyncer) requestBlock(blkNum uint32) (*Block, error) {
 			}
 			return pMsg.BlockData, nil
 		}
-	case <-t.C:
+	case <-timeoutC:
 		return nil, fmt.Errorf("timeout fetch block %d from peer %d", blkNum, self.peerIdx)
 	case <-self.server.quitC:
 		return nil, fmt.Errorf("peer syncing %d quit, failed fetching Block %d", self.peerIdx, blkNum)
@@ -478,7 +480,10 @@ func (self *PeerSyncer) requestBlockInfo(startBlkNum uint32) ([]*BlockInfo_, err
 		Msg:    msg,
 	}
 
-	t := time.NewTimer(makeProposalTimeout * 2)
+	timeoutC := make(chan struct{})
+	t := self.server.afterFunc(makeProposalTimeout*2, func() {
+		close(timeoutC)
+	})
 	defer t.Stop()
 
 	select {
@@ -494,7 +499,7 @@ func (self *PeerSyncer) requestBlockInfo(startBlkNum uint32) ([]*BlockInfo_, err
 			}
 			return pMsg.Blocks, nil
 		}
-	case <-t.C:
+	case <-timeoutC:
 		return nil, fmt.Errorf("timeout fetch blockInfo %d from peer %d", startBlkNum, self.peerIdx)
 	case <-self.server.quitC:
 		return nil, fmt.Errorf("peer syncer %d - %d quit, failed fetching BlockInfo %d",
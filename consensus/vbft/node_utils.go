@@ -28,7 +28,6 @@ import (
 	"github.com/ontio/ontology/consensus/utils"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/signature"
-	com "github.com/ontio/ontology/core/store/common"
 	msgpack "github.com/ontio/ontology/p2pserver/message/msg_pack"
 	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
 )
@@ -368,7 +367,20 @@ func (self *Server) findBlockProposal(blkNum uint32, proposer uint32, forEmpty b
 }
 
 func (self *Server) validateTxsInProposal(proposal *blockProposalMsg) error {
-	// TODO: add VBFT specific verifications
+	// sys txs are packed in both block and empty block
+	emptyBlk := proposal.Block.EmptyBlock
+	if emptyBlk == nil {
+		return nil
+	}
+	txs := proposal.Block.Block.Transactions
+	if len(emptyBlk.Transactions) > len(txs) {
+		return fmt.Errorf("empty block has more txs (%d) than block (%d)", len(emptyBlk.Transactions), len(txs))
+	}
+	for i, tx := range emptyBlk.Transactions {
+		if tx.Hash() != txs[i].Hash() {
+			return fmt.Errorf("tx %d of empty block not in block", i)
+		}
+	}
 	return nil
 }
 
@@ -466,42 +478,95 @@ func (self *Server) SendCrossShardMsgToAll(height uint32) {
 
 	msgs := self.chainStore.GetExecShardNotify(height - 1)
 
-	crossShardMsgs, hashRoot, err := utils.BuildCrossShardMsgs(self.account, self.ledger, height, msgs, crossShardMsgHash)
-	if err != nil {
-		log.Errorf("%s", err)
-		return
-	}
+	utils.SendCrossShardMsgs(self.account, self.ledger, self.p2p, height, msgs, crossShardMsgHash)
+}
 
-	for targetShardID, msg := range crossShardMsgs {
-		if targetShardID.ParentID() == self.ShardID {
-			continue
+// findChainConfigBlock: walk back through config blocks, find the block which updated chain config to view.
+// Only headers are read, config blocks may have been pruned by ledger
+func (self *Server) findChainConfigBlock(view uint32) (*Block, error) {
+	self.metaLock.RLock()
+	blkNum := self.LastConfigBlockNum
+	self.metaLock.RUnlock()
+
+	for blkNum != math.MaxUint32 {
+		blk, err := self.blockPool.getSealedBlockHeader(blkNum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config block %d: %s", blkNum, err)
+		}
+		cfg := blk.getNewChainConfig()
+		if cfg == nil {
+			return nil, fmt.Errorf("block %d has no chain config", blkNum)
+		}
+		if cfg.View == view {
+			return blk, nil
 		}
-		// get last shard-msg-root of the target shard
-		prevMsgHash, err := self.ledger.GetShardMsgHash(targetShardID)
-		if err != nil && err != com.ErrNotFound {
-			log.Errorf("SendCrossShardMsgToAll getshardmsghash err:%s", err)
-			return
+		if cfg.View < view || blkNum == 0 {
+			break
 		}
-		// save shard-msg-root
-		err = self.ledger.SaveShardMsgHash(targetShardID, hashRoot)
+		prevBlk, err := self.blockPool.getSealedBlockHeader(blkNum - 1)
 		if err != nil {
-			log.Errorf("SaveShardMsgHash shardID:%v,msgHash:%s,err:%s", targetShardID, hashRoot.ToHexString(), err)
-			return
+			return nil, fmt.Errorf("failed to get block %d: %s", blkNum-1, err)
 		}
-		// save cross-shard-msg
-		err = self.ledger.SaveCrossShardMsgByHash(prevMsgHash, msg)
+		if prevBlk.getNewChainConfig() != nil {
+			blkNum = prevBlk.getBlockNum()
+		} else {
+			blkNum = prevBlk.getLastConfigBlockNum()
+		}
+	}
+	return nil, fmt.Errorf("chain config view %d not found", view)
+}
+
+// verifyChainConfigBlock: config block from peers should be the next chain config of local node,
+// and signed by consensus peers of current chain config
+func (self *Server) verifyChainConfigBlock(blk *Block) error {
+	if blk == nil || blk.Block == nil || blk.Info == nil {
+		return fmt.Errorf("invalid config block")
+	}
+	cfg := blk.getNewChainConfig()
+	if cfg == nil {
+		return fmt.Errorf("block %d has no chain config", blk.getBlockNum())
+	}
+
+	// config fetched before is the base of next config, even if local node hasn't synced to it
+	currentCfg, lastConfigBlkNum := self.getLatestChainConfig()
+
+	if cfg.View != currentCfg.View+1 {
+		return fmt.Errorf("unexpected chain config view %d, current view %d", cfg.View, currentCfg.View)
+	}
+	blkNum := blk.getBlockNum()
+	if lastConfigBlkNum != math.MaxUint32 && blkNum <= lastConfigBlkNum {
+		return fmt.Errorf("config block %d is not after last config block %d", blkNum, lastConfigBlkNum)
+	}
+	if blkNum <= self.GetCommittedBlockNo() {
+		// block has been committed locally
+		localBlk, err := self.blockPool.getSealedBlockHeader(blkNum)
 		if err != nil {
-			log.Errorf("SaveCrossShardMsgByHash preMsgHash:%s,err:%s", prevMsgHash.ToHexString(), err)
-			return
+			return fmt.Errorf("failed to get local block %d: %s", blkNum, err)
 		}
-		// broadcast
-		sink := common.ZeroCopySink{}
-		msg.Serialization(&sink)
-		msg := &p2pmsg.CrossShardPayload{
-			Version: common.VERSION_SUPPORT_SHARD,
-			ShardID: targetShardID,
-			Data:    sink.Bytes(),
+		if localBlk.Block.Hash() != blk.Block.Hash() {
+			return fmt.Errorf("config block %d unmatch with local ledger", blkNum)
 		}
-		self.p2p.Broadcast(msg)
+		return nil
 	}
+
+	// same verification as ledger does on vbft block header
+	peers := make(map[string]bool)
+	for _, p := range currentCfg.Peers {
+		peers[p.ID] = true
+	}
+	header := blk.Block.Header
+	m := len(peers) - (len(peers)*6)/7
+	if len(header.Bookkeepers) < m {
+		return fmt.Errorf("header bookkeepers %d less than %d", len(header.Bookkeepers), m)
+	}
+	for _, bookkeeper := range header.Bookkeepers {
+		if !peers[vconfig.PubkeyID(bookkeeper)] {
+			return fmt.Errorf("invalid bookkeeper %s", vconfig.PubkeyID(bookkeeper))
+		}
+	}
+	hash := header.Hash()
+	if err := signature.VerifyMultiSignature(hash[:], header.Bookkeepers, m, header.SigData); err != nil {
+		return fmt.Errorf("verify header signature: %s", err)
+	}
+	return nil
 }
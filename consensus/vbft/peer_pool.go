@@ -142,7 +142,7 @@ func (pool *PeerPool) peerConnected(peerIdx uint32) {
 	pool.peers[peerIdx] = &Peer{
 		Index:          peerIdx,
 		PubKey:         pool.peers[peerIdx].PubKey,
-		LastUpdateTime: time.Now(),
+		LastUpdateTime: pool.server.now(),
 		connected:      true,
 	}
 	if C, present := pool.peerConnectionWaitings[peerIdx]; present {
@@ -177,7 +177,7 @@ func (pool *PeerPool) peerHandshake(peerIdx uint32, msg *peerHandshakeMsg) {
 		PubKey:         pool.peers[peerIdx].PubKey,
 		handShake:      msg,
 		LatestInfo:     pool.peers[peerIdx].LatestInfo,
-		LastUpdateTime: time.Now(),
+		LastUpdateTime: pool.server.now(),
 		connected:      true,
 	}
 }
@@ -197,7 +197,7 @@ func (pool *PeerPool) peerHeartbeat(peerIdx uint32, msg *peerHeartbeatMsg) {
 		PubKey:         pool.peers[peerIdx].PubKey,
 		handShake:      pool.peers[peerIdx].handShake,
 		LatestInfo:     msg,
-		LastUpdateTime: time.Now(),
+		LastUpdateTime: pool.server.now(),
 		connected:      true,
 	}
 }
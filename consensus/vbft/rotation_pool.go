@@ -0,0 +1,356 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+// RotationPool keeps votes of rotating crashed peers out of shard consensus.
+// Votes are only valid in the chain config they are voted in, the pool is reset when chain config updated.
+type RotationPool struct {
+	lock       sync.RWMutex
+	server     *Server
+	configHash common.Uint256
+	startTime  time.Time                                  // peers never connected are checked from start time
+	votes      map[string]map[string]*nutils.RotationVote // rotated peer => voter => vote
+	selfVotes  map[string]*emergencyRotationVoteMsg       // votes of self, rebroadcast until rotated
+}
+
+func newRotationPool(server *Server) *RotationPool {
+	return &RotationPool{
+		server:    server,
+		startTime: server.now(),
+		votes:     make(map[string]map[string]*nutils.RotationVote),
+		selfVotes: make(map[string]*emergencyRotationVoteMsg),
+	}
+}
+
+func (pool *RotationPool) reset(cfg *vconfig.ChainConfig) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	cfgHash := cfg.Hash()
+	if pool.configHash == cfgHash {
+		return
+	}
+	pool.configHash = cfgHash
+	pool.startTime = pool.server.now()
+	pool.votes = make(map[string]map[string]*nutils.RotationVote)
+	pool.selfVotes = make(map[string]*emergencyRotationVoteMsg)
+}
+
+func (pool *RotationPool) addVote(peer string, vote *nutils.RotationVote) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if _, present := pool.votes[peer]; !present {
+		pool.votes[peer] = make(map[string]*nutils.RotationVote)
+	}
+	pool.votes[peer][vote.Voter] = vote
+}
+
+func (pool *RotationPool) addSelfVote(msg *emergencyRotationVoteMsg) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.selfVotes[msg.PeerPubkey] = msg
+}
+
+func (pool *RotationPool) hasSelfVote(peer string) bool {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	_, present := pool.selfVotes[peer]
+	return present
+}
+
+func (pool *RotationPool) getSelfVotes() []*emergencyRotationVoteMsg {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	msgs := make([]*emergencyRotationVoteMsg, 0, len(pool.selfVotes))
+	for _, msg := range pool.selfVotes {
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// getRotation: get rotation of the first peer with votes from 2/3 of peers
+func (pool *RotationPool) getRotation(shardID common.ShardID, view uint32, peerCount int) *nutils.EmergencyRotation {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	rotatedPeers := make([]string, 0)
+	for peer, votes := range pool.votes {
+		if 3*len(votes) >= 2*peerCount {
+			rotatedPeers = append(rotatedPeers, peer)
+		}
+	}
+	if len(rotatedPeers) == 0 {
+		return nil
+	}
+	sort.Strings(rotatedPeers)
+	peer := rotatedPeers[0]
+	rotation := &nutils.EmergencyRotation{
+		ShardID:    shardID,
+		View:       view,
+		PeerPubkey: peer,
+		Votes:      make([]*nutils.RotationVote, 0),
+	}
+	for _, vote := range pool.votes[peer] {
+		rotation.Votes = append(rotation.Votes, vote)
+	}
+	sort.Slice(rotation.Votes, func(i, j int) bool {
+		return rotation.Votes[i].Voter < rotation.Votes[j].Voter
+	})
+	return rotation
+}
+
+func (self *Server) emergencyRotationEnabled() bool {
+	return !self.ShardID.IsRootShard() && config.DefConfig.Consensus.EmergencyRotationTimeout > 0
+}
+
+func chainConfigPeers(cfg *vconfig.ChainConfig) []string {
+	peers := make([]string, 0, len(cfg.Peers))
+	for _, p := range cfg.Peers {
+		peers = append(peers, p.ID)
+	}
+	return peers
+}
+
+// checkCrashedPeers: vote to rotate out consensus peers without heartbeat in emergency rotation timeout,
+// votes not rotated yet are rebroadcast in case of msg loss
+func (self *Server) checkCrashedPeers() {
+	if !self.emergencyRotationEnabled() || self.nonConsensusNode() {
+		return
+	}
+	self.metaLock.RLock()
+	cfg := self.config
+	self.metaLock.RUnlock()
+	self.rotationPool.reset(cfg)
+
+	for _, msg := range self.rotationPool.getSelfVotes() {
+		self.broadcast(msg)
+	}
+
+	timeout := time.Duration(config.DefConfig.Consensus.EmergencyRotationTimeout) * time.Second
+	now := self.now()
+	for _, p := range cfg.Peers {
+		if p.Index == self.Index || self.rotationPool.hasSelfVote(p.ID) {
+			continue
+		}
+		peer := self.peerPool.getPeer(p.Index)
+		if peer == nil {
+			continue
+		}
+		lastSeen := peer.LastUpdateTime
+		if lastSeen.Before(self.rotationPool.startTime) {
+			lastSeen = self.rotationPool.startTime
+		}
+		if now.Sub(lastSeen) < timeout {
+			continue
+		}
+		if _, err := rotatedChainConfig(cfg, p.ID); err != nil {
+			log.Warnf("server %d, peer %d crashed, but cannot be rotated out: %s", self.Index, p.Index, err)
+			continue
+		}
+		msg, err := self.constructEmergencyRotationVoteMsg(cfg.View, p.ID)
+		if err != nil {
+			log.Errorf("server %d, failed to build rotation vote for %d: %s", self.Index, p.Index, err)
+			continue
+		}
+		log.Warnf("server %d, peer %d crashed, vote to rotate it out in view %d", self.Index, p.Index, cfg.View)
+		self.rotationPool.addSelfVote(msg)
+		self.onEmergencyRotationVote(self.Index, msg)
+		self.broadcast(msg)
+	}
+}
+
+func (self *Server) constructEmergencyRotationVoteMsg(view uint32, peer string) (*emergencyRotationVoteMsg, error) {
+	hash := nutils.RotationVoteHash(self.ShardID, view, peer)
+	sig, err := signature.Sign(self.account, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign rotation vote: %s", err)
+	}
+	return &emergencyRotationVoteMsg{
+		ShardID:    self.ShardID.ToUint64(),
+		View:       view,
+		PeerPubkey: peer,
+		Sig:        sig,
+	}, nil
+}
+
+// onEmergencyRotationVote: msg signature has been verified with pubkey of voter
+func (self *Server) onEmergencyRotationVote(peerIdx uint32, msg *emergencyRotationVoteMsg) {
+	if self.ShardID.IsRootShard() || msg.ShardID != self.ShardID.ToUint64() {
+		return
+	}
+	self.metaLock.RLock()
+	cfg := self.config
+	self.metaLock.RUnlock()
+	if msg.View != cfg.View {
+		log.Infof("server %d, rotation vote from %d with view %d, current view %d",
+			self.Index, peerIdx, msg.View, cfg.View)
+		return
+	}
+	voter := ""
+	rotated := false
+	for _, p := range cfg.Peers {
+		if p.Index == peerIdx {
+			voter = p.ID
+		}
+		if p.ID == msg.PeerPubkey {
+			rotated = true
+		}
+	}
+	if voter == "" || !rotated || voter == msg.PeerPubkey {
+		log.Errorf("server %d, invalid rotation vote from %d", self.Index, peerIdx)
+		return
+	}
+	self.rotationPool.reset(cfg)
+	self.rotationPool.addVote(msg.PeerPubkey, &nutils.RotationVote{
+		Voter: voter,
+		Sig:   msg.Sig,
+	})
+}
+
+// getEmergencyRotation: get rotation with enough votes in current chain config
+func (self *Server) getEmergencyRotation() (*nutils.EmergencyRotation, *vconfig.ChainConfig) {
+	if self.ShardID.IsRootShard() {
+		return nil, nil
+	}
+	self.metaLock.RLock()
+	cfg := self.config
+	self.metaLock.RUnlock()
+	self.rotationPool.reset(cfg)
+
+	rotation := self.rotationPool.getRotation(self.ShardID, cfg.View, len(cfg.Peers))
+	if rotation == nil {
+		return nil, nil
+	}
+	if err := rotation.Verify(chainConfigPeers(cfg)); err != nil {
+		log.Errorf("server %d, invalid emergency rotation: %s", self.Index, err)
+		return nil, nil
+	}
+	newCfg, err := rotatedChainConfig(cfg, rotation.PeerPubkey)
+	if err != nil {
+		log.Errorf("server %d, rotate peer out: %s", self.Index, err)
+		return nil, nil
+	}
+	return rotation, newCfg
+}
+
+// verifyEmergencyRotation: rotation in proposal must have enough votes, and chain config is rotated with it
+func (self *Server) verifyEmergencyRotation(msg *blockProposalMsg) error {
+	info := msg.Block.Info
+	if len(info.EmergencyRotation) == 0 {
+		return nil
+	}
+	if self.ShardID.IsRootShard() {
+		return fmt.Errorf("emergency rotation at root shard")
+	}
+	rotation := &nutils.EmergencyRotation{}
+	if err := rotation.Deserialize(bytes.NewReader(info.EmergencyRotation)); err != nil {
+		return fmt.Errorf("deserialize rotation: %s", err)
+	}
+	self.metaLock.RLock()
+	cfg := self.config
+	self.metaLock.RUnlock()
+	if rotation.ShardID != self.ShardID || rotation.View != cfg.View {
+		return fmt.Errorf("rotation shard %d view %d unmatch", rotation.ShardID.ToUint64(), rotation.View)
+	}
+	if err := rotation.Verify(chainConfigPeers(cfg)); err != nil {
+		return err
+	}
+	newCfg, err := rotatedChainConfig(cfg, rotation.PeerPubkey)
+	if err != nil {
+		return err
+	}
+	if info.NewChainConfig == nil || info.NewChainConfig.Hash() != newCfg.Hash() {
+		return fmt.Errorf("chain config unmatch with rotation")
+	}
+	return nil
+}
+
+// rotatedChainConfig: remove peer from chain config, the network size and pos table shrink,
+// and the fault tolerance is decreased if needed. Peer set is changed, so view is increased.
+func rotatedChainConfig(cfg *vconfig.ChainConfig, peer string) (*vconfig.ChainConfig, error) {
+	var peerIdx uint32
+	found := false
+	peers := make([]*vconfig.PeerConfig, 0, len(cfg.Peers))
+	for _, p := range cfg.Peers {
+		if p.ID == peer {
+			peerIdx = p.Index
+			found = true
+			continue
+		}
+		peers = append(peers, &vconfig.PeerConfig{Index: p.Index, ID: p.ID})
+	}
+	if !found {
+		return nil, fmt.Errorf("peer %s not in chain config", peer)
+	}
+	n := uint32(len(peers))
+	c := cfg.C
+	if n < 3*c+1 {
+		c = (n - 1) / 3
+	}
+	if c == 0 {
+		return nil, fmt.Errorf("too few peers after rotation: %d", n)
+	}
+	posTable := make([]uint32, 0, len(cfg.PosTable))
+	for _, idx := range cfg.PosTable {
+		if idx != peerIdx {
+			posTable = append(posTable, idx)
+		}
+	}
+	return &vconfig.ChainConfig{
+		Version:              cfg.Version,
+		View:                 cfg.View + 1,
+		N:                    n,
+		C:                    c,
+		BlockMsgDelay:        cfg.BlockMsgDelay,
+		HashMsgDelay:         cfg.HashMsgDelay,
+		PeerHandshakeTimeout: cfg.PeerHandshakeTimeout,
+		Peers:                peers,
+		PosTable:             posTable,
+		MaxBlockChangeView:   cfg.MaxBlockChangeView,
+	}, nil
+}
+
+// createEmergencyRotationTransaction: inform parent shard of the rotation through shard management contract
+func (self *Server) createEmergencyRotationTransaction(blkNum uint32, rotation []byte) (*types.Transaction, error) {
+	mutable := utils.BuildNativeTransaction(nutils.ShardMgmtContractAddress, shardmgmt.NOTIFY_PARENT_EMERGENCY_ROTATION, rotation)
+	mutable.Nonce = blkNum
+	return self.signSysTransaction(mutable)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vbft
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+func constructRotationChainConfig(n uint32) *vconfig.ChainConfig {
+	cfg := &vconfig.ChainConfig{
+		Version:  1,
+		View:     3,
+		N:        n,
+		C:        (n - 1) / 3,
+		PosTable: make([]uint32, 0),
+	}
+	for i := uint32(1); i <= n; i++ {
+		cfg.Peers = append(cfg.Peers, &vconfig.PeerConfig{Index: i, ID: string(rune('a' + i))})
+		cfg.PosTable = append(cfg.PosTable, i, i)
+	}
+	return cfg
+}
+
+func TestRotatedChainConfig(t *testing.T) {
+	cfg := constructRotationChainConfig(7)
+	rotated, err := rotatedChainConfig(cfg, cfg.Peers[2].ID)
+	if err != nil {
+		t.Fatalf("rotate peer: %s", err)
+	}
+	if rotated.N != 6 || len(rotated.Peers) != 6 || rotated.C != 1 || rotated.View != cfg.View+1 {
+		t.Fatalf("invalid rotated config: N %d, C %d, view %d", rotated.N, rotated.C, rotated.View)
+	}
+	for _, idx := range rotated.PosTable {
+		if idx == cfg.Peers[2].Index {
+			t.Fatalf("rotated peer %d still in pos table", idx)
+		}
+	}
+	if len(cfg.Peers) != 7 {
+		t.Fatalf("original config modified")
+	}
+	if _, err := rotatedChainConfig(cfg, "unknown"); err == nil {
+		t.Fatalf("rotate unknown peer should fail")
+	}
+	if _, err := rotatedChainConfig(constructRotationChainConfig(4), "b"); err == nil {
+		t.Fatalf("rotate peer from 4 peers should fail")
+	}
+}
+
+func TestRotationPoolQuorum(t *testing.T) {
+	cfg := constructRotationChainConfig(7)
+	shardID := common.NewShardIDUnchecked(1)
+	pool := newRotationPool(nil)
+	pool.reset(cfg)
+	for i := 0; i < 4; i++ {
+		pool.addVote("c", &nutils.RotationVote{Voter: cfg.Peers[i+3].ID})
+	}
+	if rotation := pool.getRotation(shardID, cfg.View, len(cfg.Peers)); rotation != nil {
+		t.Fatalf("rotation without enough votes")
+	}
+	pool.addVote("c", &nutils.RotationVote{Voter: cfg.Peers[0].ID})
+	rotation := pool.getRotation(shardID, cfg.View, len(cfg.Peers))
+	if rotation == nil || rotation.PeerPubkey != "c" || len(rotation.Votes) != 5 {
+		t.Fatalf("failed to get rotation")
+	}
+
+	// votes are dropped with chain config updated
+	cfg.View++
+	pool.reset(cfg)
+	if rotation := pool.getRotation(shardID, cfg.View, len(cfg.Peers)); rotation != nil {
+		t.Fatalf("rotation not reset")
+	}
+}
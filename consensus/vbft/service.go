@@ -122,14 +122,24 @@ type Server struct {
 	shardLastConsensusHeight uint32
 	config                   *vconfig.ChainConfig
 	currentParticipantConfig *BlockParticipantConfig
+	fetchedConfigBlock       *Block // newer chain config fetched from peers, applied after synced to the block
 
-	chainStore *ChainStore // block store
-	msgPool    *MsgPool    // consensus msg pool
-	blockPool  *BlockPool  // received block proposals
-	peerPool   *PeerPool   // consensus peers
-	syncer     *Syncer
-	stateMgr   *StateMgr
-	timer      *EventTimer
+	roundMsgsLock sync.Mutex
+	roundMsgs     *RoundConsensusMsgs // persisted self msgs of current round, with proposals they vote for
+
+	chainStore   *ChainStore   // block store
+	msgPool      *MsgPool      // consensus msg pool
+	blockPool    *BlockPool    // received block proposals
+	faultyPool   *FaultyPool   // evidences of faulty peers
+	rotationPool *RotationPool // votes of rotating crashed peers out
+	peerPool     *PeerPool     // consensus peers
+	syncer       *Syncer
+	stateMgr     *StateMgr
+	timer        *EventTimer
+
+	roundMetrics roundMetrics
+	clock        Clock          // system clock if nil
+	byzantine    byzantineState // scripted misbehaviors, only built with byzantine tag
 
 	msgRecvC   map[uint32]chan *p2pMsgPayload
 	msgC       chan ConsensusMsg
@@ -142,6 +152,12 @@ type Server struct {
 }
 
 func NewVbftServer(shardID common.ShardID, account *account.Account, txpool *actor.PID, lgr *ledger.Ledger, p2p *actor.PID) (*Server, error) {
+	return NewVbftServerWithClock(shardID, account, txpool, lgr, p2p, systemClock{})
+}
+
+// NewVbftServerWithClock: new vbft server with all timers driven by clock, used by simulation tests
+func NewVbftServerWithClock(shardID common.ShardID, account *account.Account, txpool *actor.PID, lgr *ledger.Ledger,
+	p2p *actor.PID, clock Clock) (*Server, error) {
 	if account == nil {
 		return nil, fmt.Errorf("new vbft service with nil account")
 	}
@@ -154,6 +170,7 @@ func NewVbftServer(shardID common.ShardID, account *account.Account, txpool *act
 		poolActor:          &actorTypes.TxPoolActor{Pool: txpool},
 		p2p:                &actorTypes.P2PActor{P2P: p2p},
 		incrValidator:      increment.NewIncrementValidator(20),
+		clock:              clock,
 	}
 	server.stateMgr = newStateMgr(server)
 
@@ -256,6 +273,11 @@ func (self *Server) handleBlockPersistCompleted(block *types.Block) {
 			} else {
 				self.shardLastConsensusHeight = height
 			}
+		} else if blk, _ := self.blockPool.getSealedBlock(self.completedBlockNum); blk != nil && len(blk.Info.EmergencyRotation) > 0 {
+			// crashed peer rotated out by shard peers, root shard is informed by the rotation tx
+			if err := self.updateChainConfig(); err != nil {
+				log.Errorf("update shard ChainConfig with emergency rotation failed:%s", err)
+			}
 		}
 	}
 }
@@ -312,7 +334,8 @@ func (self *Server) NewConsensusPayload(payload *p2pmsg.ConsensusPayload) {
 func (self *Server) LoadChainConfig(chainStore *ChainStore) error {
 	//get chainconfig from genesis block
 
-	block, err := chainStore.GetBlock(chainStore.GetChainedBlockNum())
+	// read from headers, block bodies may have been pruned by ledger
+	block, err := chainStore.GetBlockHeader(chainStore.GetChainedBlockNum())
 	if err != nil {
 		return err
 	}
@@ -325,7 +348,7 @@ func (self *Server) LoadChainConfig(chainStore *ChainStore) error {
 	} else {
 		cfgBlock := block
 		if block.getLastConfigBlockNum() != math.MaxUint32 {
-			cfgBlock, err = chainStore.GetBlock(block.getLastConfigBlockNum())
+			cfgBlock, err = chainStore.GetBlockHeader(block.getLastConfigBlockNum())
 			if err != nil {
 				return fmt.Errorf("failed to get cfg block: %s", err)
 			}
@@ -353,7 +376,6 @@ func (self *Server) LoadChainConfig(chainStore *ChainStore) error {
 	commitBlockTimeout = time.Duration(self.config.HashMsgDelay * 3)
 	peerHandshakeTimeout = time.Duration(self.config.PeerHandshakeTimeout)
 	zeroTxBlockTimeout = time.Duration(self.config.BlockMsgDelay * 3)
-	// TODO: load sealed blocks from chainStore
 
 	// protected by server.metaLock
 	self.completedBlockNum = self.GetCommittedBlockNo()
@@ -370,9 +392,166 @@ func (self *Server) LoadChainConfig(chainStore *ChainStore) error {
 	if err != nil {
 		return fmt.Errorf("failed to build participant config: %s", err)
 	}
+
+	// load consensus msgs of last sealed block from chainStore
+	self.loadBlockConsensusMsgs(self.GetCommittedBlockNo())
 	return nil
 }
 
+// persistBlockConsensusMsgs: save the endorsements and commits on the sealed block
+func (self *Server) persistBlockConsensusMsgs(blkNum uint32) error {
+	_, blkHash := self.blockPool.getSealedBlock(blkNum)
+	msgs := &BlockConsensusMsgs{
+		BlockNum:     blkNum,
+		BlockHash:    blkHash,
+		Endorsements: make([]*blockEndorseMsg, 0),
+		Commits:      make([]*blockCommitMsg, 0),
+	}
+	for _, msg := range self.msgPool.GetEndorsementsMsgs(blkNum) {
+		if e, ok := msg.(*blockEndorseMsg); ok && e.EndorsedBlockHash == blkHash {
+			msgs.Endorsements = append(msgs.Endorsements, e)
+		}
+	}
+	for _, msg := range self.msgPool.GetCommitMsgs(blkNum) {
+		if c, ok := msg.(*blockCommitMsg); ok && c.CommitBlockHash == blkHash {
+			msgs.Commits = append(msgs.Commits, c)
+		}
+	}
+	if len(msgs.Endorsements) == 0 && len(msgs.Commits) == 0 {
+		// block synced from peers
+		return nil
+	}
+	return self.chainStore.SaveBlockConsensusMsgs(msgs)
+}
+
+// loadBlockConsensusMsgs: reload persisted consensus msgs to msg pool after restarted
+func (self *Server) loadBlockConsensusMsgs(blkNum uint32) {
+	msgs, err := self.chainStore.GetBlockConsensusMsgs(blkNum)
+	if err != nil {
+		if err != com.ErrNotFound {
+			log.Errorf("server %d, load consensus msgs of block %d: %s", self.Index, blkNum, err)
+		}
+		return
+	}
+	for _, e := range msgs.Endorsements {
+		if h, err := HashMsg(e); err == nil {
+			self.msgPool.AddMsg(e, h)
+		}
+	}
+	for _, c := range msgs.Commits {
+		if h, err := HashMsg(c); err == nil {
+			self.msgPool.AddMsg(c, h)
+		}
+	}
+	log.Infof("server %d, loaded consensus msgs of block %d, endorsements %d, commits %d",
+		self.Index, blkNum, len(msgs.Endorsements), len(msgs.Commits))
+}
+
+// persistRoundConsensusMsgs: save self msgs of current round, with the proposals they vote for,
+// should be done before broadcasting self msgs. Msgs received from peers are not persisted,
+// they are requested from peers again after restart
+func (self *Server) persistRoundConsensusMsgs(blkNum uint32, roundMsgs ...ConsensusMsg) error {
+	self.roundMsgsLock.Lock()
+	defer self.roundMsgsLock.Unlock()
+	if self.roundMsgs == nil || self.roundMsgs.BlockNum != blkNum {
+		self.roundMsgs = &RoundConsensusMsgs{
+			BlockNum: blkNum,
+			Msgs:     make([][]byte, 0),
+		}
+	}
+	added := false
+	for _, msg := range roundMsgs {
+		data, err := SerializeVbftMsg(msg)
+		if err != nil {
+			return fmt.Errorf("serialize msg (type: %d): %s", msg.Type(), err)
+		}
+		if self.roundMsgs.add(data) {
+			added = true
+		}
+	}
+	if !added {
+		return nil
+	}
+	return self.chainStore.SaveRoundConsensusMsgs(self.roundMsgs)
+}
+
+// loadRoundConsensusMsgs: reload persisted consensus msgs of current round after restarted,
+// self endorsement and commitment are restored to block pool, to avoid voting twice in one round
+func (self *Server) loadRoundConsensusMsgs(blkNum uint32) {
+	msgs, err := self.chainStore.GetRoundConsensusMsgs()
+	if err != nil {
+		if err != com.ErrNotFound {
+			log.Errorf("server %d, load consensus msgs of round %d: %s", self.Index, blkNum, err)
+		}
+		return
+	}
+	if msgs.BlockNum != blkNum {
+		// round has finished before restarted
+		return
+	}
+	self.roundMsgsLock.Lock()
+	self.roundMsgs = msgs
+	self.roundMsgsLock.Unlock()
+
+	proposals := make(map[uint32]*blockProposalMsg)
+	selfMsgs := make([]ConsensusMsg, 0)
+	for _, data := range msgs.Msgs {
+		msg, err := DeserializeVbftMsg(data)
+		if err != nil {
+			log.Errorf("server %d, load consensus msg of round %d: %s", self.Index, blkNum, err)
+			continue
+		}
+		h, err := HashMsg(msg)
+		if err != nil {
+			continue
+		}
+		if err := self.msgPool.AddMsg(msg, h); err != nil {
+			log.Errorf("server %d, failed to add msg of round %d to pool: %s", self.Index, blkNum, err)
+			continue
+		}
+		switch m := msg.(type) {
+		case *blockProposalMsg:
+			if err := self.blockPool.newBlockProposal(m); err != nil {
+				log.Errorf("server %d, failed to add proposal of round %d from %d: %s",
+					self.Index, blkNum, m.Block.getProposer(), err)
+				continue
+			}
+			proposals[m.Block.getProposer()] = m
+		case *blockEndorseMsg:
+			if m.Endorser == self.Index {
+				selfMsgs = append(selfMsgs, m)
+			}
+		case *blockCommitMsg:
+			if m.Committer == self.Index {
+				selfMsgs = append(selfMsgs, m)
+			}
+		}
+	}
+
+	for _, msg := range selfMsgs {
+		switch m := msg.(type) {
+		case *blockEndorseMsg:
+			if p := proposals[m.EndorsedProposer]; p != nil {
+				if err := self.blockPool.setProposalEndorsed(p, m.EndorseForEmpty); err != nil {
+					log.Errorf("server %d, restore endorsement of round %d: %s", self.Index, blkNum, err)
+				}
+			}
+		case *blockCommitMsg:
+			if p := proposals[m.BlockProposer]; p != nil {
+				if err := self.blockPool.setProposalCommitted(p, m.CommitForEmpty); err != nil {
+					log.Errorf("server %d, restore commitment of round %d: %s", self.Index, blkNum, err)
+				}
+			}
+		}
+	}
+	log.Infof("server %d, loaded consensus msgs of round %d, msgs %d", self.Index, blkNum, len(msgs.Msgs))
+}
+
+// GetBlockConsensusMsgs returns the persisted endorsements and commits of block, to prove finality of block
+func (self *Server) GetBlockConsensusMsgs(blkNum uint32) (*BlockConsensusMsgs, error) {
+	return self.chainStore.GetBlockConsensusMsgs(blkNum)
+}
+
 func (self *Server) nonConsensusNode() bool {
 	return self.Index == math.MaxUint32
 }
@@ -387,6 +566,11 @@ func (self *Server) updateChainConfig() error {
 		return fmt.Errorf("GetNewChainConfig nil,%d", self.completedBlockNum)
 	}
 	log.Infof("updateChainConfig blkNum:%d", self.completedBlockNum)
+	return self.applyChainConfig(block)
+}
+
+// applyChainConfig: update chain config and consensus peers with the config block
+func (self *Server) applyChainConfig(block *Block) error {
 	self.metaLock.Lock()
 	self.config = block.Info.NewChainConfig
 	self.LastConfigBlockNum = block.getLastConfigBlockNum()
@@ -407,6 +591,32 @@ func (self *Server) updateChainConfig() error {
 			self.Index = p.Index
 			log.Infof("updateChainConfig add index :%d", self.Index)
 		}
+	}
+	if err := self.addChainConfigPeers(self.config); err != nil {
+		return err
+	}
+	for index, peer := range self.peerPool.peers {
+		_, present := peermap[index]
+		if !present {
+			if index == self.Index {
+				self.Index = math.MaxUint32
+				log.Infof("updateChainConfig remove index :%d", index)
+			} else {
+				if C, present := self.msgRecvC[index]; present {
+					pubkey := vconfig.PubkeyID(peer.PubKey)
+					self.peerPool.RemovePeerIndex(pubkey)
+					log.Infof("updateChainConfig remove consensus:index:%d,id:%v", index, pubkey)
+					C <- nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addChainConfigPeers: connect to peers of chain config which are not in peer pool, caller holds metaLock
+func (self *Server) addChainConfigPeers(cfg *vconfig.ChainConfig) error {
+	for _, p := range cfg.Peers {
 		_, present := self.peerPool.GetPeerIndex(p.ID)
 		if !present {
 			// check if peer pubkey support VRF
@@ -437,23 +647,37 @@ func (self *Server) updateChainConfig() error {
 			log.Infof("updateChainConfig add peer index:%v,id:%v", p.ID, p.Index)
 		}
 	}
-	for index, peer := range self.peerPool.peers {
-		_, present := peermap[index]
-		if !present {
-			if index == self.Index {
-				self.Index = math.MaxUint32
-				log.Infof("updateChainConfig remove index :%d", index)
-			} else {
-				if C, present := self.msgRecvC[index]; present {
-					pubkey := vconfig.PubkeyID(peer.PubKey)
-					self.peerPool.RemovePeerIndex(pubkey)
-					log.Infof("updateChainConfig remove consensus:index:%d,id:%v", index, pubkey)
-					C <- nil
-				}
-			}
+	return nil
+}
+
+// setFetchedChainConfig: keep chain config fetched from peers, blocks before the config block are still
+// verified with current chain config, the config is applied while the config block is committed
+func (self *Server) setFetchedChainConfig(block *Block) error {
+	self.metaLock.Lock()
+	self.fetchedConfigBlock = block
+	self.metaLock.Unlock()
+	self.metaLock.RLock()
+	defer self.metaLock.RUnlock()
+	// new peers of chain config may serve the blocks to sync, indexes of current peers are kept
+	// until the config is applied
+	cfg := &vconfig.ChainConfig{}
+	for _, p := range block.getNewChainConfig().Peers {
+		if self.peerPool.getPeer(p.Index) == nil {
+			cfg.Peers = append(cfg.Peers, p)
 		}
 	}
-	return nil
+	return self.addChainConfigPeers(cfg)
+}
+
+// getLatestChainConfig: chain config fetched from peers if it is newer than current one,
+// with block number of the config block
+func (self *Server) getLatestChainConfig() (*vconfig.ChainConfig, uint32) {
+	self.metaLock.RLock()
+	defer self.metaLock.RUnlock()
+	if blk := self.fetchedConfigBlock; blk != nil && blk.getNewChainConfig().View > self.config.View {
+		return blk.getNewChainConfig(), blk.getBlockNum()
+	}
+	return self.config, self.LastConfigBlockNum
 }
 
 func (self *Server) initialize() error {
@@ -477,6 +701,8 @@ func (self *Server) initialize() error {
 		return fmt.Errorf("init blockpool: %s", err)
 	}
 	self.msgPool = newMsgPool(self, self.msgHistoryDuration)
+	self.faultyPool = newFaultyPool(self)
+	self.rotationPool = newRotationPool(self)
 	self.peerPool = NewPeerPool(0, self) // FIXME: maxSize
 	self.timer = NewEventTimer(self)
 	self.syncer = newSyncer(self)
@@ -522,6 +748,8 @@ func (self *Server) initialize() error {
 	} else {
 		self.Index = math.MaxUint32
 	}
+	// resume consensus msgs of current round
+	self.loadRoundConsensusMsgs(self.GetCurrentBlockNo())
 	self.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
 	go self.syncer.run()
 	go self.stateMgr.run()
@@ -701,6 +929,7 @@ func (self *Server) updateParticipantConfig() error {
 
 func (self *Server) startNewRound() error {
 	blkNum := self.GetCurrentBlockNo()
+	self.onRoundStarted(blkNum)
 
 	if err := self.updateParticipantConfig(); err != nil {
 		log.Errorf("startNewRound error:%s", err)
@@ -1112,6 +1341,62 @@ func (self *Server) onConsensusMsg(peerIdx uint32, msg ConsensusMsg, msgHash com
 				forEmpty: false,
 			}
 		}
+	case ChainConfigFetchMessage:
+		pMsg, ok := msg.(*chainConfigFetchMsg)
+		if !ok {
+			log.Errorf("invalid msg with chainconfig fetch msg type")
+			return
+		}
+		blk, err := self.findChainConfigBlock(pMsg.ChainConfigView)
+		if err != nil {
+			log.Errorf("server %d, handle chainconfig fetch view %d from %d: %s",
+				self.Index, pMsg.ChainConfigView, peerIdx, err)
+			return
+		}
+		log.Infof("server %d, handle chainconfig fetch view %d from %d, config blk %d",
+			self.Index, pMsg.ChainConfigView, peerIdx, blk.getBlockNum())
+		self.msgSendC <- &SendMsgEvent{
+			ToPeer: peerIdx,
+			Msg:    self.constructChainConfigFetchRespMsg(pMsg.ChainConfigView, blk),
+		}
+
+	case ChainConfigFetchRespMessage:
+		pMsg, ok := msg.(*ChainConfigFetchRespMsg)
+		if !ok {
+			log.Errorf("invalid msg with chainconfig fetch resp msg type")
+			return
+		}
+		if err := self.verifyChainConfigBlock(pMsg.BlockData); err != nil {
+			log.Errorf("server %d, invalid chainconfig view %d from %d: %s",
+				self.Index, pMsg.ChainConfigView, peerIdx, err)
+			return
+		}
+		self.stateMgr.StateEventC <- &StateEvent{
+			Type:     UpdateChainConfig,
+			blockNum: pMsg.BlockData.getBlockNum(),
+			block:    pMsg.BlockData,
+		}
+
+	case FaultyEvidenceMessage:
+		pMsg, ok := msg.(*faultyEvidenceMsg)
+		if !ok {
+			log.Error("invalid msg with faulty evidence msg type")
+			return
+		}
+		evidence := &nutils.FaultyEvidence{}
+		if err := evidence.Deserialize(bytes.NewReader(pMsg.Evidence)); err != nil {
+			log.Errorf("server %d failed to deserialize faulty evidence from %d: %s", self.Index, peerIdx, err)
+			return
+		}
+		self.onFaultyEvidence(evidence)
+
+	case EmergencyRotationVoteMessage:
+		pMsg, ok := msg.(*emergencyRotationVoteMsg)
+		if !ok {
+			log.Error("invalid msg with emergency rotation vote msg type")
+			return
+		}
+		self.onEmergencyRotationVote(peerIdx, pMsg)
 	}
 }
 
@@ -1126,6 +1411,7 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 	msgPrevBlkHash := msg.Block.getPrevBlockHash()
 	if prevBlkHash != msgPrevBlkHash {
 		log.Errorf("BlockPrposalMessage check blocknum:%d,prevhash:%s,msg prevhash:%s", msg.GetBlockNum(), prevBlkHash.ToHexString(), msgPrevBlkHash.ToHexString())
+		self.incProposalRejection(REJECT_PREV_BLOCK_HASH)
 		self.msgPool.DropMsg(msg)
 		return
 	}
@@ -1139,26 +1425,66 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 		return
 	}
 	if msg.Block.getPrevBlockMerkleRoot() != merkleRoot {
+		self.incProposalRejection(REJECT_MERKLE_ROOT)
 		self.msgPool.DropMsg(msg)
 		msgMerkleRoot := msg.Block.getPrevBlockMerkleRoot()
 		log.Errorf("BlockPrposalMessage check MerkleRoot blocknum:%d,msg MerkleRoot:%s,self MerkleRoot:%s", msg.GetBlockNum(), msgMerkleRoot.ToHexString(), merkleRoot.ToHexString())
 		return
 	}
+	if vconfig.RequirePrevStateRoot(msgBlkNum) && len(msg.Block.Info.PrevStateRoot) == 0 {
+		self.incProposalRejection(REJECT_MERKLE_ROOT)
+		self.msgPool.DropMsg(msg)
+		log.Errorf("BlockPrposalMessage check PrevStateRoot blocknum:%d, PrevStateRoot is required", msg.GetBlockNum())
+		return
+	}
+	if stateRoot := msg.Block.Info.PrevStateRoot; len(stateRoot) != 0 && !bytes.Equal(stateRoot, merkleRoot[:]) {
+		self.incProposalRejection(REJECT_MERKLE_ROOT)
+		self.msgPool.DropMsg(msg)
+		log.Errorf("BlockPrposalMessage check PrevStateRoot blocknum:%d,msg PrevStateRoot:%x,self MerkleRoot:%s", msg.GetBlockNum(), stateRoot, merkleRoot.ToHexString())
+		return
+	}
+	// storage root is committed by all the blocks from the check height, and never before it
+	if storageRoot := msg.Block.Info.PrevStorageRoot; vconfig.RequirePrevStorageRoot(msgBlkNum) {
+		selfStorageRoot, err := self.chainStore.GetExecStorageRoot(msgBlkNum - 1)
+		if err != nil {
+			log.Errorf("failed to GetExecStorageRoot: %s,blkNum:%d", err, (msgBlkNum - 1))
+			return
+		}
+		if !bytes.Equal(storageRoot, selfStorageRoot[:]) {
+			self.incProposalRejection(REJECT_STORAGE_ROOT)
+			self.msgPool.DropMsg(msg)
+			log.Errorf("BlockPrposalMessage check PrevStorageRoot blocknum:%d,msg PrevStorageRoot:%x,self StorageRoot:%s", msg.GetBlockNum(), storageRoot, selfStorageRoot.ToHexString())
+			return
+		}
+	} else if len(storageRoot) != 0 {
+		self.incProposalRejection(REJECT_STORAGE_ROOT)
+		self.msgPool.DropMsg(msg)
+		log.Errorf("BlockPrposalMessage check PrevStorageRoot blocknum:%d, PrevStorageRoot before check height", msg.GetBlockNum())
+		return
+	}
 	cfg := vconfig.ChainConfig{}
 	if blk.getNewChainConfig() != nil {
 		cfg = *blk.getNewChainConfig()
 		if cfg.Hash() != self.config.Hash() {
 			log.Errorf("processProposalMsg chainconfig unqeual to blockinfo cfg,view:(%d,%d),N:(%d,%d),C:(%d,%d),BlockMsgDelay:(%d,%d),HashMsgDelay:(%d,%d),PeerHandshakeTimeout:(%d,%d),posTable:(%v,%v),MaxBlockChangeView:(%d,%d)", cfg.View, self.config.View, cfg.N, self.config.N, cfg.C,
 				self.config.C, cfg.BlockMsgDelay, self.config.BlockMsgDelay, cfg.HashMsgDelay, self.config.HashMsgDelay, cfg.PeerHandshakeTimeout, self.config.PeerHandshakeTimeout, cfg.PosTable, self.config.PosTable, cfg.MaxBlockChangeView, self.config.MaxBlockChangeView)
+			self.incProposalRejection(REJECT_CHAIN_CONFIG)
 			self.msgPool.DropMsg(msg)
 			return
 		}
 	}
+	if err := self.verifyEmergencyRotation(msg); err != nil {
+		log.Errorf("BlockPrposalMessage check emergency rotation blocknum:%d: %s", msg.GetBlockNum(), err)
+		self.incProposalRejection(REJECT_CHAIN_CONFIG)
+		self.msgPool.DropMsg(msg)
+		return
+	}
 
 	prevBlockTimestamp := blk.Block.Header.Timestamp
 	currentBlockTimestamp := msg.Block.Block.Header.Timestamp
-	if currentBlockTimestamp <= prevBlockTimestamp || currentBlockTimestamp > uint32(time.Now().Add(time.Minute*10).Unix()) {
+	if currentBlockTimestamp <= prevBlockTimestamp || currentBlockTimestamp > uint32(self.now().Add(time.Minute*10).Unix()) {
 		log.Errorf("BlockPrposalMessage check  blocknum:%d,prevBlockTimestamp:%d,currentBlockTimestamp:%d", msg.GetBlockNum(), prevBlockTimestamp, currentBlockTimestamp)
+		self.incProposalRejection(REJECT_TIMESTAMP)
 		self.msgPool.DropMsg(msg)
 		return
 	}
@@ -1168,12 +1494,14 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 	if proposerPk == nil {
 		log.Errorf("server %d failed to get proposer %d pk of block %d",
 			self.Index, msg.Block.getProposer(), msgBlkNum)
+		self.incProposalRejection(REJECT_VRF)
 		self.msgPool.DropMsg(msg)
 		return
 	}
 	if err := verifyVrf(proposerPk, msgBlkNum, blk.getVrfValue(), msg.Block.getVrfValue(), msg.Block.getVrfProof()); err != nil {
 		log.Errorf("server %d failed to verify vrf of block %d proposal from %d",
 			self.Index, msgBlkNum, msg.Block.getProposer())
+		self.incProposalRejection(REJECT_VRF)
 		self.msgPool.DropMsg(msg)
 		return
 	}
@@ -1188,9 +1516,11 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 		return
 	}
 	if !self.verifyShardEventMsg(msg) {
+		self.incProposalRejection(REJECT_SHARD_EVENT)
 		return
 	}
 	if !self.verifyCrossShardTx(msg) {
+		self.incProposalRejection(REJECT_CROSS_SHARD_TXS)
 		return
 	}
 	txs := msg.Block.Block.Transactions
@@ -1210,6 +1540,7 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 			if err := self.poolActor.VerifyBlock(txs, validHeight); err != nil && err != actor.ErrTimeout {
 				log.Errorf("server %d verify proposal blk from %d failed, blk %d, txs %d, err: %s",
 					self.Index, msg.Block.getProposer(), msgBlkNum, len(txs), err)
+				self.incProposalRejection(REJECT_TX_VERIFY_FAILURE)
 				return
 			} else if err == actor.ErrTimeout {
 				log.Errorf("server %d verify proposal blk from %d timedout, blk %d, txs %d, err: %s",
@@ -1219,6 +1550,7 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 				if err := self.incrValidator.Verify(tx, validHeight); err != nil {
 					log.Errorf("server %d verify proposal tx from %d failed, blk %d, txs %d, err: %s",
 						self.Index, msg.Block.getProposer(), msgBlkNum, len(txs), err)
+					self.incProposalRejection(REJECT_TX_VERIFY_FAILURE)
 					return
 				}
 			}
@@ -1313,7 +1645,9 @@ func (self *Server) processMsgEvent() error {
 			pMsg := msg.(*blockProposalMsg)
 
 			if err := self.validateTxsInProposal(pMsg); err != nil {
-				// TODO: report faulty proposal
+				log.Warnf("server %d received invalid proposal from %d, blk %d",
+					self.Index, pMsg.Block.getProposer(), pMsg.GetBlockNum())
+				self.incProposalRejection(REJECT_INVALID_TXS)
 				return fmt.Errorf("failed to validate tx in proposal: %s", err)
 			}
 
@@ -1322,7 +1656,8 @@ func (self *Server) processMsgEvent() error {
 				// add proposal to block-pool
 				if err := self.blockPool.newBlockProposal(pMsg); err != nil {
 					if err == errDupProposal {
-						// TODO: faulty proposer detected
+						self.incProposalRejection(REJECT_DUPLICATED)
+						self.reportFaultyProposal(pMsg)
 					}
 					log.Errorf("failed to add block proposal (%d): %s", msgBlkNum, err)
 					return nil
@@ -1377,6 +1712,7 @@ func (self *Server) processMsgEvent() error {
 
 			if msgBlkNum == self.GetCurrentBlockNo() {
 				// add endorse to block-pool
+				self.reportFaultyEndorsement(pMsg)
 				self.blockPool.newBlockEndorsement(pMsg)
 				log.Infof("server %d received endorse from %d, for proposer %d, block %d, empty: %t",
 					self.Index, pMsg.Endorser, pMsg.EndorsedProposer, msgBlkNum, pMsg.EndorseForEmpty)
@@ -1510,6 +1846,9 @@ func (self *Server) actionLoop() {
 						log.Errorf("server %d failed to making proposal (%d): %s",
 							self.Index, blkNum, err)
 					}
+				} else {
+					// proposal resumed from persisted round msgs, rebroadcast it
+					self.broadcast(proposal)
 				}
 
 			case EndorseBlock:
@@ -1539,6 +1878,7 @@ func (self *Server) actionLoop() {
 			case FastForward:
 				// 1. from current block num, check commit msgs in msg pool
 				// 2. if commit consensused, seal the proposal
+				fastForwardCounter.Inc(self.metricsShardLabel())
 				for {
 					blkNum := self.GetCurrentBlockNo()
 					C := int(self.config.C)
@@ -1756,6 +2096,7 @@ func (self *Server) timerLoop() {
 }
 
 func (self *Server) processTimerEvent(evt *TimerEvent) error {
+	self.incTimerEvent(evt.evtType)
 	switch evt.evtType {
 	case EventProposalBackoff:
 		// 1. if endorsed, return
@@ -1942,6 +2283,7 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 
 	case EventPeerHeartbeat:
 		self.heartbeat()
+		self.checkCrashedPeers()
 
 	case EventTxPool:
 		self.timer.stopTxTicker(evt.blockNum)
@@ -2039,6 +2381,9 @@ func (self *Server) endorseBlock(proposal *blockProposalMsg, forEmpty bool) erro
 	if forEmpty || self.isEndorser(blkNum, self.Index) {
 		h, _ := HashMsg(endorseMsg)
 		self.msgPool.AddMsg(endorseMsg, h)
+		if err := self.persistRoundConsensusMsgs(blkNum, proposal, endorseMsg); err != nil {
+			return fmt.Errorf("failed to persist endorse msg: %s", err)
+		}
 		log.Infof("endorser %d, endorsed block %d, from server %d",
 			self.Index, blkNum, proposal.Block.getProposer())
 		// broadcast my endorsement
@@ -2107,6 +2452,9 @@ func (self *Server) commitBlock(proposal *blockProposalMsg, forEmpty bool) error
 	if forEmpty || self.isCommitter(blkNum, self.Index) {
 		h, _ := HashMsg(commitMsg)
 		self.msgPool.AddMsg(commitMsg, h)
+		if err := self.persistRoundConsensusMsgs(blkNum, proposal, commitMsg); err != nil {
+			return fmt.Errorf("failed to persist commit msg: %s", err)
+		}
 		log.Infof("committer %d, set block %d committed, from server %d",
 			self.Index, blkNum, proposal.Block.getProposer())
 		// broadcast my commitment
@@ -2189,14 +2537,21 @@ func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
 		return fmt.Errorf("failed to seal proposal: %s", err)
 	}
 
-	// TODO: also persistent the block endorsers and committer msgs
+	// persistent the block endorsers and committer msgs
+	if err := self.persistBlockConsensusMsgs(sealedBlkNum); err != nil {
+		log.Errorf("server %d, persist consensus msgs of block %d: %s", self.Index, sealedBlkNum, err)
+	}
 
 	// notify other modules that block sealed
+	self.onRoundSealed(sealedBlkNum)
 	self.timer.onBlockSealed(sealedBlkNum)
 	self.msgPool.onBlockSealed(sealedBlkNum)
 	self.blockPool.onBlockSealed(sealedBlkNum)
 
-	_, h := self.blockPool.getSealedBlock(sealedBlkNum)
+	sealedBlk, h := self.blockPool.getSealedBlock(sealedBlkNum)
+	if sealedBlk != nil {
+		self.faultyPool.onBlockSealed(sealedBlk.Block)
+	}
 	prevBlkHash := block.getPrevBlockHash()
 	log.Infof("server %d, sealed block %d, proposer %d, prevhash: %s, hash: %s", self.Index,
 		sealedBlkNum, block.getProposer(), prevBlkHash.ToHexString(), h.ToHexString())
@@ -2223,21 +2578,8 @@ func (self *Server) msgSendLoop() {
 			if self.nonConsensusNode() {
 				continue
 			}
-			payload, err := SerializeVbftMsg(evt.Msg)
-			if err != nil {
-				log.Errorf("server %d failed to serialized msg (type: %d): %s", self.Index, evt.Msg.Type(), err)
-				continue
-			}
-			if evt.ToPeer == math.MaxUint32 {
-				// broadcast
-				if err := self.broadcastToAll(payload); err != nil {
-					log.Errorf("server %d xmit msg (type %d): %s",
-						self.Index, evt.Msg.Type(), err)
-				}
-			} else {
-				if err := self.sendToPeer(evt.ToPeer, payload); err != nil {
-					log.Errorf("server %d xmit to peer %d failed: %s", self.Index, evt.ToPeer, err)
-				}
+			for _, evt := range self.applyByzantineBehavior(evt) {
+				self.sendMsgEvent(evt)
 			}
 
 		case <-self.quitC:
@@ -2247,35 +2589,39 @@ func (self *Server) msgSendLoop() {
 	}
 }
 
+func (self *Server) sendMsgEvent(evt *SendMsgEvent) {
+	payload, err := SerializeVbftMsg(evt.Msg)
+	if err != nil {
+		log.Errorf("server %d failed to serialized msg (type: %d): %s", self.Index, evt.Msg.Type(), err)
+		return
+	}
+	if evt.ToPeer == math.MaxUint32 {
+		// broadcast
+		if err := self.broadcastToAll(payload); err != nil {
+			log.Errorf("server %d xmit msg (type %d): %s",
+				self.Index, evt.Msg.Type(), err)
+		}
+	} else {
+		if err := self.sendToPeer(evt.ToPeer, payload); err != nil {
+			log.Errorf("server %d xmit to peer %d failed: %s", self.Index, evt.ToPeer, err)
+		}
+	}
+}
+
 //create shard ong transaction
 func (self *Server) createShardGovTransaction(blkNum uint32) (*types.Transaction, error) {
 	//build transaction
 	mutable := utils.BuildNativeTransaction(nutils.ShardMgmtContractAddress, shardmgmt.NOTIFY_PARENT_COMMIT_DPOS, []byte{})
-	mutable.GasPrice = 0
-	mutable.GasLimit = 200000
-	mutable.Payer = self.account.Address
 	mutable.Nonce = blkNum
-	// add signatures
-	txHash := mutable.Hash()
-	sig, err := signature.Sign(self.account.SigScheme, self.account.PrivateKey, txHash.ToArray(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("sign tx: %s", err)
-	}
-	sigData, err := signature.Serialize(sig)
-	if err != nil {
-		return nil, fmt.Errorf("serialize sig: %s", err)
-	}
-	mutable.Sigs = []types.Sig{
-		{
-			PubKeys: []keypair.PublicKey{self.account.PubKey()},
-			M:       1,
-			SigData: [][]byte{sigData},
-		},
-	}
-	return mutable.IntoImmutable()
+	return self.signSysTransaction(mutable)
+}
+
+// signSysTransaction: sign consensus system tx with node account, the node pays no fee
+func (self *Server) signSysTransaction(mutable *types.MutableTransaction) (*types.Transaction, error) {
+	return csm.SignSysTransaction(self.account, mutable)
 }
 
-//creategovernaceTransaction invoke governance native contract commit_pos
+// creategovernaceTransaction invoke governance native contract commit_pos
 func (self *Server) creategovernaceTransaction(blkNum uint32) (*types.Transaction, error) {
 	mutable := utils.BuildNativeTransaction(nutils.GovernanceContractAddress, gover.COMMIT_DPOS, []byte{})
 	mutable.Nonce = blkNum
@@ -2283,7 +2629,7 @@ func (self *Server) creategovernaceTransaction(blkNum uint32) (*types.Transactio
 	return tx, err
 }
 
-//checkNeedUpdateChainConfig use blockcount
+// checkNeedUpdateChainConfig use blockcount
 func (self *Server) checkNeedUpdateChainConfig(blockNum uint32) bool {
 	prevBlk, _ := self.blockPool.getSealedBlock(blockNum - 1)
 	if prevBlk == nil {
@@ -2297,7 +2643,7 @@ func (self *Server) checkNeedUpdateChainConfig(blockNum uint32) bool {
 	return false
 }
 
-//checkUpdateChainConfig query leveldb check is force update
+// checkUpdateChainConfig query leveldb check is force update
 func (self *Server) checkUpdateChainConfig(blkNum uint32) bool {
 	force, err := isUpdate(self.ledger, self.chainStore.GetExecWriteSet(blkNum-1), self.config.View)
 	if err != nil {
@@ -2406,6 +2752,10 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 					}
 					sysTxs = append(sysTxs, tx)
 					chainconfig.View++
+					if chainconfig.View <= self.config.View {
+						// view has been increased by emergency rotation
+						chainconfig.View = self.config.View + 1
+					}
 				}
 				forEmpty = true
 				cfg = chainconfig
@@ -2415,6 +2765,28 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 	if self.nonConsensusNode() {
 		return fmt.Errorf("%d quit consensus node", self.Index)
 	}
+	var rotationData []byte
+	if cfg == nil {
+		// rotate crashed peer out, root shard will be informed after the rotation
+		if rotation, rotatedCfg := self.getEmergencyRotation(); rotation != nil {
+			buf := new(bytes.Buffer)
+			if err := rotation.Serialize(buf); err != nil {
+				return fmt.Errorf("serialize emergency rotation: %s", err)
+			}
+			tx, err := self.createEmergencyRotationTransaction(blkNum, buf.Bytes())
+			if err != nil {
+				return fmt.Errorf("construct emergency rotation transaction error: %v", err)
+			}
+			sysTxs = append(sysTxs, tx)
+			rotationData = buf.Bytes()
+			forEmpty = true
+			cfg = rotatedCfg
+		}
+	}
+	if cfg == nil {
+		// report faulty peers, not in config-changing block
+		sysTxs = append(sysTxs, self.faultyPool.getEvidenceTxs()...)
+	}
 
 	if !forEmpty {
 		for _, e := range self.poolActor.GetTxnPool(true, validHeight) {
@@ -2428,7 +2800,7 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 			}
 		}
 	}
-	proposal, err := self.constructProposalMsg(blkNum, sysTxs, userTxs, cfg)
+	proposal, err := self.constructProposalMsg(blkNum, sysTxs, userTxs, cfg, rotationData)
 	if err != nil {
 		return fmt.Errorf("failed to construct proposal: %s", err)
 	}
@@ -2438,6 +2810,9 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 	// add proposal to self
 	h, _ := HashMsg(proposal)
 	self.msgPool.AddMsg(proposal, h)
+	if err := self.persistRoundConsensusMsgs(blkNum, proposal); err != nil {
+		return fmt.Errorf("failed to persist proposal: %s", err)
+	}
 	self.processProposalMsg(proposal)
 	self.broadcast(proposal)
 	return nil
@@ -2586,6 +2961,7 @@ func (self *Server) catchConsensus(blkNum uint32) error {
 	if !self.isEndorser(blkNum, self.Index) && !self.isCommitter(blkNum, self.Index) {
 		return nil
 	}
+	catchConsensusCounter.Inc(self.metricsShardLabel())
 
 	proposals := make(map[uint32]*blockProposalMsg)
 	pMsgs := self.msgPool.GetProposalMsgs(blkNum)
@@ -61,12 +61,14 @@ const (
 	SyncReadyTimeout
 	SyncDone
 	LiveTick
+	UpdateChainConfig // chain config fetched from peers
 )
 
 type StateEvent struct {
 	Type      StateEventType
 	peerState *PeerState
 	blockNum  uint32
+	block     *Block
 }
 
 type PeerState struct {
@@ -83,9 +85,12 @@ type StateMgr struct {
 	StateEventC      chan *StateEvent
 	peers            map[uint32]*PeerState
 
-	liveTicker             *time.Timer
+	liveTicker             Timer
 	lastTickChainHeight    uint32
 	lastBlockSyncReqHeight uint32
+
+	lastChainConfigFetchView uint32
+	lastChainConfigFetchTime time.Time
 }
 
 func newStateMgr(server *Server) *StateMgr {
@@ -103,7 +108,7 @@ func (self *StateMgr) getState() ServerState {
 }
 
 func (self *StateMgr) run() {
-	self.liveTicker = time.AfterFunc(peerHandshakeTimeout*5, func() {
+	self.liveTicker = self.server.afterFunc(peerHandshakeTimeout*5, func() {
 		self.StateEventC <- &StateEvent{
 			Type:     LiveTick,
 			blockNum: self.server.GetCommittedBlockNo(),
@@ -136,14 +141,13 @@ func (self *StateMgr) run() {
 
 				if self.currentState >= LocalConfigured {
 					v := self.getSyncedChainConfigView()
-					if v == self.server.config.View && self.currentState < Syncing {
+					if v == self.getLatestChainConfigView() && self.currentState < Syncing {
 						log.Infof("server %d, start syncing", self.server.Index)
 						self.currentState = Syncing
-					} else if v > self.server.config.View {
-						// update ChainConfig
-						log.Errorf("todo: chain config changed, need update chain config from peers")
-						// TODO: fetch config from neighbours, update chain config
+					} else if v > self.getLatestChainConfigView() {
+						// chain config changed, fetch config from peers
 						self.currentState = LocalConfigured
+						self.fetchChainConfig(v)
 					}
 				}
 			case UpdatePeerState:
@@ -163,6 +167,11 @@ func (self *StateMgr) run() {
 				if err := self.onLiveTick(evt); err != nil {
 					log.Errorf("server %d, live ticker: %s", self.server.Index, err)
 				}
+
+			case UpdateChainConfig:
+				if err := self.onChainConfigFetched(evt.block); err != nil {
+					log.Errorf("server %d, update chain config from blk %d: %s", self.server.Index, evt.blockNum, err)
+				}
 			}
 
 		case <-self.server.quitC:
@@ -198,10 +207,12 @@ func (self *StateMgr) onPeerUpdate(peerState *PeerState) {
 	case LocalConfigured:
 		v := self.getSyncedChainConfigView()
 		log.Infof("server %d statemgr update, current state: %d, from peer: %d, peercnt: %d, v1: %d, v2: %d",
-			self.server.Index, self.currentState, peerIdx, len(self.peers), v, self.server.config.View)
+			self.server.Index, self.currentState, peerIdx, len(self.peers), v, self.getLatestChainConfigView())
 
-		if v == self.server.config.View {
+		if v == self.getLatestChainConfigView() {
 			self.currentState = Syncing
+		} else if v > self.getLatestChainConfigView() {
+			self.fetchChainConfig(v)
 		}
 	case Configured:
 	case Syncing:
@@ -306,6 +317,61 @@ func (self *StateMgr) onLiveTick(evt *StateEvent) error {
 	return self.server.reBroadcastCurrentRoundMsgs()
 }
 
+// fetchChainConfig: request next chain config from peers which have newer chain config,
+// config is updated view by view until reached the synced view
+func (self *StateMgr) fetchChainConfig(syncedView uint32) {
+	view := self.getLatestChainConfigView() + 1
+	if view == self.lastChainConfigFetchView && self.server.now().Sub(self.lastChainConfigFetchTime) < peerHandshakeTimeout {
+		return
+	}
+	self.lastChainConfigFetchView = view
+	self.lastChainConfigFetchTime = self.server.now()
+
+	log.Infof("server %d, fetch chain config view %d from peers, synced view %d",
+		self.server.Index, view, syncedView)
+	msg := self.server.constructChainConfigFetchMsg(view)
+	for peerIdx, p := range self.peers {
+		if p.chainConfigView >= view {
+			self.server.msgSendC <- &SendMsgEvent{
+				ToPeer: peerIdx,
+				Msg:    msg,
+			}
+		}
+	}
+}
+
+func (self *StateMgr) onChainConfigFetched(blk *Block) error {
+	if self.currentState != LocalConfigured {
+		return nil
+	}
+	cfg := blk.getNewChainConfig()
+	if cfg == nil || cfg.View != self.getLatestChainConfigView()+1 {
+		// duplicated response
+		return nil
+	}
+	// blocks before config block are produced by current peers, config is applied while the block committed
+	if err := self.server.setFetchedChainConfig(blk); err != nil {
+		return err
+	}
+	log.Infof("server %d, chain config of view %d fetched from peers, config blk %d",
+		self.server.Index, cfg.View, blk.getBlockNum())
+
+	v := self.getSyncedChainConfigView()
+	if v == cfg.View {
+		log.Infof("server %d, start syncing", self.server.Index)
+		self.currentState = Syncing
+	} else if v > cfg.View {
+		self.fetchChainConfig(v)
+	}
+	return nil
+}
+
+// getLatestChainConfigView: view of chain config, include the one fetched from peers but not synced yet
+func (self *StateMgr) getLatestChainConfigView() uint32 {
+	cfg, _ := self.server.getLatestChainConfig()
+	return cfg.View
+}
+
 func (self *StateMgr) getMinActivePeerCount() int {
 	n := int(self.server.config.C) * 2 // plus self
 	if n > MAX_PEER_CONNECTIONS {
@@ -358,7 +424,7 @@ func (self *StateMgr) setSyncedReady() error {
 	if prevState <= SyncReady {
 		log.Infof("server %d start sync ready", self.server.Index)
 		blkNum := self.server.GetCurrentBlockNo()
-		time.AfterFunc(self.syncReadyTimeout, func() {
+		self.server.afterFunc(self.syncReadyTimeout, func() {
 			self.StateEventC <- &StateEvent{
 				Type:     SyncReadyTimeout,
 				blockNum: blkNum,
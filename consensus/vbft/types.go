@@ -19,6 +19,7 @@
 package vbft
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -171,3 +172,46 @@ func initVbftBlock(block *types.Block, prevMerkleRoot common.Uint256) (*Block, e
 		PrevBlockMerkleRoot: prevMerkleRoot,
 	}, nil
 }
+
+// BlockConsensusMsgs is the endorsement and commit quorum of sealed block,
+// persisted with block to prove the finality of block
+type BlockConsensusMsgs struct {
+	BlockNum     uint32             `json:"block_num"`
+	BlockHash    common.Uint256     `json:"block_hash"`
+	Endorsements []*blockEndorseMsg `json:"endorsements"`
+	Commits      []*blockCommitMsg  `json:"commits"`
+}
+
+func (msgs *BlockConsensusMsgs) Serialize() ([]byte, error) {
+	return json.Marshal(msgs)
+}
+
+func (msgs *BlockConsensusMsgs) Deserialize(data []byte) error {
+	return json.Unmarshal(data, msgs)
+}
+
+// RoundConsensusMsgs is the self proposal, endorsement and commit of current round, and proposals voted by them,
+// persisted before broadcasting to resume the round after restart
+type RoundConsensusMsgs struct {
+	BlockNum uint32   `json:"block_num"`
+	Msgs     [][]byte `json:"msgs"`
+}
+
+// add: append serialized msg, false if it has been added
+func (msgs *RoundConsensusMsgs) add(data []byte) bool {
+	for _, msg := range msgs.Msgs {
+		if bytes.Equal(msg, data) {
+			return false
+		}
+	}
+	msgs.Msgs = append(msgs.Msgs, data)
+	return true
+}
+
+func (msgs *RoundConsensusMsgs) Serialize() ([]byte, error) {
+	return json.Marshal(msgs)
+}
+
+func (msgs *RoundConsensusMsgs) Deserialize(data []byte) error {
+	return json.Unmarshal(data, msgs)
+}
@@ -218,3 +218,29 @@ func TestInitVbftBlock(t *testing.T) {
 	}
 	t.Log("TestInitVbftBlock succ")
 }
+
+func TestBlockConsensusMsgs(t *testing.T) {
+	msgs := &BlockConsensusMsgs{
+		BlockNum:  10,
+		BlockHash: common.Uint256{1, 2, 3},
+		Endorsements: []*blockEndorseMsg{
+			{Endorser: 1, BlockNum: 10, EndorsedBlockHash: common.Uint256{1, 2, 3}, EndorserSig: []byte{1}},
+		},
+		Commits: []*blockCommitMsg{
+			{Committer: 2, BlockNum: 10, CommitBlockHash: common.Uint256{1, 2, 3}, CommitterSig: []byte{2}},
+		},
+	}
+	data, err := msgs.Serialize()
+	if err != nil {
+		t.Errorf("BlockConsensusMsgs Serialize failed: %v", err)
+		return
+	}
+	result := &BlockConsensusMsgs{}
+	if err := result.Deserialize(data); err != nil {
+		t.Errorf("BlockConsensusMsgs Deserialize failed: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(msgs, result) {
+		t.Errorf("BlockConsensusMsgs unmatch: %v vs %v", msgs, result)
+	}
+}
@@ -332,6 +332,16 @@ func getShardConfig(lgr *ledger.Ledger, shardID common.ShardID, blkNum uint32) (
 			}
 		}
 	}
+	// peers rotated out in emergency have quit consensus, network shrinks as in rotation
+	if n := uint32(len(peersinfo)); n < chainconfig.K {
+		if n > 0 && n < 3*chainconfig.C+1 {
+			chainconfig.C = (n - 1) / 3
+		}
+		if n == 0 || chainconfig.C == 0 {
+			return nil, fmt.Errorf("too few consensus peers of shard %d: %d", shardID, n)
+		}
+		chainconfig.K = n
+	}
 	cfg, err := vconfig.GenesisChainConfig(chainconfig, peersinfo, shardView.TxHash, blkNum)
 	if err != nil {
 		log.Errorf("GenesisShardChainConfig failed: %s", err)
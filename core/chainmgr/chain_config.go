@@ -53,24 +53,43 @@ func (self *ChainManager) buildShardConfig(shardID common.ShardID, shardState *s
 		return nil, fmt.Errorf("init child config: %s", err)
 	}
 
-	if shardConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
-		// add seeds and bookkeepers to config
-		if shardConfig.Genesis.SOLO.Bookkeepers == nil || len(shardConfig.Genesis.SOLO.Bookkeepers) == 0 {
-			bookkeepers := make([]string, 0)
-			for peerPK, _ := range shardState.Peers {
-				bookkeepers = append(bookkeepers, peerPK)
-			}
-			shardConfig.Genesis.SOLO.Bookkeepers = bookkeepers
+	// shard runs consensus configured in shardmgmt, which may be different with parent shard
+	seedList := make([]string, 0)
+	for _, info := range shardState.Peers {
+		seedList = append(seedList, info.IpAddress)
+	}
+	shardConfig.Genesis.SeedList = seedList
+	shardConfig.Genesis.ConsensusType = shardState.Config.GetConsensusType()
+	switch shardConfig.Genesis.ConsensusType {
+	case config.CONSENSUS_TYPE_SOLO:
+		// solo shard has only one bookkeeper
+		bookkeepers := xshard.GetShardBookkeepers(shardState, shardState.Config.SoloCfg.Bookkeepers)
+		if len(bookkeepers) > 1 {
+			bookkeepers = bookkeepers[:1]
+		}
+		shardConfig.Genesis.SOLO = &config.SOLOConfig{
+			GenBlockTime: shardState.Config.SoloCfg.GenBlockTime,
+			Bookkeepers:  bookkeepers,
+		}
+	case config.CONSENSUS_TYPE_DBFT:
+		shardConfig.Genesis.DBFT = &config.DBFTConfig{
+			GenBlockTime:       shardState.Config.DbftCfg.GenBlockTime,
+			Bookkeepers:        xshard.GetShardBookkeepers(shardState, shardState.Config.DbftCfg.Bookkeepers),
+			MaxBlockChangeView: shardState.Config.DbftCfg.MaxBlockChangeView,
+		}
+	case config.CONSENSUS_TYPE_SBFT:
+		// bookkeepers are sorted in GetBookkeepers
+		shardConfig.Genesis.SBFT = &config.SBFTConfig{
+			GenBlockTime: shardState.Config.SbftCfg.GenBlockTime,
+			Bookkeepers:  xshard.GetShardBookkeepers(shardState, shardState.Config.SbftCfg.Bookkeepers),
 		}
-	} else if shardConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_VBFT {
+	case config.CONSENSUS_TYPE_VBFT:
 		peers := make([]*config.VBFTPeerStakeInfo, 0)
 		peerStakeInfo, err := xshard.GetShardPeerStakeInfo(ledger.GetShardLedger(common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)), shardState.ShardID, 0)
 		if err != nil {
 			return nil, fmt.Errorf("buildShardConfig GetShardPeerStakeInfo: failed, err: %s", err)
 		}
-		seedList := make([]string, 0)
 		for peerPK, info := range shardState.Peers {
-			seedList = append(seedList, info.IpAddress)
 			vbftpeerstakeinfo := &config.VBFTPeerStakeInfo{
 				Index:      info.Index,
 				PeerPubkey: peerPK,
@@ -89,7 +108,6 @@ func (self *ChainManager) buildShardConfig(shardID common.ShardID, shardState *s
 			}
 			return false
 		})
-		shardConfig.Genesis.SeedList = seedList
 		shardConfig.Genesis.VBFT.N = shardState.Config.VbftCfg.N
 		shardConfig.Genesis.VBFT.C = shardState.Config.VbftCfg.C
 		shardConfig.Genesis.VBFT.K = shardState.Config.VbftCfg.K
@@ -103,8 +121,8 @@ func (self *ChainManager) buildShardConfig(shardID common.ShardID, shardState *s
 		shardConfig.Genesis.VBFT.VrfValue = shardState.Config.VbftCfg.VrfValue
 		shardConfig.Genesis.VBFT.VrfProof = shardState.Config.VbftCfg.VrfProof
 		shardConfig.Genesis.VBFT.Peers = peers
-	} else {
-		return nil, fmt.Errorf("only solo suppported")
+	default:
+		return nil, fmt.Errorf("unsupported consensus type %s", shardConfig.Genesis.ConsensusType)
 	}
 	// TODO: init config for shard $shardID, including genesis config, data dir, net port, etc
 
@@ -21,6 +21,7 @@ package chainmgr
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 
 	"github.com/ontio/ontology-eventbus/actor"
@@ -41,6 +42,7 @@ import (
 	"github.com/ontio/ontology/p2pserver/actor/req"
 	"github.com/ontio/ontology/p2pserver/actor/server"
 	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_sysmsg"
 	shardstates "github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 	"github.com/ontio/ontology/txnpool"
 	tc "github.com/ontio/ontology/txnpool/common"
@@ -143,8 +145,8 @@ func (self *ChainManager) LoadFromLedger(stateHashHeight uint32) error {
 	if err != nil {
 		return fmt.Errorf("get shard %d failed: %s", self.shardID, err)
 	}
-	// skip if shard is not active
-	if shardState.State != shardstates.SHARD_STATE_ACTIVE {
+	// skip if shard is not active, stopping shard should keep running until archived
+	if shardState.State != shardstates.SHARD_STATE_ACTIVE && shardState.State != shardstates.SHARD_STATE_STOPPING {
 		return nil
 	}
 	shardInfo := self.initShardInfo(shardState)
@@ -277,6 +279,16 @@ func (self *ChainManager) startConsensus() error {
 	return nil
 }
 
+func (self *ChainManager) stopConsensus() {
+	if self.consensus == nil {
+		return
+	}
+	if err := self.consensus.Halt(); err != nil {
+		log.Errorf("shard %d, halt consensus: %s", self.shardID, err)
+	}
+	self.consensus = nil
+}
+
 func (self *ChainManager) initShardTxPool() error {
 	lgr := ledger.GetShardLedger(self.shardID)
 	if lgr == nil {
@@ -324,7 +336,11 @@ func (self *ChainManager) Start(p2pPid *actor.PID, txPoolMgr *txnpool.TxnPoolMan
 		}
 	}
 
-	return self.startConsensus()
+	self.redeliverShardSysMsgs()
+	if err := self.startConsensus(); err != nil {
+		return err
+	}
+	return self.resumeShardStopping()
 }
 
 func (self *ChainManager) Receive(context actor.Context) {
@@ -390,6 +406,82 @@ func (self *ChainManager) handleShardSysEvents(shardEvts []*message.ShardSystemE
 				log.Errorf("processing shard activation event: %s", err)
 			}
 		case shardstates.EVENT_SHARD_PEER_LEAVE:
+		case shardstates.EVENT_SHARD_STOPPING:
+			evt := &shardstates.ShardStopEvent{}
+			if err := evt.Deserialization(common.NewZeroCopySource(shardEvt.Payload)); err != nil {
+				log.Errorf("deserialize shard stop event: %s", err)
+				continue
+			}
+			if err := self.onShardStopping(evt); err != nil {
+				log.Errorf("processing shard stop event: %s", err)
+			}
+		case shardstates.EVENT_SHARD_ARCHIVED:
+			evt := &shardstates.ShardArchiveEvent{}
+			if err := evt.Deserialization(common.NewZeroCopySource(shardEvt.Payload)); err != nil {
+				log.Errorf("deserialize shard archive event: %s", err)
+				continue
+			}
+			if err := self.onShardArchived(evt); err != nil {
+				log.Errorf("processing shard archive event: %s", err)
+			}
+		}
+	}
+}
+
+// fetchShardSysMsgs reads system messages delivered at height from shard sysmsg queues of ledger,
+// delivered messages are kept in queue until acked in the next block, so they are consumed again
+// if node restarted before handling them.
+func (self *ChainManager) fetchShardSysMsgs(ledgerShardID common.ShardID, height uint32) []*message.ShardSystemEventMsg {
+	lgr := ledger.GetShardLedger(ledgerShardID)
+	if lgr == nil {
+		return nil
+	}
+	queueShards, err := xshard.GetShardSysMsgQueueShards(lgr)
+	if err != nil {
+		log.Errorf("fetch shard sysmsg of ledger %d: %s", ledgerShardID.ToUint64(), err)
+		return nil
+	}
+
+	sysMsgs := make([]*shardsysmsg.SysMsg, 0)
+	for _, shardID := range queueShards {
+		state, err := xshard.GetShardSysMsgQueueState(lgr, shardID)
+		if err != nil {
+			log.Errorf("fetch shard sysmsg queue %d of ledger %d: %s", shardID.ToUint64(), ledgerShardID.ToUint64(), err)
+			continue
+		}
+		if state.DeliverHeight != height {
+			continue
+		}
+		for seq := state.Head; seq < state.Delivered; seq++ {
+			msg, err := xshard.GetShardSysMsg(lgr, shardID, seq)
+			if err != nil {
+				log.Errorf("fetch shard sysmsg %d of queue %d: %s", seq, shardID.ToUint64(), err)
+				break
+			}
+			sysMsgs = append(sysMsgs, msg)
+		}
+	}
+
+	// keep messages of different queues in the order of enqueued height
+	sort.SliceStable(sysMsgs, func(i, j int) bool {
+		return sysMsgs[i].Height < sysMsgs[j].Height
+	})
+	evts := make([]*message.ShardSystemEventMsg, 0, len(sysMsgs))
+	for _, msg := range sysMsgs {
+		evts = append(evts, msg.Msg)
+	}
+	return evts
+}
+
+// redeliverShardSysMsgs handles system messages delivered at current height of ledgers when node starting,
+// which may be not handled before node stopped
+func (self *ChainManager) redeliverShardSysMsgs() {
+	for shardID := self.shardID; ; shardID = shardID.ParentID() {
+		if lgr := ledger.GetShardLedger(shardID); lgr != nil {
+			self.handleShardSysEvents(self.fetchShardSysMsgs(shardID, lgr.GetCurrentBlockHeight()))
+		}
+		if shardID.IsRootShard() {
+			break
 		}
 	}
 }
@@ -421,8 +513,8 @@ func (self *ChainManager) localEventLoop() {
 	for {
 		select {
 		case msg := <-self.localBlockMsgC:
-			self.handleShardSysEvents(msg.ShardSysEvents)
 			blk := msg.Block
+			self.handleShardSysEvents(self.fetchShardSysMsgs(blk.Header.ShardID, blk.Header.Height))
 			self.onBlockPersistCompleted(blk)
 			if msg.SourceAndShardTxHashMap != nil {
 				self.saveSourceAndShardTxHash(msg.Block.Header.ShardID, msg.SourceAndShardTxHashMap)
@@ -467,5 +559,9 @@ func (self *ChainManager) Close() {
 }
 
 func (self *ChainManager) Stop() {
-	// TODO
+	if self.localEventSub != nil {
+		self.localEventSub.Unsubscribe(message.TOPIC_SHARD_SYSTEM_EVENT)
+		self.localEventSub.Unsubscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	}
+	self.stopConsensus()
 }
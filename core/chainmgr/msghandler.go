@@ -145,6 +145,74 @@ func (self ChainManager) startChildShard(shardID common.ShardID, shardState *sha
 	return self.startConsensus()
 }
 
+func (self *ChainManager) onShardStopping(evt *shardstates.ShardStopEvent) error {
+	if evt.ShardID != self.shardID {
+		return nil
+	}
+	if self.txPoolMgr == nil {
+		return fmt.Errorf("shard %d stopping, txpool not available", self.shardID)
+	}
+	srv := self.txPoolMgr.GetTxnPoolServer(self.shardID)
+	if srv == nil {
+		return fmt.Errorf("shard %d stopping, txpool server not started", self.shardID)
+	}
+	// only accept cross shard txs, pending cross shard txs can be finished before archived
+	srv.SetDraining(true)
+	log.Infof("chainmgr shard %d stopping at parent height %d, draining txpool", self.shardID, evt.Height)
+	return nil
+}
+
+// resume draining txpool if shard is stopping while node restarted
+func (self *ChainManager) resumeShardStopping() error {
+	if self.shardID.IsRootShard() {
+		return nil
+	}
+	shardState, err := xshard.GetShardState(ledger.GetShardLedger(self.shardID.ParentID()), self.shardID)
+	if err == com.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get shard %d failed: %s", self.shardID, err)
+	}
+	if shardState.State != shardstates.SHARD_STATE_STOPPING {
+		return nil
+	}
+	evt := &shardstates.ShardStopEvent{}
+	evt.SourceShardID = self.shardID.ParentID()
+	evt.ShardID = self.shardID
+	return self.onShardStopping(evt)
+}
+
+func (self *ChainManager) onShardArchived(evt *shardstates.ShardArchiveEvent) error {
+	self.lock.Lock()
+	delete(self.shards, evt.ShardID)
+	self.lock.Unlock()
+	if evt.ShardID != self.shardID {
+		return nil
+	}
+	return self.stopChildShard(evt.ShardID)
+}
+
+// stop consensus / syncer / txpool / ledger of archived shard
+func (self *ChainManager) stopChildShard(shardID common.ShardID) error {
+	self.stopConsensus()
+	if self.txPoolMgr != nil {
+		self.txPoolMgr.StopTxnPoolServer(shardID)
+	}
+	if self.p2pPid != nil {
+		self.p2pPid.Tell(&server.StopSync{ShardID: shardID.ToUint64()})
+	}
+	// queries are served by parent ledger after shard ledger closed
+	if lgr := ledger.GetShardLedger(shardID.ParentID()); lgr != nil && ledger.DefLedger == ledger.GetShardLedger(shardID) {
+		ledger.DefLedger = lgr
+	}
+	if err := ledger.CloseShardLedger(shardID); err != nil {
+		return fmt.Errorf("stopChildShard shard %d, close ledger: %s", shardID, err)
+	}
+	log.Infof("chainmgr shard %d archived, consensus/txpool/ledger stopped", shardID)
+	return nil
+}
+
 func (self *ChainManager) onBlockPersistCompleted(blk *types.Block) {
 	if self.shardID.IsRootShard() {
 		// main-chain has no parent-chain, and not support xshard-txn
@@ -159,7 +227,9 @@ func (self *ChainManager) onBlockPersistCompleted(blk *types.Block) {
 	}
 }
 func (self *ChainManager) handleRootChainConfig(block *types.Block) error {
-	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
+	// DefConfig is config of local shard, which may run different consensus with root chain,
+	// only vbft blocks carry chain config in consensus payload
+	if len(block.Header.ConsensusPayload) == 0 {
 		return nil
 	}
 	blkInfo := &vconfig.VbftBlockInfo{}
@@ -66,7 +66,8 @@ func (self *ChainManager) updateShardConfig(shardID common.ShardID, shardcfg *sh
 	} else {
 		cfg := &config.OntologyConfig{
 			Genesis: &config.GenesisConfig{
-				VBFT: shardcfg.VbftCfg,
+				ConsensusType: shardcfg.GetConsensusType(),
+				VBFT:          shardcfg.VbftCfg,
 			},
 			Common: &config.CommonConfig{
 				GasLimit: shardcfg.GasLimit,
@@ -21,11 +21,13 @@ package xshard
 import (
 	"bytes"
 	"fmt"
+	"sort"
 
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/ledger"
 	sComm "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/smartcontract/service/native/shard_stake"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_sysmsg"
 	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
 	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
@@ -99,3 +101,66 @@ func GetShardCommitDposHeight(lgr *ledger.Ledger) (uint32, error) {
 	}
 	return shardCommitDposInfo.Height, nil
 }
+
+func GetShardSysMsgQueueShards(lgr *ledger.Ledger) ([]common.ShardID, error) {
+	data, err := lgr.GetStorageItem(utils.ShardSysMsgContractAddress, shardsysmsg.GenQueueShardsKey())
+	if err == sComm.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get shard sysmsg queue shards: %s", err)
+	}
+	shards := &shardsysmsg.QueueShards{}
+	if err := shards.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("deserialize shard sysmsg queue shards: %s", err)
+	}
+	return shards.Shards, nil
+}
+
+func GetShardSysMsgQueueState(lgr *ledger.Ledger, shardID common.ShardID) (*shardsysmsg.QueueState, error) {
+	shardIDBytes := utils.GetUint64Bytes(shardID.ToUint64())
+	data, err := lgr.GetStorageItem(utils.ShardSysMsgContractAddress, shardsysmsg.GenQueueStateKey(shardIDBytes))
+	if err == sComm.ErrNotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get shard sysmsg queue state: %s", err)
+	}
+	state := &shardsysmsg.QueueState{}
+	if err := state.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("deserialize shard sysmsg queue state: %s", err)
+	}
+	return state, nil
+}
+
+func GetShardSysMsg(lgr *ledger.Ledger, shardID common.ShardID, seq uint64) (*shardsysmsg.SysMsg, error) {
+	shardIDBytes := utils.GetUint64Bytes(shardID.ToUint64())
+	key := shardsysmsg.GenSysMsgKey(shardIDBytes, utils.GetUint64Bytes(seq))
+	data, err := lgr.GetStorageItem(utils.ShardSysMsgContractAddress, key)
+	if err == sComm.ErrNotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get shard sysmsg %d: %s", seq, err)
+	}
+	msg := &shardsysmsg.SysMsg{}
+	if err := msg.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("deserialize shard sysmsg %d: %s", seq, err)
+	}
+	return msg, nil
+}
+
+// GetShardBookkeepers: all consensus peers of shard are bookkeepers if not configured
+func GetShardBookkeepers(shardState *shardstates.ShardState, configured []string) []string {
+	if len(configured) > 0 {
+		return configured
+	}
+	bookkeepers := make([]string, 0)
+	for peerPK, info := range shardState.Peers {
+		if info.NodeType == shardstates.CONSENSUS_NODE {
+			bookkeepers = append(bookkeepers, peerPK)
+		}
+	}
+	sort.Strings(bookkeepers)
+	return bookkeepers
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package xshard
+
+import (
+	"sort"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/chainmgr/xshard_state"
+	"github.com/ontio/ontology/core/ledger"
+	sComm "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/xshard_types"
+)
+
+// TxTraceMsg is a shard msg sent by shard for the cross shard tx
+type TxTraceMsg struct {
+	Height uint32 // block height of sending shard
+	Msg    xshard_types.CommonShardMsg
+}
+
+// TxTraceNode: execution of one cross shard tx on one shard.
+//
+//	children are the executions of the same tx on remote shards (by remote invocation),
+//	and the shard txs triggered by its notifications.
+type TxTraceNode struct {
+	ShardTxID xshard_types.ShardTxID
+	ShardID   common.ShardID
+	Available bool           // ledger of shard is held by this node
+	TxHash    common.Uint256 // source tx hash for root node, shard tx hash for others
+	Height    uint32         // block height of source tx for root node, of shard tx for others
+	State     *xshard_state.TxState
+	Msgs      []*TxTraceMsg
+	Children  []*TxTraceNode
+}
+
+type traceKey struct {
+	id    xshard_types.ShardTxID
+	shard common.ShardID
+}
+
+// TraceCrossShardTx builds the execution tree of a user transaction across the shard ledgers held by this node
+func TraceCrossShardTx(sourceTxHash common.Uint256) (*TxTraceNode, error) {
+	lgrs := heldLedgers()
+	var sourceShard common.ShardID
+	var sourceHeight uint32
+	found := false
+	for id, lgr := range lgrs {
+		tx, height, err := lgr.GetTransactionWithHeight(sourceTxHash)
+		if err == nil && tx != nil {
+			sourceShard, sourceHeight, found = id, height, true
+			break
+		}
+	}
+	if !found {
+		return nil, sComm.ErrNotFound
+	}
+
+	visited := make(map[traceKey]bool)
+	root := traceTxNode(lgrs, sourceTxHash, xshard_types.NewShardTxID(sourceTxHash), sourceShard, visited)
+	root.TxHash = sourceTxHash
+	root.Height = sourceHeight
+	return root, nil
+}
+
+func heldLedgers() map[common.ShardID]*ledger.Ledger {
+	ledger.DefLedgerMgr.Lock.RLock()
+	defer ledger.DefLedgerMgr.Lock.RUnlock()
+
+	lgrs := make(map[common.ShardID]*ledger.Ledger)
+	for id, lgr := range ledger.DefLedgerMgr.Ledgers {
+		lgrs[id] = lgr
+	}
+	return lgrs
+}
+
+func traceTxNode(lgrs map[common.ShardID]*ledger.Ledger, sourceTxHash common.Uint256, id xshard_types.ShardTxID,
+	shardID common.ShardID, visited map[traceKey]bool) *TxTraceNode {
+	node := &TxTraceNode{
+		ShardTxID: id,
+		ShardID:   shardID,
+		Msgs:      make([]*TxTraceMsg, 0),
+		Children:  make([]*TxTraceNode, 0),
+	}
+	visited[traceKey{id, shardID}] = true
+	lgr, present := lgrs[shardID]
+	if !present {
+		return node
+	}
+	node.Available = true
+	if shardTxHash, err := lgr.GetShardTxHashBySourceTxHash(sourceTxHash); err == nil {
+		node.TxHash = shardTxHash
+		// height is kept in store after shard tx pruned
+		if _, height, err := lgr.GetShardTxWithHeight(shardTxHash); err == nil || err == sComm.ErrPruned {
+			node.Height = height
+		}
+	}
+	node.Msgs = getTxTraceMsgs(lgr, id)
+	if node.Height == 0 && len(node.Msgs) > 0 {
+		// shard tx hash not recorded, the tx sends its first msgs in the block executing it
+		node.Height = node.Msgs[0].Height
+	}
+
+	state, err := lgr.GetShardTxStateByID(id)
+	if err != nil {
+		return node
+	}
+	node.State = state
+
+	// remote shards invoked by the tx
+	shards := make([]common.ShardID, 0, len(state.Shards))
+	for s := range state.Shards {
+		shards = append(shards, s)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i].ToUint64() < shards[j].ToUint64() })
+	for _, s := range shards {
+		if visited[traceKey{id, s}] {
+			continue
+		}
+		node.Children = append(node.Children, traceTxNode(lgrs, sourceTxHash, id, s, visited))
+	}
+	// shard txs triggered by notifications, tx id of notification is tx id + notify id
+	for _, n := range state.ShardNotifies {
+		sink := common.NewZeroCopySink(0)
+		sink.WriteBytes([]byte(id))
+		sink.WriteUint32(n.NotifyID)
+		notifyTxID := xshard_types.ShardTxID(string(sink.Bytes()))
+		if visited[traceKey{notifyTxID, n.TargetShardID}] {
+			continue
+		}
+		node.Children = append(node.Children, traceTxNode(lgrs, sourceTxHash, notifyTxID, n.TargetShardID, visited))
+	}
+	return node
+}
+
+func getTxTraceMsgs(lgr *ledger.Ledger, id xshard_types.ShardTxID) []*TxTraceMsg {
+	msgs := make([]*TxTraceMsg, 0)
+	heights, err := lgr.GetXShardTxMsgHeights(id)
+	if err != nil {
+		return msgs
+	}
+	for _, height := range heights {
+		shards, err := lgr.GetRelatedShardIDsInBlock(height)
+		if err != nil {
+			continue
+		}
+		for _, shard := range shards {
+			shardMsgs, err := lgr.GetShardMsgsInBlock(height, shard)
+			if err != nil {
+				continue
+			}
+			for _, msg := range shardMsgs {
+				if msg.GetShardTxID() == id {
+					msgs = append(msgs, &TxTraceMsg{Height: height, Msg: msg})
+				}
+			}
+		}
+	}
+	return msgs
+}
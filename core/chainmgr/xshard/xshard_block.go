@@ -37,6 +37,7 @@ import (
 	com "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/core/xshard_types"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 )
 
 // cross  shard pool
@@ -208,11 +209,29 @@ func AddCrossShardInfo(lgr *ledger.Ledger, crossShardMsg *types.CrossShardMsg) e
 // NOTE: all cross-shard tx/events should be indexed with (parentHeight, shardHeight)
 //
 
-func GetCrossShardTxs(lgr *ledger.Ledger, account *account.Account, toShardID common.ShardID, beginParentblkNum, endParentblkNum uint32) (map[common.ShardID][]*types.CrossShardTxInfos, error) {
+// CrossShardTxBudget returns max count of cross-shard txs in block proposed with blockTxs txs from tx pool,
+// cross-shard txs fill the rest of block, and reserved count of them are always allowed. 0 for no limit,
+// if no room is reserved for cross-shard txs
+func CrossShardTxBudget(blockTxs int) int {
+	reserved := int(config.DefConfig.Consensus.ReservedSysTxInBlock)
+	if reserved == 0 {
+		return 0
+	}
+	budget := int(config.DefConfig.Consensus.MaxTxInBlock) - blockTxs
+	if budget < reserved {
+		budget = reserved
+	}
+	return budget
+}
+
+// GetCrossShardTxs: at most maxTxs cross-shard txs from shards other than parent are returned, 0 for no limit.
+// Txs of parent shard are not limited, parent height of block goes with them
+func GetCrossShardTxs(lgr *ledger.Ledger, account *account.Account, toShardID common.ShardID, beginParentblkNum, endParentblkNum uint32, maxTxs int) (map[common.ShardID][]*types.CrossShardTxInfos, error) {
 	pool := crossShardPool
 	pool.lock.RLock()
 	defer pool.lock.RUnlock()
 	crossShardMapInfos := make(map[common.ShardID][]*types.CrossShardTxInfos)
+	txCount := 0
 	if !toShardID.IsRootShard() && lgr.ParentLedger != nil {
 		crossShardInfo := make([]*types.CrossShardTxInfos, 0)
 		for blkNum := beginParentblkNum + 1; blkNum <= endParentblkNum; blkNum++ {
@@ -233,6 +252,7 @@ func GetCrossShardTxs(lgr *ledger.Ledger, account *account.Account, toShardID co
 			crossShardInfo = append(crossShardInfo, shardTxInfo)
 		}
 		crossShardMapInfos[toShardID.ParentID()] = crossShardInfo
+		txCount += len(crossShardInfo)
 		log.Infof("GetCrossShardTxs, shardId %v, txNum %d", toShardID.ParentID(), len(crossShardInfo))
 	}
 	for shardID, shardMsgs := range pool.Shards {
@@ -261,6 +281,16 @@ func GetCrossShardTxs(lgr *ledger.Ledger, account *account.Account, toShardID co
 				msgHash = CalCrossShardMsgRootHash(shardMsg.CrossShardMsgInfo, shardMsg.ShardMsg)
 			}
 		}
+		// msgs of shard are chained, later msgs are left in pool for next blocks
+		if maxTxs > 0 {
+			left := maxTxs - txCount
+			if left < 0 {
+				left = 0
+			}
+			if len(crossShardMsgs) > left {
+				crossShardMsgs = crossShardMsgs[:left]
+			}
+		}
 		for _, msg := range crossShardMsgs {
 			tx, err := crossshard.NewCrossShardTxMsg(account, msg.CrossShardMsgInfo.SignMsgHeight, toShardID, config.DefConfig.Common.GasPrice, config.DefConfig.Common.GasLimit, msg.ShardMsg)
 			if err != nil {
@@ -274,6 +304,7 @@ func GetCrossShardTxs(lgr *ledger.Ledger, account *account.Account, toShardID co
 			crossShardInfo = append(crossShardInfo, shardTxInfo)
 		}
 		crossShardMapInfos[shardID] = crossShardInfo
+		txCount += len(crossShardInfo)
 		log.Infof("GetCrossShardTxs, shardId %v, txNum %d", shardID, len(crossShardInfo))
 	}
 	return crossShardMapInfos, nil
@@ -322,19 +353,30 @@ func CalCrossShardMsgRootHash(crossShardMsgInfo *types.CrossShardMsgInfo, msgs [
 }
 
 func VerifyCrossShardMsg(shardID common.ShardID, sourceShardID common.ShardID, lgr *ledger.Ledger, crossShardMsgInfo *types.CrossShardMsgInfo, shardMsg []xshard_types.CommonShardMsg) bool {
-	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
-		return true
-	}
 	if !shardID.IsRootShard() {
 		lgr = lgr.ParentLedger
 	}
+	if !sourceShardID.IsRootShard() {
+		// msgs from non-vbft shard are signed by bookkeepers of shard, not verifiable with vbft chain config,
+		// bookkeepers are resolved with shard config at the height msgs signed, not current shard state
+		if shardState, err := getShardStateAtHeight(lgr, sourceShardID, crossShardMsgInfo.SignMsgHeight); err == nil {
+			if shardState.Config.GetConsensusType() != config.CONSENSUS_TYPE_VBFT {
+				return verifyShardBookkeepersCrossShardMsg(shardState, crossShardMsgInfo, shardMsg)
+			}
+		}
+	}
+	if sourceShardID.IsRootShard() {
+		// consensus config of root is only recorded by vbft config blocks
+		if _, err := lgr.GetShardConsensusConfig(sourceShardID, crossShardMsgInfo.SignMsgHeight); err == com.ErrNotFound {
+			return verifyRootBookkeepersCrossShardMsg(lgr, crossShardMsgInfo, shardMsg)
+		}
+	}
 	chainconfig, err := csm.GetShardConfigByShardID(lgr, sourceShardID, crossShardMsgInfo.SignMsgHeight)
 	if err != nil {
 		log.Errorf("GetShardConfigByShardID shardID:%v,height:%d err:%s", sourceShardID, crossShardMsgInfo.SignMsgHeight, err)
 		return false
 	}
 	var bookkeepers []keypair.PublicKey
-	m := int(chainconfig.N - (chainconfig.N-1)/3)
 	for _, peer := range chainconfig.Peers {
 		pubkey, err := vconfig.Pubkey(peer.ID)
 		if err != nil {
@@ -343,15 +385,165 @@ func VerifyCrossShardMsg(shardID common.ShardID, sourceShardID common.ShardID, l
 		}
 		bookkeepers = append(bookkeepers, pubkey)
 	}
+	m := int(chainconfig.N - (chainconfig.N-1)/3)
+	return verifyCrossShardMsgSigs(bookkeepers, m, crossShardMsgInfo, shardMsg)
+}
+
+// getShardStateAtHeight: config and peers of shard recorded in ledger, which are effective at height of shard
+func getShardStateAtHeight(lgr *ledger.Ledger, shardID common.ShardID, height uint32) (*shardstates.ShardState, error) {
+	data, err := lgr.GetShardConsensusConfig(shardID, height)
+	if err != nil {
+		return nil, fmt.Errorf("get shard %d config at height %d: %s", shardID.ToUint64(), height, err)
+	}
+	evt := &shardstates.ConfigShardEvent{}
+	if err := evt.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("deserialize shard %d config at height %d: %s", shardID.ToUint64(), height, err)
+	}
+	return &shardstates.ShardState{
+		ShardID: shardID,
+		Config:  evt.Config,
+		Peers:   evt.Peers,
+	}, nil
+}
+
+// verifyShardBookkeepersCrossShardMsg: msgs from solo/dbft/sbft shard are signed by M-of-N bookkeepers of shard,
+// the bookkeepers are the same as the shard chain config built from shard state
+func verifyShardBookkeepersCrossShardMsg(shardState *shardstates.ShardState, crossShardMsgInfo *types.CrossShardMsgInfo, shardMsg []xshard_types.CommonShardMsg) bool {
+	var configured []string
+	switch shardState.Config.GetConsensusType() {
+	case config.CONSENSUS_TYPE_SOLO:
+		if shardState.Config.SoloCfg != nil {
+			configured = shardState.Config.SoloCfg.Bookkeepers
+		}
+	case config.CONSENSUS_TYPE_DBFT:
+		if shardState.Config.DbftCfg != nil {
+			configured = shardState.Config.DbftCfg.Bookkeepers
+		}
+	case config.CONSENSUS_TYPE_SBFT:
+		if shardState.Config.SbftCfg != nil {
+			configured = shardState.Config.SbftCfg.Bookkeepers
+		}
+	default:
+		log.Errorf("verify crossshardMsg: unknown consensus type of shard %d", shardState.ShardID.ToUint64())
+		return false
+	}
+	peers := GetShardBookkeepers(shardState, configured)
+	if shardState.Config.GetConsensusType() == config.CONSENSUS_TYPE_SOLO && len(peers) > 1 {
+		// solo shard has only one bookkeeper
+		peers = peers[:1]
+	}
+	var bookkeepers []keypair.PublicKey
+	for _, peer := range peers {
+		pubkey, err := vconfig.Pubkey(peer)
+		if err != nil {
+			log.Errorf("pubKey bookkeeper:%s, err:%s", peer, err)
+			return false
+		}
+		bookkeepers = append(bookkeepers, pubkey)
+	}
+	m := len(bookkeepers) - (len(bookkeepers)-1)/3
+	return verifyCrossShardMsgSigs(bookkeepers, m, crossShardMsgInfo, shardMsg)
+}
+
+// verifyRootBookkeepersCrossShardMsg: msgs from non-vbft root are signed by M-of-N bookkeepers of root ledger
+func verifyRootBookkeepersCrossShardMsg(lgr *ledger.Ledger, crossShardMsgInfo *types.CrossShardMsgInfo, shardMsg []xshard_types.CommonShardMsg) bool {
+	for lgr != nil && !lgr.ShardID.IsRootShard() {
+		lgr = lgr.ParentLedger
+	}
+	if lgr == nil {
+		log.Errorf("verify crossshardMsg: root ledger not found")
+		return false
+	}
+	bkState, err := lgr.GetBookkeeperState()
+	if err != nil {
+		log.Errorf("verify crossshardMsg: get root bookkeepers: %s", err)
+		return false
+	}
+	bookkeepers := bkState.CurrBookkeeper
+	m := len(bookkeepers) - (len(bookkeepers)-1)/3
+	return verifyCrossShardMsgSigs(bookkeepers, m, crossShardMsgInfo, shardMsg)
+}
+
+func verifyCrossShardMsgSigs(bookkeepers []keypair.PublicKey, m int, crossShardMsgInfo *types.CrossShardMsgInfo, shardMsg []xshard_types.CommonShardMsg) bool {
+	if len(bookkeepers) == 0 {
+		log.Errorf("verify crossshardMsg: no bookkeepers")
+		return false
+	}
 	sigData := make([][]byte, 0)
 	for _, sig := range crossShardMsgInfo.ShardMsgInfo.SigData {
 		sigData = append(sigData, sig)
 	}
 	msgRoot := CalCrossShardMsgRootHash(crossShardMsgInfo, shardMsg)
-	err = sign.VerifyMultiSignature(msgRoot[:], bookkeepers, m, sigData)
-	if err != nil {
-		log.Errorf("verifycrossshardMsg VerifyMultiSignature:%s,Bookkeepers:%d,pubkey:%d,signnum:%d", err, len(bookkeepers), m, len(sigData))
+	if err := sign.VerifyMultiSignature(msgRoot[:], bookkeepers, m, sigData); err != nil {
+		log.Errorf("verifycrossshardMsg VerifyMultiSignature:%s,Bookkeepers:%d,m:%d,signnum:%d", err, len(bookkeepers), m, len(sigData))
 		return false
 	}
 	return true
 }
+
+// VerifyCrossShardTxs: check cross shard txs in block proposal, msgs from parent shard are delivered with parent blocks
+// in parent height range of proposal, msgs from other shards are checked with received cross shard msgs
+func VerifyCrossShardTxs(lgr *ledger.Ledger, shardID common.ShardID, block *types.Block, prevParentHeight uint32) error {
+	for sourceShardID, crossTxs := range block.ShardTxs {
+		if sourceShardID.IsRootShard() && !shardID.IsRootShard() {
+			msgs := make([]xshard_types.CommonShardMsg, 0)
+			for _, crossTx := range crossTxs {
+				shardCall, ok := crossTx.Tx.Payload.(*payload.ShardCall)
+				if !ok {
+					return fmt.Errorf("invalid cross shard tx from shard %d", sourceShardID.ToUint64())
+				}
+				msgs = append(msgs, shardCall.Msgs...)
+			}
+			parentMsgs := make([]xshard_types.CommonShardMsg, 0)
+			for blkNum := prevParentHeight + 1; blkNum <= block.Header.ParentHeight; blkNum++ {
+				shardMsgs, err := lgr.ParentLedger.GetShardMsgsInBlock(blkNum, shardID)
+				if err == com.ErrNotFound {
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("GetShardMsgsInBlock shardID:%v,height:%d err:%s", shardID, blkNum, err)
+				}
+				parentMsgs = append(parentMsgs, shardMsgs...)
+			}
+			if xshard_types.GetShardCommonMsgsHash(msgs) != xshard_types.GetShardCommonMsgsHash(parentMsgs) {
+				return fmt.Errorf("cross shard msgs from parent shard unmatched")
+			}
+			continue
+		}
+		for _, crossTx := range crossTxs {
+			shardCall, ok := crossTx.Tx.Payload.(*payload.ShardCall)
+			if !ok || crossTx.ShardMsg == nil {
+				return fmt.Errorf("invalid cross shard tx from shard %d", sourceShardID.ToUint64())
+			}
+			txMsg, err := GetCrossShardMsg(lgr, sourceShardID, crossTx.ShardMsg.PreCrossShardMsgHash)
+			if err != nil {
+				return fmt.Errorf("GetCrossShardMsg err:%s", err)
+			}
+			if xshard_types.GetShardCommonMsgsHash(shardCall.Msgs) != xshard_types.GetShardCommonMsgsHash(txMsg.ShardMsg) {
+				return fmt.Errorf("cross shard msgs from shard %d unmatched", sourceShardID.ToUint64())
+			}
+		}
+	}
+	return nil
+}
+
+// GetShardMsgsInBlock: get all cross shard msgs generated in block, ordered by target shard
+func GetShardMsgsInBlock(lgr *ledger.Ledger, blkNum uint32) ([]xshard_types.CommonShardMsg, error) {
+	shards, err := lgr.GetRelatedShardIDsInBlock(blkNum)
+	if err == com.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetRelatedShardIDsInBlock height:%d err:%s", blkNum, err)
+	}
+	common.SortShardID(shards)
+	msgs := make([]xshard_types.CommonShardMsg, 0)
+	for _, shardID := range shards {
+		shardMsgs, err := lgr.GetShardMsgsInBlock(blkNum, shardID)
+		if err != nil {
+			return nil, fmt.Errorf("GetShardMsgsInBlock shardID:%v,height:%d err:%s", shardID, blkNum, err)
+		}
+		msgs = append(msgs, shardMsgs...)
+	}
+	return msgs, nil
+}
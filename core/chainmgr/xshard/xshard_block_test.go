@@ -24,10 +24,13 @@ import (
 	"github.com/ontio/ontology/account"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/chainmgr/message"
 	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/core/xshard_types"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -126,3 +129,69 @@ func TestCrossShardTxInfos_Serialize(t *testing.T) {
 		t.Errorf("cross shardTx info index:%d not equal index:%d", crossShardTxInfos.ShardMsg.Index, sharTxInfos.ShardMsg.Index)
 	}
 }
+
+func TestVerifyShardBookkeepersCrossShardMsg(t *testing.T) {
+	accs := make([]*account.Account, 0)
+	bookkeepers := make([]string, 0)
+	for i := 0; i < 4; i++ {
+		acc := account.NewAccount("")
+		accs = append(accs, acc)
+		bookkeepers = append(bookkeepers, vconfig.PubkeyID(acc.PublicKey))
+	}
+	shardState := &shardstates.ShardState{
+		ShardID: common.NewShardIDUnchecked(1),
+		Config: &shardstates.ShardConfig{
+			ConsensusType: config.CONSENSUS_TYPE_SBFT,
+			SbftCfg:       &config.SBFTConfig{Bookkeepers: bookkeepers},
+		},
+	}
+	crossShardMsg := newTestShardMsg(t)
+	msgInfo := crossShardMsg.CrossShardMsgInfo
+	msgRoot := CalCrossShardMsgRootHash(msgInfo, crossShardMsg.ShardMsg)
+	sign := func(signers []*account.Account) {
+		msgInfo.ShardMsgInfo.SigData = make(map[uint32][]byte)
+		for i, acc := range signers {
+			sig, err := signature.Sign(acc, msgRoot[:])
+			assert.Nil(t, err)
+			msgInfo.ShardMsgInfo.SigData[uint32(i)] = sig
+		}
+	}
+
+	// sbft shard msgs need a quorum of bookkeepers
+	sign(accs[:1])
+	assert.False(t, verifyShardBookkeepersCrossShardMsg(shardState, msgInfo, crossShardMsg.ShardMsg))
+	sign(accs[:2])
+	assert.False(t, verifyShardBookkeepersCrossShardMsg(shardState, msgInfo, crossShardMsg.ShardMsg))
+	sign(accs[:3])
+	assert.True(t, verifyShardBookkeepersCrossShardMsg(shardState, msgInfo, crossShardMsg.ShardMsg))
+	// msgs signed by others are rejected
+	sign([]*account.Account{account.NewAccount(""), account.NewAccount(""), account.NewAccount("")})
+	assert.False(t, verifyShardBookkeepersCrossShardMsg(shardState, msgInfo, crossShardMsg.ShardMsg))
+
+	// solo shard msgs are signed by the first bookkeeper only
+	shardState.Config = &shardstates.ShardConfig{
+		ConsensusType: config.CONSENSUS_TYPE_SOLO,
+		SoloCfg:       &config.SOLOConfig{Bookkeepers: bookkeepers},
+	}
+	sign(accs[1:2])
+	assert.False(t, verifyShardBookkeepersCrossShardMsg(shardState, msgInfo, crossShardMsg.ShardMsg))
+	sign(accs[:1])
+	assert.True(t, verifyShardBookkeepersCrossShardMsg(shardState, msgInfo, crossShardMsg.ShardMsg))
+}
+
+func TestCrossShardTxBudget(t *testing.T) {
+	maxTxs, reserved := config.DefConfig.Consensus.MaxTxInBlock, config.DefConfig.Consensus.ReservedSysTxInBlock
+	defer func() {
+		config.DefConfig.Consensus.MaxTxInBlock, config.DefConfig.Consensus.ReservedSysTxInBlock = maxTxs, reserved
+	}()
+
+	config.DefConfig.Consensus.MaxTxInBlock = 100
+	config.DefConfig.Consensus.ReservedSysTxInBlock = 0
+	assert.Equal(t, 0, CrossShardTxBudget(100))
+
+	// cross-shard txs fill the rest of block, at least reserved count
+	config.DefConfig.Consensus.ReservedSysTxInBlock = 10
+	assert.Equal(t, 70, CrossShardTxBudget(30))
+	assert.Equal(t, 10, CrossShardTxBudget(90))
+	assert.Equal(t, 10, CrossShardTxBudget(120))
+}
@@ -108,6 +108,7 @@ type TxState struct {
 	ResultErr      string
 	LockedAddress  []common.Address
 	LockedKeys     [][]byte
+	PreparedFee    uint64 // fee charged from payer when tx is prepared, refunded if tx is aborted
 	WriteSet       *overlaydb.MemDB
 	Notify         *event.ExecuteNotify
 }
@@ -306,6 +307,10 @@ func (self *TxState) Deserialization(source *common.ZeroCopySource) error {
 	if err != nil {
 		return err
 	}
+	self.PreparedFee, eof = source.NextUint64()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
 
 	buf, _, irr, eof := source.NextVarBytes()
 	if irr {
@@ -396,6 +401,7 @@ func (self *TxState) Serialization(sink *common.ZeroCopySink) {
 
 	sink.WriteAddrList(self.LockedAddress)
 	sink.WriteVarBytesArray(self.LockedKeys)
+	sink.WriteUint64(self.PreparedFee)
 
 	buf, _ := json.Marshal(self.Notify)
 	sink.WriteVarBytes(buf)
@@ -56,6 +56,7 @@ var (
 var GenBlockTime = (config.DEFAULT_GEN_BLOCK_TIME * time.Second)
 
 const NAME_GAS_PRICE = "gasPrice"
+const NAME_XSHARD_TX_TIMEOUT = "xshardTxTimeout"
 
 var INIT_PARAM = map[string]string{
 	NAME_GAS_PRICE: "0",
@@ -34,7 +34,9 @@ import (
 	"github.com/ontio/ontology/core/store/ledgerstore"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/core/xshard_types"
+	"github.com/ontio/ontology/events"
 	"github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/merkle"
 	"github.com/ontio/ontology/smartcontract/event"
 	cstate "github.com/ontio/ontology/smartcontract/states"
 )
@@ -47,6 +49,7 @@ type Ledger struct {
 	ParentLedger *Ledger
 	ldgStore     store.LedgerStore
 	cshardStore  store.CrossShardStore
+	consStore    store.ConsensusStore
 	ChildLedger  *Ledger
 }
 
@@ -74,10 +77,17 @@ func NewLedger(dataDir string, stateHashHeight uint32) (*Ledger, error) {
 	if err != nil {
 		return nil, fmt.Errorf("NewCrossShardStore error %s", err)
 	}
+	ldgStore.SetCrossShardStore(cshardStore)
+	consStore, err := ledgerstore.NewConsensusStore(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("NewConsensusStore error %s", err)
+	}
+	ldgStore.SetConsensusStore(consStore)
 	lgr := &Ledger{
 		ShardID:     common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID),
 		ldgStore:    ldgStore,
 		cshardStore: cshardStore,
+		consStore:   consStore,
 	}
 
 	DefLedgerMgr.Lock.Lock()
@@ -118,14 +128,24 @@ func NewShardLedger(shardID common.ShardID, dataDir string, mainLedger *Ledger)
 	if err != nil {
 		return nil, fmt.Errorf("NewCrossShardStore %d error %s", shardID, err)
 	}
+	ldgStore.SetCrossShardStore(cshardStore)
+	consStore, err := ledgerstore.NewConsensusStore(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("NewConsensusStore %d error %s", shardID, err)
+	}
+	ldgStore.SetConsensusStore(consStore)
 
 	lgr := &Ledger{
 		ShardID:      shardID,
 		ParentLedger: parentLedger,
 		ldgStore:     ldgStore,
 		cshardStore:  cshardStore,
+		consStore:    consStore,
 	}
 	parentLedger.ChildLedger = lgr
+	if parentStore, ok := parentLedger.ldgStore.(*ledgerstore.LedgerStoreImp); ok {
+		parentStore.AddChildShardStore(shardID, ldgStore)
+	}
 	DefLedgerMgr.Lock.Lock()
 	defer DefLedgerMgr.Lock.Unlock()
 	DefLedgerMgr.Ledgers[lgr.ShardID] = lgr
@@ -148,6 +168,21 @@ func RemoveLedger(shardID common.ShardID) {
 	delete(DefLedgerMgr.Ledgers, shardID)
 }
 
+// CloseShardLedger closes ledger of shard, and removes it from ledger manager
+func CloseShardLedger(shardID common.ShardID) error {
+	DefLedgerMgr.Lock.Lock()
+	defer DefLedgerMgr.Lock.Unlock()
+	lgr := DefLedgerMgr.Ledgers[shardID]
+	if lgr == nil {
+		return nil
+	}
+	delete(DefLedgerMgr.Ledgers, shardID)
+	if lgr.ParentLedger != nil && lgr.ParentLedger.ChildLedger == lgr {
+		lgr.ParentLedger.ChildLedger = nil
+	}
+	return lgr.Close()
+}
+
 func CloseLedgers() {
 	DefLedgerMgr.Lock.Lock()
 	defer DefLedgerMgr.Lock.Unlock()
@@ -294,6 +329,10 @@ func (self *Ledger) GetCurrentHeaderHash() common.Uint256 {
 	return self.ldgStore.GetCurrentHeaderHash()
 }
 
+func (self *Ledger) GetShardTxWithHeight(shardTxHash common.Uint256) (*types.CrossShardTxInfos, uint32, error) {
+	return self.ldgStore.GetShardTx(shardTxHash)
+}
+
 func (self *Ledger) IsContainShardTx(shardTxHash common.Uint256) (bool, error) {
 	return self.ldgStore.IsContainShardTx(shardTxHash)
 }
@@ -329,6 +368,33 @@ func (self *Ledger) GetStorageItem(codeHash common.Address, key []byte) ([]byte,
 	return storageItem.Value, nil
 }
 
+func (self *Ledger) GetStorageItemAtHeight(codeHash common.Address, key []byte, height uint32) ([]byte, error) {
+	storageKey := &states.StorageKey{
+		ContractAddress: codeHash,
+		Key:             key,
+	}
+	storageItem, err := self.ldgStore.GetStorageItemAtHeight(storageKey, height)
+	if err != nil {
+		return nil, err
+	}
+	if storageItem == nil {
+		return nil, nil
+	}
+	return storageItem.Value, nil
+}
+
+func (self *Ledger) GetStorageTrieRoot(height uint32) (common.Uint256, error) {
+	return self.ldgStore.GetStorageTrieRoot(height)
+}
+
+func (self *Ledger) GetStorageProof(codeHash common.Address, key []byte, height uint32) ([]byte, *merkle.SparseMerkleProof, error) {
+	storageKey := &states.StorageKey{
+		ContractAddress: codeHash,
+		Key:             key,
+	}
+	return self.ldgStore.GetStorageProof(storageKey, height)
+}
+
 func (self *Ledger) GetContractState(contractHash common.Address) (*payload.DeployCode, error) {
 	return self.ldgStore.GetContractState(contractHash)
 }
@@ -341,6 +407,10 @@ func (self *Ledger) PreExecuteContract(tx *types.Transaction) (*cstate.PreExecRe
 	return self.ldgStore.PreExecuteContract(tx)
 }
 
+func (self *Ledger) PreExecuteContractAtHeight(tx *types.Transaction, height uint32) (*cstate.PreExecResult, error) {
+	return self.ldgStore.PreExecuteContractAtHeight(tx, height)
+}
+
 func (self *Ledger) GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error) {
 	return self.ldgStore.GetEventNotifyByTx(tx)
 }
@@ -376,6 +446,14 @@ func (self *Ledger) GetShardTxState(txHash common.Uint256, notifyId uint32, hasN
 	return self.ldgStore.GetShardTxState(txHash, notifyId, hasNotifyId)
 }
 
+func (self *Ledger) GetShardTxStateByID(id xshard_types.ShardTxID) (*xshard_state.TxState, error) {
+	return self.ldgStore.GetShardTxStateByID(id)
+}
+
+func (self *Ledger) GetXShardTxMsgHeights(id xshard_types.ShardTxID) ([]uint32, error) {
+	return self.ldgStore.GetXShardTxMsgHeights(id)
+}
+
 func (self *Ledger) GetShardTxHashBySourceTxHash(sourceTxHash common.Uint256) (common.Uint256, error) {
 	return self.cshardStore.GetShardTxHashBySourceTxHash(sourceTxHash)
 }
@@ -383,7 +461,29 @@ func (self *Ledger) SaveShardTxHashWithSourceTxHash(sourceTxHash, shardTxHash co
 	return self.cshardStore.SaveShardTxHashWithSourceTxHash(sourceTxHash, shardTxHash)
 }
 func (self *Ledger) SaveCrossShardMsgByHash(msgHash common.Uint256, crossShardMsg *types.CrossShardMsg) error {
-	return self.cshardStore.SaveCrossShardMsgByHash(msgHash, crossShardMsg)
+	if err := self.cshardStore.SaveCrossShardMsgByHash(msgHash, crossShardMsg); err != nil {
+		return err
+	}
+	if len(crossShardMsg.ShardMsg) == 0 {
+		return nil
+	}
+	fromShard := crossShardMsg.ShardMsg[0].GetSourceShardID()
+	toShard := crossShardMsg.ShardMsg[0].GetTargetShardID()
+	if fromShard != self.ShardID {
+		crossShardMsgCounter.Inc(shardLabel(self.ShardID), "in", shardLabel(fromShard))
+	} else {
+		crossShardMsgCounter.Inc(shardLabel(self.ShardID), "out", shardLabel(toShard))
+	}
+	if events.DefActorPublisher != nil {
+		events.DefActorPublisher.Publish(message.TOPIC_CROSS_SHARD_MSG,
+			&message.CrossShardMsgEvent{
+				FromShard: fromShard,
+				ToShard:   toShard,
+				Incoming:  fromShard != self.ShardID,
+				Msg:       crossShardMsg,
+			})
+	}
+	return nil
 }
 func (self *Ledger) GetCrossShardMsgByHash(msgHash common.Uint256) (*types.CrossShardMsg, error) {
 	return self.cshardStore.GetCrossShardMsgByHash(msgHash)
@@ -411,12 +511,38 @@ func (self *Ledger) GetShardMsgHash(shardID common.ShardID) (common.Uint256, err
 	return self.cshardStore.GetShardMsgHash(shardID)
 }
 
+// SaveBlockConsensusMsgs saves consensus msgs which prove the finality of block
+func (self *Ledger) SaveBlockConsensusMsgs(height uint32, data []byte) error {
+	return self.consStore.SaveBlockConsensusMsgs(height, data)
+}
+
+func (self *Ledger) GetBlockConsensusMsgs(height uint32) ([]byte, error) {
+	return self.consStore.GetBlockConsensusMsgs(height)
+}
+
+// SaveRoundConsensusMsgs saves consensus msgs of current round, to resume consensus after restart
+func (self *Ledger) SaveRoundConsensusMsgs(data []byte) error {
+	return self.consStore.SaveRoundConsensusMsgs(data)
+}
+
+func (self *Ledger) GetRoundConsensusMsgs() ([]byte, error) {
+	return self.consStore.GetRoundConsensusMsgs()
+}
+
 func (self *Ledger) Close() error {
+	if self.ParentLedger != nil {
+		if parentStore, ok := self.ParentLedger.ldgStore.(*ledgerstore.LedgerStoreImp); ok {
+			parentStore.RemoveChildShardStore(self.ShardID)
+		}
+	}
 	err := self.ldgStore.Close()
 	if err != nil {
 		return err
 	}
-	return self.cshardStore.Close()
+	if err := self.cshardStore.Close(); err != nil {
+		return err
+	}
+	return self.consStore.Close()
 }
 
 func (self *Ledger) GetParentHeight() uint32 {
@@ -0,0 +1,35 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledger
+
+import (
+	"strconv"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/metrics"
+)
+
+// crossShardMsgCounter counts cross-shard msgs saved to ledger of shard,
+// direction is "in" for msgs from remote shard, "out" for msgs to remote shard
+var crossShardMsgCounter = metrics.NewCounter("ontology_cross_shard_msgs_total",
+	"Cross-shard messages saved to shard ledger", "shard", "direction", "remote_shard")
+
+func shardLabel(shardID common.ShardID) string {
+	return strconv.FormatUint(shardID.ToUint64(), 10)
+}
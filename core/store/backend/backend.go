@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package backend is the registry of the PersistStore implementations, which the ledger stores
+// are opened with according to the configured db backend
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ontio/ontology/core/store/badgerstore"
+	"github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+)
+
+const (
+	LEVELDB = "leveldb" // default backend
+	BADGER  = "badger"
+	MEMORY  = "memory" // data is lost when store closed, only for tests
+)
+
+// Creator opens the PersistStore at path
+type Creator func(path string) (common.PersistStore, error)
+
+var (
+	lock     sync.RWMutex
+	creators = map[string]Creator{
+		LEVELDB: func(path string) (common.PersistStore, error) {
+			return leveldbstore.NewLevelDBStore(path)
+		},
+		BADGER: func(path string) (common.PersistStore, error) {
+			return badgerstore.NewBadgerStore(path)
+		},
+		MEMORY: func(path string) (common.PersistStore, error) {
+			return leveldbstore.NewMemLevelDBStore()
+		},
+	}
+)
+
+// Register adds a PersistStore implementation to the registry
+func Register(name string, creator Creator) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := creators[name]; ok {
+		return fmt.Errorf("db backend %s already registered", name)
+	}
+	creators[name] = creator
+	return nil
+}
+
+// Backends returns the sorted names of the registered backends
+func Backends() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	names := make([]string, 0, len(creators))
+	for name := range creators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPersistStore opens the PersistStore at path with the backend of name, empty name for leveldb
+func NewPersistStore(name, path string) (common.PersistStore, error) {
+	if name == "" {
+		name = LEVELDB
+	}
+	lock.RLock()
+	creator, ok := creators[name]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported db backend %s, available backends: %v", name, Backends())
+	}
+	return creator(path)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/ontio/ontology/core/store/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPersistStore(t *testing.T) {
+	_, err := NewPersistStore("unknown", "")
+	assert.NotNil(t, err)
+	assert.NotNil(t, Register(LEVELDB, nil))
+	assert.Nil(t, Register("test", func(path string) (common.PersistStore, error) {
+		return NewPersistStore(MEMORY, path)
+	}))
+	assert.Equal(t, []string{BADGER, LEVELDB, MEMORY, "test"}, Backends())
+}
+
+func TestBackendIterator(t *testing.T) {
+	for _, name := range Backends() {
+		dir, err := ioutil.TempDir("", name)
+		assert.Nil(t, err)
+		store, err := NewPersistStore(name, dir)
+		assert.Nil(t, err, name)
+		testIterator(t, name, store)
+		assert.Nil(t, store.Close())
+		os.RemoveAll(dir)
+	}
+}
+
+func testIterator(t *testing.T, name string, store common.PersistStore) {
+	N := 20
+	key := func(i int) []byte {
+		return []byte("key" + strconv.Itoa(100+i))
+	}
+	store.NewBatch()
+	for i := 0; i < N; i++ {
+		store.BatchPut(key(i), []byte(strconv.Itoa(i)))
+	}
+	store.BatchPut([]byte("kez"), []byte("out of prefix"))
+	store.BatchDelete(key(N - 1))
+	assert.Nil(t, store.BatchCommit(), name)
+
+	iter := store.NewIterator([]byte("key"))
+	n := 0
+	for has := iter.First(); has; has = iter.Next() {
+		assert.Equal(t, key(n), iter.Key(), name)
+		assert.Equal(t, []byte(strconv.Itoa(n)), iter.Value(), name)
+		n++
+	}
+	assert.Equal(t, N-1, n, name)
+	for has := iter.Last(); has; has = iter.Prev() {
+		n--
+		assert.Equal(t, key(n), iter.Key(), name)
+	}
+	assert.Equal(t, 0, n, name)
+
+	assert.True(t, iter.Seek(key(5)), name)
+	assert.Equal(t, key(5), iter.Key(), name)
+	assert.True(t, iter.Prev(), name)
+	assert.Equal(t, key(4), iter.Key(), name)
+	assert.True(t, iter.Next(), name)
+	assert.Equal(t, key(5), iter.Key(), name)
+	assert.False(t, iter.Seek(key(N)), name)
+	iter.Release()
+	assert.Nil(t, iter.Error(), name)
+}
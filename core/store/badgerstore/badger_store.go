@@ -0,0 +1,375 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package badgerstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/store/common"
+)
+
+const (
+	VALUE_LOG_GC_INTERVAL      = 10 * time.Minute // interval of reclaiming the value log space of stale values
+	VALUE_LOG_GC_DISCARD_RATIO = 0.5              // rewrite a value log file if half of it can be discarded
+	// badger limits the size of a transaction to 15% of the table size, larger table allows larger batch
+	MAX_TABLE_SIZE = 256 << 20
+	MAX_KEY_SIZE   = 65000 // max key size accepted by badger
+
+	// batch too big for one transaction is journaled before written, journal keys are out of range of ledger keys
+	BATCH_JOURNAL_ENTRY  = "\xff\xffbatch-journal-e"
+	BATCH_JOURNAL_MARKER = "\xff\xffbatch-journal-m" // journal is completed and to be replayed
+
+	batchOpPut    byte = 0
+	batchOpDelete byte = 1
+)
+
+type batchOp struct {
+	op    byte
+	key   []byte
+	value []byte
+}
+
+// BadgerDB store
+type BadgerStore struct {
+	db       *badger.DB // BadgerDB instance
+	batch    []*batchOp
+	batchErr error
+	closing  chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewBadgerStore return BadgerStore instance
+func NewBadgerStore(dir string) (*BadgerStore, error) {
+	o := badger.DefaultOptions(dir).WithLogger(badgerLogger{}).WithMaxTableSize(MAX_TABLE_SIZE)
+	db, err := badger.Open(o)
+	if err == badger.ErrTruncateNeeded {
+		// value log was corrupted by unclean shutdown, truncate the broken tail like leveldb recovery
+		db, err = badger.Open(o.WithTruncate(true))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	store := &BadgerStore{
+		db:      db,
+		closing: make(chan struct{}),
+	}
+	if err := store.recoverBatch(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("recover batch journal: %s", err)
+	}
+	store.wg.Add(1)
+	go store.runValueLogGC()
+	return store, nil
+}
+
+func (self *BadgerStore) runValueLogGC() {
+	defer self.wg.Done()
+	ticker := time.NewTicker(VALUE_LOG_GC_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// each call rewrites at most one value log file, repeat until nothing to rewrite
+			for {
+				if err := self.db.RunValueLogGC(VALUE_LOG_GC_DISCARD_RATIO); err != nil {
+					break
+				}
+			}
+		case <-self.closing:
+			return
+		}
+	}
+}
+
+// Put a key-value pair to badger
+func (self *BadgerStore) Put(key []byte, value []byte) error {
+	return self.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+// Get the value of a key from badger
+func (self *BadgerStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := self.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// Has return whether the key is exist in badger
+func (self *BadgerStore) Has(key []byte) (bool, error) {
+	_, err := self.Get(key)
+	if err == common.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete the the in badger
+func (self *BadgerStore) Delete(key []byte) error {
+	return self.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+// NewBatch start commit batch. Batch fitting in a transaction of badger is committed in the transaction,
+// larger batch is journaled before written in several transactions, and replayed at restart if interrupted
+func (self *BadgerStore) NewBatch() {
+	self.batch = make([]*batchOp, 0)
+	self.batchErr = nil
+}
+
+func (self *BadgerStore) checkBatchKey(key []byte) {
+	if self.batchErr != nil {
+		return
+	}
+	if len(key) == 0 {
+		self.batchErr = fmt.Errorf("batch key error %s", badger.ErrEmptyKey)
+	} else if len(key) > MAX_KEY_SIZE {
+		self.batchErr = fmt.Errorf("batch key error, key size %d exceeds %d", len(key), MAX_KEY_SIZE)
+	}
+}
+
+// BatchPut put a key-value pair to badger batch
+func (self *BadgerStore) BatchPut(key []byte, value []byte) {
+	self.checkBatchKey(key)
+	// badger keeps the reference of key and value until the batch committed
+	self.batch = append(self.batch, &batchOp{op: batchOpPut, key: append([]byte{}, key...),
+		value: append([]byte{}, value...)})
+}
+
+// BatchDelete delete a key to badger batch
+func (self *BadgerStore) BatchDelete(key []byte) {
+	self.checkBatchKey(key)
+	self.batch = append(self.batch, &batchOp{op: batchOpDelete, key: append([]byte{}, key...)})
+}
+
+// BatchCommit commit batch to badger
+func (self *BadgerStore) BatchCommit() error {
+	batch, batchErr := self.batch, self.batchErr
+	self.batch = nil
+	self.batchErr = nil
+	if batchErr != nil {
+		// nothing of the batch is written
+		return batchErr
+	}
+
+	txn := self.db.NewTransaction(true)
+	defer txn.Discard()
+	for _, op := range batch {
+		if err := applyBatchOp(txn, op); err == badger.ErrTxnTooBig {
+			return self.commitJournaledBatch(batch)
+		} else if err != nil {
+			return fmt.Errorf("batch commit error %s", err)
+		}
+	}
+	return txn.Commit()
+}
+
+type batchWriter interface {
+	Set(key, value []byte) error
+	Delete(key []byte) error
+}
+
+func applyBatchOp(w batchWriter, op *batchOp) error {
+	if op.op == batchOpDelete {
+		return w.Delete(op.key)
+	}
+	return w.Set(op.key, op.value)
+}
+
+func journalEntryKey(seq uint64) []byte {
+	key := make([]byte, len(BATCH_JOURNAL_ENTRY)+8)
+	copy(key, BATCH_JOURNAL_ENTRY)
+	binary.BigEndian.PutUint64(key[len(BATCH_JOURNAL_ENTRY):], seq)
+	return key
+}
+
+func encodeBatchOp(op *batchOp) []byte {
+	buf := make([]byte, 1+binary.MaxVarintLen64, 1+binary.MaxVarintLen64+len(op.key)+len(op.value))
+	buf[0] = op.op
+	n := binary.PutUvarint(buf[1:], uint64(len(op.key)))
+	buf = append(buf[:1+n], op.key...)
+	return append(buf, op.value...)
+}
+
+func decodeBatchOp(data []byte) (*batchOp, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty batch op")
+	}
+	keyLen, n := binary.Uvarint(data[1:])
+	if n <= 0 || uint64(len(data)-1-n) < keyLen {
+		return nil, fmt.Errorf("invalid batch op key")
+	}
+	key := data[1+n : 1+n+int(keyLen)]
+	return &batchOp{op: data[0], key: key, value: data[1+n+int(keyLen):]}, nil
+}
+
+// commitJournaledBatch: write batch ops to journal, then to db, the marker written after the journal
+// makes the batch atomic across restart
+func (self *BadgerStore) commitJournaledBatch(batch []*batchOp) error {
+	wb := self.db.NewWriteBatch()
+	for i, op := range batch {
+		if err := wb.Set(journalEntryKey(uint64(i)), encodeBatchOp(op)); err != nil {
+			wb.Cancel()
+			return fmt.Errorf("batch journal error %s", err)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("batch journal error %s", err)
+	}
+	if err := self.Put([]byte(BATCH_JOURNAL_MARKER), nil); err != nil {
+		return fmt.Errorf("batch journal marker error %s", err)
+	}
+	if err := self.writeBatchOps(batch); err != nil {
+		// batch is replayed at restart
+		return err
+	}
+	return self.clearBatchJournal(len(batch))
+}
+
+func (self *BadgerStore) writeBatchOps(batch []*batchOp) error {
+	wb := self.db.NewWriteBatch()
+	for _, op := range batch {
+		if err := applyBatchOp(wb, op); err != nil {
+			wb.Cancel()
+			return fmt.Errorf("batch commit error %s", err)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("batch commit error %s", err)
+	}
+	return nil
+}
+
+// loadBatchJournal: journal entries in order of batch ops, and the number of entries to clear
+func (self *BadgerStore) loadBatchJournal() ([]*batchOp, int, error) {
+	batch := make([]*batchOp, 0)
+	entries := 0
+	err := self.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		prefix := []byte(BATCH_JOURNAL_ENTRY)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			// entries of uncompleted batch may be not continuous
+			entries = int(binary.BigEndian.Uint64(it.Item().Key()[len(prefix):])) + 1
+			data, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			op, err := decodeBatchOp(data)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, op)
+		}
+		return nil
+	})
+	return batch, entries, err
+}
+
+// clearBatchJournal: delete journal entries, and then the marker
+func (self *BadgerStore) clearBatchJournal(entries int) error {
+	wb := self.db.NewWriteBatch()
+	for i := 0; i < entries; i++ {
+		if err := wb.Delete(journalEntryKey(uint64(i))); err != nil {
+			wb.Cancel()
+			return fmt.Errorf("clear batch journal error %s", err)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("clear batch journal error %s", err)
+	}
+	return self.Delete([]byte(BATCH_JOURNAL_MARKER))
+}
+
+// recoverBatch: replay the batch interrupted after journaled, journal of uncompleted batch is dropped
+func (self *BadgerStore) recoverBatch() error {
+	journaled, err := self.Has([]byte(BATCH_JOURNAL_MARKER))
+	if err != nil {
+		return err
+	}
+	batch, entries, err := self.loadBatchJournal()
+	if err != nil {
+		return fmt.Errorf("load batch journal error %s", err)
+	}
+	if !journaled && entries == 0 {
+		return nil
+	}
+	if journaled {
+		log.Warnf("badger: replay interrupted batch of %d ops", len(batch))
+		if err := self.writeBatchOps(batch); err != nil {
+			return err
+		}
+	}
+	return self.clearBatchJournal(entries)
+}
+
+// Close badger
+func (self *BadgerStore) Close() error {
+	close(self.closing)
+	self.wg.Wait()
+	self.batch = nil
+	return self.db.Close()
+}
+
+// NewIterator return a iterator of badger with the key prefix
+func (self *BadgerStore) NewIterator(prefix []byte) common.StoreIterator {
+	return newIterator(self.db.NewTransaction(false), prefix)
+}
+
+// badgerLogger redirects badger logs to ontology log, badger infos are logged at debug level
+type badgerLogger struct{}
+
+func (badgerLogger) Errorf(format string, v ...interface{}) {
+	log.Errorf("badger: "+format, v...)
+}
+
+func (badgerLogger) Warningf(format string, v ...interface{}) {
+	log.Warnf("badger: "+format, v...)
+}
+
+func (badgerLogger) Infof(format string, v ...interface{}) {
+	log.Debugf("badger: "+format, v...)
+}
+
+func (badgerLogger) Debugf(format string, v ...interface{}) {
+	log.Debugf("badger: "+format, v...)
+}
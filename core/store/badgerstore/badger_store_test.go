@@ -0,0 +1,209 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package badgerstore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ontio/ontology/core/store/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBadgerDB *BadgerStore
+
+func TestMain(m *testing.M) {
+	dbDir := "./test"
+	var err error
+	testBadgerDB, err = NewBadgerStore(dbDir)
+	if err != nil {
+		fmt.Printf("NewBadgerStore error:%s\n", err)
+		return
+	}
+	m.Run()
+	testBadgerDB.Close()
+	os.RemoveAll(dbDir)
+	os.RemoveAll("ActorLog")
+}
+
+func TestBadgerDB(t *testing.T) {
+	key := []byte("foo")
+	value := []byte("bar")
+	assert.Nil(t, testBadgerDB.Put(key, value))
+	v, err := testBadgerDB.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, v)
+
+	assert.Nil(t, testBadgerDB.Delete(key))
+	ok, err := testBadgerDB.Has(key)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, err = testBadgerDB.Get(key)
+	assert.Equal(t, common.ErrNotFound, err)
+}
+
+func TestBatch(t *testing.T) {
+	testBadgerDB.NewBatch()
+	key := []byte("batch1")
+	testBadgerDB.BatchPut(key, []byte("bar1"))
+	// key is copied by batch
+	key[len(key)-1] = '2'
+	testBadgerDB.BatchPut(key, []byte("bar2"))
+	testBadgerDB.BatchDelete([]byte("batch2"))
+	ok, err := testBadgerDB.Has([]byte("batch1"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, testBadgerDB.BatchCommit())
+
+	v, err := testBadgerDB.Get([]byte("batch1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("bar1"), v)
+	ok, err = testBadgerDB.Has([]byte("batch2"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// failed batch writes nothing
+	testBadgerDB.NewBatch()
+	testBadgerDB.BatchPut([]byte("batch3"), []byte("bar3"))
+	testBadgerDB.BatchPut(nil, []byte("bar"))
+	assert.NotNil(t, testBadgerDB.BatchCommit())
+	ok, err = testBadgerDB.Has([]byte("batch3"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestBigBatch(t *testing.T) {
+	assert.Nil(t, testBadgerDB.Put([]byte("big-deleted"), []byte("bar")))
+	count := int(testBadgerDB.db.MaxBatchCount()) + 1
+	testBadgerDB.NewBatch()
+	for i := 0; i < count; i++ {
+		testBadgerDB.BatchPut([]byte(fmt.Sprintf("big%d", i)), []byte(fmt.Sprintf("bar%d", i)))
+	}
+	testBadgerDB.BatchDelete([]byte("big-deleted"))
+	testBadgerDB.BatchPut([]byte("big0"), []byte("bar"))
+	assert.Nil(t, testBadgerDB.BatchCommit())
+
+	for i := 1; i < count; i++ {
+		v, err := testBadgerDB.Get([]byte(fmt.Sprintf("big%d", i)))
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("bar%d", i), string(v))
+	}
+	v, err := testBadgerDB.Get([]byte("big0"))
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", string(v))
+	ok, err := testBadgerDB.Has([]byte("big-deleted"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// journal is cleared after committed
+	ok, err = testBadgerDB.Has([]byte(BATCH_JOURNAL_MARKER))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, entries, err := testBadgerDB.loadBatchJournal()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, entries)
+}
+
+func TestRecoverBatch(t *testing.T) {
+	dbDir := "./test_recover"
+	defer os.RemoveAll(dbDir)
+	db, err := NewBadgerStore(dbDir)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Put([]byte("deleted"), []byte("bar")))
+
+	// batch interrupted after journaled is replayed
+	batch := []*batchOp{
+		{op: batchOpPut, key: []byte("k1"), value: []byte("v1")},
+		{op: batchOpDelete, key: []byte("deleted")},
+		{op: batchOpPut, key: []byte("k1"), value: []byte("v2")},
+	}
+	for i, op := range batch {
+		assert.Nil(t, db.Put(journalEntryKey(uint64(i)), encodeBatchOp(op)))
+	}
+	assert.Nil(t, db.Put([]byte(BATCH_JOURNAL_MARKER), nil))
+	assert.Nil(t, db.Close())
+	db, err = NewBadgerStore(dbDir)
+	assert.Nil(t, err)
+	v, err := db.Get([]byte("k1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v2"), v)
+	ok, err := db.Has([]byte("deleted"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, entries, err := db.loadBatchJournal()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, entries)
+
+	// batch interrupted before journaled is dropped
+	assert.Nil(t, db.Put(journalEntryKey(1), encodeBatchOp(&batchOp{op: batchOpPut, key: []byte("k2"),
+		value: []byte("v")})))
+	assert.Nil(t, db.Close())
+	db, err = NewBadgerStore(dbDir)
+	assert.Nil(t, err)
+	ok, err = db.Has([]byte("k2"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, entries, err = db.loadBatchJournal()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, entries)
+	assert.Nil(t, db.Close())
+}
+
+func TestIterator(t *testing.T) {
+	keys := []string{"it", "it1", "it2", "it\xff", "it\xff\xff", "iu"}
+	for _, key := range keys {
+		assert.Nil(t, testBadgerDB.Put([]byte(key), []byte("v"+key)))
+	}
+
+	iter := testBadgerDB.NewIterator([]byte("it"))
+	var forward []string
+	for iter.Next() {
+		assert.Equal(t, "v"+string(iter.Key()), string(iter.Value()))
+		forward = append(forward, string(iter.Key()))
+	}
+	assert.Equal(t, keys[:5], forward)
+	var backward []string
+	for iter.Prev() {
+		backward = append(backward, string(iter.Key()))
+	}
+	assert.Equal(t, []string{"it\xff\xff", "it\xff", "it2", "it1", "it"}, backward)
+
+	assert.True(t, iter.Seek([]byte("it10")))
+	assert.Equal(t, "it2", string(iter.Key()))
+	assert.True(t, iter.Prev())
+	assert.Equal(t, "it1", string(iter.Key()))
+	assert.True(t, iter.Next())
+	assert.Equal(t, "it2", string(iter.Key()))
+	assert.True(t, iter.Seek([]byte("a")))
+	assert.Equal(t, "it", string(iter.Key()))
+	assert.False(t, iter.Seek([]byte("iu")))
+	iter.Release()
+	assert.Nil(t, iter.Error())
+
+	iter = testBadgerDB.NewIterator([]byte("it\xff"))
+	assert.True(t, iter.Last())
+	assert.Equal(t, "it\xff\xff", string(iter.Key()))
+	assert.True(t, iter.First())
+	assert.Equal(t, "it\xff", string(iter.Key()))
+	assert.False(t, iter.Prev())
+	iter.Release()
+	assert.False(t, iter.Next())
+	assert.Nil(t, iter.Error())
+}
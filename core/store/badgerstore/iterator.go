@@ -0,0 +1,197 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package badgerstore
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Iterator iterates the keys with prefix in a read only transaction of badger. Badger iterator
+// only moves in one direction, so it is recreated in the opposite direction when Next and Prev
+// are interleaved
+type Iterator struct {
+	txn        *badger.Txn
+	iter       *badger.Iterator
+	prefix     []byte
+	limit      []byte // the smallest key greater than all keys with prefix, nil for no limit
+	reverse    bool   // direction of iter
+	released   bool
+	key, value []byte
+	err        error
+}
+
+func newIterator(txn *badger.Txn, prefix []byte) *Iterator {
+	prefix = append([]byte{}, prefix...)
+	return &Iterator{
+		txn:    txn,
+		prefix: prefix,
+		limit:  prefixLimit(prefix),
+	}
+}
+
+func (self *Iterator) First() bool {
+	if !self.setDirection(false) {
+		return false
+	}
+	self.iter.Seek(self.prefix)
+	return self.fill()
+}
+
+func (self *Iterator) Last() bool {
+	if !self.setDirection(true) {
+		return false
+	}
+	if self.limit == nil {
+		self.iter.Rewind()
+	} else {
+		self.seekReverse(self.limit)
+	}
+	return self.fill()
+}
+
+func (self *Iterator) Seek(key []byte) bool {
+	if !self.setDirection(false) {
+		return false
+	}
+	if bytes.Compare(key, self.prefix) < 0 {
+		key = self.prefix
+	}
+	self.iter.Seek(key)
+	return self.fill()
+}
+
+func (self *Iterator) Next() bool {
+	if self.key == nil {
+		// iterator is exhausted or not positioned yet
+		if self.iter != nil && !self.reverse {
+			return false
+		}
+		return self.First()
+	}
+	if self.reverse {
+		key := self.key
+		self.setDirection(false)
+		self.iter.Seek(key)
+		if self.iter.Valid() && bytes.Equal(self.iter.Item().Key(), key) {
+			self.iter.Next()
+		}
+	} else {
+		self.iter.Next()
+	}
+	return self.fill()
+}
+
+func (self *Iterator) Prev() bool {
+	if self.key == nil {
+		if self.iter != nil && self.reverse {
+			return false
+		}
+		return self.Last()
+	}
+	if !self.reverse {
+		key := self.key
+		self.setDirection(true)
+		self.seekReverse(key)
+	} else {
+		self.iter.Next()
+	}
+	return self.fill()
+}
+
+func (self *Iterator) Key() []byte {
+	return self.key
+}
+
+func (self *Iterator) Value() []byte {
+	return self.value
+}
+
+func (self *Iterator) Release() {
+	if self.released {
+		return
+	}
+	if self.iter != nil {
+		self.iter.Close()
+		self.iter = nil
+	}
+	self.txn.Discard()
+	self.key = nil
+	self.value = nil
+	self.released = true
+}
+
+func (self *Iterator) Error() error {
+	return self.err
+}
+
+func (self *Iterator) setDirection(reverse bool) bool {
+	if self.released {
+		return false
+	}
+	if self.iter != nil && self.reverse == reverse {
+		return true
+	}
+	if self.iter != nil {
+		self.iter.Close()
+	}
+	o := badger.DefaultIteratorOptions
+	o.Reverse = reverse
+	self.iter = self.txn.NewIterator(o)
+	self.reverse = reverse
+	return true
+}
+
+// seekReverse positions the reverse iterator at the last key less than key
+func (self *Iterator) seekReverse(key []byte) {
+	self.iter.Seek(key)
+	if self.iter.Valid() && bytes.Equal(self.iter.Item().Key(), key) {
+		self.iter.Next()
+	}
+}
+
+func (self *Iterator) fill() bool {
+	self.key = nil
+	self.value = nil
+	if !self.iter.ValidForPrefix(self.prefix) {
+		return false
+	}
+	item := self.iter.Item()
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		self.err = err
+		return false
+	}
+	self.key = item.KeyCopy(nil)
+	self.value = value
+	return true
+}
+
+func prefixLimit(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			limit := make([]byte, i+1)
+			copy(limit, prefix)
+			limit[i]++
+			return limit
+		}
+	}
+	return nil
+}
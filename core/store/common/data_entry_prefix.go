@@ -39,12 +39,20 @@ const (
 
 	ST_CONTRACT_META_DATA DataEntryPrefix = 0x0a // contract meta data
 
+	ST_ARCHIVE DataEntryPrefix = 0x16 // state key + block height => state value before the block is committed
+
+	ST_STORAGE_TRIE_NODE DataEntryPrefix = 0x18 // node hash => node of storage trie
+
 	//SYSTEM
 	SYS_CURRENT_BLOCK      DataEntryPrefix = 0x10 //Current block key prefix
 	SYS_VERSION            DataEntryPrefix = 0x11 //Store version key prefix
 	SYS_CURRENT_STATE_ROOT DataEntryPrefix = 0x12 //no use
 	SYS_BLOCK_MERKLE_TREE  DataEntryPrefix = 0x13 // Block merkle tree root key prefix
 	SYS_STATE_MERKLE_TREE  DataEntryPrefix = 0x20 // state merkle tree root key prefix
+	SYS_PRUNED_HEIGHT      DataEntryPrefix = 0x15 // lowest block height whose body has not been pruned
+	SYS_ARCHIVE_HEIGHT     DataEntryPrefix = 0x17 // first block height whose state changes are archived
+	SYS_STORAGE_TRIE_ROOT  DataEntryPrefix = 0x19 // block height => storage trie root
+	SYS_STORAGE_TRIE_START DataEntryPrefix = 0x1a // first block height whose storage trie root is saved
 
 	EVENT_NOTIFY DataEntryPrefix = 0x14 //Event notify key prefix
 
@@ -56,6 +64,7 @@ const (
 	XSHARD_KEY_MSG_HASH        DataEntryPrefix = 0x37 //shard msg key
 	XSHARD_KEY_LOCKED_ADDRESS                  = 0x38 // save current locked contrqct address
 	XSHARD_KEY_LOCKED_KEY      DataEntryPrefix = 0x39
+	XSHARD_KEY_TX_DEADLINE     DataEntryPrefix = 0x3a // with block#, contains cross shard txs timeout at block#
 
 	CROSS_SHARD_MSG    DataEntryPrefix = 0x40 //cross shard msg data
 	CROSS_SHARD_HEIGHT DataEntryPrefix = 0x41 //all shard consensus height info
@@ -70,4 +79,8 @@ const (
 	DATA_SHARD_TX                                    = 0x48 //shardTx hash = > shardTx key prefix
 	DATA_SHARD_TX_HASHES                             = 0x49 //shardTx hashes = > shardTx hashes key prefix
 	DATA_SOURCE_TX_HASH                              = 0x50 // sourceTx hash = > shardTx hash
+	XSHARD_TX_MSG_HEIGHTS            DataEntryPrefix = 0x51 // shard tx id => heights of blocks containing shard msgs of the tx
+
+	CONSENSUS_BLOCK_MSGS DataEntryPrefix = 0x52 // block height => consensus msgs of block
+	CONSENSUS_ROUND_MSGS DataEntryPrefix = 0x53 // consensus msgs of current round
 )
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"errors"
+
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+var errArchiveStoreReadOnly = errors.New("archive store is read only")
+
+// archiveStore is a read only PersistStore of the states at a history block height,
+// which is rebuilt from the archived state values of StateStore
+type archiveStore struct {
+	stateStore *StateStore
+	height     uint32
+}
+
+func newArchiveStore(stateStore *StateStore, height uint32) *archiveStore {
+	return &archiveStore{
+		stateStore: stateStore,
+		height:     height,
+	}
+}
+
+func (self *archiveStore) Put(key []byte, value []byte) error {
+	return errArchiveStoreReadOnly
+}
+
+func (self *archiveStore) Get(key []byte) ([]byte, error) {
+	return self.stateStore.GetStateAtHeight(key, self.height)
+}
+
+func (self *archiveStore) Has(key []byte) (bool, error) {
+	_, err := self.Get(key)
+	if err == scom.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (self *archiveStore) Delete(key []byte) error {
+	return errArchiveStoreReadOnly
+}
+
+func (self *archiveStore) NewBatch() {}
+
+func (self *archiveStore) BatchPut(key []byte, value []byte) {}
+
+func (self *archiveStore) BatchDelete(key []byte) {}
+
+func (self *archiveStore) BatchCommit() error {
+	return errArchiveStoreReadOnly
+}
+
+func (self *archiveStore) Close() error {
+	return nil
+}
+
+// NewIterator collects the keys with prefix from both current and archived states, and loads
+// their values at history height into memory. It is slow for large prefix and only used by pre-execution
+func (self *archiveStore) NewIterator(prefix []byte) scom.StoreIterator {
+	keys := make(map[string]struct{})
+	iter := self.stateStore.store.NewIterator(prefix)
+	for has := iter.First(); has; has = iter.Next() {
+		keys[string(iter.Key())] = struct{}{}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return iterator.NewEmptyIterator(err)
+	}
+	iter = self.stateStore.store.NewIterator(genArchivedStatePrefix(prefix))
+	for has := iter.First(); has; has = iter.Next() {
+		key := iter.Key()
+		keys[string(key[1:len(key)-4])] = struct{}{}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return iterator.NewEmptyIterator(err)
+	}
+
+	memdb := overlaydb.NewMemDB(0, len(keys))
+	for key := range keys {
+		value, err := self.Get([]byte(key))
+		if err == scom.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return iterator.NewEmptyIterator(err)
+		}
+		memdb.Put([]byte(key), value)
+	}
+	return memdb.NewIterator(nil)
+}
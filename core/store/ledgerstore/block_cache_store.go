@@ -25,7 +25,7 @@ import (
 
 	"github.com/ontio/ontology/common"
 	exec "github.com/ontio/ontology/core/store"
-	"github.com/ontio/ontology/core/store/leveldbstore"
+	scom "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/types"
 )
 
@@ -36,7 +36,7 @@ const (
 type BlockCacheStore struct {
 	shardID    common.ShardID
 	dbDir      string
-	store      *leveldbstore.LevelDBStore
+	store      scom.PersistStore
 	execResult map[uint32]exec.ExecuteResult
 }
 
@@ -46,7 +46,7 @@ func ResetBlockCacheStore(shardID common.ShardID, dbDir string) (*BlockCacheStor
 	// reset block cache
 	os.RemoveAll(dbPath)
 
-	store, err := leveldbstore.NewLevelDBStore(dbPath)
+	store, err := newPersistStore(dbPath)
 	if err != nil {
 		return nil, err
 	}
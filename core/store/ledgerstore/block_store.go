@@ -29,16 +29,15 @@ import (
 	"github.com/ontio/ontology/common/serialization"
 	"github.com/ontio/ontology/core/payload"
 	scom "github.com/ontio/ontology/core/store/common"
-	"github.com/ontio/ontology/core/store/leveldbstore"
 	"github.com/ontio/ontology/core/types"
 )
 
 //Block store save the data of block & transaction
 type BlockStore struct {
-	enableCache bool                       //Is enable lru cache
-	dbDir       string                     //The path of store file
-	cache       *BlockCache                //The cache of block, if have.
-	store       *leveldbstore.LevelDBStore //block store handler
+	enableCache bool              //Is enable lru cache
+	dbDir       string            //The path of store file
+	cache       *BlockCache       //The cache of block, if have.
+	store       scom.PersistStore //block store handler
 }
 
 //NewBlockStore return the block store instance
@@ -52,7 +51,7 @@ func NewBlockStore(dbDir string, enableCache bool) (*BlockStore, error) {
 		}
 	}
 
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := newPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +145,9 @@ func (this *BlockStore) GetBlock(blockHash common.Uint256) (*types.Block, error)
 	crossShardMapInfos := make(map[common.ShardID][]*types.CrossShardTxInfos)
 	for _, shardTxHash := range shardTxHashes {
 		shardTx, _, err := this.GetShardTx(shardTxHash)
+		if err == scom.ErrPruned {
+			return nil, err
+		}
 		if err != nil {
 			return nil, fmt.Errorf("GetShardTx %s error %s", shardTxHash.ToHexString(), err)
 		}
@@ -160,6 +162,9 @@ func (this *BlockStore) GetBlock(blockHash common.Uint256) (*types.Block, error)
 	txList := make([]*types.Transaction, 0, len(txHashes))
 	for _, txHash := range txHashes {
 		tx, _, err := this.GetTransaction(txHash)
+		if err == scom.ErrPruned {
+			return nil, err
+		}
 		if err != nil {
 			return nil, fmt.Errorf("GetTransaction %s error %s", txHash.ToHexString(), err)
 		}
@@ -473,6 +478,9 @@ func (this *BlockStore) loadShardTx(shardTxHash common.Uint256) (*types.CrossSha
 	if eof {
 		return nil, 0, io.ErrUnexpectedEOF
 	}
+	if source.Len() == 0 {
+		return nil, height, scom.ErrPruned
+	}
 	shardTx = new(types.CrossShardTxInfos)
 	err = shardTx.Deserialization(source)
 	if err != nil {
@@ -553,6 +561,9 @@ func (this *BlockStore) loadTransaction(txHash common.Uint256) (*types.Transacti
 	if eof {
 		return nil, 0, io.ErrUnexpectedEOF
 	}
+	if source.Len() == 0 {
+		return nil, height, scom.ErrPruned
+	}
 	tx = new(types.Transaction)
 	err = tx.Deserialization(source)
 	if err != nil {
@@ -580,6 +591,72 @@ func (this *BlockStore) ContainTransaction(txHash common.Uint256) (bool, error)
 	return true, nil
 }
 
+//PruneBlock drops the transactions and shard txs of block from store, only the height index of them is kept
+//to detect duplicated transaction. Return the shard txs pruned, which is nil if block has been pruned before
+func (this *BlockStore) PruneBlock(blockHash common.Uint256) ([]*types.CrossShardTxInfos, error) {
+	_, txHashes, err := this.loadHeaderWithTx(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("loadHeaderWithTx error %s", err)
+	}
+	for _, txHash := range txHashes {
+		_, height, err := this.loadTransaction(txHash)
+		if err == scom.ErrPruned {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loadTransaction %s error %s", txHash.ToHexString(), err)
+		}
+		this.putPrunedValue(this.getTransactionKey(txHash), height)
+	}
+	shardTxHashes, err := this.loadShardTxHashes(blockHash)
+	if err == scom.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loadShardTxHashes error %s", err)
+	}
+	shardTxs := make([]*types.CrossShardTxInfos, 0, len(shardTxHashes))
+	for _, shardTxHash := range shardTxHashes {
+		shardTx, height, err := this.loadShardTx(shardTxHash)
+		if err == scom.ErrPruned {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loadShardTx %s error %s", shardTxHash.ToHexString(), err)
+		}
+		this.putPrunedValue(this.getShardTxKey(shardTxHash), height)
+		shardTxs = append(shardTxs, shardTx)
+	}
+	this.store.BatchDelete(this.getShardTxHashesKey(blockHash))
+	return shardTxs, nil
+}
+
+func (this *BlockStore) putPrunedValue(key []byte, height uint32) {
+	sink := common.NewZeroCopySink(4)
+	sink.WriteUint32(height)
+	this.store.BatchPut(key, sink.Bytes())
+}
+
+//GetPrunedHeight return the lowest block height whose body has not been pruned
+func (this *BlockStore) GetPrunedHeight() (uint32, error) {
+	value, err := this.store.Get(this.getPrunedHeightKey())
+	if err != nil {
+		return 0, err
+	}
+	height, eof := common.NewZeroCopySource(value).NextUint32()
+	if eof {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return height, nil
+}
+
+//SavePrunedHeight persist the lowest block height whose body has not been pruned
+func (this *BlockStore) SavePrunedHeight(height uint32) {
+	sink := common.NewZeroCopySink(4)
+	sink.WriteUint32(height)
+	this.store.BatchPut(this.getPrunedHeightKey(), sink.Bytes())
+}
+
 //GetVersion return the version of store
 func (this *BlockStore) GetVersion() (byte, error) {
 	key := this.getVersionKey()
@@ -665,6 +742,10 @@ func (this *BlockStore) getBlockMerkleTreeKey() []byte {
 	return []byte{byte(scom.SYS_BLOCK_MERKLE_TREE)}
 }
 
+func (this *BlockStore) getPrunedHeightKey() []byte {
+	return []byte{byte(scom.SYS_PRUNED_HEIGHT)}
+}
+
 func (this *BlockStore) getVersionKey() []byte {
 	return []byte{byte(scom.SYS_VERSION)}
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/chainmgr/message"
 	"github.com/ontio/ontology/core/payload"
+	scom "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/core/utils"
 	"github.com/ontio/ontology/core/xshard_types"
@@ -376,6 +377,59 @@ func TestBlock(t *testing.T) {
 	}
 }
 
+func TestPruneBlock(t *testing.T) {
+	acc1 := account.NewAccount("")
+	acc2 := account.NewAccount("")
+	header := &types.Header{
+		Version:       0,
+		PrevBlockHash: common.Uint256{},
+		Timestamp:     uint32(uint32(time.Date(2019, time.February, 23, 0, 0, 0, 0, time.UTC).Unix())),
+		Height:        uint32(3),
+	}
+	tx1, err := transferTx(acc1.Address, acc2.Address, 20)
+	if err != nil {
+		t.Errorf("TestPruneBlock transferTx error:%s", err)
+		return
+	}
+	block := &types.Block{
+		Header:       header,
+		Transactions: []*types.Transaction{tx1},
+	}
+	blockHash := block.Hash()
+	tx1Hash := tx1.Hash()
+
+	testBlockStore.NewBatch()
+	err = testBlockStore.SaveBlock(block)
+	assert.Nil(t, err)
+	assert.Nil(t, testBlockStore.CommitTo())
+
+	for i := 0; i < 2; i++ {
+		testBlockStore.NewBatch()
+		shardTxs, err := testBlockStore.PruneBlock(blockHash)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(shardTxs))
+		testBlockStore.SavePrunedHeight(header.Height + 1)
+		assert.Nil(t, testBlockStore.CommitTo())
+	}
+
+	prunedHeight, err := testBlockStore.GetPrunedHeight()
+	assert.Nil(t, err)
+	assert.Equal(t, header.Height+1, prunedHeight)
+
+	_, height, err := testBlockStore.GetTransaction(tx1Hash)
+	assert.Equal(t, scom.ErrPruned, err)
+	assert.Equal(t, header.Height, height)
+	exist, err := testBlockStore.ContainTransaction(tx1Hash)
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	_, err = testBlockStore.GetBlock(blockHash)
+	assert.Equal(t, scom.ErrPruned, err)
+	h, err := testBlockStore.GetHeader(blockHash)
+	assert.Nil(t, err)
+	assert.Equal(t, blockHash, h.Hash())
+}
+
 func transferTx(from, to common.Address, amount uint64) (*types.Transaction, error) {
 	buf := bytes.NewBuffer(nil)
 	var sts []ont.State
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+)
+
+var (
+	// Storage save path.
+	DBDirConsensus = "consensus"
+)
+
+// saving consensus msgs of blocks, consensus msgs are opaque bytes encoded by consensus service
+type ConsensusStore struct {
+	dbDir string            //Store path
+	store scom.PersistStore //Store handler
+}
+
+// NewConsensusStore return consensus store instance
+func NewConsensusStore(dataDir string) (*ConsensusStore, error) {
+	dbDir := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirConsensus)
+	store, err := newPersistStore(dbDir)
+	if err != nil {
+		return nil, fmt.Errorf("NewConsensusStore error %s", err)
+	}
+	return &ConsensusStore{
+		dbDir: dbDir,
+		store: store,
+	}, nil
+}
+
+func (this *ConsensusStore) SaveBlockConsensusMsgs(height uint32, data []byte) error {
+	key := genBlockConsensusMsgsKey(height)
+	if err := this.store.Put(key, data); err != nil {
+		return fmt.Errorf("consensusStore.SaveBlockConsensusMsgs height:%d, error %s", height, err)
+	}
+	return nil
+}
+
+func (this *ConsensusStore) GetBlockConsensusMsgs(height uint32) ([]byte, error) {
+	return this.store.Get(genBlockConsensusMsgsKey(height))
+}
+
+// PruneBlockConsensusMsgs delete consensus msgs of blocks in [start, end)
+func (this *ConsensusStore) PruneBlockConsensusMsgs(start, end uint32) error {
+	this.store.NewBatch()
+	for height := start; height < end; height++ {
+		this.store.BatchDelete(genBlockConsensusMsgsKey(height))
+	}
+	if err := this.store.BatchCommit(); err != nil {
+		return fmt.Errorf("consensusStore.PruneBlockConsensusMsgs error %s", err)
+	}
+	return nil
+}
+
+func genBlockConsensusMsgsKey(height uint32) []byte {
+	key := common.NewZeroCopySink(5)
+	key.WriteByte(byte(scom.CONSENSUS_BLOCK_MSGS))
+	key.WriteUint32(height)
+	return key.Bytes()
+}
+
+// SaveRoundConsensusMsgs overwrites consensus msgs of current round,
+// which are reloaded by consensus service after restart
+func (this *ConsensusStore) SaveRoundConsensusMsgs(data []byte) error {
+	if err := this.store.Put([]byte{byte(scom.CONSENSUS_ROUND_MSGS)}, data); err != nil {
+		return fmt.Errorf("consensusStore.SaveRoundConsensusMsgs error %s", err)
+	}
+	return nil
+}
+
+func (this *ConsensusStore) GetRoundConsensusMsgs() ([]byte, error) {
+	return this.store.Get([]byte{byte(scom.CONSENSUS_ROUND_MSGS)})
+}
+
+// Close consensus store
+func (this *ConsensusStore) Close() error {
+	return this.store.Close()
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	scom "github.com/ontio/ontology/core/store/common"
+)
+
+func TestConsensusStore(t *testing.T) {
+	testConsensusDir := "test/consensus"
+	defer os.RemoveAll(testConsensusDir)
+	store, err := NewConsensusStore(testConsensusDir)
+	if err != nil {
+		t.Errorf("NewConsensusStore err:%s", err)
+		return
+	}
+	defer store.Close()
+
+	data := []byte("consensus msgs")
+	if err := store.SaveBlockConsensusMsgs(10, data); err != nil {
+		t.Errorf("SaveBlockConsensusMsgs err:%s", err)
+		return
+	}
+	msgs, err := store.GetBlockConsensusMsgs(10)
+	if err != nil {
+		t.Errorf("GetBlockConsensusMsgs err:%s", err)
+		return
+	}
+	if !bytes.Equal(msgs, data) {
+		t.Errorf("GetBlockConsensusMsgs unmatch, %s vs %s", msgs, data)
+		return
+	}
+	if _, err := store.GetBlockConsensusMsgs(11); err != scom.ErrNotFound {
+		t.Errorf("GetBlockConsensusMsgs of unknown height, err:%v", err)
+		return
+	}
+}
+
+func TestRoundConsensusMsgs(t *testing.T) {
+	testConsensusDir := "test/consensus_round"
+	defer os.RemoveAll(testConsensusDir)
+	store, err := NewConsensusStore(testConsensusDir)
+	if err != nil {
+		t.Errorf("NewConsensusStore err:%s", err)
+		return
+	}
+	defer store.Close()
+
+	if _, err := store.GetRoundConsensusMsgs(); err != scom.ErrNotFound {
+		t.Errorf("GetRoundConsensusMsgs before saving, err:%v", err)
+		return
+	}
+	for _, data := range [][]byte{[]byte("round 10 msgs"), []byte("round 11 msgs")} {
+		if err := store.SaveRoundConsensusMsgs(data); err != nil {
+			t.Errorf("SaveRoundConsensusMsgs err:%s", err)
+			return
+		}
+		msgs, err := store.GetRoundConsensusMsgs()
+		if err != nil {
+			t.Errorf("GetRoundConsensusMsgs err:%s", err)
+			return
+		}
+		if !bytes.Equal(msgs, data) {
+			t.Errorf("GetRoundConsensusMsgs unmatch, %s vs %s", msgs, data)
+			return
+		}
+	}
+}
+
+func TestPruneBlockConsensusMsgs(t *testing.T) {
+	testConsensusDir := "test/consensus_prune"
+	defer os.RemoveAll(testConsensusDir)
+	store, err := NewConsensusStore(testConsensusDir)
+	if err != nil {
+		t.Errorf("NewConsensusStore err:%s", err)
+		return
+	}
+	defer store.Close()
+
+	for height := uint32(1); height <= 5; height++ {
+		if err := store.SaveBlockConsensusMsgs(height, []byte("consensus msgs")); err != nil {
+			t.Errorf("SaveBlockConsensusMsgs err:%s", err)
+			return
+		}
+	}
+	if err := store.PruneBlockConsensusMsgs(1, 4); err != nil {
+		t.Errorf("PruneBlockConsensusMsgs err:%s", err)
+		return
+	}
+	for height := uint32(1); height <= 5; height++ {
+		_, err := store.GetBlockConsensusMsgs(height)
+		if height < 4 && err != scom.ErrNotFound {
+			t.Errorf("GetBlockConsensusMsgs of pruned height %d, err:%v", height, err)
+			return
+		}
+		if height >= 4 && err != nil {
+			t.Errorf("GetBlockConsensusMsgs of height %d err:%s", height, err)
+			return
+		}
+	}
+}
@@ -25,7 +25,6 @@ import (
 
 	"github.com/ontio/ontology/common"
 	scom "github.com/ontio/ontology/core/store/common"
-	"github.com/ontio/ontology/core/store/leveldbstore"
 	"github.com/ontio/ontology/core/types"
 )
 
@@ -36,14 +35,14 @@ var (
 
 //saving cross shard msg
 type CrossShardStore struct {
-	dbDir string                     //Store path
-	store *leveldbstore.LevelDBStore //Store handler
+	dbDir string            //Store path
+	store scom.PersistStore //Store handler
 }
 
 //NewCrossShardStore return cross shard store instance
 func NewCrossShardStore(dataDir string) (*CrossShardStore, error) {
 	dbDir := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirCrossShard)
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := newPersistStore(dbDir)
 	if err != nil {
 		return nil, fmt.Errorf("NewCrossShardStore error %s", err)
 	}
@@ -82,6 +81,16 @@ func (this *CrossShardStore) GetCrossShardMsgByHash(msgHash common.Uint256) (*ty
 	return crossShardMsg, nil
 }
 
+//DeleteCrossShardMsgByHash remove the cross shard msg which has been consumed
+func (this *CrossShardStore) DeleteCrossShardMsgByHash(msgHash common.Uint256) error {
+	key := genCrossShardMsgKeyByHash(msgHash)
+	err := this.store.Delete(key)
+	if err != nil {
+		return fmt.Errorf("crossShardStore.Delete msgHash:%s, error %s", msgHash.ToHexString(), err)
+	}
+	return nil
+}
+
 func genCrossShardMsgKeyByHash(msgHash common.Uint256) []byte {
 	key := common.NewZeroCopySink(9)
 	key.WriteByte(byte(scom.CROSS_SHARD_MSG))
@@ -30,20 +30,20 @@ import (
 	"github.com/ontio/ontology/common/serialization"
 	"github.com/ontio/ontology/core/payload"
 	scom "github.com/ontio/ontology/core/store/common"
-	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/xshard_types"
 	"github.com/ontio/ontology/events/message"
 	"github.com/ontio/ontology/smartcontract/event"
 )
 
 //Saving event notifies gen by smart contract execution
 type EventStore struct {
-	dbDir string                     //Store path
-	store *leveldbstore.LevelDBStore //Store handler
+	dbDir string            //Store path
+	store scom.PersistStore //Store handler
 }
 
 //NewEventStore return event store instance
 func NewEventStore(dbDir string) (*EventStore, error) {
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := newPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -138,6 +138,36 @@ func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNo
 	return evtNotifies, nil
 }
 
+//PruneEventNotifyByBlock delete event notify of block and all the transactions in it
+func (this *EventStore) PruneEventNotifyByBlock(height uint32) error {
+	key, err := this.getEventNotifyByBlockKey(height)
+	if err != nil {
+		return err
+	}
+	data, err := this.store.Get(key)
+	if err == scom.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	reader := bytes.NewBuffer(data)
+	size, err := serialization.ReadUint32(reader)
+	if err != nil {
+		return fmt.Errorf("ReadUint32 error %s", err)
+	}
+	for i := uint32(0); i < size; i++ {
+		var txHash common.Uint256
+		err = txHash.Deserialize(reader)
+		if err != nil {
+			return fmt.Errorf("txHash.Deserialize error %s", err)
+		}
+		this.store.BatchDelete(this.getEventNotifyByTxKey(txHash))
+	}
+	this.store.BatchDelete(key)
+	return nil
+}
+
 //CommitTo event store batch to store
 func (this *EventStore) CommitTo() error {
 	return this.store.BatchCommit()
@@ -434,3 +464,50 @@ func getConsensusHeight(heightsList []uint32, height uint32) (uint32, error) {
 	}
 	return destHeight, nil
 }
+
+// AddXShardTxMsgHeight records the block height which contains shard msgs of cross shard tx
+func (this *EventStore) AddXShardTxMsgHeight(id xshard_types.ShardTxID, height uint32) error {
+	heights, err := this.GetXShardTxMsgHeights(id)
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	if len(heights) > 0 && heights[len(heights)-1] >= height {
+		return nil
+	}
+	heights = append(heights, height)
+	value := common.NewZeroCopySink(16)
+	value.WriteUint32(uint32(len(heights)))
+	for _, h := range heights {
+		value.WriteUint32(h)
+	}
+	this.store.BatchPut(genXShardTxMsgHeightsKey(id), value.Bytes())
+	return nil
+}
+
+func (this *EventStore) GetXShardTxMsgHeights(id xshard_types.ShardTxID) ([]uint32, error) {
+	data, err := this.store.Get(genXShardTxMsgHeightsKey(id))
+	if err != nil {
+		return nil, err
+	}
+	source := common.NewZeroCopySource(data)
+	m, eof := source.NextUint32()
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	heights := make([]uint32, 0, m)
+	for i := 0; i < int(m); i++ {
+		height, eof := source.NextUint32()
+		if eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+		heights = append(heights, height)
+	}
+	return heights, nil
+}
+
+func genXShardTxMsgHeightsKey(id xshard_types.ShardTxID) []byte {
+	key := common.NewZeroCopySink(64)
+	key.WriteByte(byte(scom.XSHARD_TX_MSG_HEIGHTS))
+	key.WriteBytes([]byte(id))
+	return key.Bytes()
+}
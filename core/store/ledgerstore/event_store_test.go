@@ -27,7 +27,9 @@ import (
 	vbftcfg "github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/core/payload"
 	com "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/xshard_types"
 	msg "github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 )
 
@@ -115,6 +117,31 @@ func TestAddShardConsensusHeight(t *testing.T) {
 	}
 }
 
+func TestAddXShardTxMsgHeight(t *testing.T) {
+	txHash := common.Uint256{1, 2, 3}
+	id := xshard_types.NewShardTxID(txHash)
+	for _, height := range []uint32{100, 100, 120} {
+		testEventStore.NewBatch()
+		if err := testEventStore.AddXShardTxMsgHeight(id, height); err != nil {
+			t.Errorf("TestAddXShardTxMsgHeight AddXShardTxMsgHeight err :%s", err)
+			return
+		}
+		if err := testEventStore.CommitTo(); err != nil {
+			t.Errorf("TestAddXShardTxMsgHeight CommitTo err :%s", err)
+			return
+		}
+	}
+	heights, err := testEventStore.GetXShardTxMsgHeights(id)
+	if err != nil {
+		t.Errorf("TestAddXShardTxMsgHeight GetXShardTxMsgHeights err:%s", err)
+		return
+	}
+	if !reflect.DeepEqual(heights, []uint32{100, 120}) {
+		t.Errorf("TestAddXShardTxMsgHeight failed heights:%v", heights)
+		return
+	}
+}
+
 func TestAddShardConsensusConfig(t *testing.T) {
 	shardID := common.NewShardIDUnchecked(1)
 	height := 110
@@ -180,3 +207,43 @@ func TestSaveContractMetaHeights(t *testing.T) {
 		return
 	}
 }
+
+func TestPruneEventNotifyByBlock(t *testing.T) {
+	height := uint32(30)
+	txHash := common.Uint256{4, 5, 6}
+	testEventStore.NewBatch()
+	err := testEventStore.SaveEventNotifyByTx(txHash, &event.ExecuteNotify{TxHash: txHash, State: event.CONTRACT_STATE_SUCCESS})
+	if err != nil {
+		t.Fatalf("SaveEventNotifyByTx err: %s", err)
+	}
+	err = testEventStore.SaveEventNotifyByBlock(height, []common.Uint256{txHash})
+	if err != nil {
+		t.Fatalf("SaveEventNotifyByBlock err: %s", err)
+	}
+	if err = testEventStore.CommitTo(); err != nil {
+		t.Fatalf("CommitTo err: %s", err)
+	}
+	notifies, err := testEventStore.GetEventNotifyByBlock(height)
+	if err != nil || len(notifies) != 1 {
+		t.Fatalf("GetEventNotifyByBlock err: %v, notifies: %d", err, len(notifies))
+	}
+
+	testEventStore.NewBatch()
+	if err = testEventStore.PruneEventNotifyByBlock(height); err != nil {
+		t.Fatalf("PruneEventNotifyByBlock err: %s", err)
+	}
+	if err = testEventStore.CommitTo(); err != nil {
+		t.Fatalf("CommitTo err: %s", err)
+	}
+	if _, err = testEventStore.GetEventNotifyByTx(txHash); err != com.ErrNotFound {
+		t.Fatalf("event notify of tx should be pruned, err: %v", err)
+	}
+	if _, err = testEventStore.GetEventNotifyByBlock(height); err != com.ErrNotFound {
+		t.Fatalf("event notify of block should be pruned, err: %v", err)
+	}
+	// prune again should be ok
+	testEventStore.NewBatch()
+	if err = testEventStore.PruneEventNotifyByBlock(height); err != nil {
+		t.Fatalf("PruneEventNotifyByBlock err: %s", err)
+	}
+}
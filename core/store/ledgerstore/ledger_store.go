@@ -44,6 +44,7 @@ import (
 	"github.com/ontio/ontology/core/signature"
 	"github.com/ontio/ontology/core/states"
 	"github.com/ontio/ontology/core/store"
+	"github.com/ontio/ontology/core/store/backend"
 	scom "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/store/overlaydb"
 	"github.com/ontio/ontology/core/types"
@@ -51,6 +52,7 @@ import (
 	"github.com/ontio/ontology/errors"
 	"github.com/ontio/ontology/events"
 	"github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/merkle"
 	"github.com/ontio/ontology/smartcontract"
 	scommon "github.com/ontio/ontology/smartcontract/common"
 	"github.com/ontio/ontology/smartcontract/event"
@@ -66,6 +68,7 @@ import (
 const (
 	SYSTEM_VERSION          = byte(1)      //Version of ledger store
 	HEADER_INDEX_BATCH_SIZE = uint32(2000) //Bath size of saving header index
+	PRUNE_BATCH_SIZE        = uint32(100)  //Max count of blocks pruned after saving one block
 )
 
 var (
@@ -80,19 +83,31 @@ var (
 //LedgerStoreImp is main store struct fo ledger
 type LedgerStoreImp struct {
 	parentShardStore     store.LedgerStore
-	blockStore           *BlockStore                      //BlockStore for saving block & transaction data
-	stateStore           *StateStore                      //StateStore for saving state data, like balance, smart contract execution result, and so on.
-	eventStore           *EventStore                      //EventStore for saving log those gen after smart contract executed.
-	storedIndexCount     uint32                           //record the count of have saved block index
-	currBlockHeight      uint32                           //Current block height
-	currBlockHash        common.Uint256                   //Current block hash
-	headerCache          map[common.Uint256]*types.Header //BlockHash => Header
-	headerIndex          map[uint32]common.Uint256        //Header index, Mapping header height => block hash
+	blockStore           *BlockStore                        //BlockStore for saving block & transaction data
+	stateStore           *StateStore                        //StateStore for saving state data, like balance, smart contract execution result, and so on.
+	eventStore           *EventStore                        //EventStore for saving log those gen after smart contract executed.
+	crossShardStore      *CrossShardStore                   //CrossShardStore for saving cross shard msgs, used by pruning only
+	consensusStore       *ConsensusStore                    //ConsensusStore for saving consensus msgs of blocks, used by pruning only
+	childShardStores     map[common.ShardID]*LedgerStoreImp //Ledger stores of child shards reading cross shard msgs of this ledger
+	storedIndexCount     uint32                             //record the count of have saved block index
+	currBlockHeight      uint32                             //Current block height
+	currBlockHash        common.Uint256                     //Current block hash
+	headerCache          map[common.Uint256]*types.Header   //BlockHash => Header
+	headerIndex          map[uint32]common.Uint256          //Header index, Mapping header height => block hash
 	savingBlockSemaphore chan bool
 	vbftPeerInfoheader   map[string]uint32 //pubInfo save pubkey,peerindex
 	vbftPeerInfoblock    map[string]uint32 //pubInfo save pubkey,peerindex
 	lock                 sync.RWMutex
 	stateHashCheckHeight uint32
+	pruneRetention       uint32 //Count of latest blocks whose body is kept, 0 to disable pruning
+	prunedHeight         uint32 //Lowest block height whose body has not been pruned
+	enableArchive        bool   //Whether archive the history states
+	enableStorageTrie    bool   //Whether maintain the storage trie before it is required by consensus
+}
+
+//newPersistStore open the persist store at path with the configured db backend
+func newPersistStore(path string) (scom.PersistStore, error) {
+	return backend.NewPersistStore(config.DefConfig.Common.DBBackend, path)
 }
 
 //NewLedgerStore return LedgerStoreImp instance
@@ -101,11 +116,21 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32, parentShardStore sto
 		parentShardStore:     parentShardStore,
 		headerIndex:          make(map[uint32]common.Uint256),
 		headerCache:          make(map[common.Uint256]*types.Header, 0),
+		childShardStores:     make(map[common.ShardID]*LedgerStoreImp),
 		vbftPeerInfoheader:   make(map[string]uint32),
 		vbftPeerInfoblock:    make(map[string]uint32),
 		savingBlockSemaphore: make(chan bool, 1),
 		stateHashCheckHeight: stateHashHeight,
 	}
+	if retention := config.DefConfig.Common.PruneRetention; retention > 0 {
+		if retention < config.MIN_PRUNE_RETENTION {
+			log.Warnf("prune retention %d is too small, use %d instead", retention, config.MIN_PRUNE_RETENTION)
+			retention = config.MIN_PRUNE_RETENTION
+		}
+		ledgerStore.pruneRetention = retention
+	}
+	ledgerStore.enableArchive = config.DefConfig.Common.EnableArchive
+	ledgerStore.enableStorageTrie = config.DefConfig.Common.EnableStorageTrie
 
 	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), true)
 	if err != nil {
@@ -120,6 +145,13 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32, parentShardStore sto
 		return nil, fmt.Errorf("NewStateStore error %s", err)
 	}
 	ledgerStore.stateStore = stateStore
+	if !ledgerStore.enableArchive {
+		// state changes will not be archived from now on, so the archived states are not usable any more
+		err = stateStore.DeleteArchiveHeight()
+		if err != nil {
+			return nil, fmt.Errorf("DeleteArchiveHeight error %s", err)
+		}
+	}
 
 	eventState, err := NewEventStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirEvent))
 	if err != nil {
@@ -252,6 +284,26 @@ func (this *LedgerStoreImp) init() error {
 	if err != nil {
 		return fmt.Errorf("loadHeaderIndexList error %s", err)
 	}
+	err = this.loadPrunedHeight()
+	if err != nil {
+		return fmt.Errorf("loadPrunedHeight error %s", err)
+	}
+	_, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if this.isStorageTrieEnabled() {
+		err = this.stateStore.InitStorageTrie(stateHeight)
+		if err != nil {
+			return fmt.Errorf("InitStorageTrie error %s", err)
+		}
+	} else {
+		// storage trie will not be updated from now on, it should be rebuilt when enabled again
+		err = this.stateStore.DeleteStorageTrieStart()
+		if err != nil {
+			return fmt.Errorf("DeleteStorageTrieStart error %s", err)
+		}
+	}
 	err = this.recoverStore()
 	if err != nil {
 		return fmt.Errorf("recoverStore error %s", err)
@@ -270,6 +322,15 @@ func (this *LedgerStoreImp) loadCurrentBlock() error {
 	return nil
 }
 
+func (this *LedgerStoreImp) loadPrunedHeight() error {
+	prunedHeight, err := this.blockStore.GetPrunedHeight()
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	this.prunedHeight = prunedHeight
+	return nil
+}
+
 func (this *LedgerStoreImp) loadHeaderIndexList() error {
 	currBlockHeight := this.GetCurrentBlockHeight()
 	headerIndex, err := this.blockStore.GetHeaderIndexList()
@@ -450,37 +511,11 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[
 	}
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
 	if consensusType == "vbft" {
-		//check bookkeeppers
-		m := len(vbftPeerInfo) - (len(vbftPeerInfo)*6)/7
-		if len(header.Bookkeepers) < m {
-			return vbftPeerInfo, fmt.Errorf("header Bookkeepers %d more than 6/7 len vbftPeerInfo%d", len(header.Bookkeepers), len(vbftPeerInfo))
-		}
-		for _, bookkeeper := range header.Bookkeepers {
-			pubkey := vconfig.PubkeyID(bookkeeper)
-			_, present := vbftPeerInfo[pubkey]
-			if !present {
-				log.Errorf("invalid pubkey :%v,height:%d", pubkey, header.Height)
-				return vbftPeerInfo, fmt.Errorf("invalid pubkey :%v", pubkey)
-			}
-		}
-		hash := header.Hash()
-		err = signature.VerifyMultiSignature(hash[:], header.Bookkeepers, m, header.SigData)
+		peerInfo, err := vconfig.VerifyVbftHeader(header, vbftPeerInfo)
 		if err != nil {
-			log.Errorf("VerifyMultiSignature:%s,Bookkeepers:%d,pubkey:%d,heigh:%d", err, len(header.Bookkeepers), len(vbftPeerInfo), header.Height)
-			return vbftPeerInfo, err
-		}
-		blkInfo, err := vconfig.VbftBlock(header)
-		if err != nil {
-			return vbftPeerInfo, err
+			log.Errorf("verify vbft header %d: %s", header.Height, err)
 		}
-		if blkInfo.NewChainConfig != nil {
-			peerInfo := make(map[string]uint32)
-			for _, p := range blkInfo.NewChainConfig.Peers {
-				peerInfo[p.ID] = p.Index
-			}
-			return peerInfo, nil
-		}
-		return vbftPeerInfo, nil
+		return peerInfo, err
 	} else {
 		address, err := types.AddressFromBookkeepers(header.Bookkeepers)
 		if err != nil {
@@ -535,6 +570,26 @@ func (this *LedgerStoreImp) GetStateMerkleRoot(height uint32) (common.Uint256, e
 	return this.stateStore.GetStateMerkleRoot(height)
 }
 
+//isStorageTrieEnabled checks if storage trie is maintained. Storage trie is also maintained if vbft genesis config
+//requires PrevStorageRoot from some height, since all the nodes should verify it. It is built at startup
+func (this *LedgerStoreImp) isStorageTrieEnabled() bool {
+	return this.enableStorageTrie || config.GetStorageRootCheckHeight() != 0
+}
+
+//GetStorageTrieRoot return the storage trie root at block height. Storage trie should be enabled
+func (this *LedgerStoreImp) GetStorageTrieRoot(height uint32) (common.Uint256, error) {
+	return this.stateStore.GetStorageTrieRoot(height)
+}
+
+//GetStorageProof return the raw storage item of key and its proof against the storage trie root at block height.
+//Storage trie should be enabled
+func (this *LedgerStoreImp) GetStorageProof(key *states.StorageKey, height uint32) ([]byte, *merkle.SparseMerkleProof, error) {
+	if currHeight := this.GetCurrentBlockHeight(); height > currHeight {
+		return nil, nil, fmt.Errorf("height %d is higher than current block height %d", height, currHeight)
+	}
+	return this.stateStore.GetStorageProof(key, height)
+}
+
 func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.ExecuteResult, err error) {
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
@@ -542,6 +597,12 @@ func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.Execu
 	blockHeight := block.Header.Height
 	if blockHeight <= currBlockHeight {
 		result.MerkleRoot, err = this.GetStateMerkleRoot(blockHeight)
+		if err == nil && this.isStorageTrieEnabled() {
+			// storage trie root is not saved if block is committed before storage trie is enabled
+			if root, e := this.GetStorageTrieRoot(blockHeight); e == nil {
+				result.StorageRoot = root
+			}
+		}
 		return
 	}
 	nextBlockHeight := currBlockHeight + 1
@@ -549,7 +610,6 @@ func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.Execu
 		err = fmt.Errorf("block height %d not equal next block height %d", blockHeight, nextBlockHeight)
 		return
 	}
-
 	result, err = this.executeBlock(block)
 	return
 }
@@ -571,15 +631,152 @@ func (this *LedgerStoreImp) SubmitBlock(block *types.Block, result store.Execute
 	if err != nil {
 		return fmt.Errorf("verifyHeader error %s", err)
 	}
-
 	err = this.submitBlock(block, result)
 	if err != nil {
 		return fmt.Errorf("saveBlock error %s", err)
 	}
 	this.delHeaderCache(block.Hash())
+	err = this.pruneBlocks(blockHeight)
+	if err != nil {
+		log.Errorf("pruneBlocks at height %d error %s", blockHeight, err)
+	}
 	return nil
 }
 
+//pruneBlocks delete block bodies, event notifies and consumed cross shard msgs of blocks out of retention window.
+//Headers and merkle trees are kept. At most PRUNE_BATCH_SIZE blocks are pruned each time, pruned height is
+//saved after all the other stores are committed, so pruning will be redone if node crashed
+func (this *LedgerStoreImp) pruneBlocks(currHeight uint32) error {
+	if this.pruneRetention == 0 || currHeight <= this.pruneRetention {
+		return nil
+	}
+	start := this.getPrunedHeight()
+	end := currHeight - this.pruneRetention
+	if childHeight, ok := this.getChildShardsParentHeight(); ok && childHeight+1 < end {
+		// cross shard msgs to child shards are kept until child shards have processed the block
+		end = childHeight + 1
+	}
+	if start >= end {
+		return nil
+	}
+	if end-start > PRUNE_BATCH_SIZE {
+		end = start + PRUNE_BATCH_SIZE
+	}
+	this.blockStore.NewBatch()
+	this.eventStore.NewBatch()
+	this.stateStore.NewBatch()
+	shardTxs := make([]*types.CrossShardTxInfos, 0)
+	for height := start; height < end; height++ {
+		blockHash := this.GetBlockHash(height)
+		txs, err := this.blockStore.PruneBlock(blockHash)
+		if err != nil {
+			return fmt.Errorf("blockStore.PruneBlock height %d error %s", height, err)
+		}
+		shardTxs = append(shardTxs, txs...)
+		err = this.eventStore.PruneEventNotifyByBlock(height)
+		if err != nil {
+			return fmt.Errorf("eventStore.PruneEventNotifyByBlock height %d error %s", height, err)
+		}
+		err = this.stateStore.PruneShardMsgsInBlock(height)
+		if err != nil {
+			return fmt.Errorf("stateStore.PruneShardMsgsInBlock height %d error %s", height, err)
+		}
+	}
+	if this.crossShardStore != nil {
+		for _, shardTx := range shardTxs {
+			if shardTx.ShardMsg == nil {
+				continue
+			}
+			err := this.crossShardStore.DeleteCrossShardMsgByHash(shardTx.ShardMsg.PreCrossShardMsgHash)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if this.consensusStore != nil {
+		err := this.consensusStore.PruneBlockConsensusMsgs(start, end)
+		if err != nil {
+			return err
+		}
+	}
+	err := this.eventStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("eventStore.CommitTo error %s", err)
+	}
+	err = this.stateStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("stateStore.CommitTo error %s", err)
+	}
+	this.blockStore.SavePrunedHeight(end)
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	this.setPrunedHeight(end)
+	return nil
+}
+
+func (this *LedgerStoreImp) getPrunedHeight() uint32 {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.prunedHeight
+}
+
+func (this *LedgerStoreImp) setPrunedHeight(height uint32) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.prunedHeight = height
+}
+
+//SetCrossShardStore set the cross shard store whose consumed msgs are deleted in pruning
+func (this *LedgerStoreImp) SetCrossShardStore(crossShardStore *CrossShardStore) {
+	this.crossShardStore = crossShardStore
+}
+
+//SetConsensusStore set the consensus store whose block consensus msgs are deleted in pruning
+func (this *LedgerStoreImp) SetConsensusStore(consensusStore *ConsensusStore) {
+	this.consensusStore = consensusStore
+}
+
+//AddChildShardStore register the ledger store of child shard, cross shard msgs to child shard are not pruned
+//before child shard has processed the block
+func (this *LedgerStoreImp) AddChildShardStore(shardID common.ShardID, childStore *LedgerStoreImp) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.childShardStores[shardID] = childStore
+}
+
+//RemoveChildShardStore unregister the ledger store of child shard when it is closed
+func (this *LedgerStoreImp) RemoveChildShardStore(shardID common.ShardID) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.childShardStores, shardID)
+}
+
+//getChildShardsParentHeight return the min parent height of current blocks of child shards,
+//return false if there is no child shard
+func (this *LedgerStoreImp) getChildShardsParentHeight() (uint32, bool) {
+	this.lock.RLock()
+	childStores := make([]*LedgerStoreImp, 0, len(this.childShardStores))
+	for _, childStore := range this.childShardStores {
+		childStores = append(childStores, childStore)
+	}
+	this.lock.RUnlock()
+
+	minHeight := uint32(math.MaxUint32)
+	for _, childStore := range childStores {
+		header, err := childStore.GetHeaderByHash(childStore.GetCurrentBlockHash())
+		if err != nil || header == nil {
+			// child shard is not initialized yet
+			return 0, true
+		}
+		if header.ParentHeight < minHeight {
+			minHeight = header.ParentHeight
+		}
+	}
+	return minHeight, len(childStores) > 0
+}
+
 func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
@@ -603,6 +800,7 @@ func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 
 func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.ExecuteResult, err error) {
 	overlay := this.stateStore.NewOverlayDB()
+	xshardTxTimeout := uint32(0)
 	if block.Header.Height != 0 {
 		config := &smartcontract.Config{
 			ShardID:      block.Header.ShardID,
@@ -612,7 +810,7 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 			Tx:           &types.Transaction{},
 		}
 
-		err = refreshGlobalParam(config, storage.NewCacheDB(this.stateStore.NewOverlayDB()), this)
+		xshardTxTimeout, err = refreshGlobalParam(config, storage.NewCacheDB(this.stateStore.NewOverlayDB()), this)
 		if err != nil {
 			return
 		}
@@ -647,6 +845,9 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 
 	cache := storage.NewCacheDB(overlay)
 	xshardDB := storage.NewXShardDB(overlay)
+	if xshardTxTimeout != 0 {
+		xshardDB.SetTxTimeout(xshardTxTimeout)
+	}
 	var shardNotify []xshard_types.CommonShardMsg
 
 	// execute shard txs
@@ -696,6 +897,23 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 		result.Notify = append(result.Notify, notify.ContractEvent)
 	}
 
+	// abort the pending cross shard txs which reach deadline
+	cache.Reset()
+	timeoutNotify := &event.TransactionNotify{ContractEvent: &event.ExecuteNotify{}}
+	err = handleXShardTxTimeout(this, lockedAddress, lockedKeys, cache, xshardDB, block.Header, timeoutNotify)
+	if err != nil {
+		return
+	}
+	shardNotify = append(shardNotify, timeoutNotify.ShardMsg...)
+
+	// deliver shard system messages enqueued by the block
+	cache.Reset()
+	if block.Header.Height != 0 {
+		if err = handleShardSysMsgDelivery(this, cache, block.Header); err != nil {
+			return
+		}
+	}
+
 	xshardDB.SetXShardMsgInBlock(block.Header.Height, shardNotify)
 	addrList = addrList[:0]
 	for addr := range lockedAddress {
@@ -726,6 +944,12 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 	} else {
 		result.MerkleRoot = this.stateStore.GetStateMerkleRootWithNewHash(result.Hash)
 	}
+	if this.isStorageTrieEnabled() {
+		result.StorageRoot, err = this.stateStore.GetStorageTrieRootWithWriteSet(block.Header.Height, result.WriteSet)
+		if err != nil {
+			return
+		}
+	}
 
 	return
 }
@@ -788,6 +1012,18 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 
 	log.Debugf("the state transition hash of block %d is:%s", blockHeight, result.Hash.ToHexString())
 
+	if this.enableArchive {
+		err = this.stateStore.ArchiveWriteSet(blockHeight, result.WriteSet)
+		if err != nil {
+			return fmt.Errorf("ArchiveWriteSet error %s", err)
+		}
+	}
+	if this.isStorageTrieEnabled() {
+		_, err = this.stateStore.UpdateStorageTrie(blockHeight, result.WriteSet)
+		if err != nil {
+			return fmt.Errorf("UpdateStorageTrie error %s", err)
+		}
+	}
 	result.WriteSet.ForEach(func(key, val []byte) {
 		if len(val) == 0 {
 			this.stateStore.BatchDeleteRawKey(key)
@@ -848,6 +1084,21 @@ func (this *LedgerStoreImp) saveCrossShardDataToStore(block *types.Block, result
 	if err != nil {
 		return err
 	}
+	return this.saveXShardTxMsgHeights(block.Header.Height, result.ShardNotify)
+}
+
+func (this *LedgerStoreImp) saveXShardTxMsgHeights(height uint32, msgs []xshard_types.CommonShardMsg) error {
+	saved := make(map[xshard_types.ShardTxID]struct{})
+	for _, msg := range msgs {
+		id := msg.GetShardTxID()
+		if _, present := saved[id]; present {
+			continue
+		}
+		saved[id] = struct{}{}
+		if err := this.eventStore.AddXShardTxMsgHeight(id, height); err != nil {
+			return fmt.Errorf("save xshard tx msg height: %s", err)
+		}
+	}
 	return nil
 }
 
@@ -905,7 +1156,8 @@ func (this *LedgerStoreImp) saveCrossShardGovernanceData(block *types.Block, sha
 }
 
 func (this *LedgerStoreImp) saveParentShardConfig(block *types.Block) error {
-	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
+	// shards may run different consensus, only vbft blocks carry chain config in consensus payload
+	if len(block.Header.ConsensusPayload) == 0 {
 		return nil
 	}
 	blkInfo := &vconfig.VbftBlockInfo{}
@@ -1039,14 +1291,12 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, result store.Execute
 	}
 	this.setCurrentBlock(blockHeight, blockHash)
 
-	shardSysMsg, _, _ := extractShardEvents(result.Notify)
 	sourceAndShardTxHashMap := extractSourceAndShardTxHash(result.Notify)
 	if events.DefActorPublisher != nil {
 		events.DefActorPublisher.Publish(
 			message.TOPIC_SAVE_BLOCK_COMPLETE,
 			&message.SaveBlockCompleteMsg{
 				Block:                   block,
-				ShardSysEvents:          shardSysMsg,
 				SourceAndShardTxHashMap: sourceAndShardTxHashMap,
 			})
 	}
@@ -1098,6 +1348,18 @@ func HandleTransaction(store store.LedgerStore, overlay *overlaydb.OverlayDB, ca
 	notify := &event.TransactionNotify{
 		ContractEvent: events,
 	}
+	if tx.TxType != types.ShardCall && !isSysTransaction(tx, header) && !header.ShardID.IsRootShard() {
+		// stopping shard only finishes pending cross shard txs before archived
+		draining, err := shardmgmt.IsShardDraining(storage.NewCacheDB(overlay))
+		if err != nil {
+			return nil, fmt.Errorf("HandleTransaction tx %s error %s", txHash.ToHexString(), err)
+		}
+		if draining {
+			log.Debugf("HandleTransaction tx %s rejected, shard %d is stopping", txHash.ToHexString(),
+				header.ShardID.ToUint64())
+			return notify, nil
+		}
+	}
 	switch tx.TxType {
 	case types.Deploy:
 		err := HandleDeployTransaction(store, overlay, gasTable, cache, tx, header, notify.ContractEvent)
@@ -1179,6 +1441,11 @@ func (this *LedgerStoreImp) IsContainTransaction(txHash common.Uint256) (bool, e
 	return this.blockStore.ContainTransaction(txHash)
 }
 
+//GetShardTx return shard tx and the height of block it is executed in. Wrap function of BlockStore.GetShardTx
+func (this *LedgerStoreImp) GetShardTx(shardTxHash common.Uint256) (*types.CrossShardTxInfos, uint32, error) {
+	return this.blockStore.GetShardTx(shardTxHash)
+}
+
 //IsContainShardTx return whether the ShardTx is in store. Wrap function of BlockStore.ContainShardTx
 func (this *LedgerStoreImp) IsContainShardTx(shardTxHash common.Uint256) (bool, error) {
 	return this.blockStore.ContainShardTx(shardTxHash)
@@ -1237,12 +1504,20 @@ func (this *LedgerStoreImp) GetSysFeeAmount(blockHash common.Uint256) (common.Fi
 
 //GetTransaction return transaction by transaction hash. Wrap function of BlockStore.GetTransaction
 func (this *LedgerStoreImp) GetTransaction(txHash common.Uint256) (*types.Transaction, uint32, error) {
-	return this.blockStore.GetTransaction(txHash)
+	tx, height, err := this.blockStore.GetTransaction(txHash)
+	if err == nil && height < this.getPrunedHeight() {
+		return nil, height, scom.ErrPruned
+	}
+	return tx, height, err
 }
 
 //GetBlockByHash return block by block hash. Wrap function of BlockStore.GetBlockByHash
 func (this *LedgerStoreImp) GetBlockByHash(blockHash common.Uint256) (*types.Block, error) {
-	return this.blockStore.GetBlock(blockHash)
+	block, err := this.blockStore.GetBlock(blockHash)
+	if err == nil && block.Header.Height < this.getPrunedHeight() {
+		return nil, scom.ErrPruned
+	}
+	return block, err
 }
 
 //GetBlockByHeight return block by height.
@@ -1280,19 +1555,58 @@ func (this *LedgerStoreImp) GetStorageItem(key *states.StorageKey) (*states.Stor
 	return this.stateStore.GetStorageState(key)
 }
 
+//GetStorageItemAtHeight return the storage value of the key in smart contract at history block height. Archive should be enabled
+func (this *LedgerStoreImp) GetStorageItemAtHeight(key *states.StorageKey, height uint32) (*states.StorageItem, error) {
+	if currHeight := this.GetCurrentBlockHeight(); height > currHeight {
+		return nil, fmt.Errorf("height %d is higher than current block height %d", height, currHeight)
+	}
+	return this.stateStore.GetStorageStateAtHeight(key, height)
+}
+
 //GetEventNotifyByTx return the events notify gen by executing of smart contract.  Wrap function of EventStore.GetEventNotifyByTx
 func (this *LedgerStoreImp) GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error) {
-	return this.eventStore.GetEventNotifyByTx(tx)
+	notify, err := this.eventStore.GetEventNotifyByTx(tx)
+	if err == scom.ErrNotFound && this.getPrunedHeight() > 0 {
+		if _, _, e := this.GetTransaction(tx); e == scom.ErrPruned {
+			return nil, scom.ErrPruned
+		}
+	}
+	return notify, err
 }
 
 //GetEventNotifyByBlock return the transaction hash which have event notice after execution of smart contract. Wrap function of EventStore.GetEventNotifyByBlock
 func (this *LedgerStoreImp) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error) {
+	if height < this.getPrunedHeight() {
+		return nil, scom.ErrPruned
+	}
 	return this.eventStore.GetEventNotifyByBlock(height)
 }
 
 //PreExecuteContract return the result of smart contract execution without commit to store
 func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.PreExecResult, error) {
 	height := this.GetCurrentBlockHeight()
+	return this.preExecuteContract(tx, height, uint32(time.Now().Unix()), this.stateStore.NewOverlayDB())
+}
+
+//PreExecuteContractAtHeight return the result of smart contract execution on the states at history block height.
+//Archive should be enabled
+func (this *LedgerStoreImp) PreExecuteContractAtHeight(tx *types.Transaction, height uint32) (*sstate.PreExecResult, error) {
+	stf := &sstate.PreExecResult{State: event.CONTRACT_STATE_FAIL, Gas: neovm.MIN_TRANSACTION_GAS, Result: nil}
+	if currHeight := this.GetCurrentBlockHeight(); height > currHeight {
+		return stf, fmt.Errorf("height %d is higher than current block height %d", height, currHeight)
+	}
+	if err := this.stateStore.CheckArchivedHeight(height); err != nil {
+		return stf, err
+	}
+	header, err := this.GetHeaderByHeight(height)
+	if err != nil {
+		return stf, err
+	}
+	overlay := overlaydb.NewOverlayDB(newArchiveStore(this.stateStore, height))
+	return this.preExecuteContract(tx, height, header.Timestamp, overlay)
+}
+
+func (this *LedgerStoreImp) preExecuteContract(tx *types.Transaction, height, timestamp uint32, overlay *overlaydb.OverlayDB) (*sstate.PreExecResult, error) {
 	stf := &sstate.PreExecResult{State: event.CONTRACT_STATE_FAIL, Gas: neovm.MIN_TRANSACTION_GAS, Result: nil}
 	header, err := this.GetHeaderByHeight(height)
 	if err != nil {
@@ -1301,14 +1615,13 @@ func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.P
 
 	config := &smartcontract.Config{
 		ShardID:      tx.ShardID,
-		Time:         uint32(time.Now().Unix()),
+		Time:         timestamp,
 		Height:       height + 1,
 		ParentHeight: header.ParentHeight,
 		Tx:           tx,
 		BlockHash:    this.GetBlockHash(height),
 	}
 
-	overlay := this.stateStore.NewOverlayDB()
 	cache := storage.NewCacheDB(overlay)
 	preGas, err := this.getPreGas(config, cache)
 	if err != nil {
@@ -1400,10 +1713,16 @@ func (self *LedgerStoreImp) GetBlockShardEvents(height uint32) (events []*messag
 }
 
 func (self *LedgerStoreImp) GetShardMsgsInBlock(blockHeight uint32, shardID common.ShardID) ([]xshard_types.CommonShardMsg, error) {
+	if blockHeight < self.getPrunedHeight() {
+		return nil, scom.ErrPruned
+	}
 	return self.stateStore.GetShardMsgsInBlock(blockHeight, shardID)
 }
 
 func (self *LedgerStoreImp) GetRelatedShardIDsInBlock(blockHeight uint32) ([]common.ShardID, error) {
+	if blockHeight < self.getPrunedHeight() {
+		return nil, scom.ErrPruned
+	}
 	return self.stateStore.GetRelatedShardIDsInBlock(blockHeight)
 }
 
@@ -1476,3 +1795,12 @@ func (this *LedgerStoreImp) GetShardTxState(txHash common.Uint256, notifyId uint
 	crossShardTxID := xshard_types.ShardTxID(string(sink.Bytes()))
 	return xshardDB.GetXShardState(crossShardTxID)
 }
+
+func (this *LedgerStoreImp) GetShardTxStateByID(id xshard_types.ShardTxID) (*xshard_state.TxState, error) {
+	overlay := this.stateStore.NewOverlayDB()
+	return storage.NewXShardDB(overlay).GetXShardState(id)
+}
+
+func (this *LedgerStoreImp) GetXShardTxMsgHeights(id xshard_types.ShardTxID) ([]uint32, error) {
+	return this.eventStore.GetXShardTxMsgHeights(id)
+}
@@ -0,0 +1,751 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/consensus/vbft/config"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+)
+
+const (
+	SNAPSHOT_MAGIC      = "ontology-snapshot" //Magic of snapshot file
+	SNAPSHOT_VERSION    = byte(2)             //Version of snapshot file
+	SNAPSHOT_BATCH_SIZE = 10000               //Count of records committed in one batch when importing snapshot
+)
+
+// SnapshotInfo describes the ledger state carried by a snapshot
+type SnapshotInfo struct {
+	Height      uint32         //Height of the snapshot
+	BlockHash   common.Uint256 //Hash of block at snapshot height
+	BlockRoot   common.Uint256 //Block merkle root at snapshot height
+	StateRoot   common.Uint256 //State merkle root at snapshot height, empty if lower than state hash check height
+	StorageRoot common.Uint256 //Storage trie root at snapshot height, empty if storage trie is not available
+	StateCount  uint64         //Count of key-value pairs in state store
+}
+
+// ExportSnapshot dumps the ledger saved in dataDir at height to w. The snapshot holds the whole state store key space
+// except the storage trie and archived states, the block hash index, the vbft headers linking genesis to height
+// through config blocks, the genesis block, the last vbft config block, the block at height and the block merkle tree
+// hash store. Since state store only keeps the latest state, height must be the current block height, and the ledger
+// must not be opened by a running node.
+func ExportSnapshot(dataDir string, height, stateHashHeight uint32, w io.Writer) (*SnapshotInfo, error) {
+	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), false)
+	if err != nil {
+		return nil, fmt.Errorf("NewBlockStore error %s", err)
+	}
+	defer blockStore.Close()
+	dbPath := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirState)
+	merklePath := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), MerkleTreeStorePath)
+	stateStore, err := NewStateStore(dbPath, merklePath, stateHashHeight)
+	if err != nil {
+		return nil, fmt.Errorf("NewStateStore error %s", err)
+	}
+	if stateStore.merkleHashStore == nil {
+		stateStore.store.Close()
+		return nil, fmt.Errorf("merkle hash store is inconsistent with state store")
+	}
+	defer stateStore.Close()
+	crossShardStore, err := NewCrossShardStore(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	defer crossShardStore.Close()
+
+	blockHash, blockHeight, err := blockStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("blockStore.GetCurrentBlock error %s", err)
+	}
+	stateHash, stateHeight, err := stateStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if stateHeight != blockHeight || stateHash != blockHash {
+		return nil, fmt.Errorf("state store at height %d is behind block store at height %d, start node to recover it first",
+			stateHeight, blockHeight)
+	}
+	if height != blockHeight {
+		return nil, fmt.Errorf("state at height %d is not available, only current block height %d can be exported",
+			height, blockHeight)
+	}
+	block, err := blockStore.GetBlock(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("blockStore.GetBlock height:%d error:%s", height, err)
+	}
+	info := &SnapshotInfo{
+		Height:    height,
+		BlockHash: blockHash,
+		BlockRoot: block.Header.BlockRoot,
+	}
+	info.StateRoot, err = stateStore.GetStateMerkleRoot(height)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, fmt.Errorf("GetStateMerkleRoot height:%d error:%s", height, err)
+	}
+	if root, err := stateStore.GetStorageTrieRoot(height); err == nil {
+		info.StorageRoot = root
+	}
+
+	hasher := sha256.New()
+	bw := bufio.NewWriter(w)
+	writer := io.MultiWriter(bw, hasher)
+	if err := serialization.WriteString(writer, SNAPSHOT_MAGIC); err != nil {
+		return nil, err
+	}
+	if err := serialization.WriteByte(writer, SNAPSHOT_VERSION); err != nil {
+		return nil, err
+	}
+	if err := serialization.WriteUint32(writer, height); err != nil {
+		return nil, err
+	}
+
+	// block hash index is needed to load header index on node start
+	for i := uint32(0); i <= height; i++ {
+		hash, err := blockStore.GetBlockHash(i)
+		if err != nil {
+			return nil, fmt.Errorf("blockStore.GetBlockHash height:%d error:%s", i, err)
+		}
+		if err := hash.Serialize(writer); err != nil {
+			return nil, err
+		}
+	}
+
+	headerHeights, err := snapshotHeaderHeights(blockStore, height)
+	if err != nil {
+		return nil, err
+	}
+	if err := serialization.WriteVarUint(writer, uint64(len(headerHeights))); err != nil {
+		return nil, err
+	}
+	for _, h := range headerHeights {
+		header, err := getSnapshotHeader(blockStore, h)
+		if err != nil {
+			return nil, err
+		}
+		if err := serialization.WriteVarBytes(writer, common.SerializeToBytes(header)); err != nil {
+			return nil, err
+		}
+	}
+
+	heights := []uint32{0}
+	if blkInfo, err := vconfig.VbftBlock(block.Header); err == nil && blkInfo.NewChainConfig == nil &&
+		blkInfo.LastConfigBlockNum != 0 && blkInfo.LastConfigBlockNum < height {
+		heights = append(heights, blkInfo.LastConfigBlockNum)
+	}
+	if height != 0 {
+		heights = append(heights, height)
+	}
+	if err := serialization.WriteVarUint(writer, uint64(len(heights))); err != nil {
+		return nil, err
+	}
+	for _, h := range heights {
+		hash, err := blockStore.GetBlockHash(h)
+		if err != nil {
+			return nil, fmt.Errorf("blockStore.GetBlockHash height:%d error:%s", h, err)
+		}
+		blk, err := blockStore.GetBlock(hash)
+		if err != nil {
+			return nil, fmt.Errorf("blockStore.GetBlock height:%d error:%s", h, err)
+		}
+		if err := serialization.WriteVarBytes(writer, common.SerializeToBytes(blk)); err != nil {
+			return nil, err
+		}
+	}
+
+	info.StateCount, err = exportSnapshotEntries(writer, stateStore.store.NewIterator(nil), isSnapshotSkippedKey)
+	if err != nil {
+		return nil, fmt.Errorf("export state store error %s", err)
+	}
+	if _, err := exportSnapshotEntries(writer, crossShardStore.store.NewIterator(nil), nil); err != nil {
+		return nil, fmt.Errorf("export cross shard store error %s", err)
+	}
+
+	treeSize, _, err := stateStore.GetBlockMerkleTree()
+	if err != nil {
+		return nil, fmt.Errorf("GetBlockMerkleTree error %s", err)
+	}
+	hashNum := merkle.StoredHashNum(treeSize)
+	if err := serialization.WriteUint64(writer, uint64(hashNum)); err != nil {
+		return nil, err
+	}
+	for i := int64(0); i < hashNum; i++ {
+		hash, err := stateStore.merkleHashStore.GetHash(uint32(i))
+		if err != nil {
+			return nil, fmt.Errorf("merkleHashStore.GetHash pos:%d error:%s", i, err)
+		}
+		if _, err := writer.Write(hash[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := bw.Write(hasher.Sum(nil)); err != nil {
+		return nil, err
+	}
+	return info, bw.Flush()
+}
+
+// snapshotHeaderHeights returns heights of the headers verified from genesis to height: header at height, and for each
+// vbft config block, the config block and its previous block, whose LastConfigBlockNum links to the former config block
+func snapshotHeaderHeights(blockStore *BlockStore, height uint32) ([]uint32, error) {
+	heights := []uint32{height}
+	configNum, err := getSnapshotLastConfigBlockNum(blockStore, height)
+	if err != nil {
+		return nil, err
+	}
+	for configNum != 0 {
+		if configNum > heights[0] {
+			return nil, fmt.Errorf("invalid last config block num %d before height %d", configNum, heights[0])
+		}
+		if configNum != heights[0] {
+			heights = append([]uint32{configNum}, heights...)
+		}
+		heights = append([]uint32{configNum - 1}, heights...)
+		if configNum-1 == 0 {
+			break
+		}
+		configNum, err = getSnapshotLastConfigBlockNum(blockStore, configNum-1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if heights[0] == 0 {
+		heights = heights[1:]
+	}
+	return heights, nil
+}
+
+func getSnapshotHeader(blockStore *BlockStore, height uint32) (*types.Header, error) {
+	hash, err := blockStore.GetBlockHash(height)
+	if err != nil {
+		return nil, fmt.Errorf("blockStore.GetBlockHash height:%d error:%s", height, err)
+	}
+	header, err := blockStore.GetHeader(hash)
+	if err != nil {
+		return nil, fmt.Errorf("blockStore.GetHeader height:%d error:%s", height, err)
+	}
+	return header, nil
+}
+
+func getSnapshotLastConfigBlockNum(blockStore *BlockStore, height uint32) (uint32, error) {
+	if height == 0 {
+		return 0, nil
+	}
+	header, err := getSnapshotHeader(blockStore, height)
+	if err != nil {
+		return 0, err
+	}
+	blkInfo, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return 0, fmt.Errorf("snapshot requires vbft header, height:%d error:%s", height, err)
+	}
+	return blkInfo.LastConfigBlockNum, nil
+}
+
+// isStorageTrieKey checks if key belongs to storage trie, which is rebuilt from the states when importing snapshot
+func isStorageTrieKey(key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	prefix := scom.DataEntryPrefix(key[0])
+	return prefix == scom.ST_STORAGE_TRIE_NODE || prefix == scom.SYS_STORAGE_TRIE_ROOT || prefix == scom.SYS_STORAGE_TRIE_START
+}
+
+// isSnapshotSkippedKey checks if key of state store is not exported, the storage trie is rebuilt when importing
+// snapshot, and archived states are local history of the exporting node
+func isSnapshotSkippedKey(key []byte) bool {
+	if len(key) == 0 || isStorageTrieKey(key) {
+		return len(key) != 0
+	}
+	prefix := scom.DataEntryPrefix(key[0])
+	return prefix == scom.ST_ARCHIVE || prefix == scom.SYS_ARCHIVE_HEIGHT
+}
+
+// snapshotTrustedPrefixes are the prefixes of states imported from snapshot without verification: the bookkeeper
+// state saved with genesis block, and the shard msgs and shard events of blocks, which are not committed in any root
+var snapshotTrustedPrefixes = []scom.DataEntryPrefix{
+	scom.ST_BOOKKEEPER,
+	scom.XSHARD_KEY_SHARDS_IN_BLOCK,
+	scom.XSHARD_KEY_REQS_IN_BLOCK,
+	scom.SHARD_EVENTS,
+}
+
+// snapshotVerifiedPrefixes are the prefixes of states imported from snapshot which are verified after import besides
+// the states committed in storage trie
+var snapshotVerifiedPrefixes = []scom.DataEntryPrefix{
+	scom.SYS_CURRENT_BLOCK,
+	scom.SYS_BLOCK_MERKLE_TREE,
+	scom.SYS_STATE_MERKLE_TREE,
+	scom.DATA_STATE_MERKLE_ROOT,
+}
+
+// isSnapshotRejectedKey checks if key of state store in snapshot is unknown, or should not be exported
+func isSnapshotRejectedKey(key []byte) bool {
+	if len(key) == 0 || isSnapshotSkippedKey(key) {
+		return true
+	}
+	if isStorageTrieState(key) {
+		return false
+	}
+	for _, prefixes := range [][]scom.DataEntryPrefix{snapshotVerifiedPrefixes, snapshotTrustedPrefixes} {
+		for _, prefix := range prefixes {
+			if key[0] == byte(prefix) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func exportSnapshotEntries(w io.Writer, iter scom.StoreIterator, skip func(key []byte) bool) (uint64, error) {
+	defer iter.Release()
+	count := uint64(0)
+	for iter.Next() {
+		if skip != nil && skip(iter.Key()) {
+			continue
+		}
+		if err := serialization.WriteBool(w, true); err != nil {
+			return count, err
+		}
+		if err := serialization.WriteVarBytes(w, iter.Key()); err != nil {
+			return count, err
+		}
+		if err := serialization.WriteVarBytes(w, iter.Value()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := iter.Error(); err != nil {
+		return count, err
+	}
+	return count, serialization.WriteBool(w, false)
+}
+
+// ImportSnapshot restores the ledger in dataDir from snapshot read from r. Before the block store is written, the
+// snapshot checksum is checked, the vbft headers in snapshot are verified from the local genesis block through the
+// config blocks to the header at snapshot height, and nextHeader, the header at snapshot height + 1 got from any node,
+// is verified with the chain config of snapshot height. Then the states committed in storage trie are verified against
+// the storage trie root committed in nextHeader, the block merkle tree against the block root of the header at
+// snapshot height, and the state merkle tree against the state merkle root committed in nextHeader. Block hashes of
+// the heights between the verified headers can not be verified. The states of snapshotTrustedPrefixes and the cross
+// shard store are not committed in any root, so they are trusted, and states of any other prefix are rejected. dataDir must not hold a ledger, and is left without ledger if the
+// snapshot is rejected.
+func ImportSnapshot(dataDir string, r io.Reader, genesisHash common.Uint256, nextHeader *types.Header) (*SnapshotInfo, error) {
+	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), false)
+	if err != nil {
+		return nil, fmt.Errorf("NewBlockStore error %s", err)
+	}
+	defer blockStore.Close()
+	_, err = blockStore.GetVersion()
+	if err == nil {
+		return nil, fmt.Errorf("ledger already exists in %s", dataDir)
+	} else if err != scom.ErrNotFound {
+		return nil, fmt.Errorf("GetVersion error %s", err)
+	}
+
+	dbPath := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirState)
+	merklePath := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), MerkleTreeStorePath)
+	crossShardPath := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirCrossShard)
+	for _, p := range []string{dbPath, merklePath, crossShardPath} {
+		if err := os.RemoveAll(p); err != nil {
+			return nil, fmt.Errorf("remove %s error %s", p, err)
+		}
+	}
+	info, err := importSnapshot(blockStore, dbPath, merklePath, dataDir, r, genesisHash, nextHeader)
+	if err != nil {
+		for _, p := range []string{dbPath, merklePath, crossShardPath} {
+			os.RemoveAll(p)
+		}
+		return nil, err
+	}
+	return info, nil
+}
+
+func importSnapshot(blockStore *BlockStore, dbPath, merklePath, dataDir string, r io.Reader,
+	genesisHash common.Uint256, nextHeader *types.Header) (*SnapshotInfo, error) {
+	if nextHeader == nil {
+		return nil, fmt.Errorf("header of snapshot height + 1 is required to verify snapshot")
+	}
+	store, err := newPersistStore(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("newPersistStore error %s", err)
+	}
+	defer store.Close()
+	stateStore := &StateStore{dbDir: dbPath, store: store, merklePath: merklePath}
+	crossShardStore, err := NewCrossShardStore(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	defer crossShardStore.Close()
+
+	hasher := sha256.New()
+	br := bufio.NewReader(r)
+	reader := io.TeeReader(br, hasher)
+	magic, err := serialization.ReadString(reader)
+	if err != nil || magic != SNAPSHOT_MAGIC {
+		return nil, fmt.Errorf("invalid snapshot file")
+	}
+	version, err := serialization.ReadByte(reader)
+	if err != nil {
+		return nil, err
+	}
+	if version != SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	height, err := serialization.ReadUint32(reader)
+	if err != nil {
+		return nil, err
+	}
+	blockHashes := make([]common.Uint256, 0)
+	for i := uint32(0); i <= height; i++ {
+		var hash common.Uint256
+		if err := hash.Deserialize(reader); err != nil {
+			return nil, fmt.Errorf("read block hash height:%d error:%s", i, err)
+		}
+		blockHashes = append(blockHashes, hash)
+	}
+	if blockHashes[0] != genesisHash {
+		return nil, fmt.Errorf("genesis block hash %s mismatches local genesis block hash %s",
+			blockHashes[0].ToHexString(), genesisHash.ToHexString())
+	}
+
+	headerCount, err := serialization.ReadVarUint(reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]*types.Header, 0, headerCount)
+	for i := uint64(0); i < headerCount; i++ {
+		raw, err := serialization.ReadVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		header, err := types.HeaderFromRawBytes(raw)
+		if err != nil {
+			return nil, fmt.Errorf("HeaderFromRawBytes error %s", err)
+		}
+		if header.Height == 0 || header.Height > height || header.Hash() != blockHashes[header.Height] {
+			return nil, fmt.Errorf("header at height %d mismatches block hash index", header.Height)
+		}
+		if len(headers) > 0 && header.Height <= headers[len(headers)-1].Height {
+			return nil, fmt.Errorf("header at height %d is out of order", header.Height)
+		}
+		headers = append(headers, header)
+	}
+	if height > 0 && (len(headers) == 0 || headers[len(headers)-1].Height != height) {
+		return nil, fmt.Errorf("header at snapshot height %d not found", height)
+	}
+
+	count, err := serialization.ReadVarUint(reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]*types.Block, 0)
+	var block, genesis *types.Block
+	for i := uint64(0); i < count; i++ {
+		raw, err := serialization.ReadVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		blk, err := types.BlockFromRawBytes(raw)
+		if err != nil {
+			return nil, fmt.Errorf("BlockFromRawBytes error %s", err)
+		}
+		if blk.Header.Height > height || blk.Hash() != blockHashes[blk.Header.Height] {
+			return nil, fmt.Errorf("block at height %d mismatches block hash index", blk.Header.Height)
+		}
+		if blk.Header.Height == height {
+			block = blk
+		}
+		if blk.Header.Height == 0 {
+			genesis = blk
+		}
+		blocks = append(blocks, blk)
+	}
+	if block == nil || genesis == nil {
+		return nil, fmt.Errorf("block at snapshot height %d or genesis block not found", height)
+	}
+	if height > 0 && block.Header.PrevBlockHash != blockHashes[height-1] {
+		return nil, fmt.Errorf("prev block hash of block at height %d mismatches block hash index", height)
+	}
+	stateRoot, storageRoot, err := verifySnapshotHeaders(genesis.Header, headers, nextHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	stateCount, err := importSnapshotEntries(reader, store, isSnapshotRejectedKey)
+	if err != nil {
+		return nil, fmt.Errorf("import state store error %s", err)
+	}
+	if _, err := importSnapshotEntries(reader, crossShardStore.store, nil); err != nil {
+		return nil, fmt.Errorf("import cross shard store error %s", err)
+	}
+
+	hashNum, err := serialization.ReadUint64(reader)
+	if err != nil {
+		return nil, err
+	}
+	hashStore, err := merkle.NewFileHashStore(merklePath, 0)
+	if err != nil {
+		return nil, fmt.Errorf("NewFileHashStore error %s", err)
+	}
+	defer hashStore.Close()
+	hashes := make([]common.Uint256, 0, SNAPSHOT_BATCH_SIZE)
+	for i := uint64(0); i < hashNum; i++ {
+		var hash common.Uint256
+		if _, err := io.ReadFull(reader, hash[:]); err != nil {
+			return nil, fmt.Errorf("read merkle hash pos:%d error:%s", i, err)
+		}
+		hashes = append(hashes, hash)
+		if len(hashes) == SNAPSHOT_BATCH_SIZE || i+1 == hashNum {
+			if err := hashStore.Append(hashes); err != nil {
+				return nil, fmt.Errorf("merkle hash store append error %s", err)
+			}
+			hashes = hashes[:0]
+		}
+	}
+	if err := hashStore.Flush(); err != nil {
+		return nil, err
+	}
+
+	checksum := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(br, checksum); err != nil {
+		return nil, fmt.Errorf("read checksum error %s", err)
+	}
+	if !bytes.Equal(checksum, hasher.Sum(nil)) {
+		return nil, fmt.Errorf("snapshot checksum mismatch")
+	}
+
+	info := &SnapshotInfo{
+		Height:     height,
+		BlockHash:  blockHashes[height],
+		BlockRoot:  block.Header.BlockRoot,
+		StateCount: stateCount,
+	}
+	if err := verifySnapshotState(stateStore, info, hashNum, stateRoot, storageRoot); err != nil {
+		return nil, err
+	}
+
+	blockStore.NewBatch()
+	for h, hash := range blockHashes {
+		blockStore.SaveBlockHash(uint32(h), hash)
+		if (h+1)%SNAPSHOT_BATCH_SIZE == 0 {
+			if err := blockStore.CommitTo(); err != nil {
+				return nil, fmt.Errorf("blockStore.CommitTo error %s", err)
+			}
+			blockStore.NewBatch()
+		}
+	}
+	for _, blk := range blocks {
+		if err := blockStore.SaveBlock(blk); err != nil {
+			return nil, fmt.Errorf("SaveBlock height %d error %s", blk.Header.Height, err)
+		}
+	}
+	if err := blockStore.SaveCurrentBlock(height, info.BlockHash); err != nil {
+		return nil, fmt.Errorf("SaveCurrentBlock error %s", err)
+	}
+	if err := blockStore.CommitTo(); err != nil {
+		return nil, fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	// version marks the ledger initialized, so it is saved at last
+	if err := blockStore.SaveVersion(SYSTEM_VERSION); err != nil {
+		return nil, fmt.Errorf("SaveVersion error %s", err)
+	}
+	return info, nil
+}
+
+// verifySnapshotHeaders verifies the headers from genesis to snapshot height and the next header with vbft chain
+// configs. A config block must follow the header before it, which links to the former config block by
+// LastConfigBlockNum, so that no chain config change is skipped. Returns the state merkle root and the storage trie
+// root of snapshot height committed in the next header.
+func verifySnapshotHeaders(genesis *types.Header, headers []*types.Header, nextHeader *types.Header) (common.Uint256,
+	common.Uint256, error) {
+	genesisInfo, err := vconfig.VbftBlock(genesis)
+	if err != nil {
+		return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("snapshot requires vbft genesis block: %s", err)
+	}
+	if genesisInfo.NewChainConfig == nil {
+		return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("chain config not found in genesis block")
+	}
+	last := genesis
+	if len(headers) > 0 {
+		last = headers[len(headers)-1]
+	}
+	if nextHeader.Height != last.Height+1 {
+		return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("next header %d does not follow snapshot height %d",
+			nextHeader.Height, last.Height)
+	}
+	peers := vconfig.ChainConfigPeers(genesisInfo.NewChainConfig)
+	prev := genesis
+	lastConfigNum := uint32(0)
+	var nextInfo *vconfig.VbftBlockInfo
+	for _, header := range append(headers, nextHeader) {
+		blkInfo, err := vconfig.VbftBlock(header)
+		if err != nil {
+			return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("header %d: %s", header.Height, err)
+		}
+		consecutive := header.Height == prev.Height+1
+		if consecutive && header.PrevBlockHash != prev.Hash() {
+			return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("prev block hash of header %d mismatches",
+				header.Height)
+		}
+		if blkInfo.NewChainConfig != nil {
+			if !consecutive || blkInfo.LastConfigBlockNum != header.Height {
+				return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("config block %d is not linked",
+					header.Height)
+			}
+		} else if blkInfo.LastConfigBlockNum != lastConfigNum {
+			return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("last config block num %d of header %d mismatches %d",
+				blkInfo.LastConfigBlockNum, header.Height, lastConfigNum)
+		}
+		peers, err = vconfig.VerifyVbftHeader(header, peers)
+		if err != nil {
+			return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("verify header %d: %s", header.Height, err)
+		}
+		if blkInfo.NewChainConfig != nil {
+			lastConfigNum = header.Height
+		}
+		prev, nextInfo = header, blkInfo
+	}
+
+	if len(nextInfo.PrevStorageRoot) == 0 {
+		return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("storage trie root is not committed in header %d",
+			nextHeader.Height)
+	}
+	storageRoot, err := common.Uint256ParseFromBytes(nextInfo.PrevStorageRoot)
+	if err != nil {
+		return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("invalid PrevStorageRoot of header %d: %s",
+			nextHeader.Height, err)
+	}
+	stateRoot := common.UINT256_EMPTY
+	if len(nextInfo.PrevStateRoot) != 0 {
+		stateRoot, err = common.Uint256ParseFromBytes(nextInfo.PrevStateRoot)
+		if err != nil {
+			return common.UINT256_EMPTY, common.UINT256_EMPTY, fmt.Errorf("invalid PrevStateRoot of header %d: %s",
+				nextHeader.Height, err)
+		}
+	}
+	return stateRoot, storageRoot, nil
+}
+
+func verifySnapshotState(stateStore *StateStore, info *SnapshotInfo, hashNum uint64, stateRoot,
+	storageRoot common.Uint256) error {
+	hash, height, err := stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if height != info.Height || hash != info.BlockHash {
+		return fmt.Errorf("current block of state store mismatches snapshot height %d", info.Height)
+	}
+
+	treeSize, hashes, err := stateStore.GetBlockMerkleTree()
+	if err != nil {
+		return fmt.Errorf("GetBlockMerkleTree error %s", err)
+	}
+	if treeSize != info.Height+1 {
+		return fmt.Errorf("block merkle tree size %d mismatches snapshot height %d", treeSize, info.Height)
+	}
+	if uint64(merkle.StoredHashNum(treeSize)) != hashNum {
+		return fmt.Errorf("merkle hash store is inconsistent with block merkle tree")
+	}
+	// genesis block root is not checked when saving block
+	if info.Height != 0 {
+		if root := merkle.NewTree(treeSize, hashes, nil).Root(); root != info.BlockRoot {
+			return fmt.Errorf("block merkle root %s mismatches header block root %s",
+				root.ToHexString(), info.BlockRoot.ToHexString())
+		}
+	}
+
+	// storage trie is rebuilt from the imported states
+	if err := stateStore.InitStorageTrie(info.Height); err != nil {
+		return fmt.Errorf("InitStorageTrie error %s", err)
+	}
+	info.StorageRoot, err = stateStore.GetStorageTrieRoot(info.Height)
+	if err != nil {
+		return fmt.Errorf("GetStorageTrieRoot height:%d error:%s", info.Height, err)
+	}
+	if info.StorageRoot != storageRoot {
+		return fmt.Errorf("storage trie root %s mismatches storage trie root %s committed in header",
+			info.StorageRoot.ToHexString(), storageRoot.ToHexString())
+	}
+
+	info.StateRoot, err = stateStore.GetStateMerkleRoot(info.Height)
+	if err == scom.ErrNotFound {
+		if stateRoot != common.UINT256_EMPTY {
+			return fmt.Errorf("state merkle root of height %d not found in snapshot", info.Height)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("GetStateMerkleRoot height:%d error:%s", info.Height, err)
+	}
+	treeSize, hashes, err = stateStore.GetStateMerkleTree()
+	if err != nil {
+		return fmt.Errorf("GetStateMerkleTree error %s", err)
+	}
+	if root := merkle.NewTree(treeSize, hashes, nil).Root(); root != info.StateRoot {
+		return fmt.Errorf("state merkle root %s mismatches saved state merkle root %s",
+			root.ToHexString(), info.StateRoot.ToHexString())
+	}
+	if stateRoot != common.UINT256_EMPTY && stateRoot != info.StateRoot {
+		return fmt.Errorf("state merkle root %s mismatches state merkle root %s committed in header",
+			info.StateRoot.ToHexString(), stateRoot.ToHexString())
+	}
+	return nil
+}
+
+func importSnapshotEntries(r io.Reader, store scom.PersistStore, reject func(key []byte) bool) (uint64, error) {
+	count := uint64(0)
+	store.NewBatch()
+	for {
+		more, err := serialization.ReadBool(r)
+		if err != nil {
+			return count, err
+		}
+		if !more {
+			break
+		}
+		key, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return count, err
+		}
+		value, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return count, err
+		}
+		if reject != nil && reject(key) {
+			return count, fmt.Errorf("unexpected key %x", key)
+		}
+		store.BatchPut(key, value)
+		count++
+		if count%SNAPSHOT_BATCH_SIZE == 0 {
+			if err := store.BatchCommit(); err != nil {
+				return count, err
+			}
+			store.NewBatch()
+		}
+	}
+	return count, store.BatchCommit()
+}
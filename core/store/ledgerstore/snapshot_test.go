@@ -0,0 +1,317 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/signature"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var snapshotStorageKey = []byte{0x05, 1, 2, 3}
+
+// snapshotChain is the vbft chain of snapshot ledger, chain config is changed at the middle height
+type snapshotChain struct {
+	genesisHash common.Uint256
+	nextHeader  *types.Header
+	peers       []*account.Account // peers of the last chain config
+}
+
+func newSnapshotPeers(n int) ([]*account.Account, *vconfig.ChainConfig) {
+	accs := make([]*account.Account, 0, n)
+	cfg := &vconfig.ChainConfig{N: uint32(n)}
+	for i := 0; i < n; i++ {
+		acc := account.NewAccount("")
+		accs = append(accs, acc)
+		cfg.Peers = append(cfg.Peers, &vconfig.PeerConfig{Index: uint32(i + 1), ID: vconfig.PubkeyID(acc.PublicKey)})
+	}
+	return accs, cfg
+}
+
+func signSnapshotHeader(t *testing.T, header *types.Header, info *vconfig.VbftBlockInfo, signer *account.Account) {
+	payload, err := json.Marshal(info)
+	assert.Nil(t, err)
+	header.ConsensusPayload = payload
+	if signer == nil {
+		return
+	}
+	hash := header.Hash()
+	sig, err := signature.Sign(signer, hash[:])
+	assert.Nil(t, err)
+	header.Bookkeepers = append(header.Bookkeepers, signer.PublicKey)
+	header.SigData = append(header.SigData, sig)
+}
+
+func newSnapshotNextHeader(t *testing.T, prev *types.Header, info *vconfig.VbftBlockInfo, signer *account.Account) *types.Header {
+	header := &types.Header{
+		PrevBlockHash:    prev.Hash(),
+		TransactionsRoot: common.ComputeMerkleRoot(nil),
+		Timestamp:        prev.Timestamp + 1,
+		Height:           prev.Height + 1,
+	}
+	signSnapshotHeader(t, header, info, signer)
+	return header
+}
+
+func buildSnapshotLedger(t *testing.T, dataDir string, height uint32) *snapshotChain {
+	blockStore, err := NewBlockStore(path.Join(dataDir, DBDirBlock), false)
+	assert.Nil(t, err)
+	stateStore, err := NewStateStore(path.Join(dataDir, DBDirState), path.Join(dataDir, MerkleTreeStorePath), 0)
+	assert.Nil(t, err)
+	crossShardStore, err := NewCrossShardStore(dataDir)
+	assert.Nil(t, err)
+
+	chain := &snapshotChain{}
+	peers, cfg := newSnapshotPeers(4)
+	newPeers, newCfg := newSnapshotPeers(4)
+	configHeight := height / 2
+	lastConfigNum := uint32(0)
+	var prev *types.Header
+	for h := uint32(0); h <= height; h++ {
+		txRoot := common.Uint256(sha256.Sum256([]byte{byte(h)}))
+		block := &types.Block{
+			Header: &types.Header{
+				TransactionsRoot: txRoot,
+				BlockRoot:        stateStore.GetBlockRootWithNewTxRoots([]common.Uint256{txRoot}),
+				Timestamp:        h + 1,
+				Height:           h,
+			},
+			Transactions: []*types.Transaction{},
+		}
+		switch {
+		case h == 0:
+			signSnapshotHeader(t, block.Header, &vconfig.VbftBlockInfo{LastConfigBlockNum: math.MaxUint32, NewChainConfig: cfg}, nil)
+		case h == configHeight:
+			block.Header.PrevBlockHash = prev.Hash()
+			signSnapshotHeader(t, block.Header, &vconfig.VbftBlockInfo{LastConfigBlockNum: h, NewChainConfig: newCfg}, peers[0])
+			lastConfigNum = h
+			peers = newPeers
+		default:
+			block.Header.PrevBlockHash = prev.Hash()
+			signSnapshotHeader(t, block.Header, &vconfig.VbftBlockInfo{LastConfigBlockNum: lastConfigNum}, peers[h%4])
+		}
+		blockHash := block.Hash()
+		blockStore.NewBatch()
+		blockStore.SaveBlockHash(h, blockHash)
+		assert.Nil(t, blockStore.SaveBlock(block))
+		assert.Nil(t, blockStore.SaveCurrentBlock(h, blockHash))
+		assert.Nil(t, blockStore.CommitTo())
+
+		stateStore.NewBatch()
+		assert.Nil(t, stateStore.AddStateMerkleTreeRoot(h, common.Uint256(sha256.Sum256(txRoot[:]))))
+		assert.Nil(t, stateStore.AddBlockMerkleTreeRoot(txRoot))
+		assert.Nil(t, stateStore.SaveCurrentBlock(h, blockHash))
+		stateStore.BatchPutRawKeyVal(snapshotStorageKey, []byte{byte(h)})
+		assert.Nil(t, stateStore.CommitTo())
+		if h == 0 {
+			chain.genesisHash = blockHash
+		}
+		prev = block.Header
+	}
+	assert.Nil(t, blockStore.SaveVersion(SYSTEM_VERSION))
+	assert.Nil(t, crossShardStore.store.Put([]byte("crossshard"), []byte("msg")))
+
+	// header of next block commits the state of snapshot height
+	assert.Nil(t, stateStore.InitStorageTrie(height))
+	storageRoot, err := stateStore.GetStorageTrieRoot(height)
+	assert.Nil(t, err)
+	stateRoot, err := stateStore.GetStateMerkleRoot(height)
+	assert.Nil(t, err)
+	chain.nextHeader = newSnapshotNextHeader(t, prev, &vconfig.VbftBlockInfo{
+		LastConfigBlockNum: lastConfigNum,
+		PrevStateRoot:      stateRoot[:],
+		PrevStorageRoot:    storageRoot[:],
+	}, peers[0])
+	chain.peers = peers
+
+	blockStore.Close()
+	stateStore.Close()
+	crossShardStore.Close()
+	return chain
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	srcDir := path.Join(dir, "src")
+	height := uint32(10)
+	chain := buildSnapshotLedger(t, srcDir, height)
+
+	_, err = ExportSnapshot(srcDir, height-1, 0, new(bytes.Buffer))
+	assert.NotNil(t, err)
+	buf := new(bytes.Buffer)
+	info, err := ExportSnapshot(srcDir, height, 0, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, height, info.Height)
+	assert.NotEqual(t, common.UINT256_EMPTY, info.StateRoot)
+	assert.NotEqual(t, common.UINT256_EMPTY, info.StorageRoot)
+	data := buf.Bytes()
+
+	// corrupted snapshot and snapshot of other chain are rejected
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	_, err = ImportSnapshot(path.Join(dir, "corrupted"), bytes.NewReader(corrupted), chain.genesisHash, chain.nextHeader)
+	assert.NotNil(t, err)
+	_, err = ImportSnapshot(path.Join(dir, "genesis"), bytes.NewReader(data), common.Uint256{1}, chain.nextHeader)
+	assert.NotNil(t, err)
+	_, err = ImportSnapshot(path.Join(dir, "noheader"), bytes.NewReader(data), chain.genesisHash, nil)
+	assert.NotNil(t, err)
+
+	dstDir := path.Join(dir, "dst")
+	imported, err := ImportSnapshot(dstDir, bytes.NewReader(data), chain.genesisHash, chain.nextHeader)
+	assert.Nil(t, err)
+	assert.Equal(t, info, imported)
+	_, err = ImportSnapshot(dstDir, bytes.NewReader(data), chain.genesisHash, chain.nextHeader)
+	assert.NotNil(t, err)
+
+	blockStore, err := NewBlockStore(path.Join(dstDir, DBDirBlock), false)
+	assert.Nil(t, err)
+	defer blockStore.Close()
+	blockHash, blockHeight, err := blockStore.GetCurrentBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, height, blockHeight)
+	assert.Equal(t, info.BlockHash, blockHash)
+	for h := uint32(0); h <= height; h++ {
+		hash, err := blockStore.GetBlockHash(h)
+		assert.Nil(t, err)
+		assert.NotEqual(t, common.UINT256_EMPTY, hash)
+	}
+
+	stateStore, err := NewStateStore(path.Join(dstDir, DBDirState), path.Join(dstDir, MerkleTreeStorePath), 0)
+	assert.Nil(t, err)
+	defer stateStore.Close()
+	value, err := stateStore.store.Get(snapshotStorageKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{byte(height)}, value)
+	proof, err := stateStore.GetMerkleProof(1, height)
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, len(proof))
+	storageRoot, err := stateStore.GetStorageTrieRoot(height)
+	assert.Nil(t, err)
+	assert.Equal(t, info.StorageRoot, storageRoot)
+}
+
+func TestSnapshotNextHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	srcDir := path.Join(dir, "src")
+	height := uint32(10)
+	chain := buildSnapshotLedger(t, srcDir, height)
+	buf := new(bytes.Buffer)
+	info, err := ExportSnapshot(srcDir, height, 0, buf)
+	assert.Nil(t, err)
+	data := buf.Bytes()
+
+	blockStore, err := NewBlockStore(path.Join(srcDir, DBDirBlock), false)
+	assert.Nil(t, err)
+	prev, err := blockStore.GetHeader(info.BlockHash)
+	assert.Nil(t, err)
+	blockStore.Close()
+	nextInfo, err := vconfig.VbftBlock(chain.nextHeader)
+	assert.Nil(t, err)
+
+	// signed by peers out of chain config
+	others, _ := newSnapshotPeers(4)
+	header := newSnapshotNextHeader(t, prev, nextInfo, others[0])
+	_, err = ImportSnapshot(path.Join(dir, "unknown"), bytes.NewReader(data), chain.genesisHash, header)
+	assert.NotNil(t, err)
+
+	// storage trie root is not committed
+	header = newSnapshotNextHeader(t, prev, &vconfig.VbftBlockInfo{
+		LastConfigBlockNum: nextInfo.LastConfigBlockNum,
+		PrevStateRoot:      nextInfo.PrevStateRoot,
+	}, chain.peers[0])
+	_, err = ImportSnapshot(path.Join(dir, "nostorage"), bytes.NewReader(data), chain.genesisHash, header)
+	assert.NotNil(t, err)
+
+	// not following the snapshot height
+	header = newSnapshotNextHeader(t, chain.nextHeader, nextInfo, chain.peers[0])
+	_, err = ImportSnapshot(path.Join(dir, "height"), bytes.NewReader(data), chain.genesisHash, header)
+	assert.NotNil(t, err)
+}
+
+func TestSnapshotTamperedStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	srcDir := path.Join(dir, "src")
+	height := uint32(10)
+	chain := buildSnapshotLedger(t, srcDir, height)
+	buf := new(bytes.Buffer)
+	_, err = ExportSnapshot(srcDir, height, 0, buf)
+	assert.Nil(t, err)
+	data := buf.Bytes()
+
+	// change the storage value and fix the checksum
+	entry := append([]byte{1, byte(len(snapshotStorageKey))}, snapshotStorageKey...)
+	entry = append(entry, 1, byte(height))
+	pos := bytes.Index(data, entry)
+	assert.True(t, pos > 0)
+	tampered := append([]byte{}, data...)
+	tampered[pos+len(entry)-1] ^= 0xff
+	checksum := sha256.Sum256(tampered[:len(tampered)-sha256.Size])
+	copy(tampered[len(tampered)-sha256.Size:], checksum[:])
+
+	tamperedDir := path.Join(dir, "tampered")
+	_, err = ImportSnapshot(tamperedDir, bytes.NewReader(tampered), chain.genesisHash, chain.nextHeader)
+	assert.NotNil(t, err)
+	_, err = os.Stat(path.Join(tamperedDir, DBDirState))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSnapshotUnknownState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	srcDir := path.Join(dir, "src")
+	height := uint32(10)
+	chain := buildSnapshotLedger(t, srcDir, height)
+	buf := new(bytes.Buffer)
+	_, err = ExportSnapshot(srcDir, height, 0, buf)
+	assert.Nil(t, err)
+	data := buf.Bytes()
+
+	// states of unknown prefix are neither verified nor trusted
+	entry := append([]byte{1, byte(len(snapshotStorageKey))}, snapshotStorageKey...)
+	pos := bytes.Index(data, entry)
+	assert.True(t, pos > 0)
+	tampered := append([]byte{}, data...)
+	tampered[pos+2] = byte(scom.ST_VALIDATOR)
+	checksum := sha256.Sum256(tampered[:len(tampered)-sha256.Size])
+	copy(tampered[len(tampered)-sha256.Size:], checksum[:])
+
+	_, err = ImportSnapshot(path.Join(dir, "unknown"), bytes.NewReader(tampered), chain.genesisHash, chain.nextHeader)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unexpected key")
+}
@@ -59,7 +59,7 @@ type StateStore struct {
 //NewStateStore return state store instance
 func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*StateStore, error) {
 	var err error
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := newPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -331,6 +331,143 @@ func (self *StateStore) GetRelatedShardIDsInBlock(blockHeight uint32) ([]common.
 	return shards, nil
 }
 
+//PruneShardMsgsInBlock delete the shard msgs sent in block and the related shard list of block
+func (self *StateStore) PruneShardMsgsInBlock(blockHeight uint32) error {
+	shardIDs, err := self.GetRelatedShardIDsInBlock(blockHeight)
+	if err == scom.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("GetRelatedShardIDsInBlock error %s", err)
+	}
+	for _, shardID := range shardIDs {
+		key := common.NewZeroCopySink(16)
+		key.WriteByte(byte(scom.XSHARD_KEY_REQS_IN_BLOCK))
+		key.WriteUint32(blockHeight)
+		key.WriteShardID(shardID)
+		self.store.BatchDelete(key.Bytes())
+	}
+	key := common.NewZeroCopySink(8)
+	key.WriteByte(byte(scom.XSHARD_KEY_SHARDS_IN_BLOCK))
+	key.WriteUint32(blockHeight)
+	self.store.BatchDelete(key.Bytes())
+	return nil
+}
+
+//ArchiveWriteSet save the state values before they are overwritten by the write set of block,
+//so states at history block height can be queried
+func (self *StateStore) ArchiveWriteSet(blockHeight uint32, writeSet *overlaydb.MemDB) error {
+	_, err := self.GetArchiveHeight()
+	if err == scom.ErrNotFound {
+		sink := common.NewZeroCopySink(4)
+		sink.WriteUint32(blockHeight)
+		self.store.BatchPut(genArchiveHeightKey(), sink.Bytes())
+	} else if err != nil {
+		return fmt.Errorf("GetArchiveHeight error %s", err)
+	}
+	err = nil
+	writeSet.ForEach(func(key, val []byte) {
+		if err != nil {
+			return
+		}
+		prev, e := self.store.Get(key)
+		if e != nil && e != scom.ErrNotFound {
+			err = e
+			return
+		}
+		self.store.BatchPut(genArchivedStateKey(key, blockHeight), prev)
+	})
+	return err
+}
+
+//GetArchiveHeight return the first block height whose state changes are archived
+func (self *StateStore) GetArchiveHeight() (uint32, error) {
+	value, err := self.store.Get(genArchiveHeightKey())
+	if err != nil {
+		return 0, err
+	}
+	height, eof := common.NewZeroCopySource(value).NextUint32()
+	if eof {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return height, nil
+}
+
+//DeleteArchiveHeight invalidate the archived states, it should be called when archive is disabled,
+//since the state changes after that will not be archived
+func (self *StateStore) DeleteArchiveHeight() error {
+	return self.store.Delete(genArchiveHeightKey())
+}
+
+//CheckArchivedHeight return error if states at height are not archived
+func (self *StateStore) CheckArchivedHeight(height uint32) error {
+	start, err := self.GetArchiveHeight()
+	if err == scom.ErrNotFound {
+		return fmt.Errorf("state archive is not enabled")
+	} else if err != nil {
+		return fmt.Errorf("GetArchiveHeight error %s", err)
+	}
+	if height+1 < start {
+		return fmt.Errorf("state at height %d is not archived, archive starts from height %d", height, start)
+	}
+	return nil
+}
+
+//GetStateAtHeight return the raw state value of key after block at height is committed
+func (self *StateStore) GetStateAtHeight(key []byte, height uint32) ([]byte, error) {
+	if err := self.CheckArchivedHeight(height); err != nil {
+		return nil, err
+	}
+	// current value should be read before archived values, since block committed during the query
+	// archives the overwritten value
+	value, err := self.store.Get(key)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, err
+	}
+	prefix := genArchivedStatePrefix(key)
+	iter := self.store.NewIterator(prefix)
+	// archived keys are sorted by height, skip the values overwritten before height
+	for has := iter.Seek(genArchivedStateKey(key, height)); has; has = iter.Next() {
+		archivedKey := iter.Key()
+		// skip the archived keys which have the key as prefix
+		if len(archivedKey) != len(prefix)+4 {
+			continue
+		}
+		// the value before first block overwrote the key after height is the value at height
+		if binary.BigEndian.Uint32(archivedKey[len(prefix):]) > height {
+			value = append([]byte{}, iter.Value()...)
+			break
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return nil, scom.ErrNotFound
+	}
+	return value, nil
+}
+
+func genArchiveHeightKey() []byte {
+	return []byte{byte(scom.SYS_ARCHIVE_HEIGHT)}
+}
+
+func genArchivedStatePrefix(key []byte) []byte {
+	prefix := make([]byte, 1+len(key))
+	prefix[0] = byte(scom.ST_ARCHIVE)
+	copy(prefix[1:], key)
+	return prefix
+}
+
+func genArchivedStateKey(key []byte, height uint32) []byte {
+	archivedKey := make([]byte, 1+len(key)+4)
+	archivedKey[0] = byte(scom.ST_ARCHIVE)
+	copy(archivedKey[1:], key)
+	binary.BigEndian.PutUint32(archivedKey[1+len(key):], height)
+	return archivedKey
+}
+
 //GetMerkleProof return merkle proof of block
 func (self *StateStore) GetMerkleProof(proofHeight, rootHeight uint32) ([]common.Uint256, error) {
 	return self.merkleTree.InclusionProof(proofHeight, rootHeight+1)
@@ -440,6 +577,26 @@ func (self *StateStore) GetStorageState(key *states.StorageKey) (*states.Storage
 	return storageState, nil
 }
 
+//GetStorageStateAtHeight return the storage item of smart contract after block at height is committed
+func (self *StateStore) GetStorageStateAtHeight(key *states.StorageKey, height uint32) (*states.StorageItem, error) {
+	storeKey, err := self.getStorageKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := self.GetStateAtHeight(storeKey, height)
+	if err != nil {
+		return nil, err
+	}
+	reader := bytes.NewReader(data)
+	storageState := new(states.StorageItem)
+	err = storageState.Deserialize(reader)
+	if err != nil {
+		return nil, err
+	}
+	return storageState, nil
+}
+
 //GetCurrentBlock return current block height and current hash in state store
 func (self *StateStore) GetCurrentBlock() (common.Uint256, uint32, error) {
 	key := self.getCurrentBlockKey()
@@ -23,6 +23,8 @@ import (
 	"testing"
 
 	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
 	"github.com/ontio/ontology/merkle"
 	"github.com/stretchr/testify/assert"
 )
@@ -71,3 +73,62 @@ func TestStateMerkleRoot(t *testing.T) {
 	}
 
 }
+
+func TestGetStateAtHeight(t *testing.T) {
+	db := NewMemStateStore(0)
+	key := []byte("key")
+	commit := func(height uint32, val []byte) {
+		writeSet := overlaydb.NewMemDB(0, 0)
+		if val == nil {
+			writeSet.Delete(key)
+		} else {
+			writeSet.Put(key, val)
+		}
+		db.NewBatch()
+		err := db.ArchiveWriteSet(height, writeSet)
+		assert.Nil(t, err)
+		writeSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		err = db.CommitTo()
+		assert.Nil(t, err)
+	}
+
+	_, err := db.GetStateAtHeight(key, 10)
+	assert.NotNil(t, err)
+
+	commit(10, []byte("v10"))
+	commit(12, []byte("v12"))
+	commit(13, nil)
+	commit(15, []byte("v15"))
+
+	_, err = db.GetStateAtHeight(key, 8)
+	assert.NotNil(t, err)
+	expects := map[uint32][]byte{9: nil, 10: []byte("v10"), 11: []byte("v10"), 12: []byte("v12"),
+		13: nil, 14: nil, 15: []byte("v15"), 16: []byte("v15")}
+	for height, expect := range expects {
+		value, err := db.GetStateAtHeight(key, height)
+		if expect == nil {
+			assert.Equal(t, scom.ErrNotFound, err)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, expect, value)
+		}
+	}
+
+	// keys which have the key as prefix should not affect the archived states of key
+	longKey := append([]byte("key"), 0, 0, 0, 0)
+	writeSet := overlaydb.NewMemDB(0, 0)
+	writeSet.Put(longKey, []byte("long"))
+	db.NewBatch()
+	assert.Nil(t, db.ArchiveWriteSet(16, writeSet))
+	db.BatchPutRawKeyVal(longKey, []byte("long"))
+	assert.Nil(t, db.CommitTo())
+	value, err := db.GetStateAtHeight(key, 11)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v10"), value)
+}
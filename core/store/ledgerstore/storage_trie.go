@@ -0,0 +1,280 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/merkle"
+)
+
+// Count of storage items put to storage trie in one batch when building storage trie
+const STORAGE_TRIE_BUILD_BATCH_SIZE = 10000
+
+// storageTriePrefixes are the prefixes of states committed in storage trie, which are all the states changed by
+// block execution except shard msgs of blocks, since the latter are pruned
+var storageTriePrefixes = []scom.DataEntryPrefix{
+	scom.ST_CONTRACT,
+	scom.ST_STORAGE,
+	scom.ST_CONTRACT_META_DATA,
+	scom.XSHARD_STATE,
+	scom.XSHARD_KEY_LOCKED_ADDRESS,
+	scom.XSHARD_KEY_LOCKED_KEY,
+	scom.XSHARD_KEY_TX_DEADLINE,
+}
+
+// isStorageTrieState checks if the state of key is committed in storage trie, the whole key is used as trie key
+func isStorageTrieState(key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for _, prefix := range storageTriePrefixes {
+		if key[0] == byte(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// storageTrieNodes is the node store of storage trie, new nodes are cached until they are committed
+type storageTrieNodes struct {
+	store scom.PersistStore
+	nodes map[common.Uint256][]byte
+}
+
+func newStorageTrieNodes(store scom.PersistStore) *storageTrieNodes {
+	return &storageTrieNodes{
+		store: store,
+		nodes: make(map[common.Uint256][]byte),
+	}
+}
+
+func (self *storageTrieNodes) GetNode(hash common.Uint256) ([]byte, error) {
+	if node, ok := self.nodes[hash]; ok {
+		return node, nil
+	}
+	return self.store.Get(genStorageTrieNodeKey(hash))
+}
+
+func (self *storageTrieNodes) PutNode(hash common.Uint256, node []byte) {
+	self.nodes[hash] = node
+}
+
+// batchCommit put cached nodes to the batch of store
+func (self *storageTrieNodes) batchCommit() {
+	for hash, node := range self.nodes {
+		self.store.BatchPut(genStorageTrieNodeKey(hash), node)
+	}
+	self.nodes = make(map[common.Uint256][]byte)
+}
+
+// GetStorageTrieStart return the first block height whose storage trie root is saved
+func (self *StateStore) GetStorageTrieStart() (uint32, error) {
+	value, err := self.store.Get(genStorageTrieStartKey())
+	if err != nil {
+		return 0, err
+	}
+	height, eof := common.NewZeroCopySource(value).NextUint32()
+	if eof {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return height, nil
+}
+
+// DeleteStorageTrieStart invalidate the storage trie, it should be called when storage trie is disabled,
+// since the storage trie will not be updated after that
+func (self *StateStore) DeleteStorageTrieStart() error {
+	return self.store.Delete(genStorageTrieStartKey())
+}
+
+// GetStorageTrieRoot return the storage trie root after block at height is committed
+func (self *StateStore) GetStorageTrieRoot(height uint32) (common.Uint256, error) {
+	start, err := self.GetStorageTrieStart()
+	if err == scom.ErrNotFound {
+		return common.UINT256_EMPTY, fmt.Errorf("storage trie is not enabled")
+	} else if err != nil {
+		return common.UINT256_EMPTY, fmt.Errorf("GetStorageTrieStart error %s", err)
+	}
+	if height < start {
+		return common.UINT256_EMPTY, fmt.Errorf("storage trie root at height %d is not saved, storage trie starts from height %d",
+			height, start)
+	}
+	value, err := self.store.Get(genStorageTrieRootKey(height))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return common.Uint256ParseFromBytes(value)
+}
+
+// InitStorageTrie build storage trie from all the states at current block height, if the storage trie
+// is not built yet. Storage trie root is only determined by the states, no matter how the trie is built
+func (self *StateStore) InitStorageTrie(currHeight uint32) error {
+	if _, err := self.GetStorageTrieStart(); err == nil {
+		return nil
+	} else if err != scom.ErrNotFound {
+		return fmt.Errorf("GetStorageTrieStart error %s", err)
+	}
+	log.Infof("building storage trie at height %d", currHeight)
+	nodes := newStorageTrieNodes(self.store)
+	trie := merkle.NewSparseMerkleTree(common.UINT256_EMPTY, nodes)
+	keys := make([][]byte, 0, STORAGE_TRIE_BUILD_BATCH_SIZE)
+	values := make([][]byte, 0, STORAGE_TRIE_BUILD_BATCH_SIZE)
+	flush := func() error {
+		err := trie.Update(keys, values)
+		if err != nil {
+			return err
+		}
+		self.store.NewBatch()
+		nodes.batchCommit()
+		keys, values = keys[:0], values[:0]
+		return self.store.BatchCommit()
+	}
+	var err error
+	for _, prefix := range storageTriePrefixes {
+		iter := self.store.NewIterator([]byte{byte(prefix)})
+		for has := iter.First(); has; has = iter.Next() {
+			keys = append(keys, append([]byte{}, iter.Key()...))
+			values = append(values, append([]byte{}, iter.Value()...))
+			if len(keys) == STORAGE_TRIE_BUILD_BATCH_SIZE {
+				if err = flush(); err != nil {
+					break
+				}
+			}
+		}
+		iter.Release()
+		if err == nil {
+			err = iter.Error()
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return fmt.Errorf("build storage trie error %s", err)
+	}
+
+	self.store.NewBatch()
+	root := trie.Root()
+	self.store.BatchPut(genStorageTrieRootKey(currHeight), root[:])
+	self.store.BatchPut(genStorageTrieStartKey(), genHeightBytes(currHeight))
+	err = self.store.BatchCommit()
+	if err != nil {
+		return err
+	}
+	log.Infof("storage trie at height %d is built, root %s", currHeight, root.ToHexString())
+	return nil
+}
+
+// GetStorageTrieRootWithWriteSet return the storage trie root after the write set of block is committed
+func (self *StateStore) GetStorageTrieRootWithWriteSet(blockHeight uint32, writeSet *overlaydb.MemDB) (common.Uint256, error) {
+	trie, err := self.updateStorageTrie(blockHeight, writeSet)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return trie.Root(), nil
+}
+
+// UpdateStorageTrie update storage trie with the write set of block, and save the new root to batch
+func (self *StateStore) UpdateStorageTrie(blockHeight uint32, writeSet *overlaydb.MemDB) (common.Uint256, error) {
+	trie, err := self.updateStorageTrie(blockHeight, writeSet)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if blockHeight == 0 {
+		self.store.BatchPut(genStorageTrieStartKey(), genHeightBytes(blockHeight))
+	}
+	trie.nodes.batchCommit()
+	root := trie.Root()
+	self.store.BatchPut(genStorageTrieRootKey(blockHeight), root[:])
+	return root, nil
+}
+
+type storageTrie struct {
+	*merkle.SparseMerkleTree
+	nodes *storageTrieNodes
+}
+
+func (self *StateStore) updateStorageTrie(blockHeight uint32, writeSet *overlaydb.MemDB) (*storageTrie, error) {
+	root := common.UINT256_EMPTY
+	if blockHeight != 0 {
+		var err error
+		root, err = self.GetStorageTrieRoot(blockHeight - 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	nodes := newStorageTrieNodes(self.store)
+	trie := merkle.NewSparseMerkleTree(root, nodes)
+	var keys, values [][]byte
+	writeSet.ForEach(func(key, val []byte) {
+		if isStorageTrieState(key) {
+			keys = append(keys, key)
+			values = append(values, val)
+		}
+	})
+	err := trie.Update(keys, values)
+	if err != nil {
+		return nil, fmt.Errorf("update storage trie error %s", err)
+	}
+	return &storageTrie{SparseMerkleTree: trie, nodes: nodes}, nil
+}
+
+// GetStorageProof return the raw storage item of key and its proof against the storage trie root at height
+func (self *StateStore) GetStorageProof(key *states.StorageKey, height uint32) ([]byte, *merkle.SparseMerkleProof, error) {
+	root, err := self.GetStorageTrieRoot(height)
+	if err != nil {
+		return nil, nil, err
+	}
+	storeKey, err := self.getStorageKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	trie := merkle.NewSparseMerkleTree(root, newStorageTrieNodes(self.store))
+	return trie.Prove(storeKey)
+}
+
+func genStorageTrieNodeKey(hash common.Uint256) []byte {
+	return append([]byte{byte(scom.ST_STORAGE_TRIE_NODE)}, hash[:]...)
+}
+
+func genStorageTrieRootKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_STORAGE_TRIE_ROOT)
+	binary.BigEndian.PutUint32(key[1:], height)
+	return key
+}
+
+func genStorageTrieStartKey() []byte {
+	return []byte{byte(scom.SYS_STORAGE_TRIE_START)}
+}
+
+func genHeightBytes(height uint32) []byte {
+	sink := common.NewZeroCopySink(4)
+	sink.WriteUint32(height)
+	return sink.Bytes()
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageTrie(t *testing.T) {
+	address := common.Address{1}
+	storageKey := func(key byte) *states.StorageKey {
+		return &states.StorageKey{ContractAddress: address, Key: []byte{key}}
+	}
+	contractKey := append([]byte{byte(scom.ST_CONTRACT)}, address[:]...)
+	shardMsgsKey := []byte{byte(scom.XSHARD_KEY_SHARDS_IN_BLOCK), 0, 0, 0, 1}
+	commit := func(db *StateStore, height uint32, kvs map[byte][]byte) common.Uint256 {
+		writeSet := overlaydb.NewMemDB(0, 0)
+		if height == 1 {
+			writeSet.Put(contractKey, []byte("contract"))
+			writeSet.Put(shardMsgsKey, []byte("shards"))
+		}
+		for key, value := range kvs {
+			storeKey, _ := db.getStorageKey(storageKey(key))
+			if value == nil {
+				writeSet.Delete(storeKey)
+			} else {
+				writeSet.Put(storeKey, states.GenRawStorageItem(value))
+			}
+		}
+		expect, err := db.GetStorageTrieRootWithWriteSet(height, writeSet)
+		assert.Nil(t, err)
+		db.NewBatch()
+		root, err := db.UpdateStorageTrie(height, writeSet)
+		assert.Nil(t, err)
+		assert.Equal(t, expect, root)
+		writeSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.CommitTo())
+		return root
+	}
+	checkProof := func(db *StateStore, height uint32, key byte, expect []byte) {
+		root, err := db.GetStorageTrieRoot(height)
+		assert.Nil(t, err)
+		raw, proof, err := db.GetStorageProof(storageKey(key), height)
+		assert.Nil(t, err)
+		trieKey := append([]byte{byte(scom.ST_STORAGE)}, append(address[:], key)...)
+		assert.Nil(t, merkle.VerifySparseMerkleProof(root, trieKey, raw, proof))
+		if expect == nil {
+			assert.Nil(t, raw)
+			return
+		}
+		value, err := states.GetValueFromRawStorageItem(raw)
+		assert.Nil(t, err)
+		assert.Equal(t, expect, value)
+	}
+
+	db := NewMemStateStore(0)
+	commit(db, 0, map[byte][]byte{1: {1}, 2: {2}, 3: {3}})
+	root1 := commit(db, 1, map[byte][]byte{1: {11}, 2: nil, 4: {4}})
+	checkProof(db, 0, 1, []byte{1})
+	checkProof(db, 0, 2, []byte{2})
+	checkProof(db, 0, 4, nil)
+	checkProof(db, 1, 1, []byte{11})
+	checkProof(db, 1, 2, nil)
+	checkProof(db, 1, 4, []byte{4})
+	_, err := db.GetStorageTrieRoot(2)
+	assert.NotNil(t, err)
+
+	// contract states are committed in storage trie, shard msgs of blocks are not
+	trie := merkle.NewSparseMerkleTree(root1, newStorageTrieNodes(db.store))
+	value, _, err := trie.Prove(contractKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("contract"), value)
+	value, _, err = trie.Prove(shardMsgsKey)
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+
+	// storage trie built from storage items has the same root
+	assert.Nil(t, db.DeleteStorageTrieStart())
+	_, err = db.GetStorageTrieRoot(1)
+	assert.NotNil(t, err)
+	assert.Nil(t, db.InitStorageTrie(1))
+	root, err := db.GetStorageTrieRoot(1)
+	assert.Nil(t, err)
+	assert.Equal(t, root1, root)
+	_, err = db.GetStorageTrieRoot(0)
+	assert.NotNil(t, err)
+
+	// storage trie can not be updated before it is built
+	db = NewMemStateStore(0)
+	db.NewBatch()
+	_, err = db.UpdateStorageTrie(1, overlaydb.NewMemDB(0, 0))
+	assert.NotNil(t, err)
+	_, err = db.GetStorageTrieStart()
+	assert.Equal(t, scom.ErrNotFound, err)
+}
+
+func TestStorageTrieRequired(t *testing.T) {
+	genesis := config.DefConfig.Genesis
+	config.DefConfig.Genesis = &config.GenesisConfig{VBFT: &config.VBFTConfig{}}
+	defer func() { config.DefConfig.Genesis = genesis }()
+
+	// storage trie is only maintained if it is enabled, or required by vbft genesis config
+	ledger := &LedgerStoreImp{}
+	assert.False(t, ledger.isStorageTrieEnabled())
+	config.DefConfig.Genesis.VBFT.StorageRootHeight = 100
+	assert.True(t, ledger.isStorageTrieEnabled())
+	config.DefConfig.Genesis.VBFT.StorageRootHeight = 0
+	ledger.enableStorageTrie = true
+	assert.True(t, ledger.isStorageTrieEnabled())
+}
@@ -39,14 +39,19 @@ import (
 	"github.com/ontio/ontology/core/xshard_types"
 	"github.com/ontio/ontology/errors"
 	"github.com/ontio/ontology/smartcontract"
+	"github.com/ontio/ontology/smartcontract/context"
 	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/service/native/global_params"
 	ninit "github.com/ontio/ontology/smartcontract/service/native/init"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_sysmsg"
+	"github.com/ontio/ontology/smartcontract/service/native/shardccmc"
 	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 	"github.com/ontio/ontology/smartcontract/service/neovm"
 	"github.com/ontio/ontology/smartcontract/storage"
 	ntypes "github.com/ontio/ontology/vm/neovm/types"
+	"github.com/ontio/ontology/vm/wasmvm/exec"
+	"github.com/ontio/ontology/vm/wasmvm/wasm"
 )
 
 //HandleDeployTransaction deal with smart contract deploy transaction
@@ -107,6 +112,15 @@ func HandleDeployTransaction(store store.LedgerStore, overlay *overlaydb.Overlay
 		}
 	}
 
+	if wasm.IsWasmCode(deploy.Code) {
+		if err := exec.VerifyCode(deploy.Code); err != nil {
+			notify.Notify = append(notify.Notify, notifies...)
+			notify.GasConsumed = gasConsumed
+			notify.State = event.CONTRACT_STATE_FAIL
+			return fmt.Errorf("[HandleDeployTransaction] invalid wasm code: %s", err)
+		}
+	}
+
 	log.Infof("deploy contract address:%s", address.ToHexString())
 	// store contract message
 	dep, err := cache.GetContract(address)
@@ -180,6 +194,15 @@ func HandleChangeMetadataTransaction(store store.LedgerStore, overlay *overlaydb
 	if !checkWitness {
 		return fmt.Errorf("tx cannot have owner signature")
 	}
+	if meta.IsFrozen && !newMeta.IsFrozen {
+		var frozen bool
+		if frozen, err = shardccmc.IsFrozenByCCMC(cache, meta.Contract); err != nil {
+			return err
+		}
+		if frozen {
+			return fmt.Errorf("contract frozen by ccmc cannot be unfrozen")
+		}
+	}
 	// can only change the owner and active or freeze contract, and invoked contract
 	// cannot change shard info
 	meta.Owner = newMeta.Owner
@@ -238,16 +261,48 @@ func resumeTxState(txState *xshard_state.TxState, rspMsg *xshard_types.XShardTxR
 	return tx, nil
 }
 
-func handleShardAbortMsg(msg *xshard_types.XShardAbortMsg, lockedAddress map[common.Address]struct{},
-	lockedKeys map[string]struct{}, xshardDB *storage.XShardDB) {
+func handleShardAbortMsg(msg *xshard_types.XShardAbortMsg, store store.LedgerStore, lockedAddress map[common.Address]struct{},
+	lockedKeys map[string]struct{}, cache *storage.CacheDB, xshardDB *storage.XShardDB, header *types.Header,
+	notify *event.TransactionNotify) {
 	shardTxID := msg.ShardTxID
 
 	txState, err := xshardDB.GetXShardState(shardTxID)
 	if err != nil {
 		return
 	}
+	if txState.ExecState == xshard_state.ExecAborted {
+		return
+	}
+	abortShardTx(txState, msg.SourceShardID, msg.SourceTxHash, store, lockedAddress, lockedKeys, cache, xshardDB, header, notify)
+	// keep the fee refund from being dropped by the following msgs of the shard call
+	cache.Commit()
+}
+
+// abortShardTx aborts the shard tx and releases its locked contracts and keys,
+// the abort msg is propagated to all participant shards except the shard it comes from
+func abortShardTx(txState *xshard_state.TxState, fromShard common.ShardID, sourceTxHash common.Uint256,
+	store store.LedgerStore, lockedAddress map[common.Address]struct{}, lockedKeys map[string]struct{},
+	cache *storage.CacheDB, xshardDB *storage.XShardDB, header *types.Header, notify *event.TransactionNotify) {
+	for _, shard := range txState.GetTxShards() {
+		if shard == fromShard {
+			continue
+		}
+		abort := &xshard_types.XShardAbortMsg{
+			ShardMsgHeader: xshard_types.ShardMsgHeader{
+				SourceShardID: header.ShardID,
+				TargetShardID: shard,
+				SourceTxHash:  sourceTxHash,
+				ShardTxID:     txState.TxID,
+			},
+		}
+		notify.ShardMsg = append(notify.ShardMsg, abort)
+	}
+	if txState.ExecState == xshard_state.ExecPrepared && txState.PreparedFee > 0 {
+		refundPreparedFee(txState, store, cache, header, notify)
+	}
 	// update tx state
 	txState.ExecState = xshard_state.ExecAborted
+	txState.PreparedFee = 0
 	// unlock contract
 	for _, addr := range txState.LockedAddress {
 		delete(lockedAddress, addr)
@@ -260,6 +315,95 @@ func handleShardAbortMsg(msg *xshard_types.XShardAbortMsg, lockedAddress map[com
 	xshardDB.SetXShardState(txState)
 }
 
+// refundPreparedFee returns the fee charged when the tx was prepared, the write set of the tx is discarded
+// on abort, so the payer should not pay for it
+func refundPreparedFee(txState *xshard_state.TxState, store store.LedgerStore, cache *storage.CacheDB,
+	header *types.Header, notify *event.TransactionNotify) {
+	tx, err := types.TransactionFromRawBytes(txState.TxPayload)
+	if err != nil {
+		log.Errorf("refundPreparedFee: failed to re-init original tx %x: %s", []byte(txState.TxID), err)
+		return
+	}
+	config := &smartcontract.Config{
+		ShardID:      header.ShardID,
+		Time:         header.Timestamp,
+		Height:       header.Height,
+		ParentHeight: header.ParentHeight,
+		Tx:           tx,
+		BlockHash:    header.Hash(),
+	}
+	notifies, err := refundCostGas(tx.Payer, txState.PreparedFee, config, cache, store, header.ShardID)
+	if err != nil {
+		log.Errorf("refundPreparedFee: refund xshard tx %x fee failed: %s", []byte(txState.TxID), err)
+		return
+	}
+	notify.ContractEvent.Notify = append(notify.ContractEvent.Notify, notifies...)
+}
+
+// handleXShardTxTimeout aborts the shard txs coordinated by current shard which are still pending at the deadline.
+// The payer only pays the fee of remote requests which have been responded,
+// the fee carried by the unanswered request is not charged.
+func handleXShardTxTimeout(store store.LedgerStore, lockedAddress map[common.Address]struct{},
+	lockedKeys map[string]struct{}, cache *storage.CacheDB, xshardDB *storage.XShardDB, header *types.Header,
+	notify *event.TransactionNotify) error {
+	shardTxIDs, err := xshardDB.GetXShardTxsByDeadline(header.Height)
+	if err != nil {
+		return fmt.Errorf("handleXShardTxTimeout: failed, err: %s", err)
+	}
+	for _, shardTxID := range shardTxIDs {
+		txState, err := xshardDB.GetXShardState(shardTxID)
+		if err != nil {
+			return fmt.Errorf("handleXShardTxTimeout: failed, err: %s", err)
+		}
+		if txState.ExecState != xshard_state.ExecYielded && txState.ExecState != xshard_state.ExecPrepared {
+			continue
+		}
+		tx, err := types.TransactionFromRawBytes(txState.TxPayload)
+		if err != nil {
+			return fmt.Errorf("handleXShardTxTimeout: failed to re-init original tx: %s", err)
+		}
+		log.Infof("xshard tx %x timeout at height %d, state %d", []byte(shardTxID), header.Height, txState.ExecState)
+		cache.Reset()
+		if txState.ExecState == xshard_state.ExecYielded && tx.GasPrice > 0 && len(txState.OutReqResp) > 0 {
+			// tx fee is charged at prepare, so only yielded tx need to charge the responded remote requests
+			if !chargeWholeRespFee(txState, tx, header, cache, store, header.ShardID, notify) {
+				log.Debugf("handleXShardTxTimeout: charge xshard tx %x fee failed", []byte(shardTxID))
+			}
+		}
+		// the fee charged at prepare is refunded by abortShardTx
+		abortShardTx(txState, header.ShardID, tx.Hash(), store, lockedAddress, lockedKeys, cache, xshardDB, header, notify)
+		cache.Commit()
+	}
+	xshardDB.DeleteXShardTxsByDeadline(header.Height)
+	return nil
+}
+
+// handleShardSysMsgDelivery acks and dequeues shard system message queues at the end of block
+func handleShardSysMsgDelivery(store store.LedgerStore, cache *storage.CacheDB, header *types.Header) error {
+	config := &smartcontract.Config{
+		ShardID:      header.ShardID,
+		Time:         header.Timestamp,
+		Height:       header.Height,
+		ParentHeight: header.ParentHeight,
+		Tx:           &types.Transaction{},
+	}
+	sc := smartcontract.SmartContract{
+		Config:  config,
+		CacheDB: cache,
+		Store:   store,
+		Gas:     math.MaxUint64,
+	}
+	service, err := sc.NewNativeService()
+	if err != nil {
+		return fmt.Errorf("handleShardSysMsgDelivery: failed, err: %s", err)
+	}
+	if err := shardsysmsg.DeliverSysMsgs(service); err != nil {
+		return fmt.Errorf("handleShardSysMsgDelivery: failed, err: %s", err)
+	}
+	cache.Commit()
+	return nil
+}
+
 func handleShardCommitMsg(msg *xshard_types.XShardCommitMsg, lockedAddress map[common.Address]struct{},
 	lockedKeys map[string]struct{}, cache *storage.CacheDB, xshardDB *storage.XShardDB, header *types.Header,
 	notify *event.TransactionNotify) {
@@ -316,6 +460,10 @@ func handleShardPreparedMsg(msg *xshard_types.XShardPreparedMsg, lockedAddress m
 	if err != nil {
 		return
 	}
+	if txState.ExecState == xshard_state.ExecAborted {
+		// tx has been aborted, maybe timeout
+		return
+	}
 
 	if _, present := txState.Shards[msg.SourceShardID]; !present {
 		log.Infof("invalid shard ID %d, in tx commit", msg.SourceShardID)
@@ -386,6 +534,10 @@ func handleShardPrepareMsg(prepMsg *xshard_types.XShardPrepareMsg, store store.L
 	if err != nil {
 		return
 	}
+	if txState.ExecState == xshard_state.ExecAborted {
+		// tx has been aborted, maybe timeout
+		return
+	}
 	if txState.ExecState == xshard_state.ExecPrepared {
 		// this case will happen when the transaction flow is as follows:
 		//        / -> req shard2 \
@@ -585,6 +737,9 @@ func handleShardNotifyMsg(msg *xshard_types.XShardNotify, store store.LedgerStor
 
 		txState.ShardNotifies = nil
 		xshardDB.SetXShardState(txState)
+		if err := xshardDB.AddXShardTxDeadline(header.Height+xshardDB.TxTimeout(), shardTxID); err != nil {
+			log.Errorf("handle shard notify: add xshard tx deadline failed, %s", err)
+		}
 		cache.Reset()
 		return
 	}
@@ -620,6 +775,12 @@ func handleShardNotifyMsg(msg *xshard_types.XShardNotify, store store.LedgerStor
 			}
 		}
 	}
+	if err != nil {
+		// failed shard call of system contracts is free, but changes of it should not be committed
+		log.Debugf("handle shard notify error %s", err)
+		cache.Reset()
+		return
+	}
 	cache.GetCache().ForEach(func(key, val []byte) {
 		lockKey(txLockedKeys, key)
 	})
@@ -746,6 +907,10 @@ func handleShardRespMsg(msg *xshard_types.XShardTxRsp, store store.LedgerStore,
 	if err != nil {
 		return
 	}
+	if txState.ExecState == xshard_state.ExecAborted {
+		// tx has been aborted, maybe timeout
+		return
+	}
 	txState.ExecState = xshard_state.ExecNone
 	if msg.FeeUsed < neovm.MIN_TRANSACTION_GAS {
 		msg.FeeUsed = neovm.MIN_TRANSACTION_GAS
@@ -904,6 +1069,10 @@ func handleShardRespMsg(msg *xshard_types.XShardTxRsp, store store.LedgerStore,
 	txState.LockedAddress = shardTxLockAddr
 	txState.Notify = evts
 	txState.ExecState = xshard_state.ExecPrepared
+	if subTx.GasPrice > 0 && gasConsumed > neovm.MIN_TRANSACTION_GAS {
+		// charged by the deferred chargeHandleRespFee, refunded if the tx is aborted later
+		txState.PreparedFee = gasConsumed - neovm.MIN_TRANSACTION_GAS
+	}
 
 	xshardDB.SetXShardState(txState)
 
@@ -981,7 +1150,7 @@ func HandleShardCallTransaction(store store.LedgerStore, overlay *overlaydb.Over
 		case *xshard_types.XShardCommitMsg:
 			handleShardCommitMsg(msg, lockedAddress, lockedKeys, cache, xshardDB, header, notify)
 		case *xshard_types.XShardAbortMsg:
-			handleShardAbortMsg(msg, lockedAddress, lockedKeys, xshardDB)
+			handleShardAbortMsg(msg, store, lockedAddress, lockedKeys, cache, xshardDB, header, notify)
 		}
 		sourceTxHash := req.GetSourceTxHash()
 		if !hasTxHash(sourceTxHash, notify.ContractEvent) {
@@ -1003,13 +1172,24 @@ func hasTxHash(txHash common.Uint256, notify *event.ExecuteNotify) bool {
 }
 
 //HandleInvokeTransaction deal with smart contract invoke transaction
+// consensus system txs and txs of genesis block are free
+func isSysTransaction(tx *types.Transaction, header *types.Header) bool {
+	if header.Height == 0 {
+		return true
+	}
+	invoke, ok := tx.Payload.(*payload.InvokeCode)
+	if !ok {
+		return false
+	}
+	return bytes.Compare(invoke.Code, ninit.COMMIT_DPOS_BYTES) == 0 ||
+		bytes.Compare(invoke.Code, ninit.SHARD_COMMIT_DPOS_BYTES) == 0
+}
+
 func HandleInvokeTransaction(store store.LedgerStore, overlay *overlaydb.OverlayDB, gasTable map[string]uint64,
 	lockedAddress map[common.Address]struct{}, lockedKeys map[string]struct{}, cache *storage.CacheDB, xshardDB *storage.XShardDB,
 	tx *types.Transaction, header *types.Header, notify *event.TransactionNotify) (result interface{}, err error) {
 	invoke := tx.Payload.(*payload.InvokeCode)
-	code := invoke.Code
-	sysTransFlag := bytes.Compare(code, ninit.COMMIT_DPOS_BYTES) == 0 ||
-		bytes.Compare(code, ninit.SHARD_COMMIT_DPOS_BYTES) == 0 || header.Height == 0
+	sysTransFlag := isSysTransaction(tx, header)
 	txHash := tx.Hash()
 	txState := xshard_state.CreateTxState(xshard_types.ShardTxID(string(txHash[:])))
 
@@ -1086,6 +1266,7 @@ func HandleInvokeTransaction(store store.LedgerStore, overlay *overlaydb.Overlay
 		Store:         store,
 		GasTable:      gasTable,
 		LockedAddress: lockedAddress,
+		LockedKeys:    lockedKeys,
 		Gas:           availableGasLimit - codeLenGasLimit,
 	}
 
@@ -1112,6 +1293,9 @@ func HandleInvokeTransaction(store store.LedgerStore, overlay *overlaydb.Overlay
 
 			txState.ShardNotifies = nil
 			xshardDB.SetXShardState(txState)
+			if err = xshardDB.AddXShardTxDeadline(header.Height+xshardDB.TxTimeout(), txState.TxID); err != nil {
+				return nil, err
+			}
 			return nil, nil
 		}
 
@@ -1181,16 +1365,47 @@ func chargeCostGas(payer common.Address, gas uint64, config *smartcontract.Confi
 	return sc.Notifications, nil
 }
 
-func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore) error {
+// refundCostGas returns the gas fee collected by chargeCostGas back to payer
+func refundCostGas(payer common.Address, gas uint64, config *smartcontract.Config,
+	cache *storage.CacheDB, store store.LedgerStore, shardID common.ShardID) ([]*event.NotifyEventInfo, error) {
+	contractAddr := utils.GovernanceContractAddress
+	if !shardID.IsRootShard() {
+		contractAddr = utils.ShardMgmtContractAddress
+	}
+	params := genNativeTransferCode(contractAddr, payer, gas)
+
+	sc := smartcontract.SmartContract{
+		Config:  config,
+		CacheDB: cache,
+		Store:   store,
+		Gas:     math.MaxUint64,
+	}
+	// the fee is transferred out by the fee collecting contract itself
+	sc.PushContext(&context.Context{ContractAddress: contractAddr})
+
+	service, _ := sc.NewNativeService()
+	_, err := service.NativeCall(utils.OngContractAddress, "transfer", params)
+	if err != nil {
+		return nil, err
+	}
+	return sc.Notifications, nil
+}
+
+// refreshGlobalParam loads gas table and gas price from global params, and returns the cross shard tx timeout
+// recorded in the global params of the ledger, 0 if it is not set
+func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore) (uint32, error) {
 	bf := new(bytes.Buffer)
-	if err := utils.WriteVarUint(bf, uint64(len(neovm.GAS_TABLE_KEYS))); err != nil {
-		return fmt.Errorf("write gas_table_keys length error:%s", err)
+	if err := utils.WriteVarUint(bf, uint64(len(neovm.GAS_TABLE_KEYS)+1)); err != nil {
+		return 0, fmt.Errorf("write gas_table_keys length error:%s", err)
 	}
 	for _, value := range neovm.GAS_TABLE_KEYS {
 		if err := serialization.WriteString(bf, value); err != nil {
-			return fmt.Errorf("serialize param name error:%s", value)
+			return 0, fmt.Errorf("serialize param name error:%s", value)
 		}
 	}
+	if err := serialization.WriteString(bf, genesis.NAME_XSHARD_TX_TIMEOUT); err != nil {
+		return 0, fmt.Errorf("serialize param name error:%s", genesis.NAME_XSHARD_TX_TIMEOUT)
+	}
 
 	sc := smartcontract.SmartContract{
 		Config:  config,
@@ -1202,11 +1417,11 @@ func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, st
 	service, _ := sc.NewNativeService()
 	result, err := service.NativeCall(utils.ParamContractAddress, "getGlobalParam", bf.Bytes())
 	if err != nil {
-		return err
+		return 0, err
 	}
 	params := new(global_params.Params)
 	if err := params.Deserialize(bytes.NewBuffer(result.([]byte))); err != nil {
-		return fmt.Errorf("deserialize global params error:%s", err)
+		return 0, fmt.Errorf("deserialize global params error:%s", err)
 	}
 	neovm.GAS_TABLE.Range(func(key, value interface{}) bool {
 		n, ps := params.GetParam(key.(string))
@@ -1224,7 +1439,17 @@ func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, st
 	if n != -1 && gasPriceParam.Value != "" {
 		neovm.GAS_PRICE, _ = strconv.ParseUint(gasPriceParam.Value, 10, 64)
 	}
-	return nil
+	txTimeout := uint32(0)
+	n, timeoutParam := params.GetParam(genesis.NAME_XSHARD_TX_TIMEOUT)
+	if n != -1 && timeoutParam.Value != "" {
+		timeout, err := strconv.ParseUint(timeoutParam.Value, 10, 32)
+		if err != nil || timeout == 0 {
+			log.Errorf("[refreshGlobalParam] invalid xshard tx timeout %v\n", timeoutParam.Value)
+		} else {
+			txTimeout = uint32(timeout)
+		}
+	}
+	return txTimeout, nil
 }
 
 func costInvalidGas(address common.Address, gas uint64, config *smartcontract.Config, cache *storage.CacheDB,
@@ -19,10 +19,28 @@
 package ledgerstore
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"sync"
 	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/chainmgr/xshard_state"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/payload"
+	cstates "github.com/ontio/ontology/core/states"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/xshard_types"
+	"github.com/ontio/ontology/smartcontract"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/ontio/ontology/smartcontract/service/native/global_params"
+	"github.com/ontio/ontology/smartcontract/service/native/ont"
+	"github.com/ontio/ontology/smartcontract/service/native/shardccmc"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSyncMapRange(t *testing.T) {
@@ -75,3 +93,195 @@ func TestSyncMapRW(t *testing.T) {
 func addsync(m *sync.Map, va int) {
 	m.Store("key", va)
 }
+
+func TestXShardTxTimeout(t *testing.T) {
+	payer := common.Address{1, 2, 3}
+	lockedContract := common.Address{4, 5, 6}
+	remoteShard := common.NewShardIDUnchecked(1)
+	preparedFee := uint64(1000)
+
+	overlay := testStateStore.NewOverlayDB()
+	cache := storage.NewCacheDB(overlay)
+	// xshard tx timeout of the ledger is 5 blocks
+	params := new(global_params.Params)
+	params.SetParam(global_params.Param{Key: genesis.NAME_XSHARD_TX_TIMEOUT, Value: "5"})
+	buf := new(bytes.Buffer)
+	assert.Nil(t, params.Serialize(buf))
+	paramKey := append(append(utils.ParamContractAddress[:], global_params.PARAM...), 0)
+	cache.Put(paramKey, (&cstates.StorageItem{Value: buf.Bytes()}).ToArray())
+	// the prepared fee has been collected by governance contract
+	cache.Put(ont.GenBalanceKey(utils.OngContractAddress, utils.GovernanceContractAddress),
+		utils.GenUInt64StorageItem(preparedFee).ToArray())
+	cache.Commit()
+
+	config := &smartcontract.Config{ShardID: common.RootShardID, Height: 1, Tx: &types.Transaction{}}
+	timeout, err := refreshGlobalParam(config, cache, testLedgerStore)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(5), timeout)
+
+	xshardDB := storage.NewXShardDB(overlay)
+	xshardDB.SetTxTimeout(timeout)
+	mutable := &types.MutableTransaction{
+		GasPrice: 1,
+		GasLimit: 20000,
+		TxType:   types.Invoke,
+		Payer:    payer,
+		Payload:  &payload.InvokeCode{Code: []byte{1}},
+		Sigs:     make([]types.Sig, 0),
+	}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	txState := xshard_state.CreateTxState(xshard_types.ShardTxID("timeout-tx"))
+	txState.TxPayload = tx.Raw
+	txState.ExecState = xshard_state.ExecPrepared
+	txState.Shards[remoteShard] = xshard_state.ExecPrepared
+	txState.LockedAddress = []common.Address{lockedContract}
+	txState.LockedKeys = [][]byte{[]byte("key")}
+	txState.PreparedFee = preparedFee
+	xshardDB.SetXShardState(txState)
+	assert.Nil(t, xshardDB.AddXShardTxDeadline(1+xshardDB.TxTimeout(), txState.TxID))
+	xshardDB.Commit()
+
+	lockedAddress := map[common.Address]struct{}{lockedContract: {}}
+	lockedKeys := map[string]struct{}{"key": {}}
+	handleTimeout := func(height uint32) *event.TransactionNotify {
+		notify := &event.TransactionNotify{ContractEvent: &event.ExecuteNotify{}}
+		header := &types.Header{ShardID: common.RootShardID, Height: height}
+		assert.Nil(t, handleXShardTxTimeout(testLedgerStore, lockedAddress, lockedKeys, cache, xshardDB, header, notify))
+		return notify
+	}
+
+	// tx is still pending before deadline
+	notify := handleTimeout(5)
+	assert.Equal(t, 0, len(notify.ShardMsg))
+	state, err := xshardDB.GetXShardState(txState.TxID)
+	assert.Nil(t, err)
+	assert.Equal(t, xshard_state.ExecPrepared, state.ExecState)
+	assert.Equal(t, 1, len(lockedAddress))
+
+	// tx is aborted at deadline, locks are released and prepared fee is refunded
+	notify = handleTimeout(6)
+	assert.Equal(t, 1, len(notify.ShardMsg))
+	abort, ok := notify.ShardMsg[0].(*xshard_types.XShardAbortMsg)
+	assert.True(t, ok)
+	assert.Equal(t, remoteShard, abort.TargetShardID)
+	state, err = xshardDB.GetXShardState(txState.TxID)
+	assert.Nil(t, err)
+	assert.Equal(t, xshard_state.ExecAborted, state.ExecState)
+	assert.Equal(t, uint64(0), state.PreparedFee)
+	assert.Equal(t, 0, len(lockedAddress))
+	assert.Equal(t, 0, len(lockedKeys))
+	ids, err := xshardDB.GetXShardTxsByDeadline(6)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(ids))
+
+	balance, err := getBalanceFromNative(config, cache, testLedgerStore, payer)
+	assert.Nil(t, err)
+	assert.Equal(t, preparedFee, balance)
+	balance, err = getBalanceFromNative(config, cache, testLedgerStore, utils.GovernanceContractAddress)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), balance)
+}
+
+func TestChangeMetadataOfCCMCFrozenContract(t *testing.T) {
+	owner := common.Address{1, 2, 3}
+	frozenByOwner, frozenByCCMC := common.Address{4, 5, 6}, common.Address{7, 8, 9}
+
+	overlay := testStateStore.NewOverlayDB()
+	cache := storage.NewCacheDB(overlay)
+	for _, addr := range []common.Address{frozenByOwner, frozenByCCMC} {
+		meta := payload.NewDefaultMetaData()
+		meta.Contract = addr
+		meta.Owner = owner
+		meta.IsFrozen = true
+		cache.PutMetaData(meta)
+	}
+	cache.Put(utils.ConcatKey(utils.ShardCCMCAddress, []byte(shardccmc.KEY_CC_FROZEN), frozenByCCMC[:]),
+		cstates.GenRawStorageItem([]byte{1}))
+	cache.Commit()
+
+	unfreeze := func(addr common.Address) error {
+		newMeta := payload.NewDefaultMetaData()
+		newMeta.Contract = addr
+		newMeta.Owner = owner
+		mutable := &types.MutableTransaction{
+			Version: common.VERSION_SUPPORT_SHARD,
+			TxType:  types.MetaData,
+			Payer:   owner,
+			Payload: newMeta,
+			Sigs:    make([]types.Sig, 0),
+		}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		tx.SignedAddr = []common.Address{owner}
+		notify := &event.ExecuteNotify{}
+		header := &types.Header{ShardID: common.RootShardID, Height: 1}
+		return HandleChangeMetadataTransaction(testLedgerStore, overlay, nil, cache, tx, header, notify)
+	}
+
+	assert.Nil(t, unfreeze(frozenByOwner))
+	meta, err := cache.GetMetaData(frozenByOwner)
+	assert.Nil(t, err)
+	assert.False(t, meta.IsFrozen)
+
+	assert.NotNil(t, unfreeze(frozenByCCMC))
+	meta, err = cache.GetMetaData(frozenByCCMC)
+	assert.Nil(t, err)
+	assert.True(t, meta.IsFrozen)
+}
+
+func TestDrainingShardRejectsTx(t *testing.T) {
+	owner := common.Address{1, 2, 3}
+	contract := common.Address{4, 5, 6}
+	shardID := common.NewShardIDUnchecked(1)
+
+	overlay := testStateStore.NewOverlayDB()
+	cache := storage.NewCacheDB(overlay)
+	meta := payload.NewDefaultMetaData()
+	meta.Contract = contract
+	meta.Owner = owner
+	meta.IsFrozen = true
+	cache.PutMetaData(meta)
+	cache.Commit()
+
+	setFrozen := func(frozen bool) *event.ExecuteNotify {
+		newMeta := payload.NewDefaultMetaData()
+		newMeta.Contract = contract
+		newMeta.Owner = owner
+		newMeta.IsFrozen = frozen
+		mutable := &types.MutableTransaction{
+			Version: common.VERSION_SUPPORT_SHARD,
+			TxType:  types.MetaData,
+			Payer:   owner,
+			Payload: newMeta,
+			Sigs:    make([]types.Sig, 0),
+		}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		tx.SignedAddr = []common.Address{owner}
+		header := &types.Header{ShardID: shardID, Height: 1}
+		notify, err := HandleTransaction(testLedgerStore, overlay, storage.NewCacheDB(overlay), nil, nil, nil, nil,
+			header, tx)
+		assert.Nil(t, err)
+		return notify.ContractEvent
+	}
+	isFrozen := func() bool {
+		meta, err := storage.NewCacheDB(overlay).GetMetaData(contract)
+		assert.Nil(t, err)
+		return meta.IsFrozen
+	}
+
+	setFrozen(false)
+	assert.False(t, isFrozen())
+
+	// shard stopped by parent, txs other than cross shard txs are not executed
+	cache.Put(utils.ConcatKey(utils.ShardMgmtContractAddress, []byte(shardmgmt.KEY_SHARD_DRAINING)),
+		cstates.GenRawStorageItem([]byte{1}))
+	cache.Commit()
+	draining, err := shardmgmt.IsShardDraining(storage.NewCacheDB(overlay))
+	assert.Nil(t, err)
+	assert.True(t, draining)
+	notify := setFrozen(true)
+	assert.Equal(t, event.CONTRACT_STATE_FAIL, notify.State)
+	assert.False(t, isFrozen())
+}
@@ -37,6 +37,7 @@ type JoinIter struct {
 	keyOrigin   KeyOrigin
 	nextMemEnd  bool
 	nextBackEnd bool
+	reverse     bool // whether the sub iterators are moving backward
 	cmp         comparer.BasicComparer
 }
 
@@ -49,62 +50,24 @@ func NewJoinIter(memIter, backendIter common.StoreIterator) *JoinIter {
 }
 
 func (iter *JoinIter) First() bool {
-	f := iter.first()
-	if f == false {
-		return false
-	}
-	for len(iter.value) == 0 {
-		if iter.next() == false {
-			return false
-		}
-	}
+	iter.reverse = false
+	iter.nextBackEnd = !iter.backend.First()
+	iter.nextMemEnd = !iter.memdb.First()
+	return iter.skipDeleted(iter.pick(), iter.next)
+}
 
-	return true
+func (iter *JoinIter) Last() bool {
+	iter.reverse = true
+	iter.nextBackEnd = !iter.backend.Last()
+	iter.nextMemEnd = !iter.memdb.Last()
+	return iter.skipDeleted(iter.pick(), iter.prev)
 }
 
-func (iter *JoinIter) first() bool {
-	var bkey, bval, mkey, mval []byte
-	back := iter.backend.First()
-	mem := iter.memdb.First()
-	// check error
-	if iter.Error() != nil {
-		return false
-	}
-	if back {
-		bkey = iter.backend.Key()
-		bval = iter.backend.Value()
-		if mem == false {
-			iter.key = bkey
-			iter.value = bval
-			iter.keyOrigin = FromBack
-			return true
-		}
-		mkey = iter.memdb.Key()
-		mval = iter.memdb.Value()
-		cmp := iter.cmp.Compare(mkey, bkey)
-		if cmp < 1 {
-			iter.key = mkey
-			iter.value = mval
-			if cmp == 0 {
-				iter.keyOrigin = FromBoth
-			} else {
-				iter.keyOrigin = FromMem
-			}
-		} else {
-			iter.key = bkey
-			iter.value = bval
-			iter.keyOrigin = FromBack
-		}
-		return true
-	} else {
-		if mem {
-			iter.key = iter.memdb.Key()
-			iter.value = iter.memdb.Value()
-			iter.keyOrigin = FromMem
-			return true
-		}
-		return false
-	}
+func (iter *JoinIter) Seek(key []byte) bool {
+	iter.reverse = false
+	iter.nextBackEnd = !iter.backend.Seek(key)
+	iter.nextMemEnd = !iter.memdb.Seek(key)
+	return iter.skipDeleted(iter.pick(), iter.next)
 }
 
 func (iter *JoinIter) Key() []byte {
@@ -116,30 +79,77 @@ func (iter *JoinIter) Value() []byte {
 }
 
 func (iter *JoinIter) Next() bool {
-	f := iter.next()
-	if f == false {
-		return false
-	}
+	return iter.skipDeleted(iter.next(), iter.next)
+}
 
-	for len(iter.value) == 0 {
-		if iter.next() == false {
-			return false
-		}
-	}
+func (iter *JoinIter) Prev() bool {
+	return iter.skipDeleted(iter.prev(), iter.prev)
+}
 
-	return true
+// skipDeleted moves on until the current item is not deleted in memdb
+func (iter *JoinIter) skipDeleted(valid bool, move func() bool) bool {
+	for valid && len(iter.value) == 0 {
+		valid = move()
+	}
+	return valid
 }
 
 func (iter *JoinIter) next() bool {
+	if iter.key == nil {
+		// iterator is exhausted or not positioned yet
+		if iter.reverse || (iter.nextMemEnd == false && iter.nextBackEnd == false) {
+			return iter.First()
+		}
+		return false
+	}
+	if iter.reverse {
+		// reposition the sub iterators at the first key after current key
+		key := append([]byte{}, iter.key...)
+		iter.reverse = false
+		iter.nextMemEnd = !seekAfter(iter.memdb, key, iter.cmp)
+		iter.nextBackEnd = !seekAfter(iter.backend, key, iter.cmp)
+		return iter.pick()
+	}
 	if (iter.keyOrigin == FromMem || iter.keyOrigin == FromBoth) && iter.nextMemEnd == false {
 		iter.nextMemEnd = !iter.memdb.Next()
 	}
 	if (iter.keyOrigin == FromBack || iter.keyOrigin == FromBoth) && iter.nextBackEnd == false {
 		iter.nextBackEnd = !iter.backend.Next()
 	}
+	return iter.pick()
+}
 
+func (iter *JoinIter) prev() bool {
+	if iter.key == nil {
+		if !iter.reverse || (iter.nextMemEnd == false && iter.nextBackEnd == false) {
+			return iter.Last()
+		}
+		return false
+	}
+	if !iter.reverse {
+		// reposition the sub iterators at the last key before current key
+		key := append([]byte{}, iter.key...)
+		iter.reverse = true
+		iter.nextMemEnd = !seekBefore(iter.memdb, key)
+		iter.nextBackEnd = !seekBefore(iter.backend, key)
+		return iter.pick()
+	}
+	if (iter.keyOrigin == FromMem || iter.keyOrigin == FromBoth) && iter.nextMemEnd == false {
+		iter.nextMemEnd = !iter.memdb.Prev()
+	}
+	if (iter.keyOrigin == FromBack || iter.keyOrigin == FromBoth) && iter.nextBackEnd == false {
+		iter.nextBackEnd = !iter.backend.Prev()
+	}
+	return iter.pick()
+}
+
+// pick the current item from the positioned sub iterators, the smaller key goes first when moving
+// forward and the larger key goes first when moving backward. memdb value shadows backend value
+func (iter *JoinIter) pick() bool {
 	// check error
 	if iter.Error() != nil {
+		iter.key = nil
+		iter.value = nil
 		return false
 	}
 
@@ -162,21 +172,22 @@ func (iter *JoinIter) next() bool {
 			bkey := iter.backend.Key()
 			mkey := iter.memdb.Key()
 			cmp := iter.cmp.Compare(mkey, bkey)
-			switch cmp {
-			case -1:
+			if iter.reverse {
+				cmp = -cmp
+			}
+			switch {
+			case cmp < 0:
 				iter.key = mkey
 				iter.value = iter.memdb.Value()
 				iter.keyOrigin = FromMem
-			case 0:
+			case cmp == 0:
 				iter.key = mkey
 				iter.value = iter.memdb.Value()
 				iter.keyOrigin = FromBoth
-			case 1:
+			default:
 				iter.key = bkey
 				iter.value = iter.backend.Value()
 				iter.keyOrigin = FromBack
-			default:
-				panic("unreachable")
 			}
 		}
 	}
@@ -197,3 +208,22 @@ func (iter *JoinIter) Error() error {
 	}
 	return nil
 }
+
+// seekAfter positions the iterator at the first key greater than key
+func seekAfter(iter common.StoreIterator, key []byte, cmp comparer.BasicComparer) bool {
+	if !iter.Seek(key) {
+		return false
+	}
+	if cmp.Compare(iter.Key(), key) == 0 {
+		return iter.Next()
+	}
+	return true
+}
+
+// seekBefore positions the iterator at the last key less than key
+func seekBefore(iter common.StoreIterator, key []byte) bool {
+	if !iter.Seek(key) {
+		return iter.Last()
+	}
+	return iter.Prev()
+}
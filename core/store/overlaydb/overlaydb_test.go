@@ -68,6 +68,70 @@ func TestNewOverlayDB(t *testing.T) {
 	}
 }
 
+func TestJoinIterSeekAndPrev(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+
+	N := 100
+	// even keys in backend, odd keys in memdb, and every 4th key is deleted in memdb
+	for i := 0; i < N; i += 2 {
+		assert.Nil(t, store.Put(makeKey(i), []byte("back"+strconv.Itoa(i))))
+	}
+	overlay := NewOverlayDB(store)
+	for i := 1; i < N; i += 2 {
+		overlay.Put(makeKey(i), []byte("mem"+strconv.Itoa(i)))
+	}
+	for i := 0; i < N; i += 4 {
+		overlay.Delete(makeKey(i))
+	}
+	var expected []int
+	for i := 0; i < N; i++ {
+		if i%4 != 0 {
+			expected = append(expected, i)
+		}
+	}
+	checkItem := func(iter interface {
+		Key() []byte
+		Value() []byte
+	}, i int) {
+		assert.Equal(t, makeKey(i), iter.Key())
+		if i%2 == 0 {
+			assert.Equal(t, []byte("back"+strconv.Itoa(i)), iter.Value())
+		} else {
+			assert.Equal(t, []byte("mem"+strconv.Itoa(i)), iter.Value())
+		}
+	}
+
+	iter := overlay.NewIterator([]byte("key"))
+	n := 0
+	for has := iter.Last(); has; has = iter.Prev() {
+		n++
+		checkItem(iter, expected[len(expected)-n])
+	}
+	assert.Equal(t, len(expected), n)
+	// move forward after reaching the start
+	assert.True(t, iter.Next())
+	checkItem(iter, expected[0])
+
+	// seek to a deleted key skips to the next one
+	assert.True(t, iter.Seek(makeKey(40)))
+	checkItem(iter, 41)
+	assert.True(t, iter.Prev())
+	checkItem(iter, 39)
+	assert.True(t, iter.Prev())
+	checkItem(iter, 38)
+	assert.True(t, iter.Next())
+	checkItem(iter, 39)
+	assert.True(t, iter.Next())
+	checkItem(iter, 41)
+
+	assert.False(t, iter.Seek(makeKey(N)))
+	assert.True(t, iter.Prev())
+	checkItem(iter, expected[len(expected)-1])
+	iter.Release()
+	assert.Nil(t, iter.Error())
+}
+
 func BenchmarkOverlayDBSerialPut(b *testing.B) {
 	store, _ := leveldbstore.NewMemLevelDBStore()
 
@@ -28,6 +28,7 @@ import (
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/core/xshard_types"
 	"github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/merkle"
 	"github.com/ontio/ontology/smartcontract/event"
 	cstates "github.com/ontio/ontology/smartcontract/states"
 )
@@ -36,6 +37,7 @@ type ExecuteResult struct {
 	WriteSet    *overlaydb.MemDB
 	Hash        common.Uint256
 	MerkleRoot  common.Uint256
+	StorageRoot common.Uint256 // empty if storage trie is disabled
 	Notify      []*event.ExecuteNotify
 	ShardNotify []xshard_types.CommonShardMsg
 }
@@ -59,6 +61,7 @@ type LedgerStore interface {
 	GetBlockByHash(blockHash common.Uint256) (*types.Block, error)
 	GetBlockByHeight(height uint32) (*types.Block, error)
 	GetTransaction(txHash common.Uint256) (*types.Transaction, uint32, error)
+	GetShardTx(shardTxHash common.Uint256) (*types.CrossShardTxInfos, uint32, error)
 	IsContainBlock(blockHash common.Uint256) (bool, error)
 	IsContainTransaction(txHash common.Uint256) (bool, error)
 	IsContainShardTx(shardTxHash common.Uint256) (bool, error)
@@ -68,7 +71,11 @@ type LedgerStore interface {
 	GetContractMetaData(contractHash common.Address) (*payload.MetaDataCode, error)
 	GetBookkeeperState() (*states.BookkeeperState, error)
 	GetStorageItem(key *states.StorageKey) (*states.StorageItem, error)
+	GetStorageItemAtHeight(key *states.StorageKey, height uint32) (*states.StorageItem, error)
+	GetStorageTrieRoot(height uint32) (common.Uint256, error)
+	GetStorageProof(key *states.StorageKey, height uint32) ([]byte, *merkle.SparseMerkleProof, error)
 	PreExecuteContract(tx *types.Transaction) (*cstates.PreExecResult, error)
+	PreExecuteContractAtHeight(tx *types.Transaction, height uint32) (*cstates.PreExecResult, error)
 	GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error)
 	GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error)
 	GetBlockShardEvents(height uint32) (events []*message.ShardSystemEventMsg, err error)
@@ -80,4 +87,6 @@ type LedgerStore interface {
 	GetParentContract(blockHeight uint32, addr common.Address) (*payload.DeployCode, error)
 	GetShardConsensusConfig(shardID common.ShardID, height uint32) ([]byte, error)
 	GetShardTxState(txHash common.Uint256, notifyId uint32, hasNotifyId bool) (*xshard_state.TxState, error)
+	GetShardTxStateByID(id xshard_types.ShardTxID) (*xshard_state.TxState, error)
+	GetXShardTxMsgHeights(id xshard_types.ShardTxID) ([]uint32, error)
 }
@@ -34,21 +34,7 @@ type Block struct {
 
 func (b *Block) Serialization(sink *common.ZeroCopySink) {
 	b.Header.Serialization(sink)
-
-	// serialize cross-shard txs, ordered by ShardID
-	sink.WriteUint32(uint32(len(b.ShardTxs)))
-	shardIds := make([]common.ShardID, 0, len(b.ShardTxs))
-
-	for id := range b.ShardTxs {
-		shardIds = append(shardIds, id)
-	}
-
-	common.SortShardID(shardIds)
-
-	for _, shardID := range shardIds {
-		evts := b.ShardTxs[shardID]
-		zcpSerializeShardTxs(sink, shardID, evts)
-	}
+	SerializeShardTxs(sink, b.ShardTxs)
 
 	// serialize transactions
 	sink.WriteUint32(uint32(len(b.Transactions)))
@@ -78,11 +64,7 @@ func (self *Block) Deserialization(source *common.ZeroCopySource) error {
 	}
 
 	// deserialize cross-shard Txs
-	nShardTxs, eof := source.NextUint32()
-	if eof {
-		return io.ErrUnexpectedEOF
-	}
-	shardTxs, err := zcpDeserializeShardTxs(source, nShardTxs)
+	shardTxs, err := DeserializeShardTxs(source)
 	if err != nil {
 		return err
 	}
@@ -144,6 +126,31 @@ func (b *Block) RebuildMerkleRoot() {
 	b.Header.TransactionsRoot = hash
 }
 
+// SerializeShardTxs serializes cross-shard txs, ordered by ShardID
+func SerializeShardTxs(sink *common.ZeroCopySink, shardTxs map[common.ShardID][]*CrossShardTxInfos) {
+	sink.WriteUint32(uint32(len(shardTxs)))
+	shardIds := make([]common.ShardID, 0, len(shardTxs))
+
+	for id := range shardTxs {
+		shardIds = append(shardIds, id)
+	}
+
+	common.SortShardID(shardIds)
+
+	for _, shardID := range shardIds {
+		evts := shardTxs[shardID]
+		zcpSerializeShardTxs(sink, shardID, evts)
+	}
+}
+
+func DeserializeShardTxs(source *common.ZeroCopySource) (map[common.ShardID][]*CrossShardTxInfos, error) {
+	nShardTxs, eof := source.NextUint32()
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return zcpDeserializeShardTxs(source, nShardTxs)
+}
+
 func zcpSerializeShardTxs(sink *common.ZeroCopySink, shardID common.ShardID, shardTxs []*CrossShardTxInfos) {
 	if shardTxs == nil {
 		return
@@ -30,11 +30,11 @@ const (
 	TOPIC_NODE_CONSENSUS_DISCONNECT = "nodcnsdis"
 	TOPIC_SMART_CODE_EVENT          = "scevt"
 	TOPIC_SHARD_SYSTEM_EVENT        = "shardevt"
+	TOPIC_CROSS_SHARD_MSG           = "xshardmsg"
 )
 
 type SaveBlockCompleteMsg struct {
 	Block                   *types.Block
-	ShardSysEvents          []*ShardSystemEventMsg
 	SourceAndShardTxHashMap map[common.Uint256]common.Uint256
 }
 
@@ -49,3 +49,12 @@ type SmartCodeEventMsg struct {
 type BlockConsensusComplete struct {
 	Block *types.Block
 }
+
+// CrossShardMsgEvent is published when a cross-shard msg is saved to ledger,
+// Incoming is true if the msg is received from FromShard, false if it is sent to ToShard
+type CrossShardMsgEvent struct {
+	FromShard common.ShardID
+	ToShard   common.ShardID
+	Incoming  bool
+	Msg       *types.CrossShardMsg
+}
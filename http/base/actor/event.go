@@ -27,6 +27,7 @@ import (
 type EventActor struct {
 	blockPersistCompleted func(v interface{})
 	smartCodeEvt          func(v interface{})
+	crossShardMsg         func(v interface{})
 }
 
 //receive from subscribed actor
@@ -36,17 +37,21 @@ func (t *EventActor) Receive(c actor.Context) {
 		t.blockPersistCompleted(*msg.Block)
 	case *message.SmartCodeEventMsg:
 		t.smartCodeEvt(*msg.Event)
+	case *message.CrossShardMsgEvent:
+		t.crossShardMsg(*msg)
 	default:
 	}
 }
 
-//Subscribe save block complete and smartcontract Event
+//Subscribe save block complete, smartcontract Event and cross shard msg
 func SubscribeEvent(topic string, handler func(v interface{})) {
 	var props = actor.FromProducer(func() actor.Actor {
 		if topic == message.TOPIC_SAVE_BLOCK_COMPLETE {
 			return &EventActor{blockPersistCompleted: handler}
 		} else if topic == message.TOPIC_SMART_CODE_EVENT {
 			return &EventActor{smartCodeEvt: handler}
+		} else if topic == message.TOPIC_CROSS_SHARD_MSG {
+			return &EventActor{crossShardMsg: handler}
 		} else {
 			return &EventActor{}
 		}
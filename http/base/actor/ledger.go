@@ -20,10 +20,12 @@ package actor
 
 import (
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
 	"github.com/ontio/ontology/core/chainmgr/xshard_state"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
 	"github.com/ontio/ontology/smartcontract/event"
 	cstate "github.com/ontio/ontology/smartcontract/states"
 )
@@ -38,6 +40,11 @@ func GetHeaderByHeight(height uint32) (*types.Header, error) {
 	return ledger.DefLedger.GetHeaderByHeight(height)
 }
 
+//GetHeaderByHash from ledger
+func GetHeaderByHash(hash common.Uint256) (*types.Header, error) {
+	return ledger.DefLedger.GetHeaderByHash(hash)
+}
+
 //GetBlockByHeight from ledger
 func GetBlockByHeight(height uint32) (*types.Block, error) {
 	return ledger.DefLedger.GetBlockByHeight(height)
@@ -73,11 +80,31 @@ func GetStorageItem(address common.Address, key []byte) ([]byte, error) {
 	return ledger.DefLedger.GetStorageItem(address, key)
 }
 
+//GetStorageItemAtHeight from ledger
+func GetStorageItemAtHeight(address common.Address, key []byte, height uint32) ([]byte, error) {
+	return ledger.DefLedger.GetStorageItemAtHeight(address, key, height)
+}
+
+//GetStorageTrieRoot from ledger
+func GetStorageTrieRoot(height uint32) (common.Uint256, error) {
+	return ledger.DefLedger.GetStorageTrieRoot(height)
+}
+
+//GetStorageProof from ledger
+func GetStorageProof(address common.Address, key []byte, height uint32) ([]byte, *merkle.SparseMerkleProof, error) {
+	return ledger.DefLedger.GetStorageProof(address, key, height)
+}
+
 //GetStorageItem from ledger
 func GetShardTxState(txHash common.Uint256, notifyId uint32, hasNotifyId bool) (*xshard_state.TxState, error) {
 	return ledger.DefLedger.GetShardTxState(txHash, notifyId, hasNotifyId)
 }
 
+// TraceShardTx from all shard ledgers of node
+func TraceShardTx(sourceTxHash common.Uint256) (*xshard.TxTraceNode, error) {
+	return xshard.TraceCrossShardTx(sourceTxHash)
+}
+
 //GetContractStateFromStore from ledger
 func GetContractStateFromStore(hash common.Address) (*payload.DeployCode, error) {
 	hash = updateNativeSCAddr(hash)
@@ -95,6 +122,11 @@ func PreExecuteContract(tx *types.Transaction) (*cstate.PreExecResult, error) {
 	return ledger.DefLedger.PreExecuteContract(tx)
 }
 
+//PreExecuteContractAtHeight from ledger
+func PreExecuteContractAtHeight(tx *types.Transaction, height uint32) (*cstate.PreExecResult, error) {
+	return ledger.DefLedger.PreExecuteContractAtHeight(tx, height)
+}
+
 func GetShardTxHashBySourceTxHash(sourceTxHash common.Uint256) (common.Uint256, error) {
 	return ledger.DefLedger.GetShardTxHashBySourceTxHash(sourceTxHash)
 }
@@ -28,6 +28,7 @@ import (
 	"github.com/ontio/ontology/common/constants"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
 	"github.com/ontio/ontology/core/chainmgr/xshard_state"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/payload"
@@ -36,11 +37,13 @@ import (
 	cutils "github.com/ontio/ontology/core/utils"
 	"github.com/ontio/ontology/core/xshard_types"
 	ontErrors "github.com/ontio/ontology/errors"
+	"github.com/ontio/ontology/events/message"
 	bactor "github.com/ontio/ontology/http/base/actor"
 	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/service/native/ont"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 	cstate "github.com/ontio/ontology/smartcontract/states"
+	"sort"
 	"strings"
 	"time"
 )
@@ -62,6 +65,13 @@ type MerkleProof struct {
 	TargetHashes     []string
 }
 
+type StorageProof struct {
+	Height      uint32
+	StorageRoot string
+	Value       string // raw storage item, empty if the key does not exist
+	Proof       string
+}
+
 type LogEventArgs struct {
 	TxHash          string
 	ContractAddress string
@@ -305,11 +315,22 @@ func GetBlockInfo(block *types.Block) BlockInfo {
 }
 
 func GetBalance(address common.Address) (*BalanceOfRsp, error) {
-	ont, err := GetContractBalance(0, utils.OntContractAddress, address)
+	return getBalance(address, bactor.PreExecuteContract)
+}
+
+//GetBalanceAtHeight return the balance of address at history block height, archive should be enabled
+func GetBalanceAtHeight(address common.Address, height uint32) (*BalanceOfRsp, error) {
+	return getBalance(address, func(tx *types.Transaction) (*cstate.PreExecResult, error) {
+		return bactor.PreExecuteContractAtHeight(tx, height)
+	})
+}
+
+func getBalance(address common.Address, preExec func(*types.Transaction) (*cstate.PreExecResult, error)) (*BalanceOfRsp, error) {
+	ont, err := getContractBalance(0, utils.OntContractAddress, address, preExec)
 	if err != nil {
 		return nil, fmt.Errorf("get ont balance error:%s", err)
 	}
-	ong, err := GetContractBalance(0, utils.OngContractAddress, address)
+	ong, err := getContractBalance(0, utils.OngContractAddress, address, preExec)
 	if err != nil {
 		return nil, fmt.Errorf("get ont balance error:%s", err)
 	}
@@ -355,6 +376,11 @@ func GetAllowance(asset string, from, to common.Address) (string, error) {
 }
 
 func GetContractBalance(cVersion byte, contractAddr, accAddr common.Address) (uint64, error) {
+	return getContractBalance(cVersion, contractAddr, accAddr, bactor.PreExecuteContract)
+}
+
+func getContractBalance(cVersion byte, contractAddr, accAddr common.Address,
+	preExec func(*types.Transaction) (*cstate.PreExecResult, error)) (uint64, error) {
 	mutable, err := NewNativeInvokeTransaction(0, 0, contractAddr, cVersion, "balanceOf", []interface{}{accAddr[:]})
 	if err != nil {
 		return 0, fmt.Errorf("NewNativeInvokeTransaction error:%s", err)
@@ -363,7 +389,7 @@ func GetContractBalance(cVersion byte, contractAddr, accAddr common.Address) (ui
 	if err != nil {
 		return 0, err
 	}
-	result, err := bactor.PreExecuteContract(tx)
+	result, err := preExec(tx)
 	if err != nil {
 		return 0, fmt.Errorf("PrepareInvokeContract error:%s", err)
 	}
@@ -692,3 +718,195 @@ func parseXShardTxReq(req *xshard_types.XShardTxReq) (XShardTxReq, ShardMsgHeade
 	}
 	return xShardTxReq, shardMsgHeader
 }
+
+// XShardTxTraceInfo is the execution tree of a user transaction across shards
+type XShardTxTraceInfo struct {
+	SourceTxHash string
+	Shards       []uint64 // all shards involved in the transaction
+	Root         *XShardTxTrace
+}
+
+type XShardTxTrace struct {
+	ShardTxID   string
+	ShardID     uint64
+	Available   bool // false if shard ledger is not held by this node
+	TxHash      string
+	Height      uint32
+	ExecState   uint8
+	Shards      map[uint64]uint8
+	FeeUsed     uint64 // fee used by remote requests and fee paid for notifications
+	GasConsumed uint64
+	Result      string
+	ResultErr   string
+	Notifies    []XShardNotify
+	OutReqResp  []XShardTxReqResp
+	InReqResp   map[uint64][]XShardTxReqResp
+	Msgs        []XShardTraceMsg
+	Children    []*XShardTxTrace
+}
+
+type XShardTraceMsg struct {
+	ShardMsgHeader
+	Height uint32
+	Type   string
+}
+
+func ParseShardTxTrace(root *xshard.TxTraceNode) XShardTxTraceInfo {
+	shards := make(map[uint64]struct{})
+	info := XShardTxTraceInfo{
+		SourceTxHash: root.TxHash.ToHexString(),
+		Shards:       make([]uint64, 0),
+		Root:         parseTxTraceNode(root, shards),
+	}
+	for shard := range shards {
+		info.Shards = append(info.Shards, shard)
+	}
+	sort.Slice(info.Shards, func(i, j int) bool { return info.Shards[i] < info.Shards[j] })
+	return info
+}
+
+func parseTxTraceNode(node *xshard.TxTraceNode, shards map[uint64]struct{}) *XShardTxTrace {
+	shards[node.ShardID.ToUint64()] = struct{}{}
+	trace := &XShardTxTrace{
+		ShardTxID: common.ToHexString([]byte(string(node.ShardTxID))),
+		ShardID:   node.ShardID.ToUint64(),
+		Available: node.Available,
+		Height:    node.Height,
+		Msgs:      make([]XShardTraceMsg, 0, len(node.Msgs)),
+		Children:  make([]*XShardTxTrace, 0, len(node.Children)),
+	}
+	if node.TxHash != common.UINT256_EMPTY {
+		trace.TxHash = node.TxHash.ToHexString()
+	}
+	if state := node.State; state != nil {
+		trace.ExecState = uint8(state.ExecState)
+		trace.Shards = make(map[uint64]uint8)
+		for k, v := range state.Shards {
+			trace.Shards[k.ToUint64()] = uint8(v)
+		}
+		trace.Result = common.ToHexString(state.Result)
+		trace.ResultErr = state.ResultErr
+		if state.Notify != nil {
+			trace.GasConsumed = state.Notify.GasConsumed
+		}
+		trace.Notifies = parseShardNotifies(state.ShardNotifies)
+		for _, n := range state.ShardNotifies {
+			trace.FeeUsed += n.Fee
+		}
+		if state.OutReqResp != nil {
+			trace.OutReqResp = parseXShardTxReqResp(state.OutReqResp)
+			for _, reqResp := range state.OutReqResp {
+				trace.FeeUsed += reqResp.Resp.FeeUsed
+			}
+		}
+		trace.InReqResp = make(map[uint64][]XShardTxReqResp)
+		for k, v := range state.InReqResp {
+			trace.InReqResp[k.ToUint64()] = parseXShardTxReqResp(v)
+		}
+	}
+	for _, msg := range node.Msgs {
+		trace.Msgs = append(trace.Msgs, XShardTraceMsg{
+			ShardMsgHeader: parseShardMsgHeader(msg.Msg),
+			Height:         msg.Height,
+			Type:           shardMsgTypeName(msg.Msg.Type()),
+		})
+		shards[msg.Msg.GetTargetShardID().ToUint64()] = struct{}{}
+	}
+	for _, child := range node.Children {
+		trace.Children = append(trace.Children, parseTxTraceNode(child, shards))
+	}
+	return trace
+}
+
+func parseShardMsgHeader(msg xshard_types.CommonShardMsg) ShardMsgHeader {
+	sourceTxHash := msg.GetSourceTxHash()
+	return ShardMsgHeader{
+		ShardTxID:     common.ToHexString([]byte(string(msg.GetShardTxID()))),
+		SourceShardID: msg.GetSourceShardID().ToUint64(),
+		TargetShardID: msg.GetTargetShardID().ToUint64(),
+		SourceTxHash:  sourceTxHash.ToHexString(),
+	}
+}
+
+type ShardSysEventInfo struct {
+	FromAddress string
+	Version     uint32
+	EventType   uint32
+	ToShard     uint64
+	FromHeight  uint32
+	Payload     string
+}
+
+func ParseShardSysEvents(evts []*message.ShardSystemEventMsg) []ShardSysEventInfo {
+	infos := make([]ShardSysEventInfo, 0, len(evts))
+	for _, evt := range evts {
+		if evt == nil || evt.Event == nil {
+			continue
+		}
+		infos = append(infos, ShardSysEventInfo{
+			FromAddress: evt.FromAddress.ToHexString(),
+			Version:     evt.Event.Version,
+			EventType:   evt.Event.EventType,
+			ToShard:     evt.Event.ToShard.ToUint64(),
+			FromHeight:  evt.Event.FromHeight,
+			Payload:     common.ToHexString(evt.Event.Payload),
+		})
+	}
+	return infos
+}
+
+type CrossShardMsgInfo struct {
+	FromShard     uint64
+	ToShard       uint64
+	Incoming      bool
+	SignMsgHeight uint32
+	PreMsgHash    string
+	Index         uint32
+	Msgs          []XShardMsg
+}
+
+type XShardMsg struct {
+	ShardMsgHeader
+	Type string
+}
+
+func ParseCrossShardMsg(evt *message.CrossShardMsgEvent) CrossShardMsgInfo {
+	info := CrossShardMsgInfo{
+		FromShard: evt.FromShard.ToUint64(),
+		ToShard:   evt.ToShard.ToUint64(),
+		Incoming:  evt.Incoming,
+		Msgs:      make([]XShardMsg, 0, len(evt.Msg.ShardMsg)),
+	}
+	if msgInfo := evt.Msg.CrossShardMsgInfo; msgInfo != nil {
+		info.SignMsgHeight = msgInfo.SignMsgHeight
+		info.PreMsgHash = msgInfo.PreCrossShardMsgHash.ToHexString()
+		info.Index = msgInfo.Index
+	}
+	for _, msg := range evt.Msg.ShardMsg {
+		info.Msgs = append(info.Msgs, XShardMsg{
+			ShardMsgHeader: parseShardMsgHeader(msg),
+			Type:           shardMsgTypeName(msg.Type()),
+		})
+	}
+	return info
+}
+
+func shardMsgTypeName(msgType uint32) string {
+	switch msgType {
+	case xshard_types.EVENT_SHARD_NOTIFY:
+		return "notify"
+	case xshard_types.EVENT_SHARD_TXREQ:
+		return "request"
+	case xshard_types.EVENT_SHARD_TXRSP:
+		return "response"
+	case xshard_types.EVENT_SHARD_PREPARE:
+		return "prepare"
+	case xshard_types.EVENT_SHARD_PREPARED:
+		return "prepared"
+	case xshard_types.EVENT_SHARD_COMMIT:
+		return "commit"
+	case xshard_types.EVENT_SHARD_ABORT:
+		return "abort"
+	}
+	return fmt.Sprintf("unknown(%d)", msgType)
+}
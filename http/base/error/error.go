@@ -39,6 +39,7 @@ const (
 	UNKNOWN_ASSET       int64 = 44002
 	UNKNOWN_BLOCK       int64 = 44003
 	UNKNOWN_CONTRACT    int64 = 44004
+	DATA_PRUNED         int64 = 44005
 
 	INTERNAL_ERROR  int64 = 45001
 	SMARTCODE_ERROR int64 = 47001
@@ -63,6 +64,7 @@ var ErrMap = map[int64]string{
 	UNKNOWN_ASSET:       "UNKNOWN ASSET",
 	UNKNOWN_BLOCK:       "UNKNOWN BLOCK",
 	UNKNOWN_CONTRACT:    "UNKNOWN CONTRACT",
+	DATA_PRUNED:         "DATA PRUNED",
 
 	INTERNAL_ERROR:                           "INTERNAL ERROR",
 	SMARTCODE_ERROR:                          "SMARTCODE EXEC ERROR",
@@ -35,6 +35,7 @@ import (
 	bcomn "github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	cstates "github.com/ontio/ontology/smartcontract/states"
 )
 
 const TLS_PORT int = 443
@@ -98,6 +99,9 @@ func GetBlockHash(cmd map[string]interface{}) map[string]interface{} {
 
 func getBlock(hash common.Uint256, getTxBytes bool) (interface{}, int64) {
 	block, err := bactor.GetBlockFromStore(hash)
+	if err == scom.ErrPruned {
+		return nil, berr.DATA_PRUNED
+	}
 	if err != nil {
 		return nil, berr.UNKNOWN_BLOCK
 	}
@@ -145,6 +149,10 @@ func GetBlockHeightByTxHash(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if err == scom.ErrPruned {
+		resp["Result"] = height
+		return resp
+	}
 	if err != nil {
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
@@ -173,6 +181,9 @@ func GetBlockTxsByHeight(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	block, err := bactor.GetBlockFromStore(hash)
+	if err == scom.ErrPruned {
+		return ResponsePack(berr.DATA_PRUNED)
+	}
 	if err != nil {
 		return ResponsePack(berr.UNKNOWN_BLOCK)
 	}
@@ -225,6 +236,9 @@ func GetTransactionByHash(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if err == scom.ErrPruned {
+		return ResponsePack(berr.DATA_PRUNED)
+	}
 	if tx == nil {
 		return ResponsePack(berr.UNKNOWN_TRANSACTION)
 	}
@@ -268,7 +282,16 @@ func SendRawTransaction(cmd map[string]interface{}) map[string]interface{} {
 	log.Debugf("SendRawTransaction recv %s", hash.ToHexString())
 	if txn.TxType == types.Invoke || txn.TxType == types.Deploy {
 		if preExec, ok := cmd["PreExec"].(string); ok && preExec == "1" {
-			rst, err := bactor.PreExecuteContract(txn)
+			var rst *cstates.PreExecResult
+			if height, ok := cmd["Height"].(string); ok && height != "" {
+				h, e := strconv.ParseUint(height, 10, 32)
+				if e != nil {
+					return ResponsePack(berr.INVALID_PARAMS)
+				}
+				rst, err = bactor.PreExecuteContractAtHeight(txn, uint32(h))
+			} else {
+				rst, err = bactor.PreExecuteContract(txn)
+			}
 			if err != nil {
 				log.Infof("PreExec: ", err)
 				resp = ResponsePack(berr.SMARTCODE_ERROR)
@@ -323,6 +346,9 @@ func GetSmartCodeEventTxsByHeight(cmd map[string]interface{}) map[string]interfa
 		if scom.ErrNotFound == err {
 			return ResponsePack(berr.SUCCESS)
 		}
+		if scom.ErrPruned == err {
+			return ResponsePack(berr.DATA_PRUNED)
+		}
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
 	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
@@ -355,6 +381,9 @@ func GetSmartCodeEventByTxHash(cmd map[string]interface{}) map[string]interface{
 		if scom.ErrNotFound == err {
 			return ResponsePack(berr.SUCCESS)
 		}
+		if scom.ErrPruned == err {
+			return ResponsePack(berr.DATA_PRUNED)
+		}
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
 	if eventInfo == nil {
@@ -393,6 +422,9 @@ func GetShardSmartCodeEvent(cmd map[string]interface{}) map[string]interface{} {
 		if scom.ErrNotFound == err {
 			return ResponsePack(berr.SUCCESS)
 		}
+		if scom.ErrPruned == err {
+			return ResponsePack(berr.DATA_PRUNED)
+		}
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
 	if eventInfo == nil {
@@ -481,7 +513,16 @@ func GetStorage(cmd map[string]interface{}) map[string]interface{} {
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	value, err := bactor.GetStorageItem(address, item)
+	var value []byte
+	if height, ok := cmd["Height"].(string); ok && height != "" {
+		h, e := strconv.ParseUint(height, 10, 32)
+		if e != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		value, err = bactor.GetStorageItemAtHeight(address, item, uint32(h))
+	} else {
+		value, err = bactor.GetStorageItem(address, item)
+	}
 	if err != nil {
 		if err == scom.ErrNotFound {
 			return ResponsePack(berr.SUCCESS)
@@ -565,6 +606,28 @@ func GetShardTxState(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+// get execution tree of cross shard tx by source tx hash
+func GetShardTxTrace(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Hash"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	trace, err := bactor.TraceShardTx(hash)
+	if err != nil {
+		if scom.ErrNotFound == err {
+			return ResponsePack(berr.UNKNOWN_TRANSACTION)
+		}
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp["Result"] = bcomn.ParseShardTxTrace(trace)
+	return resp
+}
+
 //get balance of address
 func GetBalance(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -576,7 +639,16 @@ func GetBalance(cmd map[string]interface{}) map[string]interface{} {
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	balance, err := bcomn.GetBalance(address)
+	var balance *bcomn.BalanceOfRsp
+	if height, ok := cmd["Height"].(string); ok && height != "" {
+		h, e := strconv.ParseUint(height, 10, 32)
+		if e != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		balance, err = bcomn.GetBalanceAtHeight(address, uint32(h))
+	} else {
+		balance, err = bcomn.GetBalance(address)
+	}
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
@@ -595,11 +667,8 @@ func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
-		return ResponsePack(berr.INVALID_PARAMS)
-	}
-	if tx == nil {
+	height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if err != nil && err != scom.ErrPruned {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	header, err := bactor.GetHeaderByHeight(height)
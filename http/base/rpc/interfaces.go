@@ -36,6 +36,7 @@ import (
 	bcomn "github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	cstates "github.com/ontio/ontology/smartcontract/states"
 )
 
 //get best block hash
@@ -73,6 +74,9 @@ func GetBlock(params []interface{}) map[string]interface{} {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	block, err := bactor.GetBlockFromStore(hash)
+	if err == scom.ErrPruned {
+		return responsePack(berr.DATA_PRUNED, "block pruned")
+	}
 	if err != nil {
 		return responsePack(berr.UNKNOWN_BLOCK, "unknown block")
 	}
@@ -90,6 +94,39 @@ func GetBlock(params []interface{}) map[string]interface{} {
 	return responseSuccess(common.ToHexString(block.ToArray()))
 }
 
+// get raw block header by height or hash, used by light client to follow the chain
+// Input JSON string examples for getheader method as following:
+//   {"jsonrpc": "2.0", "method": "getheader", "params": [1], "id": 0}
+//   {"jsonrpc": "2.0", "method": "getheader", "params": ["aabbcc.."], "id": 0}
+func GetHeader(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	var header *types.Header
+	var hash common.Uint256
+	var err error
+	switch (params[0]).(type) {
+	// block height
+	case float64:
+		header, err = bactor.GetHeaderByHeight(uint32(params[0].(float64)))
+		// block hash
+	case string:
+		hash, err = common.Uint256FromHexString(params[0].(string))
+		if err != nil {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		header, err = bactor.GetHeaderByHash(hash)
+	default:
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if err != nil || header == nil {
+		return responsePack(berr.UNKNOWN_BLOCK, "unknown block")
+	}
+	sink := common.NewZeroCopySink(0)
+	header.Serialization(sink)
+	return responseSuccess(common.ToHexString(sink.Bytes()))
+}
+
 //get block height
 func GetBlockCount(params []interface{}) map[string]interface{} {
 	height := bactor.GetCurrentBlockHeight()
@@ -179,6 +216,9 @@ func GetRawTransaction(params []interface{}) map[string]interface{} {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		h, t, err := bactor.GetTxnWithHeightByTxHash(hash)
+		if err == scom.ErrPruned {
+			return responsePack(berr.DATA_PRUNED, "transaction pruned")
+		}
 		if err != nil {
 			return responsePack(berr.UNKNOWN_TRANSACTION, "unknown transaction")
 		}
@@ -208,6 +248,7 @@ func GetRawTransaction(params []interface{}) map[string]interface{} {
 
 //get storage from contract
 //   {"jsonrpc": "2.0", "method": "getstorage", "params": ["code hash", "key"], "id": 0}
+//   {"jsonrpc": "2.0", "method": "getstorage", "params": ["code hash", "key", height], "id": 0}
 func GetStorage(params []interface{}) map[string]interface{} {
 	if len(params) < 2 {
 		return responsePack(berr.INVALID_PARAMS, nil)
@@ -238,7 +279,17 @@ func GetStorage(params []interface{}) map[string]interface{} {
 	default:
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
-	value, err := bactor.GetStorageItem(address, key)
+	var value []byte
+	var err error
+	if len(params) > 2 {
+		height, ok := params[2].(float64)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		value, err = bactor.GetStorageItemAtHeight(address, key, uint32(height))
+	} else {
+		value, err = bactor.GetStorageItem(address, key)
+	}
 	if err != nil {
 		if err == scom.ErrNotFound {
 			return responseSuccess(nil)
@@ -353,9 +404,35 @@ func GetShardTxState(params []interface{}) map[string]interface{} {
 	return responseSuccess(r)
 }
 
+// get execution tree of cross shard tx, aggregated from all shard ledgers of node
+// A JSON example: {"jsonrpc": "2.0", "method": "getshardtxtrace", "params": ["source tx hash"], "id": 0}
+func GetShardTxTrace(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	trace, err := bactor.TraceShardTx(hash)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return responseSuccess(nil)
+		}
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	return responseSuccess(bcomn.ParseShardTxTrace(trace))
+}
+
 //send raw transaction
 // A JSON example for sendrawtransaction method as following:
 //   {"jsonrpc": "2.0", "method": "sendrawtransaction", "params": ["raw transactioin in hex"], "id": 0}
+// pre-execute the transaction on the states at history block height:
+//   {"jsonrpc": "2.0", "method": "sendrawtransaction", "params": ["raw transactioin in hex", 1, height], "id": 0}
 func SendRawTransaction(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
 		return responsePack(berr.INVALID_PARAMS, nil)
@@ -378,7 +455,16 @@ func SendRawTransaction(params []interface{}) map[string]interface{} {
 			if len(params) > 1 {
 				preExec, ok := params[1].(float64)
 				if ok && preExec == 1 {
-					result, err := bactor.PreExecuteContract(txn)
+					var result *cstates.PreExecResult
+					if len(params) > 2 {
+						height, ok := params[2].(float64)
+						if !ok {
+							return responsePack(berr.INVALID_PARAMS, "")
+						}
+						result, err = bactor.PreExecuteContractAtHeight(txn, uint32(height))
+					} else {
+						result, err = bactor.PreExecuteContract(txn)
+					}
 					if err != nil {
 						log.Infof("PreExec: ", err)
 						return responsePack(berr.SMARTCODE_ERROR, err.Error())
@@ -495,6 +581,9 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 			if err == scom.ErrNotFound {
 				return responseSuccess(nil)
 			}
+			if err == scom.ErrPruned {
+				return responsePack(berr.DATA_PRUNED, "event pruned")
+			}
 			return responsePack(berr.INTERNAL_ERROR, "")
 		}
 		eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
@@ -515,6 +604,9 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 			if scom.ErrNotFound == err {
 				return responseSuccess(nil)
 			}
+			if scom.ErrPruned == err {
+				return responsePack(berr.DATA_PRUNED, "event pruned")
+			}
 			return responsePack(berr.INTERNAL_ERROR, "")
 		}
 		_, notify := bcomn.GetExecuteNotify(eventInfo)
@@ -554,6 +646,9 @@ func GetShardSmartCodeEvent(params []interface{}) map[string]interface{} {
 			if scom.ErrNotFound == err {
 				return responseSuccess(nil)
 			}
+			if scom.ErrPruned == err {
+				return responsePack(berr.DATA_PRUNED, "event pruned")
+			}
 			return responsePack(berr.INTERNAL_ERROR, "")
 		}
 		_, notify := bcomn.GetExecuteNotify(eventInfo)
@@ -586,7 +681,7 @@ func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
-		if err != nil {
+		if err != nil && err != scom.ErrPruned {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		return responseSuccess(height)
@@ -615,7 +710,16 @@ func GetBalance(params []interface{}) map[string]interface{} {
 	if err != nil {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
-	rsp, err := bcomn.GetBalance(address)
+	var rsp *bcomn.BalanceOfRsp
+	if len(params) > 1 {
+		height, ok := params[1].(float64)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		rsp, err = bcomn.GetBalanceAtHeight(address, uint32(height))
+	} else {
+		rsp, err = bcomn.GetBalance(address)
+	}
 	if err != nil {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
@@ -668,7 +772,7 @@ func GetMerkleProof(params []interface{}) map[string]interface{} {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
+	if err != nil && err != scom.ErrPruned {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	header, err := bactor.GetHeaderByHeight(height)
@@ -693,6 +797,51 @@ func GetMerkleProof(params []interface{}) map[string]interface{} {
 		curHeader.BlockRoot.ToHexString(), curHeight, hashes})
 }
 
+//get storage value and its merkle proof against the storage trie root at block height, which is
+//committed in the header of next block. Default height is current block height
+//   {"jsonrpc": "2.0", "method": "getstorageproof", "params": ["code hash", "key", height], "id": 0}
+func GetStorageProof(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok = params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, err := hex.DecodeString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height := bactor.GetCurrentBlockHeight()
+	if len(params) > 2 {
+		h, ok := params[2].(float64)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		height = uint32(h)
+	}
+	root, err := bactor.GetStorageTrieRoot(height)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	value, proof, err := bactor.GetStorageProof(address, key, height)
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	sink := common.NewZeroCopySink(0)
+	proof.Serialization(sink)
+	return responseSuccess(bcomn.StorageProof{height, root.ToHexString(), common.ToHexString(value),
+		common.ToHexString(sink.Bytes())})
+}
+
 //get block transactions by height
 func GetBlockTxsByHeight(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -706,6 +855,9 @@ func GetBlockTxsByHeight(params []interface{}) map[string]interface{} {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		block, err := bactor.GetBlockFromStore(hash)
+		if err == scom.ErrPruned {
+			return responsePack(berr.DATA_PRUNED, "block pruned")
+		}
 		if err != nil {
 			return responsePack(berr.UNKNOWN_BLOCK, "")
 		}
@@ -36,6 +36,7 @@ func StartRPCServer() error {
 
 	rpc.HandleFunc("getbestblockhash", rpc.GetBestBlockHash)
 	rpc.HandleFunc("getblock", rpc.GetBlock)
+	rpc.HandleFunc("getheader", rpc.GetHeader)
 	rpc.HandleFunc("getblockcount", rpc.GetBlockCount)
 	rpc.HandleFunc("getblockhash", rpc.GetBlockHash)
 	rpc.HandleFunc("getconnectioncount", rpc.GetConnectionCount)
@@ -58,6 +59,7 @@ func StartRPCServer() error {
 	rpc.HandleFunc("getbalance", rpc.GetBalance)
 	rpc.HandleFunc("getallowance", rpc.GetAllowance)
 	rpc.HandleFunc("getmerkleproof", rpc.GetMerkleProof)
+	rpc.HandleFunc("getstorageproof", rpc.GetStorageProof)
 	rpc.HandleFunc("getblocktxsbyheight", rpc.GetBlockTxsByHeight)
 	rpc.HandleFunc("getgasprice", rpc.GetGasPrice)
 	rpc.HandleFunc("getunboundong", rpc.GetUnboundOng)
@@ -65,6 +67,7 @@ func StartRPCServer() error {
 
 	rpc.HandleFunc("getshardstorage", rpc.GetShardStorage)
 	rpc.HandleFunc("getshardtxstate", rpc.GetShardTxState)
+	rpc.HandleFunc("getshardtxtrace", rpc.GetShardTxTrace)
 	err := http.ListenAndServe(":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpJsonPort)), nil)
 	if err != nil {
 		return fmt.Errorf("ListenAndServe error:%s", err)
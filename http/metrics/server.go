@@ -0,0 +1,46 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package metrics privides http server which exports node metrics in prometheus text format
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
+)
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := metrics.DefRegistry.WriteTo(w); err != nil {
+		log.Errorf("metrics handler: write metrics failed, err: %s", err)
+	}
+}
+
+// StartServer starts metrics server on configured port
+func StartServer() {
+	port := int(config.DefConfig.P2PNode.HttpMetricsPort)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", metricsHandler)
+	if err := http.ListenAndServe(":"+strconv.Itoa(port), mux); err != nil {
+		log.Errorf("metrics server: listen on port %d failed, err: %s", port, err)
+	}
+}
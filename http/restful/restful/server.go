@@ -66,6 +66,7 @@ const (
 	GET_SHARD_STORAGE      = "/api/v1/shardstorage/:shardid/:hash/:key"
 	GET_SHARD_TX_STATE_NID = "/api/v1/shardtxstate/:txhash/:notifyid"
 	GET_SHARD_TX_STATE     = "/api/v1/shardtxstate/:txhash"
+	GET_SHARD_TX_TRACE     = "/api/v1/shardtxtrace/:hash"
 	GET_BALANCE            = "/api/v1/balance/:addr"
 	GET_CONTRACT_STATE     = "/api/v1/contract/:hash"
 	GET_SMTCOCE_EVT_TXS    = "/api/v1/smartcode/event/transactions/:height"
@@ -157,6 +158,7 @@ func (this *restServer) registryMethod() {
 		GET_SHARD_STORAGE:      {name: "getshardstorage", handler: rest.GetShardStorage},
 		GET_SHARD_TX_STATE_NID: {name: "getshardtxstate", handler: rest.GetShardTxState},
 		GET_SHARD_TX_STATE:     {name: "getshardtxstate", handler: rest.GetShardTxState},
+		GET_SHARD_TX_TRACE:     {name: "getshardtxtrace", handler: rest.GetShardTxTrace},
 		GET_BALANCE:            {name: "getbalance", handler: rest.GetBalance},
 		GET_ALLOWANCE:          {name: "getallowance", handler: rest.GetAllowance},
 		GET_MERKLE_PROOF:       {name: "getmerkleproof", handler: rest.GetMerkleProof},
@@ -205,6 +207,8 @@ func (this *restServer) getPath(url string) string {
 		return GET_SHARD_TX_STATE_NID
 	} else if strings.Contains(url, strings.TrimRight(GET_SHARD_TX_STATE, ":txhash")) {
 		return GET_SHARD_TX_STATE
+	} else if strings.Contains(url, strings.TrimRight(GET_SHARD_TX_TRACE, ":hash")) {
+		return GET_SHARD_TX_TRACE
 	} else if strings.Contains(url, strings.TrimRight(GET_STORAGE, ":hash/:key")) {
 		return GET_STORAGE
 	} else if strings.Contains(url, strings.TrimRight(GET_BALANCE, ":addr")) {
@@ -241,15 +245,18 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 	case GET_CONTRACT_STATE:
 		req["Hash"], req["Raw"] = getParam(r, "hash"), r.FormValue("raw")
 	case POST_RAW_TX:
-		req["PreExec"] = r.FormValue("preExec")
+		req["PreExec"], req["Height"] = r.FormValue("preExec"), r.FormValue("height")
 	case GET_STORAGE:
 		req["Hash"], req["Key"] = getParam(r, "hash"), getParam(r, "key")
+		req["Height"] = r.FormValue("height")
 	case GET_SHARD_STORAGE:
 		req["ShardID"], req["Hash"], req["Key"] = getParam(r, "shardid"), getParam(r, "hash"), getParam(r, "key")
 	case GET_SHARD_TX_STATE_NID:
 		req["TxHash"], req["NotifyId"] = getParam(r, "txhash"), getParam(r, "notifyid")
 	case GET_SHARD_TX_STATE:
 		req["TxHash"] = getParam(r, "txhash")
+	case GET_SHARD_TX_TRACE:
+		req["Hash"] = getParam(r, "hash")
 	case GET_SMTCOCE_EVT_TXS:
 		req["Height"] = getParam(r, "height")
 	case GET_SMTCOCE_EVTS:
@@ -261,7 +268,7 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 	case GET_BLK_HGT_BY_TXHASH:
 		req["Hash"] = getParam(r, "hash")
 	case GET_BALANCE:
-		req["Addr"] = getParam(r, "addr")
+		req["Addr"], req["Height"] = getParam(r, "addr"), r.FormValue("height")
 	case GET_MERKLE_PROOF:
 		req["Hash"] = getParam(r, "hash")
 	case GET_ALLOWANCE:
@@ -20,10 +20,15 @@
 package websocket
 
 import (
+	"bytes"
+	"sync"
+
 	"github.com/ontio/ontology/common"
 	cfg "github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/xshard_types"
 	"github.com/ontio/ontology/events/message"
 	bactor "github.com/ontio/ontology/http/base/actor"
 	bcomn "github.com/ontio/ontology/http/base/common"
@@ -35,9 +40,21 @@ import (
 
 var ws *websocket.WsServer
 
+// last pushed TxState of subscribed shard tx ids, key: shardID and shard tx id
+var shardTxStates = struct {
+	sync.Mutex
+	m map[shardTxStateKey][]byte
+}{m: make(map[shardTxStateKey][]byte)}
+
+type shardTxStateKey struct {
+	shardID   common.ShardID
+	shardTxID string
+}
+
 func StartServer() {
 	bactor.SubscribeEvent(message.TOPIC_SAVE_BLOCK_COMPLETE, sendBlock2WSclient)
 	bactor.SubscribeEvent(message.TOPIC_SMART_CODE_EVENT, pushSmartCodeEvent)
+	bactor.SubscribeEvent(message.TOPIC_CROSS_SHARD_MSG, pushCrossShardMsg)
 	go func() {
 		ws = websocket.InitWsServer()
 		ws.Start()
@@ -48,6 +65,8 @@ func sendBlock2WSclient(v interface{}) {
 		go func() {
 			pushBlock(v)
 			pushBlockTransactions(v)
+			pushShardSysEvents(v)
+			pushShardTxStates(v)
 		}()
 	}
 }
@@ -126,3 +145,101 @@ func pushBlockTransactions(v interface{}) {
 		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_TXHASHS, resp)
 	}
 }
+
+func pushShardSysEvents(v interface{}) {
+	if ws == nil {
+		return
+	}
+	block, ok := v.(types.Block)
+	if !ok {
+		return
+	}
+	lgr := ledger.GetShardLedger(block.Header.ShardID)
+	if lgr == nil {
+		return
+	}
+	evts, err := lgr.GetBlockShardEvents(block.Header.Height)
+	if err != nil || len(evts) == 0 {
+		return
+	}
+	resp := rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "sendshardsysevent"
+	resp["Result"] = map[string]interface{}{
+		"ShardID": block.Header.ShardID.ToUint64(),
+		"Height":  block.Header.Height,
+		"Events":  bcomn.ParseShardSysEvents(evts),
+	}
+	ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_SHARD_SYS_EVENT, resp)
+}
+
+func pushShardTxStates(v interface{}) {
+	if ws == nil {
+		return
+	}
+	block, ok := v.(types.Block)
+	if !ok {
+		return
+	}
+	lgr := ledger.GetShardLedger(block.Header.ShardID)
+	if lgr == nil {
+		return
+	}
+	ids := ws.GetSubscribedShardTxIDs()
+
+	shardTxStates.Lock()
+	defer shardTxStates.Unlock()
+	for key := range shardTxStates.m {
+		if !ids[key.shardTxID] {
+			delete(shardTxStates.m, key)
+		}
+	}
+	for id := range ids {
+		rawID, err := common.HexToBytes(id)
+		if err != nil {
+			continue
+		}
+		state, err := lgr.GetShardTxStateByID(xshard_types.ShardTxID(string(rawID)))
+		if err != nil || state == nil {
+			continue
+		}
+		sink := common.NewZeroCopySink(0)
+		state.Serialization(sink)
+		key := shardTxStateKey{shardID: block.Header.ShardID, shardTxID: id}
+		if bytes.Equal(shardTxStates.m[key], sink.Bytes()) {
+			continue
+		}
+		shardTxStates.m[key] = sink.Bytes()
+
+		info, err := bcomn.ParseShardState(state)
+		if err != nil {
+			log.Errorf("[pushShardTxStates] parse tx state %s: %s", id, err)
+			continue
+		}
+		resp := rest.ResponsePack(Err.SUCCESS)
+		resp["Action"] = "sendshardtxstate"
+		resp["Result"] = map[string]interface{}{
+			"ShardID": block.Header.ShardID.ToUint64(),
+			"Height":  block.Header.Height,
+			"State":   info,
+		}
+		ws.PushShardTxState(id, resp)
+	}
+}
+
+func pushCrossShardMsg(v interface{}) {
+	if ws == nil || cfg.DefConfig.Ws.HttpWsPort == 0 {
+		return
+	}
+	evt, ok := v.(message.CrossShardMsgEvent)
+	if !ok || evt.Msg == nil {
+		log.Errorf("[pushCrossShardMsg] CrossShardMsgEvent err")
+		return
+	}
+	go func() {
+		resp := rest.ResponsePack(Err.SUCCESS)
+		resp["Action"] = "sendcrossshardmsg"
+		resp["Result"] = bcomn.ParseCrossShardMsg(&evt)
+		pair := websocket.ShardPair{FromShard: evt.FromShard.ToUint64(), ToShard: evt.ToShard.ToUint64()}
+		ws.BroadcastCrossShardMsg(pair, resp)
+	}()
+}
@@ -23,6 +23,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"strconv"
@@ -43,8 +44,15 @@ const (
 	WSTOPIC_JSON_BLOCK = 2
 	WSTOPIC_RAW_BLOCK  = 3
 	WSTOPIC_TXHASHS    = 4
+
+	WSTOPIC_SHARD_SYS_EVENT = 5
+	WSTOPIC_CROSS_SHARD_MSG = 6
 )
 
+// MAX_SHARD_TX_IDS_FILTER: max shard tx ids subscribed by one session, tx states of subscribed ids
+// are queried from ledger on every block
+const MAX_SHARD_TX_IDS_FILTER = 100
+
 type handler func(map[string]interface{}) map[string]interface{}
 type Handler struct {
 	handler  handler
@@ -58,6 +66,17 @@ type subscribe struct {
 	SubscribeJsonBlock    bool     `json:"SubscribeJsonBlock"`
 	SubscribeRawBlock     bool     `json:"SubscribeRawBlock"`
 	SubscribeBlockTxHashs bool     `json:"SubscribeBlockTxHashs"`
+
+	SubscribeShardSysEvent bool        `json:"SubscribeShardSysEvent"`
+	SubscribeCrossShardMsg bool        `json:"SubscribeCrossShardMsg"`
+	ShardPairsFilter       []ShardPair `json:"ShardPairsFilter"` // empty for all shard pairs
+	ShardTxIDsFilter       []string    `json:"ShardTxIDsFilter"` // push TxState transitions of these shard tx ids
+}
+
+// shard pair of cross shard msg, msgs sent from FromShard to ToShard
+type ShardPair struct {
+	FromShard uint64 `json:"FromShard"`
+	ToShard   uint64 `json:"ToShard"`
 }
 type WsServer struct {
 	sync.RWMutex
@@ -144,6 +163,12 @@ func (self *WsServer) registryMethod() {
 		defer self.Unlock()
 
 		sessionId, _ := cmd["SessionId"].(string)
+		if ids, ok := cmd["ShardTxIDsFilter"].([]interface{}); ok && len(ids) > MAX_SHARD_TX_IDS_FILTER {
+			resp = rest.ResponsePack(Err.INVALID_PARAMS)
+			resp["Action"] = "subscribe"
+			resp["Result"] = fmt.Sprintf("ShardTxIDsFilter exceeds max %d ids", MAX_SHARD_TX_IDS_FILTER)
+			return resp
+		}
 		sub := self.SubscribeMap[sessionId]
 		if b, ok := cmd["SubscribeEvent"].(bool); ok {
 			sub.SubscribeEvent = b
@@ -165,6 +190,34 @@ func (self *WsServer) registryMethod() {
 				}
 			}
 		}
+		if b, ok := cmd["SubscribeShardSysEvent"].(bool); ok {
+			sub.SubscribeShardSysEvent = b
+		}
+		if b, ok := cmd["SubscribeCrossShardMsg"].(bool); ok {
+			sub.SubscribeCrossShardMsg = b
+		}
+		if pairs, ok := cmd["ShardPairsFilter"].([]interface{}); ok {
+			sub.ShardPairsFilter = []ShardPair{}
+			for _, v := range pairs {
+				pair, k := v.(map[string]interface{})
+				if !k {
+					continue
+				}
+				from, k1 := pair["FromShard"].(float64)
+				to, k2 := pair["ToShard"].(float64)
+				if k1 && k2 {
+					sub.ShardPairsFilter = append(sub.ShardPairsFilter, ShardPair{FromShard: uint64(from), ToShard: uint64(to)})
+				}
+			}
+		}
+		if ids, ok := cmd["ShardTxIDsFilter"].([]interface{}); ok {
+			sub.ShardTxIDsFilter = []string{}
+			for _, v := range ids {
+				if id, k := v.(string); k {
+					sub.ShardTxIDsFilter = append(sub.ShardTxIDsFilter, id)
+				}
+			}
+		}
 		self.SubscribeMap[sessionId] = sub
 
 		resp["Action"] = "subscribe"
@@ -197,6 +250,7 @@ func (self *WsServer) registryMethod() {
 		"getstorage":                {handler: rest.GetStorage},
 		"getshardstorage":           {handler: rest.GetShardStorage},
 		"getshardtxstate":           {handler: rest.GetShardTxState},
+		"getshardtxtrace":           {handler: rest.GetShardTxTrace},
 		"getallowance":              {handler: rest.GetAllowance},
 		"getmerkleproof":            {handler: rest.GetMerkleProof},
 		"getblocktxsbyheight":       {handler: rest.GetBlockTxsByHeight},
@@ -433,6 +487,8 @@ func (self *WsServer) BroadcastToSubscribers(contractAddrs map[string]bool, sub
 			s.Send(data)
 		} else if sub == WSTOPIC_TXHASHS && v.SubscribeBlockTxHashs {
 			s.Send(data)
+		} else if sub == WSTOPIC_SHARD_SYS_EVENT && v.SubscribeShardSysEvent {
+			s.Send(data)
 		} else if sub == WSTOPIC_EVENT && v.SubscribeEvent {
 			if len(v.ContractsFilter) == 0 {
 				s.Send(data)
@@ -448,6 +504,64 @@ func (self *WsServer) BroadcastToSubscribers(contractAddrs map[string]bool, sub
 	}
 }
 
+// broadcast cross shard msg of shard pair to subscribers
+func (self *WsServer) BroadcastCrossShardMsg(pair ShardPair, resp map[string]interface{}) {
+	self.Lock()
+	defer self.Unlock()
+	data := marshalResp(resp)
+	for sid, v := range self.SubscribeMap {
+		if !v.SubscribeCrossShardMsg {
+			continue
+		}
+		s := self.SessionList.GetSessionById(sid)
+		if s == nil {
+			continue
+		}
+		if len(v.ShardPairsFilter) == 0 {
+			s.Send(data)
+			continue
+		}
+		for _, p := range v.ShardPairsFilter {
+			if p == pair {
+				s.Send(data)
+				break
+			}
+		}
+	}
+}
+
+// get all shard tx ids subscribed by sessions
+func (self *WsServer) GetSubscribedShardTxIDs() map[string]bool {
+	self.RLock()
+	defer self.RUnlock()
+	ids := make(map[string]bool)
+	for _, v := range self.SubscribeMap {
+		for _, id := range v.ShardTxIDsFilter {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// push TxState of shard tx id to subscribers
+func (self *WsServer) PushShardTxState(shardTxID string, resp map[string]interface{}) {
+	self.Lock()
+	defer self.Unlock()
+	data := marshalResp(resp)
+	for sid, v := range self.SubscribeMap {
+		s := self.SessionList.GetSessionById(sid)
+		if s == nil {
+			continue
+		}
+		for _, id := range v.ShardTxIDsFilter {
+			if id == shardTxID {
+				s.Send(data)
+				break
+			}
+		}
+	}
+}
+
 func (self *WsServer) initTlsListen() (net.Listener, error) {
 
 	certPath := cfg.DefConfig.Ws.HttpCertPath
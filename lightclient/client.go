@@ -0,0 +1,230 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package lightclient
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+)
+
+// Client syncs headers from a full node and verifies data the node returns against them
+type Client struct {
+	rpc      *rpcClient
+	store    *HeaderStore
+	verifier *HeaderVerifier
+}
+
+// NewClient creates light client following full node at rpcAddr. genesis is the trust anchor,
+// which is built from local config rather than fetched from the node.
+func NewClient(rpcAddr string, store *HeaderStore, genesis *types.Header) (*Client, error) {
+	current, err := store.GetCurrentHeader()
+	if err == scom.ErrNotFound {
+		if _, err := chainConfig(genesis); err != nil {
+			return nil, fmt.Errorf("invalid genesis header: %s", err)
+		}
+		if err := store.SaveHeader(genesis); err != nil {
+			return nil, fmt.Errorf("save genesis header: %s", err)
+		}
+		current = genesis
+	} else if err != nil {
+		return nil, fmt.Errorf("get current header: %s", err)
+	} else {
+		stored, err := store.GetHeaderByHeight(0)
+		if err != nil {
+			return nil, fmt.Errorf("get genesis header: %s", err)
+		}
+		if stored.Hash() != genesis.Hash() {
+			return nil, fmt.Errorf("genesis header unmatched with header store")
+		}
+	}
+
+	cfgHeight, err := configHeight(current)
+	if err != nil {
+		return nil, err
+	}
+	cfgHeader, err := store.GetHeaderByHeight(cfgHeight)
+	if err != nil {
+		return nil, fmt.Errorf("get config header %d: %s", cfgHeight, err)
+	}
+	cfg, err := chainConfig(cfgHeader)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		rpc:      &rpcClient{addr: rpcAddr},
+		store:    store,
+		verifier: NewHeaderVerifier(current, cfg),
+	}, nil
+}
+
+// CurrentHeight returns height of the last verified header
+func (self *Client) CurrentHeight() uint32 {
+	return self.verifier.Current().Height
+}
+
+// GetHeader returns verified header at height
+func (self *Client) GetHeader(height uint32) (*types.Header, error) {
+	return self.store.GetHeaderByHeight(height)
+}
+
+// Sync fetches and verifies headers up to current height of full node
+func (self *Client) Sync() error {
+	var count uint32
+	if err := self.rpc.call("getblockcount", &count); err != nil {
+		return err
+	}
+	for height := self.CurrentHeight() + 1; height < count; height++ {
+		header, err := self.fetchHeader(height)
+		if err != nil {
+			return err
+		}
+		if err := self.verifier.Verify(header); err != nil {
+			return fmt.Errorf("verify header %d: %s", height, err)
+		}
+		if err := self.store.SaveHeader(header); err != nil {
+			return fmt.Errorf("save header %d: %s", height, err)
+		}
+		log.Debugf("light client: header %d verified", height)
+	}
+	return nil
+}
+
+func (self *Client) fetchHeader(height uint32) (*types.Header, error) {
+	var raw string
+	if err := self.rpc.call("getheader", &raw, height); err != nil {
+		return nil, err
+	}
+	data, err := common.HexToBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode header %d: %s", height, err)
+	}
+	return types.HeaderFromRawBytes(data)
+}
+
+// StateRoot returns state merkle root at height, which is committed by bookkeepers in next header
+func (self *Client) StateRoot(height uint32) (common.Uint256, error) {
+	header, err := self.store.GetHeaderByHeight(height + 1)
+	if err != nil {
+		return common.UINT256_EMPTY, fmt.Errorf("header %d not synced: %s", height+1, err)
+	}
+	return committedStateRoot(header)
+}
+
+// VerifyStateRoot checks state merkle root returned by full node for height
+func (self *Client) VerifyStateRoot(height uint32, root common.Uint256) error {
+	committed, err := self.StateRoot(height)
+	if err != nil {
+		return err
+	}
+	if committed != root {
+		return fmt.Errorf("unmatched state root at height %d: %s vs %s", height, root.ToHexString(), committed.ToHexString())
+	}
+	return nil
+}
+
+// StorageRoot returns storage trie root at height, which is committed by bookkeepers in next header
+func (self *Client) StorageRoot(height uint32) (common.Uint256, error) {
+	header, err := self.store.GetHeaderByHeight(height + 1)
+	if err != nil {
+		return common.UINT256_EMPTY, fmt.Errorf("header %d not synced: %s", height+1, err)
+	}
+	return committedStorageRoot(header)
+}
+
+// GetStorage queries storage value of contract at height from full node, and verifies it with merkle proof
+// against the committed storage trie root. It returns nil if key does not exist
+func (self *Client) GetStorage(address common.Address, key []byte, height uint32) ([]byte, error) {
+	root, err := self.StorageRoot(height)
+	if err != nil {
+		return nil, err
+	}
+	var storageProof struct {
+		Height      uint32
+		StorageRoot string
+		Value       string
+		Proof       string
+	}
+	if err := self.rpc.call("getstorageproof", &storageProof, address.ToHexString(), common.ToHexString(key),
+		height); err != nil {
+		return nil, err
+	}
+	raw, err := common.HexToBytes(storageProof.Value)
+	if err != nil {
+		return nil, fmt.Errorf("decode storage value: %s", err)
+	}
+	data, err := common.HexToBytes(storageProof.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("decode storage proof: %s", err)
+	}
+	proof := &merkle.SparseMerkleProof{}
+	if err := proof.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("deserialize storage proof: %s", err)
+	}
+	trieKey := append([]byte{byte(scom.ST_STORAGE)}, append(address[:], key...)...)
+	if err := merkle.VerifySparseMerkleProof(root, trieKey, raw, proof); err != nil {
+		return nil, fmt.Errorf("verify storage proof at height %d: %s", height, err)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return states.GetValueFromRawStorageItem(raw)
+}
+
+// VerifyTransaction checks transaction is included in block of verified header, and returns block height
+func (self *Client) VerifyTransaction(txHash common.Uint256) (uint32, error) {
+	var height uint32
+	if err := self.rpc.call("getblockheightbytxhash", &height, txHash.ToHexString()); err != nil {
+		return 0, err
+	}
+	header, err := self.store.GetHeaderByHeight(height)
+	if err != nil {
+		return 0, fmt.Errorf("header %d not synced: %s", height, err)
+	}
+	var blockTxs struct {
+		Hash         string
+		Height       uint32
+		Transactions []string
+	}
+	if err := self.rpc.call("getblocktxsbyheight", &blockTxs, height); err != nil {
+		return 0, err
+	}
+	hashes := make([]common.Uint256, 0, len(blockTxs.Transactions))
+	found := false
+	for _, str := range blockTxs.Transactions {
+		hash, err := common.Uint256FromHexString(str)
+		if err != nil {
+			return 0, fmt.Errorf("invalid tx hash %s: %s", str, err)
+		}
+		found = found || hash == txHash
+		hashes = append(hashes, hash)
+	}
+	if !found {
+		return 0, fmt.Errorf("tx %s not in block %d", txHash.ToHexString(), height)
+	}
+	if common.ComputeMerkleRoot(hashes) != header.TransactionsRoot {
+		return 0, fmt.Errorf("unmatched transactions root of block %d", height)
+	}
+	return height, nil
+}
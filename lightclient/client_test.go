@@ -0,0 +1,251 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package lightclient
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPeers(n int) ([]*account.Account, *vconfig.ChainConfig) {
+	accs := make([]*account.Account, 0, n)
+	cfg := &vconfig.ChainConfig{N: uint32(n)}
+	for i := 0; i < n; i++ {
+		acc := account.NewAccount("")
+		accs = append(accs, acc)
+		cfg.Peers = append(cfg.Peers, &vconfig.PeerConfig{Index: uint32(i + 1), ID: vconfig.PubkeyID(acc.PublicKey)})
+	}
+	return accs, cfg
+}
+
+func newTestHeader(t *testing.T, prev *types.Header, info *vconfig.VbftBlockInfo, signers ...*account.Account) *types.Header {
+	payload, err := json.Marshal(info)
+	assert.Nil(t, err)
+	header := &types.Header{
+		Version:          common.CURR_HEADER_VERSION,
+		TransactionsRoot: common.ComputeMerkleRoot(nil),
+		Timestamp:        1,
+		ConsensusPayload: payload,
+	}
+	if prev != nil {
+		header.PrevBlockHash = prev.Hash()
+		header.Height = prev.Height + 1
+		header.Timestamp = prev.Timestamp + 1
+	}
+	hash := header.Hash()
+	for _, signer := range signers {
+		sig, err := signature.Sign(signer, hash[:])
+		assert.Nil(t, err)
+		header.Bookkeepers = append(header.Bookkeepers, signer.PublicKey)
+		header.SigData = append(header.SigData, sig)
+	}
+	return header
+}
+
+func newTestGenesis(t *testing.T, cfg *vconfig.ChainConfig) *types.Header {
+	return newTestHeader(t, nil, &vconfig.VbftBlockInfo{LastConfigBlockNum: math.MaxUint32, NewChainConfig: cfg})
+}
+
+func TestHeaderVerifier(t *testing.T) {
+	accs, cfg := newTestPeers(4)
+	genesis := newTestGenesis(t, cfg)
+	verifier := NewHeaderVerifier(genesis, cfg)
+
+	header1 := newTestHeader(t, genesis, &vconfig.VbftBlockInfo{LastConfigBlockNum: 0}, accs[0])
+	assert.Nil(t, verifier.Verify(header1))
+	assert.Equal(t, header1.Hash(), verifier.Current().Hash())
+
+	// not following current header
+	assert.NotNil(t, verifier.Verify(header1))
+
+	// signed by unknown peer
+	others, newCfg := newTestPeers(4)
+	header2 := newTestHeader(t, header1, &vconfig.VbftBlockInfo{LastConfigBlockNum: 0}, others[0])
+	assert.NotNil(t, verifier.Verify(header2))
+
+	// chain config changed by header2, header3 should be signed by new peers
+	header2 = newTestHeader(t, header1, &vconfig.VbftBlockInfo{LastConfigBlockNum: 2, NewChainConfig: newCfg}, accs[1])
+	assert.Nil(t, verifier.Verify(header2))
+	header3 := newTestHeader(t, header2, &vconfig.VbftBlockInfo{LastConfigBlockNum: 2}, accs[0])
+	assert.NotNil(t, verifier.Verify(header3))
+	header3 = newTestHeader(t, header2, &vconfig.VbftBlockInfo{LastConfigBlockNum: 2}, others[0])
+	assert.Nil(t, verifier.Verify(header3))
+}
+
+func TestHeaderVerifierPrevStateRoot(t *testing.T) {
+	genesisConfig := config.DefConfig.Genesis
+	config.DefConfig.Genesis = &config.GenesisConfig{VBFT: &config.VBFTConfig{StateRootHeight: 1, StorageRootHeight: 1}}
+	defer func() { config.DefConfig.Genesis = genesisConfig }()
+
+	accs, cfg := newTestPeers(4)
+	genesis := newTestGenesis(t, cfg)
+	verifier := NewHeaderVerifier(genesis, cfg)
+
+	// state root and storage root are required from the check height
+	root := common.Uint256{1}
+	header1 := newTestHeader(t, genesis, &vconfig.VbftBlockInfo{LastConfigBlockNum: 0, PrevStorageRoot: root[:]}, accs[0])
+	assert.NotNil(t, verifier.Verify(header1))
+	header1 = newTestHeader(t, genesis, &vconfig.VbftBlockInfo{LastConfigBlockNum: 0, PrevStateRoot: root[:]}, accs[0])
+	assert.NotNil(t, verifier.Verify(header1))
+	header1 = newTestHeader(t, genesis, &vconfig.VbftBlockInfo{LastConfigBlockNum: 0, PrevStateRoot: root[:],
+		PrevStorageRoot: root[:]}, accs[0])
+	assert.Nil(t, verifier.Verify(header1))
+}
+
+type testNodeStore map[common.Uint256][]byte
+
+func (self testNodeStore) GetNode(hash common.Uint256) ([]byte, error) {
+	node, ok := self[hash]
+	if !ok {
+		return nil, fmt.Errorf("node %s not found", hash.ToHexString())
+	}
+	return node, nil
+}
+
+func (self testNodeStore) PutNode(hash common.Uint256, node []byte) {
+	self[hash] = node
+}
+
+// newTestNode returns json rpc server of full node, storage proofs are generated from trie
+func newTestNode(t *testing.T, headers []*types.Header, trie *merkle.SparseMerkleTree) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &jsonRpcRequest{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(req))
+		var result interface{}
+		switch req.Method {
+		case "getblockcount":
+			result = len(headers)
+		case "getheader":
+			sink := common.NewZeroCopySink(0)
+			headers[int(req.Params[0].(float64))].Serialization(sink)
+			result = common.ToHexString(sink.Bytes())
+		case "getstorageproof":
+			address, err := common.AddressFromHexString(req.Params[0].(string))
+			assert.Nil(t, err)
+			key, err := hex.DecodeString(req.Params[1].(string))
+			assert.Nil(t, err)
+			value, proof, err := trie.Prove(append([]byte{byte(scom.ST_STORAGE)}, append(address[:], key...)...))
+			assert.Nil(t, err)
+			sink := common.NewZeroCopySink(0)
+			proof.Serialization(sink)
+			root := trie.Root()
+			result = map[string]interface{}{
+				"Height":      req.Params[2],
+				"StorageRoot": root.ToHexString(),
+				"Value":       common.ToHexString(value),
+				"Proof":       common.ToHexString(sink.Bytes()),
+			}
+		}
+		data, _ := json.Marshal(result)
+		json.NewEncoder(w).Encode(&jsonRpcResponse{Result: data})
+	}))
+}
+
+func TestClientSync(t *testing.T) {
+	accs, cfg := newTestPeers(4)
+	genesis := newTestGenesis(t, cfg)
+	stateRoot := common.Uint256{1, 2, 3}
+	headers := []*types.Header{genesis}
+	for i := 0; i < 3; i++ {
+		info := &vconfig.VbftBlockInfo{LastConfigBlockNum: 0, PrevStateRoot: stateRoot[:]}
+		headers = append(headers, newTestHeader(t, headers[i], info, accs[i]))
+	}
+	node := newTestNode(t, headers, nil)
+	defer node.Close()
+
+	store := NewMemHeaderStore()
+	client, err := NewClient(node.URL, store, genesis)
+	assert.Nil(t, err)
+	assert.Nil(t, client.Sync())
+	assert.Equal(t, uint32(3), client.CurrentHeight())
+
+	header, err := client.GetHeader(2)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[2].Hash(), header.Hash())
+	assert.Nil(t, client.VerifyStateRoot(1, stateRoot))
+	assert.NotNil(t, client.VerifyStateRoot(1, common.Uint256{4}))
+	// state root of current block is not committed yet
+	_, err = client.StateRoot(3)
+	assert.NotNil(t, err)
+
+	// restart from header store
+	client, err = NewClient(node.URL, store, genesis)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), client.CurrentHeight())
+	_, err = NewClient(node.URL, store, headers[1])
+	assert.NotNil(t, err)
+}
+
+func TestClientGetStorage(t *testing.T) {
+	address := common.Address{1, 2, 3}
+	trie := merkle.NewSparseMerkleTree(common.UINT256_EMPTY, make(testNodeStore))
+	var keys, values [][]byte
+	for i := 0; i < 10; i++ {
+		keys = append(keys, append([]byte{byte(scom.ST_STORAGE)}, append(address[:], byte(i))...))
+		values = append(values, states.GenRawStorageItem([]byte{byte(i), byte(i)}))
+	}
+	assert.Nil(t, trie.Update(keys, values))
+	storageRoot := trie.Root()
+
+	accs, cfg := newTestPeers(4)
+	genesis := newTestGenesis(t, cfg)
+	headers := []*types.Header{genesis}
+	for i := 0; i < 3; i++ {
+		info := &vconfig.VbftBlockInfo{LastConfigBlockNum: 0, PrevStorageRoot: storageRoot[:]}
+		if i == 2 {
+			// storage root of block 2 is not the one of trie
+			info.PrevStorageRoot = common.UINT256_EMPTY[:]
+		}
+		headers = append(headers, newTestHeader(t, headers[i], info, accs[i]))
+	}
+	node := newTestNode(t, headers, trie)
+	defer node.Close()
+
+	client, err := NewClient(node.URL, NewMemHeaderStore(), genesis)
+	assert.Nil(t, err)
+	assert.Nil(t, client.Sync())
+
+	value, err := client.GetStorage(address, []byte{1}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 1}, value)
+	value, err = client.GetStorage(address, []byte{10}, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+	_, err = client.GetStorage(address, []byte{1}, 2)
+	assert.NotNil(t, err)
+	// storage root of current block is not committed yet
+	_, err = client.GetStorage(address, []byte{1}, 3)
+	assert.NotNil(t, err)
+}
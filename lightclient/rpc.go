@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package lightclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+const JSON_RPC_VERSION = "2.0"
+
+type jsonRpcRequest struct {
+	Version string        `json:"jsonrpc"`
+	Id      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type jsonRpcResponse struct {
+	Error  int64           `json:"error"`
+	Desc   string          `json:"desc"`
+	Result json.RawMessage `json:"result"`
+}
+
+// rpcClient queries full node with json rpc
+type rpcClient struct {
+	addr string
+}
+
+func (self *rpcClient) call(method string, result interface{}, params ...interface{}) error {
+	data, err := json.Marshal(&jsonRpcRequest{
+		Version: JSON_RPC_VERSION,
+		Id:      "light",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal %s request: %s", method, err)
+	}
+	resp, err := http.Post(self.addr, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %s", method, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read %s response: %s", method, err)
+	}
+	rsp := &jsonRpcResponse{}
+	if err := json.Unmarshal(body, rsp); err != nil {
+		return fmt.Errorf("unmarshal %s response %s: %s", method, body, err)
+	}
+	if rsp.Error != 0 {
+		return fmt.Errorf("%s error %d: %s", method, rsp.Error, rsp.Desc)
+	}
+	return json.Unmarshal(rsp.Result, result)
+}
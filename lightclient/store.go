@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package lightclient
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/types"
+)
+
+// HeaderStore persists verified headers of light client
+type HeaderStore struct {
+	store scom.PersistStore
+}
+
+// NewHeaderStore opens header store at path
+func NewHeaderStore(path string) (*HeaderStore, error) {
+	store, err := leveldbstore.NewLevelDBStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &HeaderStore{store: store}, nil
+}
+
+// NewMemHeaderStore creates header store in memory, for test
+func NewMemHeaderStore() *HeaderStore {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	return &HeaderStore{store: store}
+}
+
+// SaveHeader saves header and moves current header to it
+func (self *HeaderStore) SaveHeader(header *types.Header) error {
+	hash := header.Hash()
+	sink := common.NewZeroCopySink(0)
+	header.Serialization(sink)
+
+	self.store.NewBatch()
+	self.store.BatchPut(genHeaderKey(hash), sink.Bytes())
+	self.store.BatchPut(genBlockHashKey(header.Height), hash[:])
+	self.store.BatchPut([]byte{byte(scom.SYS_CURRENT_BLOCK)}, hash[:])
+	return self.store.BatchCommit()
+}
+
+// GetHeaderByHash returns header by block hash
+func (self *HeaderStore) GetHeaderByHash(hash common.Uint256) (*types.Header, error) {
+	data, err := self.store.Get(genHeaderKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	return types.HeaderFromRawBytes(data)
+}
+
+// GetHeaderByHeight returns header by block height
+func (self *HeaderStore) GetHeaderByHeight(height uint32) (*types.Header, error) {
+	data, err := self.store.Get(genBlockHashKey(height))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := common.Uint256ParseFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetHeaderByHash(hash)
+}
+
+// GetCurrentHeader returns the last saved header, scom.ErrNotFound if store is empty
+func (self *HeaderStore) GetCurrentHeader() (*types.Header, error) {
+	data, err := self.store.Get([]byte{byte(scom.SYS_CURRENT_BLOCK)})
+	if err != nil {
+		return nil, err
+	}
+	hash, err := common.Uint256ParseFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse current block hash: %s", err)
+	}
+	return self.GetHeaderByHash(hash)
+}
+
+// Close closes the store
+func (self *HeaderStore) Close() error {
+	return self.store.Close()
+}
+
+func genHeaderKey(hash common.Uint256) []byte {
+	key := make([]byte, 1+common.UINT256_SIZE)
+	key[0] = byte(scom.DATA_HEADER)
+	copy(key[1:], hash[:])
+	return key
+}
+
+func genBlockHashKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.DATA_BLOCK)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
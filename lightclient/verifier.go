@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package lightclient follows vbft block headers without executing blocks. Headers are checked
+// against the bookkeepers of the chain config in effect, which is switched at config blocks, so
+// a client only needs to trust the genesis header of the chain.
+package lightclient
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ontio/ontology/common"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/types"
+)
+
+// HeaderVerifier verifies headers one by one from a trusted header
+type HeaderVerifier struct {
+	peers   map[string]uint32 // pubkey id => peer index of current chain config
+	current *types.Header
+}
+
+// NewHeaderVerifier creates verifier starting from trusted header, cfg is the chain config
+// used to sign the next header
+func NewHeaderVerifier(trusted *types.Header, cfg *vconfig.ChainConfig) *HeaderVerifier {
+	return &HeaderVerifier{
+		peers:   vconfig.ChainConfigPeers(cfg),
+		current: trusted,
+	}
+}
+
+// Current returns the last verified header
+func (self *HeaderVerifier) Current() *types.Header {
+	return self.current
+}
+
+// Verify checks header follows the last verified header, and moves to it on success
+func (self *HeaderVerifier) Verify(header *types.Header) error {
+	if header.ShardID != self.current.ShardID {
+		return fmt.Errorf("unmatched shard id: %d vs %d", header.ShardID.ToUint64(), self.current.ShardID.ToUint64())
+	}
+	if header.Height != self.current.Height+1 {
+		return fmt.Errorf("block height is incorrect: %d, expect %d", header.Height, self.current.Height+1)
+	}
+	if header.PrevBlockHash != self.current.Hash() {
+		return fmt.Errorf("unmatched prev block hash at height %d", header.Height)
+	}
+	if header.Timestamp <= self.current.Timestamp {
+		return fmt.Errorf("block timestamp is incorrect at height %d", header.Height)
+	}
+	peers, err := vconfig.VerifyVbftHeader(header, self.peers)
+	if err != nil {
+		return err
+	}
+	self.peers = peers
+	self.current = header
+	return nil
+}
+
+// configHeight returns height of the block carrying chain config which signs the next block of header
+func configHeight(header *types.Header) (uint32, error) {
+	info, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return 0, err
+	}
+	if info.NewChainConfig != nil {
+		return header.Height, nil
+	}
+	if info.LastConfigBlockNum == math.MaxUint32 {
+		// chain config not changed since genesis
+		return 0, nil
+	}
+	return info.LastConfigBlockNum, nil
+}
+
+// chainConfig returns chain config carried in config block header
+func chainConfig(header *types.Header) (*vconfig.ChainConfig, error) {
+	info, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return nil, err
+	}
+	if info.NewChainConfig == nil {
+		return nil, fmt.Errorf("no chain config in block %d", header.Height)
+	}
+	return info.NewChainConfig, nil
+}
+
+// committedStateRoot returns state merkle root of previous block committed in header
+func committedStateRoot(header *types.Header) (common.Uint256, error) {
+	info, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if len(info.PrevStateRoot) == 0 {
+		return common.UINT256_EMPTY, fmt.Errorf("no state root committed in block %d", header.Height)
+	}
+	return common.Uint256ParseFromBytes(info.PrevStateRoot)
+}
+
+// committedStorageRoot returns storage trie root of previous block committed in header
+func committedStorageRoot(header *types.Header) (common.Uint256, error) {
+	info, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if len(info.PrevStorageRoot) == 0 {
+		return common.UINT256_EMPTY, fmt.Errorf("no storage root committed in block %d", header.Height)
+	}
+	return common.Uint256ParseFromBytes(info.PrevStorageRoot)
+}
@@ -40,16 +40,20 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	cmetrics "github.com/ontio/ontology/common/metrics"
 	"github.com/ontio/ontology/core/chainmgr"
+	"github.com/ontio/ontology/core/genesis"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/events"
 	bactor "github.com/ontio/ontology/http/base/actor"
 	hserver "github.com/ontio/ontology/http/base/actor"
 	"github.com/ontio/ontology/http/jsonrpc"
 	"github.com/ontio/ontology/http/localrpc"
+	"github.com/ontio/ontology/http/metrics"
 	"github.com/ontio/ontology/http/nodeinfo"
 	"github.com/ontio/ontology/http/restful"
 	"github.com/ontio/ontology/http/websocket"
+	"github.com/ontio/ontology/lightclient"
 	"github.com/ontio/ontology/p2pserver"
 	netreqactor "github.com/ontio/ontology/p2pserver/actor/req"
 	p2pactor "github.com/ontio/ontology/p2pserver/actor/server"
@@ -73,12 +77,15 @@ func setupAPP() *cli.App {
 		cmd.ContractCommand,
 		cmd.ImportCommand,
 		cmd.ExportCommand,
+		cmd.SnapshotCommand,
 		cmd.TxCommond,
 		cmd.SigTxCommand,
 		cmd.MultiSigAddrCommand,
 		cmd.MultiSigTxCommand,
 		cmd.SendTxCommand,
 		cmd.ShowTxCommand,
+		cmd.ShardCommand,
+		cmd.ShardStakeCommand,
 	}
 	app.Flags = []cli.Flag{
 		//common setting
@@ -86,6 +93,10 @@ func setupAPP() *cli.App {
 		utils.LogLevelFlag,
 		utils.DisableEventLogFlag,
 		utils.DataDirFlag,
+		utils.PruneRetentionFlag,
+		utils.EnableArchiveFlag,
+		utils.EnableStorageTrieFlag,
+		utils.DBBackendFlag,
 		//account setting
 		utils.WalletFileFlag,
 		utils.AccountAddressFlag,
@@ -93,6 +104,10 @@ func setupAPP() *cli.App {
 		//consensus setting
 		utils.EnableConsensusFlag,
 		utils.MaxTxInBlockFlag,
+		utils.EmergencyRotationTimeoutFlag,
+		utils.MaxTxPerPayerFlag,
+		utils.ReservedSysTxInBlockFlag,
+		utils.MaxBlockGasFlag,
 		//txpool setting
 		utils.GasPriceFlag,
 		utils.GasLimitFlag,
@@ -105,6 +120,7 @@ func setupAPP() *cli.App {
 		utils.NetworkIdFlag,
 		utils.NodePortFlag,
 		utils.HttpInfoPortFlag,
+		utils.HttpMetricsPortFlag,
 		utils.MaxConnInBoundFlag,
 		utils.MaxConnOutBoundFlag,
 		utils.MaxConnInBoundForSingleIPFlag,
@@ -127,6 +143,9 @@ func setupAPP() *cli.App {
 		utils.ShardIDFlag,
 		utils.EnableSoloShardFlag,
 		utils.ShardParentHeightFlag,
+		//light node setting
+		utils.LightFlag,
+		utils.LightRpcAddrFlag,
 	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -153,9 +172,66 @@ func startOntology(ctx *cli.Context) {
 	log.Infof("ontology version %s", config.Version)
 
 	setMaxOpenFiles()
+	if ctx.GlobalBool(utils.GetFlagName(utils.LightFlag)) {
+		startLightNode(ctx)
+		return
+	}
 	startMainChain(ctx, shardID)
 }
 
+func startLightNode(ctx *cli.Context) {
+	if _, err := initConfig(ctx); err != nil {
+		log.Errorf("initConfig error:%s", err)
+		return
+	}
+	if config.DefConfig.Genesis.ConsensusType != config.CONSENSUS_TYPE_VBFT {
+		log.Errorf("light node only supports vbft, consensus type: %s", config.DefConfig.Genesis.ConsensusType)
+		return
+	}
+	// genesis block built from local config is the trust anchor of light node
+	bookkeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		log.Errorf("GetBookkeepers error:%s", err)
+		return
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis, config.DefConfig.Shard)
+	if err != nil {
+		log.Errorf("BuildGenesisBlock error:%s", err)
+		return
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	store, err := lightclient.NewHeaderStore(dbDir + string(os.PathSeparator) + "light")
+	if err != nil {
+		log.Errorf("open header store error:%s", err)
+		return
+	}
+	defer store.Close()
+
+	rpcAddr := ctx.GlobalString(utils.GetFlagName(utils.LightRpcAddrFlag))
+	client, err := lightclient.NewClient(rpcAddr, store, genesisBlock.Header)
+	if err != nil {
+		log.Errorf("init light client error:%s", err)
+		return
+	}
+	log.Infof("light node following %s from height %d", rpcAddr, client.CurrentHeight())
+
+	go syncLightHeaders(client)
+	waitToExit()
+}
+
+func syncLightHeaders(client *lightclient.Client) {
+	ticker := time.NewTicker(config.DEFAULT_GEN_BLOCK_TIME * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			if err := client.Sync(); err != nil {
+				log.Warnf("light node sync headers: %s", err)
+			}
+			log.Infof("CurrentHeaderHeight = %d", client.CurrentHeight())
+		}
+	}
+}
+
 func startMainChain(ctx *cli.Context, shardID common.ShardID) {
 	initLog(ctx)
 
@@ -211,6 +287,7 @@ func startMainChain(ctx *cli.Context, shardID common.ShardID) {
 	initRestful(ctx)
 	initWs(ctx)
 	initNodeInfo(ctx, p2pSvr)
+	initMetrics(ctx, p2pSvr)
 
 	go logCurrBlockHeight(shardID)
 	waitToExit()
@@ -416,6 +493,19 @@ func initNodeInfo(ctx *cli.Context, p2pSvr *p2pserver.P2PServer) {
 	log.Infof("Nodeinfo init success")
 }
 
+func initMetrics(ctx *cli.Context, p2pSvr *p2pserver.P2PServer) {
+	if config.DefConfig.P2PNode.HttpMetricsPort == 0 {
+		return
+	}
+	connCnt := cmetrics.NewGauge("ontology_p2p_connections", "P2P connection count")
+	cmetrics.DefRegistry.AddCollector(func() {
+		connCnt.Set(float64(p2pSvr.GetConnectionCnt()))
+	})
+	go metrics.StartServer()
+
+	log.Infof("Metrics init success")
+}
+
 func logCurrBlockHeight(shardID common.ShardID) {
 	ticker := time.NewTicker(config.DEFAULT_GEN_BLOCK_TIME * time.Second)
 	for {
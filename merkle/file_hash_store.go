@@ -65,6 +65,11 @@ func NewFileHashStore(name string, tree_size uint32) (HashStore, error) {
 	return store, nil
 }
 
+// StoredHashNum returns the count of hashes a HashStore holds for a tree of tree_size leaves
+func StoredHashNum(tree_size uint32) int64 {
+	return getStoredHashNum(tree_size)
+}
+
 func getStoredHashNum(tree_size uint32) int64 {
 	subtreesize := getSubTreeSize(tree_size)
 	sum := int64(0)
@@ -0,0 +1,425 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ontio/ontology/common"
+)
+
+const (
+	smtLeafNode     byte = 0
+	smtInternalNode byte = 1
+
+	// SMT_MAX_DEPTH is the bit length of key path
+	SMT_MAX_DEPTH = common.UINT256_SIZE * 8
+)
+
+// NodeStore is an interface for persist nodes of SparseMerkleTree by node hash
+type NodeStore interface {
+	GetNode(hash common.Uint256) ([]byte, error)
+	PutNode(hash common.Uint256, node []byte)
+}
+
+// SparseMerkleTree is an authenticated key value map. Key is hashed to a 256 bits path, and a subtree holding
+// only one leaf is replaced by the leaf, so the root hash only depends on the key value set.
+// Nodes are immutable and referenced by hash, the tree of an old root is still accessible.
+type SparseMerkleTree struct {
+	store NodeStore
+	root  common.Uint256
+}
+
+type smtKV struct {
+	path  common.Uint256
+	value []byte
+}
+
+// NewSparseMerkleTree returns a SparseMerkleTree with root, root should be common.UINT256_EMPTY for empty tree
+func NewSparseMerkleTree(root common.Uint256, store NodeStore) *SparseMerkleTree {
+	return &SparseMerkleTree{
+		store: store,
+		root:  root,
+	}
+}
+
+// Root returns the root hash of tree
+func (self *SparseMerkleTree) Root() common.Uint256 {
+	return self.root
+}
+
+// Update puts key values to tree, empty value means deleting the key
+func (self *SparseMerkleTree) Update(keys, values [][]byte) error {
+	if len(keys) != len(values) {
+		return errors.New("count of keys and values mismatch")
+	}
+	kvs := make([]smtKV, 0, len(keys))
+	for i, key := range keys {
+		kvs = append(kvs, smtKV{path: sha256.Sum256(key), value: values[i]})
+	}
+	kvs = sortKVs(kvs)
+	root, err := self.update(self.root, 0, kvs)
+	if err != nil {
+		return err
+	}
+	self.root = root
+	return nil
+}
+
+// Get returns value of key, nil if not exist
+func (self *SparseMerkleTree) Get(key []byte) ([]byte, error) {
+	value, _, err := self.Prove(key)
+	return value, err
+}
+
+// Prove returns value of key and its proof against root, value is nil if key does not exist
+func (self *SparseMerkleTree) Prove(key []byte) ([]byte, *SparseMerkleProof, error) {
+	path := sha256.Sum256(key)
+	proof := &SparseMerkleProof{}
+	hash := self.root
+	for depth := 0; ; depth++ {
+		if hash == common.UINT256_EMPTY {
+			return nil, proof, nil
+		}
+		node, err := self.getNode(hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		if node[0] == smtLeafNode {
+			leafPath, value, err := decodeLeafNode(node)
+			if err != nil {
+				return nil, nil, err
+			}
+			proof.HasLeaf = true
+			proof.LeafPath = leafPath
+			proof.LeafValueHash = sha256.Sum256(value)
+			if leafPath != path {
+				return nil, proof, nil
+			}
+			return value, proof, nil
+		}
+		left, right, err := decodeInternalNode(node)
+		if err != nil {
+			return nil, nil, err
+		}
+		if depth >= SMT_MAX_DEPTH {
+			return nil, nil, fmt.Errorf("tree is deeper than %d", SMT_MAX_DEPTH)
+		}
+		if getBit(path, depth) {
+			proof.Siblings = append(proof.Siblings, left)
+			hash = right
+		} else {
+			proof.Siblings = append(proof.Siblings, right)
+			hash = left
+		}
+	}
+}
+
+func (self *SparseMerkleTree) update(hash common.Uint256, depth int, kvs []smtKV) (common.Uint256, error) {
+	if len(kvs) == 0 {
+		return hash, nil
+	}
+	if hash == common.UINT256_EMPTY {
+		return self.build(depth, kvs)
+	}
+	node, err := self.getNode(hash)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if node[0] == smtLeafNode {
+		leafPath, value, err := decodeLeafNode(node)
+		if err != nil {
+			return common.UINT256_EMPTY, err
+		}
+		overwritten := false
+		for _, kv := range kvs {
+			if kv.path == leafPath {
+				overwritten = true
+				break
+			}
+		}
+		if !overwritten {
+			// kvs is shared with the caller, should not be appended in place
+			kvs = sortKVs(append(append(make([]smtKV, 0, len(kvs)+1), kvs...), smtKV{path: leafPath, value: value}))
+		}
+		return self.build(depth, kvs)
+	}
+	if depth >= SMT_MAX_DEPTH {
+		return common.UINT256_EMPTY, fmt.Errorf("tree is deeper than %d", SMT_MAX_DEPTH)
+	}
+	left, right, err := decodeInternalNode(node)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	pos := splitKVs(kvs, depth)
+	left, err = self.update(left, depth+1, kvs[:pos])
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	right, err = self.update(right, depth+1, kvs[pos:])
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return self.combine(left, right)
+}
+
+// build creates subtree at depth from sorted key values, deleted keys are skipped
+func (self *SparseMerkleTree) build(depth int, kvs []smtKV) (common.Uint256, error) {
+	leaves := make([]smtKV, 0, len(kvs))
+	for _, kv := range kvs {
+		if len(kv.value) != 0 {
+			leaves = append(leaves, kv)
+		}
+	}
+	return self.buildSubTree(depth, leaves)
+}
+
+func (self *SparseMerkleTree) buildSubTree(depth int, kvs []smtKV) (common.Uint256, error) {
+	switch len(kvs) {
+	case 0:
+		return common.UINT256_EMPTY, nil
+	case 1:
+		node := encodeLeafNode(kvs[0].path, kvs[0].value)
+		hash := hashLeafNode(kvs[0].path, sha256.Sum256(kvs[0].value))
+		self.store.PutNode(hash, node)
+		return hash, nil
+	}
+	if depth >= SMT_MAX_DEPTH {
+		return common.UINT256_EMPTY, errors.New("duplicated key path")
+	}
+	pos := splitKVs(kvs, depth)
+	left, err := self.buildSubTree(depth+1, kvs[:pos])
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	right, err := self.buildSubTree(depth+1, kvs[pos:])
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return self.combine(left, right)
+}
+
+// combine creates internal node of children, a subtree holding only one leaf is replaced by the leaf
+func (self *SparseMerkleTree) combine(left, right common.Uint256) (common.Uint256, error) {
+	if left == common.UINT256_EMPTY && right == common.UINT256_EMPTY {
+		return common.UINT256_EMPTY, nil
+	}
+	if left == common.UINT256_EMPTY || right == common.UINT256_EMPTY {
+		child := left
+		if child == common.UINT256_EMPTY {
+			child = right
+		}
+		node, err := self.getNode(child)
+		if err != nil {
+			return common.UINT256_EMPTY, err
+		}
+		if node[0] == smtLeafNode {
+			return child, nil
+		}
+	}
+	hash := hashInternalNode(left, right)
+	self.store.PutNode(hash, encodeInternalNode(left, right))
+	return hash, nil
+}
+
+func (self *SparseMerkleTree) getNode(hash common.Uint256) ([]byte, error) {
+	node, err := self.store.GetNode(hash)
+	if err != nil {
+		return nil, fmt.Errorf("get node %s: %s", hash.ToHexString(), err)
+	}
+	if len(node) == 0 {
+		return nil, fmt.Errorf("empty node %s", hash.ToHexString())
+	}
+	return node, nil
+}
+
+// SparseMerkleProof proves the value of key against root of SparseMerkleTree
+type SparseMerkleProof struct {
+	Siblings      []common.Uint256 // sibling hashes from root to the node key path ends at
+	HasLeaf       bool             // whether key path ends at a leaf or an empty node
+	LeafPath      common.Uint256   // path of the leaf key path ends at
+	LeafValueHash common.Uint256   // value hash of the leaf key path ends at
+}
+
+func (self *SparseMerkleProof) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarUint(uint64(len(self.Siblings)))
+	for _, sibling := range self.Siblings {
+		sink.WriteHash(sibling)
+	}
+	sink.WriteBool(self.HasLeaf)
+	if self.HasLeaf {
+		sink.WriteHash(self.LeafPath)
+		sink.WriteHash(self.LeafValueHash)
+	}
+}
+
+func (self *SparseMerkleProof) Deserialization(source *common.ZeroCopySource) error {
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if n > SMT_MAX_DEPTH {
+		return fmt.Errorf("too many siblings: %d", n)
+	}
+	self.Siblings = make([]common.Uint256, 0, n)
+	for i := uint64(0); i < n; i++ {
+		sibling, eof := source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		self.Siblings = append(self.Siblings, sibling)
+	}
+	self.HasLeaf, irregular, eof = source.NextBool()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if self.HasLeaf {
+		self.LeafPath, eof = source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		self.LeafValueHash, eof = source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+	}
+	return nil
+}
+
+// VerifySparseMerkleProof checks the value of key against root, empty value means key does not exist
+func VerifySparseMerkleProof(root common.Uint256, key, value []byte, proof *SparseMerkleProof) error {
+	if len(proof.Siblings) > SMT_MAX_DEPTH {
+		return fmt.Errorf("too many siblings: %d", len(proof.Siblings))
+	}
+	path := sha256.Sum256(key)
+	hash := common.UINT256_EMPTY
+	if len(value) != 0 {
+		if !proof.HasLeaf || proof.LeafPath != path {
+			return errors.New("key not found in proof")
+		}
+		if proof.LeafValueHash != sha256.Sum256(value) {
+			return errors.New("unmatched value hash")
+		}
+		hash = hashLeafNode(path, proof.LeafValueHash)
+	} else if proof.HasLeaf {
+		if proof.LeafPath == path {
+			return errors.New("key exists in proof")
+		}
+		for depth := range proof.Siblings {
+			if getBit(path, depth) != getBit(proof.LeafPath, depth) {
+				return errors.New("leaf path does not match key path")
+			}
+		}
+		hash = hashLeafNode(proof.LeafPath, proof.LeafValueHash)
+	}
+	for depth := len(proof.Siblings) - 1; depth >= 0; depth-- {
+		if getBit(path, depth) {
+			hash = hashInternalNode(proof.Siblings[depth], hash)
+		} else {
+			hash = hashInternalNode(hash, proof.Siblings[depth])
+		}
+	}
+	if hash != root {
+		return fmt.Errorf("unmatched root: %s vs %s", hash.ToHexString(), root.ToHexString())
+	}
+	return nil
+}
+
+func hashLeafNode(path, valueHash common.Uint256) common.Uint256 {
+	data := append([]byte{smtLeafNode}, path[:]...)
+	data = append(data, valueHash[:]...)
+	return sha256.Sum256(data)
+}
+
+func hashInternalNode(left, right common.Uint256) common.Uint256 {
+	data := append([]byte{smtInternalNode}, left[:]...)
+	data = append(data, right[:]...)
+	return sha256.Sum256(data)
+}
+
+func encodeLeafNode(path common.Uint256, value []byte) []byte {
+	node := make([]byte, 0, 1+common.UINT256_SIZE+len(value))
+	node = append(node, smtLeafNode)
+	node = append(node, path[:]...)
+	return append(node, value...)
+}
+
+func decodeLeafNode(node []byte) (common.Uint256, []byte, error) {
+	if len(node) <= 1+common.UINT256_SIZE {
+		return common.UINT256_EMPTY, nil, errors.New("invalid leaf node")
+	}
+	var path common.Uint256
+	copy(path[:], node[1:])
+	return path, node[1+common.UINT256_SIZE:], nil
+}
+
+func encodeInternalNode(left, right common.Uint256) []byte {
+	node := make([]byte, 0, 1+2*common.UINT256_SIZE)
+	node = append(node, smtInternalNode)
+	node = append(node, left[:]...)
+	return append(node, right[:]...)
+}
+
+func decodeInternalNode(node []byte) (common.Uint256, common.Uint256, error) {
+	var left, right common.Uint256
+	if len(node) != 1+2*common.UINT256_SIZE || node[0] != smtInternalNode {
+		return left, right, errors.New("invalid internal node")
+	}
+	copy(left[:], node[1:])
+	copy(right[:], node[1+common.UINT256_SIZE:])
+	return left, right, nil
+}
+
+// getBit returns whether the bit at depth of path is 1, the most significant bit of first byte is at depth 0
+func getBit(path common.Uint256, depth int) bool {
+	return path[depth/8]&(0x80>>uint(depth%8)) != 0
+}
+
+// sortKVs sorts key values by path, the last one is kept for duplicated paths
+func sortKVs(kvs []smtKV) []smtKV {
+	sort.SliceStable(kvs, func(i, j int) bool {
+		return bytes.Compare(kvs[i].path[:], kvs[j].path[:]) < 0
+	})
+	count := 0
+	for i, kv := range kvs {
+		if i+1 < len(kvs) && kvs[i+1].path == kv.path {
+			continue
+		}
+		kvs[count] = kv
+		count++
+	}
+	return kvs[:count]
+}
+
+// splitKVs returns the index of first key value whose path bit at depth is 1
+func splitKVs(kvs []smtKV, depth int) int {
+	return sort.Search(len(kvs), func(i int) bool {
+		return getBit(kvs[i].path, depth)
+	})
+}
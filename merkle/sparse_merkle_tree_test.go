@@ -0,0 +1,133 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package merkle
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type memNodeStore map[common.Uint256][]byte
+
+func (self memNodeStore) GetNode(hash common.Uint256) ([]byte, error) {
+	node, ok := self[hash]
+	if !ok {
+		return nil, fmt.Errorf("node %s not found", hash.ToHexString())
+	}
+	return node, nil
+}
+
+func (self memNodeStore) PutNode(hash common.Uint256, node []byte) {
+	self[hash] = node
+}
+
+func checkSparseMerkleTree(t *testing.T, tree *SparseMerkleTree, kvs map[string][]byte, absent [][]byte) {
+	for key, value := range kvs {
+		val, proof, err := tree.Prove([]byte(key))
+		assert.Nil(t, err)
+		assert.Equal(t, value, val)
+		assert.Nil(t, VerifySparseMerkleProof(tree.Root(), []byte(key), value, proof))
+		assert.NotNil(t, VerifySparseMerkleProof(tree.Root(), []byte(key), append(value, 0), proof))
+		assert.NotNil(t, VerifySparseMerkleProof(tree.Root(), []byte(key), nil, proof))
+	}
+	for _, key := range absent {
+		val, proof, err := tree.Prove(key)
+		assert.Nil(t, err)
+		assert.Nil(t, val)
+		assert.Nil(t, VerifySparseMerkleProof(tree.Root(), key, nil, proof))
+		assert.NotNil(t, VerifySparseMerkleProof(tree.Root(), key, []byte{1}, proof))
+	}
+}
+
+func TestSparseMerkleTree(t *testing.T) {
+	store := make(memNodeStore)
+	tree := NewSparseMerkleTree(common.UINT256_EMPTY, store)
+	kvs := make(map[string][]byte)
+	var absent [][]byte
+	for round := 0; round < 20; round++ {
+		var keys, values [][]byte
+		for i := 0; i < 50; i++ {
+			key := []byte(fmt.Sprintf("key%d", rand.Intn(300)))
+			value := []byte(fmt.Sprintf("value%d", rand.Int()))
+			if rand.Intn(4) == 0 {
+				value = nil
+			}
+			keys = append(keys, key)
+			values = append(values, value)
+			if len(value) == 0 {
+				delete(kvs, string(key))
+			} else {
+				kvs[string(key)] = value
+			}
+		}
+		assert.Nil(t, tree.Update(keys, values))
+		absent = absent[:0]
+		for i := 0; i < 300; i++ {
+			key := []byte(fmt.Sprintf("key%d", i))
+			if _, ok := kvs[string(key)]; !ok {
+				absent = append(absent, key)
+			}
+		}
+		checkSparseMerkleTree(t, tree, kvs, absent)
+
+		// root only depends on the key value set
+		rebuilt := NewSparseMerkleTree(common.UINT256_EMPTY, make(memNodeStore))
+		keys, values = keys[:0], values[:0]
+		for key, value := range kvs {
+			keys = append(keys, []byte(key))
+			values = append(values, value)
+		}
+		assert.Nil(t, rebuilt.Update(keys, values))
+		assert.Equal(t, tree.Root(), rebuilt.Root())
+	}
+
+	// tree of old root is still accessible
+	oldRoot, oldKVs := tree.Root(), kvs
+	var keys, values [][]byte
+	for key := range kvs {
+		keys = append(keys, []byte(key))
+		values = append(values, nil)
+	}
+	assert.Nil(t, tree.Update(keys, values))
+	assert.Equal(t, common.UINT256_EMPTY, tree.Root())
+	checkSparseMerkleTree(t, NewSparseMerkleTree(oldRoot, store), oldKVs, nil)
+}
+
+func TestSparseMerkleProofSerialization(t *testing.T) {
+	tree := NewSparseMerkleTree(common.UINT256_EMPTY, make(memNodeStore))
+	var keys, values [][]byte
+	for i := 0; i < 100; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("key%d", i)))
+		values = append(values, []byte(fmt.Sprintf("value%d", i)))
+	}
+	assert.Nil(t, tree.Update(keys, values))
+	for _, key := range [][]byte{keys[0], []byte("absent")} {
+		value, proof, err := tree.Prove(key)
+		assert.Nil(t, err)
+		sink := common.NewZeroCopySink(0)
+		proof.Serialization(sink)
+		proof2 := &SparseMerkleProof{}
+		assert.Nil(t, proof2.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+		assert.Equal(t, proof, proof2)
+		assert.Nil(t, VerifySparseMerkleProof(tree.Root(), key, value, proof2))
+	}
+}
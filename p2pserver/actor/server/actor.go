@@ -103,6 +103,12 @@ func (this *P2PActor) Receive(ctx actor.Context) {
 		} else {
 			log.Errorf("[p2p] start syncing, invalid shardID: %d", msg.ShardID)
 		}
+	case *StopSync:
+		if shardID, err := common2.NewShardID(msg.ShardID); err == nil {
+			this.server.StopSyncShard(shardID)
+		} else {
+			log.Errorf("[p2p] stop syncing, invalid shardID: %d", msg.ShardID)
+		}
 	default:
 		err := this.server.Xmit(ctx.Message())
 		if nil != err {
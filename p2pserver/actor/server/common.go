@@ -118,3 +118,7 @@ type StartSync struct {
 	ShardID    uint64
 	ShardSeeds []string
 }
+
+type StopSync struct {
+	ShardID uint64
+}
@@ -103,6 +103,7 @@ func (this *MessageRouter) hookChan(channel chan *types.MsgPayload,
 		case data, ok := <-channel:
 			if ok {
 				msgType := data.Payload.CmdType()
+				msgCommon.RecvMsgCounter.Inc(msgType)
 
 				handler, ok := this.msgHandlers[msgType]
 				if ok {
@@ -145,6 +145,17 @@ func (this *P2PServer) StartSyncShard(shardID comm.ShardID, shardSeeds []string)
 	return nil
 }
 
+// StopSyncShard stops the block syncer of shard
+func (this *P2PServer) StopSyncShard(shardID comm.ShardID) {
+	syncer, present := this.blockSyncers[shardID]
+	if !present {
+		return
+	}
+	syncer.Close()
+	delete(this.blockSyncers, shardID)
+	log.Infof("syncer for shard %d stopped", shardID.ToUint64())
+}
+
 //Stop halt all service by send signal to channels
 func (this *P2PServer) Stop() {
 	this.network.Halt()
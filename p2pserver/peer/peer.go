@@ -210,6 +210,7 @@ func (this *Peer) GetPort() uint16 {
 //SendTo call sync link to send buffer
 func (this *Peer) SendRaw(msgType string, msgPayload []byte) error {
 	if this.Link != nil && this.Link.Valid() {
+		common.SendMsgCounter.Inc(msgType)
 		return this.Link.SendRaw(msgPayload)
 	}
 	return errors.New("[p2p]sync link invalid")
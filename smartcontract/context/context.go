@@ -39,6 +39,7 @@ type ContextRef interface {
 	CheckWitness(address common.Address) bool
 	PushNotifications(notifications []*event.NotifyEventInfo)
 	NewExecuteEngine(code []byte) (Engine, error)
+	NewWasmExecuteEngine(code []byte, method string, args []byte) (Engine, error)
 	CheckUseGas(gas uint64) bool
 	CheckExecStep() bool
 	IsPreExec() bool
@@ -76,6 +76,7 @@ const (
 	REDUCE_INIT_POS                  = "reduceInitPos"
 	SET_PROMISE_POS                  = "setPromisePos"
 	SET_GAS_ADDRESS                  = "setGasAddress"
+	REPORT_FAULTY_NODE               = "reportFaultyNode"
 
 	//key prefix
 	GLOBAL_PARAM      = "globalParam"
@@ -95,6 +96,7 @@ const (
 	PROMISE_POS       = "promisePos"
 	PRE_CONFIG        = "preConfig"
 	GAS_ADDRESS       = "gasAddress"
+	FAULTY_EVIDENCE   = "faultyEvidence"
 
 	//global
 	PRECISE            = 1000000
@@ -152,6 +154,7 @@ func RegisterGovernanceContract(native *native.NativeService) {
 	native.Register(TRANSFER_PENALTY, TransferPenalty)
 	native.Register(SET_PROMISE_POS, SetPromisePos)
 	native.Register(SET_GAS_ADDRESS, SetGasAddress)
+	native.Register(REPORT_FAULTY_NODE, ReportFaultyNode)
 }
 
 //Init governance contract, include vbft config, global param and ontid admin.
@@ -594,56 +597,51 @@ func BlackNode(native *native.NativeService) ([]byte, error) {
 	}
 	contract := native.ContextRef.CurrentContext().ContractAddress
 
-	//get current view
-	view, err := utils.GetView(native, contract, []byte(GOVERNANCE_VIEW))
+	err = blackNode(native, contract, params.PeerPubkeyList)
 	if err != nil {
-		return utils.BYTE_FALSE, fmt.Errorf("getView, get view error: %v", err)
+		return utils.BYTE_FALSE, fmt.Errorf("blackNode, black node error: %v", err)
 	}
-	//get peerPoolMap
-	peerPoolMap, err := utils.GetPeerPoolMap(native, contract, view, PEER_POOL)
-	if err != nil {
-		return utils.BYTE_FALSE, fmt.Errorf("getPeerPoolMap, get peerPoolMap error: %v", err)
+	return utils.BYTE_TRUE, nil
+}
+
+// Report a consensus node which signed conflict consensus messages, the node will be put into black list.
+// Evidence is verified by the signatures in it, so no witness is needed.
+func ReportFaultyNode(native *native.NativeService) ([]byte, error) {
+	evidence := new(utils.FaultyEvidence)
+	if err := evidence.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("deserialize, contract params deserialize error: %v", err)
 	}
-	commit := false
-	for _, peerPubkey := range params.PeerPubkeyList {
-		peerPubkeyPrefix, err := hex.DecodeString(peerPubkey)
-		if err != nil {
-			return utils.BYTE_FALSE, fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
-		}
-		peerPoolItem, ok := peerPoolMap.PeerPoolMap[peerPubkey]
-		if !ok {
-			return utils.BYTE_FALSE, fmt.Errorf("blackNode, peerPubkey is not in peerPoolMap")
-		}
+	if err := evidence.Verify(native.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("reportFaultyNode, verify evidence error: %v", err)
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
 
-		blackListItem := &BlackListItem{
-			PeerPubkey: peerPoolItem.PeerPubkey,
-			Address:    peerPoolItem.Address,
-			InitPos:    peerPoolItem.InitPos,
-		}
-		bf := new(bytes.Buffer)
-		if err := blackListItem.Serialize(bf); err != nil {
-			return utils.BYTE_FALSE, fmt.Errorf("serialize, serialize blackListItem error: %v", err)
-		}
-		//put peer into black list
-		native.CacheDB.Put(utils.ConcatKey(contract, []byte(BLACK_LIST), peerPubkeyPrefix), cstates.GenRawStorageItem(bf.Bytes()))
-		//change peerPool status
-		if peerPoolItem.Status == ConsensusStatus {
-			commit = true
-		}
-		peerPoolItem.Status = BlackStatus
-		peerPoolMap.PeerPoolMap[peerPubkey] = peerPoolItem
+	peerPubkeyPrefix, err := hex.DecodeString(evidence.PeerPubkey)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
 	}
-	err = utils.PutPeerPoolMap(native, contract, view, peerPoolMap, PEER_POOL)
+	//one evidence can only be used once, in case of node is removed from black list
+	evidenceKey := utils.ConcatKey(contract, []byte(FAULTY_EVIDENCE), peerPubkeyPrefix, []byte{evidence.Type},
+		utils.GetUint32Bytes(evidence.Height()))
+	evidenceBytes, err := native.CacheDB.Get(evidenceKey)
 	if err != nil {
-		return utils.BYTE_FALSE, fmt.Errorf("putPeerPoolMap, put peerPoolMap error: %v", err)
+		return utils.BYTE_FALSE, fmt.Errorf("native.CacheDB.Get, get faulty evidence error: %v", err)
 	}
+	if evidenceBytes != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("reportFaultyNode, evidence has been reported")
+	}
+	blackListBytes, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(BLACK_LIST), peerPubkeyPrefix))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("native.CacheDB.Get, get BlackList error: %v", err)
+	}
+	if blackListBytes != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("reportFaultyNode, peer is already in black list")
+	}
+	native.CacheDB.Put(evidenceKey, cstates.GenRawStorageItem(utils.GetUint32Bytes(native.Height)))
 
-	//commitDpos
-	if commit {
-		err = executeCommitDpos(native, contract)
-		if err != nil {
-			return utils.BYTE_FALSE, fmt.Errorf("executeCommitDpos, executeCommitDpos error: %v", err)
-		}
+	err = blackNode(native, contract, []string{evidence.PeerPubkey})
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("blackNode, black node error: %v", err)
 	}
 	return utils.BYTE_TRUE, nil
 }
@@ -268,6 +268,62 @@ func authorizeForPeer(native *native.NativeService, flag string) error {
 	return nil
 }
 
+// put peers into black list, and remove them from consensus
+func blackNode(native *native.NativeService, contract common.Address, peerPubkeyList []string) error {
+	//get current view
+	view, err := utils.GetView(native, contract, []byte(GOVERNANCE_VIEW))
+	if err != nil {
+		return fmt.Errorf("getView, get view error: %v", err)
+	}
+	//get peerPoolMap
+	peerPoolMap, err := utils.GetPeerPoolMap(native, contract, view, PEER_POOL)
+	if err != nil {
+		return fmt.Errorf("getPeerPoolMap, get peerPoolMap error: %v", err)
+	}
+	commit := false
+	for _, peerPubkey := range peerPubkeyList {
+		peerPubkeyPrefix, err := hex.DecodeString(peerPubkey)
+		if err != nil {
+			return fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
+		}
+		peerPoolItem, ok := peerPoolMap.PeerPoolMap[peerPubkey]
+		if !ok {
+			return fmt.Errorf("blackNode, peerPubkey is not in peerPoolMap")
+		}
+
+		blackListItem := &BlackListItem{
+			PeerPubkey: peerPoolItem.PeerPubkey,
+			Address:    peerPoolItem.Address,
+			InitPos:    peerPoolItem.InitPos,
+		}
+		bf := new(bytes.Buffer)
+		if err := blackListItem.Serialize(bf); err != nil {
+			return fmt.Errorf("serialize, serialize blackListItem error: %v", err)
+		}
+		//put peer into black list
+		native.CacheDB.Put(utils.ConcatKey(contract, []byte(BLACK_LIST), peerPubkeyPrefix), cstates.GenRawStorageItem(bf.Bytes()))
+		//change peerPool status
+		if peerPoolItem.Status == ConsensusStatus {
+			commit = true
+		}
+		peerPoolItem.Status = BlackStatus
+		peerPoolMap.PeerPoolMap[peerPubkey] = peerPoolItem
+	}
+	err = utils.PutPeerPoolMap(native, contract, view, peerPoolMap, PEER_POOL)
+	if err != nil {
+		return fmt.Errorf("putPeerPoolMap, put peerPoolMap error: %v", err)
+	}
+
+	//commitDpos
+	if commit {
+		err = executeCommitDpos(native, contract)
+		if err != nil {
+			return fmt.Errorf("executeCommitDpos, executeCommitDpos error: %v", err)
+		}
+	}
+	return nil
+}
+
 func normalQuit(native *native.NativeService, contract common.Address, peerPoolItem *utils.PeerPoolItem) error {
 	peerPubkeyPrefix, err := hex.DecodeString(peerPoolItem.PeerPubkey)
 	if err != nil {
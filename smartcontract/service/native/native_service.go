@@ -51,6 +51,7 @@ type NativeService struct {
 	Notifications []*event.NotifyEventInfo
 	InvokeParam   sstates.ContractInvokeParam
 	LockedAddress map[common.Address]struct{}
+	LockedKeys    map[string]struct{}
 	Input         []byte
 	Tx            *types.Transaction
 	ShardID       common.ShardID
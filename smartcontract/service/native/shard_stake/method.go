@@ -542,6 +542,132 @@ func withdrawStakeAsset(native *native.NativeService, id common.ShardID, user co
 	return amount, nil
 }
 
+// unfreeze all peers init pos to peer owner, peers cannot be staked anymore
+func archiveShard(native *native.NativeService, id common.ShardID) error {
+	currentView, err := GetShardCurrentViewIndex(native, id)
+	if err != nil {
+		return fmt.Errorf("archiveShard: failed, err: %s", err)
+	}
+	currentViewInfo, err := GetShardViewInfo(native, id, currentView)
+	if err != nil {
+		return fmt.Errorf("archiveShard: get current view info failed, err: %s", err)
+	}
+	for peer, peerInfo := range currentViewInfo.Peers {
+		peerInfo.CanStake = false
+		currentViewInfo.Peers[peer] = peerInfo
+	}
+	setShardViewInfo(native, id, currentView, currentViewInfo)
+	nextView := currentView + 1
+	nextViewInfo, err := GetShardViewInfo(native, id, nextView)
+	if err != nil {
+		return fmt.Errorf("archiveShard: get next view info failed, err: %s", err)
+	}
+	if nextViewInfo.Peers == nil || len(nextViewInfo.Peers) == 0 {
+		nextViewInfo = currentViewInfo
+	}
+	for peer, peerInfo := range nextViewInfo.Peers {
+		lastStakeView, err := getUserLastStakeView(native, id, peerInfo.Owner)
+		if err != nil {
+			return fmt.Errorf("archiveShard: peer %s, err: %s", peer, err)
+		}
+		if lastStakeView > nextView {
+			return fmt.Errorf("archiveShard: peer %s last stake view %d and next view %d unmatch",
+				peer, lastStakeView, nextView)
+		}
+		lastOwnerStakeInfo, err := getShardViewUserStake(native, id, lastStakeView, peerInfo.Owner)
+		if err != nil {
+			return fmt.Errorf("archiveShard: peer %s, err: %s", peer, err)
+		}
+		nextOwnerStakeInfo := lastOwnerStakeInfo
+		if lastStakeView != nextView {
+			nextOwnerStakeInfo = &UserStakeInfo{}
+			copyUserLastStakeInfoToNext(lastOwnerStakeInfo, nextOwnerStakeInfo)
+		}
+		if isUserStakePeerEmpty(nextOwnerStakeInfo) {
+			nextOwnerStakeInfo.Peers = make(map[string]*UserPeerStakeInfo)
+		}
+		ownerStakeSelfInfo, ok := nextOwnerStakeInfo.Peers[peer]
+		if !ok {
+			ownerStakeSelfInfo = &UserPeerStakeInfo{PeerPubKey: peer}
+		}
+		ownerStakeSelfInfo.UnfreezeAmount += peerInfo.InitPos
+		nextOwnerStakeInfo.Peers[peer] = ownerStakeSelfInfo
+		peerInfo.UserUnfreezeAmount += peerInfo.InitPos
+		peerInfo.InitPos = 0
+		peerInfo.CanStake = false
+		nextViewInfo.Peers[peer] = peerInfo
+		setUserLastStakeView(native, id, peerInfo.Owner, nextView)
+		setShardViewUserStake(native, id, nextView, peerInfo.Owner, nextOwnerStakeInfo)
+	}
+	setShardViewInfo(native, id, nextView, nextViewInfo)
+	return nil
+}
+
+func peerPenalty(native *native.NativeService, id common.ShardID, peer string) error {
+	currentView, err := GetShardCurrentViewIndex(native, id)
+	if err != nil {
+		return fmt.Errorf("peerPenalty: failed, err: %s", err)
+	}
+	currentViewInfo, err := GetShardViewInfo(native, id, currentView)
+	if err != nil {
+		return fmt.Errorf("peerPenalty: get current view info failed, err: %s", err)
+	}
+	currentPeerInfo, ok := currentViewInfo.Peers[peer]
+	if !ok {
+		return fmt.Errorf("peerPenalty: peer %s not exist", peer)
+	}
+	currentPeerInfo.CanStake = false
+	currentViewInfo.Peers[peer] = currentPeerInfo
+	setShardViewInfo(native, id, currentView, currentViewInfo)
+	nextView := currentView + 1
+	nextViewInfo, err := GetShardViewInfo(native, id, nextView)
+	if err != nil {
+		return fmt.Errorf("peerPenalty: get next view info failed, err: %s", err)
+	}
+	if nextViewInfo.Peers == nil {
+		nextViewInfo.Peers = make(map[string]*PeerViewInfo)
+	}
+	nextPeerInfo, ok := nextViewInfo.Peers[peer]
+	if !ok {
+		nextPeerInfo = currentPeerInfo
+	}
+	penalty, err := getShardPenaltyStake(native, id)
+	if err != nil {
+		return fmt.Errorf("peerPenalty: failed, err: %s", err)
+	}
+	setShardPenaltyStake(native, id, penalty+nextPeerInfo.InitPos)
+	nextPeerInfo.InitPos = 0
+	nextPeerInfo.CanStake = false
+	nextViewInfo.Peers[peer] = nextPeerInfo
+	setShardViewInfo(native, id, nextView, nextViewInfo)
+	return nil
+}
+
+// return withdraw amount, user could withdraw all stake asset after shard archived
+func withdrawArchivedStakeAsset(native *native.NativeService, id common.ShardID, user common.Address) (uint64, error) {
+	lastStakeView, err := getUserLastStakeView(native, id, user)
+	if err != nil {
+		return 0, fmt.Errorf("withdrawArchivedStakeAsset: failed, err: %s", err)
+	}
+	lastUserStakeInfo, err := getShardViewUserStake(native, id, lastStakeView, user)
+	if err != nil {
+		return 0, fmt.Errorf("withdrawArchivedStakeAsset: get user last stake info failed, err: %s", err)
+	}
+	if isUserStakePeerEmpty(lastUserStakeInfo) {
+		return 0, fmt.Errorf("withdrawArchivedStakeAsset: user stake peer info is empty")
+	}
+	amount := uint64(0)
+	for peer, userPeerStakeInfo := range lastUserStakeInfo.Peers {
+		amount += userPeerStakeInfo.StakeAmount + userPeerStakeInfo.UnfreezeAmount
+		userPeerStakeInfo.StakeAmount = 0
+		userPeerStakeInfo.CurrentViewStakeAmount = 0
+		userPeerStakeInfo.UnfreezeAmount = 0
+		lastUserStakeInfo.Peers[peer] = userPeerStakeInfo
+	}
+	setShardViewUserStake(native, id, lastStakeView, user, lastUserStakeInfo)
+	return amount, nil
+}
+
 // return the amount that user could withdraw
 func withdrawFee(native *native.NativeService, shardId common.ShardID, user common.Address) (uint64, error) {
 	userWithdrawView, err := getUserLastWithdrawView(native, shardId, user)
@@ -535,6 +535,58 @@ func (this *PeerExitParam) Deserialize(r io.Reader) error {
 	return nil
 }
 
+type PeerPenaltyParam struct {
+	ShardId common.ShardID
+	Peer    string
+}
+
+func (this *PeerPenaltyParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardId); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	if err := serialization.WriteString(w, this.Peer); err != nil {
+		return fmt.Errorf("serialize: write pub key failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *PeerPenaltyParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardId, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	if this.Peer, err = serialization.ReadString(r); err != nil {
+		return fmt.Errorf("deserialize: read peer failed, err: %s", err)
+	}
+	return nil
+}
+
+type TransferPenaltyParam struct {
+	ShardId common.ShardID
+	Address common.Address
+}
+
+func (this *TransferPenaltyParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardId); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	if err := utils.WriteAddress(w, this.Address); err != nil {
+		return fmt.Errorf("serialize: write addr failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *TransferPenaltyParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardId, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	if this.Address, err = utils.ReadAddress(r); err != nil {
+		return fmt.Errorf("deserialize: read addr failed, err: %s", err)
+	}
+	return nil
+}
+
 type GetPeerInfoParam struct {
 	ShardId common.ShardID
 	View    uint64
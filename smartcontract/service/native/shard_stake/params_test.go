@@ -257,6 +257,36 @@ func TestPeerExitParam(t *testing.T) {
 	assert.Equal(t, param, newParam)
 }
 
+func TestPeerPenaltyParam(t *testing.T) {
+	acc := account.NewAccount("")
+	peer := hex.EncodeToString(keypair.SerializePublicKey(acc.PubKey()))
+	param := &PeerPenaltyParam{
+		ShardId: common.NewShardIDUnchecked(8),
+		Peer:    peer,
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &PeerPenaltyParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
+func TestTransferPenaltyParam(t *testing.T) {
+	param := &TransferPenaltyParam{
+		ShardId: common.NewShardIDUnchecked(8),
+		Address: common.Address{1, 2, 3},
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &TransferPenaltyParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
 func TestGetPeerInfoParam(t *testing.T) {
 	param := &GetPeerInfoParam{
 		ShardId: common.NewShardIDUnchecked(8),
@@ -26,9 +26,11 @@ import (
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/common/serialization"
 	"math/big"
+	"strings"
 
 	"github.com/ontio/ontology/common/constants"
 	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/global_params"
 	"github.com/ontio/ontology/smartcontract/service/native/ont"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 )
@@ -49,6 +51,9 @@ const (
 	PEER_EXIT                = "peerExit"
 	DELETE_PEER              = "deletePeer"
 	WITHDRAW_ONG             = "withdrawOng"
+	ARCHIVE_SHARD            = "archiveShard"
+	PEER_PENALTY             = "peerPenalty"
+	TRANSFER_PENALTY         = "transferPenalty"
 
 	// for pre-execute
 	GET_CURRENT_VIEW    = "getCurrentView"
@@ -78,6 +83,9 @@ func RegisterShardStake(native *native.NativeService) {
 	native.Register(DELETE_PEER, DeletePeer)
 	native.Register(PEER_EXIT, PeerExit)
 	native.Register(WITHDRAW_ONG, WithdrawOng)
+	native.Register(ARCHIVE_SHARD, ArchiveShard)
+	native.Register(PEER_PENALTY, PeerPenalty)
+	native.Register(TRANSFER_PENALTY, TransferPenalty)
 
 	native.Register(GET_IS_COMMITTING, GetIsCommitting)
 	native.Register(GET_CURRENT_VIEW, GetCurrentView)
@@ -118,6 +126,9 @@ func PeerInitStake(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("PeerInitStake: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("PeerInitStake: failed, err: %s", err)
+	}
 	err := peerInitStake(native, param)
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("PeerInitStake: deserialize param pub key failed, err: %s", err)
@@ -161,6 +172,9 @@ func AddInitPos(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("AddInitPos: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("AddInitPos: failed, err: %s", err)
+	}
 	if err := addInitPos(native, param.ShardId, param.PeerOwner, param.Value); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("AddInitPos: failed, err: %s", err)
 	}
@@ -198,6 +212,9 @@ func ReduceInitPos(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ReduceInitPos: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ReduceInitPos: failed, err: %s", err)
+	}
 	if err := utils.ValidateOwner(native, param.PeerOwner); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ReduceInitPos: check witness failed, err: %s", err)
 	}
@@ -219,6 +236,9 @@ func PeerExit(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("PeerExit: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("PeerExit: failed, err: %s", err)
+	}
 	currentView, err := GetShardCurrentViewIndex(native, param.ShardId)
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("PeerExit: failed, err: %s", err)
@@ -264,6 +284,9 @@ func DeletePeer(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("DeletePeer: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("DeletePeer: failed, err: %s", err)
+	}
 	currentView, err := GetShardCurrentViewIndex(native, param.ShardId)
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("DeletePeer: failed, err: %s", err)
@@ -307,6 +330,80 @@ func DeletePeer(native *native.NativeService) ([]byte, error) {
 	return utils.BYTE_TRUE, nil
 }
 
+// shard stopped and archived, all stake asset are unfrozen, only call by shard mgmt at archiveShard
+func ArchiveShard(native *native.NativeService) ([]byte, error) {
+	if native.ContextRef.CallingContext().ContractAddress != utils.ShardMgmtContractAddress {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: only can be invoked by shardmgmt contract")
+	}
+	shardId, err := utils.DeserializeShardId(bytes.NewReader(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: deserialize shard id faield, err: %s", err)
+	}
+	if err := checkCommittingDpos(native, shardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	}
+	if err := checkShardArchived(native, shardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	}
+	if err := archiveShard(native, shardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	}
+	setShardArchived(native, shardId)
+	return utils.BYTE_TRUE, nil
+}
+
+// peer is punished for faulty consensus behavior, whole init pos of peer is confiscated and peer cannot be staked any
+// more, only call by shard mgmt
+func PeerPenalty(native *native.NativeService) ([]byte, error) {
+	param := new(PeerPenaltyParam)
+	if err := param.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("PeerPenalty: invalid param: %s", err)
+	}
+	if native.ContextRef.CallingContext().ContractAddress != utils.ShardMgmtContractAddress {
+		return utils.BYTE_FALSE, fmt.Errorf("PeerPenalty: only can be invoked by shardmgmt contract")
+	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("PeerPenalty: failed, err: %s", err)
+	}
+	if err := peerPenalty(native, param.ShardId, strings.ToLower(param.Peer)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("PeerPenalty: failed, err: %s", err)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+// transfer all confiscated init pos of faulty peers at shard to a certain address, only call by admin
+func TransferPenalty(native *native.NativeService) ([]byte, error) {
+	param := new(TransferPenaltyParam)
+	if err := param.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferPenalty: invalid param: %s", err)
+	}
+	adminAddress, err := global_params.GetStorageRole(native,
+		global_params.GenerateOperatorKey(utils.ParamContractAddress))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferPenalty: get admin failed, err: %s", err)
+	}
+	if err := utils.ValidateOwner(native, adminAddress); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferPenalty: check witness failed, err: %s", err)
+	}
+	penalty, err := getShardPenaltyStake(native, param.ShardId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferPenalty: failed, err: %s", err)
+	}
+	if penalty == 0 {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferPenalty: shard has no penalty stake")
+	}
+	stakeAssetAddr, err := getShardStakeAssetAddr(native, param.ShardId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferPenalty: failed, err: %s", err)
+	}
+	setShardPenaltyStake(native, param.ShardId, 0)
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	if err := ont.AppCallTransfer(native, stakeAssetAddr, contract, param.Address, penalty); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferPenalty: transfer failed, amount %d, err: %s", penalty, err)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
 func UserStake(native *native.NativeService) ([]byte, error) {
 	param := new(UserStakeParam)
 	if err := param.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
@@ -318,6 +415,9 @@ func UserStake(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("UserStake: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("UserStake: failed, err: %s", err)
+	}
 	err := userStake(native, param.ShardId, param.User, param.Value)
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("UserStake: failed, err: %s", err)
@@ -363,6 +463,9 @@ func UnfreezeStake(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("UnfreezeStake: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("UnfreezeStake: failed, err: %s", err)
+	}
 	if err := utils.ValidateOwner(native, param.User); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("UnfreezeStake: check witness failed, err: %s", err)
 	}
@@ -384,7 +487,16 @@ func WithdrawStake(native *native.NativeService) ([]byte, error) {
 	if err := utils.ValidateOwner(native, param.User); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("WithdrawStake: check witness failed, err: %s", err)
 	}
-	num, err := withdrawStakeAsset(native, param.ShardId, param.User)
+	isArchived, err := IsShardArchived(native, param.ShardId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("WithdrawStake: failed, err: %s", err)
+	}
+	var num uint64
+	if isArchived {
+		num, err = withdrawArchivedStakeAsset(native, param.ShardId, param.User)
+	} else {
+		num, err = withdrawStakeAsset(native, param.ShardId, param.User)
+	}
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("WithdrawStake: failed, err: %s", err)
 	}
@@ -454,6 +566,9 @@ func PreCommitDpos(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, shardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("PreCommitDpos: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, shardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("PreCommitDpos: failed, err: %s", err)
+	}
 	setShardCommitting(native, shardId, true)
 	currentView, err := GetShardCurrentViewIndex(native, shardId)
 	if err != nil {
@@ -510,6 +625,9 @@ func ChangeMaxAuthorization(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ChangeMaxAuthorization: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ChangeMaxAuthorization: failed, err: %s", err)
+	}
 	if err := utils.ValidateOwner(native, param.User); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ChangeMaxAuthorization: check witness failed, err: %s", err)
 	}
@@ -528,6 +646,9 @@ func ChangeProportion(native *native.NativeService) ([]byte, error) {
 	if err := checkCommittingDpos(native, param.ShardId); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ChangeProportion: failed, err: %s", err)
 	}
+	if err := checkShardArchived(native, param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ChangeProportion: failed, err: %s", err)
+	}
 	if err := utils.ValidateOwner(native, param.User); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ChangeProportion: check witness failed, err: %s", err)
 	}
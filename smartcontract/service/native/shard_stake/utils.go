@@ -38,6 +38,7 @@ const (
 	KEY_VIEW_INFO  = "view_info"
 
 	KEY_IS_COMMITTING = "is_committing" // shard is committing dpos or not
+	KEY_IS_ARCHIVED   = "is_archived"   // shard is archived or not, stake asset could be withdrawn straightly after archived
 
 	KEY_SHARD_STAKE_ASSET_ADDR = "shard_stake_asset"
 
@@ -50,6 +51,8 @@ const (
 	KEY_XSHARD_FEE = "xshard_fee"
 
 	KEY_UNBOUND_ONG = "unbound_ong"
+
+	KEY_SHARD_PENALTY_STAKE = "shard_penalty_stake" // init pos of faulty peers confiscated at shard
 )
 
 func GenShardViewKey(shardIdBytes []byte) []byte {
@@ -70,6 +73,11 @@ func genShardIsCommittingKey(shardId common.ShardID) []byte {
 	return utils.ConcatKey(utils.ShardStakeAddress, shardIdBytes, []byte(KEY_IS_COMMITTING))
 }
 
+func genShardIsArchivedKey(shardId common.ShardID) []byte {
+	shardIdBytes := utils.GetUint64Bytes(shardId.ToUint64())
+	return utils.ConcatKey(utils.ShardStakeAddress, shardIdBytes, []byte(KEY_IS_ARCHIVED))
+}
+
 func genShardViewInfoKey(contract common.Address, shardIdBytes []byte, viewBytes []byte) []byte {
 	return utils.ConcatKey(contract, GenShardViewInfoKey(shardIdBytes, viewBytes))
 }
@@ -98,6 +106,10 @@ func genUserUnboundOngKey(contract, user common.Address) []byte {
 	return utils.ConcatKey(contract, []byte(KEY_UNBOUND_ONG), user[:])
 }
 
+func genShardPenaltyStakeKey(contract common.Address, shardIdBytes []byte) []byte {
+	return utils.ConcatKey(contract, shardIdBytes, []byte(KEY_SHARD_PENALTY_STAKE))
+}
+
 func genXShardFeeKey(shardId common.ShardID, view View) []byte {
 	sink := common.NewZeroCopySink(0)
 	sink.WriteShardID(shardId)
@@ -204,6 +216,48 @@ func checkCommittingDpos(native *native.NativeService, id common.ShardID) error
 	return nil
 }
 
+func setShardArchived(native *native.NativeService, id common.ShardID) {
+	key := genShardIsArchivedKey(id)
+	sink := common.NewZeroCopySink(0)
+	sink.WriteBool(true)
+	native.CacheDB.Put(key, cstates.GenRawStorageItem(sink.Bytes()))
+}
+
+func IsShardArchived(native *native.NativeService, id common.ShardID) (bool, error) {
+	key := genShardIsArchivedKey(id)
+	dataBytes, err := native.CacheDB.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("IsShardArchived: read db failed, err: %s", err)
+	}
+	if len(dataBytes) == 0 {
+		return false, nil
+	}
+	value, err := cstates.GetValueFromRawStorageItem(dataBytes)
+	if err != nil {
+		return false, fmt.Errorf("IsShardArchived: parse store info failed, err: %s", err)
+	}
+	source := common.NewZeroCopySource(value)
+	isArchived, irr, eof := source.NextBool()
+	if irr {
+		return false, fmt.Errorf("IsShardArchived: deserialize failed, err: %s", common.ErrIrregularData)
+	}
+	if eof {
+		return false, fmt.Errorf("IsShardArchived: deserialize failed, err: %s", io.ErrUnexpectedEOF)
+	}
+	return isArchived, nil
+}
+
+func checkShardArchived(native *native.NativeService, id common.ShardID) error {
+	isArchived, err := IsShardArchived(native, id)
+	if err != nil {
+		return fmt.Errorf("checkShardArchived: failed, err: %s", err)
+	}
+	if isArchived {
+		return fmt.Errorf("checkShardArchived: shard has been archived")
+	}
+	return nil
+}
+
 func getShardViewUserStake(native *native.NativeService, id common.ShardID, view View, user common.Address) (*UserStakeInfo,
 	error) {
 	shardIDBytes := utils.GetUint64Bytes(id.ToUint64())
@@ -405,3 +459,30 @@ func getXShardFeeInfo(native *native.NativeService, shardId common.ShardID, view
 	}
 	return info, nil
 }
+
+func getShardPenaltyStake(native *native.NativeService, id common.ShardID) (uint64, error) {
+	shardIDBytes := utils.GetUint64Bytes(id.ToUint64())
+	key := genShardPenaltyStakeKey(utils.ShardStakeAddress, shardIDBytes)
+	storeValue, err := native.CacheDB.Get(key)
+	if err != nil {
+		return 0, fmt.Errorf("getShardPenaltyStake: read db failed, err: %s", err)
+	}
+	if len(storeValue) == 0 {
+		return 0, nil
+	}
+	data, err := cstates.GetValueFromRawStorageItem(storeValue)
+	if err != nil {
+		return 0, fmt.Errorf("getShardPenaltyStake: parse store value failed, err: %s", err)
+	}
+	amount, err := utils.GetBytesUint64(data)
+	if err != nil {
+		return 0, fmt.Errorf("getShardPenaltyStake: dese value failed, err: %s", err)
+	}
+	return amount, nil
+}
+
+func setShardPenaltyStake(native *native.NativeService, id common.ShardID, amount uint64) {
+	shardIDBytes := utils.GetUint64Bytes(id.ToUint64())
+	key := genShardPenaltyStakeKey(utils.ShardStakeAddress, shardIDBytes)
+	native.CacheDB.Put(key, cstates.GenRawStorageItem(utils.GetUint64Bytes(amount)))
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shardsysmsg
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+type DequeueParam struct {
+	ShardID common.ShardID
+	Count   uint64
+}
+
+func (this *DequeueParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardID); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	if err := utils.WriteVarUint(w, this.Count); err != nil {
+		return fmt.Errorf("serialize: write count failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *DequeueParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardID, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	if this.Count, err = utils.ReadVarUint(r); err != nil {
+		return fmt.Errorf("deserialize: read count failed, err: %s", err)
+	}
+	return nil
+}
+
+// AckParam acks all delivered messages of shard queue up to Seq (inclusive)
+type AckParam struct {
+	ShardID common.ShardID
+	Seq     uint64
+}
+
+func (this *AckParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardID); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	if err := utils.WriteVarUint(w, this.Seq); err != nil {
+		return fmt.Errorf("serialize: write seq failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *AckParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardID, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	if this.Seq, err = utils.ReadVarUint(r); err != nil {
+		return fmt.Errorf("deserialize: read seq failed, err: %s", err)
+	}
+	return nil
+}
+
+type GetSysMsgParam struct {
+	ShardID common.ShardID
+	Seq     uint64
+}
+
+func (this *GetSysMsgParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardID); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	if err := utils.WriteVarUint(w, this.Seq); err != nil {
+		return fmt.Errorf("serialize: write seq failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *GetSysMsgParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardID, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	if this.Seq, err = utils.ReadVarUint(r); err != nil {
+		return fmt.Errorf("deserialize: read seq failed, err: %s", err)
+	}
+	return nil
+}
@@ -19,6 +19,11 @@
 package shardsysmsg
 
 import (
+	"bytes"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/events/message"
 	"github.com/ontio/ontology/smartcontract/service/native"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 )
@@ -27,11 +32,28 @@ import (
 //
 // Shard-system contract
 //
+// maintains an ordered queue of system messages (shard lifecycle events, config changes, stake commits)
+// for each target shard. Messages are appended by shard system contracts, and consumed by target shard with
+// dequeue/ack, sequence numbers of queue are monotonic so that message cannot be delivered or acked twice.
+// At the end of each block, messages delivered in previous block are acked and pending messages are dequeued,
+// so nodes consume the messages delivered in the block, and the unacked ones again after restart.
+//
 /////////
 
 const (
 	// function names
-	INIT_NAME = "init"
+	INIT_NAME    = "init"
+	ENQUEUE_NAME = "enqueue"
+	DEQUEUE_NAME = "dequeue"
+	ACK_NAME     = "ack"
+
+	// for pre-execute
+	GET_QUEUE_SHARDS_NAME = "getQueueShards"
+	GET_QUEUE_STATE_NAME  = "getQueueState"
+	GET_SYS_MSG_NAME      = "getSysMsg"
+
+	// max messages dequeued in one invocation
+	MAX_DEQUEUE_COUNT = 64
 )
 
 func InitShardSystemMessageContract() {
@@ -40,9 +62,243 @@ func InitShardSystemMessageContract() {
 
 func RegisterShardSysMsgContract(ctx *native.NativeService) {
 	ctx.Register(INIT_NAME, ShardSysMsgInit)
+	ctx.Register(ENQUEUE_NAME, Enqueue)
+	ctx.Register(DEQUEUE_NAME, Dequeue)
+	ctx.Register(ACK_NAME, Ack)
+
+	ctx.Register(GET_QUEUE_SHARDS_NAME, GetQueueShards)
+	ctx.Register(GET_QUEUE_STATE_NAME, GetQueueState)
+	ctx.Register(GET_SYS_MSG_NAME, GetSysMsg)
 }
 
 func ShardSysMsgInit(ctx *native.NativeService) ([]byte, error) {
-	// TODO: init sys-msg-queue Store
+	ver, err := getVersion(ctx)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardSysMsgInit: %s", err)
+	}
+	if ver == 0 {
+		setVersion(ctx, utils.VERSION_CONTRACT_SHARD_SYSMSG)
+		setQueueShards(ctx, &QueueShards{Shards: make([]common.ShardID, 0)})
+	} else if ver != utils.VERSION_CONTRACT_SHARD_SYSMSG {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardSysMsgInit: unsupported version %d", ver)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+// Enqueue appends a system event to the queue of event target shard, only shard system contracts can invoke
+func Enqueue(ctx *native.NativeService) ([]byte, error) {
+	caller := ctx.ContextRef.CallingContext().ContractAddress
+	if !isShardSystemContract(caller) {
+		return utils.BYTE_FALSE, fmt.Errorf("Enqueue: only shard system contract can invoke")
+	}
+	evt := &message.ShardEventState{}
+	if err := evt.Deserialization(common.NewZeroCopySource(ctx.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Enqueue: invalid param: %s", err)
+	}
+	seq, err := enqueue(ctx, caller, evt)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Enqueue: failed, err: %s", err)
+	}
+	utils.AddCommonEvent(ctx, utils.ShardSysMsgContractAddress, ENQUEUE_NAME, []interface{}{evt.ToShard.ToUint64(), seq})
 	return utils.BYTE_TRUE, nil
 }
+
+// Dequeue delivers at most Count pending messages of shard queue, invoked by target shard or shard system contracts
+func Dequeue(ctx *native.NativeService) ([]byte, error) {
+	param := &DequeueParam{}
+	if err := param.Deserialize(bytes.NewReader(ctx.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Dequeue: invalid param: %s", err)
+	}
+	if err := checkConsumer(ctx, param.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Dequeue: %s", err)
+	}
+	msgs, err := dequeue(ctx, param.ShardID, param.Count)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Dequeue: failed, err: %s", err)
+	}
+	sink := common.NewZeroCopySink(0)
+	msgs.Serialization(sink)
+	return sink.Bytes(), nil
+}
+
+// Ack acknowledges delivered messages of shard queue up to seq
+func Ack(ctx *native.NativeService) ([]byte, error) {
+	param := &AckParam{}
+	if err := param.Deserialize(bytes.NewReader(ctx.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Ack: invalid param: %s", err)
+	}
+	if err := checkConsumer(ctx, param.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Ack: %s", err)
+	}
+	if err := ack(ctx, param.ShardID, param.Seq); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Ack: failed, err: %s", err)
+	}
+	utils.AddCommonEvent(ctx, utils.ShardSysMsgContractAddress, ACK_NAME, []interface{}{param.ShardID.ToUint64(), param.Seq})
+	return utils.BYTE_TRUE, nil
+}
+
+// DeliverSysMsgs acks the messages delivered in previous blocks and dequeues pending messages of all queues,
+// it is invoked by ledger at the end of each block. Nodes consume the messages in [Head, Delivered) of queues
+// whose DeliverHeight is the block height.
+func DeliverSysMsgs(ctx *native.NativeService) error {
+	shards, err := getQueueShards(ctx)
+	if err != nil {
+		return fmt.Errorf("DeliverSysMsgs: %s", err)
+	}
+	for _, shardID := range shards.Shards {
+		state, err := getQueueState(ctx, shardID)
+		if err != nil {
+			return fmt.Errorf("DeliverSysMsgs: %s", err)
+		}
+		if state == nil || state.DeliverHeight == ctx.Height {
+			continue
+		}
+		if state.Delivered > state.Head {
+			if err := ack(ctx, shardID, state.Delivered-1); err != nil {
+				return fmt.Errorf("DeliverSysMsgs: ack queue %d: %s", shardID.ToUint64(), err)
+			}
+		}
+		if state.Pending() == 0 {
+			continue
+		}
+		count := state.Pending()
+		if count > MAX_DEQUEUE_COUNT {
+			count = MAX_DEQUEUE_COUNT
+		}
+		if _, err := dequeue(ctx, shardID, count); err != nil {
+			return fmt.Errorf("DeliverSysMsgs: dequeue queue %d: %s", shardID.ToUint64(), err)
+		}
+	}
+	return nil
+}
+
+func GetQueueShards(ctx *native.NativeService) ([]byte, error) {
+	shards, err := getQueueShards(ctx)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetQueueShards: failed, err: %s", err)
+	}
+	sink := common.NewZeroCopySink(0)
+	shards.Serialization(sink)
+	return sink.Bytes(), nil
+}
+
+func GetQueueState(ctx *native.NativeService) ([]byte, error) {
+	shardID, err := utils.DeserializeShardId(bytes.NewReader(ctx.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetQueueState: read shardId failed, err: %s", err)
+	}
+	state, err := getQueueState(ctx, shardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetQueueState: failed, err: %s", err)
+	}
+	if state == nil {
+		state = &QueueState{}
+	}
+	sink := common.NewZeroCopySink(0)
+	state.Serialization(sink)
+	return sink.Bytes(), nil
+}
+
+func GetSysMsg(ctx *native.NativeService) ([]byte, error) {
+	param := &GetSysMsgParam{}
+	if err := param.Deserialize(bytes.NewReader(ctx.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetSysMsg: invalid param: %s", err)
+	}
+	msg, err := getSysMsg(ctx, param.ShardID, param.Seq)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetSysMsg: failed, err: %s", err)
+	}
+	sink := common.NewZeroCopySink(0)
+	msg.Serialization(sink)
+	return sink.Bytes(), nil
+}
+
+func checkConsumer(ctx *native.NativeService, shardID common.ShardID) error {
+	if ctx.ContextRef.CheckCallShard(shardID) {
+		return nil
+	}
+	if isShardSystemContract(ctx.ContextRef.CallingContext().ContractAddress) {
+		return nil
+	}
+	return fmt.Errorf("only shard %d or shard system contract can consume queue", shardID.ToUint64())
+}
+
+func enqueue(ctx *native.NativeService, from common.Address, evt *message.ShardEventState) (uint64, error) {
+	state, err := getQueueState(ctx, evt.ToShard)
+	if err != nil {
+		return 0, err
+	}
+	if state == nil {
+		// first message to shard, register its queue
+		shards, err := getQueueShards(ctx)
+		if err != nil {
+			return 0, err
+		}
+		shards.Shards = append(shards.Shards, evt.ToShard)
+		setQueueShards(ctx, shards)
+		state = &QueueState{}
+	}
+	msg := &SysMsg{
+		Seq:    state.Tail,
+		Height: ctx.Height,
+		Msg: &message.ShardSystemEventMsg{
+			FromAddress: from,
+			Event:       evt,
+		},
+	}
+	setSysMsg(ctx, evt.ToShard, msg)
+	state.Tail++
+	setQueueState(ctx, evt.ToShard, state)
+	return msg.Seq, nil
+}
+
+func dequeue(ctx *native.NativeService, shardID common.ShardID, count uint64) (*SysMsgList, error) {
+	if count == 0 || count > MAX_DEQUEUE_COUNT {
+		return nil, fmt.Errorf("invalid dequeue count %d", count)
+	}
+	state, err := getQueueState(ctx, shardID)
+	if err != nil {
+		return nil, err
+	}
+	msgs := &SysMsgList{Msgs: make([]*SysMsg, 0)}
+	if state == nil {
+		return msgs, nil
+	}
+	if count > state.Pending() {
+		count = state.Pending()
+	}
+	for i := uint64(0); i < count; i++ {
+		msg, err := getSysMsg(ctx, shardID, state.Delivered+i)
+		if err != nil {
+			return nil, err
+		}
+		msgs.Msgs = append(msgs.Msgs, msg)
+	}
+	state.Delivered += count
+	state.DeliverHeight = ctx.Height
+	setQueueState(ctx, shardID, state)
+	return msgs, nil
+}
+
+func ack(ctx *native.NativeService, shardID common.ShardID, seq uint64) error {
+	state, err := getQueueState(ctx, shardID)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("queue of shard %d not exist", shardID.ToUint64())
+	}
+	if seq < state.Head {
+		return fmt.Errorf("msg %d has been acked", seq)
+	}
+	if seq >= state.Delivered {
+		return fmt.Errorf("msg %d has not been delivered", seq)
+	}
+	if state.DeliverHeight == ctx.Height {
+		return fmt.Errorf("msgs delivered at current height %d cannot be acked", ctx.Height)
+	}
+	// acked messages are kept for query, only the cursor moved
+	state.Head = seq + 1
+	setQueueState(ctx, shardID, state)
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shardsysmsg
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliverSysMsgs(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(memback))}
+	shardID := common.NewShardIDUnchecked(1)
+	enqueueAt := func(height uint32, n int) {
+		service.Height = height
+		for i := 0; i < n; i++ {
+			_, err := enqueue(service, utils.ShardMgmtContractAddress, &message.ShardEventState{ToShard: shardID, FromHeight: height})
+			assert.Nil(t, err)
+		}
+	}
+	deliverAt := func(height uint32) *QueueState {
+		service.Height = height
+		assert.Nil(t, DeliverSysMsgs(service))
+		state, err := getQueueState(service, shardID)
+		assert.Nil(t, err)
+		return state
+	}
+
+	enqueueAt(10, 2)
+	state := deliverAt(10)
+	assert.Equal(t, &QueueState{Head: 0, Delivered: 2, Tail: 2, DeliverHeight: 10}, state)
+	// delivered msgs cannot be acked in the block they are delivered
+	assert.NotNil(t, ack(service, shardID, 1))
+	// delivered twice in one block makes no change
+	assert.Equal(t, state, deliverAt(10))
+
+	// msgs delivered in previous block are acked in next delivery
+	enqueueAt(11, 1)
+	state = deliverAt(11)
+	assert.Equal(t, &QueueState{Head: 2, Delivered: 3, Tail: 3, DeliverHeight: 11}, state)
+	msg, err := getSysMsg(service, shardID, state.Head)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(11), msg.Height)
+
+	// nothing pending, the delivered msgs are acked only
+	state = deliverAt(12)
+	assert.Equal(t, &QueueState{Head: 3, Delivered: 3, Tail: 3, DeliverHeight: 11}, state)
+
+	// at most MAX_DEQUEUE_COUNT msgs are delivered in one block
+	enqueueAt(13, MAX_DEQUEUE_COUNT+1)
+	state = deliverAt(13)
+	assert.Equal(t, uint64(MAX_DEQUEUE_COUNT), state.Delivered-state.Head)
+	state = deliverAt(14)
+	assert.Equal(t, uint64(1), state.Delivered-state.Head)
+	assert.Equal(t, uint64(0), state.Pending())
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shardsysmsg
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/events/message"
+)
+
+// QueueState is the cursor set of a shard system message queue.
+// Messages in [Head, Delivered) have been dequeued but not yet acked,
+// messages in [Delivered, Tail) are pending.
+type QueueState struct {
+	Head          uint64 // first seq not acked
+	Delivered     uint64 // first seq not dequeued
+	Tail          uint64 // seq of next enqueued message
+	DeliverHeight uint32 // block height of last dequeue
+}
+
+func (this *QueueState) Pending() uint64 {
+	return this.Tail - this.Delivered
+}
+
+func (this *QueueState) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Head)
+	sink.WriteUint64(this.Delivered)
+	sink.WriteUint64(this.Tail)
+	sink.WriteUint32(this.DeliverHeight)
+}
+
+func (this *QueueState) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Head, eof = source.NextUint64()
+	this.Delivered, eof = source.NextUint64()
+	this.Tail, eof = source.NextUint64()
+	this.DeliverHeight, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if this.Head > this.Delivered || this.Delivered > this.Tail {
+		return fmt.Errorf("invalid queue state, head %d, delivered %d, tail %d", this.Head, this.Delivered, this.Tail)
+	}
+	return nil
+}
+
+// SysMsg is a system message stored in queue, Height is the block height it was enqueued at
+type SysMsg struct {
+	Seq    uint64
+	Height uint32
+	Msg    *message.ShardSystemEventMsg
+}
+
+func (this *SysMsg) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Seq)
+	sink.WriteUint32(this.Height)
+	this.Msg.Serialization(sink)
+}
+
+func (this *SysMsg) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Seq, eof = source.NextUint64()
+	this.Height, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.Msg = &message.ShardSystemEventMsg{}
+	return this.Msg.Deserialization(source)
+}
+
+type SysMsgList struct {
+	Msgs []*SysMsg
+}
+
+func (this *SysMsgList) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarUint(uint64(len(this.Msgs)))
+	for _, msg := range this.Msgs {
+		msg.Serialization(sink)
+	}
+}
+
+func (this *SysMsgList) Deserialization(source *common.ZeroCopySource) error {
+	num, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.Msgs = make([]*SysMsg, 0, num)
+	for i := uint64(0); i < num; i++ {
+		msg := &SysMsg{}
+		if err := msg.Deserialization(source); err != nil {
+			return fmt.Errorf("deserialize msg %d: %s", i, err)
+		}
+		this.Msgs = append(this.Msgs, msg)
+	}
+	return nil
+}
+
+type QueueShards struct {
+	Shards []common.ShardID
+}
+
+func (this *QueueShards) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarUint(uint64(len(this.Shards)))
+	for _, id := range this.Shards {
+		sink.WriteShardID(id)
+	}
+}
+
+func (this *QueueShards) Deserialization(source *common.ZeroCopySource) error {
+	num, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.Shards = make([]common.ShardID, 0, num)
+	for i := uint64(0); i < num; i++ {
+		id, err := source.NextShardID()
+		if err != nil {
+			return fmt.Errorf("deserialize shard id: %s", err)
+		}
+		this.Shards = append(this.Shards, id)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shardsysmsg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueState(t *testing.T) {
+	state := &QueueState{Head: 3, Delivered: 5, Tail: 9, DeliverHeight: 20}
+	sink := common.NewZeroCopySink(0)
+	state.Serialization(sink)
+	newState := &QueueState{}
+	err := newState.Deserialization(common.NewZeroCopySource(sink.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, state, newState)
+	assert.Equal(t, uint64(4), newState.Pending())
+
+	invalid := &QueueState{Head: 5, Delivered: 3, Tail: 9}
+	sink = common.NewZeroCopySink(0)
+	invalid.Serialization(sink)
+	err = newState.Deserialization(common.NewZeroCopySource(sink.Bytes()))
+	assert.NotNil(t, err)
+}
+
+func TestSysMsgList(t *testing.T) {
+	list := &SysMsgList{Msgs: make([]*SysMsg, 0)}
+	for i := uint64(0); i < 3; i++ {
+		list.Msgs = append(list.Msgs, &SysMsg{
+			Seq:    i,
+			Height: uint32(100 + i),
+			Msg: &message.ShardSystemEventMsg{
+				FromAddress: utils.ShardMgmtContractAddress,
+				Event: &message.ShardEventState{
+					Version:    utils.VERSION_CONTRACT_SHARD_MGMT,
+					EventType:  uint32(i),
+					ToShard:    common.NewShardIDUnchecked(1),
+					FromHeight: uint32(100 + i),
+					Payload:    []byte{1, 2, 3},
+				},
+			},
+		})
+	}
+	sink := common.NewZeroCopySink(0)
+	list.Serialization(sink)
+	newList := &SysMsgList{}
+	err := newList.Deserialization(common.NewZeroCopySource(sink.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, list, newList)
+}
+
+func TestQueueShards(t *testing.T) {
+	shards := &QueueShards{Shards: []common.ShardID{common.NewShardIDUnchecked(1), common.NewShardIDUnchecked(2)}}
+	sink := common.NewZeroCopySink(0)
+	shards.Serialization(sink)
+	newShards := &QueueShards{}
+	err := newShards.Deserialization(common.NewZeroCopySource(sink.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, shards, newShards)
+}
+
+func TestAckParam(t *testing.T) {
+	param := &AckParam{ShardID: common.NewShardIDUnchecked(3), Seq: 18}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &AckParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
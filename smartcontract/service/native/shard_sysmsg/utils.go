@@ -0,0 +1,161 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shardsysmsg
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	cstates "github.com/ontio/ontology/core/states"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+const (
+	KEY_VERSION      = "version"
+	KEY_QUEUE_SHARDS = "queue_shards" // shards which have sys msg queue
+	KEY_QUEUE_STATE  = "queue_state"
+	KEY_SYS_MSG      = "sys_msg"
+)
+
+func GenQueueShardsKey() []byte {
+	return []byte(KEY_QUEUE_SHARDS)
+}
+
+func GenQueueStateKey(shardIDBytes []byte) []byte {
+	return append([]byte(KEY_QUEUE_STATE), shardIDBytes...)
+}
+
+func GenSysMsgKey(shardIDBytes []byte, seqBytes []byte) []byte {
+	key := append([]byte(KEY_SYS_MSG), shardIDBytes...)
+	return append(key, seqBytes...)
+}
+
+func getStorageValue(native *native.NativeService, key []byte) ([]byte, error) {
+	data, err := native.CacheDB.Get(utils.ConcatKey(utils.ShardSysMsgContractAddress, key))
+	if err != nil {
+		return nil, fmt.Errorf("read db failed, err: %s", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	value, err := cstates.GetValueFromRawStorageItem(data)
+	if err != nil {
+		return nil, fmt.Errorf("deserialize from raw storage: %s", err)
+	}
+	return value, nil
+}
+
+func putStorageValue(native *native.NativeService, key []byte, value []byte) {
+	native.CacheDB.Put(utils.ConcatKey(utils.ShardSysMsgContractAddress, key), cstates.GenRawStorageItem(value))
+}
+
+func getVersion(native *native.NativeService) (uint32, error) {
+	data, err := getStorageValue(native, []byte(KEY_VERSION))
+	if err != nil {
+		return 0, fmt.Errorf("getVersion: %s", err)
+	}
+	if data == nil {
+		return 0, nil
+	}
+	version, eof := common.NewZeroCopySource(data).NextUint32()
+	if eof {
+		return 0, fmt.Errorf("getVersion: read version failed")
+	}
+	return version, nil
+}
+
+func setVersion(native *native.NativeService, version uint32) {
+	sink := common.NewZeroCopySink(0)
+	sink.WriteUint32(version)
+	putStorageValue(native, []byte(KEY_VERSION), sink.Bytes())
+}
+
+func getQueueShards(native *native.NativeService) (*QueueShards, error) {
+	shards := &QueueShards{Shards: make([]common.ShardID, 0)}
+	data, err := getStorageValue(native, GenQueueShardsKey())
+	if err != nil {
+		return nil, fmt.Errorf("getQueueShards: %s", err)
+	}
+	if data == nil {
+		return shards, nil
+	}
+	if err := shards.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("getQueueShards: deserialize failed, err: %s", err)
+	}
+	return shards, nil
+}
+
+func setQueueShards(native *native.NativeService, shards *QueueShards) {
+	sink := common.NewZeroCopySink(0)
+	shards.Serialization(sink)
+	putStorageValue(native, GenQueueShardsKey(), sink.Bytes())
+}
+
+// getQueueState returns nil if the queue of shard not exist
+func getQueueState(native *native.NativeService, shardID common.ShardID) (*QueueState, error) {
+	shardIDBytes := utils.GetUint64Bytes(shardID.ToUint64())
+	data, err := getStorageValue(native, GenQueueStateKey(shardIDBytes))
+	if err != nil {
+		return nil, fmt.Errorf("getQueueState: %s", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	state := &QueueState{}
+	if err := state.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("getQueueState: deserialize failed, err: %s", err)
+	}
+	return state, nil
+}
+
+func setQueueState(native *native.NativeService, shardID common.ShardID, state *QueueState) {
+	shardIDBytes := utils.GetUint64Bytes(shardID.ToUint64())
+	sink := common.NewZeroCopySink(0)
+	state.Serialization(sink)
+	putStorageValue(native, GenQueueStateKey(shardIDBytes), sink.Bytes())
+}
+
+func getSysMsg(native *native.NativeService, shardID common.ShardID, seq uint64) (*SysMsg, error) {
+	shardIDBytes := utils.GetUint64Bytes(shardID.ToUint64())
+	data, err := getStorageValue(native, GenSysMsgKey(shardIDBytes, utils.GetUint64Bytes(seq)))
+	if err != nil {
+		return nil, fmt.Errorf("getSysMsg: %s", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("getSysMsg: msg %d of shard %d not exist", seq, shardID.ToUint64())
+	}
+	msg := &SysMsg{}
+	if err := msg.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("getSysMsg: deserialize failed, err: %s", err)
+	}
+	return msg, nil
+}
+
+func setSysMsg(native *native.NativeService, shardID common.ShardID, msg *SysMsg) {
+	shardIDBytes := utils.GetUint64Bytes(shardID.ToUint64())
+	sink := common.NewZeroCopySink(0)
+	msg.Serialization(sink)
+	putStorageValue(native, GenSysMsgKey(shardIDBytes, utils.GetUint64Bytes(msg.Seq)), sink.Bytes())
+}
+
+func isShardSystemContract(addr common.Address) bool {
+	return addr == utils.ShardMgmtContractAddress || addr == utils.ShardStakeAddress ||
+		addr == utils.ShardCCMCAddress || addr == utils.ShardAssetAddress
+}
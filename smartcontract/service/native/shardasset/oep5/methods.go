@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package oep5
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ontio/ontology/common"
+	scomm "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+var one = big.NewInt(1)
+
+func checkTokenOwner(native *native.NativeService, asset AssetId, owner common.Address, tokenId *big.Int) error {
+	tokenOwner, err := getTokenOwner(native, asset, tokenId)
+	if err == scomm.ErrNotFound {
+		return fmt.Errorf("token %s not exist", tokenId.String())
+	} else if err != nil {
+		return err
+	}
+	if tokenOwner != owner {
+		return fmt.Errorf("%s is not owner of token %s", owner.ToBase58(), tokenId.String())
+	}
+	return nil
+}
+
+func transfer(native *native.NativeService, asset AssetId, from, to common.Address, tokenId *big.Int) error {
+	if err := checkTokenOwner(native, asset, from, tokenId); err != nil {
+		return fmt.Errorf("transfer: failed, err: %s", err)
+	}
+	deleteApproved(native, asset, tokenId)
+	if from != to {
+		fromBalance, err := getUserBalance(native, asset, from)
+		if err != nil {
+			return fmt.Errorf("transfer: get from balance failed, err: %s", err)
+		}
+		fromBalance.Sub(fromBalance, one)
+		setUserBalance(native, asset, from, fromBalance)
+		toBalance, err := getUserBalance(native, asset, to)
+		if err != nil {
+			return fmt.Errorf("transfer: get to balance failed, err: %s", err)
+		}
+		toBalance.Add(toBalance, one)
+		setUserBalance(native, asset, to, toBalance)
+		setTokenOwner(native, asset, tokenId, to)
+	}
+	event := &TransferEvent{AssetId: asset, From: from, To: to, TokenId: tokenId}
+	NotifyEvent(native, event.ToNotify())
+	return nil
+}
+
+func userBurn(native *native.NativeService, asset AssetId, user common.Address, tokenId *big.Int) error {
+	if err := checkTokenOwner(native, asset, user, tokenId); err != nil {
+		return fmt.Errorf("userBurn: failed, err: %s", err)
+	}
+	totalSupply, err := getTotalSupply(native, asset)
+	if err != nil {
+		return fmt.Errorf("userBurn: failed, err: %s", err)
+	}
+	if totalSupply.Sign() <= 0 {
+		return fmt.Errorf("userBurn: total supply not enough")
+	}
+	totalSupply.Sub(totalSupply, one)
+	setTotalSupply(native, asset, totalSupply)
+	balance, err := getUserBalance(native, asset, user)
+	if err != nil {
+		return fmt.Errorf("userBurn: failed, err: %s", err)
+	}
+	balance.Sub(balance, one)
+	setUserBalance(native, asset, user, balance)
+	deleteTokenOwner(native, asset, tokenId)
+	deleteApproved(native, asset, tokenId)
+	return nil
+}
+
+func userMint(native *native.NativeService, asset AssetId, user common.Address, tokenId *big.Int) error {
+	if _, err := getTokenOwner(native, asset, tokenId); err == nil {
+		return fmt.Errorf("userMint: token %s already exist", tokenId.String())
+	} else if err != scomm.ErrNotFound {
+		return fmt.Errorf("userMint: failed, err: %s", err)
+	}
+	totalSupply, err := getTotalSupply(native, asset)
+	if err != nil {
+		return fmt.Errorf("userMint: failed, err: %s", err)
+	}
+	totalSupply.Add(totalSupply, one)
+	setTotalSupply(native, asset, totalSupply)
+	balance, err := getUserBalance(native, asset, user)
+	if err != nil {
+		return fmt.Errorf("userMint: failed, err: %s", err)
+	}
+	balance.Add(balance, one)
+	setUserBalance(native, asset, user, balance)
+	setTokenOwner(native, asset, tokenId, user)
+	return nil
+}
+
+func xShardTransfer(native *native.NativeService, asset AssetId, from, to common.Address, toShard common.ShardID,
+	tokenId *big.Int) (*big.Int, error) {
+	transferNum, err := getXShardTransferNum(native, asset, from)
+	if err != nil {
+		return nil, fmt.Errorf("xShardTransfer: failed, err: %s", err)
+	}
+	transferNum.Add(transferNum, one)
+	transfer := &XShardTransferState{
+		Id:        transferNum,
+		ToShard:   toShard,
+		ToAccount: to,
+		TokenId:   tokenId,
+		Status:    XSHARD_TRANSFER_PENDING,
+	}
+	setXShardTransfer(native, asset, from, transferNum, transfer)
+	setXShardTransferNum(native, asset, from, transferNum)
+	return transferNum, nil
+}
+
+// move one token from fromShard to toShard in supply info, only used at root
+func moveShardSupply(native *native.NativeService, asset AssetId, fromShard, toShard common.ShardID) error {
+	supplyInfo, err := getShardSupplyInfo(native, asset)
+	if err != nil {
+		return fmt.Errorf("moveShardSupply: failed, err: %s", err)
+	}
+	fromSupply, ok := supplyInfo[fromShard]
+	if !ok {
+		return fmt.Errorf("moveShardSupply: shard %d supply not exist", fromShard.ToUint64())
+	}
+	if fromSupply.Sign() <= 0 {
+		return fmt.Errorf("moveShardSupply: shard %d supply not enough", fromShard.ToUint64())
+	}
+	fromSupply.Sub(fromSupply, one)
+	if toSupply, ok := supplyInfo[toShard]; ok {
+		toSupply.Add(toSupply, one)
+	} else {
+		supplyInfo[toShard] = big.NewInt(1)
+	}
+	setShardSupplyInfo(native, asset, supplyInfo)
+	return nil
+}
+
+func notifyShardMint(native *native.NativeService, toShard common.ShardID, param *ShardMintParam) error {
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		return fmt.Errorf("notifyShardMint: failed, err: %s", err)
+	}
+	native.NotifyRemoteShard(toShard, utils.ShardAssetAddress, native.ContextRef.GetRemainGas(), XSHARD_RECEIVE_ASSET, bf.Bytes())
+	return nil
+}
+
+func notifyTransferSuccess(native *native.NativeService, toShard common.ShardID, param *ShardMintParam) error {
+	event := &XShardReceiveEvent{
+		TransferEvent: &TransferEvent{
+			AssetId: AssetId(param.Asset),
+			From:    param.FromAccount,
+			To:      param.Account,
+			TokenId: param.TokenId,
+		},
+		TransferId: param.TransferId,
+		FromShard:  param.FromShard,
+	}
+	NotifyEvent(native, event.ToNotify())
+
+	tranSuccParam := &XShardTranSuccParam{
+		Asset:      param.Asset,
+		Account:    param.FromAccount,
+		TransferId: param.TransferId,
+	}
+	bf := new(bytes.Buffer)
+	if err := tranSuccParam.Serialize(bf); err != nil {
+		return fmt.Errorf("notifyTransferSuccess: failed, err: %s", err)
+	}
+	native.NotifyRemoteShard(toShard, utils.ShardAssetAddress, native.ContextRef.GetRemainGas(), XSHARD_TRANSFER_SUCC, bf.Bytes())
+	return nil
+}
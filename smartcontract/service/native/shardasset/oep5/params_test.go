@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package oep5
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferParam(t *testing.T) {
+	from := account.NewAccount("")
+	to := account.NewAccount("")
+	param := &TransferParam{
+		From:    from.Address,
+		To:      to.Address,
+		TokenId: big.NewInt(3847),
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &TransferParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
+func TestMultiTransferParam(t *testing.T) {
+	from := account.NewAccount("")
+	to := account.NewAccount("")
+	param := &MultiTransferParam{
+		Transfers: []*TransferParam{
+			{From: from.Address, To: to.Address, TokenId: big.NewInt(1)},
+			{From: to.Address, To: from.Address, TokenId: big.NewInt(2)},
+		},
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &MultiTransferParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
+func TestTakeOwnershipParam(t *testing.T) {
+	spender := account.NewAccount("")
+	to := account.NewAccount("")
+	param := &TakeOwnershipParam{
+		Spender: spender.Address,
+		To:      to.Address,
+		TokenId: big.NewInt(99),
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &TakeOwnershipParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
+func TestXShardTransferParam(t *testing.T) {
+	from := account.NewAccount("")
+	to := account.NewAccount("")
+	param := &XShardTransferParam{
+		From:    from.Address,
+		To:      to.Address,
+		ToShard: common.NewShardIDUnchecked(1),
+		TokenId: big.NewInt(77),
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &XShardTransferParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
+func TestShardMintParam(t *testing.T) {
+	acc := account.NewAccount("")
+	param := &ShardMintParam{
+		OriginalContract: acc.Address,
+		Asset:            3,
+		Account:          acc.Address,
+		FromShard:        common.NewShardIDUnchecked(2),
+		FromAccount:      acc.Address,
+		TransferId:       big.NewInt(5),
+		TokenId:          big.NewInt(1000),
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &ShardMintParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
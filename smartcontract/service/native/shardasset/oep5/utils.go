@@ -0,0 +1,423 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package oep5
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/states"
+	scomm "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+const (
+	KEY_OEP5_ASSET_NUM = "oep5_asset_num"
+	KEY_OEP5_ASSET_ID  = "oep5_asset_id"
+
+	KEY_OEP5_TOTAL_SUPPLY    = "oep5_total_supply"
+	KEY_OEP5_BALANCE         = "oep5_balance"
+	KEY_OEP5_OWNER           = "oep5_owner"
+	KEY_OEP5_APPROVED        = "oep5_approved"
+	KEY_OEP5_SHARD_SUPPLY    = "oep5_shard_supply" // token num at every shard
+	KEY_OEP5_TRANSFER_NUM    = "oep5_transfer_num"
+	KEY_OEP5_XSHARD_TRANSFER = "oep5_xshard_transfer"
+	KEY_OEP5_XSHARD_RECEIVE  = "oep5_xshard_receive"
+	KEY_OEP5_MINTED          = "oep5_minted" // tokens minted at root, kept while token moving between shards
+)
+
+func genAssetNumKey() []byte {
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_ASSET_NUM))
+}
+
+func genAssetIdKey(assetAddr common.Address) []byte {
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_ASSET_ID), assetAddr[:])
+}
+
+func genAssetTotalSupplyKey(id AssetId) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(id))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_TOTAL_SUPPLY), assetBytes)
+}
+
+func genBalanceKey(asset AssetId, user common.Address) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_BALANCE), assetBytes, user[:])
+}
+
+func genOwnerKey(asset AssetId, tokenId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_OWNER), assetBytes,
+		common.BigIntToNeoBytes(tokenId))
+}
+
+func genApprovedKey(asset AssetId, tokenId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_APPROVED), assetBytes,
+		common.BigIntToNeoBytes(tokenId))
+}
+
+func genMintedKey(asset AssetId, tokenId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_MINTED), assetBytes,
+		common.BigIntToNeoBytes(tokenId))
+}
+
+func genShardSupplyInfoKey(asset AssetId) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_SHARD_SUPPLY), assetBytes)
+}
+
+func genXShardTransferNumKey(asset AssetId, user common.Address) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_TRANSFER_NUM), assetBytes, user[:])
+}
+
+func genXShardTransferKey(asset AssetId, user common.Address, transferId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_XSHARD_TRANSFER), assetBytes, user[:],
+		common.BigIntToNeoBytes(transferId))
+}
+
+func genXShardReceiveKey(asset AssetId, user common.Address, fromShard common.ShardID, transferId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	shardIdBytes := utils.GetUint64Bytes(fromShard.ToUint64())
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP5_XSHARD_RECEIVE), assetBytes, shardIdBytes, user[:],
+		common.BigIntToNeoBytes(transferId))
+}
+
+func getBigInt(native *native.NativeService, key []byte) (*big.Int, error) {
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return big.NewInt(0), nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse store value failed, err: %s", err)
+	}
+	return common.BigIntFromNeoBytes(storeValue), nil
+}
+
+func setBigInt(native *native.NativeService, key []byte, value *big.Int) {
+	native.CacheDB.Put(key, states.GenRawStorageItem(common.BigIntToNeoBytes(value)))
+}
+
+func setTotalSupply(native *native.NativeService, asset AssetId, supply *big.Int) {
+	setBigInt(native, genAssetTotalSupplyKey(asset), supply)
+}
+
+func getTotalSupply(native *native.NativeService, asset AssetId) (*big.Int, error) {
+	supply, err := getBigInt(native, genAssetTotalSupplyKey(asset))
+	if err != nil {
+		return nil, fmt.Errorf("getTotalSupply: %s", err)
+	}
+	return supply, nil
+}
+
+func setUserBalance(native *native.NativeService, asset AssetId, user common.Address, balance *big.Int) {
+	setBigInt(native, genBalanceKey(asset, user), balance)
+}
+
+func getUserBalance(native *native.NativeService, asset AssetId, user common.Address) (*big.Int, error) {
+	balance, err := getBigInt(native, genBalanceKey(asset, user))
+	if err != nil {
+		return nil, fmt.Errorf("getUserBalance: %s", err)
+	}
+	return balance, nil
+}
+
+func setXShardTransferNum(native *native.NativeService, asset AssetId, user common.Address, num *big.Int) {
+	setBigInt(native, genXShardTransferNumKey(asset, user), num)
+}
+
+func getXShardTransferNum(native *native.NativeService, asset AssetId, user common.Address) (*big.Int, error) {
+	num, err := getBigInt(native, genXShardTransferNumKey(asset, user))
+	if err != nil {
+		return nil, fmt.Errorf("getXShardTransferNum: %s", err)
+	}
+	return num, nil
+}
+
+func getAddress(native *native.NativeService, key []byte) (common.Address, error) {
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return common.ADDRESS_EMPTY, scomm.ErrNotFound
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("parse store value failed, err: %s", err)
+	}
+	addr, err := common.AddressParseFromBytes(storeValue)
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("parse address failed, err: %s", err)
+	}
+	return addr, nil
+}
+
+func setTokenOwner(native *native.NativeService, asset AssetId, tokenId *big.Int, owner common.Address) {
+	native.CacheDB.Put(genOwnerKey(asset, tokenId), states.GenRawStorageItem(owner[:]))
+}
+
+// return scomm.ErrNotFound if token not exist at this shard
+func getTokenOwner(native *native.NativeService, asset AssetId, tokenId *big.Int) (common.Address, error) {
+	owner, err := getAddress(native, genOwnerKey(asset, tokenId))
+	if err == scomm.ErrNotFound {
+		return owner, err
+	} else if err != nil {
+		return owner, fmt.Errorf("getTokenOwner: %s", err)
+	}
+	return owner, nil
+}
+
+func deleteTokenOwner(native *native.NativeService, asset AssetId, tokenId *big.Int) {
+	native.CacheDB.Delete(genOwnerKey(asset, tokenId))
+}
+
+func setApproved(native *native.NativeService, asset AssetId, tokenId *big.Int, spender common.Address) {
+	native.CacheDB.Put(genApprovedKey(asset, tokenId), states.GenRawStorageItem(spender[:]))
+}
+
+// return common.ADDRESS_EMPTY if token not approved
+func getApproved(native *native.NativeService, asset AssetId, tokenId *big.Int) (common.Address, error) {
+	spender, err := getAddress(native, genApprovedKey(asset, tokenId))
+	if err == scomm.ErrNotFound {
+		return common.ADDRESS_EMPTY, nil
+	} else if err != nil {
+		return spender, fmt.Errorf("getApproved: %s", err)
+	}
+	return spender, nil
+}
+
+func deleteApproved(native *native.NativeService, asset AssetId, tokenId *big.Int) {
+	native.CacheDB.Delete(genApprovedKey(asset, tokenId))
+}
+
+func setXShardTransfer(native *native.NativeService, asset AssetId, user common.Address, transferId *big.Int,
+	transfer *XShardTransferState) {
+	key := genXShardTransferKey(asset, user, transferId)
+	sink := common.NewZeroCopySink(0)
+	transfer.Serialization(sink)
+	native.CacheDB.Put(key, states.GenRawStorageItem(sink.Bytes()))
+}
+
+func getXShardTransfer(native *native.NativeService, asset AssetId, user common.Address,
+	transferId *big.Int) (*XShardTransferState, error) {
+	key := genXShardTransferKey(asset, user, transferId)
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("getXShardTransfer: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("getXShardTransfer: transfer not exist")
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return nil, fmt.Errorf("getXShardTransfer: parse store value failed, err: %s", err)
+	}
+	state := &XShardTransferState{}
+	if err := state.Deserialization(common.NewZeroCopySource(storeValue)); err != nil {
+		return nil, fmt.Errorf("getXShardTransfer: deserialize failed, err: %s", err)
+	}
+	return state, nil
+}
+
+func receiveTransfer(native *native.NativeService, param *ShardMintParam) {
+	key := genXShardReceiveKey(AssetId(param.Asset), param.FromAccount, param.FromShard, param.TransferId)
+	sink := common.NewZeroCopySink(0)
+	sink.WriteBool(true)
+	native.CacheDB.Put(key, states.GenRawStorageItem(sink.Bytes()))
+}
+
+func isTransferReceived(native *native.NativeService, param *ShardMintParam) (bool, error) {
+	key := genXShardReceiveKey(AssetId(param.Asset), param.FromAccount, param.FromShard, param.TransferId)
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("isTransferReceived: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return false, nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return false, fmt.Errorf("isTransferReceived: parse store value failed, err: %s", err)
+	}
+	source := common.NewZeroCopySource(storeValue)
+	isReceived, irr, eof := source.NextBool()
+	if irr {
+		return false, fmt.Errorf("isTransferReceived: deserialize store value, err: %s", common.ErrIrregularData)
+	}
+	if eof {
+		return false, fmt.Errorf("isTransferReceived: deserialize store value, err: %s", io.ErrUnexpectedEOF)
+	}
+	return isReceived, nil
+}
+
+func setTokenMinted(native *native.NativeService, asset AssetId, tokenId *big.Int) {
+	sink := common.NewZeroCopySink(0)
+	sink.WriteBool(true)
+	native.CacheDB.Put(genMintedKey(asset, tokenId), states.GenRawStorageItem(sink.Bytes()))
+}
+
+func deleteTokenMinted(native *native.NativeService, asset AssetId, tokenId *big.Int) {
+	native.CacheDB.Delete(genMintedKey(asset, tokenId))
+}
+
+// isTokenMinted checks if token has been minted at root, no matter which shard the token is at now
+func isTokenMinted(native *native.NativeService, asset AssetId, tokenId *big.Int) (bool, error) {
+	raw, err := native.CacheDB.Get(genMintedKey(asset, tokenId))
+	if err != nil {
+		return false, fmt.Errorf("isTokenMinted: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return false, nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return false, fmt.Errorf("isTokenMinted: parse store value failed, err: %s", err)
+	}
+	source := common.NewZeroCopySource(storeValue)
+	isMinted, irr, eof := source.NextBool()
+	if irr {
+		return false, fmt.Errorf("isTokenMinted: deserialize store value, err: %s", common.ErrIrregularData)
+	}
+	if eof {
+		return false, fmt.Errorf("isTokenMinted: deserialize store value, err: %s", io.ErrUnexpectedEOF)
+	}
+	return isMinted, nil
+}
+
+func setAssetNum(native *native.NativeService, num uint64) {
+	native.CacheDB.Put(genAssetNumKey(), states.GenRawStorageItem(utils.GetUint64Bytes(num)))
+}
+
+func getAssetNum(native *native.NativeService) (uint64, error) {
+	raw, err := native.CacheDB.Get(genAssetNumKey())
+	if err != nil {
+		return 0, fmt.Errorf("getAssetNum: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetNum: parse store value failed, err: %s", err)
+	}
+	num, err := utils.GetBytesUint64(storeValue)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetNum: deserialize store value failed, err: %s", err)
+	}
+	return num, nil
+}
+
+func registerAsset(native *native.NativeService, assetAddr common.Address, assetId AssetId) {
+	native.CacheDB.Put(genAssetIdKey(assetAddr), states.GenRawStorageItem(utils.GetUint64Bytes(uint64(assetId))))
+}
+
+func getAssetId(native *native.NativeService, assetAddr common.Address) (AssetId, error) {
+	raw, err := native.CacheDB.Get(genAssetIdKey(assetAddr))
+	if err != nil {
+		return 0, fmt.Errorf("getAssetId: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return 0, scomm.ErrNotFound
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetId: parse store value failed, err: %s", err)
+	}
+	id, err := utils.GetBytesUint64(storeValue)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetId: deserialize store value failed, err: %s", err)
+	}
+	return AssetId(id), nil
+}
+
+func isAssetRegister(native *native.NativeService, assetAddr common.Address) (bool, error) {
+	raw, err := native.CacheDB.Get(genAssetIdKey(assetAddr))
+	if err != nil {
+		return false, fmt.Errorf("isAssetRegister: read db failed, err: %s", err)
+	}
+	return len(raw) != 0, nil
+}
+
+func deleteAssetId(native *native.NativeService, assetAddr common.Address) {
+	native.CacheDB.Delete(genAssetIdKey(assetAddr))
+}
+
+func setShardSupplyInfo(native *native.NativeService, asset AssetId, supplyInfo map[common.ShardID]*big.Int) {
+	key := genShardSupplyInfoKey(asset)
+	sink := common.NewZeroCopySink(0)
+	sink.WriteUint64(uint64(len(supplyInfo)))
+	shards := make([]common.ShardID, 0, len(supplyInfo))
+	for shard := range supplyInfo {
+		shards = append(shards, shard)
+	}
+	sort.SliceStable(shards, func(i, j int) bool {
+		return shards[i].ToUint64() < shards[j].ToUint64()
+	})
+	for _, shard := range shards {
+		utils.SerializationShardId(sink, shard)
+		sink.WriteVarBytes(common.BigIntToNeoBytes(supplyInfo[shard]))
+	}
+	native.CacheDB.Put(key, states.GenRawStorageItem(sink.Bytes()))
+}
+
+func getShardSupplyInfo(native *native.NativeService, asset AssetId) (map[common.ShardID]*big.Int, error) {
+	raw, err := native.CacheDB.Get(genShardSupplyInfoKey(asset))
+	if err != nil {
+		return nil, fmt.Errorf("getShardSupplyInfo: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return map[common.ShardID]*big.Int{}, nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return nil, fmt.Errorf("getShardSupplyInfo: parse store value failed, err: %s", err)
+	}
+	source := common.NewZeroCopySource(storeValue)
+	shardNum, eof := source.NextUint64()
+	if eof {
+		return nil, fmt.Errorf("getShardSupplyInfo: deserialize shard num failed, err: %s", io.ErrUnexpectedEOF)
+	}
+	shards := make(map[common.ShardID]*big.Int)
+	for i := uint64(0); i < shardNum; i++ {
+		shard, err := utils.DeserializationShardId(source)
+		if err != nil {
+			return nil, fmt.Errorf("getShardSupplyInfo: deserialize shard failed, index %d, err: %s", i, err)
+		}
+		supplyBytes, _, irr, eof := source.NextVarBytes()
+		if irr {
+			return nil, fmt.Errorf("getShardSupplyInfo: deserialize supply failed, index %d, err: %s", i, common.ErrIrregularData)
+		}
+		if eof {
+			return nil, fmt.Errorf("getShardSupplyInfo: deserialize supply failed, index %d, err: %s", i, io.ErrUnexpectedEOF)
+		}
+		shards[shard] = common.BigIntFromNeoBytes(supplyBytes)
+	}
+	return shards, nil
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package oep5
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	scomm "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenMintedAfterXShardTransfer(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(memback))}
+	asset, user, tokenId := AssetId(1), common.Address{1}, big.NewInt(7)
+
+	minted, err := isTokenMinted(service, asset, tokenId)
+	assert.Nil(t, err)
+	assert.False(t, minted)
+	assert.Nil(t, userMint(service, asset, user, tokenId))
+	setTokenMinted(service, asset, tokenId)
+
+	// token is burned at root while transferring to shard, but still registered as minted
+	assert.Nil(t, userBurn(service, asset, user, tokenId))
+	_, err = getTokenOwner(service, asset, tokenId)
+	assert.Equal(t, scomm.ErrNotFound, err)
+	minted, err = isTokenMinted(service, asset, tokenId)
+	assert.Nil(t, err)
+	assert.True(t, minted)
+
+	deleteTokenMinted(service, asset, tokenId)
+	minted, err = isTokenMinted(service, asset, tokenId)
+	assert.Nil(t, err)
+	assert.False(t, minted)
+}
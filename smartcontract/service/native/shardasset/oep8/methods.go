@@ -0,0 +1,199 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package oep8
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+func transfer(native *native.NativeService, asset AssetId, param *TransferParam) error {
+	if param.Amount.Sign() < 0 {
+		return fmt.Errorf("transfer: invalid amount")
+	}
+	fromBalance, err := getUserBalance(native, asset, param.TokenId, param.From)
+	if err != nil {
+		return fmt.Errorf("transfer: get from balance failed, err: %s", err)
+	}
+	if fromBalance.Cmp(param.Amount) < 0 {
+		return fmt.Errorf("transfer: from balance not enough")
+	}
+	fromBalance.Sub(fromBalance, param.Amount)
+	setUserBalance(native, asset, param.TokenId, param.From, fromBalance)
+	toBalance, err := getUserBalance(native, asset, param.TokenId, param.To)
+	if err != nil {
+		return fmt.Errorf("transfer: get to balance failed, err: %s", err)
+	}
+	toBalance.Add(toBalance, param.Amount)
+	setUserBalance(native, asset, param.TokenId, param.To, toBalance)
+	event := &TransferEvent{AssetId: asset, From: param.From, To: param.To, TokenId: param.TokenId,
+		Amount: param.Amount}
+	NotifyEvent(native, event.ToNotify())
+	return nil
+}
+
+func userBurn(native *native.NativeService, asset AssetId, tokenId *big.Int, user common.Address,
+	amount *big.Int) error {
+	if amount.Sign() < 0 {
+		return fmt.Errorf("userBurn: invalid amount")
+	}
+	totalSupply, err := getTotalSupply(native, asset, tokenId)
+	if err != nil {
+		return fmt.Errorf("userBurn: failed, err: %s", err)
+	}
+	if totalSupply.Cmp(amount) < 0 {
+		return fmt.Errorf("userBurn: total supply not enough")
+	}
+	totalSupply.Sub(totalSupply, amount)
+	setTotalSupply(native, asset, tokenId, totalSupply)
+	balance, err := getUserBalance(native, asset, tokenId, user)
+	if err != nil {
+		return fmt.Errorf("userBurn: failed, err: %s", err)
+	}
+	if balance.Cmp(amount) < 0 {
+		return fmt.Errorf("userBurn: from balance not enough")
+	}
+	balance.Sub(balance, amount)
+	setUserBalance(native, asset, tokenId, user, balance)
+	return nil
+}
+
+func userMint(native *native.NativeService, asset AssetId, tokenId *big.Int, user common.Address,
+	amount *big.Int) error {
+	if amount.Sign() < 0 {
+		return fmt.Errorf("userMint: invalid amount")
+	}
+	totalSupply, err := getTotalSupply(native, asset, tokenId)
+	if err != nil {
+		return fmt.Errorf("userMint: failed, err: %s", err)
+	}
+	totalSupply.Add(totalSupply, amount)
+	setTotalSupply(native, asset, tokenId, totalSupply)
+	balance, err := getUserBalance(native, asset, tokenId, user)
+	if err != nil {
+		return fmt.Errorf("userMint: failed, err: %s", err)
+	}
+	balance.Add(balance, amount)
+	setUserBalance(native, asset, tokenId, user, balance)
+	return nil
+}
+
+func xShardTransfer(native *native.NativeService, asset AssetId, from, to common.Address, toShard common.ShardID,
+	tokenId, amount *big.Int) (*big.Int, error) {
+	transferNum, err := getXShardTransferNum(native, asset, from)
+	if err != nil {
+		return nil, fmt.Errorf("xShardTransfer: failed, err: %s", err)
+	}
+	transferNum.Add(transferNum, big.NewInt(1))
+	transfer := &XShardTransferState{
+		Id:        transferNum,
+		ToShard:   toShard,
+		ToAccount: to,
+		TokenId:   tokenId,
+		Amount:    amount,
+		Status:    XSHARD_TRANSFER_PENDING,
+	}
+	setXShardTransfer(native, asset, from, transferNum, transfer)
+	setXShardTransferNum(native, asset, from, transferNum)
+	return transferNum, nil
+}
+
+// change root supply of token while mint or burn at root
+func changeRootSupply(native *native.NativeService, asset AssetId, tokenId *big.Int, delta *big.Int) error {
+	supplyInfo, err := getShardSupplyInfo(native, asset, tokenId)
+	if err != nil {
+		return fmt.Errorf("changeRootSupply: failed, err: %s", err)
+	}
+	rootSupply, ok := supplyInfo[native.ShardID]
+	if !ok {
+		rootSupply = new(big.Int)
+		supplyInfo[native.ShardID] = rootSupply
+	}
+	rootSupply.Add(rootSupply, delta)
+	if rootSupply.Sign() < 0 {
+		return fmt.Errorf("changeRootSupply: root supply not enough")
+	}
+	setShardSupplyInfo(native, asset, tokenId, supplyInfo)
+	return nil
+}
+
+// move amount of token from fromShard to toShard in supply info, only used at root
+func moveShardSupply(native *native.NativeService, asset AssetId, tokenId *big.Int, fromShard, toShard common.ShardID,
+	amount *big.Int) error {
+	supplyInfo, err := getShardSupplyInfo(native, asset, tokenId)
+	if err != nil {
+		return fmt.Errorf("moveShardSupply: failed, err: %s", err)
+	}
+	fromSupply, ok := supplyInfo[fromShard]
+	if !ok {
+		return fmt.Errorf("moveShardSupply: shard %d supply not exist", fromShard.ToUint64())
+	}
+	if fromSupply.Cmp(amount) < 0 {
+		return fmt.Errorf("moveShardSupply: shard %d supply not enough", fromShard.ToUint64())
+	}
+	fromSupply.Sub(fromSupply, amount)
+	if toSupply, ok := supplyInfo[toShard]; ok {
+		toSupply.Add(toSupply, amount)
+	} else {
+		supplyInfo[toShard] = new(big.Int).Set(amount)
+	}
+	setShardSupplyInfo(native, asset, tokenId, supplyInfo)
+	return nil
+}
+
+func notifyShardMint(native *native.NativeService, toShard common.ShardID, param *ShardMintParam) error {
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		return fmt.Errorf("notifyShardMint: failed, err: %s", err)
+	}
+	native.NotifyRemoteShard(toShard, utils.ShardAssetAddress, native.ContextRef.GetRemainGas(), XSHARD_RECEIVE_ASSET, bf.Bytes())
+	return nil
+}
+
+func notifyTransferSuccess(native *native.NativeService, toShard common.ShardID, param *ShardMintParam) error {
+	event := &XShardReceiveEvent{
+		TransferEvent: &TransferEvent{
+			AssetId: AssetId(param.Asset),
+			From:    param.FromAccount,
+			To:      param.Account,
+			TokenId: param.TokenId,
+			Amount:  param.Amount,
+		},
+		TransferId: param.TransferId,
+		FromShard:  param.FromShard,
+	}
+	NotifyEvent(native, event.ToNotify())
+
+	tranSuccParam := &XShardTranSuccParam{
+		Asset:      param.Asset,
+		Account:    param.FromAccount,
+		TransferId: param.TransferId,
+	}
+	bf := new(bytes.Buffer)
+	if err := tranSuccParam.Serialize(bf); err != nil {
+		return fmt.Errorf("notifyTransferSuccess: failed, err: %s", err)
+	}
+	native.NotifyRemoteShard(toShard, utils.ShardAssetAddress, native.ContextRef.GetRemainGas(), XSHARD_TRANSFER_SUCC, bf.Bytes())
+	return nil
+}
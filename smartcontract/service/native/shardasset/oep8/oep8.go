@@ -0,0 +1,662 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package oep8
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/serialization"
+	scomm "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+const (
+	REGISTER = "oep8Register"
+	MIGRATE  = "oep8Migrate"
+	ASSET_ID = "oep8AssetId"
+
+	TOTAL_SUPPLY   = "oep8TotalSupply" // query total supply of token, if invoked at shard, there are no value
+	SHARD_SUPPLY   = "oep8ShardSupply" // query shard supply of token at root
+	WHOLE_SUPPLY   = "oep8WholeSupply" // sum supply of token at all shard, only can be invoked at root
+	SUPPLY_INFO    = "oep8SupplyInfo"  // query every shard supply of token at root
+	BALANCE_OF     = "oep8BalanceOf"
+	TRANSFER       = "oep8Transfer"
+	TRANSFER_MULTI = "oep8TransferMulti"
+	APPROVE        = "oep8Approve"
+	TRANSFER_FROM  = "oep8TransferFrom"
+	ALLOWANCE      = "oep8Allowance"
+	MINT           = "oep8Mint"
+	BURN           = "oep8Burn"
+
+	XSHARD_TRANSFER       = "oep8XShardTransfer"
+	XSHARD_TRANFSER_RETRY = "oep8XShardTransferRetry"
+	XSHARD_TRANSFER_SUCC  = "oep8XShardTransferSuccess"
+	XSHARD_RECEIVE_ASSET  = "oep8ShardReceive"
+
+	GET_PENDING_TRANSFER = "getOep8PendingTransfer"
+	GET_TRANSFER         = "getOep8Transfer"
+)
+
+func RegisterOEP8(native *native.NativeService) {
+	native.Register(REGISTER, Register)
+	native.Register(ASSET_ID, GetAssetId)
+	native.Register(MIGRATE, Migrate)
+
+	native.Register(TOTAL_SUPPLY, TotalSupply)
+	native.Register(SHARD_SUPPLY, ShardSupply)
+	native.Register(WHOLE_SUPPLY, WholeSupply)
+	native.Register(SUPPLY_INFO, GetSupplyInfo)
+	native.Register(BALANCE_OF, BalanceOf)
+	native.Register(TRANSFER, Transfer)
+	native.Register(TRANSFER_MULTI, TransferMulti)
+	native.Register(APPROVE, Approve)
+	native.Register(TRANSFER_FROM, TransferFrom)
+	native.Register(ALLOWANCE, Allowance)
+	native.Register(MINT, Mint)
+	native.Register(BURN, Burn)
+
+	native.Register(XSHARD_TRANSFER, XShardTransfer)
+	native.Register(XSHARD_TRANFSER_RETRY, XShardTransferRetry)
+	native.Register(XSHARD_RECEIVE_ASSET, ShardReceiveAsset)
+	native.Register(XSHARD_TRANSFER_SUCC, XShardTransferSucc)
+
+	native.Register(GET_PENDING_TRANSFER, GetPendingXShardTransfer)
+	native.Register(GET_TRANSFER, GetXShardTransferState)
+}
+
+// assetId start form 1, init supply of every token is transferred to param account
+func Register(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("Register: only can be invoked at root")
+	}
+	param := &RegisterParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Register: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	isReg, err := isAssetRegister(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Register: failed, err: %s", err)
+	}
+	if isReg {
+		return utils.BYTE_FALSE, fmt.Errorf("Register: failed, asset has already registered")
+	}
+	assetNum, err := getAssetNum(native)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Register: failed, err: %s", err)
+	}
+	if assetNum == math.MaxUint64 {
+		return utils.BYTE_FALSE, fmt.Errorf("Register: failed, asset num exceed")
+	}
+	setAssetNum(native, assetNum+1)
+	assetId := AssetId(assetNum + 1)
+	registerAsset(native, callAddr, assetId)
+	for index, token := range param.Tokens {
+		if err := userMint(native, assetId, token.TokenId, param.Account, token.Supply); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("Register: failed, index %d, err: %s", index, err)
+		}
+		if err := changeRootSupply(native, assetId, token.TokenId, token.Supply); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("Register: failed, index %d, err: %s", index, err)
+		}
+		transferEvent := &TransferEvent{
+			AssetId: assetId,
+			From:    common.ADDRESS_EMPTY,
+			To:      param.Account,
+			TokenId: token.TokenId,
+			Amount:  token.Supply,
+		}
+		NotifyEvent(native, transferEvent.ToNotify())
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+func GetAssetId(native *native.NativeService) ([]byte, error) {
+	addr, err := utils.ReadAddress(bytes.NewReader(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetAssetId: read param addr failed, err: %s", err)
+	}
+	assetId, err := getAssetId(native, addr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetAssetId: failed, err: %s", err)
+	}
+	return common.BigIntToNeoBytes(new(big.Int).SetUint64(uint64(assetId))), nil
+}
+
+func Migrate(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("Migrate: only can be invoked at root")
+	}
+	param := &MigrateParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Migrate: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	assetId, err := getAssetId(native, callAddr)
+	if err == scomm.ErrNotFound {
+		return utils.BYTE_FALSE, fmt.Errorf("Migrate: failed, asset has not registered")
+	} else if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Migrate: failed, err: %s", err)
+	}
+	isReg, err := isAssetRegister(native, param.NewAsset)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Migrate: failed, err: %s", err)
+	}
+	if isReg {
+		return utils.BYTE_FALSE, fmt.Errorf("Migrate: failed, new asset has already registered")
+	}
+	deleteAssetId(native, callAddr)
+	registerAsset(native, param.NewAsset, assetId)
+	return utils.BYTE_TRUE, nil
+}
+
+func TotalSupply(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("TotalSupply: only can be invoked at root")
+	}
+	param := &TokenParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TotalSupply: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TotalSupply: failed, err: %s", err)
+	}
+	supply, err := getTotalSupply(native, asset, param.TokenId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TotalSupply: failed, err: %s", err)
+	}
+	return common.BigIntToNeoBytes(supply), nil
+}
+
+func ShardSupply(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardSupply: only can be invoked at root")
+	}
+	param := &ShardSupplyParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardSupply: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardSupply: failed, err: %s", err)
+	}
+	shardSupplyInfo, err := getShardSupplyInfo(native, asset, param.TokenId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardSupply: failed, err: %s", err)
+	}
+	if supply, ok := shardSupplyInfo[param.ShardId]; ok {
+		return common.BigIntToNeoBytes(supply), nil
+	}
+	return common.BigIntToNeoBytes(big.NewInt(0)), nil
+}
+
+func WholeSupply(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("WholeSupply: only can be invoked at root")
+	}
+	param := &TokenParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("WholeSupply: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("WholeSupply: failed, err: %s", err)
+	}
+	shardSupplyInfo, err := getShardSupplyInfo(native, asset, param.TokenId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("WholeSupply: failed, err: %s", err)
+	}
+	whole := new(big.Int)
+	for _, supply := range shardSupplyInfo {
+		whole.Add(whole, supply)
+	}
+	return common.BigIntToNeoBytes(whole), nil
+}
+
+func GetSupplyInfo(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("GetSupplyInfo: only can be invoked at root")
+	}
+	param := &SupplyInfoParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetSupplyInfo: read param failed, err: %s", err)
+	}
+	supplyInfo, err := getShardSupplyInfo(native, AssetId(param.Asset), param.TokenId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetSupplyInfo: failed, err: %s", err)
+	}
+	jsonSupply := make(map[uint64]string)
+	for shard, supply := range supplyInfo {
+		jsonSupply[shard.ToUint64()] = supply.String()
+	}
+	data, err := json.Marshal(jsonSupply)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetSupplyInfo: marshal supply info failed, err: %s", err)
+	}
+	return data, nil
+}
+
+func BalanceOf(native *native.NativeService) ([]byte, error) {
+	param := &BalanceParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("BalanceOf: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("BalanceOf: failed, err: %s", err)
+	}
+	userBalance, err := getUserBalance(native, asset, param.TokenId, param.User)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("BalanceOf: failed, err: %s", err)
+	}
+	return common.BigIntToNeoBytes(userBalance), nil
+}
+
+func Transfer(native *native.NativeService) ([]byte, error) {
+	param := &TransferParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Transfer: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Transfer: failed, err: %s", err)
+	}
+	if err := utils.ValidateOwner(native, param.From); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Transfer: check witness err: %s", err)
+	}
+	if err := transfer(native, asset, param); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Transfer: failed, err: %s", err)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+func TransferMulti(native *native.NativeService) ([]byte, error) {
+	param := &MultiTransferParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferMulti: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferMulti: failed, err: %s", err)
+	}
+	for index, tranParam := range param.Transfers {
+		if err := utils.ValidateOwner(native, tranParam.From); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("TransferMulti: check witness failed, index %d, err: %s", index, err)
+		}
+		if err := transfer(native, asset, tranParam); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("TransferMulti: failed, index %d, err: %s", index, err)
+		}
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+func Approve(native *native.NativeService) ([]byte, error) {
+	param := &ApproveParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Approve: failed, err: %s", err)
+	}
+	if err := utils.ValidateOwner(native, param.Owner); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Approve: check witness err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Approve: failed, err: %s", err)
+	}
+	balance, err := getUserBalance(native, asset, param.TokenId, param.Owner)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Approve: failed, err: %s", err)
+	}
+	if balance.Cmp(param.Allowance) < 0 {
+		return utils.BYTE_FALSE, fmt.Errorf("Approve: owner balance not enough")
+	}
+	setUserAllowance(native, asset, param.TokenId, param.Owner, param.Spender, param.Allowance)
+	event := &ApproveEvent{AssetId: asset, Owner: param.Owner, Spender: param.Spender, TokenId: param.TokenId,
+		Allowance: param.Allowance}
+	NotifyEvent(native, event.ToNotify())
+	return utils.BYTE_TRUE, nil
+}
+
+func TransferFrom(native *native.NativeService) ([]byte, error) {
+	param := &TransferFromParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferFrom: failed, err: %s", err)
+	}
+	if err := utils.ValidateOwner(native, param.Spender); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferFrom: check witness err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferFrom: failed, err: %s", err)
+	}
+	allowance, err := getUserAllowance(native, asset, param.TokenId, param.From, param.Spender)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferFrom: failed, err: %s", err)
+	}
+	if allowance.Cmp(param.Amount) < 0 {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferFrom: allowance not enough")
+	}
+	allowance.Sub(allowance, param.Amount)
+	setUserAllowance(native, asset, param.TokenId, param.From, param.Spender, allowance)
+	tranParam := &TransferParam{From: param.From, To: param.To, TokenId: param.TokenId, Amount: param.Amount}
+	if err := transfer(native, asset, tranParam); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("TransferFrom: failed, err: %s", err)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+func Allowance(native *native.NativeService) ([]byte, error) {
+	param := &AllowanceParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Allowance: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Allowance: failed, err: %s", err)
+	}
+	allowance, err := getUserAllowance(native, asset, param.TokenId, param.Owner, param.Spender)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Allowance: failed, err: %s", err)
+	}
+	return common.BigIntToNeoBytes(allowance), nil
+}
+
+// user should check witness before call this function
+func Mint(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("Mint: only can be invoked at root")
+	}
+	param := &MintParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Mint: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Mint: failed, err: %s", err)
+	}
+	if err = userMint(native, asset, param.TokenId, param.User, param.Amount); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Mint: failed, err: %s", err)
+	}
+	if err = changeRootSupply(native, asset, param.TokenId, param.Amount); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Mint: failed, err: %s", err)
+	}
+	mintEvent := &MintEvent{AssetId: asset, User: param.User, TokenId: param.TokenId, Amount: param.Amount}
+	NotifyEvent(native, mintEvent.ToNotify())
+	transferEvent := &TransferEvent{AssetId: asset, From: common.ADDRESS_EMPTY, To: param.User,
+		TokenId: param.TokenId, Amount: param.Amount}
+	NotifyEvent(native, transferEvent.ToNotify())
+	return utils.BYTE_TRUE, nil
+}
+
+func Burn(native *native.NativeService) ([]byte, error) {
+	if !native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("Burn: only can be invoked at root")
+	}
+	param := &BurnParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Burn: failed, err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Burn: failed, err: %s", err)
+	}
+	if err = utils.ValidateOwner(native, param.User); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Burn: check witness err: %s", err)
+	}
+	if err = userBurn(native, asset, param.TokenId, param.User, param.Amount); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Burn: failed, err: %s", err)
+	}
+	if err = changeRootSupply(native, asset, param.TokenId, new(big.Int).Neg(param.Amount)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("Burn: failed, err: %s", err)
+	}
+	burnEvent := &BurnEvent{AssetId: asset, User: param.User, TokenId: param.TokenId, Amount: param.Amount}
+	NotifyEvent(native, burnEvent.ToNotify())
+	transferEvent := &TransferEvent{AssetId: asset, From: param.User, To: common.ADDRESS_EMPTY,
+		TokenId: param.TokenId, Amount: param.Amount}
+	NotifyEvent(native, transferEvent.ToNotify())
+	return utils.BYTE_TRUE, nil
+}
+
+func XShardTransfer(native *native.NativeService) ([]byte, error) {
+	param := &XShardTransferParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: failed, err: %s", err)
+	}
+	// check shard id
+	if native.ShardID.ToUint64() == param.ToShard.ToUint64() {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: unsupport transfer in same shard")
+	}
+	if !native.ShardID.IsRootShard() && !param.ToShard.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: unsupport transfer between shard")
+	}
+	if err := utils.ValidateOwner(native, param.From); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: check witness err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: failed, err: %s", err)
+	}
+	if err := userBurn(native, asset, param.TokenId, param.From, param.Amount); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: failed, err: %s", err)
+	}
+	txId, err := xShardTransfer(native, asset, param.From, param.To, param.ToShard, param.TokenId, param.Amount)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: failed, err: %s", err)
+	}
+	shardMintParam := &ShardMintParam{
+		OriginalContract: callAddr,
+		Asset:            uint64(asset),
+		Account:          param.To,
+		FromShard:        native.ShardID,
+		FromAccount:      param.From,
+		TransferId:       txId,
+		TokenId:          param.TokenId,
+		Amount:           param.Amount,
+	}
+	if err := notifyShardMint(native, param.ToShard, shardMintParam); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransfer: failed, err: %s", err)
+	}
+	return common.BigIntToNeoBytes(txId), nil
+}
+
+func XShardTransferRetry(native *native.NativeService) ([]byte, error) {
+	param := &XShardTransferRetryParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferRetry: failed, err: %s", err)
+	}
+	if err := utils.ValidateOwner(native, param.From); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferRetry: check witness err: %s", err)
+	}
+	callAddr := native.ContextRef.CallingContext().ContractAddress
+	asset, err := getAssetId(native, callAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferRetry: failed, err: %s", err)
+	}
+	transfer, err := getXShardTransfer(native, asset, param.From, param.TransferId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferRetry: failed, err: %s", err)
+	}
+	if transfer.Status == XSHARD_TRANSFER_COMPLETE {
+		return utils.BYTE_TRUE, nil
+	}
+	shardMintParam := &ShardMintParam{
+		OriginalContract: callAddr,
+		Asset:            uint64(asset),
+		Account:          transfer.ToAccount,
+		FromShard:        native.ShardID,
+		FromAccount:      param.From,
+		TransferId:       param.TransferId,
+		TokenId:          transfer.TokenId,
+		Amount:           transfer.Amount,
+	}
+	if err := notifyShardMint(native, transfer.ToShard, shardMintParam); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferRetry: failed, err: %s", err)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+func XShardTransferSucc(native *native.NativeService) ([]byte, error) {
+	data, err := serialization.ReadVarBytes(bytes.NewReader(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferSucc: read input failed, err: %s", err)
+	}
+	param := &XShardTranSuccParam{}
+	if err := param.Deserialize(bytes.NewReader(data)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferSucc: failed, err: %s", err)
+	}
+	transfer, err := getXShardTransfer(native, AssetId(param.Asset), param.Account, param.TransferId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferSucc: failed, err: %s", err)
+	}
+	if !native.ContextRef.CheckCallShard(transfer.ToShard) {
+		return utils.BYTE_FALSE, fmt.Errorf("XShardTransferSucc: check call shard failed")
+	}
+	if transfer.Status == XSHARD_TRANSFER_COMPLETE {
+		// success of retried transfer, supply has been updated
+		return utils.BYTE_TRUE, nil
+	}
+	transfer.Status = XSHARD_TRANSFER_COMPLETE
+	setXShardTransfer(native, AssetId(param.Asset), param.Account, param.TransferId, transfer)
+	if native.ShardID.IsRootShard() {
+		err := moveShardSupply(native, AssetId(param.Asset), transfer.TokenId, native.ShardID, transfer.ToShard,
+			transfer.Amount)
+		if err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("XShardTransferSucc: failed, err: %s", err)
+		}
+	}
+	event := XShardTransferEvent{
+		TransferEvent: &TransferEvent{
+			AssetId: AssetId(param.Asset),
+			From:    param.Account,
+			To:      transfer.ToAccount,
+			TokenId: transfer.TokenId,
+			Amount:  transfer.Amount,
+		},
+		TransferId: param.TransferId,
+		ToShard:    transfer.ToShard,
+	}
+	NotifyEvent(native, event.ToNotify())
+	return utils.BYTE_TRUE, nil
+}
+
+func ShardReceiveAsset(native *native.NativeService) ([]byte, error) {
+	data, err := serialization.ReadVarBytes(bytes.NewReader(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: read input failed, err: %s", err)
+	}
+	param := &ShardMintParam{}
+	if err := param.Deserialize(bytes.NewReader(data)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: failed, err: %s", err)
+	}
+	if !native.ContextRef.CheckCallShard(param.FromShard) {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: check call shard failed")
+	}
+	assetId, err := getAssetId(native, param.OriginalContract)
+	if err == scomm.ErrNotFound {
+		registerAsset(native, param.OriginalContract, AssetId(param.Asset))
+	} else if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: failed, err: %s", err)
+	} else if uint64(assetId) != param.Asset {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: assetId unmatch, local %d vs param %d",
+			assetId, param.Asset)
+	}
+	isReceived, err := isTransferReceived(native, param)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: failed, err: %s", err)
+	}
+	if !isReceived {
+		if err := userMint(native, AssetId(param.Asset), param.TokenId, param.Account, param.Amount); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: failed, err: %s", err)
+		}
+		receiveTransfer(native, param)
+		if native.ShardID.IsRootShard() {
+			err := moveShardSupply(native, AssetId(param.Asset), param.TokenId, param.FromShard, native.ShardID,
+				param.Amount)
+			if err != nil {
+				return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: failed, err: %s", err)
+			}
+		}
+	}
+	if err := notifyTransferSuccess(native, param.FromShard, param); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardReceiveAsset: failed, err: %s", err)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+func GetPendingXShardTransfer(native *native.NativeService) ([]byte, error) {
+	param := &GetPendingXShardTransferParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetPendingXShardTransfer: failed, err: %s", err)
+	}
+	transferNum, err := getXShardTransferNum(native, AssetId(param.Asset), param.Account)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetPendingXShardTransfer: failed, err: %s", err)
+	}
+	increase := big.NewInt(1)
+	transfers := make([]*XShardTransferState, 0)
+	for i := big.NewInt(1); i.Cmp(transferNum) <= 0; i.Add(i, increase) {
+		transfer, err := getXShardTransfer(native, AssetId(param.Asset), param.Account, i)
+		if err != nil {
+			log.Debugf("GetPendingXShardTransfer: read transfer failed, tranId %s, err: %s", i.String(), err)
+			continue
+		}
+		if transfer.Status == XSHARD_TRANSFER_PENDING {
+			transfers = append(transfers, transfer)
+		}
+	}
+	data, err := json.Marshal(transfers)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetPendingXShardTransfer: marshal failed, err: %s", err)
+	}
+	return data, nil
+}
+
+func GetXShardTransferState(native *native.NativeService) ([]byte, error) {
+	param := &GetXShardTransferInfoParam{}
+	if err := param.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetXShardTransferState: failed, err: %s", err)
+	}
+	transfer, err := getXShardTransfer(native, AssetId(param.Asset), param.Account, param.TransferId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetXShardTransferState: failed, err: %s", err)
+	}
+	data, err := json.Marshal(transfer)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("GetXShardTransferState: marshal info failed, err: %s", err)
+	}
+	return data, nil
+}
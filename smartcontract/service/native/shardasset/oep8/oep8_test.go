@@ -0,0 +1,182 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package oep8
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/smartcontract/context"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type remoteNotify struct {
+	target common.ShardID
+	method string
+	args   []byte
+}
+
+// only implement the methods used by shard asset
+type mockContextRef struct {
+	context.ContextRef
+	contract  common.Address
+	callShard common.ShardID
+	notifies  []*remoteNotify
+}
+
+func (this *mockContextRef) CallingContext() *context.Context {
+	return &context.Context{ContractAddress: this.contract}
+}
+
+func (this *mockContextRef) CheckWitness(address common.Address) bool {
+	return true
+}
+
+func (this *mockContextRef) CheckCallShard(fromShard common.ShardID) bool {
+	return this.callShard == fromShard
+}
+
+func (this *mockContextRef) GetRemainGas() uint64 {
+	return 20000
+}
+
+func (this *mockContextRef) NotifyRemoteShard(target common.ShardID, cont common.Address, fee uint64, method string,
+	args []byte) {
+	this.notifies = append(this.notifies, &remoteNotify{target: target, method: method, args: args})
+}
+
+func (this *mockContextRef) popNotify() *remoteNotify {
+	if len(this.notifies) == 0 {
+		return nil
+	}
+	notify := this.notifies[len(this.notifies)-1]
+	this.notifies = this.notifies[:len(this.notifies)-1]
+	return notify
+}
+
+func newNativeService(shardId common.ShardID, contract common.Address) (*native.NativeService, *mockContextRef) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	ctx := &mockContextRef{contract: contract}
+	service := &native.NativeService{
+		CacheDB:    storage.NewCacheDB(overlaydb.NewOverlayDB(memback)),
+		ShardID:    shardId,
+		ContextRef: ctx,
+	}
+	return service, ctx
+}
+
+// remote shard receives notify args as var bytes
+func remoteInput(args []byte) []byte {
+	bf := new(bytes.Buffer)
+	serialization.WriteVarBytes(bf, args)
+	return bf.Bytes()
+}
+
+func TestXShardTransferFlow(t *testing.T) {
+	contract := common.Address{0xaa}
+	from, to, tokenId := common.Address{1}, common.Address{2}, big.NewInt(7)
+	shard1 := common.NewShardIDUnchecked(1)
+	root, rootCtx := newNativeService(common.RootShardID, contract)
+	shard, shardCtx := newNativeService(shard1, contract)
+
+	bf := new(bytes.Buffer)
+	regParam := &RegisterParam{Account: from, Tokens: []*TokenSupply{{TokenId: tokenId, Supply: big.NewInt(100)}}}
+	assert.Nil(t, regParam.Serialize(bf))
+	root.Input = bf.Bytes()
+	_, err := Register(root)
+	assert.Nil(t, err)
+	asset, err := getAssetId(root, contract)
+	assert.Nil(t, err)
+
+	// transfer part of token from root to shard
+	bf = new(bytes.Buffer)
+	xParam := &XShardTransferParam{From: from, To: to, ToShard: shard1, TokenId: tokenId, Amount: big.NewInt(40)}
+	assert.Nil(t, xParam.Serialize(bf))
+	root.Input = bf.Bytes()
+	txId, err := XShardTransfer(root)
+	assert.Nil(t, err)
+	transferId := common.BigIntFromNeoBytes(txId)
+	balance, err := getUserBalance(root, asset, tokenId, from)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(60), balance.Uint64())
+	transfer, err := getXShardTransfer(root, asset, from, transferId)
+	assert.Nil(t, err)
+	assert.Equal(t, XSHARD_TRANSFER_PENDING, transfer.Status)
+	mintNotify := rootCtx.popNotify()
+	assert.NotNil(t, mintNotify)
+	assert.Equal(t, shard1, mintNotify.target)
+	assert.Equal(t, XSHARD_RECEIVE_ASSET, mintNotify.method)
+
+	// receive at shard, only the notify from source shard is accepted
+	shard.Input = remoteInput(mintNotify.args)
+	shardCtx.callShard = common.NewShardIDUnchecked(2)
+	_, err = ShardReceiveAsset(shard)
+	assert.NotNil(t, err)
+	shardCtx.callShard = common.RootShardID
+	_, err = ShardReceiveAsset(shard)
+	assert.Nil(t, err)
+	balance, err = getUserBalance(shard, asset, tokenId, to)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(40), balance.Uint64())
+	succNotify := shardCtx.popNotify()
+	assert.NotNil(t, succNotify)
+	assert.Equal(t, common.RootShardID, succNotify.target)
+	assert.Equal(t, XSHARD_TRANSFER_SUCC, succNotify.method)
+
+	// duplicate receive of retried transfer doesn't mint again, but still notify success
+	_, err = ShardReceiveAsset(shard)
+	assert.Nil(t, err)
+	balance, err = getUserBalance(shard, asset, tokenId, to)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(40), balance.Uint64())
+	dupSuccNotify := shardCtx.popNotify()
+	assert.NotNil(t, dupSuccNotify)
+	assert.Equal(t, succNotify.args, dupSuccNotify.args)
+
+	// transfer success at root moves supply to shard
+	root.Input = remoteInput(succNotify.args)
+	_, err = XShardTransferSucc(root)
+	assert.NotNil(t, err)
+	rootCtx.callShard = shard1
+	_, err = XShardTransferSucc(root)
+	assert.Nil(t, err)
+	transfer, err = getXShardTransfer(root, asset, from, transferId)
+	assert.Nil(t, err)
+	assert.Equal(t, XSHARD_TRANSFER_COMPLETE, transfer.Status)
+	supplyInfo, err := getShardSupplyInfo(root, asset, tokenId)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(60), supplyInfo[common.RootShardID].Uint64())
+	assert.Equal(t, uint64(40), supplyInfo[shard1].Uint64())
+
+	// duplicate success doesn't move supply again
+	root.Input = remoteInput(dupSuccNotify.args)
+	_, err = XShardTransferSucc(root)
+	assert.Nil(t, err)
+	supplyInfo, err = getShardSupplyInfo(root, asset, tokenId)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(60), supplyInfo[common.RootShardID].Uint64())
+	assert.Equal(t, uint64(40), supplyInfo[shard1].Uint64())
+}
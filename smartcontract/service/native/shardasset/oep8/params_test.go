@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package oep8
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterParam(t *testing.T) {
+	acc := account.NewAccount("")
+	param := &RegisterParam{
+		Account: acc.Address,
+		Tokens: []*TokenSupply{
+			{TokenId: big.NewInt(1), Supply: big.NewInt(1000000)},
+			{TokenId: big.NewInt(2), Supply: big.NewInt(1)},
+		},
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &RegisterParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
+func TestTransferFromParam(t *testing.T) {
+	acc := account.NewAccount("")
+	param := &TransferFromParam{
+		Spender: acc.Address,
+		From:    acc.Address,
+		To:      acc.Address,
+		TokenId: big.NewInt(3),
+		Amount:  big.NewInt(2000),
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &TransferFromParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
+
+func TestShardMintParam(t *testing.T) {
+	acc := account.NewAccount("")
+	param := &ShardMintParam{
+		OriginalContract: acc.Address,
+		Asset:            3,
+		Account:          acc.Address,
+		FromShard:        common.NewShardIDUnchecked(2),
+		FromAccount:      acc.Address,
+		TransferId:       big.NewInt(5),
+		TokenId:          big.NewInt(7),
+		Amount:           big.NewInt(1000),
+	}
+	bf := new(bytes.Buffer)
+	err := param.Serialize(bf)
+	assert.Nil(t, err)
+	newParam := &ShardMintParam{}
+	err = newParam.Deserialize(bf)
+	assert.Nil(t, err)
+	assert.Equal(t, param, newParam)
+}
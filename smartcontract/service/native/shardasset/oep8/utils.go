@@ -0,0 +1,344 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package oep8
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/states"
+	scomm "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+const (
+	KEY_OEP8_ASSET_NUM = "oep8_asset_num"
+	KEY_OEP8_ASSET_ID  = "oep8_asset_id"
+
+	KEY_OEP8_TOTAL_SUPPLY    = "oep8_total_supply"
+	KEY_OEP8_BALANCE         = "oep8_balance"
+	KEY_OEP8_SHARD_SUPPLY    = "oep8_shard_supply" // token distribute shard
+	KEY_OEP8_ALLOWANCE       = "oep8_allowance"
+	KEY_OEP8_TRANSFER_NUM    = "oep8_transfer_num"
+	KEY_OEP8_XSHARD_TRANSFER = "oep8_xshard_transfer"
+	KEY_OEP8_XSHARD_RECEIVE  = "oep8_xshard_receive"
+)
+
+func genAssetNumKey() []byte {
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_ASSET_NUM))
+}
+
+func genAssetIdKey(assetAddr common.Address) []byte {
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_ASSET_ID), assetAddr[:])
+}
+
+// token id is var bytes so that keys of different tokens do not prefix each other
+func tokenIdBytes(tokenId *big.Int) []byte {
+	sink := common.NewZeroCopySink(0)
+	sink.WriteVarBytes(common.BigIntToNeoBytes(tokenId))
+	return sink.Bytes()
+}
+
+func genTotalSupplyKey(asset AssetId, tokenId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_TOTAL_SUPPLY), assetBytes, tokenIdBytes(tokenId))
+}
+
+func genBalanceKey(asset AssetId, tokenId *big.Int, user common.Address) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_BALANCE), assetBytes, tokenIdBytes(tokenId),
+		user[:])
+}
+
+func genShardSupplyInfoKey(asset AssetId, tokenId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_SHARD_SUPPLY), assetBytes, tokenIdBytes(tokenId))
+}
+
+func genAllowanceKey(asset AssetId, tokenId *big.Int, owner, spender common.Address) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_ALLOWANCE), assetBytes, tokenIdBytes(tokenId),
+		owner[:], spender[:])
+}
+
+func genXShardTransferNumKey(asset AssetId, user common.Address) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_TRANSFER_NUM), assetBytes, user[:])
+}
+
+func genXShardTransferKey(asset AssetId, user common.Address, transferId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_XSHARD_TRANSFER), assetBytes, user[:],
+		common.BigIntToNeoBytes(transferId))
+}
+
+func genXShardReceiveKey(asset AssetId, user common.Address, fromShard common.ShardID, transferId *big.Int) []byte {
+	assetBytes := utils.GetUint64Bytes(uint64(asset))
+	shardIdBytes := utils.GetUint64Bytes(fromShard.ToUint64())
+	return utils.ConcatKey(utils.ShardAssetAddress, []byte(KEY_OEP8_XSHARD_RECEIVE), assetBytes, shardIdBytes, user[:],
+		common.BigIntToNeoBytes(transferId))
+}
+
+func getBigInt(native *native.NativeService, key []byte) (*big.Int, error) {
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return big.NewInt(0), nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse store value failed, err: %s", err)
+	}
+	return common.BigIntFromNeoBytes(storeValue), nil
+}
+
+func setBigInt(native *native.NativeService, key []byte, value *big.Int) {
+	native.CacheDB.Put(key, states.GenRawStorageItem(common.BigIntToNeoBytes(value)))
+}
+
+func setTotalSupply(native *native.NativeService, asset AssetId, tokenId *big.Int, supply *big.Int) {
+	setBigInt(native, genTotalSupplyKey(asset, tokenId), supply)
+}
+
+func getTotalSupply(native *native.NativeService, asset AssetId, tokenId *big.Int) (*big.Int, error) {
+	supply, err := getBigInt(native, genTotalSupplyKey(asset, tokenId))
+	if err != nil {
+		return nil, fmt.Errorf("getTotalSupply: %s", err)
+	}
+	return supply, nil
+}
+
+func setUserBalance(native *native.NativeService, asset AssetId, tokenId *big.Int, user common.Address,
+	balance *big.Int) {
+	setBigInt(native, genBalanceKey(asset, tokenId, user), balance)
+}
+
+func getUserBalance(native *native.NativeService, asset AssetId, tokenId *big.Int,
+	user common.Address) (*big.Int, error) {
+	balance, err := getBigInt(native, genBalanceKey(asset, tokenId, user))
+	if err != nil {
+		return nil, fmt.Errorf("getUserBalance: %s", err)
+	}
+	return balance, nil
+}
+
+func setUserAllowance(native *native.NativeService, asset AssetId, tokenId *big.Int, owner, spender common.Address,
+	allowance *big.Int) {
+	setBigInt(native, genAllowanceKey(asset, tokenId, owner, spender), allowance)
+}
+
+func getUserAllowance(native *native.NativeService, asset AssetId, tokenId *big.Int,
+	owner, spender common.Address) (*big.Int, error) {
+	allowance, err := getBigInt(native, genAllowanceKey(asset, tokenId, owner, spender))
+	if err != nil {
+		return nil, fmt.Errorf("getUserAllowance: %s", err)
+	}
+	return allowance, nil
+}
+
+func setXShardTransferNum(native *native.NativeService, asset AssetId, user common.Address, num *big.Int) {
+	setBigInt(native, genXShardTransferNumKey(asset, user), num)
+}
+
+func getXShardTransferNum(native *native.NativeService, asset AssetId, user common.Address) (*big.Int, error) {
+	num, err := getBigInt(native, genXShardTransferNumKey(asset, user))
+	if err != nil {
+		return nil, fmt.Errorf("getXShardTransferNum: %s", err)
+	}
+	return num, nil
+}
+
+func setXShardTransfer(native *native.NativeService, asset AssetId, user common.Address, transferId *big.Int,
+	transfer *XShardTransferState) {
+	key := genXShardTransferKey(asset, user, transferId)
+	sink := common.NewZeroCopySink(0)
+	transfer.Serialization(sink)
+	native.CacheDB.Put(key, states.GenRawStorageItem(sink.Bytes()))
+}
+
+func getXShardTransfer(native *native.NativeService, asset AssetId, user common.Address,
+	transferId *big.Int) (*XShardTransferState, error) {
+	key := genXShardTransferKey(asset, user, transferId)
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("getXShardTransfer: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("getXShardTransfer: transfer not exist")
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return nil, fmt.Errorf("getXShardTransfer: parse store value failed, err: %s", err)
+	}
+	state := &XShardTransferState{}
+	if err := state.Deserialization(common.NewZeroCopySource(storeValue)); err != nil {
+		return nil, fmt.Errorf("getXShardTransfer: deserialize failed, err: %s", err)
+	}
+	return state, nil
+}
+
+func receiveTransfer(native *native.NativeService, param *ShardMintParam) {
+	key := genXShardReceiveKey(AssetId(param.Asset), param.FromAccount, param.FromShard, param.TransferId)
+	sink := common.NewZeroCopySink(0)
+	sink.WriteBool(true)
+	native.CacheDB.Put(key, states.GenRawStorageItem(sink.Bytes()))
+}
+
+func isTransferReceived(native *native.NativeService, param *ShardMintParam) (bool, error) {
+	key := genXShardReceiveKey(AssetId(param.Asset), param.FromAccount, param.FromShard, param.TransferId)
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("isTransferReceived: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return false, nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return false, fmt.Errorf("isTransferReceived: parse store value failed, err: %s", err)
+	}
+	source := common.NewZeroCopySource(storeValue)
+	isReceived, irr, eof := source.NextBool()
+	if irr {
+		return false, fmt.Errorf("isTransferReceived: deserialize store value, err: %s", common.ErrIrregularData)
+	}
+	if eof {
+		return false, fmt.Errorf("isTransferReceived: deserialize store value, err: %s", io.ErrUnexpectedEOF)
+	}
+	return isReceived, nil
+}
+
+func setAssetNum(native *native.NativeService, num uint64) {
+	native.CacheDB.Put(genAssetNumKey(), states.GenRawStorageItem(utils.GetUint64Bytes(num)))
+}
+
+func getAssetNum(native *native.NativeService) (uint64, error) {
+	raw, err := native.CacheDB.Get(genAssetNumKey())
+	if err != nil {
+		return 0, fmt.Errorf("getAssetNum: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetNum: parse store value failed, err: %s", err)
+	}
+	num, err := utils.GetBytesUint64(storeValue)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetNum: deserialize store value failed, err: %s", err)
+	}
+	return num, nil
+}
+
+func registerAsset(native *native.NativeService, assetAddr common.Address, assetId AssetId) {
+	native.CacheDB.Put(genAssetIdKey(assetAddr), states.GenRawStorageItem(utils.GetUint64Bytes(uint64(assetId))))
+}
+
+func getAssetId(native *native.NativeService, assetAddr common.Address) (AssetId, error) {
+	raw, err := native.CacheDB.Get(genAssetIdKey(assetAddr))
+	if err != nil {
+		return 0, fmt.Errorf("getAssetId: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return 0, scomm.ErrNotFound
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetId: parse store value failed, err: %s", err)
+	}
+	id, err := utils.GetBytesUint64(storeValue)
+	if err != nil {
+		return 0, fmt.Errorf("getAssetId: deserialize store value failed, err: %s", err)
+	}
+	return AssetId(id), nil
+}
+
+func isAssetRegister(native *native.NativeService, assetAddr common.Address) (bool, error) {
+	raw, err := native.CacheDB.Get(genAssetIdKey(assetAddr))
+	if err != nil {
+		return false, fmt.Errorf("isAssetRegister: read db failed, err: %s", err)
+	}
+	return len(raw) != 0, nil
+}
+
+func deleteAssetId(native *native.NativeService, assetAddr common.Address) {
+	native.CacheDB.Delete(genAssetIdKey(assetAddr))
+}
+
+func setShardSupplyInfo(native *native.NativeService, asset AssetId, tokenId *big.Int,
+	supplyInfo map[common.ShardID]*big.Int) {
+	key := genShardSupplyInfoKey(asset, tokenId)
+	sink := common.NewZeroCopySink(0)
+	sink.WriteUint64(uint64(len(supplyInfo)))
+	shards := make([]common.ShardID, 0, len(supplyInfo))
+	for shard := range supplyInfo {
+		shards = append(shards, shard)
+	}
+	sort.SliceStable(shards, func(i, j int) bool {
+		return shards[i].ToUint64() < shards[j].ToUint64()
+	})
+	for _, shard := range shards {
+		utils.SerializationShardId(sink, shard)
+		sink.WriteVarBytes(common.BigIntToNeoBytes(supplyInfo[shard]))
+	}
+	native.CacheDB.Put(key, states.GenRawStorageItem(sink.Bytes()))
+}
+
+func getShardSupplyInfo(native *native.NativeService, asset AssetId,
+	tokenId *big.Int) (map[common.ShardID]*big.Int, error) {
+	raw, err := native.CacheDB.Get(genShardSupplyInfoKey(asset, tokenId))
+	if err != nil {
+		return nil, fmt.Errorf("getShardSupplyInfo: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return map[common.ShardID]*big.Int{}, nil
+	}
+	storeValue, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return nil, fmt.Errorf("getShardSupplyInfo: parse store value failed, err: %s", err)
+	}
+	source := common.NewZeroCopySource(storeValue)
+	shardNum, eof := source.NextUint64()
+	if eof {
+		return nil, fmt.Errorf("getShardSupplyInfo: deserialize shard num failed, err: %s", io.ErrUnexpectedEOF)
+	}
+	shards := make(map[common.ShardID]*big.Int)
+	for i := uint64(0); i < shardNum; i++ {
+		shard, err := utils.DeserializationShardId(source)
+		if err != nil {
+			return nil, fmt.Errorf("getShardSupplyInfo: deserialize shard failed, index %d, err: %s", i, err)
+		}
+		supplyBytes, _, irr, eof := source.NextVarBytes()
+		if irr {
+			return nil, fmt.Errorf("getShardSupplyInfo: deserialize supply failed, index %d, err: %s", i, common.ErrIrregularData)
+		}
+		if eof {
+			return nil, fmt.Errorf("getShardSupplyInfo: deserialize supply failed, index %d, err: %s", i, io.ErrUnexpectedEOF)
+		}
+		shards[shard] = common.BigIntFromNeoBytes(supplyBytes)
+	}
+	return shards, nil
+}
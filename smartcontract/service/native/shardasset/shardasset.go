@@ -20,6 +20,8 @@ package shardasset
 import (
 	"github.com/ontio/ontology/smartcontract/service/native"
 	"github.com/ontio/ontology/smartcontract/service/native/shardasset/oep4"
+	"github.com/ontio/ontology/smartcontract/service/native/shardasset/oep5"
+	"github.com/ontio/ontology/smartcontract/service/native/shardasset/oep8"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 )
 
@@ -29,5 +31,6 @@ func InitShardAsset() {
 
 func RegisterShardAsset(native *native.NativeService) {
 	oep4.RegisterOEP4(native)
-	// TODO: support oep5, oep8
+	oep5.RegisterOEP5(native)
+	oep8.RegisterOEP8(native)
 }
@@ -79,3 +79,55 @@ func (this *RegisterCCParam) Deserialize(r io.Reader) error {
 	}
 	return nil
 }
+
+type FreezeCCParam struct {
+	ContractAddr common.Address
+}
+
+func (this *FreezeCCParam) Serialize(w io.Writer) error {
+	if err := utils.WriteAddress(w, this.ContractAddr); err != nil {
+		return fmt.Errorf("serialize: write contract addr failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *FreezeCCParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ContractAddr, err = utils.ReadAddress(r); err != nil {
+		return fmt.Errorf("deserialize: read contract addr failed, err: %s", err)
+	}
+	return nil
+}
+
+type MigrateCCParam struct {
+	ContractAddr    common.Address
+	NewContractAddr common.Address
+	NewShardID      common.ShardID
+}
+
+func (this *MigrateCCParam) Serialize(w io.Writer) error {
+	if err := utils.WriteAddress(w, this.ContractAddr); err != nil {
+		return fmt.Errorf("serialize: write contract addr failed, err: %s", err)
+	}
+	if err := utils.WriteAddress(w, this.NewContractAddr); err != nil {
+		return fmt.Errorf("serialize: write new contract addr failed, err: %s", err)
+	}
+	if err := utils.SerializeShardId(w, this.NewShardID); err != nil {
+		return fmt.Errorf("serialize: write new shardId failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *MigrateCCParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ContractAddr, err = utils.ReadAddress(r); err != nil {
+		return fmt.Errorf("deserialize: read contract addr failed, err: %s", err)
+	}
+	if this.NewContractAddr, err = utils.ReadAddress(r); err != nil {
+		return fmt.Errorf("deserialize: read new contract addr failed, err: %s", err)
+	}
+	if this.NewShardID, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read new shardId failed, err: %s", err)
+	}
+	return nil
+}
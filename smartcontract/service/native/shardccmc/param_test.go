@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package shardccmc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateCCParam(t *testing.T) {
+	param := &MigrateCCParam{
+		ContractAddr:    common.Address{1},
+		NewContractAddr: common.Address{2},
+		NewShardID:      common.NewShardIDUnchecked(2),
+	}
+	buf := new(bytes.Buffer)
+	assert.Nil(t, param.Serialize(buf))
+	newParam := &MigrateCCParam{}
+	assert.Nil(t, newParam.Deserialize(buf))
+	assert.Equal(t, param, newParam)
+}
@@ -30,18 +30,24 @@ import (
 
 const (
 	// function names
-	INIT_NAME        = "init"
-	CC_REGISTER_NAME = "register"
-	CC_FREEZE_NAME   = "freeze"
-	CC_MIGRATE_NAME  = "migrate"
+	INIT_NAME             = "init"
+	SET_REGISTER_FEE_NAME = "setRegisterFee"
+	CC_REGISTER_NAME      = "register"
+	CC_FREEZE_NAME        = "freeze"
+	CC_MIGRATE_NAME       = "migrate"
 
 	// Key prefix
-	KEY_VERSION     = "version"
-	KEY_CCMC_STATE  = "ccmcState"
-	KEY_CC_INFO     = "ccInfo"     // index CC with CCID
-	KEY_CC_CONTRACT = "ccContract" // index CC with contract-addr
+	KEY_VERSION       = "version"
+	KEY_CCMC_STATE    = "ccmcState"
+	KEY_CC_INFO       = "ccInfo"     // index CC with CCID
+	KEY_CC_CONTRACT   = "ccContract" // index CC with contract-addr
+	KEY_REGISTER_FEE  = "registerFee"
+	KEY_CC_DEPENDENTS = "ccDependents" // index CCIDs of dependents with contract-addr of dependency
+	KEY_CC_FROZEN     = "ccFrozen"     // contract-addr frozen by ccmc
 
 	INIT_CCID = 100
+
+	DEFAULT_REGISTER_FEE = 10 * 1000000000 // 10 ong
 )
 
 var ShardCCMCVersion = utils.VERSION_CONTRACT_SHARD_MGMT
@@ -52,7 +58,10 @@ func InitShardCCMC() {
 
 func RegisterShardCCMC(native *native.NativeService) {
 	native.Register(INIT_NAME, ShardCCMCInit)
+	native.Register(SET_REGISTER_FEE_NAME, ShardCCMCSetRegisterFee)
 	native.Register(CC_REGISTER_NAME, ShardCCMCRegister)
+	native.Register(CC_FREEZE_NAME, ShardCCMCFreeze)
+	native.Register(CC_MIGRATE_NAME, ShardCCMCMigrate)
 }
 
 func ShardCCMCInit(native *native.NativeService) ([]byte, error) {
@@ -97,6 +106,24 @@ func ShardCCMCInit(native *native.NativeService) ([]byte, error) {
 	return utils.BYTE_TRUE, nil
 }
 
+func ShardCCMCSetRegisterFee(native *native.NativeService) ([]byte, error) {
+	adminAddress, err := global_params.GetStorageRole(native,
+		global_params.GenerateOperatorKey(utils.ParamContractAddress))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("set register fee, get admin error: %v", err)
+	}
+	if err := utils.ValidateOwner(native, adminAddress); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("set register fee, checkWitness error: %v", err)
+	}
+	fee, err := utils.ReadVarUint(bytes.NewBuffer(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("set register fee, read fee failed: %s", err)
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	setRegisterFee(native, contract, fee)
+	return utils.BYTE_TRUE, nil
+}
+
 func ShardCCMCRegister(native *native.NativeService) ([]byte, error) {
 	params := RegisterCCParam{}
 	if err := params.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
@@ -119,10 +146,15 @@ func ShardCCMCRegister(native *native.NativeService) ([]byte, error) {
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("register cc, failed to load ccmc: %s", err)
 	}
-
-	// TODO: validate shardID
-	// TODO: verify dependencies
-	// TODO: transfer registration fee
+	if err := checkShardActive(native, params.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("register cc, invalid shard: %s", err)
+	}
+	if err := checkDependencies(native, contract, params.ContractAddr, params.Dependencies); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("register cc, invalid dependencies: %s", err)
+	}
+	if err := chargeRegisterFee(native, contract, params.Owner); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("register cc, charge fee failed: %s", err)
+	}
 
 	ccInfo := &ccmc_states.ShardCCInfo{
 		CCID:         ccmc.NextCCID,
@@ -130,6 +162,7 @@ func ShardCCMCRegister(native *native.NativeService) ([]byte, error) {
 		Owner:        params.Owner,
 		ContractAddr: params.ContractAddr,
 		Dependencies: params.Dependencies,
+		State:        ccmc_states.CC_STATE_ACTIVE,
 	}
 	ccmc.NextCCID += 1
 
@@ -137,6 +170,91 @@ func ShardCCMCRegister(native *native.NativeService) ([]byte, error) {
 	if err := setCCInfo(native, contract, ccInfo); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("register cc, failed to save ccinfo: %s", err)
 	}
+	for _, dep := range ccInfo.Dependencies {
+		if err := addDependent(native, contract, dep, ccInfo.CCID); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("register cc, %s", err)
+		}
+	}
+	utils.AddCommonEvent(native, contract, CC_REGISTER_NAME,
+		[]interface{}{ccInfo.CCID, ccInfo.ContractAddr.ToHexString(), ccInfo.ShardID.ToUint64()})
+	return utils.BYTE_TRUE, nil
+}
+
+// freeze registered contract, the contract cannot be depended by new contract and invoked any more
+func ShardCCMCFreeze(native *native.NativeService) ([]byte, error) {
+	params := FreezeCCParam{}
+	if err := params.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze cc, invalid input param: %s", err)
+	}
+
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	ccInfo, err := getCCInfoByContract(native, contract, params.ContractAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze cc, %s", err)
+	}
+	if err := utils.ValidateOwner(native, ccInfo.Owner); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze cc, invalid owner: %s", err)
+	}
+	if ccInfo.State == ccmc_states.CC_STATE_FROZEN {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze cc, contract %s already frozen", params.ContractAddr.ToHexString())
+	}
+
+	ccInfo.State = ccmc_states.CC_STATE_FROZEN
+	if err := setCCInfo(native, contract, ccInfo); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze cc, failed to save ccinfo: %s", err)
+	}
+	if err := freezeContractMeta(native, ccInfo.ContractAddr); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze cc, %s", err)
+	}
+	utils.AddCommonEvent(native, contract, CC_FREEZE_NAME,
+		[]interface{}{ccInfo.CCID, ccInfo.ContractAddr.ToHexString()})
+	return utils.BYTE_TRUE, nil
+}
 
-	return utils.BYTE_FALSE, nil
+// migrate registered contract to new address at new shard, the CCID is kept and the old contract is frozen
+func ShardCCMCMigrate(native *native.NativeService) ([]byte, error) {
+	params := MigrateCCParam{}
+	if err := params.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, invalid input param: %s", err)
+	}
+
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	ccInfo, err := getCCInfoByContract(native, contract, params.ContractAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, %s", err)
+	}
+	if err := utils.ValidateOwner(native, ccInfo.Owner); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, invalid owner: %s", err)
+	}
+	if ccInfo.State == ccmc_states.CC_STATE_FROZEN {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, contract %s is frozen", params.ContractAddr.ToHexString())
+	}
+	newCCID, err := getCCID(native, contract, params.NewContractAddr)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, getCCID: %s", err)
+	}
+	if newCCID != 0 {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, new contract registered with ID: %d", newCCID)
+	}
+	if err := checkShardActive(native, params.NewShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, invalid shard: %s", err)
+	}
+
+	oldShardID := ccInfo.ShardID
+	deleteCCID(native, contract, ccInfo.ContractAddr)
+	ccInfo.ContractAddr = params.NewContractAddr
+	ccInfo.ShardID = params.NewShardID
+	if err := setCCInfo(native, contract, ccInfo); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, failed to save ccinfo: %s", err)
+	}
+	if err := updateDependents(native, contract, params.ContractAddr, params.NewContractAddr); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, %s", err)
+	}
+	if err := freezeContractMeta(native, params.ContractAddr); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("migrate cc, %s", err)
+	}
+	utils.AddCommonEvent(native, contract, CC_MIGRATE_NAME,
+		[]interface{}{ccInfo.CCID, params.ContractAddr.ToHexString(), oldShardID.ToUint64(),
+			ccInfo.ContractAddr.ToHexString(), ccInfo.ShardID.ToUint64()})
+	return utils.BYTE_TRUE, nil
 }
@@ -21,8 +21,10 @@ package ccmc_states
 import (
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/serialization"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 )
 
@@ -59,12 +61,18 @@ func (this *ShardCCMCState) Deserialization(source *common.ZeroCopySource) error
 	return nil
 }
 
+const (
+	CC_STATE_ACTIVE = iota // registered and can be invoked
+	CC_STATE_FROZEN        // frozen by owner, new calls are blocked
+)
+
 type ShardCCInfo struct {
 	CCID         uint64
 	ShardID      common.ShardID
 	Owner        common.Address
 	ContractAddr common.Address
 	Dependencies []common.Address
+	State        uint32
 }
 
 func (this *ShardCCInfo) Serialize(w io.Writer) error {
@@ -88,6 +96,9 @@ func (this *ShardCCInfo) Serialize(w io.Writer) error {
 			return fmt.Errorf("serialize: write dependencies failed, index %d, err: %s", i, err)
 		}
 	}
+	if err := serialization.WriteVarUint(w, uint64(this.State)); err != nil {
+		return fmt.Errorf("serialize: write state failed, err: %s", err)
+	}
 	return nil
 }
 
@@ -117,6 +128,14 @@ func (this *ShardCCInfo) Deserialize(r io.Reader) error {
 		}
 		this.Dependencies[i] = dep
 	}
+	// state is not saved by old version, which are all active
+	if state, err := serialization.ReadVarUint(r, math.MaxUint32); err == io.EOF {
+		this.State = CC_STATE_ACTIVE
+	} else if err != nil {
+		return fmt.Errorf("deserialize: read state failed, err: %s", err)
+	} else {
+		this.State = uint32(state)
+	}
 	return nil
 }
 
@@ -129,6 +148,7 @@ func (this *ShardCCInfo) Serialization(sink *common.ZeroCopySink) {
 	for _, dep := range this.Dependencies {
 		sink.WriteAddress(dep)
 	}
+	sink.WriteVarUint(uint64(this.State))
 }
 
 func (this *ShardCCInfo) Deserialization(source *common.ZeroCopySource) error {
@@ -153,5 +173,20 @@ func (this *ShardCCInfo) Deserialization(source *common.ZeroCopySource) error {
 		}
 		this.Dependencies[i] = dep
 	}
+	if source.Len() == 0 {
+		this.State = CC_STATE_ACTIVE
+		return nil
+	}
+	state, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if state > math.MaxUint32 {
+		return fmt.Errorf("deserialization: invalid state %d", state)
+	}
+	this.State = uint32(state)
 	return nil
 }
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ccmc_states
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardCCInfoSerialize(t *testing.T) {
+	info := &ShardCCInfo{
+		CCID:         100,
+		ShardID:      common.NewShardIDUnchecked(1),
+		Owner:        common.Address{1, 2, 3},
+		ContractAddr: common.Address{4, 5, 6},
+		Dependencies: []common.Address{{7}, {8}},
+		State:        CC_STATE_FROZEN,
+	}
+	sink := common.NewZeroCopySink(0)
+	info.Serialization(sink)
+	newInfo := &ShardCCInfo{}
+	err := newInfo.Deserialization(common.NewZeroCopySource(sink.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, info, newInfo)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, info.Serialize(buf))
+	newInfo = &ShardCCInfo{}
+	assert.Nil(t, newInfo.Deserialize(buf))
+	assert.Equal(t, info, newInfo)
+}
+
+func TestShardCCInfoDeserializeWithoutState(t *testing.T) {
+	info := &ShardCCInfo{
+		CCID:         100,
+		ShardID:      common.NewShardIDUnchecked(1),
+		Owner:        common.Address{1, 2, 3},
+		ContractAddr: common.Address{4, 5, 6},
+		Dependencies: []common.Address{{7}},
+		State:        CC_STATE_ACTIVE,
+	}
+	// record saved before state is added
+	sink := common.NewZeroCopySink(0)
+	info.Serialization(sink)
+	data := sink.Bytes()
+	newInfo := &ShardCCInfo{State: CC_STATE_FROZEN}
+	assert.Nil(t, newInfo.Deserialization(common.NewZeroCopySource(data[:len(data)-1])))
+	assert.Equal(t, info, newInfo)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, info.Serialize(buf))
+	data = buf.Bytes()
+	newInfo = &ShardCCInfo{State: CC_STATE_FROZEN}
+	assert.Nil(t, newInfo.Deserialize(bytes.NewBuffer(data[:len(data)-1])))
+	assert.Equal(t, info, newInfo)
+}
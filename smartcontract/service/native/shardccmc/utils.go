@@ -27,8 +27,12 @@ import (
 	"github.com/ontio/ontology/common/serialization"
 	cstates "github.com/ontio/ontology/core/states"
 	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/ont"
 	"github.com/ontio/ontology/smartcontract/service/native/shardccmc/states"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/storage"
 )
 
 func getVersion(native *native.NativeService, contract common.Address) (uint32, error) {
@@ -95,7 +99,7 @@ func getCCInfo(native *native.NativeService, contract common.Address, CCID uint6
 	if err != nil {
 		return nil, fmt.Errorf("getCCInfo: %s", err)
 	}
-	if ccidBytes == nil {
+	if ccstateBytes == nil {
 		return nil, nil
 	}
 
@@ -150,3 +154,208 @@ func getCCID(native *native.NativeService, contract common.Address, addr common.
 
 	return utils.GetBytesUint64(ccidBytes)
 }
+
+func getCCInfoByContract(native *native.NativeService, contract common.Address, addr common.Address) (*ccmc_states.ShardCCInfo, error) {
+	ccid, err := getCCID(native, contract, addr)
+	if err != nil {
+		return nil, err
+	}
+	if ccid == 0 {
+		return nil, fmt.Errorf("contract %s not registered", addr.ToHexString())
+	}
+	ccInfo, err := getCCInfo(native, contract, ccid)
+	if err != nil {
+		return nil, err
+	}
+	if ccInfo == nil {
+		return nil, fmt.Errorf("cc %d not exist", ccid)
+	}
+	return ccInfo, nil
+}
+
+func deleteCCID(native *native.NativeService, contract common.Address, addr common.Address) {
+	native.CacheDB.Delete(utils.ConcatKey(contract, []byte(KEY_CC_CONTRACT), addr[:]))
+}
+
+func getRegisterFee(native *native.NativeService, contract common.Address) (uint64, error) {
+	value, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(KEY_REGISTER_FEE)))
+	if err != nil {
+		return 0, fmt.Errorf("getRegisterFee: %s", err)
+	}
+	if value == nil {
+		return DEFAULT_REGISTER_FEE, nil
+	}
+
+	feeBytes, err := cstates.GetValueFromRawStorageItem(value)
+	if err != nil {
+		return 0, fmt.Errorf("getRegisterFee, deserialize from raw storage: %s", err)
+	}
+	return utils.GetBytesUint64(feeBytes)
+}
+
+func setRegisterFee(native *native.NativeService, contract common.Address, fee uint64) {
+	native.CacheDB.Put(utils.ConcatKey(contract, []byte(KEY_REGISTER_FEE)),
+		cstates.GenRawStorageItem(utils.GetUint64Bytes(fee)))
+}
+
+// chargeRegisterFee transfer registration fee from cc owner to governance contract
+func chargeRegisterFee(native *native.NativeService, contract common.Address, owner common.Address) error {
+	fee, err := getRegisterFee(native, contract)
+	if err != nil {
+		return err
+	}
+	if fee == 0 {
+		return nil
+	}
+	return ont.AppCallTransfer(native, utils.OngContractAddress, owner, utils.GovernanceContractAddress, fee)
+}
+
+// checkShardActive check the shard has been created by shard mgmt and is running
+func checkShardActive(native *native.NativeService, shardID common.ShardID) error {
+	shard, err := shardmgmt.GetShardState(native, utils.ShardMgmtContractAddress, shardID)
+	if err != nil {
+		return err
+	}
+	if shard.State != shardstates.SHARD_STATE_ACTIVE {
+		return fmt.Errorf("shard %d state %d is not active", shardID.ToUint64(), shard.State)
+	}
+	return nil
+}
+
+// checkDependencies check all dependencies are registered and not frozen
+func checkDependencies(native *native.NativeService, contract common.Address, self common.Address,
+	deps []common.Address) error {
+	checked := make(map[common.Address]bool)
+	for _, dep := range deps {
+		if dep == self {
+			return fmt.Errorf("contract cannot depend on itself")
+		}
+		if checked[dep] {
+			return fmt.Errorf("duplicated dependency %s", dep.ToHexString())
+		}
+		checked[dep] = true
+		depInfo, err := getCCInfoByContract(native, contract, dep)
+		if err != nil {
+			return fmt.Errorf("dependency %s: %s", dep.ToHexString(), err)
+		}
+		if depInfo.State != ccmc_states.CC_STATE_ACTIVE {
+			return fmt.Errorf("dependency %s is frozen", dep.ToHexString())
+		}
+	}
+	return nil
+}
+
+func getDependents(native *native.NativeService, contract common.Address, addr common.Address) ([]uint64, error) {
+	value, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(KEY_CC_DEPENDENTS), addr[:]))
+	if err != nil {
+		return nil, fmt.Errorf("getDependents: %s", err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	data, err := cstates.GetValueFromRawStorageItem(value)
+	if err != nil {
+		return nil, fmt.Errorf("getDependents, deserialize from raw storage: %s", err)
+	}
+	source := common.NewZeroCopySource(data)
+	n, _, irr, eof := source.NextVarUint()
+	if irr || eof {
+		return nil, fmt.Errorf("getDependents, read count failed")
+	}
+	ccids := make([]uint64, 0, n)
+	for i := uint64(0); i < n; i++ {
+		ccid, eof := source.NextUint64()
+		if eof {
+			return nil, fmt.Errorf("getDependents, read ccid failed")
+		}
+		ccids = append(ccids, ccid)
+	}
+	return ccids, nil
+}
+
+func setDependents(native *native.NativeService, contract common.Address, addr common.Address, ccids []uint64) {
+	key := utils.ConcatKey(contract, []byte(KEY_CC_DEPENDENTS), addr[:])
+	if len(ccids) == 0 {
+		native.CacheDB.Delete(key)
+		return
+	}
+	sink := common.NewZeroCopySink(0)
+	sink.WriteVarUint(uint64(len(ccids)))
+	for _, ccid := range ccids {
+		sink.WriteUint64(ccid)
+	}
+	native.CacheDB.Put(key, cstates.GenRawStorageItem(sink.Bytes()))
+}
+
+// addDependent index the cc as dependent of contract dep
+func addDependent(native *native.NativeService, contract common.Address, dep common.Address, ccid uint64) error {
+	ccids, err := getDependents(native, contract, dep)
+	if err != nil {
+		return err
+	}
+	for _, id := range ccids {
+		if id == ccid {
+			return nil
+		}
+	}
+	setDependents(native, contract, dep, append(ccids, ccid))
+	return nil
+}
+
+// updateDependents replace the migrated contract address in dependencies of its dependents,
+// and move the dependents index to the new address
+func updateDependents(native *native.NativeService, contract common.Address, oldAddr, newAddr common.Address) error {
+	ccids, err := getDependents(native, contract, oldAddr)
+	if err != nil {
+		return fmt.Errorf("updateDependents: %s", err)
+	}
+	for _, ccid := range ccids {
+		ccInfo, err := getCCInfo(native, contract, ccid)
+		if err != nil {
+			return fmt.Errorf("updateDependents: %s", err)
+		}
+		if ccInfo == nil {
+			return fmt.Errorf("updateDependents: dependent cc %d not exist", ccid)
+		}
+		for i, dep := range ccInfo.Dependencies {
+			if dep == oldAddr {
+				ccInfo.Dependencies[i] = newAddr
+			}
+		}
+		if err := setCCInfo(native, contract, ccInfo); err != nil {
+			return fmt.Errorf("updateDependents: failed to save cc %d, err: %s", ccid, err)
+		}
+	}
+	setDependents(native, contract, oldAddr, nil)
+	setDependents(native, contract, newAddr, ccids)
+	return nil
+}
+
+// IsFrozenByCCMC check if the contract has been frozen by freeze or migrate of ccmc,
+// the meta data of such contract cannot be unfrozen by its owner
+func IsFrozenByCCMC(cache *storage.CacheDB, addr common.Address) (bool, error) {
+	value, err := cache.Get(utils.ConcatKey(utils.ShardCCMCAddress, []byte(KEY_CC_FROZEN), addr[:]))
+	if err != nil {
+		return false, fmt.Errorf("IsFrozenByCCMC: %s", err)
+	}
+	return value != nil, nil
+}
+
+// freezeContractMeta mark the meta data of contract frozen, so that it cannot be invoked any more
+func freezeContractMeta(native *native.NativeService, addr common.Address) error {
+	meta, err := native.CacheDB.GetMetaData(addr)
+	if err != nil {
+		return fmt.Errorf("freezeContractMeta: read meta data failed, err: %s", err)
+	}
+	if meta == nil {
+		return fmt.Errorf("freezeContractMeta: meta data of %s not found", addr.ToHexString())
+	}
+	native.CacheDB.Put(utils.ConcatKey(utils.ShardCCMCAddress, []byte(KEY_CC_FROZEN), addr[:]),
+		cstates.GenRawStorageItem([]byte{1}))
+	if meta.IsFrozen {
+		return nil
+	}
+	meta.IsFrozen = true
+	native.CacheDB.PutMetaData(meta)
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package shardccmc
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/payload"
+	cstates "github.com/ontio/ontology/core/states"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/smartcontract"
+	"github.com/ontio/ontology/smartcontract/context"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/shardccmc/states"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	shardstates "github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateDependents(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(memback))}
+	contract := utils.ShardCCMCAddress
+	oldAddr, newAddr := common.Address{1}, common.Address{2}
+
+	infos := []*ccmc_states.ShardCCInfo{
+		{CCID: INIT_CCID, ContractAddr: oldAddr},
+		{CCID: INIT_CCID + 1, ContractAddr: common.Address{3}, Dependencies: []common.Address{oldAddr}},
+		{CCID: INIT_CCID + 2, ContractAddr: common.Address{4}, Dependencies: []common.Address{{3}}},
+	}
+	for _, info := range infos {
+		assert.Nil(t, setCCInfo(service, contract, info))
+		for _, dep := range info.Dependencies {
+			assert.Nil(t, addDependent(service, contract, dep, info.CCID))
+		}
+	}
+	setCCMCState(service, contract, &ccmc_states.ShardCCMCState{NextCCID: INIT_CCID + uint64(len(infos))})
+
+	assert.Nil(t, updateDependents(service, contract, oldAddr, newAddr))
+	info, err := getCCInfo(service, contract, INIT_CCID+1)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{newAddr}, info.Dependencies)
+	info, err = getCCInfo(service, contract, INIT_CCID+2)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{{3}}, info.Dependencies)
+
+	dependents, err := getDependents(service, contract, oldAddr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(dependents))
+	dependents, err = getDependents(service, contract, newAddr)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{INIT_CCID + 1}, dependents)
+}
+
+func TestFreezeContractMetaNotFound(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(memback))}
+	assert.NotNil(t, freezeContractMeta(service, common.Address{1}))
+}
+
+type witnessSmartContract struct {
+	smartcontract.SmartContract
+}
+
+func (this *witnessSmartContract) CheckWitness(address common.Address) bool {
+	return true
+}
+
+func TestRegisterFreezeMigrate(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	sc := &witnessSmartContract{}
+	sc.PushContext(&context.Context{ContractAddress: utils.ShardCCMCAddress})
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(memback)), ContextRef: sc}
+	contract := utils.ShardCCMCAddress
+	owner := common.Address{9}
+	shard1, shard2 := common.NewShardIDUnchecked(1), common.NewShardIDUnchecked(2)
+
+	_, err := ShardCCMCInit(service)
+	assert.Nil(t, err)
+	setRegisterFee(service, contract, 0)
+	for _, shardID := range []common.ShardID{shard1, shard2} {
+		shard := &shardstates.ShardState{
+			ShardID: shardID,
+			State:   shardstates.SHARD_STATE_ACTIVE,
+			Config:  &shardstates.ShardConfig{VbftCfg: &config.VBFTConfig{}},
+		}
+		sink := common.NewZeroCopySink(0)
+		shard.Serialization(sink)
+		service.CacheDB.Put(utils.ConcatKey(utils.ShardMgmtContractAddress, []byte(shardmgmt.KEY_SHARD_STATE),
+			utils.GetUint64Bytes(shardID.ToUint64())), cstates.GenRawStorageItem(sink.Bytes()))
+	}
+	lib, libV2, app1, app2, app3 := common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4},
+		common.Address{5}
+	for _, addr := range []common.Address{lib, libV2, app1, app2, app3} {
+		meta := payload.NewDefaultMetaData()
+		meta.Contract = addr
+		meta.Owner = owner
+		service.CacheDB.PutMetaData(meta)
+	}
+	call := func(method func(*native.NativeService) ([]byte, error), param interface {
+		Serialize(w io.Writer) error
+	}) error {
+		bf := new(bytes.Buffer)
+		assert.Nil(t, param.Serialize(bf))
+		service.Input = bf.Bytes()
+		_, err := method(service)
+		return err
+	}
+	register := func(addr common.Address, deps ...common.Address) error {
+		return call(ShardCCMCRegister,
+			&RegisterCCParam{ShardID: shard1, Owner: owner, ContractAddr: addr, Dependencies: deps})
+	}
+	assertFrozen := func(addr common.Address) {
+		meta, err := service.CacheDB.GetMetaData(addr)
+		assert.Nil(t, err)
+		assert.True(t, meta.IsFrozen)
+		frozen, err := IsFrozenByCCMC(service.CacheDB, addr)
+		assert.Nil(t, err)
+		assert.True(t, frozen)
+	}
+
+	// register contracts with dependencies
+	assert.Nil(t, register(lib))
+	assert.NotNil(t, register(lib))
+	assert.NotNil(t, register(app1, common.Address{10}))
+	assert.NotNil(t, register(app1, app1))
+	assert.Nil(t, register(app1, lib))
+	assert.Nil(t, register(app2, lib, app1))
+	dependents, err := getDependents(service, contract, lib)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{INIT_CCID + 1, INIT_CCID + 2}, dependents)
+
+	// migrate dependency, dependents of it are updated and old contract is frozen
+	assert.Nil(t, call(ShardCCMCMigrate, &MigrateCCParam{ContractAddr: lib, NewContractAddr: libV2, NewShardID: shard2}))
+	libInfo, err := getCCInfoByContract(service, contract, libV2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(INIT_CCID), libInfo.CCID)
+	assert.Equal(t, shard2, libInfo.ShardID)
+	for _, app := range []common.Address{app1, app2} {
+		info, err := getCCInfoByContract(service, contract, app)
+		assert.Nil(t, err)
+		assert.Equal(t, libV2, info.Dependencies[0])
+	}
+	dependents, err = getDependents(service, contract, libV2)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{INIT_CCID + 1, INIT_CCID + 2}, dependents)
+	dependents, err = getDependents(service, contract, lib)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(dependents))
+	assertFrozen(lib)
+	assert.NotNil(t, register(app3, lib))
+
+	// frozen contract cannot be depended, migrated or frozen again
+	assert.Nil(t, call(ShardCCMCFreeze, &FreezeCCParam{ContractAddr: app1}))
+	info, err := getCCInfoByContract(service, contract, app1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(ccmc_states.CC_STATE_FROZEN), info.State)
+	assertFrozen(app1)
+	assert.NotNil(t, call(ShardCCMCFreeze, &FreezeCCParam{ContractAddr: app1}))
+	assert.NotNil(t, call(ShardCCMCMigrate, &MigrateCCParam{ContractAddr: app1, NewContractAddr: app3, NewShardID: shard2}))
+	assert.NotNil(t, register(app3, app1))
+	assert.Nil(t, register(app3, libV2))
+}
@@ -121,6 +121,32 @@ func deletePeer(native *native.NativeService, shardId common.ShardID, peers []st
 	return nil
 }
 
+func peerPenalty(native *native.NativeService, shardId common.ShardID, peer string) error {
+	param := &shard_stake.PeerPenaltyParam{
+		ShardId: shardId,
+		Peer:    peer,
+	}
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		return fmt.Errorf("peerPenalty: failed, err: %s", err)
+	}
+	if _, err := native.NativeCall(utils.ShardStakeAddress, shard_stake.PEER_PENALTY, bf.Bytes()); err != nil {
+		return fmt.Errorf("peerPenalty: failed, err: %s", err)
+	}
+	return nil
+}
+
+func archiveStakeShard(native *native.NativeService, shardId common.ShardID) error {
+	bf := new(bytes.Buffer)
+	if err := utils.SerializeShardId(bf, shardId); err != nil {
+		return fmt.Errorf("archiveStakeShard: serialize shardId failed, err: %s", err)
+	}
+	if _, err := native.NativeCall(utils.ShardStakeAddress, shard_stake.ARCHIVE_SHARD, bf.Bytes()); err != nil {
+		return fmt.Errorf("archiveStakeShard: failed, err: %s", err)
+	}
+	return nil
+}
+
 func updateNewCfg(native *native.NativeService, shard *shardstates.ShardState, newCfg *utils.Configuration) {
 	shard.Config.VbftCfg.N = newCfg.N
 	shard.Config.VbftCfg.C = newCfg.C
@@ -146,7 +172,9 @@ func preCommitDpos(native *native.NativeService, shardId common.ShardID) error {
 
 //check the configuration while update shard config
 func checkNewCfg(configuration *utils.Configuration, shard *shardstates.ShardState) error {
-	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
+	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO ||
+		config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SBFT ||
+		shard.Config.GetConsensusType() != config.CONSENSUS_TYPE_VBFT {
 		return nil
 	}
 	candidateNum := uint32(0)
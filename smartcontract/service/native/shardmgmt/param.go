@@ -27,6 +27,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 )
 
@@ -67,12 +68,10 @@ func (this *InitShardParam) Deserialize(r io.Reader) error {
 	return nil
 }
 
-//
 // params for shard creation
 // @ParentShardID : local shard ID
 // @Creator : account address of shard creator.
 // shard creator is also the shard operator after shard activated
-//
 type CreateShardParam struct {
 	ParentShardID common.ShardID
 	Creator       common.Address
@@ -109,21 +108,52 @@ func (this *CreateShardParam) Deserialize(r io.Reader) error {
 //
 
 type ConfigShardParam struct {
-	ShardID           common.ShardID
-	NetworkMin        uint32
-	StakeAssetAddress common.Address
-	GasAssetAddress   common.Address
-	GasPrice          uint64
-	GasLimit          uint64
-	VbftConfigData    []byte
-}
-
+	ShardID             common.ShardID
+	NetworkMin          uint32
+	StakeAssetAddress   common.Address
+	GasAssetAddress     common.Address
+	GasPrice            uint64
+	GasLimit            uint64
+	VbftConfigData      []byte
+	ConsensusType       string // consensus of shard, must be set
+	ConsensusConfigData []byte // serialized solo/dbft/sbft config
+}
+
+// GetConfig decodes vbft config, which is optional for shard not running vbft
 func (this *ConfigShardParam) GetConfig() (*config.VBFTConfig, error) {
+	if len(this.VbftConfigData) == 0 && this.ConsensusType != config.CONSENSUS_TYPE_VBFT {
+		return nil, nil
+	}
 	cfg := &config.VBFTConfig{}
 	err := cfg.Deserialize(bytes.NewReader(this.VbftConfigData))
 	return cfg, err
 }
 
+// GetConsensusConfig decodes consensus config of solo/dbft/sbft shard into shard config
+func (this *ConfigShardParam) GetConsensusConfig(shardCfg *shardstates.ShardConfig) error {
+	source := common.NewZeroCopySource(this.ConsensusConfigData)
+	switch this.ConsensusType {
+	case "":
+		return fmt.Errorf("consensus type not set")
+	case config.CONSENSUS_TYPE_VBFT:
+		shardCfg.ConsensusType = config.CONSENSUS_TYPE_VBFT
+		return nil
+	case config.CONSENSUS_TYPE_SOLO:
+		shardCfg.SoloCfg = &config.SOLOConfig{}
+		shardCfg.ConsensusType = this.ConsensusType
+		return shardCfg.SoloCfg.Deserialization(source)
+	case config.CONSENSUS_TYPE_DBFT:
+		shardCfg.DbftCfg = &config.DBFTConfig{}
+		shardCfg.ConsensusType = this.ConsensusType
+		return shardCfg.DbftCfg.Deserialization(source)
+	case config.CONSENSUS_TYPE_SBFT:
+		shardCfg.SbftCfg = &config.SBFTConfig{}
+		shardCfg.ConsensusType = this.ConsensusType
+		return shardCfg.SbftCfg.Deserialization(source)
+	}
+	return fmt.Errorf("unsupported consensus type %s", this.ConsensusType)
+}
+
 func (this *ConfigShardParam) Serialize(w io.Writer) error {
 	if err := utils.SerializeShardId(w, this.ShardID); err != nil {
 		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
@@ -146,6 +176,12 @@ func (this *ConfigShardParam) Serialize(w io.Writer) error {
 	if err := serialization.WriteVarBytes(w, this.VbftConfigData); err != nil {
 		return fmt.Errorf("serialize: write cfg data failed, err: %s", err)
 	}
+	if err := serialization.WriteString(w, this.ConsensusType); err != nil {
+		return fmt.Errorf("serialize: write consensus type failed, err: %s", err)
+	}
+	if err := serialization.WriteVarBytes(w, this.ConsensusConfigData); err != nil {
+		return fmt.Errorf("serialize: write consensus cfg data failed, err: %s", err)
+	}
 	return nil
 }
 
@@ -174,6 +210,16 @@ func (this *ConfigShardParam) Deserialize(r io.Reader) error {
 	if this.VbftConfigData, err = serialization.ReadVarBytes(r); err != nil {
 		return fmt.Errorf("deserialize: read config data failed, err: %s", err)
 	}
+	// params without consensus type config vbft shard
+	if this.ConsensusType, err = serialization.ReadString(r); err == io.EOF {
+		this.ConsensusType = config.CONSENSUS_TYPE_VBFT
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("deserialize: read consensus type failed, err: %s", err)
+	}
+	if this.ConsensusConfigData, err = serialization.ReadVarBytes(r); err != nil {
+		return fmt.Errorf("deserialize: read consensus cfg data failed, err: %s", err)
+	}
 	return nil
 }
 
@@ -277,13 +323,11 @@ func (this *ApproveJoinShardParam) Deserialize(r io.Reader) error {
 	return nil
 }
 
-//
 // param for peer join shard request
 // @ShardID : ID of shard which peer node is going to join
 // @PeerOwner : wallet address of peer owner (to pay stake token)
 // @PeerPubKey : peer public key, to verify message signatures sent from peer, run ontology wallet account
 // @StakeAmount : amount of token stake for the peer
-//
 type JoinShardParam struct {
 	ShardID     common.ShardID
 	IpAddress   string
@@ -364,11 +408,9 @@ func (this *ExitShardParam) Deserialize(r io.Reader) error {
 	return nil
 }
 
-//
 // param of shard-activation request
 // The request can only be initiated by operator of the shard
 // @ShardID : ID of shard which is to be activated
-//
 type ActivateShardParam struct {
 	ShardID common.ShardID
 }
@@ -388,6 +430,50 @@ func (this *ActivateShardParam) Deserialize(r io.Reader) error {
 	return nil
 }
 
+// param of shard-stop request
+// The request can only be initiated by creator of the shard
+// @ShardID : ID of shard which is to be stopped
+type StopShardParam struct {
+	ShardID common.ShardID
+}
+
+func (this *StopShardParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardID); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *StopShardParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardID, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	return nil
+}
+
+// param of shard-archive request
+// The request can only be initiated by creator of the shard, after the shard stopped and commit dpos
+// @ShardID : ID of shard which is to be archived
+type ArchiveShardParam struct {
+	ShardID common.ShardID
+}
+
+func (this *ArchiveShardParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardID); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *ArchiveShardParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardID, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	return nil
+}
+
 type CommitDposParam struct {
 	ShardID   common.ShardID
 	FeeAmount uint64
@@ -452,6 +538,7 @@ type NotifyRootCommitDPosParam struct {
 	ShardId     common.ShardID
 	Height      uint32
 	ForceCommit bool
+	Drained     bool // no cross shard tx is locking state of shard
 }
 
 func (this *NotifyRootCommitDPosParam) Serialize(w io.Writer) error {
@@ -464,6 +551,9 @@ func (this *NotifyRootCommitDPosParam) Serialize(w io.Writer) error {
 	if err := serialization.WriteBool(w, this.ForceCommit); err != nil {
 		return fmt.Errorf("serialize: write force commit failed, err: %s", err)
 	}
+	if err := serialization.WriteBool(w, this.Drained); err != nil {
+		return fmt.Errorf("serialize: write drained failed, err: %s", err)
+	}
 	return nil
 }
 
@@ -480,5 +570,38 @@ func (this *NotifyRootCommitDPosParam) Deserialize(r io.Reader) error {
 	if this.ForceCommit, err = serialization.ReadBool(r); err != nil {
 		return fmt.Errorf("deserialize: read force commit failed, err: %s", err)
 	}
+	// params of shard which doesn't report drained state
+	if this.Drained, err = serialization.ReadBool(r); err == io.EOF {
+		this.Drained = false
+	} else if err != nil {
+		return fmt.Errorf("deserialize: read drained failed, err: %s", err)
+	}
+	return nil
+}
+
+type NotifyFaultyPeerParam struct {
+	ShardId  common.ShardID
+	Evidence *utils.FaultyEvidence
+}
+
+func (this *NotifyFaultyPeerParam) Serialize(w io.Writer) error {
+	if err := utils.SerializeShardId(w, this.ShardId); err != nil {
+		return fmt.Errorf("serialize: write shard id failed, err: %s", err)
+	}
+	if err := this.Evidence.Serialize(w); err != nil {
+		return fmt.Errorf("serialize: write evidence failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *NotifyFaultyPeerParam) Deserialize(r io.Reader) error {
+	var err error = nil
+	if this.ShardId, err = utils.DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize: read shard id failed, err: %s", err)
+	}
+	this.Evidence = &utils.FaultyEvidence{}
+	if err := this.Evidence.Deserialize(r); err != nil {
+		return fmt.Errorf("deserialize: read evidence failed, err: %s", err)
+	}
 	return nil
 }
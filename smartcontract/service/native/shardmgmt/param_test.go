@@ -24,7 +24,9 @@ import (
 	"testing"
 
 	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 )
 
 func newCreateShardParam(t *testing.T, acc *account.Account) []byte {
@@ -60,3 +62,56 @@ func TestCreateShardParam(t *testing.T) {
 		t.Fatalf("unmatched parent shard id: %d vs %d", param.ParentShardID, 100)
 	}
 }
+
+func TestConfigShardParamConsensusType(t *testing.T) {
+	soloCfg := &config.SOLOConfig{GenBlockTime: 3}
+	sink := common.NewZeroCopySink(0)
+	soloCfg.Serialization(sink)
+	param := &shardmgmt.ConfigShardParam{
+		ShardID:             common.NewShardIDUnchecked(1),
+		NetworkMin:          1,
+		ConsensusType:       config.CONSENSUS_TYPE_SOLO,
+		ConsensusConfigData: sink.Bytes(),
+	}
+	buf := new(bytes.Buffer)
+	if err := param.Serialize(buf); err != nil {
+		t.Fatalf("serialize config shard param: %s", err)
+	}
+
+	newParam := &shardmgmt.ConfigShardParam{}
+	if err := newParam.Deserialize(bytes.NewBuffer(buf.Bytes())); err != nil {
+		t.Fatalf("deserialize config shard param: %s", err)
+	}
+	shardCfg := &shardstates.ShardConfig{}
+	if err := newParam.GetConsensusConfig(shardCfg); err != nil {
+		t.Fatalf("get consensus config: %s", err)
+	}
+	if shardCfg.ConsensusType != config.CONSENSUS_TYPE_SOLO || shardCfg.SoloCfg.GenBlockTime != 3 {
+		t.Fatalf("unmatched consensus config: %s, %v", shardCfg.ConsensusType, shardCfg.SoloCfg)
+	}
+
+	// params without consensus type config vbft shard
+	param.ConsensusType = ""
+	param.ConsensusConfigData = nil
+	buf.Reset()
+	if err := param.Serialize(buf); err != nil {
+		t.Fatalf("serialize config shard param: %s", err)
+	}
+	data := buf.Bytes()
+	newParam = &shardmgmt.ConfigShardParam{}
+	if err := newParam.Deserialize(bytes.NewBuffer(data[:len(data)-2])); err != nil {
+		t.Fatalf("deserialize config shard param: %s", err)
+	}
+	if newParam.ConsensusType != config.CONSENSUS_TYPE_VBFT {
+		t.Fatalf("unmatched consensus type: %s", newParam.ConsensusType)
+	}
+
+	// params with empty consensus type are rejected
+	newParam = &shardmgmt.ConfigShardParam{}
+	if err := newParam.Deserialize(bytes.NewBuffer(data)); err != nil {
+		t.Fatalf("deserialize config shard param: %s", err)
+	}
+	if err := newParam.GetConsensusConfig(&shardstates.ShardConfig{}); err == nil {
+		t.Fatalf("config without consensus type should be rejected")
+	}
+}
@@ -48,6 +48,8 @@ import (
 //	. config shard
 //	. join shard
 //	. activate shard
+//	. stop shard
+//	. archive shard
 //
 /////////
 
@@ -65,6 +67,9 @@ const (
 	JOIN_SHARD_NAME          = "joinShard"
 	EXIT_SHARD_NAME          = "exitShard"
 	ACTIVATE_SHARD_NAME      = "activateShard"
+	STOP_SHARD_NAME          = "stopShard"
+	ARCHIVE_SHARD_NAME       = "archiveShard"
+	SHARD_STOPPING_NAME      = "shardStopping"
 	NOTIFY_SHARD_COMMIT_DPOS = "notifyShardCommitDpos"
 	UPDATE_CONFIG            = "updateConfig"
 
@@ -74,11 +79,23 @@ const (
 	SHARD_RETRY_COMMIT_DPOS    = "shardRetryCommitDpos"
 	UPDATE_XSHARD_HANDLING_FEE = "updateXShardHandlingFee"
 
+	// shard consensus reports faulty peer to parent, peer will be punished and removed from shard
+	NOTIFY_PARENT_FAULTY_PEER = "notifyParentFaultyPeer"
+	FAULTY_PEER_NAME          = "faultyPeer"
+
+	// shard consensus has rotated crashed peer out, parent is informed afterward
+	NOTIFY_PARENT_EMERGENCY_ROTATION = "notifyParentEmergencyRotation"
+	EMERGENCY_ROTATION_NAME          = "emergencyRotation"
+
 	// query shard commit Dpos info, include xshard transfer ong
 	// id, commit dpos height and block hash at shard, and whole handling fee at last consensus epoch at shard
 	GET_SHARD_COMMIT_DPOS_INFO = "getShardCommitDPosInfo"
 	// query shard detail after create it
 	GET_SHARD_DETAIL = "getShardDetail"
+
+	// stake config of shard not running vbft without vbft config
+	DEFAULT_MAX_BLOCK_CHANGE_VIEW = 120000
+	DEFAULT_MIN_INIT_STAKE        = 10000
 )
 
 func InitShardManagement() {
@@ -98,6 +115,9 @@ func RegisterShardMgmtContract(native *native.NativeService) {
 	native.Register(JOIN_SHARD_NAME, JoinShard)
 	native.Register(ACTIVATE_SHARD_NAME, ActivateShard)
 	native.Register(EXIT_SHARD_NAME, ExitShard)
+	native.Register(STOP_SHARD_NAME, StopShard)
+	native.Register(ARCHIVE_SHARD_NAME, ArchiveShard)
+	native.Register(SHARD_STOPPING_NAME, ShardStopping)
 	native.Register(NOTIFY_SHARD_COMMIT_DPOS, NotifyShardCommitDpos)
 	native.Register(UPDATE_CONFIG, UpdateConfig)
 
@@ -106,6 +126,10 @@ func RegisterShardMgmtContract(native *native.NativeService) {
 	native.Register(SHARD_COMMIT_DPOS, ShardCommitDpos)
 	native.Register(SHARD_RETRY_COMMIT_DPOS, ShardRetryCommitDpos)
 	native.Register(UPDATE_XSHARD_HANDLING_FEE, UpdateXShardHandlingFee)
+	native.Register(NOTIFY_PARENT_FAULTY_PEER, NotifyParentFaultyPeer)
+	native.Register(FAULTY_PEER_NAME, FaultyPeer)
+	native.Register(NOTIFY_PARENT_EMERGENCY_ROTATION, NotifyParentEmergencyRotation)
+	native.Register(EMERGENCY_ROTATION_NAME, EmergencyRotation)
 
 	native.Register(GET_SHARD_COMMIT_DPOS_INFO, GetShardCommitDPosInfo)
 	native.Register(GET_SHARD_DETAIL, GetShardDetail)
@@ -222,7 +246,9 @@ func CreateShard(native *native.NativeService) ([]byte, error) {
 		Height:        native.Height,
 		NewShardID:    shard.ShardID,
 	}
-	AddNotification(native, contract, evt)
+	if err := AddNotification(native, contract, evt); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("CreateShard: add notification failed, err: %s", err)
+	}
 	return utils.BYTE_TRUE, nil
 }
 
@@ -282,13 +308,20 @@ func ConfigShard(native *native.NativeService) ([]byte, error) {
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ConfigShard: decode config failed, err: %s", err)
 	}
-	if err := utils.CheckVBFTConfig(cfg); err != nil {
+	shard.Config.VbftCfg = cfg
+	if err := params.GetConsensusConfig(shard.Config); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ConfigShard: decode consensus config failed, err: %s", err)
+	}
+	if shard.Config.VbftCfg == nil {
+		shard.Config.VbftCfg = defaultStakeConfig(shard.Config)
+	}
+	if err := checkShardConsensusConfig(shard.Config); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ConfigShard: failed, err: %s", err)
 	}
-	shard.Config.VbftCfg = cfg
 	shard.State = shardstates.SHARD_STATE_CONFIGURED
 
-	if err := initStakeContractShard(native, params.ShardID, uint64(cfg.MinInitStake), params.StakeAssetAddress); err != nil {
+	if err := initStakeContractShard(native, params.ShardID, uint64(shard.Config.VbftCfg.MinInitStake),
+		params.StakeAssetAddress); err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ConfigShard: failed, err: %s", err)
 	}
 	setShardState(native, contract, shard)
@@ -300,7 +333,9 @@ func ConfigShard(native *native.NativeService) ([]byte, error) {
 	}
 	evt.SourceShardID = native.ShardID
 	evt.ShardID = native.ShardID
-	AddNotification(native, contract, evt)
+	if err := AddNotification(native, contract, evt); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ConfigShard: add notification failed, err: %s", err)
+	}
 	return utils.BYTE_TRUE, nil
 }
 
@@ -326,7 +361,7 @@ func ApplyJoinShard(native *native.NativeService) ([]byte, error) {
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ApplyJoinShard: get shard: %s", err)
 	}
-	if shard.State < shardstates.SHARD_STATE_CONFIGURED {
+	if shard.State < shardstates.SHARD_STATE_CONFIGURED || shard.State > shardstates.SHARD_STATE_ACTIVE {
 		return utils.BYTE_FALSE, fmt.Errorf("ApplyJoinShard: shard state unmatch")
 	}
 	state, err := getShardPeerState(native, contract, params.ShardId, params.PeerPubKey)
@@ -388,6 +423,9 @@ func JoinShard(native *native.NativeService) ([]byte, error) {
 	if shard.ShardID.ParentID() != native.ShardID {
 		return utils.BYTE_FALSE, fmt.Errorf("JoinShard: not on parent shard")
 	}
+	if shard.State > shardstates.SHARD_STATE_ACTIVE {
+		return utils.BYTE_FALSE, fmt.Errorf("JoinShard: shard is stopped")
+	}
 
 	peerIndex := uint32(len(shard.Peers) + 1)
 	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_VBFT {
@@ -452,7 +490,9 @@ func JoinShard(native *native.NativeService) ([]byte, error) {
 	}
 	evt.SourceShardID = native.ShardID
 	evt.ShardID = native.ShardID
-	AddNotification(native, contract, evt)
+	if err := AddNotification(native, contract, evt); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("JoinShard: add notification failed, err: %s", err)
+	}
 	return utils.BYTE_TRUE, nil
 }
 
@@ -473,6 +513,9 @@ func ExitShard(native *native.NativeService) ([]byte, error) {
 	if err != nil {
 		return utils.BYTE_FALSE, fmt.Errorf("ExitShard: get shard state failed, err: %s", err)
 	}
+	if shard.State > shardstates.SHARD_STATE_ACTIVE {
+		return utils.BYTE_FALSE, fmt.Errorf("ExitShard: shard is stopped")
+	}
 	shardPeerInfo, ok := shard.Peers[strings.ToLower(param.PeerPubKey)]
 	if !ok {
 		return utils.BYTE_FALSE, fmt.Errorf("ExitShard: peer not exist in shard, err: %s", err)
@@ -485,7 +528,7 @@ func ExitShard(native *native.NativeService) ([]byte, error) {
 	}
 	if shardPeerInfo.NodeType == shardstates.CONSENSUS_NODE {
 		if len(shard.Peers)-1 < int(shard.Config.VbftCfg.K) &&
-			config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_VBFT {
+			shard.Config.GetConsensusType() == config.CONSENSUS_TYPE_VBFT {
 			return utils.BYTE_FALSE, fmt.Errorf("ExitShard: peer cannot exit")
 		}
 		shardPeerInfo.NodeType = shardstates.QUIT_CONSENSUS_NODE
@@ -539,7 +582,133 @@ func ActivateShard(native *native.NativeService) ([]byte, error) {
 	evt := &shardstates.ShardActiveEvent{Height: native.Height}
 	evt.SourceShardID = native.ShardID
 	evt.ShardID = shard.ShardID
-	AddNotification(native, contract, evt)
+	if err := AddNotification(native, contract, evt); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ActivateShard: add notification failed, err: %s", err)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+// stop shard, shard will not accept new transaction and only handle the pending cross shard transactions,
+// shard creator should commit dpos to settle shard fee before archive it
+func StopShard(native *native.NativeService) ([]byte, error) {
+	params := new(StopShardParam)
+	if err := params.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: invalid param: %s", err)
+	}
+
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	if ok, err := checkVersion(native, contract); !ok || err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: check version: %s", err)
+	}
+
+	shard, err := GetShardState(native, contract, params.ShardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: get shard: %s", err)
+	}
+	if err := utils.ValidateOwner(native, shard.Creator); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: invalid creator: %s", err)
+	}
+	if shard.State != shardstates.SHARD_STATE_ACTIVE {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: invalid shard state: %d", shard.State)
+	}
+	if shard.ShardID.ParentID() != native.ShardID {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: not on parent shard")
+	}
+	currentView, err := shard_stake.GetShardCurrentViewIndex(native, shard.ShardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: failed, err: %s", err)
+	}
+	setShardStopView(native, shard.ShardID, currentView)
+	shard.State = shardstates.SHARD_STATE_STOPPING
+	setShardState(native, contract, shard)
+
+	evt := &shardstates.ShardStopEvent{Height: native.Height}
+	evt.SourceShardID = native.ShardID
+	evt.ShardID = shard.ShardID
+	if err := AddNotification(native, contract, evt); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("StopShard: add notification failed, err: %s", err)
+	}
+	// shard rejects new txs in its blocks, not only in txpool of nodes
+	native.NotifyRemoteShard(shard.ShardID, contract, native.ContextRef.GetRemainGas(), SHARD_STOPPING_NAME, []byte{})
+	return utils.BYTE_TRUE, nil
+}
+
+// invoked by parent shard while shard stopped, shard only executes cross shard txs afterward
+func ShardStopping(native *native.NativeService) ([]byte, error) {
+	if native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardStopping: only can be invoked at child shard")
+	}
+	if !native.ContextRef.CheckCallShard(native.ShardID.ParentID()) {
+		return utils.BYTE_FALSE, fmt.Errorf("ShardStopping: only can be invoked by ShardCall of parent")
+	}
+	setShardDraining(native)
+	return utils.BYTE_TRUE, nil
+}
+
+// archive stopped shard, the stake asset and fee of shard could be withdrawn after archived
+func ArchiveShard(native *native.NativeService) ([]byte, error) {
+	params := new(ArchiveShardParam)
+	if err := params.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: invalid param: %s", err)
+	}
+
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	if ok, err := checkVersion(native, contract); !ok || err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: check version: %s", err)
+	}
+
+	shard, err := GetShardState(native, contract, params.ShardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: get shard: %s", err)
+	}
+	if err := utils.ValidateOwner(native, shard.Creator); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: invalid creator: %s", err)
+	}
+	if shard.State != shardstates.SHARD_STATE_STOPPING {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: invalid shard state: %d", shard.State)
+	}
+	if shard.ShardID.ParentID() != native.ShardID {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: not on parent shard")
+	}
+	// shard fee should be committed after stopped
+	if isCommitting, err := shard_stake.IsShardCommitting(native, shard.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	} else if isCommitting {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: shard is committing dpos")
+	}
+	stopView, err := getShardStopView(native, shard.ShardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	}
+	currentView, err := shard_stake.GetShardCurrentViewIndex(native, shard.ShardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	}
+	if currentView <= stopView {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: shard doesn't commit dpos after stopped")
+	}
+	// cross shard txs locking state of shard should be committed or aborted before archived
+	if drained, err := isShardDrained(native, shard.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	} else if !drained {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: shard has pending cross shard txs")
+	}
+	if err := archiveStakeShard(native, shard.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: failed, err: %s", err)
+	}
+	// peers could join other shards
+	for peer := range shard.Peers {
+		setShardPeerState(native, contract, shard.ShardID, state_default, peer)
+	}
+	shard.State = shardstates.SHARD_STATE_ARCHIVED
+	setShardState(native, contract, shard)
+
+	evt := &shardstates.ShardArchiveEvent{Height: native.Height}
+	evt.SourceShardID = native.ShardID
+	evt.ShardID = shard.ShardID
+	if err := AddNotification(native, contract, evt); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("ArchiveShard: add notification failed, err: %s", err)
+	}
 	return utils.BYTE_TRUE, nil
 }
 
@@ -653,10 +822,13 @@ func NotifyParentCommitDpos(native *native.NativeService) ([]byte, error) {
 	if native.ShardID.IsRootShard() {
 		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentCommitDpos: only can be invoked at shard")
 	}
+	// locked keys are only available while invoked by consensus system tx
+	drained := native.LockedKeys != nil && len(native.LockedKeys) == 0 && len(native.LockedAddress) == 0
 	param := &NotifyRootCommitDPosParam{
 		Height:      native.Height,
 		ShardId:     native.ShardID,
 		ForceCommit: false,
+		Drained:     drained,
 	}
 	bf := new(bytes.Buffer)
 	if err := param.Serialize(bf); err != nil {
@@ -703,6 +875,10 @@ func CommitDpos(native *native.NativeService) ([]byte, error) {
 		shardCurrentView.Height == 0 && param.Height-shardCurrentView.Height+1 < shard.Config.VbftCfg.MaxBlockChangeView {
 		return utils.BYTE_FALSE, fmt.Errorf("CommitDpos: shard height not enough")
 	}
+	if shard.State == shardstates.SHARD_STATE_STOPPING {
+		// only shard itself can report that its cross shard txs have been finished
+		setShardDrained(native, shardId, param.Drained && !param.ForceCommit)
+	}
 	quitPeers := make([]string, 0)
 	// check peer exit shard
 	for peer, info := range shard.Peers {
@@ -736,12 +912,168 @@ func CommitDpos(native *native.NativeService) ([]byte, error) {
 	}
 	evt.SourceShardID = native.ShardID
 	evt.ShardID = native.ShardID
-	AddNotification(native, contract, evt)
+	if err := AddNotification(native, contract, evt); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("CommitDpos: add notification failed, err: %s", err)
+	}
 	setShardState(native, contract, shard)
 	native.NotifyRemoteShard(shardId, contract, native.ContextRef.GetRemainGas(), SHARD_COMMIT_DPOS, []byte{})
 	return utils.BYTE_TRUE, nil
 }
 
+func NotifyParentFaultyPeer(native *native.NativeService) ([]byte, error) {
+	if native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentFaultyPeer: only can be invoked at shard")
+	}
+	evidence := &utils.FaultyEvidence{}
+	if err := evidence.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentFaultyPeer: invalid param: %s", err)
+	}
+	if err := evidence.Verify(native.ShardID); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentFaultyPeer: failed, err: %s", err)
+	}
+	param := &NotifyFaultyPeerParam{
+		ShardId:  native.ShardID,
+		Evidence: evidence,
+	}
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentFaultyPeer: failed, err: %s", err)
+	}
+	native.NotifyRemoteShard(native.ShardID.ParentID(), utils.ShardMgmtContractAddress,
+		native.ContextRef.GetRemainGas(), FAULTY_PEER_NAME, bf.Bytes())
+	return utils.BYTE_TRUE, nil
+}
+
+func FaultyPeer(native *native.NativeService) ([]byte, error) {
+	data, err := serialization.ReadVarBytes(bytes.NewReader(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("decode input failed, err: %s", err)
+	}
+	param := &NotifyFaultyPeerParam{}
+	if err := param.Deserialize(bytes.NewReader(data)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: invalid param: %s", err)
+	}
+	if param.ShardId.ParentID() != native.ShardID {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: only can be invoked by child shard")
+	}
+	if !native.ContextRef.CheckCallShard(param.ShardId) {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: only can be invoked by ShardCall")
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	if ok, err := checkVersion(native, contract); !ok || err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: check version: %s", err)
+	}
+	if err := param.Evidence.Verify(param.ShardId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: failed, err: %s", err)
+	}
+	shard, err := GetShardState(native, contract, param.ShardId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: get shard state failed, err: %s", err)
+	}
+	if shard.State > shardstates.SHARD_STATE_ACTIVE {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: shard is stopped")
+	}
+	peer := strings.ToLower(param.Evidence.PeerPubkey)
+	shardPeerInfo, ok := shard.Peers[peer]
+	if !ok {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: peer %s not exist in shard", peer)
+	}
+	if reported, err := isFaultyEvidenceReported(native, param.ShardId, param.Evidence); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: failed, err: %s", err)
+	} else if reported {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: evidence has been reported")
+	}
+	setFaultyEvidenceReported(native, param.ShardId, param.Evidence)
+	if err := peerPenalty(native, param.ShardId, peer); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("FaultyPeer: failed, err: %s", err)
+	}
+	// faulty peer is removed from shard at next consensus epoch
+	if shardPeerInfo.NodeType == shardstates.CONSENSUS_NODE {
+		shardPeerInfo.NodeType = shardstates.QUIT_CONSENSUS_NODE
+	} else if shardPeerInfo.NodeType == shardstates.CONDIDATE_NODE {
+		shardPeerInfo.NodeType = shardstates.QUITING_CONSENSUS_NODE
+	}
+	shard.Peers[peer] = shardPeerInfo
+	setShardState(native, contract, shard)
+	return utils.BYTE_TRUE, nil
+}
+
+func NotifyParentEmergencyRotation(native *native.NativeService) ([]byte, error) {
+	if native.ShardID.IsRootShard() {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentEmergencyRotation: only can be invoked at shard")
+	}
+	rotation := &utils.EmergencyRotation{}
+	if err := rotation.Deserialize(bytes.NewReader(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentEmergencyRotation: invalid param: %s", err)
+	}
+	if rotation.ShardID != native.ShardID {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentEmergencyRotation: shard unmatch")
+	}
+	// votes are verified by shard consensus before rotation, and verified again at parent
+	bf := new(bytes.Buffer)
+	if err := rotation.Serialize(bf); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("NotifyParentEmergencyRotation: failed, err: %s", err)
+	}
+	native.NotifyRemoteShard(native.ShardID.ParentID(), utils.ShardMgmtContractAddress,
+		native.ContextRef.GetRemainGas(), EMERGENCY_ROTATION_NAME, bf.Bytes())
+	return utils.BYTE_TRUE, nil
+}
+
+func EmergencyRotation(native *native.NativeService) ([]byte, error) {
+	data, err := serialization.ReadVarBytes(bytes.NewReader(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("decode input failed, err: %s", err)
+	}
+	rotation := &utils.EmergencyRotation{}
+	if err := rotation.Deserialize(bytes.NewReader(data)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: invalid param: %s", err)
+	}
+	if rotation.ShardID.ParentID() != native.ShardID {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: only can be invoked by child shard")
+	}
+	if !native.ContextRef.CheckCallShard(rotation.ShardID) {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: only can be invoked by ShardCall")
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	if ok, err := checkVersion(native, contract); !ok || err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: check version: %s", err)
+	}
+	shard, err := GetShardState(native, contract, rotation.ShardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: get shard state failed, err: %s", err)
+	}
+	if shard.State != shardstates.SHARD_STATE_ACTIVE {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: shard is not active")
+	}
+	// rotation is voted in vbft view of shard, which is increased by every rotation and commit dpos of shard,
+	// so rotations are accepted in increasing views, and votes of one rotation cannot be replayed
+	lastView, rotated, err := getShardRotationView(native, rotation.ShardID)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: failed, err: %s", err)
+	}
+	if rotated && rotation.View <= lastView {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: rotation view %d not after last rotation view %d",
+			rotation.View, lastView)
+	}
+	consensusPeers := make([]string, 0)
+	for peer, info := range shard.Peers {
+		if info.NodeType == shardstates.CONSENSUS_NODE {
+			consensusPeers = append(consensusPeers, peer)
+		}
+	}
+	if err := rotation.Verify(consensusPeers); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("EmergencyRotation: failed, err: %s", err)
+	}
+	// crashed peer is not punished, it quits shard at next consensus epoch
+	peer := strings.ToLower(rotation.PeerPubkey)
+	shardPeerInfo := shard.Peers[peer]
+	shardPeerInfo.NodeType = shardstates.QUIT_CONSENSUS_NODE
+	shard.Peers[peer] = shardPeerInfo
+	setShardState(native, contract, shard)
+	setShardRotationView(native, rotation.ShardID, rotation.View)
+	return utils.BYTE_TRUE, nil
+}
+
 func NotifyShardCommitDpos(native *native.NativeService) ([]byte, error) {
 	shardId, err := utils.DeserializeShardId(bytes.NewReader(native.Input))
 	if err != nil {
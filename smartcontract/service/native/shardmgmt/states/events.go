@@ -34,6 +34,8 @@ const (
 	EVENT_SHARD_PEER_JOIN
 	EVENT_SHARD_ACTIVATED
 	EVENT_SHARD_PEER_LEAVE
+	EVENT_SHARD_STOPPING
+	EVENT_SHARD_ARCHIVED
 )
 
 type ShardMgmtEvent interface {
@@ -140,7 +142,7 @@ func (evt *ConfigShardEvent) GetType() uint32 {
 func (this *ConfigShardEvent) Serialization(sink *common.ZeroCopySink) {
 	this.ImplSourceTargetShardID.Serialization(sink)
 	sink.WriteUint32(this.Height)
-	this.Config.Serialization(sink)
+	this.Config.serializationStakeCfg(sink)
 	sink.WriteUint64(uint64(len(this.Peers)))
 	peers := make([]*PeerShardStakeInfo, 0)
 	for _, peer := range this.Peers {
@@ -152,6 +154,7 @@ func (this *ConfigShardEvent) Serialization(sink *common.ZeroCopySink) {
 	for _, peer := range peers {
 		peer.Serialization(sink)
 	}
+	this.Config.serializationConsensusCfg(sink)
 }
 
 func (this *ConfigShardEvent) Deserialization(source *common.ZeroCopySource) error {
@@ -165,7 +168,7 @@ func (this *ConfigShardEvent) Deserialization(source *common.ZeroCopySource) err
 		return io.ErrUnexpectedEOF
 	}
 	this.Config = &ShardConfig{}
-	if err := this.Config.Deserialization(source); err != nil {
+	if err := this.Config.deserializationStakeCfg(source); err != nil {
 		return fmt.Errorf("read config err: %s", err)
 	}
 	peersNum, eof := source.NextUint64()
@@ -180,6 +183,9 @@ func (this *ConfigShardEvent) Deserialization(source *common.ZeroCopySource) err
 		}
 		this.Peers[strings.ToLower(peer.PeerPubKey)] = peer
 	}
+	if err := this.Config.deserializationConsensusCfg(source); err != nil {
+		return fmt.Errorf("read consensus config err: %s", err)
+	}
 	return nil
 }
 
@@ -250,3 +256,65 @@ func (this *ShardActiveEvent) Deserialization(source *common.ZeroCopySource) err
 	}
 	return nil
 }
+
+type ShardStopEvent struct {
+	ImplSourceTargetShardID
+	Height uint32 `json:"height"`
+}
+
+func (evt *ShardStopEvent) GetHeight() uint32 {
+	return evt.Height
+}
+
+func (evt *ShardStopEvent) GetType() uint32 {
+	return EVENT_SHARD_STOPPING
+}
+
+func (this *ShardStopEvent) Serialization(sink *common.ZeroCopySink) {
+	this.ImplSourceTargetShardID.Serialization(sink)
+	sink.WriteUint32(this.Height)
+}
+
+func (this *ShardStopEvent) Deserialization(source *common.ZeroCopySource) error {
+	this.ImplSourceTargetShardID = ImplSourceTargetShardID{}
+	if err := this.ImplSourceTargetShardID.Deserialization(source); err != nil {
+		return fmt.Errorf("read impl err: %s", err)
+	}
+	var eof bool
+	this.Height, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+type ShardArchiveEvent struct {
+	ImplSourceTargetShardID
+	Height uint32 `json:"height"`
+}
+
+func (evt *ShardArchiveEvent) GetHeight() uint32 {
+	return evt.Height
+}
+
+func (evt *ShardArchiveEvent) GetType() uint32 {
+	return EVENT_SHARD_ARCHIVED
+}
+
+func (this *ShardArchiveEvent) Serialization(sink *common.ZeroCopySink) {
+	this.ImplSourceTargetShardID.Serialization(sink)
+	sink.WriteUint32(this.Height)
+}
+
+func (this *ShardArchiveEvent) Deserialization(source *common.ZeroCopySource) error {
+	this.ImplSourceTargetShardID = ImplSourceTargetShardID{}
+	if err := this.ImplSourceTargetShardID.Deserialization(source); err != nil {
+		return fmt.Errorf("read impl err: %s", err)
+	}
+	var eof bool
+	this.Height, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
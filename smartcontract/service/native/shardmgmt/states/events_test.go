@@ -46,3 +46,43 @@ func TestCreateShardEvent(t *testing.T) {
 		t.Fatalf("mismatch evt")
 	}
 }
+
+func TestShardStopEvent(t *testing.T) {
+	evt := &shardstates.ShardStopEvent{Height: 120}
+	evt.SourceShardID = common.NewShardIDUnchecked(0)
+	evt.ShardID = common.NewShardIDUnchecked(1)
+
+	sink := common.NewZeroCopySink(0)
+	evt.Serialization(sink)
+
+	evt2 := &shardstates.ShardStopEvent{}
+	if err := evt2.Deserialization(common.NewZeroCopySource(sink.Bytes())); err != nil {
+		t.Fatalf("deserialize stopEvt: %s", err)
+	}
+
+	if evt.SourceShardID != evt2.SourceShardID ||
+		evt.ShardID != evt2.ShardID ||
+		evt.Height != evt2.Height {
+		t.Fatalf("mismatch evt")
+	}
+}
+
+func TestShardArchiveEvent(t *testing.T) {
+	evt := &shardstates.ShardArchiveEvent{Height: 130}
+	evt.SourceShardID = common.NewShardIDUnchecked(0)
+	evt.ShardID = common.NewShardIDUnchecked(1)
+
+	sink := common.NewZeroCopySink(0)
+	evt.Serialization(sink)
+
+	evt2 := &shardstates.ShardArchiveEvent{}
+	if err := evt2.Deserialization(common.NewZeroCopySource(sink.Bytes())); err != nil {
+		t.Fatalf("deserialize archiveEvt: %s", err)
+	}
+
+	if evt.SourceShardID != evt2.SourceShardID ||
+		evt.ShardID != evt2.ShardID ||
+		evt.Height != evt2.Height {
+		t.Fatalf("mismatch evt")
+	}
+}
@@ -85,10 +85,35 @@ type ShardConfig struct {
 	NetworkSize       uint32
 	StakeAssetAddress common.Address
 	GasAssetAddress   common.Address
-	VbftCfg           *config.VBFTConfig
+	VbftCfg           *config.VBFTConfig // stake config of shard, also consensus config if shard runs vbft
+	ConsensusType     string             // consensus of shard, same as parent shard if empty
+	SoloCfg           *config.SOLOConfig
+	DbftCfg           *config.DBFTConfig
+	SbftCfg           *config.SBFTConfig
+}
+
+// GetConsensusType returns consensus type of shard, shards configured before consensus type was configurable
+// run the consensus of parent shard, as their config is copied from parent shard
+func (this *ShardConfig) GetConsensusType() string {
+	if this.ConsensusType == "" {
+		return config.DefConfig.Genesis.ConsensusType
+	}
+	return this.ConsensusType
 }
 
 func (this *ShardConfig) Serialization(sink *common.ZeroCopySink) {
+	this.serializationStakeCfg(sink)
+	this.serializationConsensusCfg(sink)
+}
+
+func (this *ShardConfig) Deserialization(source *common.ZeroCopySource) error {
+	if err := this.deserializationStakeCfg(source); err != nil {
+		return err
+	}
+	return this.deserializationConsensusCfg(source)
+}
+
+func (this *ShardConfig) serializationStakeCfg(sink *common.ZeroCopySink) {
 	sink.WriteUint64(this.GasPrice)
 	sink.WriteUint64(this.GasLimit)
 	sink.WriteUint32(this.NetworkSize)
@@ -97,7 +122,7 @@ func (this *ShardConfig) Serialization(sink *common.ZeroCopySink) {
 	this.VbftCfg.Serialization(sink)
 }
 
-func (this *ShardConfig) Deserialization(source *common.ZeroCopySource) error {
+func (this *ShardConfig) deserializationStakeCfg(source *common.ZeroCopySource) error {
 	var eof bool
 	this.GasPrice, eof = source.NextUint64()
 	this.GasLimit, eof = source.NextUint64()
@@ -111,6 +136,48 @@ func (this *ShardConfig) Deserialization(source *common.ZeroCopySource) error {
 	return this.VbftCfg.Deserialization(source)
 }
 
+// consensus config is serialized at the end of ShardState and ConfigShardEvent, to keep compatible with
+// the data saved before consensus type was configurable
+func (this *ShardConfig) serializationConsensusCfg(sink *common.ZeroCopySink) {
+	sink.WriteString(this.ConsensusType)
+	switch this.ConsensusType {
+	case config.CONSENSUS_TYPE_SOLO:
+		this.SoloCfg.Serialization(sink)
+	case config.CONSENSUS_TYPE_DBFT:
+		this.DbftCfg.Serialization(sink)
+	case config.CONSENSUS_TYPE_SBFT:
+		this.SbftCfg.Serialization(sink)
+	}
+}
+
+func (this *ShardConfig) deserializationConsensusCfg(source *common.ZeroCopySource) error {
+	if source.Len() == 0 {
+		// saved before consensus type was configurable, shard runs vbft
+		this.ConsensusType = ""
+		return nil
+	}
+	var eof, irregular bool
+	this.ConsensusType, _, irregular, eof = source.NextString()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	switch this.ConsensusType {
+	case config.CONSENSUS_TYPE_SOLO:
+		this.SoloCfg = &config.SOLOConfig{}
+		return this.SoloCfg.Deserialization(source)
+	case config.CONSENSUS_TYPE_DBFT:
+		this.DbftCfg = &config.DBFTConfig{}
+		return this.DbftCfg.Deserialization(source)
+	case config.CONSENSUS_TYPE_SBFT:
+		this.SbftCfg = &config.SBFTConfig{}
+		return this.SbftCfg.Deserialization(source)
+	}
+	return nil
+}
+
 type PeerShardStakeInfo struct {
 	Index      uint32
 	IpAddress  string
@@ -162,7 +229,7 @@ func (this *ShardState) Serialization(sink *common.ZeroCopySink) {
 	sink.WriteAddress(this.Creator)
 	sink.WriteUint32(this.State)
 	sink.WriteUint32(this.GenesisParentHeight)
-	this.Config.Serialization(sink)
+	this.Config.serializationStakeCfg(sink)
 	sink.WriteUint64(uint64(len(this.Peers)))
 	peers := make([]*PeerShardStakeInfo, 0)
 	for _, peer := range this.Peers {
@@ -174,6 +241,7 @@ func (this *ShardState) Serialization(sink *common.ZeroCopySink) {
 	for _, peer := range peers {
 		peer.Serialization(sink)
 	}
+	this.Config.serializationConsensusCfg(sink)
 }
 
 func (this *ShardState) Deserialization(source *common.ZeroCopySource) error {
@@ -190,7 +258,7 @@ func (this *ShardState) Deserialization(source *common.ZeroCopySource) error {
 		return io.ErrUnexpectedEOF
 	}
 	this.Config = &ShardConfig{}
-	err = this.Config.Deserialization(source)
+	err = this.Config.deserializationStakeCfg(source)
 	if err != nil {
 		return fmt.Errorf("dese config: %s", err)
 	}
@@ -206,6 +274,9 @@ func (this *ShardState) Deserialization(source *common.ZeroCopySource) error {
 		}
 		this.Peers[strings.ToLower(peer.PeerPubKey)] = peer
 	}
+	if err := this.Config.deserializationConsensusCfg(source); err != nil {
+		return fmt.Errorf("dese consensus config: %s", err)
+	}
 	return nil
 }
 
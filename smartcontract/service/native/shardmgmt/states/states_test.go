@@ -24,6 +24,8 @@ import (
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,3 +39,89 @@ func TestEncodeByte(t *testing.T) {
 	genPubKey, _ := keypair.DeserializePublicKey(data)
 	assert.Equal(t, genPubKey, acc.PublicKey)
 }
+
+func TestShardConfigSerialization(t *testing.T) {
+	acc := account.NewAccount("")
+	peerPK := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
+	cfg := &ShardConfig{
+		GasPrice:      500,
+		GasLimit:      200000,
+		NetworkSize:   1,
+		VbftCfg:       &config.VBFTConfig{K: 1, MinInitStake: 10000, Peers: []*config.VBFTPeerStakeInfo{}},
+		ConsensusType: config.CONSENSUS_TYPE_SOLO,
+		SoloCfg:       &config.SOLOConfig{GenBlockTime: 3, Bookkeepers: []string{peerPK}},
+	}
+	sink := common.NewZeroCopySink(0)
+	cfg.Serialization(sink)
+
+	newCfg := &ShardConfig{}
+	assert.Nil(t, newCfg.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, cfg, newCfg)
+	assert.Equal(t, config.CONSENSUS_TYPE_SOLO, newCfg.GetConsensusType())
+
+	vbftCfg := &ShardConfig{VbftCfg: &config.VBFTConfig{Peers: []*config.VBFTPeerStakeInfo{}}}
+	sink = common.NewZeroCopySink(0)
+	vbftCfg.Serialization(sink)
+	newCfg = &ShardConfig{}
+	assert.Nil(t, newCfg.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, config.CONSENSUS_TYPE_VBFT, newCfg.GetConsensusType())
+	assert.Nil(t, newCfg.SoloCfg)
+}
+
+func TestShardStateConsensusCfgCompatible(t *testing.T) {
+	acc := account.NewAccount("")
+	peerPK := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
+	state := &ShardState{
+		ShardID: common.NewShardIDUnchecked(1),
+		Config: &ShardConfig{
+			VbftCfg:       &config.VBFTConfig{Peers: []*config.VBFTPeerStakeInfo{}},
+			ConsensusType: config.CONSENSUS_TYPE_SOLO,
+			SoloCfg:       &config.SOLOConfig{GenBlockTime: 3, Bookkeepers: []string{peerPK}},
+		},
+		Peers: map[string]*PeerShardStakeInfo{peerPK: {Index: 1, PeerPubKey: peerPK}},
+	}
+	sink := common.NewZeroCopySink(0)
+	state.Serialization(sink)
+	newState := &ShardState{}
+	assert.Nil(t, newState.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, state, newState)
+
+	// shard state saved before consensus type was configurable
+	legacySink := common.NewZeroCopySink(0)
+	state.Config.ConsensusType = ""
+	state.Config.SoloCfg = nil
+	state.Serialization(legacySink)
+	legacy := legacySink.Bytes()
+	legacy = legacy[:len(legacy)-1]
+	newState = &ShardState{}
+	assert.Nil(t, newState.Deserialization(common.NewZeroCopySource(legacy)))
+	assert.Equal(t, config.CONSENSUS_TYPE_VBFT, newState.Config.GetConsensusType())
+	assert.Equal(t, 1, len(newState.Peers))
+
+	// legacy shard runs the consensus of parent shard
+	parentConsensus := config.DefConfig.Genesis.ConsensusType
+	defer func() { config.DefConfig.Genesis.ConsensusType = parentConsensus }()
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_SOLO
+	assert.Equal(t, config.CONSENSUS_TYPE_SOLO, newState.Config.GetConsensusType())
+}
+
+func TestShardConfigDbftCompatible(t *testing.T) {
+	cfg := &ShardConfig{
+		VbftCfg:       &config.VBFTConfig{Peers: []*config.VBFTPeerStakeInfo{}},
+		ConsensusType: config.CONSENSUS_TYPE_DBFT,
+		DbftCfg:       &config.DBFTConfig{GenBlockTime: 3, Bookkeepers: []string{}, MaxBlockChangeView: 100},
+	}
+	sink := common.NewZeroCopySink(0)
+	cfg.Serialization(sink)
+	newCfg := &ShardConfig{}
+	assert.Nil(t, newCfg.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, cfg, newCfg)
+
+	// dbft config saved before max block change view was configurable
+	legacy := sink.Bytes()
+	legacy = legacy[:len(legacy)-4]
+	newCfg = &ShardConfig{}
+	assert.Nil(t, newCfg.Deserialization(common.NewZeroCopySource(legacy)))
+	assert.Equal(t, uint32(0), newCfg.DbftCfg.MaxBlockChangeView)
+	assert.Equal(t, uint(3), newCfg.DbftCfg.GenBlockTime)
+}
@@ -19,18 +19,24 @@
 package shardmgmt
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math/big"
+	"strings"
 
+	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	cstates "github.com/ontio/ontology/core/states"
 	com "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/events/message"
 	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/service/native"
 	"github.com/ontio/ontology/smartcontract/service/native/shard_stake"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_sysmsg"
 	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/storage"
 )
 
 const (
@@ -47,6 +53,14 @@ const (
 	KEY_MGMT_SHARD_FEE_ADDR = "mgmt_shard_fee_address"
 	KEY_CREATE_SHARD_FEE    = "create_shard_fee"
 	KEY_JOIN_SHARD_FEE      = "join_shard_fee"
+
+	KEY_SHARD_STOP_VIEW = "shard_stop_view"
+	KEY_SHARD_DRAINED   = "shard_drained"
+	KEY_SHARD_DRAINING  = "shard_draining"
+
+	KEY_FAULTY_EVIDENCE = "faulty_evidence"
+
+	KEY_SHARD_ROTATION_VIEW = "shard_rotation_view"
 )
 
 type peerState string
@@ -62,6 +76,12 @@ func genPeerStateKey(contract common.Address, shardIdBytes []byte, pubKey string
 	return utils.ConcatKey(contract, shardIdBytes, []byte(KEY_SHARD_PEER_STATE), []byte(pubKey))
 }
 
+func genFaultyEvidenceKey(shardId common.ShardID, evidence *utils.FaultyEvidence) []byte {
+	shardIdBytes := utils.GetUint64Bytes(shardId.ToUint64())
+	return utils.ConcatKey(utils.ShardMgmtContractAddress, shardIdBytes, []byte(KEY_FAULTY_EVIDENCE),
+		[]byte(strings.ToLower(evidence.PeerPubkey)), []byte{evidence.Type}, utils.GetUint32Bytes(evidence.Height()))
+}
+
 func genRetryCommitDposKey() []byte {
 	return utils.ConcatKey(utils.ShardMgmtContractAddress, []byte(KEY_RETRY_COMMIT_DPOS))
 }
@@ -82,6 +102,18 @@ func genJoinShardFeeKey() []byte {
 	return utils.ConcatKey(utils.ShardMgmtContractAddress, []byte(KEY_JOIN_SHARD_FEE))
 }
 
+func genShardStopViewKey(shardIdBytes []byte) []byte {
+	return utils.ConcatKey(utils.ShardMgmtContractAddress, shardIdBytes, []byte(KEY_SHARD_STOP_VIEW))
+}
+
+func genShardDrainedKey(shardIdBytes []byte) []byte {
+	return utils.ConcatKey(utils.ShardMgmtContractAddress, shardIdBytes, []byte(KEY_SHARD_DRAINED))
+}
+
+func genShardRotationViewKey(shardIdBytes []byte) []byte {
+	return utils.ConcatKey(utils.ShardMgmtContractAddress, shardIdBytes, []byte(KEY_SHARD_ROTATION_VIEW))
+}
+
 func getVersion(native *native.NativeService, contract common.Address) (uint32, error) {
 	versionBytes, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(KEY_VERSION)))
 	if err != nil {
@@ -173,7 +205,8 @@ func setShardState(native *native.NativeService, contract common.Address, state
 	native.CacheDB.Put(key, cstates.GenRawStorageItem(sink.Bytes()))
 }
 
-func AddNotification(native *native.NativeService, contract common.Address, info shardstates.ShardMgmtEvent) {
+// AddNotification notifies the shard event and appends it to the system message queue of target shard
+func AddNotification(native *native.NativeService, contract common.Address, info shardstates.ShardMgmtEvent) error {
 	sink := common.NewZeroCopySink(0)
 	info.Serialization(sink)
 	eventState := &message.ShardEventState{
@@ -188,6 +221,12 @@ func AddNotification(native *native.NativeService, contract common.Address, info
 			ContractAddress: contract,
 			States:          eventState,
 		})
+	evtSink := common.NewZeroCopySink(0)
+	eventState.Serialization(evtSink)
+	if _, err := native.NativeCall(utils.ShardSysMsgContractAddress, shardsysmsg.ENQUEUE_NAME, evtSink.Bytes()); err != nil {
+		return fmt.Errorf("enqueue sys msg: %s", err)
+	}
+	return nil
 }
 
 func setShardPeerState(native *native.NativeService, contract common.Address, shardId common.ShardID, state peerState,
@@ -361,3 +400,206 @@ func getJoinShardFee(native *native.NativeService) (*big.Int, error) {
 	}
 	return common.BigIntFromNeoBytes(storeValue), nil
 }
+
+// record the stake view of shard while it is stopped, shard can be archived after view changed
+func setShardStopView(native *native.NativeService, shardId common.ShardID, view shard_stake.View) {
+	key := genShardStopViewKey(utils.GetUint64Bytes(shardId.ToUint64()))
+	native.CacheDB.Put(key, cstates.GenRawStorageItem(utils.GetUint32Bytes(uint32(view))))
+}
+
+func getShardStopView(native *native.NativeService, shardId common.ShardID) (shard_stake.View, error) {
+	key := genShardStopViewKey(utils.GetUint64Bytes(shardId.ToUint64()))
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return 0, fmt.Errorf("getShardStopView: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return 0, fmt.Errorf("getShardStopView: shard %d isn't stopped", shardId.ToUint64())
+	}
+	storeValue, err := cstates.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return 0, fmt.Errorf("getShardStopView: parse store value failed, err: %s", err)
+	}
+	view, err := utils.GetBytesUint32(storeValue)
+	if err != nil {
+		return 0, fmt.Errorf("getShardStopView: deserialize view failed, err: %s", err)
+	}
+	return shard_stake.View(view), nil
+}
+
+// record whether stopping shard reported that all cross shard txs have been finished
+func setShardDrained(native *native.NativeService, shardId common.ShardID, drained bool) {
+	key := genShardDrainedKey(utils.GetUint64Bytes(shardId.ToUint64()))
+	sink := common.NewZeroCopySink(1)
+	sink.WriteBool(drained)
+	native.CacheDB.Put(key, cstates.GenRawStorageItem(sink.Bytes()))
+}
+
+func isShardDrained(native *native.NativeService, shardId common.ShardID) (bool, error) {
+	key := genShardDrainedKey(utils.GetUint64Bytes(shardId.ToUint64()))
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("isShardDrained: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return false, nil
+	}
+	storeValue, err := cstates.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return false, fmt.Errorf("isShardDrained: parse store value failed, err: %s", err)
+	}
+	drained, irr, eof := common.NewZeroCopySource(storeValue).NextBool()
+	if irr || eof {
+		return false, fmt.Errorf("isShardDrained: deserialize failed")
+	}
+	return drained, nil
+}
+
+// record vbft view of the last emergency rotation accepted from shard, rotations must be in increasing views
+func setShardRotationView(native *native.NativeService, shardId common.ShardID, view uint32) {
+	key := genShardRotationViewKey(utils.GetUint64Bytes(shardId.ToUint64()))
+	native.CacheDB.Put(key, cstates.GenRawStorageItem(utils.GetUint32Bytes(view)))
+}
+
+// getShardRotationView: return false if no emergency rotation accepted from shard
+func getShardRotationView(native *native.NativeService, shardId common.ShardID) (uint32, bool, error) {
+	key := genShardRotationViewKey(utils.GetUint64Bytes(shardId.ToUint64()))
+	raw, err := native.CacheDB.Get(key)
+	if err != nil {
+		return 0, false, fmt.Errorf("getShardRotationView: read db failed, err: %s", err)
+	}
+	if len(raw) == 0 {
+		return 0, false, nil
+	}
+	storeValue, err := cstates.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return 0, false, fmt.Errorf("getShardRotationView: parse store value failed, err: %s", err)
+	}
+	view, err := utils.GetBytesUint32(storeValue)
+	if err != nil {
+		return 0, false, fmt.Errorf("getShardRotationView: deserialize view failed, err: %s", err)
+	}
+	return view, true, nil
+}
+
+// mark local shard as stopping at parent, only cross shard txs can be executed at shard afterward
+func setShardDraining(native *native.NativeService) {
+	key := utils.ConcatKey(utils.ShardMgmtContractAddress, []byte(KEY_SHARD_DRAINING))
+	native.CacheDB.Put(key, cstates.GenRawStorageItem([]byte{1}))
+}
+
+// IsShardDraining returns whether local shard has been stopped by parent shard
+func IsShardDraining(cache *storage.CacheDB) (bool, error) {
+	raw, err := cache.Get(utils.ConcatKey(utils.ShardMgmtContractAddress, []byte(KEY_SHARD_DRAINING)))
+	if err != nil {
+		return false, fmt.Errorf("IsShardDraining: read db failed, err: %s", err)
+	}
+	return len(raw) != 0, nil
+}
+
+func isFaultyEvidenceReported(native *native.NativeService, shardId common.ShardID, evidence *utils.FaultyEvidence) (bool, error) {
+	data, err := native.CacheDB.Get(genFaultyEvidenceKey(shardId, evidence))
+	if err != nil {
+		return false, fmt.Errorf("isFaultyEvidenceReported: read db failed, err: %s", err)
+	}
+	return len(data) > 0, nil
+}
+
+func setFaultyEvidenceReported(native *native.NativeService, shardId common.ShardID, evidence *utils.FaultyEvidence) {
+	native.CacheDB.Put(genFaultyEvidenceKey(shardId, evidence), cstates.GenRawStorageItem(utils.GetUint32Bytes(native.Height)))
+}
+
+// defaultStakeConfig: stake config of shard not running vbft if vbft config is not set,
+// every bookkeeper is required to be consensus peer
+func defaultStakeConfig(cfg *shardstates.ShardConfig) *config.VBFTConfig {
+	var bookkeepers []string
+	switch cfg.GetConsensusType() {
+	case config.CONSENSUS_TYPE_SOLO:
+		bookkeepers = cfg.SoloCfg.Bookkeepers
+	case config.CONSENSUS_TYPE_DBFT:
+		bookkeepers = cfg.DbftCfg.Bookkeepers
+	case config.CONSENSUS_TYPE_SBFT:
+		bookkeepers = cfg.SbftCfg.Bookkeepers
+	}
+	n := uint32(len(bookkeepers))
+	if n == 0 {
+		n = 1
+	}
+	maxBlockChangeView := uint32(DEFAULT_MAX_BLOCK_CHANGE_VIEW)
+	if cfg.DbftCfg != nil && cfg.DbftCfg.MaxBlockChangeView != 0 {
+		maxBlockChangeView = cfg.DbftCfg.MaxBlockChangeView
+	}
+	return &config.VBFTConfig{
+		N:                  n,
+		K:                  n,
+		MaxBlockChangeView: maxBlockChangeView,
+		MinInitStake:       DEFAULT_MIN_INIT_STAKE,
+	}
+}
+
+// checkShardConsensusConfig: validate consensus config of shard,
+// shards not running vbft still use vbft config for peer staking
+func checkShardConsensusConfig(cfg *shardstates.ShardConfig) error {
+	if cfg.VbftCfg == nil {
+		return fmt.Errorf("vbft config not set")
+	}
+	switch cfg.GetConsensusType() {
+	case config.CONSENSUS_TYPE_VBFT:
+		return utils.CheckVBFTConfig(cfg.VbftCfg)
+	case config.CONSENSUS_TYPE_SOLO:
+		if cfg.SoloCfg == nil {
+			return fmt.Errorf("solo config not set")
+		}
+		if len(cfg.SoloCfg.Bookkeepers) > 1 {
+			return fmt.Errorf("solo shard supports only one bookkeeper")
+		}
+		if err := checkShardBookkeepers(cfg.SoloCfg.GenBlockTime, cfg.SoloCfg.Bookkeepers); err != nil {
+			return fmt.Errorf("invalid solo config: %s", err)
+		}
+	case config.CONSENSUS_TYPE_DBFT:
+		if cfg.DbftCfg == nil {
+			return fmt.Errorf("dbft config not set")
+		}
+		if err := checkShardBookkeepers(cfg.DbftCfg.GenBlockTime, cfg.DbftCfg.Bookkeepers); err != nil {
+			return fmt.Errorf("invalid dbft config: %s", err)
+		}
+	case config.CONSENSUS_TYPE_SBFT:
+		if cfg.SbftCfg == nil {
+			return fmt.Errorf("sbft config not set")
+		}
+		if err := checkShardBookkeepers(cfg.SbftCfg.GenBlockTime, cfg.SbftCfg.Bookkeepers); err != nil {
+			return fmt.Errorf("invalid sbft config: %s", err)
+		}
+	default:
+		return fmt.Errorf("unsupported consensus type %s", cfg.ConsensusType)
+	}
+	if cfg.VbftCfg.K == 0 || cfg.VbftCfg.N < cfg.VbftCfg.K {
+		return fmt.Errorf("stake config not match N >= K > 0")
+	}
+	if cfg.VbftCfg.MinInitStake < 10000 {
+		return fmt.Errorf("stake config MinInitStake must >= 10000")
+	}
+	return nil
+}
+
+// checkShardBookkeepers: bookkeepers are optional, all consensus peers of shard are bookkeepers if not set
+func checkShardBookkeepers(genBlockTime uint, bookkeepers []string) error {
+	if genBlockTime == 0 {
+		return fmt.Errorf("gen block time can not be 0")
+	}
+	bookkeeperMap := make(map[string]struct{})
+	for _, bookkeeper := range bookkeepers {
+		pubKey, err := hex.DecodeString(bookkeeper)
+		if err != nil {
+			return fmt.Errorf("decode bookkeeper %s failed, err: %s", bookkeeper, err)
+		}
+		if _, err := keypair.DeserializePublicKey(pubKey); err != nil {
+			return fmt.Errorf("invalid bookkeeper %s, err: %s", bookkeeper, err)
+		}
+		if _, present := bookkeeperMap[strings.ToLower(bookkeeper)]; present {
+			return fmt.Errorf("duplicated bookkeeper %s", bookkeeper)
+		}
+		bookkeeperMap[strings.ToLower(bookkeeper)] = struct{}{}
+	}
+	return nil
+}
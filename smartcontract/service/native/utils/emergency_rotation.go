@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/core/signature"
+)
+
+// RotationVote is the signature of a consensus peer agreeing to rotate the crashed peer out
+type RotationVote struct {
+	Voter string
+	Sig   []byte
+}
+
+// EmergencyRotation is the proof of 2/3 consensus peers of shard agreeing to remove a crashed peer
+// from consensus, the peer is rotated out at next block, root chain is informed afterward
+type EmergencyRotation struct {
+	ShardID    common.ShardID
+	View       uint32 // shard consensus view in which the peer is rotated out
+	PeerPubkey string
+	Votes      []*RotationVote
+}
+
+// RotationVoteHash returns the data signed by voters
+func RotationVoteHash(shardID common.ShardID, view uint32, peerPubkey string) common.Uint256 {
+	buf := new(bytes.Buffer)
+	serialization.WriteUint64(buf, shardID.ToUint64())
+	serialization.WriteUint32(buf, view)
+	serialization.WriteString(buf, strings.ToLower(peerPubkey))
+	return sha256.Sum256(buf.Bytes())
+}
+
+func (this *EmergencyRotation) Serialize(w io.Writer) error {
+	if err := SerializeShardId(w, this.ShardID); err != nil {
+		return fmt.Errorf("serialize shard id failed, err: %s", err)
+	}
+	if err := serialization.WriteUint32(w, this.View); err != nil {
+		return fmt.Errorf("serialize view failed, err: %s", err)
+	}
+	if err := serialization.WriteString(w, this.PeerPubkey); err != nil {
+		return fmt.Errorf("serialize peer pubkey failed, err: %s", err)
+	}
+	if err := WriteVarUint(w, uint64(len(this.Votes))); err != nil {
+		return fmt.Errorf("serialize vote num failed, err: %s", err)
+	}
+	for index, vote := range this.Votes {
+		if err := serialization.WriteString(w, vote.Voter); err != nil {
+			return fmt.Errorf("serialize voter failed, index %d, err: %s", index, err)
+		}
+		if err := serialization.WriteVarBytes(w, vote.Sig); err != nil {
+			return fmt.Errorf("serialize vote sig failed, index %d, err: %s", index, err)
+		}
+	}
+	return nil
+}
+
+func (this *EmergencyRotation) Deserialize(r io.Reader) error {
+	var err error
+	if this.ShardID, err = DeserializeShardId(r); err != nil {
+		return fmt.Errorf("deserialize shard id failed, err: %s", err)
+	}
+	if this.View, err = serialization.ReadUint32(r); err != nil {
+		return fmt.Errorf("deserialize view failed, err: %s", err)
+	}
+	if this.PeerPubkey, err = serialization.ReadString(r); err != nil {
+		return fmt.Errorf("deserialize peer pubkey failed, err: %s", err)
+	}
+	num, err := ReadVarUint(r)
+	if err != nil {
+		return fmt.Errorf("deserialize vote num failed, err: %s", err)
+	}
+	this.Votes = make([]*RotationVote, 0)
+	for i := uint64(0); i < num; i++ {
+		vote := &RotationVote{}
+		if vote.Voter, err = serialization.ReadString(r); err != nil {
+			return fmt.Errorf("deserialize voter failed, index %d, err: %s", i, err)
+		}
+		if vote.Sig, err = serialization.ReadVarBytes(r); err != nil {
+			return fmt.Errorf("deserialize vote sig failed, index %d, err: %s", i, err)
+		}
+		this.Votes = append(this.Votes, vote)
+	}
+	return nil
+}
+
+// Verify checks the rotation is signed by at least 2/3 of consensus peers, the rotated peer must be one
+// of consensus peers and cannot vote for itself
+func (this *EmergencyRotation) Verify(consensusPeers []string) error {
+	peers := make(map[string]bool)
+	for _, peer := range consensusPeers {
+		peers[strings.ToLower(peer)] = true
+	}
+	rotated := strings.ToLower(this.PeerPubkey)
+	if !peers[rotated] {
+		return fmt.Errorf("EmergencyRotation.Verify: peer %s is not consensus peer", this.PeerPubkey)
+	}
+	hash := RotationVoteHash(this.ShardID, this.View, rotated)
+	voted := make(map[string]bool)
+	for index, vote := range this.Votes {
+		voter := strings.ToLower(vote.Voter)
+		if !peers[voter] || voter == rotated {
+			return fmt.Errorf("EmergencyRotation.Verify: invalid voter %s", vote.Voter)
+		}
+		if voted[voter] {
+			return fmt.Errorf("EmergencyRotation.Verify: duplicated voter %s", vote.Voter)
+		}
+		pubKeyData, err := hex.DecodeString(voter)
+		if err != nil {
+			return fmt.Errorf("EmergencyRotation.Verify: decode voter %d failed, err: %s", index, err)
+		}
+		pubKey, err := keypair.DeserializePublicKey(pubKeyData)
+		if err != nil {
+			return fmt.Errorf("EmergencyRotation.Verify: deserialize voter %d failed, err: %s", index, err)
+		}
+		if err := signature.Verify(pubKey, hash[:], vote.Sig); err != nil {
+			return fmt.Errorf("EmergencyRotation.Verify: verify vote %d failed, err: %s", index, err)
+		}
+		voted[voter] = true
+	}
+	if 3*len(voted) < 2*len(peers) {
+		return fmt.Errorf("EmergencyRotation.Verify: votes %d less than 2/3 of %d peers", len(voted), len(peers))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRotation(t *testing.T, accs []*account.Account, rotated int, voters []int) *EmergencyRotation {
+	shardID := common.NewShardIDUnchecked(1)
+	peer := hex.EncodeToString(keypair.SerializePublicKey(accs[rotated].PublicKey))
+	rotation := &EmergencyRotation{
+		ShardID:    shardID,
+		View:       3,
+		PeerPubkey: peer,
+		Votes:      make([]*RotationVote, 0),
+	}
+	hash := RotationVoteHash(shardID, 3, peer)
+	for _, i := range voters {
+		sig, err := signature.Sign(accs[i], hash[:])
+		assert.Nil(t, err)
+		rotation.Votes = append(rotation.Votes, &RotationVote{
+			Voter: hex.EncodeToString(keypair.SerializePublicKey(accs[i].PublicKey)),
+			Sig:   sig,
+		})
+	}
+	return rotation
+}
+
+func TestEmergencyRotation(t *testing.T) {
+	accs := make([]*account.Account, 0)
+	peers := make([]string, 0)
+	for i := 0; i < 7; i++ {
+		acc := account.NewAccount("")
+		accs = append(accs, acc)
+		peers = append(peers, hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey)))
+	}
+
+	rotation := newTestRotation(t, accs, 0, []int{1, 2, 3, 4, 5})
+	assert.Nil(t, rotation.Verify(peers))
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, rotation.Serialize(buf))
+	decoded := &EmergencyRotation{}
+	assert.Nil(t, decoded.Deserialize(buf))
+	assert.Equal(t, rotation, decoded)
+	assert.Nil(t, decoded.Verify(peers))
+
+	// not enough votes
+	assert.NotNil(t, newTestRotation(t, accs, 0, []int{1, 2, 3, 4}).Verify(peers))
+	// duplicated votes
+	assert.NotNil(t, newTestRotation(t, accs, 0, []int{1, 2, 3, 4, 4}).Verify(peers))
+	// rotated peer votes for itself
+	assert.NotNil(t, newTestRotation(t, accs, 0, []int{0, 1, 2, 3, 4}).Verify(peers))
+	// rotated peer is not consensus peer
+	assert.NotNil(t, newTestRotation(t, accs, 0, []int{1, 2, 3, 4, 5}).Verify(peers[1:]))
+	// vote signed for another view
+	rotation = newTestRotation(t, accs, 0, []int{1, 2, 3, 4, 5})
+	rotation.View = 4
+	assert.NotNil(t, rotation.Verify(peers))
+}
@@ -0,0 +1,329 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+)
+
+const (
+	FAULTY_PROPOSAL    uint8 = 1 // proposer signed two different proposals for the same block
+	FAULTY_ENDORSEMENT uint8 = 2 // endorser endorsed two different non-empty blocks for the same block
+)
+
+// FaultyProposal is the signed part of a vbft proposal, block header and empty block header are
+// both signed by proposer, tx hashes are used to check the headers and to distinguish the block from
+// the empty block
+type FaultyProposal struct {
+	Header        *types.Header
+	EmptyHeader   *types.Header
+	TxHashes      []common.Uint256
+	EmptyTxHashes []common.Uint256
+}
+
+func NewFaultyProposal(block, emptyBlock *types.Block) *FaultyProposal {
+	return &FaultyProposal{
+		Header:        proposerSignedHeader(block.Header),
+		EmptyHeader:   proposerSignedHeader(emptyBlock.Header),
+		TxHashes:      blockTxHashes(block),
+		EmptyTxHashes: blockTxHashes(emptyBlock),
+	}
+}
+
+// keep the proposer signature only, endorser signatures are appended to header when block sealed
+func proposerSignedHeader(header *types.Header) *types.Header {
+	h := *header
+	if len(h.Bookkeepers) > 1 {
+		h.Bookkeepers = h.Bookkeepers[:1]
+	}
+	if len(h.SigData) > 1 {
+		h.SigData = h.SigData[:1]
+	}
+	return &h
+}
+
+func blockTxHashes(block *types.Block) []common.Uint256 {
+	hashes := make([]common.Uint256, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		hashes = append(hashes, tx.Hash())
+	}
+	return hashes
+}
+
+func (this *FaultyProposal) Serialize(w io.Writer) error {
+	if err := serialization.WriteVarBytes(w, this.Header.ToArray()); err != nil {
+		return fmt.Errorf("serialize header failed, err: %s", err)
+	}
+	if err := serialization.WriteVarBytes(w, this.EmptyHeader.ToArray()); err != nil {
+		return fmt.Errorf("serialize empty header failed, err: %s", err)
+	}
+	if err := serializeHashes(w, this.TxHashes); err != nil {
+		return fmt.Errorf("serialize tx hashes failed, err: %s", err)
+	}
+	if err := serializeHashes(w, this.EmptyTxHashes); err != nil {
+		return fmt.Errorf("serialize empty tx hashes failed, err: %s", err)
+	}
+	return nil
+}
+
+func (this *FaultyProposal) Deserialize(r io.Reader) error {
+	raw, err := serialization.ReadVarBytes(r)
+	if err != nil {
+		return fmt.Errorf("read header failed, err: %s", err)
+	}
+	if this.Header, err = types.HeaderFromRawBytes(raw); err != nil {
+		return fmt.Errorf("deserialize header failed, err: %s", err)
+	}
+	raw, err = serialization.ReadVarBytes(r)
+	if err != nil {
+		return fmt.Errorf("read empty header failed, err: %s", err)
+	}
+	if this.EmptyHeader, err = types.HeaderFromRawBytes(raw); err != nil {
+		return fmt.Errorf("deserialize empty header failed, err: %s", err)
+	}
+	if this.TxHashes, err = deserializeHashes(r); err != nil {
+		return fmt.Errorf("deserialize tx hashes failed, err: %s", err)
+	}
+	if this.EmptyTxHashes, err = deserializeHashes(r); err != nil {
+		return fmt.Errorf("deserialize empty tx hashes failed, err: %s", err)
+	}
+	return nil
+}
+
+func serializeHashes(w io.Writer, hashes []common.Uint256) error {
+	if err := WriteVarUint(w, uint64(len(hashes))); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if err := hash.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deserializeHashes(r io.Reader) ([]common.Uint256, error) {
+	num, err := ReadVarUint(r)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]common.Uint256, 0)
+	for i := uint64(0); i < num; i++ {
+		hash := common.Uint256{}
+		if err := hash.Deserialize(r); err != nil {
+			return nil, fmt.Errorf("index %d, err: %s", i, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// verify headers signature and tx root, return proposer pubkey
+func (this *FaultyProposal) verify(shardID common.ShardID) (keypair.PublicKey, error) {
+	if this.Header == nil || this.EmptyHeader == nil {
+		return nil, fmt.Errorf("header is nil")
+	}
+	if this.Header.Height != this.EmptyHeader.Height {
+		return nil, fmt.Errorf("height of header and empty header unmatch")
+	}
+	if this.Header.ShardID.ToUint64() != shardID.ToUint64() ||
+		this.EmptyHeader.ShardID.ToUint64() != shardID.ToUint64() {
+		return nil, fmt.Errorf("shard of header unmatch")
+	}
+	if len(this.Header.Bookkeepers) == 0 || len(this.EmptyHeader.Bookkeepers) == 0 {
+		return nil, fmt.Errorf("header has no bookkeeper")
+	}
+	proposer := this.Header.Bookkeepers[0]
+	if !bytes.Equal(keypair.SerializePublicKey(proposer), keypair.SerializePublicKey(this.EmptyHeader.Bookkeepers[0])) {
+		return nil, fmt.Errorf("proposer of header and empty header unmatch")
+	}
+	for _, header := range []*types.Header{this.Header, this.EmptyHeader} {
+		if len(header.SigData) == 0 {
+			return nil, fmt.Errorf("header has no sig data")
+		}
+		hash := header.Hash()
+		if err := signature.Verify(proposer, hash[:], header.SigData[0]); err != nil {
+			return nil, fmt.Errorf("verify header %s failed, err: %s", hash.ToHexString(), err)
+		}
+	}
+	if common.ComputeMerkleRoot(this.TxHashes) != this.Header.TransactionsRoot {
+		return nil, fmt.Errorf("tx root of header unmatch")
+	}
+	if common.ComputeMerkleRoot(this.EmptyTxHashes) != this.EmptyHeader.TransactionsRoot {
+		return nil, fmt.Errorf("tx root of empty header unmatch")
+	}
+	// txs of empty block are the sys txs, which are prefix of block txs
+	if len(this.EmptyTxHashes) > len(this.TxHashes) {
+		return nil, fmt.Errorf("empty block has more txs than block")
+	}
+	for i, hash := range this.EmptyTxHashes {
+		if this.TxHashes[i] != hash {
+			return nil, fmt.Errorf("txs of empty block is not prefix of block")
+		}
+	}
+	return proposer, nil
+}
+
+// FaultyEvidence is the proof of a vbft peer signing two conflict messages for the same block
+type FaultyEvidence struct {
+	Type        uint8
+	PeerPubkey  string
+	Proposals   []*FaultyProposal
+	EndorseSigs [][]byte // endorser signatures on block hash of each proposal, only for endorsement
+}
+
+func (this *FaultyEvidence) Serialize(w io.Writer) error {
+	if err := serialization.WriteUint8(w, this.Type); err != nil {
+		return fmt.Errorf("serialize type failed, err: %s", err)
+	}
+	if err := serialization.WriteString(w, this.PeerPubkey); err != nil {
+		return fmt.Errorf("serialize peer pubkey failed, err: %s", err)
+	}
+	if err := WriteVarUint(w, uint64(len(this.Proposals))); err != nil {
+		return fmt.Errorf("serialize proposal num failed, err: %s", err)
+	}
+	for index, proposal := range this.Proposals {
+		if err := proposal.Serialize(w); err != nil {
+			return fmt.Errorf("serialize proposal failed, index %d, err: %s", index, err)
+		}
+	}
+	if err := WriteVarUint(w, uint64(len(this.EndorseSigs))); err != nil {
+		return fmt.Errorf("serialize endorse sig num failed, err: %s", err)
+	}
+	for index, sig := range this.EndorseSigs {
+		if err := serialization.WriteVarBytes(w, sig); err != nil {
+			return fmt.Errorf("serialize endorse sig failed, index %d, err: %s", index, err)
+		}
+	}
+	return nil
+}
+
+func (this *FaultyEvidence) Deserialize(r io.Reader) error {
+	var err error
+	if this.Type, err = serialization.ReadUint8(r); err != nil {
+		return fmt.Errorf("deserialize type failed, err: %s", err)
+	}
+	if this.PeerPubkey, err = serialization.ReadString(r); err != nil {
+		return fmt.Errorf("deserialize peer pubkey failed, err: %s", err)
+	}
+	num, err := ReadVarUint(r)
+	if err != nil {
+		return fmt.Errorf("deserialize proposal num failed, err: %s", err)
+	}
+	this.Proposals = make([]*FaultyProposal, 0)
+	for i := uint64(0); i < num; i++ {
+		proposal := &FaultyProposal{}
+		if err := proposal.Deserialize(r); err != nil {
+			return fmt.Errorf("deserialize proposal failed, index %d, err: %s", i, err)
+		}
+		this.Proposals = append(this.Proposals, proposal)
+	}
+	num, err = ReadVarUint(r)
+	if err != nil {
+		return fmt.Errorf("deserialize endorse sig num failed, err: %s", err)
+	}
+	this.EndorseSigs = make([][]byte, 0)
+	for i := uint64(0); i < num; i++ {
+		sig, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return fmt.Errorf("deserialize endorse sig failed, index %d, err: %s", i, err)
+		}
+		this.EndorseSigs = append(this.EndorseSigs, sig)
+	}
+	return nil
+}
+
+// Height returns the block height where the peer is faulty, call it after Verify
+func (this *FaultyEvidence) Height() uint32 {
+	return this.Proposals[0].Header.Height
+}
+
+// Verify checks the evidence is valid on shard, the evidence must be deterministically verifiable with
+// the signatures in it, so that it can be verified at contract
+func (this *FaultyEvidence) Verify(shardID common.ShardID) error {
+	if len(this.Proposals) != 2 {
+		return fmt.Errorf("FaultyEvidence.Verify: invalid proposal num %d", len(this.Proposals))
+	}
+	pubKeyData, err := hex.DecodeString(this.PeerPubkey)
+	if err != nil {
+		return fmt.Errorf("FaultyEvidence.Verify: decode peer pubkey failed, err: %s", err)
+	}
+	peerPubKey, err := keypair.DeserializePublicKey(pubKeyData)
+	if err != nil {
+		return fmt.Errorf("FaultyEvidence.Verify: deserialize peer pubkey failed, err: %s", err)
+	}
+	proposers := make([]keypair.PublicKey, 0)
+	for index, proposal := range this.Proposals {
+		proposer, err := proposal.verify(shardID)
+		if err != nil {
+			return fmt.Errorf("FaultyEvidence.Verify: proposal %d, %s", index, err)
+		}
+		proposers = append(proposers, proposer)
+	}
+	p1, p2 := this.Proposals[0], this.Proposals[1]
+	if p1.Header.Height != p2.Header.Height {
+		return fmt.Errorf("FaultyEvidence.Verify: height of proposals unmatch")
+	}
+	switch this.Type {
+	case FAULTY_PROPOSAL:
+		for _, proposer := range proposers {
+			if !bytes.Equal(keypair.SerializePublicKey(proposer), pubKeyData) {
+				return fmt.Errorf("FaultyEvidence.Verify: proposal is not proposed by peer")
+			}
+		}
+		// honest proposer only signs one block and one empty block for each height
+		for _, h1 := range []common.Uint256{p1.Header.Hash(), p1.EmptyHeader.Hash()} {
+			for _, h2 := range []common.Uint256{p2.Header.Hash(), p2.EmptyHeader.Hash()} {
+				if h1 == h2 {
+					return fmt.Errorf("FaultyEvidence.Verify: proposals are not conflict")
+				}
+			}
+		}
+	case FAULTY_ENDORSEMENT:
+		if len(this.EndorseSigs) != len(this.Proposals) {
+			return fmt.Errorf("FaultyEvidence.Verify: invalid endorse sig num %d", len(this.EndorseSigs))
+		}
+		// endorser could endorse one block and one empty block for each height, the endorsed blocks
+		// must have user txs, so that they cannot be taken as empty block
+		for index, proposal := range this.Proposals {
+			if len(proposal.TxHashes) <= len(proposal.EmptyTxHashes) {
+				return fmt.Errorf("FaultyEvidence.Verify: proposal %d has no user tx", index)
+			}
+			hash := proposal.Header.Hash()
+			if err := signature.Verify(peerPubKey, hash[:], this.EndorseSigs[index]); err != nil {
+				return fmt.Errorf("FaultyEvidence.Verify: verify endorse sig %d failed, err: %s", index, err)
+			}
+		}
+		if p1.Header.Hash() == p2.Header.Hash() {
+			return fmt.Errorf("FaultyEvidence.Verify: endorsements are not conflict")
+		}
+	default:
+		return fmt.Errorf("FaultyEvidence.Verify: unknown evidence type %d", this.Type)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTx(t *testing.T, nonce uint32) *types.Transaction {
+	mutable := &types.MutableTransaction{
+		TxType:  types.Invoke,
+		Nonce:   nonce,
+		Payload: &payload.InvokeCode{Code: []byte{byte(nonce)}},
+	}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	return tx
+}
+
+func newTestBlock(t *testing.T, acc *account.Account, height uint32, timestamp uint32, txs []*types.Transaction) *types.Block {
+	hashes := make([]common.Uint256, 0)
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash())
+	}
+	header := &types.Header{
+		Height:           height,
+		Timestamp:        timestamp,
+		TransactionsRoot: common.ComputeMerkleRoot(hashes),
+	}
+	hash := header.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	assert.Nil(t, err)
+	header.Bookkeepers = []keypair.PublicKey{acc.PublicKey}
+	header.SigData = [][]byte{sig}
+	return &types.Block{
+		Header:       header,
+		Transactions: txs,
+	}
+}
+
+func newTestProposal(t *testing.T, acc *account.Account, height uint32, timestamp uint32) *FaultyProposal {
+	sysTx := newTestTx(t, 1)
+	block := newTestBlock(t, acc, height, timestamp, []*types.Transaction{sysTx, newTestTx(t, timestamp)})
+	emptyBlock := newTestBlock(t, acc, height, timestamp, []*types.Transaction{sysTx})
+	return NewFaultyProposal(block, emptyBlock)
+}
+
+func TestFaultyProposalEvidence(t *testing.T) {
+	proposer := account.NewAccount("")
+	evidence := &FaultyEvidence{
+		Type:       FAULTY_PROPOSAL,
+		PeerPubkey: hex.EncodeToString(keypair.SerializePublicKey(proposer.PublicKey)),
+		Proposals: []*FaultyProposal{
+			newTestProposal(t, proposer, 10, 100),
+			newTestProposal(t, proposer, 10, 101),
+		},
+	}
+	assert.Nil(t, evidence.Verify(common.NewShardIDUnchecked(0)))
+	assert.Equal(t, uint32(10), evidence.Height())
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, evidence.Serialize(buf))
+	result := &FaultyEvidence{}
+	assert.Nil(t, result.Deserialize(bytes.NewReader(buf.Bytes())))
+	assert.Nil(t, result.Verify(common.NewShardIDUnchecked(0)))
+	assert.Equal(t, evidence.PeerPubkey, result.PeerPubkey)
+
+	// same proposal is not faulty
+	evidence.Proposals[1] = evidence.Proposals[0]
+	assert.NotNil(t, evidence.Verify(common.NewShardIDUnchecked(0)))
+	// proposal from other shard
+	evidence.Proposals[1] = newTestProposal(t, proposer, 10, 101)
+	assert.NotNil(t, evidence.Verify(common.NewShardIDUnchecked(1)))
+}
+
+func TestFaultyEndorsementEvidence(t *testing.T) {
+	proposer := account.NewAccount("")
+	endorser := account.NewAccount("")
+	p1 := newTestProposal(t, proposer, 10, 100)
+	p2 := newTestProposal(t, proposer, 10, 101)
+	sigs := make([][]byte, 0)
+	for _, p := range []*FaultyProposal{p1, p2} {
+		hash := p.Header.Hash()
+		sig, err := signature.Sign(endorser, hash[:])
+		assert.Nil(t, err)
+		sigs = append(sigs, sig)
+	}
+	evidence := &FaultyEvidence{
+		Type:        FAULTY_ENDORSEMENT,
+		PeerPubkey:  hex.EncodeToString(keypair.SerializePublicKey(endorser.PublicKey)),
+		Proposals:   []*FaultyProposal{p1, p2},
+		EndorseSigs: sigs,
+	}
+	assert.Nil(t, evidence.Verify(common.NewShardIDUnchecked(0)))
+
+	// endorsement for empty block is not faulty
+	hash := p2.EmptyHeader.Hash()
+	sig, err := signature.Sign(endorser, hash[:])
+	assert.Nil(t, err)
+	evidence.EndorseSigs[1] = sig
+	assert.NotNil(t, evidence.Verify(common.NewShardIDUnchecked(0)))
+}
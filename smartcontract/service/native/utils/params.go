@@ -19,7 +19,10 @@ package utils
 
 import "github.com/ontio/ontology/common"
 
-const VERSION_CONTRACT_SHARD_MGMT = uint32(1)
+const (
+	VERSION_CONTRACT_SHARD_MGMT   = uint32(1)
+	VERSION_CONTRACT_SHARD_SYSMSG = uint32(1)
+)
 
 var (
 	BYTE_FALSE = []byte{0}
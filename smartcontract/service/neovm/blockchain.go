@@ -20,6 +20,7 @@ package neovm
 
 import (
 	"github.com/ontio/ontology/common"
+	scom "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
 	vm "github.com/ontio/ontology/vm/neovm"
@@ -153,7 +154,8 @@ func BlockChainGetTransactionHeight(service *NeoVmService, engine *vm.ExecutionE
 		return err
 	}
 	_, h, err := service.Store.GetTransaction(hash)
-	if err != nil {
+	// height of pruned transaction is still kept by ledger
+	if err != nil && err != scom.ErrPruned {
 		return errors.NewDetailErr(err, errors.ErrNoCode, "[BlockChainGetTransaction] GetTransaction error!")
 	}
 	vm.PushData(engine, h)
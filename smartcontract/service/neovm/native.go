@@ -84,6 +84,7 @@ func NativeInvoke(service *NeoVmService, engine *vm.ExecutionEngine) error {
 		ShardID:       service.ShardID,
 		ShardTxState:  service.ShardTxState,
 		LockedAddress: service.LockedAddress,
+		LockedKeys:    service.LockedKeys,
 		Height:        service.Height,
 		Time:          service.Time,
 		ContextRef:    service.ContextRef,
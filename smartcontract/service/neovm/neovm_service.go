@@ -35,6 +35,7 @@ import (
 	"github.com/ontio/ontology/smartcontract/storage"
 	vm "github.com/ontio/ontology/vm/neovm"
 	ntypes "github.com/ontio/ontology/vm/neovm/types"
+	"github.com/ontio/ontology/vm/wasmvm/wasm"
 )
 
 var (
@@ -133,6 +134,7 @@ type NeoVmService struct {
 	Code          []byte
 	GasTable      map[string]uint64
 	LockedAddress map[scommon.Address]struct{}
+	LockedKeys    map[string]struct{}
 	Tx            *types.Transaction
 	ShardID       scommon.ShardID
 	ShardTxState  *xshard_state.TxState
@@ -255,6 +257,14 @@ func (this *NeoVmService) Invoke() (interface{}, error) {
 			if err = this.checkMetaDataAndCode(isSelfShardContract, addr); err != nil {
 				return nil, err
 			}
+			if wasm.IsWasmCode(code) {
+				result, err := this.invokeWasm(code)
+				if err != nil {
+					return nil, err
+				}
+				vm.PushData(this.Engine, result)
+				break
+			}
 			service, err := this.ContextRef.NewExecuteEngine(code)
 			if err != nil {
 				return nil, err
@@ -277,20 +287,6 @@ func (this *NeoVmService) Invoke() (interface{}, error) {
 		}
 	}
 
-	// TODO: check txstate-db, if abort remote transactions
-	if this.Engine.EvaluationStack.Count() == 1 {
-		//tx := this.Tx.Hash()
-		//txPaused, err := xshard_state.IsTxExecutionPaused(tx)
-		//if err != nil || !txPaused {
-		// todo:
-		//if shards, err := xshard_state.GetTxShards(tx); err != xshard_state.ErrNotFound {
-		//	for _, s := range shards {
-		//		log.Errorf("TODO: abort transaction %s on shard %d", scommon.ToHexString(tx[:]), s)
-		//	}
-		//}
-		//}
-	}
-
 	this.ContextRef.PopContext()
 	this.ContextRef.PushNotifications(this.Notifications)
 	if this.Engine.EvaluationStack.Count() != 0 {
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package neovm
+
+import (
+	"bytes"
+	"fmt"
+
+	vm "github.com/ontio/ontology/vm/neovm"
+	"github.com/ontio/ontology/vm/neovm/types"
+)
+
+// invokeWasm call wasm contract code with the method and args on evaluation stack,
+// a byte array args is passed through as it is, other args are serialized as native params
+func (this *NeoVmService) invokeWasm(code []byte) ([]byte, error) {
+	if vm.EvaluationStackCount(this.Engine) < 2 {
+		return nil, fmt.Errorf("[invokeWasm] too few input parameters: %d", vm.EvaluationStackCount(this.Engine))
+	}
+	method, err := vm.PopByteArray(this.Engine)
+	if err != nil {
+		return nil, fmt.Errorf("[invokeWasm] pop method error: %v", err)
+	}
+	if len(method) > METHOD_LENGTH_LIMIT {
+		return nil, fmt.Errorf("[invokeWasm] method too long, over max length %d limit", METHOD_LENGTH_LIMIT)
+	}
+	var args []byte
+	switch item := vm.PopStackItem(this.Engine).(type) {
+	case *types.ByteArray:
+		args, _ = item.GetByteArray()
+	default:
+		buf := new(bytes.Buffer)
+		if err := BuildParamToNative(buf, item); err != nil {
+			return nil, fmt.Errorf("[invokeWasm] build args error: %v", err)
+		}
+		args = buf.Bytes()
+	}
+
+	service, err := this.ContextRef.NewWasmExecuteEngine(code, string(method), args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := service.Invoke()
+	if err != nil {
+		return nil, err
+	}
+	res, _ := result.([]byte)
+	if res == nil {
+		res = []byte{}
+	}
+	return res, nil
+}
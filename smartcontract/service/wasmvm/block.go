@@ -88,6 +88,9 @@ func (this *WasmVmService) blockGetTransactionByHash(engine *exec.ExecutionEngin
 		return false, err
 	}
 	tx, _, err := this.Store.GetTransaction(thash)
+	if err != nil {
+		return false, err
+	}
 	txbytes := tx.ToArray()
 	idx, err := vm.SetPointerMemory(txbytes)
 	if err != nil {
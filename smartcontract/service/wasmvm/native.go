@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package wasmvm
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/errors"
+	"github.com/ontio/ontology/smartcontract/service/native"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/smartcontract/service/neovm"
+	"github.com/ontio/ontology/smartcontract/states"
+	"github.com/ontio/ontology/vm/wasmvm/exec"
+	"github.com/ontio/ontology/vm/wasmvm/util"
+)
+
+// callNative
+// need 3 parameters
+//0: native contract address
+//1: method name
+//2: serialized args
+func (this *WasmVmService) callNative(engine *exec.ExecutionEngine) (bool, error) {
+	vm := engine.GetVM()
+	envCall := vm.GetEnvCall()
+	params := envCall.GetParams()
+	if len(params) != 3 {
+		return false, errors.NewErr("[callNative] parameter count error")
+	}
+	addrBytes, err := vm.GetPointerMemory(params[0])
+	if err != nil {
+		return false, err
+	}
+	addr, err := parseAddress(addrBytes)
+	if err != nil {
+		return false, fmt.Errorf("[callNative] address invalid: %s", err)
+	}
+	if this.Tx.TxType == types.ShardCall && addr != utils.OngContractAddress && addr != utils.ShardAssetAddress &&
+		addr != utils.ShardMgmtContractAddress {
+		return false, fmt.Errorf("[callNative] native contract address %x cannot be invoked by shardcall", addr)
+	}
+	method, err := vm.GetPointerMemory(params[1])
+	if err != nil {
+		return false, err
+	}
+	if len(method) > neovm.METHOD_LENGTH_LIMIT {
+		return false, fmt.Errorf("[callNative] method too long, over max length %d limit", neovm.METHOD_LENGTH_LIMIT)
+	}
+	args, err := vm.GetPointerMemory(params[2])
+	if err != nil {
+		return false, err
+	}
+
+	service := &native.NativeService{
+		CacheDB: this.CacheDB,
+		InvokeParam: states.ContractInvokeParam{
+			Address: addr,
+			Method:  util.TrimBuffToString(method),
+			Args:    args,
+		},
+		Tx:            this.Tx,
+		ShardID:       this.ShardID,
+		ShardTxState:  this.ShardTxState,
+		LockedAddress: this.LockedAddress,
+		Height:        this.Height,
+		Time:          this.Time,
+		BlockHash:     this.BlockHash,
+		ContextRef:    this.ContextRef,
+		ServiceMap:    make(map[string]native.Handler),
+	}
+	result, err := service.Invoke()
+	if err != nil {
+		return false, err
+	}
+	res, ok := result.([]byte)
+	if !ok {
+		return false, fmt.Errorf("[callNative] unsupported native result type %T", result)
+	}
+
+	idx, err := vm.SetPointerMemory(res)
+	if err != nil {
+		return false, err
+	}
+	vm.RestoreCtx()
+	if envCall.GetReturns() {
+		vm.PushResult(uint64(idx))
+	}
+	return true, nil
+}
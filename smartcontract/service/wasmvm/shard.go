@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package wasmvm
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/errors"
+	"github.com/ontio/ontology/vm/wasmvm/exec"
+	"github.com/ontio/ontology/vm/wasmvm/util"
+)
+
+// shardGetShardId push shardId to vm stack
+func (this *WasmVmService) shardGetShardId(engine *exec.ExecutionEngine) (bool, error) {
+	vm := engine.GetVM()
+	vm.RestoreCtx()
+	vm.PushResult(this.ShardID.ToUint64())
+	return true, nil
+}
+
+// notifyRemoteShard
+// need 5 parameters
+//0: target shard id
+//1: dest contract address
+//2: fee
+//3: method name
+//4: args
+func (this *WasmVmService) notifyRemoteShard(engine *exec.ExecutionEngine) (bool, error) {
+	vm := engine.GetVM()
+	envCall := vm.GetEnvCall()
+	params := envCall.GetParams()
+	if len(params) != 5 {
+		return false, errors.NewErr("[notifyRemoteShard] parameter count error")
+	}
+	target, err := common.NewShardID(params[0])
+	if err != nil {
+		return false, fmt.Errorf("[notifyRemoteShard] parse shardId failed, err: %s", err)
+	}
+	addr, err := vm.GetPointerMemory(params[1])
+	if err != nil {
+		return false, err
+	}
+	contract, err := parseAddress(addr)
+	if err != nil {
+		return false, fmt.Errorf("[notifyRemoteShard] parse dest contract failed, err: %s", err)
+	}
+	fee := params[2]
+	if fee == 0 {
+		return false, fmt.Errorf("[notifyRemoteShard] fee must larger than 0")
+	}
+	method, err := vm.GetPointerMemory(params[3])
+	if err != nil {
+		return false, err
+	}
+	args, err := vm.GetPointerMemory(params[4])
+	if err != nil {
+		return false, err
+	}
+	this.ContextRef.NotifyRemoteShard(target, contract, fee, util.TrimBuffToString(method), args)
+	vm.RestoreCtx()
+	if envCall.GetReturns() {
+		vm.PushResult(uint64(1))
+	}
+	return true, nil
+}
+
+// invokeRemoteShard
+// need 4 parameters
+//0: target shard id
+//1: dest contract address
+//2: method name
+//3: args
+// the first execution yields the transaction, the result is returned when it is re-executed with the response
+func (this *WasmVmService) invokeRemoteShard(engine *exec.ExecutionEngine) (bool, error) {
+	vm := engine.GetVM()
+	envCall := vm.GetEnvCall()
+	params := envCall.GetParams()
+	if len(params) != 4 {
+		return false, errors.NewErr("[invokeRemoteShard] parameter count error")
+	}
+	target, err := common.NewShardID(params[0])
+	if err != nil {
+		return false, fmt.Errorf("[invokeRemoteShard] parse shardId failed, err: %s", err)
+	}
+	addr, err := vm.GetPointerMemory(params[1])
+	if err != nil {
+		return false, err
+	}
+	contract, err := parseAddress(addr)
+	if err != nil {
+		return false, fmt.Errorf("[invokeRemoteShard] parse dest contract failed, err: %s", err)
+	}
+	method, err := vm.GetPointerMemory(params[2])
+	if err != nil {
+		return false, err
+	}
+	args, err := vm.GetPointerMemory(params[3])
+	if err != nil {
+		return false, err
+	}
+	result, err := this.ContextRef.InvokeRemoteShard(target, contract, util.TrimBuffToString(method), args)
+	if err != nil {
+		return false, err
+	}
+	idx, err := vm.SetPointerMemory(result)
+	if err != nil {
+		return false, err
+	}
+	vm.RestoreCtx()
+	if envCall.GetReturns() {
+		vm.PushResult(uint64(idx))
+	}
+	return true, nil
+}
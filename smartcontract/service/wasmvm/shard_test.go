@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package wasmvm_test
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/chainmgr/xshard_state"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/utils"
+	"github.com/ontio/ontology/core/xshard_types"
+	"github.com/ontio/ontology/smartcontract"
+	"github.com/ontio/ontology/smartcontract/service/neovm"
+	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// remoteShardContract build a wasm contract, its exported invoke(method, args) returns
+// ONT_Shard_InvokeRemoteShard(target, args, method, args), args is used as the dest contract address too
+func remoteShardContract(target byte) []byte {
+	section := func(id byte, payload ...byte) []byte {
+		return append([]byte{id, byte(len(payload))}, payload...)
+	}
+	api := "ONT_Shard_InvokeRemoteShard"
+	imports := append([]byte{0x01, 0x03, 'e', 'n', 'v', byte(len(api))}, api...)
+	imports = append(imports, 0x00, 0x01) // func, type 1
+	body := []byte{
+		0x00,         // no locals
+		0x42, target, // i64.const target
+		0x20, 0x01, // get_local 1
+		0x20, 0x00, // get_local 0
+		0x20, 0x01, // get_local 1
+		0x10, 0x00, // call 0
+		0x0b, // end
+	}
+
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// type 0: (i32, i32) -> i32, type 1: (i64, i32, i32, i32) -> i32
+	code = append(code, section(0x01, 0x02, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f,
+		0x60, 0x04, 0x7e, 0x7f, 0x7f, 0x7f, 0x01, 0x7f)...)
+	code = append(code, section(0x02, imports...)...)
+	code = append(code, section(0x03, 0x01, 0x00)...)
+	code = append(code, section(0x07, 0x01, 0x06, 'i', 'n', 'v', 'o', 'k', 'e', 0x00, 0x01)...)
+	code = append(code, section(0x0a, append([]byte{0x01, byte(len(body))}, body...)...)...)
+	return code
+}
+
+func newShardContext(t *testing.T, code []byte, dest common.Address, txState *xshard_state.TxState) *smartcontract.SmartContract {
+	memback, err := leveldbstore.NewMemLevelDBStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := storage.NewCacheDB(overlaydb.NewOverlayDB(memback))
+	meta := payload.NewDefaultMetaData()
+	meta.Contract = common.AddressFromVmCode(code)
+	meta.AllShard = true
+	meta.InvokedContract = []common.Address{dest}
+	cache.PutMetaData(meta)
+
+	tx, err := utils.NewInvokeTransaction(code).IntoImmutable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &smartcontract.SmartContract{
+		Config: &smartcontract.Config{
+			ShardID: common.NewShardIDUnchecked(1),
+			Height:  1,
+			Tx:      tx,
+		},
+		CacheDB:      cache,
+		ShardTxState: txState,
+		Gas:          neovm.MIN_TRANSACTION_GAS * 100,
+	}
+}
+
+func TestWasmInvokeRemoteShardYield(t *testing.T) {
+	code := remoteShardContract(2)
+	dest := common.AddressFromVmCode([]byte("dest contract"))
+	txState := xshard_state.CreateTxState(xshard_types.ShardTxID("wasm-remote-invoke"))
+
+	engine, err := newShardContext(t, code, dest, txState).NewWasmExecuteEngine(code, "remoteAdd", dest[:])
+	assert.Nil(t, err)
+	_, err = engine.Invoke()
+	assert.NotNil(t, err)
+	assert.Equal(t, xshard_state.ExecYielded, txState.ExecState)
+	req := txState.PendingOutReq
+	if !assert.NotNil(t, req) {
+		return
+	}
+	assert.Equal(t, common.NewShardIDUnchecked(2), req.TargetShardID)
+	assert.Equal(t, dest, req.Contract)
+	assert.Equal(t, "remoteAdd", req.Method)
+	assert.Equal(t, dest[:], req.Args)
+
+	// resume the transaction with the response of remote shard
+	txState.OutReqResp = append(txState.OutReqResp, &xshard_state.XShardTxReqResp{
+		Req:  req,
+		Resp: &xshard_types.XShardTxRsp{Result: []byte("remote result")},
+	})
+	txState.PendingOutReq = nil
+	txState.NextReqID = 0
+	txState.ExecState = xshard_state.ExecNone
+
+	engine, err = newShardContext(t, code, dest, txState).NewWasmExecuteEngine(code, "remoteAdd", dest[:])
+	assert.Nil(t, err)
+	res, err := engine.Invoke()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("remote result"), res)
+	assert.Equal(t, xshard_state.ExecNone, txState.ExecState)
+	assert.Nil(t, txState.PendingOutReq)
+}
+
+func TestWasmInvokeRemoteShardTrap(t *testing.T) {
+	// invoking root shard is refused by host, the error traps wasm execution
+	code := remoteShardContract(0)
+	dest := common.AddressFromVmCode([]byte("dest contract"))
+	txState := xshard_state.CreateTxState(xshard_types.ShardTxID("wasm-remote-trap"))
+
+	engine, err := newShardContext(t, code, dest, txState).NewWasmExecuteEngine(code, "remoteAdd", dest[:])
+	assert.Nil(t, err)
+	_, err = engine.Invoke()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "root cannot participate in")
+	}
+	assert.Equal(t, xshard_state.ExecNone, txState.ExecState)
+	assert.Nil(t, txState.PendingOutReq)
+	assert.Equal(t, 0, len(txState.Shards))
+}
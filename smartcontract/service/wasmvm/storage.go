@@ -22,6 +22,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/states"
 	"github.com/ontio/ontology/errors"
+	"github.com/ontio/ontology/smartcontract/service/neovm"
 	"github.com/ontio/ontology/vm/wasmvm/exec"
 	"github.com/ontio/ontology/vm/wasmvm/memory"
 	"github.com/ontio/ontology/vm/wasmvm/util"
@@ -48,6 +49,13 @@ func (this *WasmVmService) putstore(engine *exec.ExecutionEngine) (bool, error)
 	if err != nil {
 		return false, err
 	}
+	putCost, ok := this.GasTable[neovm.STORAGE_PUT_NAME]
+	if !ok {
+		return false, errors.NewErr("[putstore] get STORAGE_PUT_NAME gas failed")
+	}
+	if err := this.useGas(uint64((len(key)+len(value)-1)/1024+1) * putCost); err != nil {
+		return false, err
+	}
 	k, err := serializeStorageKey(vm.ContractAddress, []byte(util.TrimBuffToString(key)))
 	if err != nil {
 		return false, err
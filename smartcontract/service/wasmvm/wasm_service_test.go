@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package wasmvm
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/vm/wasmvm/wasm"
+)
+
+func TestWasmServiceStateMachine(t *testing.T) {
+	service := &WasmVmService{}
+	sm := service.newStateMachine()
+	for _, name := range []string{"ONT_CallNative", "ONT_Storage_Put", "ONT_Storage_Get", "ONT_Runtime_CheckWitness",
+		"ONT_Shard_GetShardId", "ONT_Shard_NotifyRemoteShard", "ONT_Shard_InvokeRemoteShard"} {
+		if !sm.Exists(name) {
+			t.Errorf("wasm service should has %s service", name)
+		}
+	}
+}
+
+func TestIsWasmCode(t *testing.T) {
+	if !wasm.IsWasmCode([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}) {
+		t.Error("wasm magic should be recognized")
+	}
+	if wasm.IsWasmCode([]byte{0x00, 0xc1, 0x04}) {
+		t.Error("neovm code should not be recognized as wasm")
+	}
+}
@@ -32,8 +32,10 @@ import (
 	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/service/native"
 	"github.com/ontio/ontology/smartcontract/service/neovm"
+	"github.com/ontio/ontology/smartcontract/service/wasmvm"
 	"github.com/ontio/ontology/smartcontract/storage"
 	vm "github.com/ontio/ontology/vm/neovm"
+	"github.com/ontio/ontology/vm/wasmvm/wasm"
 )
 
 const (
@@ -50,6 +52,7 @@ type SmartContract struct {
 	Notifications []*event.NotifyEventInfo // all execute smart contract event notify info
 	GasTable      map[string]uint64
 	LockedAddress map[common.Address]struct{}
+	LockedKeys    map[string]struct{}
 	Gas           uint64
 	ExecStep      int
 	PreExec       bool
@@ -142,12 +145,16 @@ func (this *SmartContract) NewExecuteEngine(code []byte) (context.Engine, error)
 	if !this.checkContexts() {
 		return nil, fmt.Errorf("%s", "engine over max limit!")
 	}
+	if wasm.IsWasmCode(code) {
+		return nil, fmt.Errorf("%s", "wasm code can not be executed by neovm engine!")
+	}
 	service := &neovm.NeoVmService{
 		Store:         this.Store,
 		CacheDB:       this.CacheDB,
 		ContextRef:    this,
 		GasTable:      this.GasTable,
 		LockedAddress: this.LockedAddress,
+		LockedKeys:    this.LockedKeys,
 		Code:          code,
 		Tx:            this.Config.Tx,
 		ShardID:       this.Config.ShardID,
@@ -161,6 +168,31 @@ func (this *SmartContract) NewExecuteEngine(code []byte) (context.Engine, error)
 	return service, nil
 }
 
+// NewWasmExecuteEngine create a wasm engine to invoke method of wasm contract code
+func (this *SmartContract) NewWasmExecuteEngine(code []byte, method string, args []byte) (context.Engine, error) {
+	if !this.checkContexts() {
+		return nil, fmt.Errorf("%s", "engine over max limit!")
+	}
+	service := &wasmvm.WasmVmService{
+		Store:         this.Store,
+		CacheDB:       this.CacheDB,
+		ContextRef:    this,
+		GasTable:      this.GasTable,
+		LockedAddress: this.LockedAddress,
+		Code:          code,
+		Method:        method,
+		Args:          args,
+		Tx:            this.Config.Tx,
+		ShardID:       this.Config.ShardID,
+		ShardTxState:  this.ShardTxState,
+		Time:          this.Config.Time,
+		Height:        this.Config.Height,
+		BlockHash:     this.Config.BlockHash,
+		PreExec:       this.PreExec,
+	}
+	return service, nil
+}
+
 func (this *SmartContract) NewNativeService() (*native.NativeService, error) {
 	if !this.checkContexts() {
 		return nil, fmt.Errorf("%s", "engine over max limit!")
@@ -170,6 +202,7 @@ func (this *SmartContract) NewNativeService() (*native.NativeService, error) {
 		ContextRef:    this,
 		ShardTxState:  this.ShardTxState,
 		LockedAddress: this.LockedAddress,
+		LockedKeys:    this.LockedKeys,
 		Tx:            this.Config.Tx,
 		ShardID:       this.Config.ShardID,
 		Time:          this.Config.Time,
@@ -20,9 +20,11 @@ package storage
 
 import (
 	"bytes"
+	"io"
 	"sort"
 
 	comm "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/core/chainmgr/xshard_state"
 	"github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/store/overlaydb"
@@ -32,11 +34,22 @@ import (
 // CacheDB is smart contract execute cache, it contain transaction cache and block cache
 // When smart contract execute finish, need to commit transaction cache to block cache
 type XShardDB struct {
-	cacheDB *CacheDB
+	cacheDB   *CacheDB
+	txTimeout uint32
 }
 
 func NewXShardDB(store *overlaydb.OverlayDB) *XShardDB {
-	return &XShardDB{cacheDB: NewCacheDB(store)}
+	return &XShardDB{cacheDB: NewCacheDB(store), txTimeout: config.DEFAULT_XSHARD_TX_TIMEOUT}
+}
+
+// SetTxTimeout sets the number of blocks a cross shard tx may stay pending before it is aborted,
+// the value is read from global params of the ledger before executing each block
+func (self *XShardDB) SetTxTimeout(timeout uint32) {
+	self.txTimeout = timeout
+}
+
+func (self *XShardDB) TxTimeout() uint32 {
+	return self.txTimeout
 }
 
 func (self *XShardDB) Reset() {
@@ -121,3 +134,61 @@ func (self *XShardDB) SetXShardMsgInBlock(blockHeight uint32, msgs []xshard_type
 	keys.WriteUint32(blockHeight)
 	self.cacheDB.put(common.XSHARD_KEY_SHARDS_IN_BLOCK, keys.Bytes(), shards.Bytes())
 }
+
+// AddXShardTxDeadline records that the cross shard tx should be aborted if it is still pending at block deadline
+func (self *XShardDB) AddXShardTxDeadline(deadline uint32, id xshard_types.ShardTxID) error {
+	ids, err := self.GetXShardTxsByDeadline(deadline)
+	if err != nil {
+		return err
+	}
+	ids = append(ids, id)
+
+	keys := comm.NewZeroCopySink(4)
+	keys.WriteUint32(deadline)
+	sink := comm.NewZeroCopySink(0)
+	sink.WriteVarUint(uint64(len(ids)))
+	for _, txId := range ids {
+		sink.WriteString(string(txId))
+	}
+	self.cacheDB.put(common.XSHARD_KEY_TX_DEADLINE, keys.Bytes(), sink.Bytes())
+	return nil
+}
+
+func (self *XShardDB) GetXShardTxsByDeadline(deadline uint32) ([]xshard_types.ShardTxID, error) {
+	keys := comm.NewZeroCopySink(4)
+	keys.WriteUint32(deadline)
+	val, err := self.cacheDB.get(common.XSHARD_KEY_TX_DEADLINE, keys.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+
+	source := comm.NewZeroCopySource(val)
+	num, _, irr, eof := source.NextVarUint()
+	if irr {
+		return nil, comm.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	ids := make([]xshard_types.ShardTxID, 0, num)
+	for i := uint64(0); i < num; i++ {
+		id, _, irr, eof := source.NextString()
+		if irr {
+			return nil, comm.ErrIrregularData
+		}
+		if eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+		ids = append(ids, xshard_types.ShardTxID(id))
+	}
+	return ids, nil
+}
+
+func (self *XShardDB) DeleteXShardTxsByDeadline(deadline uint32) {
+	keys := comm.NewZeroCopySink(4)
+	keys.WriteUint32(deadline)
+	self.cacheDB.delete(common.XSHARD_KEY_TX_DEADLINE, keys.Bytes())
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package storage
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/xshard_types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXShardTxDeadline(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	xshardDB := NewXShardDB(overlaydb.NewOverlayDB(memback))
+
+	ids, err := xshardDB.GetXShardTxsByDeadline(100)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(ids))
+
+	assert.Nil(t, xshardDB.AddXShardTxDeadline(100, xshard_types.ShardTxID("tx1")))
+	assert.Nil(t, xshardDB.AddXShardTxDeadline(100, xshard_types.ShardTxID("tx2")))
+	assert.Nil(t, xshardDB.AddXShardTxDeadline(101, xshard_types.ShardTxID("tx3")))
+	ids, err = xshardDB.GetXShardTxsByDeadline(100)
+	assert.Nil(t, err)
+	assert.Equal(t, []xshard_types.ShardTxID{"tx1", "tx2"}, ids)
+
+	xshardDB.DeleteXShardTxsByDeadline(100)
+	ids, err = xshardDB.GetXShardTxsByDeadline(100)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(ids))
+	ids, err = xshardDB.GetXShardTxsByDeadline(101)
+	assert.Nil(t, err)
+	assert.Equal(t, []xshard_types.ShardTxID{"tx3"}, ids)
+}
@@ -19,6 +19,9 @@
 package TestCommon
 
 import (
+	"sync"
+	"time"
+
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/p2pserver/message/types"
 )
@@ -26,23 +29,58 @@ import (
 var MockNet *MockNetwork
 
 type MockNetwork struct {
-	peers map[uint64]*MockPeer
+	lock   sync.RWMutex
+	peers  map[uint64]*MockPeer
+	policy *NetPolicy
+	clock  *SimClock
 }
 
 func init() {
-	MockNet = &MockNetwork{
+	MockNet = NewMockNetwork()
+}
+
+func NewMockNetwork() *MockNetwork {
+	return &MockNetwork{
 		peers: make(map[uint64]*MockPeer),
 	}
 }
 
+// SetPolicy: inject faults into network, nil policy delivers all msgs immediately
+func (net *MockNetwork) SetPolicy(policy *NetPolicy) {
+	net.lock.Lock()
+	defer net.lock.Unlock()
+	net.policy = policy
+}
+
+// SetClock: delay msgs with virtual clock, nil clock delays msgs with system timers
+func (net *MockNetwork) SetClock(clock *SimClock) {
+	net.lock.Lock()
+	defer net.lock.Unlock()
+	net.clock = clock
+}
+
 func (net *MockNetwork) GetPeer(id uint64) *MockPeer {
+	net.lock.RLock()
+	defer net.lock.RUnlock()
 	return net.peers[id]
 }
 
+func (net *MockNetwork) getPeers() []*MockPeer {
+	net.lock.RLock()
+	defer net.lock.RUnlock()
+	peers := make([]*MockPeer, 0, len(net.peers))
+	for _, peer := range net.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
 func (net *MockNetwork) RegisterPeer(newPeer *MockPeer) {
 	peerID := newPeer.Local.GetID()
+	net.lock.Lock()
 	net.peers[peerID] = newPeer
-	for _, peer := range net.peers {
+	net.lock.Unlock()
+	for _, peer := range net.getPeers() {
 		if peer.Local.GetID() != peerID {
 			peer.Connected(peerID)
 			newPeer.Connected(peer.Local.GetID())
@@ -51,20 +89,46 @@ func (net *MockNetwork) RegisterPeer(newPeer *MockPeer) {
 }
 
 func (net *MockNetwork) Broadcast(fromPeerID uint64, msg types.Message) {
-	if len(net.peers) < 2 {
+	peers := net.getPeers()
+	if len(peers) < 2 {
 		log.Errorf("less than two peers in network")
 	}
-	for _, peer := range net.peers {
+	for _, peer := range peers {
 		if peer.Local.GetID() != fromPeerID {
-			peer.Receive(fromPeerID, msg)
+			net.deliver(fromPeerID, peer, msg)
 		}
 	}
 }
 
 func (net *MockNetwork) Send(from, to uint64, msg types.Message) {
-	for _, peer := range net.peers {
-		if peer.Local.GetID() == to {
+	if peer := net.GetPeer(to); peer != nil {
+		net.deliver(from, peer, msg)
+	}
+}
+
+func (net *MockNetwork) deliver(from uint64, peer *MockPeer, msg types.Message) {
+	net.lock.RLock()
+	policy, clock := net.policy, net.clock
+	net.lock.RUnlock()
+	if policy == nil {
+		peer.Receive(from, msg)
+		return
+	}
+	delay, ok := policy.route(from, peer.Local.GetID(), msg)
+	if !ok {
+		return
+	}
+	if delay == 0 {
+		peer.Receive(from, msg)
+		return
+	}
+	if clock != nil {
+		clock.AfterFunc(delay, func() {
 			peer.Receive(from, msg)
-		}
+		})
+		return
 	}
+	time.AfterFunc(delay, func() {
+		peer.Receive(from, msg)
+	})
 }
@@ -28,6 +28,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/chainmgr"
+	"github.com/ontio/ontology/p2pserver/actor/server"
 	"github.com/ontio/ontology/p2pserver/message/msg_pack"
 	"github.com/ontio/ontology/p2pserver/message/types"
 )
@@ -76,6 +77,9 @@ func (this *MockP2PActor) Receive(ctx actor.Context) {
 		if err != nil {
 			log.Errorf("err sending msg")
 		}
+	case *server.TransmitConsensusMsgReq:
+		req := msg.(*server.TransmitConsensusMsgReq)
+		this.Peer.Net.Send(this.Peer.Local.GetID(), req.Target, req.Msg)
 	default:
 		log.Errorf("mock p2p xmit msg %v, type %v", msg, reflect.TypeOf(msg))
 	}
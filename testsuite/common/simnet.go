@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TestCommon
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/consensus/vbft"
+	"github.com/ontio/ontology/p2pserver/message/types"
+)
+
+// SimClock: virtual clock driving timers of simulated servers and network, only advanced by tests
+type SimClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers map[uint64]*simTimer
+}
+
+type simTimer struct {
+	clock *SimClock
+	id    uint64
+	when  time.Time
+	f     func()
+}
+
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{
+		now:    start,
+		timers: make(map[uint64]*simTimer),
+	}
+}
+
+func (clock *SimClock) Now() time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	return clock.now
+}
+
+func (clock *SimClock) AfterFunc(d time.Duration, f func()) vbft.Timer {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	t := &simTimer{clock: clock, f: f}
+	clock.scheduleLocked(t, d)
+	return t
+}
+
+func (clock *SimClock) scheduleLocked(t *simTimer, d time.Duration) {
+	clock.seq++
+	t.id = clock.seq
+	t.when = clock.now.Add(d)
+	clock.timers[t.id] = t
+}
+
+// Advance: move clock forward, timers expired are fired in order of expiration
+func (clock *SimClock) Advance(d time.Duration) {
+	clock.lock.Lock()
+	clock.now = clock.now.Add(d)
+	expired := make([]*simTimer, 0)
+	for id, t := range clock.timers {
+		if !t.when.After(clock.now) {
+			expired = append(expired, t)
+			delete(clock.timers, id)
+		}
+	}
+	clock.lock.Unlock()
+
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].when.Equal(expired[j].when) {
+			return expired[i].id < expired[j].id
+		}
+		return expired[i].when.Before(expired[j].when)
+	})
+	for _, t := range expired {
+		// same as time.AfterFunc, callback runs in its own goroutine
+		go t.f()
+	}
+}
+
+// WithSkew: view of clock shifted by skew, timers are shared with clock
+func (clock *SimClock) WithSkew(skew time.Duration) vbft.Clock {
+	return &skewedClock{clock: clock, skew: skew}
+}
+
+func (t *simTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	if _, present := t.clock.timers[t.id]; !present {
+		return false
+	}
+	delete(t.clock.timers, t.id)
+	return true
+}
+
+func (t *simTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	_, present := t.clock.timers[t.id]
+	delete(t.clock.timers, t.id)
+	t.clock.scheduleLocked(t, d)
+	return present
+}
+
+type skewedClock struct {
+	clock *SimClock
+	skew  time.Duration
+}
+
+func (c *skewedClock) Now() time.Time {
+	return c.clock.Now().Add(c.skew)
+}
+
+func (c *skewedClock) AfterFunc(d time.Duration, f func()) vbft.Timer {
+	return c.clock.AfterFunc(d, f)
+}
+
+// MsgFilter: return false to drop msg from peer to peer
+type MsgFilter func(from, to uint64, msg types.Message) bool
+
+// NetPolicy: faults injected into mock network, including latency, msg loss and partitions.
+// All random decisions are made with seeded rand, so that failed runs can be replayed.
+type NetPolicy struct {
+	lock       sync.Mutex
+	rand       *rand.Rand
+	minDelay   time.Duration
+	maxDelay   time.Duration
+	dropRate   float64
+	partitions map[uint64]int // peer id to partition group
+	filters    []MsgFilter
+}
+
+func NewNetPolicy(seed int64) *NetPolicy {
+	return &NetPolicy{
+		rand:       rand.New(rand.NewSource(seed)),
+		partitions: make(map[uint64]int),
+	}
+}
+
+// SetLatency: delay of each msg is randomly chosen in [min, max]
+func (policy *NetPolicy) SetLatency(min, max time.Duration) {
+	policy.lock.Lock()
+	defer policy.lock.Unlock()
+	if max < min {
+		max = min
+	}
+	policy.minDelay = min
+	policy.maxDelay = max
+}
+
+// SetDropRate: probability of losing a msg, in [0, 1]
+func (policy *NetPolicy) SetDropRate(rate float64) {
+	policy.lock.Lock()
+	defer policy.lock.Unlock()
+	policy.dropRate = rate
+}
+
+// Partition: split network into groups, peers in different groups can not reach each other.
+// Peers not in any group are in the same group.
+func (policy *NetPolicy) Partition(groups ...[]uint64) {
+	policy.lock.Lock()
+	defer policy.lock.Unlock()
+	policy.partitions = make(map[uint64]int)
+	for i, group := range groups {
+		for _, id := range group {
+			policy.partitions[id] = i + 1
+		}
+	}
+}
+
+// Heal: remove all partitions
+func (policy *NetPolicy) Heal() {
+	policy.Partition()
+}
+
+func (policy *NetPolicy) AddFilter(filter MsgFilter) {
+	policy.lock.Lock()
+	defer policy.lock.Unlock()
+	policy.filters = append(policy.filters, filter)
+}
+
+// route: get delay of msg from peer to peer, returns false if msg is dropped
+func (policy *NetPolicy) route(from, to uint64, msg types.Message) (time.Duration, bool) {
+	policy.lock.Lock()
+	defer policy.lock.Unlock()
+
+	if policy.partitions[from] != policy.partitions[to] {
+		return 0, false
+	}
+	for _, filter := range policy.filters {
+		if !filter(from, to, msg) {
+			return 0, false
+		}
+	}
+	if policy.dropRate > 0 && policy.rand.Float64() < policy.dropRate {
+		return 0, false
+	}
+	delay := policy.minDelay
+	if policy.maxDelay > policy.minDelay {
+		delay += time.Duration(policy.rand.Int63n(int64(policy.maxDelay - policy.minDelay + 1)))
+	}
+	return delay, true
+}
@@ -23,13 +23,17 @@ import (
 	bcomm "github.com/ontio/ontology/http/base/common"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/account"
 	cutils "github.com/ontio/ontology/cmd/utils"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/core/chainmgr"
+	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/xshard_types"
 )
 
 func CreateAdminTx(t *testing.T, shard common.ShardID, gasPrice uint64, addr common.Address, method string,
@@ -96,6 +100,37 @@ func CreateNativeTx(t *testing.T, user string, gasPrice uint64, addr common.Addr
 	return tx
 }
 
+// CreateShardCallTx builds shard call tx from child shard to root shard, without relaying of chain manager
+func CreateShardCallTx(t *testing.T, fromShard common.ShardID, contract common.Address, method string,
+	args []byte) *types.Transaction {
+	rootShardId := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	nonce := uint32(time.Now().UnixNano())
+	msg := &xshard_types.XShardNotify{
+		ShardMsgHeader: xshard_types.ShardMsgHeader{
+			ShardTxID:     xshard_types.ShardTxID(fmt.Sprintf("%s-%d", method, nonce)),
+			SourceShardID: fromShard,
+			TargetShardID: rootShardId,
+		},
+		Contract: contract,
+		Fee:      math.MaxUint32,
+		Method:   method,
+		Args:     args,
+	}
+	mutable := &types.MutableTransaction{
+		Version: common.CURR_TX_VERSION,
+		TxType:  types.ShardCall,
+		Nonce:   nonce,
+		ShardID: rootShardId,
+		Payload: &payload.ShardCall{Msgs: []xshard_types.CommonShardMsg{msg}},
+		Sigs:    make([]types.Sig, 0),
+	}
+	tx, err := mutable.IntoImmutable()
+	if err != nil {
+		t.Fatalf("build shard call tx: %s", err)
+	}
+	return tx
+}
+
 func CreateNeoInvokeTx(t *testing.T, user string, addr common.Address, params []interface{}) *types.Transaction {
 	return nil
 }
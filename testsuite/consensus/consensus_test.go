@@ -43,7 +43,16 @@ func init() {
 }
 
 func StartMockerConsensus(t *testing.T, shardID common.ShardID, name string, srcLgr *ledger.Ledger) consensus.ConsensusService {
-	shardName := chainmgr.GetShardName(shardID)
+	service, _, _ := startMockerConsensusOnNet(t, TestCommon.MockNet, nil, shardID, shardID, name, srcLgr)
+	return service
+}
+
+// startMockerConsensusOnNet: start vbft server on net, timers of server are driven by clock if not nil,
+// server runs with account of accShard, shard peers may be accounts of parent shard
+func startMockerConsensusOnNet(t *testing.T, net *TestCommon.MockNetwork, clock vbft.Clock, shardID,
+	accShard common.ShardID, name string, srcLgr *ledger.Ledger) (consensus.ConsensusService, *TestCommon.MockPeer,
+	*ledger.Ledger) {
+	shardName := chainmgr.GetShardName(accShard)
 
 	acc := TestCommon.GetAccount(shardName + "_" + name)
 	if acc == nil {
@@ -55,6 +64,7 @@ func StartMockerConsensus(t *testing.T, shardID common.ShardID, name string, src
 
 	txPool := TestCommon.NewTxnPool(t, name, shardID)
 	peer := TestCommon.NewPeer(lgr)
+	peer.Net = net
 	peer.Register()
 	p2pActor := TestCommon.NewP2PActor(t, name, peer)
 
@@ -62,12 +72,19 @@ func StartMockerConsensus(t *testing.T, shardID common.ShardID, name string, src
 	p2pActor.Start(t)
 	peer.Start()
 
-	service, err := consensus.NewConsensusService(consensus.CONSENSUS_VBFT, shardID, acc, txPool.GetPID(t), lgr, p2pActor.GetPID(t))
+	var service consensus.ConsensusService
+	var err error
+	if clock != nil {
+		service, err = vbft.NewVbftServerWithClock(shardID, acc, txPool.GetPID(t), lgr, p2pActor.GetPID(t), clock)
+	} else {
+		service, err = consensus.NewConsensusService(consensus.CONSENSUS_VBFT, shardID, acc, txPool.GetPID(t), lgr,
+			p2pActor.GetPID(t))
+	}
 	if err != nil {
 		t.Fatalf("start consensus: %s", err)
 	}
 	peer.SetConsensusPid(t, service.GetPID())
-	return service
+	return service, peer, lgr
 }
 
 func StartMokerSoloConsensus(t *testing.T, shardID common.ShardID, name string, srcLgr *ledger.Ledger) (consensus.ConsensusService, *ledger.Ledger) {
@@ -0,0 +1,90 @@
+// Copyright (C) 2019 The ontology Authors
+// This file is part of The ontology library.
+//
+// The ontology is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The ontology is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build byzantine
+
+package TestConsensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/consensus/vbft"
+	"github.com/ontio/ontology/core/chainmgr"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/smartcontract/service/native/governance"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/testsuite/common"
+)
+
+// Byzantine simulations need vbft servers built with byzantine tag,
+// run with: go test -tags byzantine ./testsuite/consensus/
+
+// isBlacklisted: peer of node has been put in black list of governance contract on ledger
+func isBlacklisted(lgr *ledger.Ledger, node *simNode) bool {
+	shardName := chainmgr.GetShardName(lgr.ShardID)
+	acc := TestCommon.GetAccount(shardName + "_" + node.name)
+	key := append([]byte(governance.BLACK_LIST), keypair.SerializePublicKey(acc.PublicKey)...)
+	value, err := lgr.GetStorageItem(nutils.GovernanceContractAddress, key)
+	return err == nil && len(value) != 0
+}
+
+// waitBlacklisted: faulty evidence of node is committed by all honest nodes in timeout of virtual time
+func (cluster *simCluster) waitBlacklisted(faulty int, timeout time.Duration, honest ...int) {
+	reported := cluster.run(timeout, func() bool {
+		for _, i := range honest {
+			if !isBlacklisted(cluster.nodes[i].lgr, cluster.nodes[faulty]) {
+				return false
+			}
+		}
+		return true
+	})
+	if !reported {
+		cluster.t.Fatalf("seed %d: faulty node %d not reported in %s, heights: %v", cluster.seed, faulty, timeout,
+			cluster.heights())
+	}
+}
+
+func Test_VbftSim_DoubleProposal(t *testing.T) {
+	cluster := newSimCluster(t, 7, 6)
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	cluster.nodes[0].server.SetByzantineBehavior(vbft.BYZANTINE_DOUBLE_PROPOSAL)
+	cluster.nodes[1].server.SetByzantineBehavior(vbft.BYZANTINE_DOUBLE_PROPOSAL)
+	cluster.start()
+
+	cluster.waitHeight(8, 120*time.Second)
+	cluster.checkSafety()
+
+	// evidences of double proposals are gossiped, packed in block and executed by governance contract
+	cluster.waitBlacklisted(0, 120*time.Second, 2, 3, 4, 5, 6)
+	cluster.waitBlacklisted(1, 120*time.Second, 2, 3, 4, 5, 6)
+	cluster.checkSafety()
+}
+
+func Test_VbftSim_WithholdEndorsement(t *testing.T) {
+	cluster := newSimCluster(t, 7, 7)
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	cluster.nodes[0].server.SetByzantineBehavior(vbft.BYZANTINE_WITHHOLD_ENDORSEMENT)
+	cluster.nodes[1].server.SetByzantineBehavior(vbft.BYZANTINE_WITHHOLD_ENDORSEMENT |
+		vbft.BYZANTINE_DOUBLE_PROPOSAL)
+	cluster.start()
+
+	cluster.waitHeight(5, 120*time.Second)
+	cluster.checkSafety()
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TestConsensus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_stake"
+	"github.com/ontio/ontology/smartcontract/service/native/shardasset/oep4"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	shardstates "github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/testsuite/common"
+	"github.com/ontio/ontology/testsuite/utils"
+)
+
+// blocks of shard epoch in shard simulations
+const simShardChangeView = 20
+
+// shardSim: child shard run by peers of root shard, root ledger is shared by all nodes of shard.
+// Shard calls from child to root are relayed by test, in place of chain manager.
+type shardSim struct {
+	*simCluster
+	shardID common.ShardID
+	rootLgr *ledger.Ledger
+}
+
+func newShardSimCluster(t *testing.T, n int, seed int64) *shardSim {
+	if testing.Short() {
+		t.Skip("skipping vbft simulation in short mode")
+	}
+	utils.ClearTestChain(t)
+
+	// . create root chain, with child shard activated
+	rootShardID := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	shardID := common.NewShardIDUnchecked(1)
+	TestCommon.CreateChain(t, "root", rootShardID, 0)
+	rootLgr := ledger.GetShardLedger(rootShardID)
+	assetInitTx := TestCommon.CreateNativeTx(t, TestCommon.GetOwnerName(rootShardID, 0), 0,
+		nutils.ShardAssetAddress, oep4.INIT, nil)
+	mgmtInitTx := TestCommon.CreateAdminTx(t, rootShardID, 0, nutils.ShardMgmtContractAddress,
+		shardmgmt.INIT_NAME, nil)
+	initBlock := TestCommon.CreateBlock(t, rootLgr, []*types.Transaction{mgmtInitTx, assetInitTx})
+	TestCommon.ExecBlock(t, rootShardID, initBlock)
+	TestCommon.SubmitBlock(t, rootShardID, initBlock)
+	assetBlock := utils.GenInitShardAssetBlock(t)
+	TestCommon.ExecBlock(t, rootShardID, assetBlock)
+	TestCommon.SubmitBlock(t, rootShardID, assetBlock)
+
+	shardCfg := TestCommon.GetConfig(t, shardID).Genesis.VBFT
+	shardCfg.MaxBlockChangeView = simShardChangeView
+	shardBlock := utils.GenRunShardBlock(t, rootShardID, shardID, TestCommon.GetUserName(rootShardID, 1))
+	TestCommon.ExecBlock(t, rootShardID, shardBlock)
+	TestCommon.SubmitBlock(t, rootShardID, shardBlock)
+
+	// . create template chain of shard, genesis peers are peers joined shard at root
+	shardCfg.Peers = TestCommon.GetConfig(t, rootShardID).Genesis.VBFT.Peers
+	xshard.InitCrossShardPool(shardID, 100)
+	TestCommon.CreateChain(t, "src", shardID, shardBlock.Header.Height)
+	srcLgr := ledger.GetShardLedger(shardID)
+	ledger.RemoveLedger(shardID)
+
+	return &shardSim{
+		simCluster: newSimClusterOnLedger(t, srcLgr, rootShardID, n, seed, nil),
+		shardID:    shardID,
+		rootLgr:    rootLgr,
+	}
+}
+
+func (sim *shardSim) stop() {
+	sim.simCluster.stop()
+	ledger.RemoveLedger(sim.rootLgr.ShardID)
+}
+
+func (sim *shardSim) peerPubkey(index int) string {
+	acc := TestCommon.GetAccount(TestCommon.GetOwnerName(sim.rootLgr.ShardID, index))
+	return hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
+}
+
+// shardCall: relay shard call of child shard to root in new root block
+func (sim *shardSim) shardCall(contract common.Address, method string, args []byte) {
+	blk := TestCommon.CreateBlock(sim.t, sim.rootLgr, nil)
+	blk.ShardTxs[sim.shardID] = []*types.CrossShardTxInfos{
+		{Tx: TestCommon.CreateShardCallTx(sim.t, sim.shardID, contract, method, args)},
+	}
+	TestCommon.ExecBlock(sim.t, sim.rootLgr.ShardID, blk)
+	TestCommon.SubmitBlock(sim.t, sim.rootLgr.ShardID, blk)
+}
+
+func (sim *shardSim) rootNodeType(index int) shardstates.NodeType {
+	shard := TestCommon.GetShardStateFromLedger(sim.t, sim.rootLgr, sim.shardID)
+	return shard.Peers[sim.peerPubkey(index)].NodeType
+}
+
+func blockInfo(lgr *ledger.Ledger, height uint32) *vconfig.VbftBlockInfo {
+	blk, err := lgr.GetBlockByHeight(height)
+	if err != nil || blk == nil {
+		return nil
+	}
+	info := &vconfig.VbftBlockInfo{}
+	if err := json.Unmarshal(blk.Header.ConsensusPayload, info); err != nil {
+		return nil
+	}
+	return info
+}
+
+// findRotation: emergency rotation of peer committed in ledger
+func findRotation(lgr *ledger.Ledger, peer string) *nutils.EmergencyRotation {
+	for h := uint32(1); h <= lgr.GetCurrentBlockHeight(); h++ {
+		info := blockInfo(lgr, h)
+		if info == nil || len(info.EmergencyRotation) == 0 {
+			continue
+		}
+		rotation := &nutils.EmergencyRotation{}
+		if err := rotation.Deserialize(bytes.NewReader(info.EmergencyRotation)); err != nil {
+			continue
+		}
+		if strings.EqualFold(rotation.PeerPubkey, peer) {
+			return rotation
+		}
+	}
+	return nil
+}
+
+// findCommitDpos: first block switched chain config by commit dpos, not by rotation
+func findCommitDpos(lgr *ledger.Ledger) (uint32, *vconfig.ChainConfig) {
+	for h := uint32(1); h <= lgr.GetCurrentBlockHeight(); h++ {
+		info := blockInfo(lgr, h)
+		if info != nil && info.NewChainConfig != nil && len(info.EmergencyRotation) == 0 {
+			return h, info.NewChainConfig
+		}
+	}
+	return 0, nil
+}
+
+// waitRotation: rotation of crashed node committed by all nodes in indexes in timeout of virtual time
+func (sim *shardSim) waitRotation(crashed int, timeout time.Duration, indexes ...int) *nutils.EmergencyRotation {
+	peer := sim.peerPubkey(crashed)
+	rotated := sim.run(timeout, func() bool {
+		for _, i := range indexes {
+			if findRotation(sim.nodes[i].lgr, peer) == nil {
+				return false
+			}
+		}
+		return true
+	})
+	if !rotated {
+		sim.t.Fatalf("seed %d: node %d not rotated out in %s, heights: %v", sim.seed, crashed, timeout,
+			sim.heights())
+	}
+	return findRotation(sim.nodes[indexes[0]].lgr, peer)
+}
+
+// notifyRotation: relay rotation to root shard, as notified by NotifyParentEmergencyRotation
+func (sim *shardSim) notifyRotation(rotation *nutils.EmergencyRotation) {
+	bf := new(bytes.Buffer)
+	if err := rotation.Serialize(bf); err != nil {
+		sim.t.Fatalf("serialize rotation: %s", err)
+	}
+	sim.shardCall(nutils.ShardMgmtContractAddress, shardmgmt.EMERGENCY_ROTATION_NAME, bf.Bytes())
+}
+
+// notifyCommitDpos: relay commit dpos of shard at height to root shard
+func (sim *shardSim) notifyCommitDpos(height uint32) {
+	mgmtParam := &shardmgmt.NotifyRootCommitDPosParam{ShardId: sim.shardID, Height: height}
+	bf := new(bytes.Buffer)
+	if err := mgmtParam.Serialize(bf); err != nil {
+		sim.t.Fatalf("serialize commit dpos param: %s", err)
+	}
+	sim.shardCall(nutils.ShardMgmtContractAddress, shardmgmt.COMMIT_DPOS_NAME, bf.Bytes())
+
+	stakeParam := &shard_stake.CommitDposParam{ShardId: sim.shardID, Height: height}
+	sink := common.NewZeroCopySink(0)
+	stakeParam.Serialization(sink)
+	sim.shardCall(nutils.ShardStakeAddress, shard_stake.COMMIT_DPOS, sink.Bytes())
+}
+
+func Test_VbftSim_ShardEmergencyRotation(t *testing.T) {
+	rotationTimeout := config.DefConfig.Consensus.EmergencyRotationTimeout
+	config.DefConfig.Consensus.EmergencyRotationTimeout = 10
+	defer func() { config.DefConfig.Consensus.EmergencyRotationTimeout = rotationTimeout }()
+
+	sim := newShardSimCluster(t, 7, 9)
+	defer sim.stop()
+	sim.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	sim.isolateConsensus(0)
+	sim.start()
+
+	// crashed peer is rotated out by shard peers, and quits consensus at root
+	first := sim.waitRotation(0, 120*time.Second, 1, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+	sim.notifyRotation(first)
+	if nodeType := sim.rootNodeType(0); nodeType != shardstates.QUIT_CONSENSUS_NODE {
+		t.Fatalf("rotated node 0 is %d at root", nodeType)
+	}
+
+	// shard commits dpos without the rotated peer, in later view
+	var height uint32
+	var cfg *vconfig.ChainConfig
+	committed := sim.run(600*time.Second, func() bool {
+		height, cfg = findCommitDpos(sim.nodes[1].lgr)
+		return cfg != nil
+	})
+	if !committed {
+		t.Fatalf("seed %d: shard not committed dpos, heights: %v", sim.seed, sim.heights())
+	}
+	for _, p := range cfg.Peers {
+		if strings.EqualFold(p.ID, sim.peerPubkey(0)) {
+			t.Fatalf("rotated node 0 in chain config of commit dpos at %d", height)
+		}
+	}
+	if cfg.View <= first.View {
+		t.Fatalf("view %d of commit dpos not after rotation view %d", cfg.View, first.View)
+	}
+	sim.notifyCommitDpos(height)
+	sim.waitHeight(height+3, 120*time.Second, 1, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+
+	// another peer crashed after commit dpos is rotated out, and accepted by root
+	sim.isolateConsensus(1)
+	second := sim.waitRotation(1, 120*time.Second, 2, 3, 4, 5, 6)
+	if second.View <= cfg.View {
+		t.Fatalf("rotation view %d not after commit dpos view %d", second.View, cfg.View)
+	}
+	sim.notifyRotation(second)
+	if nodeType := sim.rootNodeType(1); nodeType != shardstates.QUIT_CONSENSUS_NODE {
+		t.Fatalf("rotated node 1 is %d at root", nodeType)
+	}
+	sim.waitHeight(sim.nodes[2].lgr.GetCurrentBlockHeight()+3, 120*time.Second, 2, 3, 4, 5, 6)
+	sim.checkSafety()
+}
+
+func Test_VbftSim_ShardSyncOverCommitDpos(t *testing.T) {
+	sim := newShardSimCluster(t, 7, 10)
+	defer sim.stop()
+	sim.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	sim.partition([]int{0, 1, 2, 3, 4, 5}, []int{6})
+	sim.start()
+
+	// shard commits dpos while node 6 is offline
+	var height uint32
+	committed := sim.run(600*time.Second, func() bool {
+		height, _ = findCommitDpos(sim.nodes[0].lgr)
+		return height != 0
+	})
+	if !committed {
+		t.Fatalf("seed %d: shard not committed dpos, heights: %v", sim.seed, sim.heights())
+	}
+	sim.notifyCommitDpos(height)
+	sim.waitHeight(height+3, 120*time.Second, 0, 1, 2, 3, 4, 5)
+	if h := sim.nodes[6].lgr.GetCurrentBlockHeight(); h >= height {
+		t.Fatalf("offline node 6 reached commit dpos block %d at %d", height, h)
+	}
+	sim.checkSafety()
+
+	// node 6 fetches new chain config from peers, syncs over the config block, and rejoins consensus
+	sim.policy.Heal()
+	top := uint32(0)
+	for _, h := range sim.heights() {
+		if h > top {
+			top = h
+		}
+	}
+	sim.waitHeight(top+3, 300*time.Second)
+	sim.checkSafety()
+	if cfgHeight, _ := findCommitDpos(sim.nodes[6].lgr); cfgHeight != height {
+		t.Fatalf("node 6 committed dpos at %d, others at %d", cfgHeight, height)
+	}
+}
@@ -0,0 +1,330 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TestConsensus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/consensus/vbft"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/testsuite/common"
+	"github.com/ontio/ontology/testsuite/utils"
+)
+
+const (
+	// virtual time advanced in each simulation step
+	simStep = 100 * time.Millisecond
+	// real time for servers to process msgs and timers of each simulation step
+	simYield = 20 * time.Millisecond
+)
+
+// simNode: vbft server running on simulated network
+type simNode struct {
+	name   string
+	server *vbft.Server
+	peer   *TestCommon.MockPeer
+	lgr    *ledger.Ledger
+}
+
+func (node *simNode) id() uint64 {
+	return node.peer.Local.GetID()
+}
+
+// simCluster: N vbft servers running in-process, over mock network with injected faults.
+// All timers of servers and network are driven by virtual clock of cluster.
+type simCluster struct {
+	t      *testing.T
+	seed   int64
+	clock  *TestCommon.SimClock
+	policy *TestCommon.NetPolicy
+	nodes  []*simNode
+}
+
+func newSimCluster(t *testing.T, n int, seed int64) *simCluster {
+	return newSimClusterWithSkews(t, n, seed, nil)
+}
+
+// newSimClusterWithSkews: local clocks of nodes are shifted by skews, indexed by node index
+func newSimClusterWithSkews(t *testing.T, n int, seed int64, skews map[int]time.Duration) *simCluster {
+	if testing.Short() {
+		t.Skip("skipping vbft simulation in short mode")
+	}
+	utils.ClearTestChain(t)
+
+	shardID := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	xshard.InitCrossShardPool(shardID, 100)
+
+	// . create template chain
+	TestCommon.CreateChain(t, "src", shardID, 0)
+	srcLgr := ledger.GetShardLedger(shardID)
+	ledger.RemoveLedger(shardID)
+	return newSimClusterOnLedger(t, srcLgr, shardID, n, seed, skews)
+}
+
+// newSimClusterOnLedger: start nodes on chain cloned from srcLgr, with peer owner accounts of accShard
+func newSimClusterOnLedger(t *testing.T, srcLgr *ledger.Ledger, accShard common.ShardID, n int, seed int64,
+	skews map[int]time.Duration) *simCluster {
+	shardID := srcLgr.ShardID
+	clock := TestCommon.NewSimClock(time.Now())
+	net := TestCommon.NewMockNetwork()
+	policy := TestCommon.NewNetPolicy(seed)
+	net.SetPolicy(policy)
+	net.SetClock(clock)
+
+	cluster := &simCluster{
+		t:      t,
+		seed:   seed,
+		clock:  clock,
+		policy: policy,
+		nodes:  make([]*simNode, 0, n),
+	}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("peerOwner%d", i)
+		service, peer, lgr := startMockerConsensusOnNet(t, net, clock.WithSkew(skews[i]), shardID, accShard, name,
+			srcLgr)
+		server, ok := service.(*vbft.Server)
+		if !ok {
+			t.Fatalf("failed cast consensus service to vbft")
+		}
+		cluster.nodes = append(cluster.nodes, &simNode{
+			name:   name,
+			server: server,
+			peer:   peer,
+			lgr:    lgr,
+		})
+	}
+	log.Infof("simulation cluster with %d nodes, seed %d", n, seed)
+	return cluster
+}
+
+func (cluster *simCluster) start() {
+	for _, node := range cluster.nodes {
+		node.server.Start()
+	}
+}
+
+func (cluster *simCluster) stop() {
+	for _, node := range cluster.nodes {
+		node.server.Halt()
+	}
+}
+
+// run: advance virtual clock step by step, until cond is met or timeout of virtual time
+func (cluster *simCluster) run(timeout time.Duration, cond func() bool) bool {
+	deadline := cluster.clock.Now().Add(timeout)
+	for !cond() {
+		if !cluster.clock.Now().Before(deadline) {
+			return false
+		}
+		cluster.clock.Advance(simStep)
+		time.Sleep(simYield)
+	}
+	return true
+}
+
+// advance: run cluster for duration of virtual time
+func (cluster *simCluster) advance(duration time.Duration) {
+	cluster.run(duration, func() bool { return false })
+}
+
+func (cluster *simCluster) nodeIDs(indexes ...int) []uint64 {
+	ids := make([]uint64, 0, len(indexes))
+	for _, i := range indexes {
+		ids = append(ids, cluster.nodes[i].id())
+	}
+	return ids
+}
+
+// partition: split nodes into groups with node indexes
+func (cluster *simCluster) partition(groups ...[]int) {
+	partitions := make([][]uint64, 0, len(groups))
+	for _, group := range groups {
+		partitions = append(partitions, cluster.nodeIDs(group...))
+	}
+	cluster.policy.Partition(partitions...)
+}
+
+// isolateConsensus: drop all consensus msgs sent from node
+func (cluster *simCluster) isolateConsensus(index int) {
+	id := cluster.nodes[index].id()
+	cluster.policy.AddFilter(func(from, to uint64, msg types.Message) bool {
+		_, isConsensus := msg.(*types.Consensus)
+		return from != id || !isConsensus
+	})
+}
+
+func (cluster *simCluster) heights() []uint32 {
+	heights := make([]uint32, 0, len(cluster.nodes))
+	for _, node := range cluster.nodes {
+		heights = append(heights, node.lgr.GetCurrentBlockHeight())
+	}
+	return heights
+}
+
+// checkSafety: no two nodes committed different blocks at same height
+func (cluster *simCluster) checkSafety() {
+	committed := make(map[uint32]common.Uint256)
+	for i, node := range cluster.nodes {
+		height := node.lgr.GetCurrentBlockHeight()
+		for h := uint32(1); h <= height; h++ {
+			hash := node.lgr.GetBlockHash(h)
+			if hash == common.UINT256_EMPTY {
+				continue
+			}
+			if prev, present := committed[h]; present && prev != hash {
+				cluster.t.Fatalf("seed %d: node %d committed block %s at height %d, conflicts with %s",
+					cluster.seed, i, hash.ToHexString(), h, prev.ToHexString())
+			}
+			committed[h] = hash
+		}
+	}
+}
+
+func allIndexes(cluster *simCluster, indexes []int) []int {
+	if len(indexes) != 0 {
+		return indexes
+	}
+	for i := range cluster.nodes {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// waitHeight: all nodes in indexes reach height in timeout of virtual time
+func (cluster *simCluster) waitHeight(height uint32, timeout time.Duration, indexes ...int) {
+	indexes = allIndexes(cluster, indexes)
+	reached := cluster.run(timeout, func() bool {
+		for _, i := range indexes {
+			if cluster.nodes[i].lgr.GetCurrentBlockHeight() < height {
+				return false
+			}
+		}
+		return true
+	})
+	if !reached {
+		cluster.t.Fatalf("seed %d: not reached height %d in %s, heights: %v", cluster.seed, height, timeout,
+			cluster.heights())
+	}
+}
+
+// checkStalled: no node in indexes made progress in duration of virtual time
+func (cluster *simCluster) checkStalled(duration time.Duration, indexes ...int) {
+	before := cluster.heights()
+	cluster.advance(duration)
+	after := cluster.heights()
+	for _, i := range indexes {
+		if after[i] != before[i] {
+			cluster.t.Fatalf("seed %d: node %d made progress from %d to %d without quorum", cluster.seed, i,
+				before[i], after[i])
+		}
+	}
+}
+
+func Test_VbftSim_Normal(t *testing.T) {
+	cluster := newSimCluster(t, 7, 1)
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	cluster.start()
+
+	cluster.waitHeight(5, 60*time.Second)
+	cluster.checkSafety()
+}
+
+func Test_VbftSim_LossyNetwork(t *testing.T) {
+	cluster := newSimCluster(t, 7, 2)
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 200*time.Millisecond)
+	cluster.policy.SetDropRate(0.1)
+	cluster.start()
+
+	cluster.waitHeight(5, 120*time.Second)
+	cluster.checkSafety()
+}
+
+func Test_VbftSim_PartitionHeal(t *testing.T) {
+	cluster := newSimCluster(t, 7, 3)
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	cluster.start()
+	cluster.waitHeight(2, 60*time.Second)
+
+	// neither side has quorum of 7 nodes
+	cluster.partition([]int{0, 1, 2}, []int{3, 4, 5, 6})
+	cluster.advance(10 * time.Second)
+	cluster.checkStalled(20*time.Second, 0, 1, 2, 3, 4, 5, 6)
+	cluster.checkSafety()
+
+	cluster.policy.Heal()
+	height := cluster.nodes[0].lgr.GetCurrentBlockHeight()
+	for _, h := range cluster.heights() {
+		if h > height {
+			height = h
+		}
+	}
+	cluster.waitHeight(height+3, 120*time.Second)
+	cluster.checkSafety()
+}
+
+func Test_VbftSim_MinorityPartition(t *testing.T) {
+	cluster := newSimCluster(t, 7, 4)
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	cluster.partition([]int{0, 1}, []int{2, 3, 4, 5, 6})
+	cluster.start()
+
+	// majority side keeps committing blocks
+	cluster.waitHeight(5, 120*time.Second, 2, 3, 4, 5, 6)
+	cluster.checkSafety()
+
+	cluster.policy.Heal()
+	cluster.waitHeight(5, 120*time.Second)
+	cluster.checkSafety()
+}
+
+func Test_VbftSim_ClockSkew(t *testing.T) {
+	cluster := newSimClusterWithSkews(t, 7, 5, map[int]time.Duration{
+		1: 5 * time.Minute,
+		2: -5 * time.Minute,
+		3: 20 * time.Minute,
+	})
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	cluster.start()
+
+	cluster.waitHeight(5, 120*time.Second)
+	cluster.checkSafety()
+}
+
+func Test_VbftSim_SilentNode(t *testing.T) {
+	cluster := newSimCluster(t, 7, 8)
+	defer cluster.stop()
+	cluster.policy.SetLatency(10*time.Millisecond, 50*time.Millisecond)
+	cluster.isolateConsensus(0)
+	cluster.start()
+
+	cluster.waitHeight(5, 120*time.Second, 1, 2, 3, 4, 5, 6)
+	cluster.checkSafety()
+}
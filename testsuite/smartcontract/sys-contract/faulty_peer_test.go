@@ -0,0 +1,227 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TestContracts
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	cutils "github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/serialization"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/types"
+	butils "github.com/ontio/ontology/core/utils"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/ontio/ontology/smartcontract/service/native/governance"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_stake"
+	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
+	shardstates "github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/ontio/ontology/testsuite/common"
+	tutils "github.com/ontio/ontology/testsuite/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFaultyHeader(t *testing.T, acc *account.Account, shardID common.ShardID, height, timestamp uint32,
+	txHashes []common.Uint256) *types.Header {
+	header := &types.Header{
+		ShardID:          shardID,
+		Height:           height,
+		Timestamp:        timestamp,
+		TransactionsRoot: common.ComputeMerkleRoot(txHashes),
+	}
+	hash := header.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	if err != nil {
+		t.Fatalf("sign header: %s", err)
+	}
+	header.Bookkeepers = []keypair.PublicKey{acc.PublicKey}
+	header.SigData = [][]byte{sig}
+	return header
+}
+
+// newDoubleProposalEvidence: evidence of peer proposing two blocks at the same height of shard
+func newDoubleProposalEvidence(t *testing.T, acc *account.Account, shardID common.ShardID,
+	height uint32) *utils.FaultyEvidence {
+	proposals := make([]*utils.FaultyProposal, 0)
+	for _, timestamp := range []uint32{100, 101} {
+		txHashes := []common.Uint256{{1}, {byte(timestamp)}}
+		proposals = append(proposals, &utils.FaultyProposal{
+			Header:        newFaultyHeader(t, acc, shardID, height, timestamp, txHashes),
+			TxHashes:      txHashes,
+			EmptyHeader:   newFaultyHeader(t, acc, shardID, height, timestamp, txHashes[:1]),
+			EmptyTxHashes: txHashes[:1],
+		})
+	}
+	return &utils.FaultyEvidence{
+		Type:       utils.FAULTY_PROPOSAL,
+		PeerPubkey: hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey)),
+		Proposals:  proposals,
+	}
+}
+
+// txState: execution state of tx in block of ledger
+func txState(t *testing.T, lgr *ledger.Ledger, blk *types.Block, tx *types.Transaction) byte {
+	hash := tx.Hash()
+	result := TestCommon.GetResult(t, lgr.ShardID, blk.Header.Height)
+	for _, notify := range result.Notify {
+		if notify.TxHash == hash {
+			return notify.State
+		}
+	}
+	t.Fatalf("tx %s not executed in block %d", hash.ToHexString(), blk.Header.Height)
+	return event.CONTRACT_STATE_FAIL
+}
+
+func ontBalance(t *testing.T, lgr *ledger.Ledger, addr common.Address) uint64 {
+	value, err := lgr.GetStorageItem(utils.OntContractAddress, addr[:])
+	if err != nil || len(value) == 0 {
+		return 0
+	}
+	balance, err := serialization.ReadUint64(bytes.NewReader(value))
+	if err != nil {
+		t.Fatalf("read balance of %s: %s", addr.ToBase58(), err)
+	}
+	return balance
+}
+
+func TestReportFaultyNode(t *testing.T) {
+	tutils.ClearTestChain(t)
+
+	rootShardId := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	TestCommon.CreateChain(t, "root", rootShardId, 0)
+	rootLedger := ledger.GetShardLedger(rootShardId)
+	faulty := TestCommon.GetAccount(TestCommon.GetOwnerName(rootShardId, 0))
+	reporter := TestCommon.GetAccount(TestCommon.GetUserName(rootShardId, 1))
+	blackListKey := append([]byte(governance.BLACK_LIST), keypair.SerializePublicKey(faulty.PublicKey)...)
+
+	report := func(evidence *utils.FaultyEvidence, nonce uint32) byte {
+		bf := new(bytes.Buffer)
+		if err := evidence.Serialize(bf); err != nil {
+			t.Fatalf("serialize evidence: %s", err)
+		}
+		mutable := butils.BuildNativeTransaction(utils.GovernanceContractAddress, governance.REPORT_FAULTY_NODE,
+			bf.Bytes())
+		mutable.Nonce = nonce
+		if err := cutils.SignTransaction(reporter, mutable); err != nil {
+			t.Fatalf("sign tx: %s", err)
+		}
+		tx, err := mutable.IntoImmutable()
+		if err != nil {
+			t.Fatalf("to immutable tx: %s", err)
+		}
+		blk := TestCommon.CreateBlock(t, rootLedger, []*types.Transaction{tx})
+		TestCommon.ExecBlock(t, rootShardId, blk)
+		TestCommon.SubmitBlock(t, rootShardId, blk)
+		return txState(t, rootLedger, blk, tx)
+	}
+
+	// evidence of other shard is not accepted at root
+	otherShard := common.NewShardIDUnchecked(1)
+	assert.Equal(t, event.CONTRACT_STATE_FAIL, report(newDoubleProposalEvidence(t, faulty, otherShard, 5), 1))
+	value, _ := rootLedger.GetStorageItem(utils.GovernanceContractAddress, blackListKey)
+	assert.Equal(t, 0, len(value))
+
+	evidence := newDoubleProposalEvidence(t, faulty, rootShardId, 5)
+	assert.Equal(t, event.CONTRACT_STATE_SUCCESS, report(evidence, 2))
+	value, _ = rootLedger.GetStorageItem(utils.GovernanceContractAddress, blackListKey)
+	assert.NotEqual(t, 0, len(value))
+
+	// evidence can only be reported once
+	assert.Equal(t, event.CONTRACT_STATE_FAIL, report(evidence, 3))
+}
+
+func TestFaultyPeer(t *testing.T) {
+	tutils.ClearTestChain(t)
+
+	rootShardId := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	shardId, _, _ := runTestShard(t)
+	rootLedger := ledger.GetShardLedger(rootShardId)
+	faulty := TestCommon.GetAccount(TestCommon.GetOwnerName(rootShardId, 0))
+	peer := hex.EncodeToString(keypair.SerializePublicKey(faulty.PublicKey))
+
+	execBlock := func(blk *types.Block, tx *types.Transaction) byte {
+		TestCommon.ExecBlock(t, rootShardId, blk)
+		TestCommon.SubmitBlock(t, rootShardId, blk)
+		return txState(t, rootLedger, blk, tx)
+	}
+	faultyPeer := func(evidence *utils.FaultyEvidence) byte {
+		param := &shardmgmt.NotifyFaultyPeerParam{ShardId: shardId, Evidence: evidence}
+		bf := new(bytes.Buffer)
+		if err := param.Serialize(bf); err != nil {
+			t.Fatalf("serialize faulty peer param: %s", err)
+		}
+		tx := TestCommon.CreateShardCallTx(t, shardId, utils.ShardMgmtContractAddress, shardmgmt.FAULTY_PEER_NAME,
+			bf.Bytes())
+		blk := TestCommon.CreateBlock(t, rootLedger, nil)
+		blk.ShardTxs[shardId] = []*types.CrossShardTxInfos{{Tx: tx}}
+		return execBlock(blk, tx)
+	}
+	transferPenalty := func(to common.Address) byte {
+		tx := TestCommon.CreateAdminTx(t, rootShardId, 0, utils.ShardStakeAddress, shard_stake.TRANSFER_PENALTY,
+			[]interface{}{&shard_stake.TransferPenaltyParam{ShardId: shardId, Address: to}})
+		return execBlock(TestCommon.CreateBlock(t, rootLedger, []*types.Transaction{tx}), tx)
+	}
+	// init pos of peer to be confiscated at next view of shard
+	initPos := func() uint64 {
+		for _, view := range []uint32{1, 0} {
+			peers, err := xshard.GetShardPeerStakeInfo(rootLedger, shardId, view)
+			if err != nil {
+				continue
+			}
+			if info, present := peers[peer]; present {
+				return info.InitPos
+			}
+		}
+		t.Fatalf("peer %s has no stake info", peer)
+		return 0
+	}
+	penalty := initPos()
+	assert.NotEqual(t, uint64(0), penalty)
+
+	// no penalty before faulty peer reported
+	receiver := TestCommon.GetAccount(TestCommon.GetUserName(rootShardId, 2)).Address
+	balance := ontBalance(t, rootLedger, receiver)
+	assert.Equal(t, event.CONTRACT_STATE_FAIL, transferPenalty(receiver))
+
+	// evidence of root shard cannot be reported by child shard
+	assert.Equal(t, event.CONTRACT_STATE_FAIL, faultyPeer(newDoubleProposalEvidence(t, faulty, rootShardId, 5)))
+
+	evidence := newDoubleProposalEvidence(t, faulty, shardId, 5)
+	assert.Equal(t, event.CONTRACT_STATE_SUCCESS, faultyPeer(evidence))
+	shard := TestCommon.GetShardStateFromLedger(t, rootLedger, shardId)
+	assert.Equal(t, shardstates.QUIT_CONSENSUS_NODE, shard.Peers[peer].NodeType)
+	assert.Equal(t, uint64(0), initPos())
+
+	// evidence can only be reported once
+	assert.Equal(t, event.CONTRACT_STATE_FAIL, faultyPeer(evidence))
+
+	// confiscated init pos is transferred by admin, and only once
+	assert.Equal(t, event.CONTRACT_STATE_SUCCESS, transferPenalty(receiver))
+	assert.Equal(t, balance+penalty, ontBalance(t, rootLedger, receiver))
+	assert.Equal(t, event.CONTRACT_STATE_FAIL, transferPenalty(receiver))
+	assert.Equal(t, balance+penalty, ontBalance(t, rootLedger, receiver))
+}
@@ -19,14 +19,19 @@
 package TestContracts
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
 	"testing"
 
+	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/core/chainmgr"
 	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/signature"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/service/native/shard_stake"
 	"github.com/ontio/ontology/smartcontract/service/native/shardasset/oep4"
 	"github.com/ontio/ontology/smartcontract/service/native/shardmgmt"
 	shardstates "github.com/ontio/ontology/smartcontract/service/native/shardmgmt/states"
@@ -67,6 +72,91 @@ func Test_ShardMgmtInit(t *testing.T) {
 func TestStartShard(t *testing.T) {
 	tutils.ClearTestChain(t)
 
+	rootShardId := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	newShardId, creatorName, shardBlock := runTestShard(t)
+	rootLedger := ledger.GetShardLedger(rootShardId)
+
+	newShard := TestCommon.GetShardStateFromLedger(t, rootLedger, newShardId)
+	assert.Equal(t, newShardId, newShard.ShardID)
+	assert.Equal(t, TestCommon.GetAccount(creatorName).Address, newShard.Creator)
+	assert.Equal(t, uint32(shardstates.SHARD_STATE_ACTIVE), newShard.State)
+	assert.Equal(t, shardBlock.Header.Height, newShard.GenesisParentHeight)
+	shardConfig := TestCommon.GetConfig(t, newShardId)
+	assertVbftConfig(t, shardConfig.Genesis.VBFT, newShard.Config.VbftCfg)
+	assert.Equal(t, 7, len(newShard.Peers))
+	assert.Equal(t, 7, len(newShard.Config.VbftCfg.Peers))
+}
+
+func TestStopArchiveShard(t *testing.T) {
+	tutils.ClearTestChain(t)
+
+	rootShardId := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	shardId, creatorName, _ := runTestShard(t)
+	rootLedger := ledger.GetShardLedger(rootShardId)
+	maxChangeView := TestCommon.GetShardStateFromLedger(t, rootLedger, shardId).Config.VbftCfg.MaxBlockChangeView
+
+	execBlock := func(blk *types.Block) {
+		TestCommon.ExecBlock(t, rootShardId, blk)
+		TestCommon.SubmitBlock(t, rootShardId, blk)
+	}
+	execTx := func(method string, param interface{}) {
+		tx := TestCommon.CreateNativeTx(t, creatorName, 0, utils.ShardMgmtContractAddress, method,
+			[]interface{}{param})
+		execBlock(TestCommon.CreateBlock(t, rootLedger, []*types.Transaction{tx}))
+	}
+	// child shard commits dpos with shard call, root shard notifies child, and child settles fee of last view
+	commitDpos := func(height uint32, drained bool) {
+		mgmtParam := &shardmgmt.NotifyRootCommitDPosParam{ShardId: shardId, Height: height, Drained: drained}
+		bf := new(bytes.Buffer)
+		if err := mgmtParam.Serialize(bf); err != nil {
+			t.Fatalf("serialize commit dpos param: %s", err)
+		}
+		blk := TestCommon.CreateBlock(t, rootLedger, nil)
+		blk.ShardTxs[shardId] = []*types.CrossShardTxInfos{
+			{Tx: TestCommon.CreateShardCallTx(t, shardId, utils.ShardMgmtContractAddress, shardmgmt.COMMIT_DPOS_NAME, bf.Bytes())},
+		}
+		execBlock(blk)
+
+		stakeParam := &shard_stake.CommitDposParam{ShardId: shardId, Height: height}
+		sink := common.NewZeroCopySink(0)
+		stakeParam.Serialization(sink)
+		blk = TestCommon.CreateBlock(t, rootLedger, nil)
+		blk.ShardTxs[shardId] = []*types.CrossShardTxInfos{
+			{Tx: TestCommon.CreateShardCallTx(t, shardId, utils.ShardStakeAddress, shard_stake.COMMIT_DPOS, sink.Bytes())},
+		}
+		execBlock(blk)
+	}
+	assertState := func(state uint32) {
+		assert.Equal(t, state, TestCommon.GetShardStateFromLedger(t, rootLedger, shardId).State)
+	}
+
+	// active shard cannot be archived
+	execTx(shardmgmt.ARCHIVE_SHARD_NAME, &shardmgmt.ArchiveShardParam{ShardID: shardId})
+	assertState(shardstates.SHARD_STATE_ACTIVE)
+
+	execTx(shardmgmt.STOP_SHARD_NAME, &shardmgmt.StopShardParam{ShardID: shardId})
+	assertState(shardstates.SHARD_STATE_STOPPING)
+
+	// shard doesn't commit dpos after stopped
+	execTx(shardmgmt.ARCHIVE_SHARD_NAME, &shardmgmt.ArchiveShardParam{ShardID: shardId})
+	assertState(shardstates.SHARD_STATE_STOPPING)
+
+	// shard has pending cross shard txs
+	commitDpos(maxChangeView, false)
+	execTx(shardmgmt.ARCHIVE_SHARD_NAME, &shardmgmt.ArchiveShardParam{ShardID: shardId})
+	assertState(shardstates.SHARD_STATE_STOPPING)
+
+	commitDpos(2*maxChangeView, true)
+	execTx(shardmgmt.ARCHIVE_SHARD_NAME, &shardmgmt.ArchiveShardParam{ShardID: shardId})
+	assertState(shardstates.SHARD_STATE_ARCHIVED)
+
+	// archived shard cannot be stopped again
+	execTx(shardmgmt.STOP_SHARD_NAME, &shardmgmt.StopShardParam{ShardID: shardId})
+	assertState(shardstates.SHARD_STATE_ARCHIVED)
+}
+
+// runTestShard creates, configures and activates a new child shard of root shard
+func runTestShard(t *testing.T) (common.ShardID, string, *types.Block) {
 	rootShardId := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
 	rootLedger := ledger.GetShardLedger(rootShardId)
 	if rootLedger == nil {
@@ -98,16 +188,7 @@ func TestStartShard(t *testing.T) {
 	shardBlock := tutils.GenRunShardBlock(t, rootShardId, newShardId, creatorName)
 	TestCommon.ExecBlock(t, rootShardId, shardBlock)
 	TestCommon.SubmitBlock(t, rootShardId, shardBlock)
-
-	newShard := TestCommon.GetShardStateFromLedger(t, rootLedger, newShardId)
-	assert.Equal(t, newShardId, newShard.ShardID)
-	assert.Equal(t, TestCommon.GetAccount(creatorName).Address, newShard.Creator)
-	assert.Equal(t, uint32(shardstates.SHARD_STATE_ACTIVE), newShard.State)
-	assert.Equal(t, shardBlock.Header.Height, newShard.GenesisParentHeight)
-	shardConfig := TestCommon.GetConfig(t, newShardId)
-	assertVbftConfig(t, shardConfig.Genesis.VBFT, newShard.Config.VbftCfg)
-	assert.Equal(t, 7, len(newShard.Peers))
-	assert.Equal(t, 7, len(newShard.Config.VbftCfg.Peers))
+	return newShardId, creatorName, shardBlock
 }
 
 func assertVbftConfig(t *testing.T, except, actual *config.VBFTConfig) {
@@ -124,3 +205,79 @@ func assertVbftConfig(t *testing.T, except, actual *config.VBFTConfig) {
 	assert.Equal(t, except.VrfValue, actual.VrfValue)
 	assert.Equal(t, except.VrfProof, actual.VrfProof)
 }
+
+func TestEmergencyRotation(t *testing.T) {
+	tutils.ClearTestChain(t)
+
+	rootShardId := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	shardId, _, _ := runTestShard(t)
+	rootLedger := ledger.GetShardLedger(rootShardId)
+	maxChangeView := TestCommon.GetShardStateFromLedger(t, rootLedger, shardId).Config.VbftCfg.MaxBlockChangeView
+
+	execShardCall := func(contract common.Address, method string, args []byte) {
+		blk := TestCommon.CreateBlock(t, rootLedger, nil)
+		blk.ShardTxs[shardId] = []*types.CrossShardTxInfos{
+			{Tx: TestCommon.CreateShardCallTx(t, shardId, contract, method, args)},
+		}
+		TestCommon.ExecBlock(t, rootShardId, blk)
+		TestCommon.SubmitBlock(t, rootShardId, blk)
+	}
+	commitDpos := func(height uint32) {
+		mgmtParam := &shardmgmt.NotifyRootCommitDPosParam{ShardId: shardId, Height: height}
+		bf := new(bytes.Buffer)
+		if err := mgmtParam.Serialize(bf); err != nil {
+			t.Fatalf("serialize commit dpos param: %s", err)
+		}
+		execShardCall(utils.ShardMgmtContractAddress, shardmgmt.COMMIT_DPOS_NAME, bf.Bytes())
+
+		stakeParam := &shard_stake.CommitDposParam{ShardId: shardId, Height: height}
+		sink := common.NewZeroCopySink(0)
+		stakeParam.Serialization(sink)
+		execShardCall(utils.ShardStakeAddress, shard_stake.COMMIT_DPOS, sink.Bytes())
+	}
+	peerPubkey := func(index int) string {
+		acc := TestCommon.GetAccount(TestCommon.GetOwnerName(rootShardId, index))
+		return hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
+	}
+	// shard peers vote to rotate crashed peer out in vbft view of shard
+	rotate := func(view uint32, rotated int, voters ...int) {
+		rotation := &utils.EmergencyRotation{
+			ShardID:    shardId,
+			View:       view,
+			PeerPubkey: peerPubkey(rotated),
+			Votes:      make([]*utils.RotationVote, 0),
+		}
+		hash := utils.RotationVoteHash(shardId, view, rotation.PeerPubkey)
+		for _, i := range voters {
+			sig, err := signature.Sign(TestCommon.GetAccount(TestCommon.GetOwnerName(rootShardId, i)), hash[:])
+			if err != nil {
+				t.Fatalf("sign rotation vote: %s", err)
+			}
+			rotation.Votes = append(rotation.Votes, &utils.RotationVote{Voter: peerPubkey(i), Sig: sig})
+		}
+		bf := new(bytes.Buffer)
+		if err := rotation.Serialize(bf); err != nil {
+			t.Fatalf("serialize rotation: %s", err)
+		}
+		execShardCall(utils.ShardMgmtContractAddress, shardmgmt.EMERGENCY_ROTATION_NAME, bf.Bytes())
+	}
+	assertNodeType := func(index int, nodeType shardstates.NodeType) {
+		shard := TestCommon.GetShardStateFromLedger(t, rootLedger, shardId)
+		assert.Equal(t, nodeType, shard.Peers[peerPubkey(index)].NodeType)
+	}
+
+	// vbft view of shard starts from 1, and is increased by rotation
+	rotate(1, 0, 1, 2, 3, 4, 5)
+	assertNodeType(0, shardstates.QUIT_CONSENSUS_NODE)
+
+	// votes of rotation in the same view, or previous view, are rejected
+	rotate(1, 1, 2, 3, 4, 5, 6)
+	assertNodeType(1, shardstates.CONSENSUS_NODE)
+	rotate(0, 1, 2, 3, 4, 5, 6)
+	assertNodeType(1, shardstates.CONSENSUS_NODE)
+
+	// commit dpos increases vbft view of shard, rotation after commit is still accepted
+	commitDpos(maxChangeView)
+	rotate(3, 1, 2, 3, 4, 5, 6)
+	assertNodeType(1, shardstates.QUIT_CONSENSUS_NODE)
+}
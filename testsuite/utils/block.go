@@ -101,6 +101,7 @@ func GenRunShardBlock(t *testing.T, shardID, childShard common.ShardID, creatorN
 		StakeAssetAddress: utils.OntContractAddress,
 		GasAssetAddress:   utils.OngContractAddress,
 		VbftConfigData:    cfgBuff.Bytes(),
+		ConsensusType:     config.CONSENSUS_TYPE_VBFT,
 	}
 	configTx := TestCommon.CreateNativeTx(t, creatorName, 0, utils.ShardMgmtContractAddress,
 		shardmgmt.CONFIG_SHARD_NAME, []interface{}{configParam})
@@ -19,9 +19,120 @@
 package TestXShard
 
 import (
+	"math"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/chainmgr/message"
+	"github.com/ontio/ontology/core/chainmgr/xshard"
+	"github.com/ontio/ontology/core/chainmgr/xshard_state"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/xshard_types"
+	"github.com/ontio/ontology/smartcontract/service/native"
 	"github.com/ontio/ontology/testsuite"
+	"github.com/ontio/ontology/testsuite/common"
+	"github.com/ontio/ontology/testsuite/utils"
+	"github.com/stretchr/testify/assert"
 )
 
 func init() {
 	TestConsts.TestRootDir = "../"
 }
+
+// relayShardMsgs delivers shard msgs to target shards with shard call txs, one msg per block,
+// until the shard txs complete
+func relayShardMsgs(t *testing.T, msgs []xshard_types.CommonShardMsg) {
+	for len(msgs) > 0 {
+		next := make([]xshard_types.CommonShardMsg, 0)
+		for _, msg := range msgs {
+			source, target := msg.GetSourceShardID(), msg.GetTargetShardID()
+			lgr := ledger.GetShardLedger(target)
+			if lgr == nil {
+				t.Fatalf("relay msg to shard %d without ledger", target.ToUint64())
+			}
+			acc := TestCommon.GetAccount(TestCommon.GetOwnerName(source, 0))
+			tx, err := message.NewCrossShardTxMsg(acc, lgr.GetCurrentBlockHeight(), target, 0, math.MaxUint64,
+				[]xshard_types.CommonShardMsg{msg})
+			if err != nil {
+				t.Fatalf("build shard call tx: %s", err)
+			}
+			blk := TestCommon.CreateBlock(t, lgr, nil)
+			blk.ShardTxs[source] = []*types.CrossShardTxInfos{{Tx: tx}}
+			TestCommon.ExecBlock(t, target, blk)
+			TestCommon.SubmitBlock(t, target, blk)
+			// chain manager records the shard tx executing the source tx
+			if _, err := lgr.GetShardTxHashBySourceTxHash(msg.GetSourceTxHash()); err != nil {
+				if err := lgr.SaveShardTxHashWithSourceTxHash(msg.GetSourceTxHash(), tx.Hash()); err != nil {
+					t.Fatalf("save shard tx hash: %s", err)
+				}
+			}
+			next = append(next, TestCommon.GetResult(t, target, blk.Header.Height).ShardNotify...)
+		}
+		msgs = next
+	}
+}
+
+func TestTraceCrossShardTx(t *testing.T) {
+	utils.ClearTestChain(t)
+
+	rootShardID := common.NewShardIDUnchecked(config.DEFAULT_SHARD_ID)
+	shard1 := common.NewShardIDUnchecked(1)
+	shard2 := common.NewShardIDUnchecked(2)
+	TestCommon.CreateChain(t, "root", rootShardID, 0)
+	TestCommon.CreateChain(t, "shard1", shard1, 0)
+	TestCommon.CreateChain(t, "shard2", shard2, 0)
+
+	// contract on shard1 invokes itself on shard2
+	contract := common.Address{0xfe, 0x01}
+	native.Contracts[contract] = func(service *native.NativeService) {
+		service.Register("invoke", func(service *native.NativeService) ([]byte, error) {
+			return service.InvokeRemoteShard(shard2, contract, "greet", []byte{})
+		})
+		service.Register("greet", func(service *native.NativeService) ([]byte, error) {
+			return []byte("hello"), nil
+		})
+	}
+	defer delete(native.Contracts, contract)
+
+	lgr1 := ledger.GetShardLedger(shard1)
+	tx := TestCommon.CreateNativeTx(t, TestCommon.GetUserName(shard1, 1), 0, contract, "invoke", nil)
+	blk := TestCommon.CreateBlock(t, lgr1, []*types.Transaction{tx})
+	TestCommon.ExecBlock(t, shard1, blk)
+	TestCommon.SubmitBlock(t, shard1, blk)
+	// req, rsp, prepare, prepared and commit
+	relayShardMsgs(t, TestCommon.GetResult(t, shard1, blk.Header.Height).ShardNotify)
+
+	root, err := xshard.TraceCrossShardTx(tx.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, shard1, root.ShardID)
+	assert.Equal(t, xshard_types.NewShardTxID(tx.Hash()), root.ShardTxID)
+	assert.Equal(t, tx.Hash(), root.TxHash)
+	assert.Equal(t, blk.Header.Height, root.Height)
+	assert.True(t, root.Available)
+	assert.Equal(t, xshard_state.ExecCommited, root.State.ExecState)
+	assert.Equal(t, 3, len(root.Msgs))
+
+	assert.Equal(t, 1, len(root.Children))
+	child := root.Children[0]
+	assert.Equal(t, shard2, child.ShardID)
+	assert.Equal(t, root.ShardTxID, child.ShardTxID)
+	assert.True(t, child.Available)
+	assert.Equal(t, xshard_state.ExecCommited, child.State.ExecState)
+	assert.Equal(t, 2, len(child.Msgs))
+	// req of shard1 is executed in the first block of shard2
+	assert.Equal(t, uint32(1), child.Height)
+	shardTx, height, err := ledger.GetShardLedger(shard2).GetShardTxWithHeight(child.TxHash)
+	assert.Nil(t, err)
+	assert.Equal(t, child.Height, height)
+	assert.Equal(t, types.ShardCall, shardTx.Tx.TxType)
+
+	// ledger of shard2 not held by node
+	ledger.RemoveLedger(shard2)
+	root, err = xshard.TraceCrossShardTx(tx.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(root.Children))
+	assert.False(t, root.Children[0].Available)
+	assert.Nil(t, root.Children[0].State)
+}
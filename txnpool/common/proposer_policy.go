@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+)
+
+// ProposerPolicy selects and orders transactions from the verified entries of tx pool
+// for block proposal. The result is truncated to max tx count in block by tx pool.
+type ProposerPolicy interface {
+	Select(entries []*TXEntry) []*TXEntry
+}
+
+// PolicyChain applies policies one by one
+type PolicyChain []ProposerPolicy
+
+func (self PolicyChain) Select(entries []*TXEntry) []*TXEntry {
+	for _, policy := range self {
+		entries = policy.Select(entries)
+	}
+	return entries
+}
+
+// GasPricePolicy orders transactions by gas price, tx hash is compared if gas price is equal,
+// so that all proposers give the same order
+type GasPricePolicy struct{}
+
+func (self GasPricePolicy) Select(entries []*TXEntry) []*TXEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Tx.GasPrice != entries[j].Tx.GasPrice {
+			return entries[i].Tx.GasPrice > entries[j].Tx.GasPrice
+		}
+		hi, hj := entries[i].Tx.Hash(), entries[j].Tx.Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
+	return entries
+}
+
+// PayerCapPolicy limits transactions of one payer in block,
+// transactions over the cap are left in pool for next blocks
+type PayerCapPolicy struct {
+	MaxTxPerPayer int
+}
+
+func (self PayerCapPolicy) Select(entries []*TXEntry) []*TXEntry {
+	if self.MaxTxPerPayer <= 0 {
+		return entries
+	}
+	txCount := make(map[common.Address]int)
+	selected := make([]*TXEntry, 0, len(entries))
+	for _, entry := range entries {
+		if txCount[entry.Tx.Payer] >= self.MaxTxPerPayer {
+			continue
+		}
+		txCount[entry.Tx.Payer]++
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// BlockGasPolicy limits the sum of gas limit of transactions in block
+type BlockGasPolicy struct {
+	MaxBlockGas uint64
+}
+
+func (self BlockGasPolicy) Select(entries []*TXEntry) []*TXEntry {
+	if self.MaxBlockGas == 0 {
+		return entries
+	}
+	gasLeft := self.MaxBlockGas
+	selected := make([]*TXEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Tx.GasLimit > gasLeft {
+			continue
+		}
+		gasLeft -= entry.Tx.GasLimit
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// NewProposerPolicy builds the policy with consensus config, nil is returned if none of
+// the limits is configured, and tx pool keeps ordering transactions by network fee.
+// Consensus system txs and cross-shard txs are not from tx pool, room of block is reserved
+// for cross-shard txs by limiting tx count from pool, see MaxTxCountFromPool
+func NewProposerPolicy(cfg *config.ConsensusConfig) ProposerPolicy {
+	if cfg.MaxTxPerPayer == 0 && cfg.MaxBlockGas == 0 {
+		return nil
+	}
+	return PolicyChain{
+		GasPricePolicy{},
+		PayerCapPolicy{MaxTxPerPayer: int(cfg.MaxTxPerPayer)},
+		BlockGasPolicy{MaxBlockGas: cfg.MaxBlockGas},
+	}
+}
+
+// MaxTxCountFromPool returns max count of txs from tx pool in proposed block, 0 for no limit.
+// Reserved room of block is left for cross-shard txs, if it is less than max tx count in block
+func MaxTxCountFromPool(cfg *config.ConsensusConfig) int {
+	count := int(cfg.MaxTxInBlock)
+	if reserved := int(cfg.ReservedSysTxInBlock); reserved < count {
+		count -= reserved
+	}
+	return count
+}
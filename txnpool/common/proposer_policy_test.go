@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicyTestEntry(t *testing.T, payer byte, gasPrice, gasLimit uint64, nonce uint32) *TXEntry {
+	mutable := &types.MutableTransaction{
+		TxType:   types.Invoke,
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		GasLimit: gasLimit,
+		Payer:    common.Address{payer},
+		Payload:  &payload.InvokeCode{Code: []byte{}},
+	}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	return &TXEntry{Tx: tx}
+}
+
+func TestDefaultProposerPolicy(t *testing.T) {
+	assert.Nil(t, NewProposerPolicy(&config.ConsensusConfig{}))
+	assert.NotNil(t, NewProposerPolicy(&config.ConsensusConfig{MaxTxPerPayer: 2}))
+}
+
+func TestProposerPolicy(t *testing.T) {
+	entries := make([]*TXEntry, 0)
+	for i := uint32(0); i < 10; i++ {
+		entries = append(entries, newPolicyTestEntry(t, 1, 1000, 20000, i))
+	}
+	for i := uint32(0); i < 3; i++ {
+		entries = append(entries, newPolicyTestEntry(t, 2, 500, 20000, i))
+	}
+
+	// gas price priority
+	selected := GasPricePolicy{}.Select(append([]*TXEntry{}, entries...))
+	assert.Equal(t, uint64(1000), selected[0].Tx.GasPrice)
+	assert.Equal(t, uint64(500), selected[12].Tx.GasPrice)
+
+	// payer fairness cap
+	selected = PayerCapPolicy{MaxTxPerPayer: 2}.Select(append([]*TXEntry{}, entries...))
+	assert.Equal(t, 4, len(selected))
+
+	// max block gas
+	selected = BlockGasPolicy{MaxBlockGas: 50000}.Select(append([]*TXEntry{}, entries...))
+	assert.Equal(t, 2, len(selected))
+
+	chain := PolicyChain{
+		GasPricePolicy{},
+		PayerCapPolicy{MaxTxPerPayer: 3},
+		BlockGasPolicy{MaxBlockGas: 100000},
+	}
+	selected = chain.Select(append([]*TXEntry{}, entries...))
+	assert.Equal(t, 5, len(selected))
+	assert.Equal(t, uint64(1000), selected[0].Tx.GasPrice)
+	assert.Equal(t, uint64(500), selected[4].Tx.GasPrice)
+}
+
+func TestMaxTxCountFromPool(t *testing.T) {
+	assert.Equal(t, 0, MaxTxCountFromPool(&config.ConsensusConfig{}))
+	assert.Equal(t, 100, MaxTxCountFromPool(&config.ConsensusConfig{MaxTxInBlock: 100}))
+	// room reserved for cross-shard txs
+	assert.Equal(t, 90, MaxTxCountFromPool(&config.ConsensusConfig{MaxTxInBlock: 100, ReservedSysTxInBlock: 10}))
+	assert.Equal(t, 100, MaxTxCountFromPool(&config.ConsensusConfig{MaxTxInBlock: 100, ReservedSysTxInBlock: 100}))
+}
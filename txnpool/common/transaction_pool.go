@@ -49,6 +49,7 @@ type TXEntry struct {
 type TXPool struct {
 	sync.RWMutex
 	txList map[common.Uint256]*TXEntry // Transactions which have been verified
+	policy ProposerPolicy              // select transactions for block proposal
 }
 
 // Init creates a new transaction pool to gather.
@@ -56,6 +57,14 @@ func (tp *TXPool) Init() {
 	tp.Lock()
 	defer tp.Unlock()
 	tp.txList = make(map[common.Uint256]*TXEntry)
+	tp.policy = NewProposerPolicy(config.DefConfig.Consensus)
+}
+
+// SetProposerPolicy replaces the policy to select transactions for block proposal
+func (tp *TXPool) SetProposerPolicy(policy ProposerPolicy) {
+	tp.Lock()
+	defer tp.Unlock()
+	tp.policy = policy
 }
 
 // AddTxList adds a valid transaction to the transaction pool. If the
@@ -120,40 +129,36 @@ func (tp *TXPool) compareTxHeight(txEntry *TXEntry, height uint32) bool {
 }
 
 // GetTxPool gets the transaction lists from the pool for the consensus,
-// if the byCount is marked, return the configured number at most; if the
-// the byCount is not marked, return all of the current transaction pool.
+// if the byCount is marked, return the transactions ordered by network fee,
+// or selected by proposer policy if configured, the configured number at most;
+// if the byCount is not marked,
+// return all of the current transaction pool.
 func (tp *TXPool) GetTxPool(byCount bool, height uint32) ([]*TXEntry,
 	[]*types.Transaction) {
 	tp.RLock()
 	defer tp.RUnlock()
 
-	orderByFee := make([]*TXEntry, 0, len(tp.txList))
+	txList := make([]*TXEntry, 0, len(tp.txList))
+	oldTxList := make([]*types.Transaction, 0)
 	for _, txEntry := range tp.txList {
-		orderByFee = append(orderByFee, txEntry)
+		if !tp.compareTxHeight(txEntry, height) {
+			oldTxList = append(oldTxList, txEntry.Tx)
+			continue
+		}
+		txList = append(txList, txEntry)
 	}
-	sort.Sort(OrderByNetWorkFee(orderByFee))
 
-	count := int(config.DefConfig.Consensus.MaxTxInBlock)
+	count := MaxTxCountFromPool(config.DefConfig.Consensus)
 	if count <= 0 {
 		byCount = false
 	}
-	if len(tp.txList) < count || !byCount {
-		count = len(tp.txList)
+	if !byCount || tp.policy == nil {
+		sort.Sort(OrderByNetWorkFee(txList))
+	} else {
+		txList = tp.policy.Select(txList)
 	}
-
-	var num int
-	txList := make([]*TXEntry, 0, count)
-	oldTxList := make([]*types.Transaction, 0)
-	for _, txEntry := range orderByFee {
-		if !tp.compareTxHeight(txEntry, height) {
-			oldTxList = append(oldTxList, txEntry.Tx)
-			continue
-		}
-		txList = append(txList, txEntry)
-		num++
-		if num >= count {
-			break
-		}
+	if byCount && len(txList) > count {
+		txList = txList[:count]
 	}
 
 	return txList, oldTxList
@@ -0,0 +1,45 @@
+/*
+ * Copyright (C) 2019 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package proc
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/common/metrics"
+	tc "github.com/ontio/ontology/txnpool/common"
+)
+
+var txStatsNames = map[tc.TxnStatsType]string{
+	tc.RcvStats:       "received",
+	tc.SuccessStats:   "success",
+	tc.FailureStats:   "failure",
+	tc.DuplicateStats: "duplicate",
+	tc.SigErrStats:    "sig_error",
+	tc.StateErrStats:  "state_error",
+}
+
+var txStatsCounter = metrics.NewCounter("ontology_txnpool_txs_total",
+	"Transactions handled by tx pool, same as TXPoolServer.GetStats", "shard", "stats")
+
+func txStatsName(v tc.TxnStatsType) string {
+	if name, present := txStatsNames[v]; present {
+		return name
+	}
+	return fmt.Sprintf("unknown_%d", v)
+}
@@ -90,6 +90,7 @@ type TXPoolServer struct {
 	gasPrice              uint64                              // Gas price to enforce for acceptance into the pool
 	disablePreExec        bool                                // Disbale PreExecute a transaction
 	disableBroadcastNetTx bool                                // Disable broadcast tx from network
+	draining              bool                                // Only accept cross shard tx, shard is stopping
 }
 
 // NewTxPoolServer creates a new tx pool server to schedule workers to
@@ -251,6 +252,22 @@ func (s *TXPoolServer) setHeight(height uint32) {
 	s.height = height
 }
 
+// SetDraining sets the server to reject new transactions except cross shard
+// transactions, the pending cross shard transactions could be finished before
+// the shard stopped.
+func (s *TXPoolServer) SetDraining(draining bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draining = draining
+}
+
+// isDraining returns whether the server is draining
+func (s *TXPoolServer) isDraining() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.draining
+}
+
 // getGasPrice returns the current gas price enforced by the transaction pool
 func (s *TXPoolServer) getGasPrice() uint64 {
 	s.mu.RLock()
@@ -585,6 +602,7 @@ func (s *TXPoolServer) increaseStats(v tc.TxnStatsType) {
 	s.stats.Lock()
 	defer s.stats.Unlock()
 	s.stats.count[v-1]++
+	txStatsCounter.Inc(strconv.FormatUint(s.shardID.ToUint64(), 10), txStatsName(v))
 }
 
 // GetStats returns the transaction statistics
@@ -750,6 +768,10 @@ func (s *TXPoolServer) verifyBlock(req *tc.VerifyBlockReq, sender *actor.PID) {
 
 func (server *TXPoolServer) HandleTransaction(sender tc.SenderType, txn *tx.Transaction, txResultCh chan *tc.TxResult) (errors.ErrCode, string) {
 	server.increaseStats(tc.RcvStats)
+	if sender != tc.ShardSender && server.isDraining() {
+		log.Debugf("handleTransaction: reject transaction %x, shard is stopping", txn.Hash())
+		return errors.ErrUnknown, "shard is stopping"
+	}
 	if len(txn.ToArray()) > tc.MAX_TX_SIZE {
 		log.Debugf("handleTransaction: reject a transaction due to size over 1M")
 		return errors.ErrUnknown, "size is over 1M"
@@ -113,6 +113,16 @@ func (self *TxnPoolManager) StartTxnPoolServer(shardID common.ShardID, lgr *ledg
 	return s, nil
 }
 
+// StopTxnPoolServer stops the txnpool server of shard, and removes it from manager
+func (self *TxnPoolManager) StopTxnPoolServer(shardID common.ShardID) {
+	s := self.servers[shardID]
+	if s == nil {
+		return
+	}
+	s.Stop()
+	delete(self.servers, shardID)
+}
+
 func (self *TxnPoolManager) GetPID(shardId common.ShardID, actor tc.ActorType) *actor.PID {
 	if actor == tc.TxActor {
 		return self.TxActor
@@ -24,6 +24,7 @@ package exec
 
 import (
 	"errors"
+	"fmt"
 	"github.com/ontio/ontology/common/log"
 )
 
@@ -63,7 +64,11 @@ func (vm *VM) doCall(compiled compiledFunction, index int64) {
 		v, ok := vm.Services[compiled.name]
 		if ok {
 			rtn, err := v(vm.Engine)
-			if err != nil || !rtn {
+			if err != nil {
+				// trap the execution, or else the contract will continue with a broken context
+				panic(fmt.Errorf("call method %s failed: %s", compiled.name, err))
+			}
+			if !rtn {
 				log.Errorf("call method :%s failed\n", compiled.name)
 			}
 		} else {
@@ -92,6 +92,12 @@ type ExecutionEngine struct {
 	CodeContainer interfaces.CodeContainer
 	vm            *VM
 	backupVM      *vmstack
+	stepHandler   func() error
+}
+
+//SetStepHandler set the handler called before each instruction, execution traps if it returns error
+func (e *ExecutionEngine) SetStepHandler(handler func() error) {
+	e.stepHandler = handler
 }
 
 //GetVM return vm pointer
@@ -320,7 +326,7 @@ func (e *ExecutionEngine) Call(caller common.Address,
 	defer func() {
 		if err := recover(); err != nil {
 			returnbytes = nil
-			er = errors.NewErr("[Call] error happened while call wasmvm")
+			er = errors.NewErr(fmt.Sprintf("[Call] error happened while call wasmvm: %v", err))
 		}
 	}()
 
@@ -530,6 +536,27 @@ func (e *ExecutionEngine) call(caller common.Address,
 	}
 }
 
+//VerifyCode check the code is a valid wasm module which exports the invoke method
+func VerifyCode(code []byte) (er error) {
+	defer func() {
+		if err := recover(); err != nil {
+			er = errors.NewErr(fmt.Sprintf("[VerifyCode] invalid wasm code: %v", err))
+		}
+	}()
+
+	m, err := wasm.ReadModule(bytes.NewBuffer(code), importer)
+	if err != nil {
+		return errors.NewErr("[VerifyCode]Verify wasm failed!" + err.Error())
+	}
+	if m.Export == nil {
+		return errors.NewErr("[VerifyCode]No export in wasm!")
+	}
+	if _, ok := m.Export.Entries[CONTRACT_METHOD_NAME]; !ok {
+		return errors.NewErr("[VerifyCode]Method:" + CONTRACT_METHOD_NAME + " does not exist!")
+	}
+	return nil
+}
+
 //FIXME NOT IN USE BUT DON'T DELETE IT
 //current we only support the ONT SYSTEM module import
 //other imports will raise an error
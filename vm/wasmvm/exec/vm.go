@@ -385,6 +385,11 @@ func (vm *VM) ExecCode(insideCall bool, fnIndex int64, args ...uint64) (interfac
 func (vm *VM) execCode(isinside bool, compiled compiledFunction) uint64 {
 outer:
 	for int(vm.ctx.pc) < len(vm.ctx.code) {
+		if vm.Engine != nil && vm.Engine.stepHandler != nil {
+			if err := vm.Engine.stepHandler(); err != nil {
+				panic(err)
+			}
+		}
 		op := vm.ctx.code[vm.ctx.pc]
 		vm.ctx.pc++
 
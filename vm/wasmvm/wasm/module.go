@@ -23,6 +23,7 @@
 package wasm
 
 import (
+	"encoding/binary"
 	"errors"
 	"io"
 
@@ -79,6 +80,11 @@ type Module struct {
 	}
 }
 
+// IsWasmCode reports whether the code starts with the wasm magic number
+func IsWasmCode(code []byte) bool {
+	return len(code) >= 4 && binary.LittleEndian.Uint32(code[:4]) == Magic
+}
+
 // ResolveFunc is a function that takes a module name and
 // returns a valid resolved module.
 type ResolveFunc func(name string) (*Module, error)